@@ -0,0 +1,29 @@
+package fs
+
+import (
+	"errors"
+
+	gofs "io/fs"
+)
+
+// AppendFile appends data to the named file in fsys, creating it with permissions perm (before umask) if it does not
+// exist.
+//
+// The file is opened with O_WRONLY|O_CREATE|O_APPEND, so that appends behave in the same way for every provider that
+// supports O_APPEND.
+func AppendFile(fsys FS, name string, data []byte, perm gofs.FileMode) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	f, err := fsys.OpenFile(name, O_WRONLY|O_CREATE|O_APPEND, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
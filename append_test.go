@@ -0,0 +1,43 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendFile(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	assert.NoError(t, fs.AppendFile(osfs, name, []byte("first\n"), 0600))
+	assert.NoError(t, fs.AppendFile(osfs, name, []byte("second\n"), 0600))
+
+	b, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(b))
+
+	fi, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	f, err := fs.OpenAppend(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.Write([]byte("third\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	b, err = os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\nthird\n", string(b))
+}
@@ -1,9 +1,14 @@
 package fs
 
 import (
+	"crypto"
 	"fmt"
+	"maps"
+	"math"
+	"mime"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/transientvariable/anchor"
 
@@ -13,16 +18,50 @@ import (
 
 // Attribute ...
 type Attribute struct {
-	ctime    time.Time
-	gid      int32
-	group    string
-	inode    int64
-	mimeType string
-	mode     gofs.FileMode
-	mtime    time.Time
-	owner    string
-	size     int64
-	uid      int32
+	atime      time.Time
+	blocks     int64
+	blockSize  int64
+	btime      time.Time
+	checksums  map[string]string
+	ctime      time.Time
+	generation int64
+	gid        int32
+	group      string
+	inode      int64
+	linkTarget string
+	metadata   map[string]string
+	mimeType   string
+	mode       gofs.FileMode
+	mtime      time.Time
+	nlink      int64
+	owner      string
+	rdev       int64
+	size       int64
+	uid        int32
+}
+
+// attributeJSON is the JSON representation of an Attribute used by MarshalJSON and UnmarshalJSON.
+type attributeJSON struct {
+	Atime      time.Time         `json:"atime"`
+	Blocks     int64             `json:"blocks"`
+	BlockSize  int64             `json:"block_size"`
+	Btime      time.Time         `json:"btime"`
+	Checksums  map[string]string `json:"checksums,omitempty"`
+	Ctime      time.Time         `json:"ctime"`
+	Generation int64             `json:"generation"`
+	GID        int32             `json:"gid"`
+	Group      string            `json:"group"`
+	Inode      int64             `json:"inode"`
+	LinkTarget string            `json:"link_target"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	MimeType   string            `json:"mime_type"`
+	Mode       uint32            `json:"mode"`
+	Mtime      time.Time         `json:"mtime"`
+	Nlink      int64             `json:"nlink"`
+	Owner      string            `json:"owner"`
+	Rdev       int64             `json:"rdev"`
+	Size       int64             `json:"size"`
+	UID        int32             `json:"uid"`
 }
 
 // NewAttributes ..
@@ -42,11 +81,50 @@ func NewAttributes(attributes ...func(*Attribute)) (*Attribute, error) {
 	return attrs, nil
 }
 
+// Atime returns the time the entry was last accessed, which is the zero time if it is not known.
+func (a *Attribute) Atime() time.Time {
+	return a.atime
+}
+
+// Blocks returns the number of 512-byte blocks allocated for the entry.
+func (a *Attribute) Blocks() int64 {
+	return a.blocks
+}
+
+// BlockSize returns the preferred block size in bytes for I/O on the entry.
+func (a *Attribute) BlockSize() int64 {
+	return a.blockSize
+}
+
+// Btime returns the time the entry was created (birth time), which is the zero time if it is not known.
+func (a *Attribute) Btime() time.Time {
+	return a.btime
+}
+
+// Checksum returns the digest of the content of the entry computed using the named algorithm, and whether the digest
+// is known.
+func (a *Attribute) Checksum(algorithm string) (string, bool) {
+	digest, ok := a.checksums[strings.ToLower(algorithm)]
+	return digest, ok
+}
+
+// Checksums returns a copy of the known digests of the content of the entry, keyed by algorithm.
+func (a *Attribute) Checksums() map[string]string {
+	return maps.Clone(a.checksums)
+}
+
 // Ctime ...
 func (a *Attribute) Ctime() time.Time {
 	return a.ctime
 }
 
+// Generation returns the generation of the content of the entry, which is incremented by a file system each time the
+// content is changed, or is set to the native version of the content by providers that track versions. A generation
+// of 0 indicates that it is not known.
+func (a *Attribute) Generation() int64 {
+	return a.generation
+}
+
 // GID ...
 func (a *Attribute) GID() int32 {
 	return a.gid
@@ -62,6 +140,23 @@ func (a *Attribute) Inode() int64 {
 	return a.inode
 }
 
+// LinkTarget returns the destination of the entry if it is a symbolic link, and is otherwise empty.
+func (a *Attribute) LinkTarget() string {
+	return a.linkTarget
+}
+
+// Metadata returns a copy of the user-defined metadata of the entry, such as custom object metadata or application
+// tags.
+func (a *Attribute) Metadata() map[string]string {
+	return maps.Clone(a.metadata)
+}
+
+// MetadataValue returns the value of the user-defined metadata key of the entry, and whether the key is set.
+func (a *Attribute) MetadataValue(key string) (string, bool) {
+	v, ok := a.metadata[key]
+	return v, ok
+}
+
 // MimeType ...
 func (a *Attribute) MimeType() string {
 	return a.mimeType
@@ -77,11 +172,21 @@ func (a *Attribute) Mtime() time.Time {
 	return a.mtime
 }
 
+// Nlink returns the number of hard links to the entry.
+func (a *Attribute) Nlink() int64 {
+	return a.nlink
+}
+
 // Owner ...
 func (a *Attribute) Owner() string {
 	return a.owner
 }
 
+// Rdev returns the device number of the entry if it is a device file.
+func (a *Attribute) Rdev() int64 {
+	return a.rdev
+}
+
 // Size ...
 func (a *Attribute) Size() int64 {
 	return a.size
@@ -92,22 +197,213 @@ func (a *Attribute) UID() int32 {
 	return a.uid
 }
 
+// SetAtime sets the time the entry was last accessed.
+func (a *Attribute) SetAtime(atime time.Time) {
+	a.atime = atime.UTC()
+}
+
+// SetGeneration sets the generation of the content of the entry.
+func (a *Attribute) SetGeneration(generation int64) {
+	a.generation = generation
+}
+
+// SetGID sets the group id of the entry. An error is returned if gid can not be represented by the Attribute.
+func (a *Attribute) SetGID(gid uint32) error {
+	if gid > math.MaxInt32 {
+		return fmt.Errorf("attribute: gid %d is out of range: %w", gid, ErrInvalid)
+	}
+
+	if int32(gid) != a.gid {
+		a.gid = int32(gid)
+		a.touch()
+	}
+	return nil
+}
+
+// SetGroup sets the name of the group of the entry. An empty name indicates that the group is not known. An error is
+// returned if group contains whitespace, control characters, a colon or a slash.
+func (a *Attribute) SetGroup(group string) error {
+	if !validPrincipal(group) {
+		return fmt.Errorf("attribute: group %q is invalid: %w", group, ErrInvalid)
+	}
+
+	if group != a.group {
+		a.group = group
+		a.touch()
+	}
+	return nil
+}
+
+// SetMimeType sets the MIME type of the entry. An empty MIME type indicates that it is not known. An error is returned
+// if mimeType is not a valid media type as defined by RFC 2045.
+func (a *Attribute) SetMimeType(mimeType string) error {
+	if mimeType != "" {
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			return fmt.Errorf("attribute: MIME type %q is invalid: %w", mimeType, ErrInvalid)
+		}
+	}
+
+	if mimeType != a.mimeType {
+		a.mimeType = mimeType
+		a.touch()
+	}
+	return nil
+}
+
+// SetMode sets the permission bits and the setuid, setgid and sticky bits of the entry, as for Chmod. An error is
+// returned if the type bits of mode do not match the type of the entry, since the type of an entry can not be changed.
+func (a *Attribute) SetMode(mode gofs.FileMode) error {
+	if mode.Type() != a.mode.Type() {
+		return fmt.Errorf("attribute: mode %s does not match entry type %s: %w", mode, a.mode.Type(), ErrInvalid)
+	}
+
+	if mode != a.mode {
+		a.mode = mode
+		a.touch()
+	}
+	return nil
+}
+
+// SetOwner sets the name of the user that owns the entry. An empty name indicates that the owner is not known. An
+// error is returned if owner contains whitespace, control characters, a colon or a slash.
+func (a *Attribute) SetOwner(owner string) error {
+	if !validPrincipal(owner) {
+		return fmt.Errorf("attribute: owner %q is invalid: %w", owner, ErrInvalid)
+	}
+
+	if owner != a.owner {
+		a.owner = owner
+		a.touch()
+	}
+	return nil
+}
+
+// SetUID sets the user id of the entry. An error is returned if uid can not be represented by the Attribute.
+func (a *Attribute) SetUID(uid uint32) error {
+	if uid > math.MaxInt32 {
+		return fmt.Errorf("attribute: uid %d is out of range: %w", uid, ErrInvalid)
+	}
+
+	if int32(uid) != a.uid {
+		a.uid = int32(uid)
+		a.touch()
+	}
+	return nil
+}
+
+// SetMetadata sets the user-defined metadata key of the entry to value. If value is empty, the key is removed.
+func (a *Attribute) SetMetadata(key string, value string) {
+	if value == "" {
+		delete(a.metadata, key)
+		return
+	}
+
+	if a.metadata == nil {
+		a.metadata = make(map[string]string)
+	}
+	a.metadata[key] = value
+}
+
+// SetChecksum sets the digest of the content of the entry computed using the named algorithm. If digest is empty, the
+// digest for the algorithm is removed.
+func (a *Attribute) SetChecksum(algorithm string, digest string) {
+	algorithm = strings.ToLower(algorithm)
+	if digest == "" {
+		delete(a.checksums, algorithm)
+		return
+	}
+
+	if a.checksums == nil {
+		a.checksums = make(map[string]string)
+	}
+	a.checksums[algorithm] = digest
+}
+
 // Copy returns a copy of the Attribute.
 func (a *Attribute) Copy() *Attribute {
 	return &Attribute{
-		ctime:    a.Ctime(),
-		gid:      a.GID(),
-		group:    a.Group(),
-		inode:    a.Inode(),
-		mimeType: a.MimeType(),
-		mode:     a.Mode(),
-		mtime:    a.Mtime(),
-		owner:    a.Owner(),
-		size:     a.Size(),
-		uid:      a.UID(),
+		atime:      a.Atime(),
+		blocks:     a.Blocks(),
+		blockSize:  a.BlockSize(),
+		btime:      a.Btime(),
+		checksums:  a.Checksums(),
+		ctime:      a.Ctime(),
+		generation: a.Generation(),
+		gid:        a.GID(),
+		group:      a.Group(),
+		inode:      a.Inode(),
+		linkTarget: a.LinkTarget(),
+		metadata:   a.Metadata(),
+		mimeType:   a.MimeType(),
+		mode:       a.Mode(),
+		mtime:      a.Mtime(),
+		nlink:      a.Nlink(),
+		owner:      a.Owner(),
+		rdev:       a.Rdev(),
+		size:       a.Size(),
+		uid:        a.UID(),
 	}
 }
 
+// MarshalJSON returns the JSON encoding of the Attribute. Unlike String, which is intended for display, every property
+// is encoded so that the Attribute can be reconstructed exactly using UnmarshalJSON.
+func (a *Attribute) MarshalJSON() ([]byte, error) {
+	return json.Marshal(attributeJSON{
+		Atime:      a.atime,
+		Blocks:     a.blocks,
+		BlockSize:  a.blockSize,
+		Btime:      a.btime,
+		Checksums:  a.checksums,
+		Ctime:      a.ctime,
+		Generation: a.generation,
+		GID:        a.gid,
+		Group:      a.group,
+		Inode:      a.inode,
+		LinkTarget: a.linkTarget,
+		Metadata:   a.metadata,
+		MimeType:   a.mimeType,
+		Mode:       uint32(a.mode),
+		Mtime:      a.mtime,
+		Nlink:      a.nlink,
+		Owner:      a.owner,
+		Rdev:       a.rdev,
+		Size:       a.size,
+		UID:        a.uid,
+	})
+}
+
+// UnmarshalJSON sets the Attribute to the properties decoded from the JSON encoding produced by MarshalJSON.
+func (a *Attribute) UnmarshalJSON(b []byte) error {
+	var v attributeJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("attribute: %w", err)
+	}
+
+	*a = Attribute{
+		atime:      utcTime(v.Atime),
+		blocks:     v.Blocks,
+		blockSize:  v.BlockSize,
+		btime:      utcTime(v.Btime),
+		checksums:  v.Checksums,
+		ctime:      utcTime(v.Ctime),
+		generation: v.Generation,
+		gid:        v.GID,
+		group:      v.Group,
+		inode:      v.Inode,
+		linkTarget: v.LinkTarget,
+		metadata:   v.Metadata,
+		mimeType:   v.MimeType,
+		mode:       gofs.FileMode(v.Mode),
+		mtime:      utcTime(v.Mtime),
+		nlink:      v.Nlink,
+		owner:      v.Owner,
+		rdev:       v.Rdev,
+		size:       v.Size,
+		uid:        v.UID,
+	}
+	return nil
+}
+
 // ToMap returns a map representation of the Attribute properties.
 func (a *Attribute) ToMap() (map[string]any, error) {
 	var m map[string]any
@@ -120,19 +416,66 @@ func (a *Attribute) ToMap() (map[string]any, error) {
 // String returns a string representation of the Attribute properties.
 func (a *Attribute) String() string {
 	s := make(map[string]any)
+	s["atime"] = a.Atime()
+	s["blocks"] = a.Blocks()
+	s["block_size"] = a.BlockSize()
+	s["btime"] = a.Btime()
+	s["checksums"] = a.Checksums()
 	s["ctime"] = a.Ctime()
+	s["generation"] = a.Generation()
 	s["gid"] = a.GID()
 	s["group"] = a.Group()
 	s["inode"] = a.Inode()
+	s["link_target"] = a.LinkTarget()
+	s["metadata"] = a.Metadata()
 	s["mime_type"] = a.MimeType()
 	s["mode"] = a.Mode()
 	s["mtime"] = a.Mtime()
+	s["nlink"] = a.Nlink()
 	s["owner"] = a.Owner()
+	s["rdev"] = a.Rdev()
 	s["size"] = a.Size()
 	s["uid"] = a.UID()
 	return string(anchor.ToJSONFormatted(s))
 }
 
+// WithAtime sets the time the entry was last accessed.
+func WithAtime(atime time.Time) func(*Attribute) {
+	return func(a *Attribute) {
+		a.atime = atime.UTC()
+	}
+}
+
+// WithBlocks sets the number of 512-byte blocks allocated for the entry.
+func WithBlocks(blocks uint64) func(*Attribute) {
+	return func(a *Attribute) {
+		a.blocks = int64(blocks)
+	}
+}
+
+// WithBlockSize sets the preferred block size in bytes for I/O on the entry.
+func WithBlockSize(size uint64) func(*Attribute) {
+	return func(a *Attribute) {
+		a.blockSize = int64(size)
+	}
+}
+
+// WithBtime sets the time the entry was created (birth time).
+func WithBtime(btime time.Time) func(*Attribute) {
+	return func(a *Attribute) {
+		a.btime = btime.UTC()
+	}
+}
+
+// WithChecksum sets the digest of the content of the entry computed using the named algorithm, such as one returned
+// by ChecksumAlgorithm, or a provider-specific algorithm such as "etag" or "crc32c". Algorithm names are
+// case-insensitive.
+func WithChecksum(algorithm string, digest string) func(*Attribute) {
+	return func(a *Attribute) {
+		a.SetChecksum(algorithm, digest)
+	}
+}
+
 // WithCtime ...
 func WithCtime(ctime time.Time) func(*Attribute) {
 	return func(a *Attribute) {
@@ -140,6 +483,13 @@ func WithCtime(ctime time.Time) func(*Attribute) {
 	}
 }
 
+// WithGeneration sets the generation of the content of the entry.
+func WithGeneration(generation uint64) func(*Attribute) {
+	return func(a *Attribute) {
+		a.generation = int64(generation)
+	}
+}
+
 // WithGID ...
 func WithGID(gid uint32) func(*Attribute) {
 	return func(attrs *Attribute) {
@@ -161,6 +511,24 @@ func WithInode(inode uint64) func(*Attribute) {
 	}
 }
 
+// WithLinkTarget sets the destination of the entry if it is a symbolic link.
+func WithLinkTarget(target string) func(*Attribute) {
+	return func(a *Attribute) {
+		a.linkTarget = target
+	}
+}
+
+// WithMetadata sets the user-defined metadata of the entry, replacing any existing metadata. Keys with empty values
+// are ignored.
+func WithMetadata(metadata map[string]string) func(*Attribute) {
+	return func(a *Attribute) {
+		a.metadata = nil
+		for k, v := range metadata {
+			a.SetMetadata(k, v)
+		}
+	}
+}
+
 // WithMimeType ...
 func WithMimeType(mimeType string) func(*Attribute) {
 	return func(a *Attribute) {
@@ -182,6 +550,13 @@ func WithMtime(mtime time.Time) func(*Attribute) {
 	}
 }
 
+// WithNlink sets the number of hard links to the entry.
+func WithNlink(nlink uint64) func(*Attribute) {
+	return func(a *Attribute) {
+		a.nlink = int64(nlink)
+	}
+}
+
 // WithOwner ...
 func WithOwner(owner string) func(*Attribute) {
 	return func(a *Attribute) {
@@ -189,6 +564,13 @@ func WithOwner(owner string) func(*Attribute) {
 	}
 }
 
+// WithRdev sets the device number of the entry if it is a device file.
+func WithRdev(rdev uint64) func(*Attribute) {
+	return func(a *Attribute) {
+		a.rdev = int64(rdev)
+	}
+}
+
 // WithSize ...
 func WithSize(size uint64) func(*Attribute) {
 	return func(a *Attribute) {
@@ -202,3 +584,38 @@ func WithUID(uid uint32) func(*Attribute) {
 		a.uid = int32(uid)
 	}
 }
+
+// ChecksumAlgorithm returns the name of the checksum algorithm for hash used by Attribute (e.g. "sha256" for
+// crypto.SHA256).
+func ChecksumAlgorithm(hash crypto.Hash) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "/", "").Replace(hash.String()))
+}
+
+// touch records a change to the Attribute made by one of its setters by updating the modification time. Since the
+// modification time of an entry can not occur before its creation time, the creation time is set if it is not known.
+func (a *Attribute) touch() {
+	now := time.Now().UTC()
+	if a.ctime.IsZero() {
+		a.ctime = now
+	}
+
+	if now.After(a.mtime) {
+		a.mtime = now
+	}
+}
+
+// validPrincipal reports whether name is valid as the name of the owner or group of an entry.
+func validPrincipal(name string) bool {
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return r == ':' || r == '/' || unicode.IsSpace(r) || unicode.IsControl(r)
+	})
+}
+
+// utcTime returns t in UTC, or the zero time if t is zero, so that decoded times are equal to the times they were
+// encoded from.
+func utcTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Time{}
+	}
+	return t.UTC()
+}
@@ -0,0 +1,216 @@
+package fs_test
+
+import (
+	"crypto"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestAttributeLink(t *testing.T) {
+	now := time.Now()
+	attrs, err := fs.NewAttributes(
+		fs.WithBlocks(8),
+		fs.WithBlockSize(4096),
+		fs.WithCtime(now),
+		fs.WithLinkTarget("doc/fox.txt"),
+		fs.WithMode(uint32(gofs.ModeSymlink|0777)),
+		fs.WithMtime(now),
+		fs.WithNlink(2),
+		fs.WithRdev(259))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range []*fs.Attribute{attrs, attrs.Copy()} {
+		assert.Equal(t, int64(8), a.Blocks())
+		assert.Equal(t, int64(4096), a.BlockSize())
+		assert.Equal(t, "doc/fox.txt", a.LinkTarget())
+		assert.Equal(t, int64(2), a.Nlink())
+		assert.Equal(t, int64(259), a.Rdev())
+	}
+
+	m, err := attrs.ToMap()
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", m["link_target"])
+	assert.EqualValues(t, 2, m["nlink"])
+	assert.EqualValues(t, 259, m["rdev"])
+	assert.EqualValues(t, 8, m["blocks"])
+	assert.EqualValues(t, 4096, m["block_size"])
+}
+
+func TestAttributeChecksum(t *testing.T) {
+	attrs, err := fs.NewAttributes(fs.WithChecksum("ETag", "9b2cf535f27731c974343645a3985328"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, ok := attrs.Checksum("etag")
+	assert.True(t, ok)
+	assert.Equal(t, "9b2cf535f27731c974343645a3985328", digest)
+
+	attrs.SetChecksum(fs.ChecksumAlgorithm(crypto.SHA256), "abc")
+	assert.Equal(t, map[string]string{"etag": "9b2cf535f27731c974343645a3985328", "sha256": "abc"}, attrs.Copy().Checksums())
+
+	attrs.SetChecksum("etag", "")
+	_, ok = attrs.Checksum("etag")
+	assert.False(t, ok)
+
+	m, err := attrs.ToMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"sha256": "abc"}, m["checksums"])
+
+	assert.Equal(t, "sha512256", fs.ChecksumAlgorithm(crypto.SHA512_256))
+	assert.Equal(t, "md5", fs.ChecksumAlgorithm(crypto.MD5))
+}
+
+func TestAttributeMetadata(t *testing.T) {
+	attrs, err := fs.NewAttributes(fs.WithMetadata(map[string]string{"color": "brown", "empty": ""}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"color": "brown"}, attrs.Metadata())
+
+	attrs.SetMetadata("animal", "fox")
+	c := attrs.Copy()
+	attrs.SetMetadata("color", "")
+
+	v, ok := c.MetadataValue("color")
+	assert.True(t, ok)
+	assert.Equal(t, "brown", v)
+
+	_, ok = attrs.MetadataValue("color")
+	assert.False(t, ok)
+
+	m, err := c.ToMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"animal": "fox", "color": "brown"}, m["metadata"])
+}
+
+func TestAttributeTimesOSFS(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "fox.txt")
+	if err := os.WriteFile(name, []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	atime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, atime, atime); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := osfs.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "fox.txt", fi.Name())
+
+	e, ok := fi.(*fs.Entry)
+	if assert.True(t, ok) && runtime.GOOS == "linux" {
+		assert.True(t, atime.Equal(e.Attributes().Atime()))
+	}
+}
+
+func TestAttributeJSON(t *testing.T) {
+	now := time.Now()
+	attrs, err := fs.NewAttributes(
+		fs.WithAtime(now),
+		fs.WithBlocks(8),
+		fs.WithBlockSize(4096),
+		fs.WithBtime(now.Add(-time.Hour)),
+		fs.WithChecksum("sha256", "abc"),
+		fs.WithCtime(now.Add(-time.Hour)),
+		fs.WithGID(100),
+		fs.WithGroup("users"),
+		fs.WithInode(42),
+		fs.WithLinkTarget("doc/fox.txt"),
+		fs.WithMetadata(map[string]string{"color": "brown"}),
+		fs.WithMimeType("text/plain"),
+		fs.WithMode(uint32(gofs.ModeSymlink|0777)),
+		fs.WithMtime(now),
+		fs.WithNlink(1),
+		fs.WithOwner("fox"),
+		fs.WithRdev(7),
+		fs.WithSize(19),
+		fs.WithUID(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := fs.NewEntry("doc/link", fs.WithAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(entry)
+	assert.NoError(t, err)
+
+	var decoded fs.Entry
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, entry.Path(), decoded.Path())
+	assert.Equal(t, entry.Attributes(), decoded.Attributes())
+
+	var empty fs.Attribute
+	assert.NoError(t, json.Unmarshal([]byte(`{}`), &empty))
+	assert.True(t, empty.Ctime().IsZero())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"path":"/abs"}`), &decoded))
+}
+
+func TestAttributeSetters(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	attrs, err := fs.NewAttributes(fs.WithCtime(created), fs.WithMtime(created), fs.WithMode(0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, attrs.SetOwner("fox"))
+	assert.NoError(t, attrs.SetGroup("animals"))
+	assert.NoError(t, attrs.SetUID(1000))
+	assert.NoError(t, attrs.SetGID(1001))
+	assert.NoError(t, attrs.SetMode(0600|gofs.ModeSticky))
+	assert.NoError(t, attrs.SetMimeType("text/plain; charset=utf-8"))
+
+	assert.Equal(t, "fox", attrs.Owner())
+	assert.Equal(t, "animals", attrs.Group())
+	assert.Equal(t, int32(1000), attrs.UID())
+	assert.Equal(t, int32(1001), attrs.GID())
+	assert.Equal(t, 0600|gofs.ModeSticky, attrs.Mode())
+	assert.Equal(t, "text/plain; charset=utf-8", attrs.MimeType())
+	assert.True(t, attrs.Ctime().Equal(created.UTC()))
+	assert.True(t, attrs.Mtime().After(created))
+
+	mtime := attrs.Mtime()
+	assert.NoError(t, attrs.SetOwner("fox"))
+	assert.Equal(t, mtime, attrs.Mtime())
+
+	for _, err := range []error{
+		attrs.SetOwner("quick fox"),
+		attrs.SetGroup("brown:fox"),
+		attrs.SetUID(1 << 31),
+		attrs.SetGID(1 << 31),
+		attrs.SetMode(gofs.ModeDir | 0755),
+		attrs.SetMimeType("text/"),
+	} {
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	}
+	assert.Equal(t, "fox", attrs.Owner())
+	assert.Equal(t, 0600|gofs.ModeSticky, attrs.Mode())
+	assert.Equal(t, mtime, attrs.Mtime())
+
+	empty := &fs.Attribute{}
+	assert.NoError(t, empty.SetMode(0755))
+	assert.False(t, empty.Ctime().IsZero())
+	assert.False(t, empty.Mtime().Before(empty.Ctime()))
+}
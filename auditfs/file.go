@@ -0,0 +1,69 @@
+package auditfs
+
+import (
+	"io"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file audits the operations that read, write, or close a File opened through an AuditFS.
+type file struct {
+	fs.File
+	afs  *AuditFS
+	name string
+}
+
+func (f *file) Close() error {
+	start := time.Now()
+	err := f.File.Close()
+	f.afs.emit(start, Record{Op: "file.close", Path: f.name, Err: err})
+	return err
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := f.File.Read(b)
+	f.afs.emit(start, Record{Op: "file.read", Path: f.name, Bytes: int64(n), Err: ignoreEOF(err)})
+	return n, err
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	start := time.Now()
+	n, err := f.File.ReadAt(b, off)
+	f.afs.emit(start, Record{Op: "file.readAt", Path: f.name, Bytes: int64(n), Err: ignoreEOF(err)})
+	return n, err
+}
+
+func (f *file) ReadDir(n int) ([]gofs.DirEntry, error) {
+	start := time.Now()
+	entries, err := f.File.ReadDir(n)
+	f.afs.emit(start, Record{Op: "file.readDir", Path: f.name, Err: ignoreEOF(err)})
+	return entries, err
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	start := time.Now()
+	n, err := f.File.ReadFrom(r)
+	f.afs.emit(start, Record{Op: "file.readFrom", Path: f.name, Bytes: n, Err: err})
+	return n, err
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	start := time.Now()
+	n, err := f.File.Write(b)
+	f.afs.emit(start, Record{Op: "file.write", Path: f.name, Bytes: int64(n), Err: err})
+	return n, err
+}
+
+// ignoreEOF returns nil if err is io.EOF, since reaching the end of a file or directory is not a failure.
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package auditfs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.FS = (*AuditFS)(nil)
+
+// AuditFS auditing provider that implements fs.FS.
+//
+// An AuditFS passes every operation through to a backing file system and emits a Record describing the operation to a
+// Sink once it completes. Operations performed on a File opened through an AuditFS that read, write, or close the
+// file are audited as well.
+type AuditFS struct {
+	backing   fs.FS
+	logger    fs.Logger
+	principal string
+	sink      Sink
+}
+
+// New creates a new AuditFS that audits the operations performed on backing. If sink is nil, records are written to
+// the Logger set using WithLogger by a LogSink.
+func New(backing fs.FS, sink Sink, options ...func(*AuditFS)) (*AuditFS, error) {
+	if backing == nil {
+		return nil, errors.New("auditfs: backing file system is required")
+	}
+
+	a := &AuditFS{backing: backing, logger: fs.NopLogger(), sink: sink}
+	for _, opt := range options {
+		opt(a)
+	}
+
+	if a.sink == nil {
+		a.sink = LogSink(a.logger)
+	}
+	return a, nil
+}
+
+// As returns a view of the AuditFS that attributes operations to principal. The returned AuditFS shares the backing
+// file system and Sink of the receiver.
+func (a *AuditFS) As(principal string) *AuditFS {
+	return &AuditFS{backing: a.backing, logger: a.logger, principal: principal, sink: a.sink}
+}
+
+// Close ...
+func (a *AuditFS) Close() error {
+	start := time.Now()
+	err := a.backing.Close()
+	a.emit(start, Record{Op: "close", Err: err})
+	return err
+}
+
+// Create ...
+func (a *AuditFS) Create(name string) (fs.File, error) {
+	start := time.Now()
+	f, err := a.backing.Create(name)
+	a.emit(start, Record{Op: "create", Path: name, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return a.file(f, name), nil
+}
+
+// Glob ...
+func (a *AuditFS) Glob(pattern string) ([]string, error) {
+	start := time.Now()
+	matches, err := a.backing.Glob(pattern)
+	a.emit(start, Record{Op: "glob", Path: pattern, Err: err})
+	return matches, err
+}
+
+// Mkdir ...
+func (a *AuditFS) Mkdir(name string, perm gofs.FileMode) error {
+	start := time.Now()
+	err := a.backing.Mkdir(name, perm)
+	a.emit(start, Record{Op: "mkdir", Path: name, Mode: perm, Err: err})
+	return err
+}
+
+// MkdirAll ...
+func (a *AuditFS) MkdirAll(path string, perm gofs.FileMode) error {
+	start := time.Now()
+	err := a.backing.MkdirAll(path, perm)
+	a.emit(start, Record{Op: "mkdirAll", Path: path, Mode: perm, Err: err})
+	return err
+}
+
+// Open ...
+func (a *AuditFS) Open(name string) (gofs.File, error) {
+	start := time.Now()
+	f, err := a.backing.Open(name)
+	a.emit(start, Record{Op: "open", Path: name, Err: err})
+	if err != nil {
+		return nil, err
+	}
+
+	if file, ok := f.(fs.File); ok {
+		return a.file(file, name), nil
+	}
+	return f, nil
+}
+
+// OpenFile ...
+func (a *AuditFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	start := time.Now()
+	f, err := a.backing.OpenFile(name, flag, perm)
+	a.emit(start, Record{Op: "openFile", Path: name, Flag: flag, Mode: perm, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return a.file(f, name), nil
+}
+
+// PathSeparator ...
+func (a *AuditFS) PathSeparator() string {
+	return a.backing.PathSeparator()
+}
+
+// Principal returns the principal operations performed through the AuditFS are attributed to.
+func (a *AuditFS) Principal() string {
+	return a.principal
+}
+
+// Provider ...
+func (a *AuditFS) Provider() string {
+	return a.backing.Provider()
+}
+
+// ReadDir ...
+func (a *AuditFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	start := time.Now()
+	entries, err := a.backing.ReadDir(name)
+	a.emit(start, Record{Op: "readDir", Path: name, Err: err})
+	return entries, err
+}
+
+// ReadFile ...
+func (a *AuditFS) ReadFile(name string) ([]byte, error) {
+	start := time.Now()
+	b, err := a.backing.ReadFile(name)
+	a.emit(start, Record{Op: "readFile", Path: name, Bytes: int64(len(b)), Err: err})
+	return b, err
+}
+
+// Remove ...
+func (a *AuditFS) Remove(name string) error {
+	start := time.Now()
+	err := a.backing.Remove(name)
+	a.emit(start, Record{Op: "remove", Path: name, Err: err})
+	return err
+}
+
+// RemoveAll ...
+func (a *AuditFS) RemoveAll(path string) error {
+	start := time.Now()
+	err := a.backing.RemoveAll(path)
+	a.emit(start, Record{Op: "removeAll", Path: path, Err: err})
+	return err
+}
+
+// Rename ...
+func (a *AuditFS) Rename(oldpath string, newpath string) error {
+	start := time.Now()
+	err := a.backing.Rename(oldpath, newpath)
+	a.emit(start, Record{Op: "rename", Path: oldpath, NewPath: newpath, Err: err})
+	return err
+}
+
+// Root ...
+func (a *AuditFS) Root() (string, error) {
+	return a.backing.Root()
+}
+
+// Stat ...
+func (a *AuditFS) Stat(name string) (gofs.FileInfo, error) {
+	start := time.Now()
+	fi, err := a.backing.Stat(name)
+	a.emit(start, Record{Op: "stat", Path: name, Err: err})
+	return fi, err
+}
+
+// Sub returns the sub-tree for dir from the backing file system. If the sub-tree implements fs.FS, it is audited
+// using the same Sink and principal as the AuditFS.
+func (a *AuditFS) Sub(dir string) (gofs.FS, error) {
+	start := time.Now()
+	sub, err := a.backing.Sub(dir)
+	a.emit(start, Record{Op: "sub", Path: dir, Err: err})
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		return &AuditFS{backing: fsys, logger: a.logger, principal: a.principal, sink: a.sink}, nil
+	}
+	return sub, nil
+}
+
+// WriteFile ...
+func (a *AuditFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	start := time.Now()
+	err := a.backing.WriteFile(name, data, perm)
+
+	r := Record{Op: "writeFile", Path: name, Mode: perm, Err: err}
+	if err == nil {
+		r.Bytes = int64(len(data))
+	}
+	a.emit(start, r)
+	return err
+}
+
+func (a *AuditFS) emit(start time.Time, r Record) {
+	r.Time = start
+	r.Duration = time.Since(start)
+	r.Principal = a.principal
+	a.sink.Audit(r)
+}
+
+func (a *AuditFS) file(f fs.File, name string) *file {
+	return &file{File: f, afs: a, name: name}
+}
+
+// WithLogger sets the Logger that records are written to if an AuditFS is created without a Sink. By default, nothing
+// is logged.
+func WithLogger(logger fs.Logger) func(*AuditFS) {
+	return func(a *AuditFS) {
+		if logger != nil {
+			a.logger = logger
+		}
+	}
+}
+
+// WithPrincipal sets the principal operations performed through an AuditFS are attributed to.
+func WithPrincipal(principal string) func(*AuditFS) {
+	return func(a *AuditFS) {
+		a.principal = principal
+	}
+}
@@ -0,0 +1,173 @@
+package auditfs
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// recorder collects the audit records emitted by an AuditFS.
+type recorder struct {
+	mutex   sync.Mutex
+	records []Record
+}
+
+func (r *recorder) Audit(record Record) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records = append(r.records, record)
+}
+
+func (r *recorder) ops() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var ops []string
+	for _, rec := range r.records {
+		ops = append(ops, rec.Op)
+	}
+	return ops
+}
+
+func (r *recorder) last() Record {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.records[len(r.records)-1]
+}
+
+func (r *recorder) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records = nil
+}
+
+// AuditFSTestSuite ...
+type AuditFSTestSuite struct {
+	suite.Suite
+	afs  *AuditFS
+	sink *recorder
+}
+
+func NewAuditFSTestSuite() *AuditFSTestSuite {
+	return &AuditFSTestSuite{}
+}
+
+func (t *AuditFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.sink = &recorder{}
+	afs, err := New(backing, t.sink, WithPrincipal("alice"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.afs = afs
+}
+
+func TestAuditFSTestSuite(t *testing.T) {
+	suite.Run(t, NewAuditFSTestSuite())
+}
+
+func (t *AuditFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.afs, "doc/fox.txt"))
+	assert.NotEmpty(t.T(), t.sink.ops())
+}
+
+func (t *AuditFSTestSuite) TestRecord() {
+	assert.NoError(t.T(), t.afs.WriteFile("doc/cat.txt", []byte("the cat"), 0600))
+
+	r := t.sink.last()
+	assert.Equal(t.T(), "writeFile", r.Op)
+	assert.Equal(t.T(), "doc/cat.txt", r.Path)
+	assert.Equal(t.T(), int64(7), r.Bytes)
+	assert.Equal(t.T(), gofs.FileMode(0600), r.Mode)
+	assert.Equal(t.T(), "alice", r.Principal)
+	assert.NoError(t.T(), r.Err)
+	assert.False(t.T(), r.Time.IsZero())
+
+	_, err := t.afs.As("bob").Stat("doc/missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	r = t.sink.last()
+	assert.Equal(t.T(), "stat", r.Op)
+	assert.Equal(t.T(), "bob", r.Principal)
+	assert.ErrorIs(t.T(), r.Err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.afs.Rename("doc/cat.txt", "doc/kitten.txt"))
+
+	r = t.sink.last()
+	assert.Equal(t.T(), "rename", r.Op)
+	assert.Equal(t.T(), "doc/cat.txt", r.Path)
+	assert.Equal(t.T(), "doc/kitten.txt", r.NewPath)
+}
+
+func (t *AuditFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	afs, err := New(t.afs.backing, nil, WithLogger(logger), WithPrincipal("alice"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = afs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `level=DEBUG msg="[auditfs] readFile" op=readFile path=doc/fox.txt bytes=19`)
+
+	_, err = afs.Stat("doc/missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	assert.Contains(t.T(), buf.String(), `level=WARN msg="[auditfs] stat" op=stat path=doc/missing.txt`)
+	assert.Contains(t.T(), buf.String(), `principal=alice error=`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.afs.logger)
+}
+
+func (t *AuditFSTestSuite) TestFile() {
+	f, err := t.afs.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b, err := io.ReadAll(f)
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	var read int64
+	for _, r := range t.sink.records {
+		if r.Op == "file.read" {
+			read += r.Bytes
+			assert.NoError(t.T(), r.Err)
+		}
+	}
+	assert.Equal(t.T(), int64(len(b)), read)
+	assert.Equal(t.T(), "file.close", t.sink.last().Op)
+
+	t.sink.reset()
+
+	w, err := t.afs.Create("doc/cat.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = io.WriteString(w, "the cat")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), w.Close())
+	assert.Equal(t.T(), []string{"create", "file.write", "file.close"}, t.sink.ops())
+	assert.Equal(t.T(), int64(7), t.sink.records[1].Bytes)
+}
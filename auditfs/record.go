@@ -0,0 +1,97 @@
+package auditfs
+
+import (
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Record defines the structured audit record emitted for a single file system operation.
+type Record struct {
+	// Time is when the operation started.
+	Time time.Time
+
+	// Op is the name of the operation (e.g. "open", "write", "rename").
+	Op string
+
+	// Path is the path the operation was performed on.
+	Path string
+
+	// NewPath is the destination path for rename operations.
+	NewPath string
+
+	// Flag contains the flags passed to operations that open a file.
+	Flag int
+
+	// Mode contains the permission bits passed to operations that create a file or directory.
+	Mode gofs.FileMode
+
+	// Bytes is the number of bytes read or written by the operation.
+	Bytes int64
+
+	// Duration is the time taken to perform the operation.
+	Duration time.Duration
+
+	// Err is the error returned by the operation, if any.
+	Err error
+
+	// Principal identifies the caller that performed the operation.
+	Principal string
+}
+
+// Sink defines the behavior for receiving audit records.
+//
+// Audit is called synchronously after each operation completes, so implementations that perform expensive work should
+// hand records off to another goroutine.
+type Sink interface {
+	Audit(record Record)
+}
+
+// SinkFunc is an adapter that allows an ordinary function to be used as a Sink.
+type SinkFunc func(record Record)
+
+// Audit calls f(record).
+func (f SinkFunc) Audit(record Record) {
+	f(record)
+}
+
+// LogSink returns a Sink that writes audit records to logger. Records for successful operations are logged at the debug
+// level, and records for failed operations are logged at the warn level. If logger is nil, nothing is logged.
+func LogSink(logger fs.Logger) Sink {
+	if logger == nil {
+		logger = fs.NopLogger()
+	}
+
+	return SinkFunc(func(r Record) {
+		args := []any{
+			"op", r.Op,
+			"path", r.Path,
+			"bytes", r.Bytes,
+			"duration", r.Duration,
+		}
+
+		if r.NewPath != "" {
+			args = append(args, "new_path", r.NewPath)
+		}
+
+		if r.Flag != 0 {
+			args = append(args, "flag", r.Flag)
+		}
+
+		if r.Mode != 0 {
+			args = append(args, "mode", r.Mode.String())
+		}
+
+		if r.Principal != "" {
+			args = append(args, "principal", r.Principal)
+		}
+
+		if r.Err != nil {
+			logger.Warn("[auditfs] "+r.Op, append(args, "error", r.Err)...)
+			return
+		}
+		logger.Debug("[auditfs] "+r.Op, args...)
+	})
+}
@@ -0,0 +1,438 @@
+// Package backup provides full and incremental backups of any file system, stored as snapshots on another file system,
+// which can be restored to any backup point.
+//
+// Each backup point is stored in a directory named by its ID, holding a snapshot written by fs.Export with the entries
+// that changed since the previous point, and a manifest of every entry at the time of the backup:
+//
+//	b, err := backup.New(store, backup.WithDir("backups"))
+//	full, err := b.Full(src)
+//	point, err := b.Incremental(src)
+//	err = b.Restore(dst, full.ID)
+//
+// Changes are detected by comparing the fs.MerkleTree of the source with the tree recorded by the manifest of the
+// previous point, so unchanged subtrees are skipped using their digests.
+package backup
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+	gopath "path"
+)
+
+// Names of the files stored for each backup point.
+const (
+	manifestFile = "manifest.jsonl"
+	pointFile    = "point.json"
+	snapshotFile = "snapshot"
+)
+
+// idLayout is the layout of the time used as the ID of a backup point, so that IDs sort in the order the points were
+// created.
+const idLayout = "20060102T150405.000000000Z"
+
+// Backup creates and restores backup points stored on a file system.
+type Backup struct {
+	dir    string
+	logger fs.Logger
+	perm   gofs.FileMode
+	store  fs.FS
+}
+
+// Point describes a backup point.
+type Point struct {
+	// ID identifies the backup point, and sorts in the order the points were created.
+	ID string `json:"id"`
+
+	// Parent is the ID of the point that an incremental backup is based on, which is empty for a full backup.
+	Parent string `json:"parent,omitempty"`
+
+	// Time is the time the backup was started.
+	Time time.Time `json:"time"`
+
+	// Changed lists the paths of the entries stored by the backup that were added or changed since the parent point.
+	// If an entry is a directory, its subtree is stored. Full backups store every entry, and do not list them.
+	Changed []string `json:"changed,omitempty"`
+
+	// Deleted lists the paths of the entries of the parent point that were removed or replaced.
+	Deleted []string `json:"deleted,omitempty"`
+
+	// Files is the number of regular files in the source at the time of the backup.
+	Files int `json:"files"`
+
+	// Bytes is the number of bytes of content stored by the backup.
+	Bytes int64 `json:"bytes"`
+}
+
+// Full reports whether the point is a full backup.
+func (p *Point) Full() bool {
+	return p.Parent == ""
+}
+
+// New creates a new Backup that stores backup points on store.
+func New(store fs.FS, options ...func(*Backup)) (*Backup, error) {
+	if store == nil {
+		return nil, errors.New("backup: store file system is required")
+	}
+
+	b := &Backup{dir: ".", logger: fs.NopLogger(), perm: 0755, store: store}
+	for _, opt := range options {
+		opt(b)
+	}
+
+	b.dir = gopath.Clean(strings.TrimSpace(b.dir))
+	if !gofs.ValidPath(b.dir) {
+		return nil, fmt.Errorf("backup: directory %q is invalid: %w", b.dir, fs.ErrInvalid)
+	}
+
+	b.logger.Debug("[backup] new", "provider", store.Provider(), "dir", b.dir)
+	return b, nil
+}
+
+// Full creates a full backup of src, which stores every entry in src.
+func (b *Backup) Full(src gofs.FS) (*Point, error) {
+	if src == nil {
+		return nil, errors.New("backup: source file system is required")
+	}
+
+	tree, err := fs.MerkleTree(src, ".")
+	if err != nil {
+		return nil, err
+	}
+	return b.create(src, tree, &Point{}, src)
+}
+
+// Incremental creates an incremental backup of src based on the latest backup point, which only stores the entries
+// that changed since that point. If there is no backup point, a full backup is created.
+func (b *Backup) Incremental(src gofs.FS) (*Point, error) {
+	if src == nil {
+		return nil, errors.New("backup: source file system is required")
+	}
+
+	points, err := b.Points()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(points) == 0 {
+		return b.Full(src)
+	}
+	parent := points[len(points)-1]
+
+	prev, err := b.state(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := fs.MerkleTree(src, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	prevTree, err := fs.MerkleTree(prev, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := fs.MerkleDiff(tree, prevTree)
+	if err != nil {
+		return nil, err
+	}
+
+	// The root can not be replaced, so if it differs from the parent point, every entry is stored.
+	if slices.Contains(paths, ".") {
+		return b.create(src, tree, &Point{}, src)
+	}
+
+	point := &Point{Parent: parent.ID}
+	for _, p := range paths {
+		if _, err := gofs.Stat(src, p); err == nil {
+			point.Changed = append(point.Changed, p)
+		} else if !errors.Is(err, gofs.ErrNotExist) {
+			return nil, err
+		}
+
+		if _, ok := prev.entries[p]; ok {
+			point.Deleted = append(point.Deleted, p)
+		}
+	}
+	return b.create(src, tree, point, &changedFS{fsys: src, paths: point.Changed})
+}
+
+// Points returns the backup points in the order they were created. Points whose backup did not complete are ignored.
+func (b *Backup) Points() ([]*Point, error) {
+	entries, err := b.store.ReadDir(b.dir)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var points []*Point
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		point, err := b.point(e.Name())
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	slices.SortFunc(points, func(a *Point, b *Point) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return points, nil
+}
+
+// Restore restores the entries of the backup point with the provided ID to dst, by importing the snapshot of the full
+// backup it is based on, followed by the snapshot of each incremental backup up to the point. Entries deleted by an
+// incremental backup are removed from dst before its snapshot is imported.
+//
+// If dst is empty, its entries match the source at the time of the backup once the restore is complete. Otherwise,
+// existing entries that are not in the backup point are left unchanged.
+func (b *Backup) Restore(dst fs.FS, id string) error {
+	if dst == nil {
+		return errors.New("backup: destination file system is required")
+	}
+
+	var chain []*Point
+	for id != "" {
+		point, err := b.point(id)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, point)
+		id = point.Parent
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		point := chain[i]
+
+		b.logger.Debug("[backup] restore",
+			"id", point.ID,
+			"full", point.Full(),
+			"deleted", len(point.Deleted))
+
+		for _, p := range point.Deleted {
+			if err := dst.RemoveAll(p); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+				return err
+			}
+		}
+
+		if err := b.restore(dst, point); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithDir sets the directory of the store file system in which backup points are stored. The default is the root of
+// the store.
+func WithDir(dir string) func(*Backup) {
+	return func(b *Backup) {
+		b.dir = dir
+	}
+}
+
+// WithLogger sets the Logger used by a Backup to log the backup points it creates and restores. By default, nothing is
+// logged.
+func WithLogger(logger fs.Logger) func(*Backup) {
+	return func(b *Backup) {
+		if logger != nil {
+			b.logger = logger
+		}
+	}
+}
+
+// WithPerm sets the permissions of the directories created for backup points. The default is 0755.
+func WithPerm(perm gofs.FileMode) func(*Backup) {
+	return func(b *Backup) {
+		b.perm = perm
+	}
+}
+
+// create stores a backup point for src, whose tree is provided, with a snapshot of the entries of content.
+func (b *Backup) create(src gofs.FS, tree *fs.MerkleNode, point *Point, content gofs.FS) (*Point, error) {
+	point.Time = time.Now().UTC()
+	point.ID = point.Time.Format(idLayout)
+
+	dir := gopath.Join(b.dir, point.ID)
+	if err := b.store.MkdirAll(dir, b.perm); err != nil {
+		return nil, err
+	}
+
+	err := b.write(gopath.Join(dir, snapshotFile), func(w io.Writer) error {
+		cw := &countingWriter{w: w}
+		if err := fs.Export(content, ".", cw); err != nil {
+			return err
+		}
+		point.Bytes = cw.n
+		return nil
+	})
+
+	if err == nil {
+		err = b.write(gopath.Join(dir, manifestFile), func(w io.Writer) error {
+			files, err := writeManifest(w, src, tree)
+			point.Files = files
+			return err
+		})
+	}
+
+	// The point is written last, so that points whose backup did not complete are ignored.
+	if err == nil {
+		err = b.write(gopath.Join(dir, pointFile), func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(point)
+		})
+	}
+
+	if err != nil {
+		_ = b.store.RemoveAll(dir)
+		return nil, err
+	}
+
+	b.logger.Debug("[backup] create",
+		"id", point.ID,
+		"parent", point.Parent,
+		"changed", len(point.Changed),
+		"deleted", len(point.Deleted),
+		"bytes", point.Bytes)
+	return point, nil
+}
+
+// point reads the backup point with the provided ID.
+func (b *Backup) point(id string) (*Point, error) {
+	if !gofs.ValidPath(id) || strings.Contains(id, "/") {
+		return nil, fmt.Errorf("backup: %w", &gofs.PathError{Op: "point", Path: id, Err: fs.ErrInvalid})
+	}
+
+	data, err := b.store.ReadFile(gopath.Join(b.dir, id, pointFile))
+	if err != nil {
+		return nil, err
+	}
+
+	point := &Point{}
+	if err := json.Unmarshal(data, point); err != nil {
+		return nil, fmt.Errorf("backup: point %s: %w", id, err)
+	}
+	return point, nil
+}
+
+// restore imports the snapshot of the backup point to dst.
+func (b *Backup) restore(dst fs.FS, point *Point) error {
+	f, err := b.store.Open(gopath.Join(b.dir, point.ID, snapshotFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return fs.Import(dst, f)
+}
+
+// state returns the file system state recorded by the manifest of the backup point with the provided ID.
+func (b *Backup) state(id string) (*stateFS, error) {
+	f, err := b.store.Open(gopath.Join(b.dir, id, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readState(f)
+}
+
+// write writes the named file to the store using fn.
+func (b *Backup) write(name string, fn func(io.Writer) error) error {
+	f, err := b.store.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	n int64
+	w io.Writer
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeManifest writes a manifest of every entry of src, including the root, to w in the format produced by
+// fs.Manifest, with the SHA-256 checksums of regular files provided by the nodes of tree, and returns the number of
+// regular files.
+func writeManifest(w io.Writer, src gofs.FS, tree *fs.MerkleNode) (int, error) {
+	var files int
+	enc := json.NewEncoder(w)
+	err := fs.Walk(src, ".", func(p string, entry *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		e, err := fs.EntryFromFileInfo(p, entry)
+		if err != nil {
+			return err
+		}
+
+		if e.Mode().IsRegular() {
+			n, err := node(tree, p)
+			if err != nil {
+				return err
+			}
+
+			digest, err := n.Digest()
+			if err != nil {
+				return err
+			}
+			e.Attributes().SetChecksum(fs.ChecksumAlgorithm(crypto.SHA256), hex.EncodeToString(digest))
+			files++
+		}
+
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("backup: manifest: %w", err)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// node returns the node for the named entry in the tree rooted at root.
+func node(root *fs.MerkleNode, name string) (*fs.MerkleNode, error) {
+	n := root
+	for _, elem := range strings.Split(name, "/") {
+		c, err := n.Child(elem)
+		if err != nil {
+			return nil, err
+		}
+
+		if c == nil {
+			return nil, &gofs.PathError{Op: "digest", Path: name, Err: gofs.ErrNotExist}
+		}
+		n = c
+	}
+	return n, nil
+}
@@ -0,0 +1,192 @@
+package backup
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// BackupTestSuite ...
+type BackupTestSuite struct {
+	suite.Suite
+	backup *Backup
+	src    fs.FS
+	store  fs.FS
+}
+
+func NewBackupTestSuite() *BackupTestSuite {
+	return &BackupTestSuite{}
+}
+
+func (t *BackupTestSuite) SetupTest() {
+	src, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"doc/fox.txt":         "the quick brown fox",
+		"doc/animals/dog.txt": "the lazy dog",
+		"doc/animals/cat.txt": "the sleepy cat",
+		"readme.txt":          "animals",
+	} {
+		if err := src.WriteFile(name, []byte(content), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+
+	store, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b, err := New(store, WithDir("backups"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.backup = b
+	t.src = src
+	t.store = store
+}
+
+func TestBackupTestSuite(t *testing.T) {
+	suite.Run(t, NewBackupTestSuite())
+}
+
+func (t *BackupTestSuite) TestIncremental() {
+	full, err := t.backup.Incremental(t.src)
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), full.Full())
+	assert.Equal(t.T(), 4, full.Files)
+
+	assert.NoError(t.T(), t.src.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0644))
+	assert.NoError(t.T(), t.src.Remove("doc/animals/cat.txt"))
+	assert.NoError(t.T(), t.src.WriteFile("doc/birds/owl.txt", []byte("the wise owl"), 0644))
+
+	first, err := t.backup.Incremental(t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), full.ID, first.Parent)
+	assert.Equal(t.T(), []string{"doc/birds", "doc/fox.txt"}, first.Changed)
+	assert.Equal(t.T(), []string{"doc/animals/cat.txt", "doc/fox.txt"}, first.Deleted)
+	assert.Equal(t.T(), 4, first.Files)
+
+	// Unchanged files are not stored by an incremental backup.
+	assert.Less(t.T(), first.Bytes, full.Bytes)
+
+	assert.NoError(t.T(), t.src.RemoveAll("doc/birds"))
+	assert.NoError(t.T(), t.src.WriteFile("doc/birds", []byte("not a directory"), 0644))
+
+	second, err := t.backup.Incremental(t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), first.ID, second.Parent)
+	assert.Equal(t.T(), []string{"doc/birds"}, second.Changed)
+	assert.Equal(t.T(), []string{"doc/birds"}, second.Deleted)
+
+	unchanged, err := t.backup.Incremental(t.src)
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), unchanged.Changed)
+	assert.Empty(t.T(), unchanged.Deleted)
+
+	points, err := t.backup.Points()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{full.ID, first.ID, second.ID, unchanged.ID}, ids(points))
+
+	for id, want := range map[string]map[string]string{
+		full.ID: {
+			"doc/fox.txt":         "the quick brown fox",
+			"doc/animals/dog.txt": "the lazy dog",
+			"doc/animals/cat.txt": "the sleepy cat",
+			"readme.txt":          "animals",
+		},
+		first.ID: {
+			"doc/fox.txt":         "the quick brown fox jumps",
+			"doc/animals/dog.txt": "the lazy dog",
+			"doc/birds/owl.txt":   "the wise owl",
+			"readme.txt":          "animals",
+		},
+		unchanged.ID: {
+			"doc/fox.txt":         "the quick brown fox jumps",
+			"doc/animals/dog.txt": "the lazy dog",
+			"doc/birds":           "not a directory",
+			"readme.txt":          "animals",
+		},
+	} {
+		dst, err := memfs.New()
+		if err != nil {
+			t.T().Fatal(err)
+		}
+		assert.NoError(t.T(), t.backup.Restore(dst, id))
+
+		var files []string
+		assert.NoError(t.T(), fs.Walk(dst, ".", func(p string, e *fs.Entry, err error) error {
+			if err == nil && e.Mode().IsRegular() {
+				files = append(files, p)
+			}
+			return err
+		}))
+		assert.Len(t.T(), files, len(want), id)
+
+		for name, content := range want {
+			b, err := dst.ReadFile(name)
+			assert.NoError(t.T(), err, name)
+			assert.Equal(t.T(), content, string(b), name)
+		}
+	}
+}
+
+func (t *BackupTestSuite) TestPoints() {
+	points, err := t.backup.Points()
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), points)
+
+	full, err := t.backup.Full(t.src)
+	assert.NoError(t.T(), err)
+
+	// Points whose backup did not complete are ignored.
+	assert.NoError(t.T(), t.store.MkdirAll("backups/incomplete", 0755))
+
+	points, err = t.backup.Points()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{full.ID}, ids(points))
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.ErrorIs(t.T(), t.backup.Restore(dst, "missing"), gofs.ErrNotExist)
+	assert.ErrorIs(t.T(), t.backup.Restore(dst, "../backups"), fs.ErrInvalid)
+
+	_, err = New(nil)
+	assert.Error(t.T(), err)
+}
+
+func (t *BackupTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	b, err := New(t.store, WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	point, err := b.Full(t.src)
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[backup] create" id=`+point.ID+` parent="" changed=0`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.backup.logger)
+}
+
+func ids(points []*Point) []string {
+	var ids []string
+	for _, p := range points {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/transientvariable/fs-go"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+	gopath "path"
+)
+
+var (
+	_ gofs.ReadDirFS = (*changedFS)(nil)
+	_ gofs.ReadDirFS = (*stateFS)(nil)
+	_ gofs.StatFS    = (*changedFS)(nil)
+	_ gofs.StatFS    = (*stateFS)(nil)
+)
+
+// stateFS is a read-only file system holding the entries recorded by the manifest of a backup point, whose content is
+// not available. The digests of regular files are provided by their SHA-256 checksums, so that the tree of the backup
+// point can be compared with the source using fs.MerkleDiff.
+type stateFS struct {
+	children map[string][]gofs.DirEntry
+	entries  map[string]*fs.Entry
+}
+
+// readState reads the manifest written by writeManifest from r.
+func readState(r io.Reader) (*stateFS, error) {
+	s := &stateFS{children: make(map[string][]gofs.DirEntry), entries: make(map[string]*fs.Entry)}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("backup: manifest: %w", err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			e := &fs.Entry{}
+			if err := json.Unmarshal(line, e); err != nil {
+				return nil, fmt.Errorf("backup: manifest: %w", err)
+			}
+
+			s.entries[e.Path()] = e
+			if e.Path() != "." {
+				dir := gopath.Dir(e.Path())
+				s.children[dir] = append(s.children[dir], e)
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if _, ok := s.entries["."]; !ok {
+		return nil, fmt.Errorf("backup: manifest: root entry is missing: %w", fs.ErrInvalid)
+	}
+	return s, nil
+}
+
+// Digest returns the SHA-256 digest of the named regular file recorded by the manifest.
+func (s *stateFS) Digest(name string) ([]byte, error) {
+	e, err := s.entry("digest", name)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, ok := e.Attributes().Checksum(fs.ChecksumAlgorithm(crypto.SHA256))
+	if !ok {
+		return nil, &gofs.PathError{Op: "digest", Path: name, Err: fs.ErrInvalid}
+	}
+	return hex.DecodeString(sum)
+}
+
+// Open returns an error wrapping fs.ErrUnsupported, since the content of the entries is not available.
+func (s *stateFS) Open(name string) (gofs.File, error) {
+	return nil, &gofs.PathError{Op: "open", Path: name, Err: fs.ErrUnsupported}
+}
+
+// ReadDir returns the entries of the named directory, sorted by name.
+func (s *stateFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	e, err := s.entry("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.IsDir() {
+		return nil, &gofs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotDir}
+	}
+
+	entries := slices.Clone(s.children[name])
+	slices.SortFunc(entries, func(a gofs.DirEntry, b gofs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// Stat returns the named entry.
+func (s *stateFS) Stat(name string) (gofs.FileInfo, error) {
+	return s.entry("stat", name)
+}
+
+func (s *stateFS) entry(op string, name string) (*fs.Entry, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid}
+	}
+
+	e, ok := s.entries[name]
+	if !ok {
+		return nil, &gofs.PathError{Op: op, Path: name, Err: gofs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// changedFS is a view of a file system that only holds the entries at the provided paths, including the subtrees of
+// directories, and their parent directories, so that only the entries that changed since the previous backup point
+// are written to the snapshot of an incremental backup.
+type changedFS struct {
+	fsys  gofs.FS
+	paths []string
+}
+
+// Open opens the named file if it is included in the view.
+func (c *changedFS) Open(name string) (gofs.File, error) {
+	if !c.includes(name) {
+		return nil, &gofs.PathError{Op: "open", Path: name, Err: gofs.ErrNotExist}
+	}
+	return c.fsys.Open(name)
+}
+
+// ReadDir returns the entries of the named directory that are included in the view.
+func (c *changedFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	if !c.includes(name) {
+		return nil, &gofs.PathError{Op: "readdir", Path: name, Err: gofs.ErrNotExist}
+	}
+
+	entries, err := gofs.ReadDir(c.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(entries, func(e gofs.DirEntry) bool {
+		return !c.includes(gopath.Join(name, e.Name()))
+	}), nil
+}
+
+// Stat returns the named entry if it is included in the view.
+func (c *changedFS) Stat(name string) (gofs.FileInfo, error) {
+	if !c.includes(name) {
+		return nil, &gofs.PathError{Op: "stat", Path: name, Err: gofs.ErrNotExist}
+	}
+	return gofs.Stat(c.fsys, name)
+}
+
+// includes reports whether the named entry is one of the paths of the view, is in the subtree of one of the paths, or
+// is a parent directory of one of the paths.
+func (c *changedFS) includes(name string) bool {
+	if name == "." {
+		return true
+	}
+
+	for _, p := range c.paths {
+		if name == p || strings.HasPrefix(name, p+"/") || strings.HasPrefix(p, name+"/") {
+			return true
+		}
+	}
+	return false
+}
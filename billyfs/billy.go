@@ -0,0 +1,250 @@
+package billyfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var (
+	_ billy.Capable    = (*adapter)(nil)
+	_ billy.File       = (*adapterFile)(nil)
+	_ billy.Filesystem = (*adapter)(nil)
+)
+
+// Wrap returns a billy.Filesystem backed by fsys, so that go-git and other libraries built on go-billy can operate
+// directly on a file system provided by this package.
+//
+// Paths provided to the returned billy.Filesystem may be absolute, in which case they are interpreted relative to the
+// root of fsys, and a path that would escape the root returns an error wrapping billy.ErrCrossedBoundary. Following
+// the behavior of the go-billy OS file system, parent directories are created as needed when a file is created or
+// renamed. Symbolic links are not supported.
+//
+// Since paths are passed through to fsys, an OS file system should be rooted using fs.Chroot before it is wrapped.
+func Wrap(fsys fs.FS) billy.Filesystem {
+	if b, ok := fsys.(*BillyFS); ok {
+		return b.bfs
+	}
+	return &adapter{fsys: fsys}
+}
+
+type adapter struct {
+	fsys fs.FS
+}
+
+// Capabilities returns the capabilities supported by the billy.Filesystem. Truncation is only supported if the
+// files provided by the underlying file system implement Truncate, so it is not reported.
+func (a *adapter) Capabilities() billy.Capability {
+	return billy.DefaultCapabilities &^ billy.TruncateCapability
+}
+
+func (a *adapter) Chroot(path string) (billy.Filesystem, error) {
+	p, err := a.path("chroot", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == "." {
+		return a, nil
+	}
+
+	if err := a.fsys.MkdirAll(p, 0755); err != nil {
+		return nil, pathError("chroot", path, err)
+	}
+
+	fsys, err := fs.Chroot(a.fsys, p)
+	if err != nil {
+		return nil, pathError("chroot", path, err)
+	}
+	return &adapter{fsys: fsys}, nil
+}
+
+func (a *adapter) Create(filename string) (billy.File, error) {
+	return a.OpenFile(filename, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+func (a *adapter) Join(elem ...string) string {
+	return gopath.Join(elem...)
+}
+
+func (a *adapter) Lstat(filename string) (os.FileInfo, error) {
+	return a.Stat(filename)
+}
+
+func (a *adapter) MkdirAll(filename string, perm os.FileMode) error {
+	p, err := a.path("mkdirAll", filename)
+	if err != nil {
+		return err
+	}
+	return pathError("mkdirAll", filename, a.fsys.MkdirAll(p, perm))
+}
+
+func (a *adapter) Open(filename string) (billy.File, error) {
+	return a.OpenFile(filename, fs.O_RDONLY, 0)
+}
+
+func (a *adapter) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	p, err := a.path("openFile", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&fs.O_CREATE != 0 {
+		if err := a.mkdirParent(p); err != nil {
+			return nil, pathError("openFile", filename, err)
+		}
+	}
+
+	f, err := a.fsys.OpenFile(p, flag, perm)
+	if err != nil {
+		return nil, pathError("openFile", filename, err)
+	}
+	return &adapterFile{File: f, name: filename}, nil
+}
+
+func (a *adapter) ReadDir(path string) ([]os.FileInfo, error) {
+	p, err := a.path("readDir", path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := a.fsys.ReadDir(p)
+	if err != nil {
+		return nil, pathError("readDir", path, err)
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, pathError("readDir", path, err)
+		}
+		infos = append(infos, fi)
+	}
+	return infos, nil
+}
+
+func (a *adapter) Readlink(link string) (string, error) {
+	return "", fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "readlink", Path: link, Err: billy.ErrNotSupported})
+}
+
+func (a *adapter) Remove(filename string) error {
+	p, err := a.path("remove", filename)
+	if err != nil {
+		return err
+	}
+	return pathError("remove", filename, a.fsys.Remove(p))
+}
+
+func (a *adapter) Rename(oldpath string, newpath string) error {
+	o, err := a.path("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.path("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	if err := a.mkdirParent(n); err != nil {
+		return pathError("rename", newpath, err)
+	}
+	return pathError("rename", oldpath, a.fsys.Rename(o, n))
+}
+
+func (a *adapter) Root() string {
+	root, err := a.fsys.Root()
+	if err != nil {
+		return ""
+	}
+	return root
+}
+
+func (a *adapter) Stat(filename string) (os.FileInfo, error) {
+	p, err := a.path("stat", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := a.fsys.Stat(p)
+	if err != nil {
+		return nil, pathError("stat", filename, err)
+	}
+	return fi, nil
+}
+
+func (a *adapter) Symlink(target string, link string) error {
+	return fmt.Errorf("billyfs: %w", &os.LinkError{Op: "symlink", Old: target, New: link, Err: billy.ErrNotSupported})
+}
+
+func (a *adapter) TempFile(dir string, prefix string) (billy.File, error) {
+	return util.TempFile(a, dir, prefix)
+}
+
+func (a *adapter) mkdirParent(p string) error {
+	if dir := gopath.Dir(p); dir != "." {
+		return a.fsys.MkdirAll(dir, 0755)
+	}
+	return nil
+}
+
+// path returns the path in the underlying file system for the billy path name.
+func (a *adapter) path(op string, name string) (string, error) {
+	p := strings.TrimLeft(gopath.Clean(filepath.ToSlash(name)), "/")
+	if p == "" {
+		return ".", nil
+	}
+
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("billyfs: %w", &gofs.PathError{Op: op, Path: name, Err: billy.ErrCrossedBoundary})
+	}
+	return p, nil
+}
+
+// pathError returns an error for err that is compatible with the os.IsNotExist, os.IsExist, and os.IsPermission
+// checks used by go-billy consumers, which do not unwrap errors wrapped using fmt.Errorf.
+func pathError(op string, name string, err error) error {
+	for _, target := range []error{gofs.ErrNotExist, gofs.ErrExist, gofs.ErrPermission} {
+		if errors.Is(err, target) {
+			return &gofs.PathError{Op: op, Path: name, Err: target}
+		}
+	}
+	return err
+}
+
+// adapterFile provides access to a File as a billy.File.
+type adapterFile struct {
+	fs.File
+	name string
+}
+
+// Lock is a no-op, since files provided by this package do not support advisory locking.
+func (f *adapterFile) Lock() error {
+	return nil
+}
+
+func (f *adapterFile) Name() string {
+	return f.name
+}
+
+func (f *adapterFile) Truncate(size int64) error {
+	if t, ok := f.File.(interface{ Truncate(int64) error }); ok {
+		return t.Truncate(size)
+	}
+	return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "truncate", Path: f.name, Err: billy.ErrNotSupported})
+}
+
+// Unlock is a no-op, since files provided by this package do not support advisory locking.
+func (f *adapterFile) Unlock() error {
+	return nil
+}
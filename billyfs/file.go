@@ -0,0 +1,122 @@
+package billyfs
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var (
+	_ fs.File = (*dir)(nil)
+	_ fs.File = (*file)(nil)
+)
+
+// file provides access to a billy.File opened through a BillyFS.
+type file struct {
+	billy.File
+	fsys *BillyFS
+	name string
+}
+
+func newFile(fsys *BillyFS, name string, f billy.File) *file {
+	return &file{File: f, fsys: fsys, name: name}
+}
+
+func (f *file) ReadDir(int) ([]gofs.DirEntry, error) {
+	return nil, fmt.Errorf("billyfs_file: %w", &gofs.PathError{Op: "readDir", Path: f.name, Err: fs.ErrNotDir})
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(f.File, r)
+	if err != nil {
+		return n, fmt.Errorf("billyfs_file: %w", &gofs.PathError{Op: "readFrom", Path: f.name, Err: err})
+	}
+	return n, nil
+}
+
+func (f *file) Stat() (gofs.FileInfo, error) {
+	if s, ok := f.File.(interface{ Stat() (gofs.FileInfo, error) }); ok {
+		return s.Stat()
+	}
+	return f.fsys.bfs.Stat(f.name)
+}
+
+// dir provides read access to the entries for a directory provided by BillyFS.
+type dir struct {
+	closed  bool
+	entries []gofs.DirEntry
+	info    gofs.FileInfo
+	mutex   sync.Mutex
+	name    string
+	off     int
+}
+
+func newDir(name string, info gofs.FileInfo, entries []gofs.DirEntry) *dir {
+	return &dir{entries: entries, info: info, name: name}
+}
+
+func (d *dir) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.closed {
+		d.closed = true
+		return nil
+	}
+	return fmt.Errorf("billyfs_dir: %w", &gofs.PathError{Op: "close", Path: d.name, Err: gofs.ErrClosed})
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, d.isDir("read")
+}
+
+func (d *dir) ReadAt([]byte, int64) (int, error) {
+	return 0, d.isDir("readAt")
+}
+
+func (d *dir) ReadFrom(io.Reader) (int64, error) {
+	return 0, d.isDir("readFrom")
+}
+
+func (d *dir) ReadDir(n int) ([]gofs.DirEntry, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil, fmt.Errorf("billyfs_dir: %w", &gofs.PathError{Op: "readDir", Path: d.name, Err: gofs.ErrClosed})
+	}
+
+	remaining := d.entries[d.off:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(remaining) {
+			remaining = remaining[:n]
+		}
+	}
+	d.off += len(remaining)
+	return append([]gofs.DirEntry(nil), remaining...), nil
+}
+
+func (d *dir) Seek(int64, int) (int64, error) {
+	return 0, d.isDir("seek")
+}
+
+func (d *dir) Stat() (gofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Write([]byte) (int, error) {
+	return 0, d.isDir("write")
+}
+
+func (d *dir) isDir(op string) error {
+	return fmt.Errorf("billyfs_dir: %w", &gofs.PathError{Op: op, Path: d.name, Err: fs.ErrIsDir})
+}
@@ -0,0 +1,268 @@
+package billyfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var _ fs.FS = (*BillyFS)(nil)
+
+// BillyFS go-billy file system provider that implements fs.FS.
+//
+// A BillyFS provides access to a billy.Filesystem, such as the in-memory and OS file systems provided by go-billy or
+// the worktree of a go-git repository, using the interfaces defined by this package.
+type BillyFS struct {
+	bfs billy.Filesystem
+}
+
+// New creates a new BillyFS backed by bfs.
+func New(bfs billy.Filesystem) (*BillyFS, error) {
+	if bfs == nil {
+		return nil, errors.New("billyfs: billy file system is required")
+	}
+	return &BillyFS{bfs: bfs}, nil
+}
+
+// Billy returns the billy.Filesystem backing the BillyFS.
+func (b *BillyFS) Billy() billy.Filesystem {
+	return b.bfs
+}
+
+// Close is a no-op, since a billy.Filesystem does not hold resources that need to be released.
+func (b *BillyFS) Close() error {
+	return nil
+}
+
+// Create ...
+func (b *BillyFS) Create(name string) (fs.File, error) {
+	return b.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob ...
+func (b *BillyFS) Glob(pattern string) ([]string, error) {
+	if _, err := gopath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches, err := util.Glob(b.bfs, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "glob", Path: pattern, Err: err})
+	}
+	return matches, nil
+}
+
+// Mkdir ...
+func (b *BillyFS) Mkdir(name string, perm gofs.FileMode) error {
+	if !gofs.ValidPath(name) {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	if _, err := b.bfs.Stat(name); err == nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: gofs.ErrExist})
+	}
+
+	if dir := gopath.Dir(name); dir != "." {
+		fi, err := b.bfs.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: err})
+		}
+
+		if !fi.IsDir() {
+			return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotDir})
+		}
+	}
+
+	if err := b.bfs.MkdirAll(name, perm); err != nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: err})
+	}
+	return nil
+}
+
+// MkdirAll ...
+func (b *BillyFS) MkdirAll(path string, perm gofs.FileMode) error {
+	if !gofs.ValidPath(path) {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdirAll", Path: path, Err: gofs.ErrInvalid})
+	}
+
+	if err := b.bfs.MkdirAll(path, perm); err != nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "mkdirAll", Path: path, Err: err})
+	}
+	return nil
+}
+
+// Open opens the named File.
+func (b *BillyFS) Open(name string) (gofs.File, error) {
+	return b.open("open", name, fs.O_RDONLY, 0)
+}
+
+// OpenFile ...
+func (b *BillyFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	return b.open("openFile", name, flag, perm)
+}
+
+// PathSeparator ...
+func (b *BillyFS) PathSeparator() string {
+	return "/"
+}
+
+// Provider ...
+func (b *BillyFS) Provider() string {
+	return "billy"
+}
+
+// ReadDir ...
+func (b *BillyFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	if !gofs.ValidPath(name) {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "readDir", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	entries, err := b.readDir(name)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "readDir", Path: name, Err: err})
+	}
+	return entries, nil
+}
+
+// ReadFile ...
+func (b *BillyFS) ReadFile(name string) ([]byte, error) {
+	if !gofs.ValidPath(name) {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	data, err := util.ReadFile(b.bfs, name)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: err})
+	}
+	return data, nil
+}
+
+// Remove ...
+func (b *BillyFS) Remove(name string) error {
+	if !gofs.ValidPath(name) {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "remove", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	if err := b.bfs.Remove(name); err != nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "remove", Path: name, Err: err})
+	}
+	return nil
+}
+
+// RemoveAll ...
+func (b *BillyFS) RemoveAll(path string) error {
+	if !gofs.ValidPath(path) {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "removeAll", Path: path, Err: gofs.ErrInvalid})
+	}
+
+	if err := util.RemoveAll(b.bfs, path); err != nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "removeAll", Path: path, Err: err})
+	}
+	return nil
+}
+
+// Rename ...
+func (b *BillyFS) Rename(oldpath string, newpath string) error {
+	if !gofs.ValidPath(oldpath) || !gofs.ValidPath(newpath) {
+		return fmt.Errorf("billyfs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: gofs.ErrInvalid})
+	}
+
+	if err := b.bfs.Rename(oldpath, newpath); err != nil {
+		return fmt.Errorf("billyfs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err})
+	}
+	return nil
+}
+
+// Root ...
+func (b *BillyFS) Root() (string, error) {
+	return b.bfs.Root(), nil
+}
+
+// Stat ...
+func (b *BillyFS) Stat(name string) (gofs.FileInfo, error) {
+	if !gofs.ValidPath(name) {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "stat", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	fi, err := b.bfs.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "stat", Path: name, Err: err})
+	}
+	return fi, nil
+}
+
+// Sub returns a BillyFS for the sub-tree dir using billy.Filesystem.Chroot.
+func (b *BillyFS) Sub(dir string) (gofs.FS, error) {
+	fi, err := b.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotDir})
+	}
+
+	bfs, err := b.bfs.Chroot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: err})
+	}
+	return &BillyFS{bfs: bfs}, nil
+}
+
+// WriteFile ...
+func (b *BillyFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	if !gofs.ValidPath(name) {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "writeFile", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	if err := util.WriteFile(b.bfs, name, data, perm); err != nil {
+		return fmt.Errorf("billyfs: %w", &gofs.PathError{Op: "writeFile", Path: name, Err: err})
+	}
+	return nil
+}
+
+func (b *BillyFS) open(op string, name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if !gofs.ValidPath(name) {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+	}
+
+	if fi, err := b.bfs.Stat(name); err == nil && fi.IsDir() {
+		if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_TRUNC) != 0 {
+			return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: op, Path: name, Err: fs.ErrIsDir})
+		}
+
+		entries, err := b.readDir(name)
+		if err != nil {
+			return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+		}
+		return newDir(name, fi, entries), nil
+	}
+
+	f, err := b.bfs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, fmt.Errorf("billyfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return newFile(b, name, f), nil
+}
+
+func (b *BillyFS) readDir(name string) ([]gofs.DirEntry, error) {
+	infos, err := b.bfs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]gofs.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = gofs.FileInfoToDirEntry(fi)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
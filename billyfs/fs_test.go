@@ -0,0 +1,183 @@
+package billyfs
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// BillyFSTestSuite ...
+type BillyFSTestSuite struct {
+	suite.Suite
+	bfs *BillyFS
+}
+
+func NewBillyFSTestSuite() *BillyFSTestSuite {
+	return &BillyFSTestSuite{}
+}
+
+func (t *BillyFSTestSuite) SetupTest() {
+	bos := osfs.New(t.T().TempDir())
+	for name, content := range map[string]string{
+		"doc/fox.txt":    "the quick brown fox",
+		"doc/dog.txt":    "jumps over the lazy dog",
+		"pictures/empty": "",
+	} {
+		f, err := bos.Create(name)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		if _, err := io.WriteString(f, content); err != nil {
+			t.T().Fatal(err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+
+	bfs, err := New(bos)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.bfs = bfs
+}
+
+func TestBillyFSTestSuite(t *testing.T) {
+	suite.Run(t, NewBillyFSTestSuite())
+}
+
+func (t *BillyFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.bfs, "doc/fox.txt", "doc/dog.txt", "pictures/empty"))
+}
+
+func (t *BillyFSTestSuite) TestWritable() {
+	assert.NoError(t.T(), t.bfs.WriteFile("doc/cat.txt", []byte("the cat"), 0644))
+
+	b, err := t.bfs.ReadFile("doc/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat", string(b))
+
+	assert.NoError(t.T(), t.bfs.Mkdir("music", 0755))
+	assert.ErrorIs(t.T(), t.bfs.Mkdir("music", 0755), gofs.ErrExist)
+
+	assert.NoError(t.T(), t.bfs.Rename("doc/cat.txt", "music/cat.txt"))
+	_, err = t.bfs.Stat("music/cat.txt")
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), t.bfs.RemoveAll("doc"))
+	_, err = t.bfs.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	sub, err := t.bfs.Sub("music")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), fstest.TestFS(sub, "cat.txt"))
+}
+
+func (t *BillyFSTestSuite) TestWrap() {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	bfs := Wrap(mfs)
+
+	f, err := bfs.Create("/doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), "/doc/fox.txt", f.Name())
+
+	_, err = io.WriteString(f, "the quick brown fox")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	b, err := mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	infos, err := bfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), infos, 1)
+	assert.Equal(t.T(), "fox.txt", infos[0].Name())
+
+	_, err = bfs.Stat("../doc/fox.txt")
+	assert.ErrorIs(t.T(), err, billy.ErrCrossedBoundary)
+
+	cfs, err := bfs.Chroot("doc")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	fi, err := cfs.Stat("fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(19), fi.Size())
+
+	assert.ErrorIs(t.T(), cfs.Symlink("fox.txt", "link"), billy.ErrNotSupported)
+}
+
+func (t *BillyFSTestSuite) TestGit() {
+	osfs, err := fs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	root, err := fs.Chroot(osfs, t.T().TempDir())
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	worktree := Wrap(root)
+	dotgit, err := worktree.Chroot(git.GitDirName)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	repo, err := git.Init(filesystem.NewStorage(dotgit, cache.NewObjectLRUDefault()), worktree)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), root.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = wt.Add("fox.txt")
+	assert.NoError(t.T(), err)
+
+	sig := &object.Signature{Name: "fox", Email: "fox@example.com", When: time.Now()}
+	hash, err := wt.Commit("add fox", &git.CommitOptions{Author: sig})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	c, err := repo.CommitObject(hash)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), "add fox", c.Message)
+
+	_, err = root.Stat(".git/HEAD")
+	assert.NoError(t.T(), err)
+}
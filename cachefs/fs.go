@@ -0,0 +1,569 @@
+package cachefs
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	// DefaultMaxSize is the default maximum number of bytes of file content held by a CacheFS.
+	DefaultMaxSize = int64(64 << 20)
+
+	// DefaultTTL is the default duration that cached content and metadata remain valid.
+	DefaultTTL = time.Minute
+)
+
+var _ fs.FS = (*CacheFS)(nil)
+
+// CacheFS read-through caching provider that implements fs.FS.
+//
+// A CacheFS fronts a slow backing file system with a fast cache file system (e.g. memfs.MemFS). File content read from
+// the backing file system is written to the cache file system, and Stat/ReadDir results are held in memory. Cached
+// entries expire after a TTL, content is evicted in least-recently-used order once the cache exceeds its maximum size,
+// and every write operation invalidates the affected entries before being passed through to the backing file system.
+type CacheFS struct {
+	backing fs.FS
+	cache   fs.FS
+	closed  bool
+	dirs    map[string]*dirItem
+	gen     uint64
+	items   map[string]*list.Element
+	logger  fs.Logger
+	lru     *list.List
+	maxSize int64
+	meta    map[string]*metaItem
+	mutex   sync.Mutex
+	now     func() time.Time
+	size    int64
+	ttl     time.Duration
+}
+
+// New creates a new CacheFS that caches content from backing using cache as the content store.
+func New(backing fs.FS, cache fs.FS, options ...func(*CacheFS)) (*CacheFS, error) {
+	if backing == nil {
+		return nil, errors.New("cachefs: backing file system is required")
+	}
+
+	if cache == nil {
+		return nil, errors.New("cachefs: cache file system is required")
+	}
+
+	c := &CacheFS{
+		backing: backing,
+		cache:   cache,
+		dirs:    make(map[string]*dirItem),
+		items:   make(map[string]*list.Element),
+		logger:  fs.NopLogger(),
+		lru:     list.New(),
+		maxSize: DefaultMaxSize,
+		meta:    make(map[string]*metaItem),
+		now:     time.Now,
+		ttl:     DefaultTTL,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Close purges all cached entries. The backing and cache file systems are not closed.
+func (c *CacheFS) Close() error {
+	if c == nil {
+		return gofs.ErrInvalid
+	}
+
+	if err := c.Purge(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		return nil
+	}
+	return fmt.Errorf("cachefs: %w", gofs.ErrClosed)
+}
+
+// Create ...
+func (c *CacheFS) Create(name string) (fs.File, error) {
+	return c.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob is passed through to the backing file system and is not cached.
+func (c *CacheFS) Glob(pattern string) ([]string, error) {
+	return c.backing.Glob(pattern)
+}
+
+// Invalidate removes all cached content and metadata for path, any children it contains, and its parent directory
+// listing.
+func (c *CacheFS) Invalidate(path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.invalidate(path)
+}
+
+// Len returns the number of files with cached content.
+func (c *CacheFS) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lru.Len()
+}
+
+// Mkdir ...
+func (c *CacheFS) Mkdir(name string, perm gofs.FileMode) error {
+	defer c.Invalidate(name)
+	return c.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (c *CacheFS) MkdirAll(path string, perm gofs.FileMode) error {
+	defer c.Invalidate(path)
+	return c.backing.MkdirAll(path, perm)
+}
+
+// Open opens the named file. The content for regular files is served from the cache file system, and is read through
+// from the backing file system on a miss. Files larger than the maximum cache size are opened on the backing file
+// system.
+func (c *CacheFS) Open(name string) (gofs.File, error) {
+	c.logger.Debug("[cachefs] open", "name", name)
+	return c.open("open", name)
+}
+
+// OpenFile opens the named file. Files opened for writing are opened on the backing file system, and the cached
+// entries for the file are invalidated when it is opened and again when it is closed.
+func (c *CacheFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	c.logger.Debug("[cachefs] openFile", "name", name, "flag", flag)
+
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		return c.open("openFile", name)
+	}
+
+	c.Invalidate(name)
+	f, err := c.backing.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &writeFile{File: f, invalidate: func() { c.Invalidate(name) }}, nil
+}
+
+// PathSeparator ...
+func (c *CacheFS) PathSeparator() string {
+	return c.backing.PathSeparator()
+}
+
+// Provider ...
+func (c *CacheFS) Provider() string {
+	return c.backing.Provider()
+}
+
+// Purge removes all cached content and metadata.
+func (c *CacheFS) Purge() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var errs []error
+	for c.lru.Len() > 0 {
+		if err := c.evict(c.lru.Back().Value.(*item).name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	clear(c.dirs)
+	clear(c.meta)
+	return errors.Join(errs...)
+}
+
+// ReadDir ...
+func (c *CacheFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	c.logger.Debug("[cachefs] readDir", "name", name)
+
+	name, err := c.clean("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if d, ok := c.dirs[name]; ok {
+		if !c.expired(d.expires) {
+			c.mutex.Unlock()
+			return append([]gofs.DirEntry(nil), d.entries...), nil
+		}
+		delete(c.dirs, name)
+	}
+	gen := c.gen
+	c.mutex.Unlock()
+
+	entries, err := c.backing.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if c.gen == gen {
+		c.dirs[name] = &dirItem{entries: entries, expires: c.expiry()}
+	}
+	c.mutex.Unlock()
+	return append([]gofs.DirEntry(nil), entries...), nil
+}
+
+// ReadFile ...
+func (c *CacheFS) ReadFile(name string) ([]byte, error) {
+	c.logger.Debug("[cachefs] readFile", "name", name)
+
+	name, err := c.clean("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.hit(name) {
+		b, err := c.cache.ReadFile(name)
+		if err == nil {
+			return b, nil
+		}
+
+		c.logger.Warn("[cachefs] cached content unreadable", "name", name, "error", err)
+		c.Invalidate(name)
+	}
+
+	gen := c.generation()
+	b, err := c.backing.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	c.fill(name, b, gen)
+	return b, nil
+}
+
+// Remove ...
+func (c *CacheFS) Remove(name string) error {
+	defer c.Invalidate(name)
+	return c.backing.Remove(name)
+}
+
+// RemoveAll ...
+func (c *CacheFS) RemoveAll(path string) error {
+	defer c.Invalidate(path)
+	return c.backing.RemoveAll(path)
+}
+
+// Rename ...
+func (c *CacheFS) Rename(oldpath string, newpath string) error {
+	defer func() {
+		c.Invalidate(oldpath)
+		c.Invalidate(newpath)
+	}()
+	return c.backing.Rename(oldpath, newpath)
+}
+
+// Root ...
+func (c *CacheFS) Root() (string, error) {
+	return c.backing.Root()
+}
+
+// Size returns the number of bytes of cached file content.
+func (c *CacheFS) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size
+}
+
+// Stat ...
+func (c *CacheFS) Stat(name string) (gofs.FileInfo, error) {
+	c.logger.Debug("[cachefs] stat", "name", name)
+
+	name, err := c.clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if m, ok := c.meta[name]; ok {
+		if !c.expired(m.expires) {
+			c.mutex.Unlock()
+			return m.info, nil
+		}
+		delete(c.meta, name)
+	}
+	gen := c.gen
+	c.mutex.Unlock()
+
+	fi, err := c.backing.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if c.gen == gen {
+		c.meta[name] = &metaItem{info: fi, expires: c.expiry()}
+	}
+	c.mutex.Unlock()
+	return fi, nil
+}
+
+// Sub is passed through to the backing file system and is not cached.
+func (c *CacheFS) Sub(dir string) (gofs.FS, error) {
+	return c.backing.Sub(dir)
+}
+
+// WriteFile ...
+func (c *CacheFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	defer c.Invalidate(name)
+	return c.backing.WriteFile(name, data, perm)
+}
+
+func (c *CacheFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(c, name)
+	if err != nil {
+		return name, fmt.Errorf("cachefs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+// evict removes the cached content for name. The caller must hold the mutex.
+func (c *CacheFS) evict(name string) error {
+	e, ok := c.items[name]
+	if !ok {
+		return nil
+	}
+
+	i := c.lru.Remove(e).(*item)
+	delete(c.items, name)
+	c.size -= i.size
+
+	if err := c.cache.Remove(name); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *CacheFS) expired(expires time.Time) bool {
+	return !expires.IsZero() && !c.now().Before(expires)
+}
+
+func (c *CacheFS) expiry() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(c.ttl)
+}
+
+// fill stores content read from the backing file system in the cache file system, evicting the least-recently-used
+// content until the cache is within its maximum size. The content is not stored if an entry was invalidated since the
+// generation gen was recorded before the content was read, since it may predate a concurrent write.
+func (c *CacheFS) fill(name string, b []byte, gen uint64) {
+	size := int64(len(b))
+	if size > c.maxSize {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.gen != gen {
+		return
+	}
+
+	if err := c.evict(name); err != nil {
+		c.logger.Warn("[cachefs] evict", "name", name, "error", err)
+		return
+	}
+
+	if dir := gopath.Dir(name); dir != "." {
+		if err := c.cache.MkdirAll(dir, 0755); err != nil {
+			c.logger.Warn("[cachefs] fill", "name", name, "error", err)
+			return
+		}
+	}
+
+	if err := c.cache.WriteFile(name, b, 0644); err != nil {
+		c.logger.Warn("[cachefs] fill", "name", name, "error", err)
+		return
+	}
+
+	c.items[name] = c.lru.PushFront(&item{expires: c.expiry(), name: name, size: size})
+	c.size += size
+
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		victim := c.lru.Back().Value.(*item).name
+		c.logger.Debug("[cachefs] evicting least recently used", "name", victim)
+
+		if err := c.evict(victim); err != nil {
+			c.logger.Warn("[cachefs] evict", "name", victim, "error", err)
+		}
+	}
+}
+
+// generation returns the number of times entries were invalidated, which is recorded before reading the backing file
+// system, so that the result is only cached if no entry was invalidated while it was read.
+func (c *CacheFS) generation() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.gen
+}
+
+// hit reports whether unexpired content for name is cached, marking it as most recently used.
+func (c *CacheFS) hit(name string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.items[name]
+	if !ok {
+		return false
+	}
+
+	if c.expired(e.Value.(*item).expires) {
+		if err := c.evict(name); err != nil {
+			c.logger.Warn("[cachefs] evict", "name", name, "error", err)
+		}
+		return false
+	}
+	c.lru.MoveToFront(e)
+	return true
+}
+
+// invalidate removes the cached entries for path, and increments the generation so that results read from the backing
+// file system before the invalidation are not cached. The caller must hold the mutex.
+func (c *CacheFS) invalidate(path string) {
+	c.gen++
+
+	path, err := fs.CleanPath(c, path)
+	if err != nil {
+		return
+	}
+
+	prefix := path + "/"
+	matches := func(p string) bool {
+		return path == "." || p == path || strings.HasPrefix(p, prefix)
+	}
+
+	for name := range c.items {
+		if matches(name) {
+			if err := c.evict(name); err != nil {
+				c.logger.Warn("[cachefs] evict", "name", name, "error", err)
+			}
+		}
+	}
+
+	for name := range c.meta {
+		if matches(name) {
+			delete(c.meta, name)
+		}
+	}
+
+	for name := range c.dirs {
+		if matches(name) {
+			delete(c.dirs, name)
+		}
+	}
+	delete(c.dirs, gopath.Dir(path))
+}
+
+func (c *CacheFS) open(op string, name string) (fs.File, error) {
+	name, err := c.clean(op, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := c.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files that are too large to be cached are streamed from the backing file system instead of being read into
+	// memory.
+	if fi.IsDir() || fi.Size() > c.maxSize {
+		return c.backing.OpenFile(name, fs.O_RDONLY, 0)
+	}
+
+	if !c.hit(name) {
+		gen := c.generation()
+		b, err := c.backing.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		c.fill(name, b, gen)
+	}
+
+	f, err := c.cache.OpenFile(name, fs.O_RDONLY, 0)
+	if err != nil {
+		return c.backing.OpenFile(name, fs.O_RDONLY, 0)
+	}
+	return &cachedFile{File: f, info: fi}, nil
+}
+
+// WithLogger sets the Logger used by a CacheFS to log its operations and the errors that occur while filling or
+// evicting cached content. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*CacheFS) {
+	return func(c *CacheFS) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithMaxSize sets the maximum number of bytes of file content held by a CacheFS. Files larger than the maximum size
+// are never cached.
+func WithMaxSize(size int64) func(*CacheFS) {
+	return func(c *CacheFS) {
+		c.maxSize = size
+	}
+}
+
+// WithTTL sets the duration that cached content and metadata remain valid for a CacheFS. A TTL less than or equal to
+// zero disables expiry.
+func WithTTL(ttl time.Duration) func(*CacheFS) {
+	return func(c *CacheFS) {
+		c.ttl = ttl
+	}
+}
+
+type dirItem struct {
+	entries []gofs.DirEntry
+	expires time.Time
+}
+
+type item struct {
+	expires time.Time
+	name    string
+	size    int64
+}
+
+type metaItem struct {
+	expires time.Time
+	info    gofs.FileInfo
+}
+
+// cachedFile is a file opened from the cache file system that reports the file info from the backing file system.
+type cachedFile struct {
+	fs.File
+	info gofs.FileInfo
+}
+
+func (f *cachedFile) Stat() (gofs.FileInfo, error) {
+	return f.info, nil
+}
+
+// writeFile is a file opened for writing on the backing file system that invalidates cached entries when closed.
+type writeFile struct {
+	fs.File
+	invalidate func()
+}
+
+func (f *writeFile) Close() error {
+	defer f.invalidate()
+	return f.File.Close()
+}
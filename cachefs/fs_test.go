@@ -0,0 +1,251 @@
+package cachefs
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// countingFS records the number of read operations passed through to the backing file system. If afterRead is set, it
+// is called once after the next read operation, before its result is returned.
+type countingFS struct {
+	fs.FS
+	afterRead func()
+	readDirs  int
+	readFiles int
+	stats     int
+}
+
+func (c *countingFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	c.readDirs++
+	defer c.read()
+	return c.FS.ReadDir(name)
+}
+
+func (c *countingFS) ReadFile(name string) ([]byte, error) {
+	c.readFiles++
+	defer c.read()
+	return c.FS.ReadFile(name)
+}
+
+func (c *countingFS) Stat(name string) (gofs.FileInfo, error) {
+	c.stats++
+	defer c.read()
+	return c.FS.Stat(name)
+}
+
+func (c *countingFS) read() {
+	if f := c.afterRead; f != nil {
+		c.afterRead = nil
+		f()
+	}
+}
+
+// CacheFSTestSuite ...
+type CacheFSTestSuite struct {
+	suite.Suite
+	backing *countingFS
+	cache   *memfs.MemFS
+	cfs     *CacheFS
+	now     time.Time
+}
+
+func NewCacheFSTestSuite() *CacheFSTestSuite {
+	return &CacheFSTestSuite{}
+}
+
+func (t *CacheFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := map[string]string{
+		"doc/fox.txt":      "the quick brown fox",
+		"doc/dog.txt":      "jumps over the lazy dog",
+		"pictures/big.bin": "0123456789abcdef0123456789abcdef",
+	}
+	for name, content := range files {
+		if err := backing.WriteFile(name, []byte(content), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+	t.backing = &countingFS{FS: backing}
+
+	cache, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.cache = cache
+
+	cfs, err := New(t.backing, cache, WithMaxSize(40), WithTTL(time.Minute))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.now = time.Now()
+	cfs.now = func() time.Time { return t.now }
+	t.cfs = cfs
+}
+
+func TestCacheFSTestSuite(t *testing.T) {
+	suite.Run(t, NewCacheFSTestSuite())
+}
+
+func (t *CacheFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.cfs, "doc/fox.txt", "doc/dog.txt", "pictures/big.bin"))
+}
+
+func (t *CacheFSTestSuite) TestReadThrough() {
+	for i := 0; i < 3; i++ {
+		b, err := t.cfs.ReadFile("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the quick brown fox", string(b))
+	}
+	assert.Equal(t.T(), 1, t.backing.readFiles)
+
+	_, err := t.cache.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	for i := 0; i < 3; i++ {
+		_, err := t.cfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+
+		_, err = t.cfs.ReadDir("doc")
+		assert.NoError(t.T(), err)
+	}
+	assert.Equal(t.T(), 1, t.backing.stats)
+	assert.Equal(t.T(), 1, t.backing.readDirs)
+}
+
+func (t *CacheFSTestSuite) TestTTL() {
+	_, err := t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	t.now = t.now.Add(2 * time.Minute)
+
+	_, err = t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 2, t.backing.readFiles)
+}
+
+func (t *CacheFSTestSuite) TestEviction() {
+	_, err := t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = t.cfs.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, t.cfs.Len())
+	assert.LessOrEqual(t.T(), t.cfs.Size(), int64(40))
+
+	_, err = t.cache.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.cfs.ReadFile("pictures/big.bin")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, t.cfs.Len())
+}
+
+func (t *CacheFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfs, err := New(t.backing, t.cache, WithMaxSize(40), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for _, name := range []string{"doc/fox.txt", "doc/dog.txt"} {
+		_, err := cfs.ReadFile(name)
+		assert.NoError(t.T(), err)
+	}
+	assert.Contains(t.T(), buf.String(), `msg="[cachefs] evicting least recently used" name=doc/fox.txt`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.cfs.logger)
+}
+
+func (t *CacheFSTestSuite) TestInvalidateOnWrite() {
+	entries, err := t.cfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 2)
+
+	_, err = t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), t.cfs.Remove("doc/fox.txt"))
+	assert.Equal(t.T(), 0, t.cfs.Len())
+
+	_, err = t.cfs.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.cfs.WriteFile("doc/cat.txt", []byte("cat"), 0644))
+
+	entries, err = t.cfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 2)
+	assert.Equal(t.T(), 2, t.backing.readDirs)
+}
+
+func (t *CacheFSTestSuite) TestOpenLarge() {
+	content := strings.Repeat("the quick brown fox ", 4)
+	assert.NoError(t.T(), t.backing.WriteFile("doc/large.txt", []byte(content), 0644))
+
+	f, err := t.cfs.Open("doc/large.txt")
+	assert.NoError(t.T(), err)
+
+	b, err := io.ReadAll(f)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), content, string(b))
+	assert.NoError(t.T(), f.Close())
+
+	// Files larger than the maximum size are streamed from the backing file system without being read into memory.
+	assert.Equal(t.T(), 0, t.backing.readFiles)
+	assert.Equal(t.T(), 0, t.cfs.Len())
+}
+
+func (t *CacheFSTestSuite) TestStaleFill() {
+	// Content read from the backing file system before a concurrent write is not cached.
+	t.backing.afterRead = func() {
+		assert.NoError(t.T(), t.cfs.WriteFile("doc/fox.txt", []byte("the lazy fox"), 0644))
+	}
+	b, err := t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+	assert.Equal(t.T(), 0, t.cfs.Len())
+
+	b, err = t.cfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy fox", string(b))
+	assert.Equal(t.T(), 1, t.cfs.Len())
+
+	t.backing.afterRead = func() {
+		assert.NoError(t.T(), t.cfs.Remove("doc/dog.txt"))
+	}
+	_, err = t.cfs.Stat("doc/dog.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = t.cfs.Stat("doc/dog.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	t.backing.afterRead = func() {
+		assert.NoError(t.T(), t.cfs.WriteFile("doc/cat.txt", []byte("cat"), 0644))
+	}
+	entries, err := t.cfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+
+	entries, err = t.cfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 2)
+}
@@ -0,0 +1,228 @@
+package fs
+
+import (
+	"strings"
+	"time"
+)
+
+// Capability describes an optional feature of a file system. Capabilities are bit flags so that the set of features
+// supported by a file system can be represented by a single Capability.
+type Capability uint32
+
+// Enumeration of the optional features that may be supported by a file system.
+const (
+	// CapSymlinks indicates that symbolic links can be created and read using SymlinkFS.
+	CapSymlinks Capability = 1 << iota
+
+	// CapXattrs indicates that extended attributes can be read and written using XattrFS.
+	CapXattrs
+
+	// CapLocks indicates that files can be locked using LockFS.
+	CapLocks
+
+	// CapWatch indicates that changes to entries can be observed using WatchFS.
+	CapWatch
+
+	// CapAtomicRename indicates that Rename replaces an existing entry atomically, so that other operations observe
+	// either the old or the new entry, but never a missing one.
+	CapAtomicRename
+
+	// CapServerSideCopy indicates that files can be copied without transferring their content using CopyFS.
+	CapServerSideCopy
+
+	// CapChmod indicates that the mode of an entry can be changed using ChmodFS.
+	CapChmod
+
+	// CapChtimes indicates that the access and modification times of an entry can be changed using ChtimesFS.
+	CapChtimes
+
+	// CapMetadata indicates that the user-defined metadata of an entry can be changed using MetadataFS.
+	CapMetadata
+
+	// CapUsage indicates that the capacity and usage of the storage can be read using StatFSer.
+	CapUsage
+
+	// CapSignedURLs indicates that URLs granting temporary access to files can be created using SignedURLFS.
+	CapSignedURLs
+)
+
+// CapabilityFS is implemented by a file system that supports capabilities that cannot be detected from the methods it
+// implements, such as CapAtomicRename.
+type CapabilityFS interface {
+	FS
+
+	// Capabilities returns the capabilities supported by the file system, in addition to those detected by the
+	// Capabilities function.
+	Capabilities() Capability
+}
+
+// LockFS is implemented by a file system that supports advisory file locks.
+type LockFS interface {
+	FS
+
+	// Lock acquires a lock for the named file, blocking until the lock is available. If exclusive is false, a shared
+	// lock is acquired, which may be held by multiple callers at the same time. The returned function releases the lock.
+	Lock(name string, exclusive bool) (unlock func() error, err error)
+}
+
+// MetadataFS is implemented by a file system that supports user-defined metadata for its entries, such as custom
+// object metadata or application tags. The metadata of an entry is provided by the Attribute of the Entry returned by
+// Stat.
+type MetadataFS interface {
+	FS
+
+	// SetMetadata replaces the user-defined metadata of the named entry with metadata.
+	SetMetadata(name string, metadata map[string]string) error
+}
+
+// SignedURLFS is implemented by a file system that can create URLs granting temporary access to its files without
+// credentials, such as the pre-signed URLs of object stores, so that clients can download or upload content directly
+// from the provider.
+type SignedURLFS interface {
+	FS
+
+	// SignedURL returns a URL that grants access to the named file using the HTTP method (e.g. http.MethodGet to download
+	// the file, or http.MethodPut to upload it) until expiry has elapsed. An error wrapping ErrInvalid is returned if the
+	// method or expiry is not supported by the provider. The file does not need to exist if method creates it.
+	SignedURL(name string, method string, expiry time.Duration) (string, error)
+}
+
+// StatFSer is implemented by a file system that reports the capacity and usage of its storage, in the same way as the
+// statfs system call, so that callers can make placement decisions and display capacity.
+type StatFSer interface {
+	FS
+
+	// Usage returns the total number of bytes of storage, the number of bytes used and the number of bytes available to
+	// the caller, and the number of files and directories that exist. The number of bytes available may be less than
+	// the difference between total and used if storage is reserved, such as for privileged users.
+	Usage() (total uint64, used uint64, free uint64, files uint64, err error)
+}
+
+// SymlinkFS is implemented by a file system that supports symbolic links.
+type SymlinkFS interface {
+	FS
+
+	// Readlink returns the destination of the named symbolic link.
+	Readlink(name string) (string, error)
+
+	// Symlink creates newname as a symbolic link to oldname.
+	Symlink(oldname string, newname string) error
+}
+
+// WatchFS is implemented by a file system that reports changes to its entries.
+type WatchFS interface {
+	FS
+
+	// Watch calls handler with an Event for each change to the named entry, or to the entries of the named directory,
+	// until the returned stop function is called.
+	Watch(name string, handler func(Event)) (stop func() error, err error)
+}
+
+// XattrFS is implemented by a file system that supports extended attributes.
+type XattrFS interface {
+	FS
+
+	// GetXattr returns the value of the extended attribute attr of the named entry.
+	GetXattr(name string, attr string) ([]byte, error)
+
+	// ListXattrs returns the names of the extended attributes of the named entry.
+	ListXattrs(name string) ([]string, error)
+
+	// RemoveXattr removes the extended attribute attr from the named entry.
+	RemoveXattr(name string, attr string) error
+
+	// SetXattr sets the value of the extended attribute attr of the named entry.
+	SetXattr(name string, attr string, value []byte) error
+}
+
+// Capabilities returns the capabilities supported by fsys, so that generic tools can detect optional features before
+// using them instead of relying on failures at runtime.
+//
+// The capabilities are detected from the optional interfaces implemented by fsys (e.g. CapSymlinks for SymlinkFS), in
+// addition to the capabilities returned by fsys if it implements CapabilityFS.
+func Capabilities(fsys FS) Capability {
+	if fsys == nil {
+		return 0
+	}
+
+	var c Capability
+	if _, ok := fsys.(SymlinkFS); ok {
+		c |= CapSymlinks
+	}
+
+	if _, ok := fsys.(XattrFS); ok {
+		c |= CapXattrs
+	}
+
+	if _, ok := fsys.(LockFS); ok {
+		c |= CapLocks
+	}
+
+	if _, ok := fsys.(WatchFS); ok {
+		c |= CapWatch
+	}
+
+	if _, ok := fsys.(CopyFS); ok {
+		c |= CapServerSideCopy
+	}
+
+	if _, ok := fsys.(ChmodFS); ok {
+		c |= CapChmod
+	}
+
+	if _, ok := fsys.(ChtimesFS); ok {
+		c |= CapChtimes
+	}
+
+	if _, ok := fsys.(MetadataFS); ok {
+		c |= CapMetadata
+	}
+
+	if _, ok := fsys.(StatFSer); ok {
+		c |= CapUsage
+	}
+
+	if _, ok := fsys.(SignedURLFS); ok {
+		c |= CapSignedURLs
+	}
+
+	if cfs, ok := fsys.(CapabilityFS); ok {
+		c |= cfs.Capabilities()
+	}
+	return c
+}
+
+// Has reports whether c includes every capability in capabilities.
+func (c Capability) Has(capabilities Capability) bool {
+	return c&capabilities == capabilities
+}
+
+// String returns a string representation of the Capability.
+func (c Capability) String() string {
+	var caps []string
+	for _, cp := range []struct {
+		c    Capability
+		name string
+	}{
+		{CapSymlinks, "symlinks"},
+		{CapXattrs, "xattrs"},
+		{CapLocks, "locks"},
+		{CapWatch, "watch"},
+		{CapAtomicRename, "atomicRename"},
+		{CapServerSideCopy, "serverSideCopy"},
+		{CapChmod, "chmod"},
+		{CapChtimes, "chtimes"},
+		{CapMetadata, "metadata"},
+		{CapUsage, "usage"},
+		{CapSignedURLs, "signedURLs"},
+	} {
+		if c&cp.c != 0 {
+			caps = append(caps, cp.name)
+		}
+	}
+
+	if len(caps) == 0 {
+		return "none"
+	}
+	return strings.Join(caps, "|")
+}
@@ -0,0 +1,124 @@
+package fs_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caps := fs.Capabilities(osfs)
+	assert.True(t, caps.Has(fs.CapSymlinks|fs.CapAtomicRename|fs.CapServerSideCopy|fs.CapChmod|fs.CapChtimes|fs.CapUsage))
+	assert.False(t, caps.Has(fs.CapXattrs))
+	assert.Equal(t, "symlinks|atomicRename|serverSideCopy|chmod|chtimes|usage", caps.String())
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fs.CapAtomicRename|fs.CapChmod|fs.CapChtimes|fs.CapMetadata|fs.CapUsage, fs.Capabilities(mfs))
+
+	cfs, err := fs.Chroot(osfs, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fs.CapAtomicRename, fs.Capabilities(cfs))
+
+	assert.Equal(t, fs.Capability(0), fs.Capabilities(fs.ReadOnly(mfs)))
+	assert.Equal(t, "none", fs.Capability(0).String())
+}
+
+// signedFS is a file system that creates signed URLs for the files of a MemFS.
+type signedFS struct {
+	*memfs.MemFS
+}
+
+func (s signedFS) SignedURL(name string, method string, expiry time.Duration) (string, error) {
+	if method != http.MethodGet || expiry <= 0 {
+		return "", fs.ErrInvalid
+	}
+	return fmt.Sprintf("https://example.com/%s?expires=%d", name, int64(expiry.Seconds())), nil
+}
+
+func TestSignedURLFS(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fsys fs.FS = signedFS{MemFS: mfs}
+	assert.True(t, fs.Capabilities(fsys).Has(fs.CapSignedURLs))
+	assert.False(t, fs.Capabilities(mfs).Has(fs.CapSignedURLs))
+	assert.Equal(t, "signedURLs", fs.CapSignedURLs.String())
+
+	sfs, ok := fsys.(fs.SignedURLFS)
+	assert.True(t, ok)
+
+	u, err := sfs.SignedURL("doc/fox.txt", http.MethodGet, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/doc/fox.txt?expires=3600", u)
+
+	_, err = sfs.SignedURL("doc/fox.txt", http.MethodDelete, time.Hour)
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestStatFSer(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sfs fs.StatFSer = osfs
+	total, used, free, files, err := sfs.Usage()
+	if errors.Is(err, fs.ErrUnsupported) {
+		t.Skip("usage is not supported on this platform")
+	}
+	assert.NoError(t, err)
+	assert.Positive(t, total)
+	assert.LessOrEqual(t, used, total)
+	assert.LessOrEqual(t, free, total)
+
+	if runtime.GOOS != "windows" {
+		assert.Positive(t, files)
+	}
+}
+
+func TestSymlinkFS(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sfs fs.SymlinkFS = osfs
+	link := filepath.Join(dir, "link")
+	assert.NoError(t, sfs.Symlink("fox.txt", link))
+
+	dest, err := sfs.Readlink(link)
+	assert.NoError(t, err)
+	assert.Equal(t, "fox.txt", dest)
+
+	assert.ErrorIs(t, sfs.Symlink("fox.txt", link), fs.ErrExist)
+
+	_, err = sfs.Readlink(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,309 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var (
+	_ CapabilityFS = (*chrootFS)(nil)
+	_ FS           = (*chrootFS)(nil)
+)
+
+// Chroot returns a writable FS rooted at dir in fsys. Unlike Sub, which returns a read-only gofs.FS, every Readable and
+// Writable operation is supported and is passed through to fsys with dir prepended to its path.
+//
+// Paths provided to the returned FS must be valid according to gofs.ValidPath, so a path can not escape dir using ".."
+// elements or by being absolute, and paths in returned errors are relative to dir. Symbolic links are resolved by fsys,
+// so a link within dir that refers to a location outside dir is followed if fsys follows links.
+//
+// The directory dir must exist in fsys. Closing the returned FS does not close fsys.
+func Chroot(fsys FS, dir string) (FS, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	if c, ok := fsys.(*chrootFS); ok {
+		name, err := c.resolve("chroot", dir)
+		if err != nil {
+			return nil, err
+		}
+		return Chroot(c.fsys, name)
+	}
+
+	dir = gopath.Clean(strings.TrimSpace(dir))
+	fi, err := fsys.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fs: %w", &gofs.PathError{Op: "chroot", Path: dir, Err: err})
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("fs: %w", &gofs.PathError{Op: "chroot", Path: dir, Err: ErrNotDir})
+	}
+	return &chrootFS{dir: dir, fsys: fsys}, nil
+}
+
+type chrootFS struct {
+	dir  string
+	fsys FS
+}
+
+// Capabilities returns the capabilities of the underlying file system that apply to the root directory. Only
+// CapAtomicRename is retained, since the optional interfaces of the underlying file system are not exposed.
+func (c *chrootFS) Capabilities() Capability {
+	return Capabilities(c.fsys) & CapAtomicRename
+}
+
+func (c *chrootFS) Close() error {
+	return nil
+}
+
+func (c *chrootFS) Create(name string) (File, error) {
+	p, err := c.resolve("create", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.fsys.Create(p)
+	return f, c.rewrite(err)
+}
+
+func (c *chrootFS) Glob(pattern string) ([]string, error) {
+	if _, err := gopath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches, err := c.fsys.Glob(gopath.Join(escapeMeta(c.dir), pattern))
+	if err != nil {
+		return nil, c.rewrite(err)
+	}
+
+	for i, m := range matches {
+		matches[i] = c.rel(m)
+	}
+	return matches, nil
+}
+
+func (c *chrootFS) Mkdir(name string, perm gofs.FileMode) error {
+	p, err := c.resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return c.rewrite(c.fsys.Mkdir(p, perm))
+}
+
+func (c *chrootFS) MkdirAll(path string, perm gofs.FileMode) error {
+	p, err := c.resolve("mkdirAll", path)
+	if err != nil {
+		return err
+	}
+	return c.rewrite(c.fsys.MkdirAll(p, perm))
+}
+
+func (c *chrootFS) Open(name string) (gofs.File, error) {
+	p, err := c.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.fsys.Open(p)
+	if err != nil {
+		return nil, c.rewrite(err)
+	}
+	return f, nil
+}
+
+func (c *chrootFS) OpenFile(name string, flag int, perm gofs.FileMode) (File, error) {
+	p, err := c.resolve("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.fsys.OpenFile(p, flag, perm)
+	if err != nil {
+		return nil, c.rewrite(err)
+	}
+	return f, nil
+}
+
+func (c *chrootFS) PathSeparator() string {
+	return c.fsys.PathSeparator()
+}
+
+func (c *chrootFS) Provider() string {
+	return c.fsys.Provider()
+}
+
+func (c *chrootFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	p, err := c.resolve("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := c.fsys.ReadDir(p)
+	return entries, c.rewrite(err)
+}
+
+func (c *chrootFS) ReadFile(name string) ([]byte, error) {
+	p, err := c.resolve("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.fsys.ReadFile(p)
+	return b, c.rewrite(err)
+}
+
+func (c *chrootFS) Remove(name string) error {
+	if name == "." {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "remove", Path: name, Err: gofs.ErrInvalid})
+	}
+
+	p, err := c.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	return c.rewrite(c.fsys.Remove(p))
+}
+
+// RemoveAll removes path and any children it contains. If path is ".", the children of the root directory are removed
+// and the root directory itself is retained.
+func (c *chrootFS) RemoveAll(path string) error {
+	p, err := c.resolve("removeAll", path)
+	if err != nil {
+		return err
+	}
+
+	if path != "." {
+		return c.rewrite(c.fsys.RemoveAll(p))
+	}
+
+	entries, err := c.fsys.ReadDir(p)
+	if err != nil {
+		return c.rewrite(err)
+	}
+
+	for _, e := range entries {
+		if err := c.fsys.RemoveAll(gopath.Join(p, e.Name())); err != nil {
+			return c.rewrite(err)
+		}
+	}
+	return nil
+}
+
+func (c *chrootFS) Rename(oldpath string, newpath string) error {
+	if oldpath == "." || newpath == "." {
+		return fmt.Errorf("fs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: gofs.ErrInvalid})
+	}
+
+	o, err := c.resolve("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	n, err := c.resolve("rename", newpath)
+	if err != nil {
+		return err
+	}
+	return c.rewrite(c.fsys.Rename(o, n))
+}
+
+func (c *chrootFS) Root() (string, error) {
+	root, err := c.fsys.Root()
+	if err != nil {
+		return root, err
+	}
+	return gopath.Join(root, c.dir), nil
+}
+
+func (c *chrootFS) Stat(name string) (gofs.FileInfo, error) {
+	p, err := c.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := c.fsys.Stat(p)
+	return fi, c.rewrite(err)
+}
+
+// Sub returns a writable FS rooted at dir using Chroot.
+func (c *chrootFS) Sub(dir string) (gofs.FS, error) {
+	return Chroot(c, dir)
+}
+
+func (c *chrootFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	p, err := c.resolve("writeFile", name)
+	if err != nil {
+		return err
+	}
+	return c.rewrite(c.fsys.WriteFile(p, data, perm))
+}
+
+// rel returns the path for name relative to the root directory.
+func (c *chrootFS) rel(name string) string {
+	if name == c.dir {
+		return "."
+	}
+
+	if c.dir == "." {
+		return name
+	}
+
+	prefix := c.dir
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// resolve returns the path in the underlying file system for name, rejecting names that would escape the root
+// directory.
+func (c *chrootFS) resolve(op string, name string) (string, error) {
+	if !gofs.ValidPath(name) {
+		return "", fmt.Errorf("fs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+	}
+	return gopath.Join(c.dir, name), nil
+}
+
+// rewrite replaces the path in err returned by the underlying file system with the path relative to the root
+// directory.
+func (c *chrootFS) rewrite(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var oe *OpError
+	if errors.As(err, &oe) {
+		e := *oe
+		e.Path, e.NewPath = c.rel(e.Path), c.rel(e.NewPath)
+		return &e
+	}
+
+	var le *os.LinkError
+	if errors.As(err, &le) {
+		return fmt.Errorf("fs: %w", &os.LinkError{Op: le.Op, Old: c.rel(le.Old), New: c.rel(le.New), Err: le.Err})
+	}
+
+	var pe *gofs.PathError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: pe.Op, Path: c.rel(pe.Path), Err: pe.Err})
+	}
+	return err
+}
+
+// escapeMeta escapes the characters in p that are interpreted by gopath.Match.
+func escapeMeta(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
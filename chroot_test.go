@@ -0,0 +1,113 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestChroot(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("tenants/a/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("tenants/b/dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfs, err := fs.Chroot(mfs, "tenants/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fstest.TestFS(cfs, "fox.txt"))
+
+	assert.NoError(t, cfs.WriteFile("doc/cat.txt", []byte("the cat"), 0644))
+	b, err := mfs.ReadFile("tenants/a/doc/cat.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the cat", string(b))
+
+	assert.NoError(t, cfs.Rename("doc/cat.txt", "cat.txt"))
+	_, err = mfs.Stat("tenants/a/cat.txt")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"../b/dog.txt", "/tenants/b/dog.txt", "doc/../../b/dog.txt"} {
+		_, err := cfs.ReadFile(name)
+		assert.ErrorIs(t, err, gofs.ErrInvalid, name)
+	}
+	assert.ErrorIs(t, cfs.RemoveAll("../b"), gofs.ErrInvalid)
+	assert.ErrorIs(t, cfs.Rename("cat.txt", "../b/cat.txt"), gofs.ErrInvalid)
+
+	_, err = cfs.Stat("missing.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	var pe *gofs.PathError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "missing.txt", pe.Path)
+
+	sub, err := cfs.Sub("doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sfs, ok := sub.(fs.FS)
+	assert.True(t, ok)
+	assert.NoError(t, sfs.WriteFile("seal.txt", []byte("the seal"), 0644))
+	_, err = mfs.Stat("tenants/a/doc/seal.txt")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cfs.RemoveAll("."))
+	entries, err := mfs.ReadDir("tenants/a")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = fs.Chroot(mfs, "tenants/b/dog.txt")
+	assert.ErrorIs(t, err, fs.ErrNotDir)
+
+	_, err = fs.Chroot(mfs, "tenants/c")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+}
+
+func TestChrootOSFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfs, err := fs.Chroot(osfs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fstest.TestFS(cfs, "fox.txt"))
+
+	assert.NoError(t, cfs.WriteFile("dog.txt", []byte("jumps over the lazy dog"), 0644))
+	b, err := os.ReadFile(filepath.Join(dir, "dog.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "jumps over the lazy dog", string(b))
+
+	matches, err := cfs.Glob("*.txt")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dog.txt", "fox.txt"}, matches)
+
+	sub, err := osfs.Sub(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, fstest.TestFS(sub, "fox.txt", "dog.txt"))
+}
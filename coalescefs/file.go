@@ -0,0 +1,30 @@
+package coalescefs
+
+import (
+	"io"
+
+	"github.com/transientvariable/fs-go"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file records the writes performed on a File opened for writing through a CoalesceFS.
+type file struct {
+	fs.File
+	cfs *CoalesceFS
+}
+
+func (f *file) Close() error {
+	defer f.cfs.written()
+	return f.File.Close()
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	defer f.cfs.written()
+	return f.File.ReadFrom(r)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	defer f.cfs.written()
+	return f.File.Write(b)
+}
@@ -0,0 +1,199 @@
+package coalescefs
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/transientvariable/fs-go"
+
+	"golang.org/x/sync/singleflight"
+
+	gofs "io/fs"
+)
+
+var _ fs.FS = (*CoalesceFS)(nil)
+
+// CoalesceFS read-coalescing provider that implements fs.FS.
+//
+// A CoalesceFS deduplicates identical concurrent ReadFile, ReadDir, and Stat calls into a single request to the backing
+// file system, whose result is returned to every caller, which reduces the load on providers such as object stores and
+// HTTP servers under fan-out read patterns where many clients read the same entries at the same time. Callers receive
+// their own copy of the content returned by ReadFile and of the entries returned by ReadDir, so that the result can be
+// modified by a caller without affecting the others.
+//
+// Every write operation passed through the CoalesceFS, including writes to a File opened through it, ensures that
+// reads that start after the write completes are not coalesced with reads that started before it, so that a read never
+// returns a result that is older than a write that completed before the read started.
+type CoalesceFS struct {
+	backing fs.FS
+	epoch   atomic.Uint64
+	group   singleflight.Group
+	logger  fs.Logger
+}
+
+// New creates a new CoalesceFS that coalesces the concurrent reads performed on backing.
+func New(backing fs.FS, options ...func(*CoalesceFS)) (*CoalesceFS, error) {
+	if backing == nil {
+		return nil, errors.New("coalescefs: backing file system is required")
+	}
+
+	c := &CoalesceFS{backing: backing, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(c)
+	}
+
+	c.logger.Debug("[coalescefs] new", "provider", backing.Provider())
+	return c, nil
+}
+
+// Close closes the backing file system.
+func (c *CoalesceFS) Close() error {
+	return c.backing.Close()
+}
+
+// Create ...
+func (c *CoalesceFS) Create(name string) (fs.File, error) {
+	defer c.written()
+	f, err := c.backing.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, cfs: c}, nil
+}
+
+// Glob ...
+func (c *CoalesceFS) Glob(pattern string) ([]string, error) {
+	return c.backing.Glob(pattern)
+}
+
+// Mkdir ...
+func (c *CoalesceFS) Mkdir(name string, perm gofs.FileMode) error {
+	defer c.written()
+	return c.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (c *CoalesceFS) MkdirAll(path string, perm gofs.FileMode) error {
+	defer c.written()
+	return c.backing.MkdirAll(path, perm)
+}
+
+// Open ...
+func (c *CoalesceFS) Open(name string) (gofs.File, error) {
+	return c.backing.Open(name)
+}
+
+// OpenFile opens the named file. Writes to a file opened for writing are treated as write operations of the
+// CoalesceFS.
+func (c *CoalesceFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		return c.backing.OpenFile(name, flag, perm)
+	}
+
+	defer c.written()
+	f, err := c.backing.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, cfs: c}, nil
+}
+
+// PathSeparator ...
+func (c *CoalesceFS) PathSeparator() string {
+	return c.backing.PathSeparator()
+}
+
+// Provider ...
+func (c *CoalesceFS) Provider() string {
+	return c.backing.Provider()
+}
+
+// ReadDir ...
+func (c *CoalesceFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	entries, shared, err := do(c, "readDir", name, func() ([]gofs.DirEntry, error) { return c.backing.ReadDir(name) })
+	if shared {
+		entries = slices.Clone(entries)
+	}
+	return entries, err
+}
+
+// ReadFile ...
+func (c *CoalesceFS) ReadFile(name string) ([]byte, error) {
+	b, shared, err := do(c, "readFile", name, func() ([]byte, error) { return c.backing.ReadFile(name) })
+	if shared {
+		b = slices.Clone(b)
+	}
+	return b, err
+}
+
+// Remove ...
+func (c *CoalesceFS) Remove(name string) error {
+	defer c.written()
+	return c.backing.Remove(name)
+}
+
+// RemoveAll ...
+func (c *CoalesceFS) RemoveAll(path string) error {
+	defer c.written()
+	return c.backing.RemoveAll(path)
+}
+
+// Rename ...
+func (c *CoalesceFS) Rename(oldpath string, newpath string) error {
+	defer c.written()
+	return c.backing.Rename(oldpath, newpath)
+}
+
+// Root ...
+func (c *CoalesceFS) Root() (string, error) {
+	return c.backing.Root()
+}
+
+// Stat ...
+func (c *CoalesceFS) Stat(name string) (gofs.FileInfo, error) {
+	fi, _, err := do(c, "stat", name, func() (gofs.FileInfo, error) { return c.backing.Stat(name) })
+	return fi, err
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot, so that the reads of the sub-tree are coalesced with
+// those of the CoalesceFS.
+func (c *CoalesceFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(c, dir)
+}
+
+// WriteFile ...
+func (c *CoalesceFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	defer c.written()
+	return c.backing.WriteFile(name, data, perm)
+}
+
+// written records that a write operation completed, so that the reads that start after it are not coalesced with
+// reads that are in progress.
+func (c *CoalesceFS) written() {
+	c.epoch.Add(1)
+}
+
+// do performs op for the read operation named name on path, unless the same read is already in progress, in which case
+// it waits for that read and returns its result. It also reports whether the result was returned to more than one
+// caller.
+func do[T any](c *CoalesceFS, name string, path string, op func() (T, error)) (T, bool, error) {
+	key := name + "\x00" + strconv.FormatUint(c.epoch.Load(), 10) + "\x00" + path
+
+	v, err, shared := c.group.Do(key, func() (any, error) {
+		return op()
+	})
+
+	result, _ := v.(T)
+	return result, shared, err
+}
+
+// WithLogger sets the Logger used by a CoalesceFS to log its operation. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*CoalesceFS) {
+	return func(c *CoalesceFS) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
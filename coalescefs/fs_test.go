@@ -0,0 +1,152 @@
+package coalescefs
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// blockingFS counts the reads of its backing file system, and blocks them until release is closed.
+type blockingFS struct {
+	fs.FS
+	reads   atomic.Int64
+	release chan struct{}
+}
+
+func (b *blockingFS) ReadFile(name string) ([]byte, error) {
+	b.reads.Add(1)
+	<-b.release
+	return b.FS.ReadFile(name)
+}
+
+func (b *blockingFS) Stat(name string) (gofs.FileInfo, error) {
+	b.reads.Add(1)
+	<-b.release
+	return b.FS.Stat(name)
+}
+
+// CoalesceFSTestSuite ...
+type CoalesceFSTestSuite struct {
+	suite.Suite
+	backing *blockingFS
+	cfs     *CoalesceFS
+}
+
+func NewCoalesceFSTestSuite() *CoalesceFSTestSuite {
+	return &CoalesceFSTestSuite{}
+}
+
+func (t *CoalesceFSTestSuite) SetupTest() {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = &blockingFS{FS: mfs, release: make(chan struct{})}
+
+	cfs, err := New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.cfs = cfs
+}
+
+func TestCoalesceFSTestSuite(t *testing.T) {
+	suite.Run(t, NewCoalesceFSTestSuite())
+}
+
+func (t *CoalesceFSTestSuite) TestFS() {
+	close(t.backing.release)
+	assert.NoError(t.T(), fstest.TestFS(t.cfs, "doc/fox.txt"))
+}
+
+func (t *CoalesceFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	_, err := New(t.backing, WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[coalescefs] new" provider=`+t.backing.Provider())
+
+	assert.Equal(t.T(), fs.NopLogger(), t.cfs.logger)
+}
+
+func (t *CoalesceFSTestSuite) TestReadFile() {
+	const readers = 8
+
+	var (
+		results = make([][]byte, readers)
+		started sync.WaitGroup
+		wg      sync.WaitGroup
+	)
+
+	started.Add(readers)
+	wg.Add(readers)
+	for i := range readers {
+		go func() {
+			defer wg.Done()
+			started.Done()
+
+			b, err := t.cfs.ReadFile("doc/fox.txt")
+			assert.NoError(t.T(), err)
+			results[i] = b
+		}()
+	}
+
+	// The readers that have started are given time to join the read in progress before it completes.
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(t.backing.release)
+	wg.Wait()
+
+	assert.Equal(t.T(), int64(1), t.backing.reads.Load())
+	for _, b := range results {
+		assert.Equal(t.T(), "the quick brown fox", string(b))
+	}
+
+	// Each reader receives its own copy of the content.
+	results[0][0] = 'T'
+	assert.Equal(t.T(), "the quick brown fox", string(results[1]))
+}
+
+func (t *CoalesceFSTestSuite) TestWrite() {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := t.cfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+	}()
+	assert.Eventually(t.T(), func() bool { return t.backing.reads.Load() == 1 }, time.Second, time.Millisecond)
+
+	// A read that starts after a write completes is not coalesced with the read in progress.
+	assert.NoError(t.T(), t.cfs.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0644))
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		fi, err := t.cfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), int64(25), fi.Size())
+	}()
+	assert.Eventually(t.T(), func() bool { return t.backing.reads.Load() == 2 }, time.Second, time.Millisecond)
+
+	close(t.backing.release)
+	wg.Wait()
+}
@@ -0,0 +1,243 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	gofs "io/fs"
+)
+
+var (
+	_ FS   = (*contextFS)(nil)
+	_ File = (*contextFile)(nil)
+)
+
+// ContextFS defines the behavior for binding the operations of a file system to a context, so that operations that
+// take a long time, such as walking a large tree or reading from a remote provider, stop once the context is canceled
+// or its deadline expires. See WithContext.
+type ContextFS interface {
+	FS
+
+	// WithContext returns a view of the file system whose operations return an error wrapping the cause of ctx once ctx
+	// is done. Files opened using the view are bound to ctx for as long as they are open.
+	WithContext(ctx context.Context) FS
+}
+
+// WithContext returns a view of fsys whose operations, and the operations of the files it opens, return an *OpError
+// wrapping the cause of ctx once ctx is done.
+//
+// If fsys implements ContextFS, the view it returns is used, so that operations are stopped by the provider. Otherwise,
+// each operation of the returned view is performed in a separate goroutine, which the view stops waiting for once ctx
+// is done. An operation that is no longer waited for still completes in the background and may still take effect, and
+// a file that it opened is closed. Since a File whose operation was not waited for may have been left at an unknown
+// offset, it should only be closed.
+func WithContext(ctx context.Context, fsys FS) FS {
+	if c, ok := fsys.(ContextFS); ok {
+		return c.WithContext(ctx)
+	}
+	return &contextFS{FS: fsys, ctx: ctx}
+}
+
+// contextFS binds the operations of a file system that does not implement ContextFS to a context.
+type contextFS struct {
+	FS
+	ctx context.Context
+}
+
+func (c *contextFS) Create(name string) (File, error) {
+	f, err := await(c.ctx, c.FS, "create", name, func() (File, error) { return c.FS.Create(name) }, closeFile)
+	if err != nil {
+		return nil, err
+	}
+	return &contextFile{File: f, ctx: c.ctx, fsys: c.FS, name: name}, nil
+}
+
+func (c *contextFS) Glob(pattern string) ([]string, error) {
+	return await(c.ctx, c.FS, "glob", pattern, func() ([]string, error) { return c.FS.Glob(pattern) }, nil)
+}
+
+func (c *contextFS) Mkdir(name string, perm gofs.FileMode) error {
+	return awaitErr(c.ctx, c.FS, "mkdir", name, func() error { return c.FS.Mkdir(name, perm) })
+}
+
+func (c *contextFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return awaitErr(c.ctx, c.FS, "mkdirAll", path, func() error { return c.FS.MkdirAll(path, perm) })
+}
+
+func (c *contextFS) Open(name string) (gofs.File, error) {
+	f, err := await(c.ctx, c.FS, "open", name, func() (gofs.File, error) { return c.FS.Open(name) }, closeFile[gofs.File])
+	if err != nil {
+		return nil, err
+	}
+
+	if cf, ok := f.(File); ok {
+		return &contextFile{File: cf, ctx: c.ctx, fsys: c.FS, name: name}, nil
+	}
+	return f, nil
+}
+
+func (c *contextFS) OpenFile(name string, flag int, perm gofs.FileMode) (File, error) {
+	f, err := await(c.ctx, c.FS, "open", name, func() (File, error) { return c.FS.OpenFile(name, flag, perm) }, closeFile)
+	if err != nil {
+		return nil, err
+	}
+	return &contextFile{File: f, ctx: c.ctx, fsys: c.FS, name: name}, nil
+}
+
+func (c *contextFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return await(c.ctx, c.FS, "readDir", name, func() ([]gofs.DirEntry, error) { return c.FS.ReadDir(name) }, nil)
+}
+
+func (c *contextFS) ReadFile(name string) ([]byte, error) {
+	return await(c.ctx, c.FS, "readFile", name, func() ([]byte, error) { return c.FS.ReadFile(name) }, nil)
+}
+
+func (c *contextFS) Remove(name string) error {
+	return awaitErr(c.ctx, c.FS, "remove", name, func() error { return c.FS.Remove(name) })
+}
+
+func (c *contextFS) RemoveAll(path string) error {
+	return awaitErr(c.ctx, c.FS, "removeAll", path, func() error { return c.FS.RemoveAll(path) })
+}
+
+func (c *contextFS) Rename(oldpath string, newpath string) error {
+	return awaitErr(c.ctx, c.FS, "rename", oldpath, func() error { return c.FS.Rename(oldpath, newpath) })
+}
+
+func (c *contextFS) Stat(name string) (gofs.FileInfo, error) {
+	return await(c.ctx, c.FS, "stat", name, func() (gofs.FileInfo, error) { return c.FS.Stat(name) }, nil)
+}
+
+// Sub returns the sub-tree for dir from the file system, bound to the context of the view if it implements FS.
+func (c *contextFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := await(c.ctx, c.FS, "sub", dir, func() (gofs.FS, error) { return c.FS.Sub(dir) }, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(FS); ok {
+		return WithContext(c.ctx, fsys), nil
+	}
+	return sub, nil
+}
+
+// WriteFile writes a copy of data, so that data can be modified once WriteFile returns, even if the write is still in
+// progress.
+func (c *contextFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	data = bytes.Clone(data)
+	return awaitErr(c.ctx, c.FS, "writeFile", name, func() error { return c.FS.WriteFile(name, data, perm) })
+}
+
+// contextFile binds the operations of a File opened by a contextFS to its context. Reads and writes use a copy of the
+// buffer of the caller while the context can be canceled, so that a read or write that is not waited for does not
+// access the buffer once it is returned to the caller.
+type contextFile struct {
+	File
+	ctx  context.Context
+	fsys FS
+	name string
+}
+
+func (f *contextFile) Read(b []byte) (int, error) {
+	if f.ctx.Done() == nil {
+		return f.File.Read(b)
+	}
+
+	buf := make([]byte, len(b))
+	n, err := await(f.ctx, f.fsys, "read", f.name, func() (int, error) { return f.File.Read(buf) }, nil)
+	copy(b, buf[:n])
+	return n, err
+}
+
+func (f *contextFile) ReadAt(b []byte, off int64) (int, error) {
+	if f.ctx.Done() == nil {
+		return f.File.ReadAt(b, off)
+	}
+
+	buf := make([]byte, len(b))
+	n, err := await(f.ctx, f.fsys, "readAt", f.name, func() (int, error) { return f.File.ReadAt(buf, off) }, nil)
+	copy(b, buf[:n])
+	return n, err
+}
+
+func (f *contextFile) ReadDir(n int) ([]gofs.DirEntry, error) {
+	return await(f.ctx, f.fsys, "readDir", f.name, func() ([]gofs.DirEntry, error) { return f.File.ReadDir(n) }, nil)
+}
+
+// ReadFrom copies from r using Write, so that the copy stops once the context is done.
+func (f *contextFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *contextFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, NewOpError(f.fsys.Provider(), "seek", f.name, context.Cause(f.ctx))
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *contextFile) Stat() (gofs.FileInfo, error) {
+	return await(f.ctx, f.fsys, "stat", f.name, f.File.Stat, nil)
+}
+
+func (f *contextFile) Write(b []byte) (int, error) {
+	if f.ctx.Done() == nil {
+		return f.File.Write(b)
+	}
+
+	buf := bytes.Clone(b)
+	return await(f.ctx, f.fsys, "write", f.name, func() (int, error) { return f.File.Write(buf) }, nil)
+}
+
+// await performs fn for the operation named op on the named file in a separate goroutine, and returns its result, or
+// an *OpError wrapping the cause of ctx if ctx is done first. The result of an operation that is not waited for is
+// passed to discard once it completes, if it succeeded and discard is not nil.
+func await[T any](ctx context.Context, fsys FS, op string, name string, fn func() (T, error), discard func(T)) (T, error) {
+	var zero T
+	if ctx.Err() != nil {
+		return zero, NewOpError(fsys.Provider(), op, name, context.Cause(ctx))
+	}
+
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v: v, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		if discard != nil {
+			go func() {
+				if r := <-done; r.err == nil {
+					discard(r.v)
+				}
+			}()
+		}
+		return zero, NewOpError(fsys.Provider(), op, name, context.Cause(ctx))
+	}
+}
+
+// awaitErr performs fn in the same way as await for operations that only return an error.
+func awaitErr(ctx context.Context, fsys FS, op string, name string, fn func() error) error {
+	_, err := await(ctx, fsys, op, name, func() (struct{}, error) { return struct{}{}, fn() }, nil)
+	return err
+}
+
+// closeFile closes a file opened by an operation that was not waited for.
+func closeFile[F io.Closer](f F) {
+	if err := f.Close(); err != nil {
+		log().Warn("[fs:context] close", "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingFS blocks ReadFile until release is closed.
+type blockingFS struct {
+	fs.FS
+	release chan struct{}
+}
+
+func (b *blockingFS) ReadFile(name string) ([]byte, error) {
+	<-b.release
+	return b.FS.ReadFile(name)
+}
+
+// boundFS implements fs.ContextFS by recording the context of its views.
+type boundFS struct {
+	fs.FS
+	ctx context.Context
+}
+
+func (b *boundFS) WithContext(ctx context.Context) fs.FS {
+	return &boundFS{FS: b.FS, ctx: ctx}
+}
+
+func TestWithContext(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backing := &blockingFS{FS: mfs, release: make(chan struct{})}
+	defer close(backing.release)
+
+	errStop := errors.New("stopped")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	fsys := fs.WithContext(ctx, backing)
+
+	// Operations that do not block complete while the context is not done.
+	fi, err := fsys.Stat("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(19), fi.Size())
+
+	f, err := fsys.Open("doc/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An operation in progress is no longer waited for once the context is done.
+	time.AfterFunc(10*time.Millisecond, func() { cancel(errStop) })
+	_, err = fsys.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t, err, errStop)
+
+	var oe *fs.OpError
+	if assert.ErrorAs(t, err, &oe) {
+		assert.Equal(t, "readFile", oe.Op)
+		assert.Equal(t, "doc/fox.txt", oe.Path)
+	}
+
+	_, err = fsys.ReadDir("doc")
+	assert.ErrorIs(t, err, errStop)
+
+	_, err = f.Read(make([]byte, 8))
+	assert.ErrorIs(t, err, errStop)
+	assert.NoError(t, f.Close())
+
+	// The view of a file system that implements fs.ContextFS is used.
+	bound := fs.WithContext(ctx, &boundFS{FS: mfs})
+	if assert.IsType(t, &boundFS{}, bound) {
+		assert.Equal(t, ctx, bound.(*boundFS).ctx)
+	}
+}
@@ -0,0 +1,666 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// maxSymlinkDepth is the maximum number of symbolic links to directories that are followed within a single branch of a
+// tree copied by CopyAll.
+const maxSymlinkDepth = 40
+
+// Enumeration of policies for copying over an existing file.
+const (
+	// OverwriteNever rejects the copy with an error wrapping gofs.ErrExist if the destination exists.
+	OverwriteNever OverwritePolicy = iota
+
+	// OverwriteAlways replaces the content of the destination if it exists.
+	OverwriteAlways
+
+	// OverwriteSkip leaves the destination unchanged if it exists, and does not return an error.
+	OverwriteSkip
+
+	// OverwriteIfNewer replaces the content of the destination only if the source was modified after the destination.
+	OverwriteIfNewer
+)
+
+// Enumeration of policies for copying symbolic links.
+const (
+	// SymlinkSkip does not copy symbolic links.
+	SymlinkSkip SymlinkPolicy = iota
+
+	// SymlinkFollow copies the file or directory that a symbolic link refers to.
+	SymlinkFollow
+
+	// SymlinkError rejects the copy with an error wrapping ErrInvalidEntryType if a symbolic link is encountered.
+	SymlinkError
+)
+
+// errCopyStopped is returned while walking a tree copied by CopyAll once a file could not be copied, to stop the walk.
+var errCopyStopped = errors.New("fs: copy stopped")
+
+// CopyError records a file that could not be copied by CopyAll.
+type CopyError struct {
+	// Path is the path of the file in the source file system.
+	Path string
+
+	// Attempts is the number of times the copy was attempted.
+	Attempts int
+
+	// Err is the error returned by the last attempt.
+	Err error
+}
+
+// Error returns the cause of the copy error.
+func (e *CopyError) Error() string {
+	return fmt.Sprintf("copy %s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the error returned by the last attempt.
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// CopyErrors lists the files that could not be copied by CopyAll, sorted by the path of the file in the source file
+// system.
+type CopyErrors []*CopyError
+
+// Error returns the causes of the copy errors, one per line.
+func (e CopyErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the copy errors, so that errors.Is and errors.As match the error for any of the files.
+func (e CopyErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// OverwritePolicy defines how a copy is performed when the destination already exists.
+type OverwritePolicy int
+
+// SymlinkPolicy defines how symbolic links are handled by CopyAll.
+type SymlinkPolicy int
+
+// CopyProgress describes the progress of CopyAll, and is provided to the callback set using WithCopyProgress after
+// each entry is copied.
+type CopyProgress struct {
+	// Path is the path of the entry in the source file system.
+	Path string
+
+	// Dir reports whether the entry is a directory.
+	Dir bool
+
+	// Files is the number of files copied so far, including files left unchanged according to the overwrite policy.
+	Files int
+
+	// Bytes is the number of bytes copied so far.
+	Bytes int64
+}
+
+// ChmodFS is implemented by file systems that support changing the mode of a file.
+type ChmodFS interface {
+	FS
+
+	// Chmod changes the mode of the named file to mode.
+	Chmod(name string, mode gofs.FileMode) error
+}
+
+// ChtimesFS is implemented by file systems that support changing the access and modification times of a file.
+type ChtimesFS interface {
+	FS
+
+	// Chtimes changes the access and modification times of the named file.
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// CopyFS is implemented by file systems that can copy a file within the file system without streaming its content
+// through the caller, for example by cloning the file using a reflink or by performing a server-side copy.
+//
+// CopyFile and CopyAll use CopyFS automatically when the source and destination are the same file system, so that
+// object-store providers can copy objects without downloading and uploading their content.
+type CopyFS interface {
+	FS
+
+	// CopyFile copies the content of the file src to the file dst, creating or truncating dst as needed. An error
+	// wrapping errors.ErrUnsupported is returned if the copy cannot be performed without streaming the content.
+	CopyFile(dst string, src string) error
+}
+
+// CopyOption defines an option for CopyFile.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	bytesProgress   func(int64)
+	checkpoint      int64
+	continueOnError bool
+	mimeDetection   bool
+	overwrite       OverwritePolicy
+	preserve        bool
+	progress        func(CopyProgress)
+	retries         int
+	retryBackoff    time.Duration
+	symlinks        SymlinkPolicy
+	workers         int
+}
+
+// CopyFile copies the file srcPath in src to dstPath in dst.
+//
+// If src and dst are the same file system and it implements CopyFS, the copy is delegated to the file system.
+// Otherwise, the content is streamed from src to dst using the io.ReaderFrom implemented by the destination File. The
+// destination is created with the permissions of the source, and parent directories of dstPath must exist.
+//
+// By default, an error wrapping gofs.ErrExist is returned if dstPath exists. This can be changed using WithOverwrite.
+func CopyFile(dst FS, dstPath string, src gofs.FS, srcPath string, options ...CopyOption) error {
+	if dst == nil || src == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &copyOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	fi, err := gofs.Stat(src, srcPath)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: srcPath, Err: ErrIsDir})
+	}
+
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: srcPath, Err: ErrNotFile})
+	}
+
+	exists := false
+	if dfi, err := dst.Stat(dstPath); err == nil {
+		if dfi.IsDir() {
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath, Err: ErrIsDir})
+		}
+
+		switch opts.overwrite {
+		case OverwriteNever:
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath, Err: gofs.ErrExist})
+		case OverwriteSkip:
+			return nil
+		case OverwriteIfNewer:
+			if !fi.ModTime().After(dfi.ModTime()) {
+				return nil
+			}
+		}
+		exists = true
+	} else if !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	if c, ok := dst.(CopyFS); ok && sameFS(dst, src) {
+		err := c.CopyFile(dstPath, srcPath)
+		if err == nil {
+			if opts.bytesProgress != nil {
+				opts.bytesProgress(fi.Size())
+			}
+
+			if err := detectMimeType(dst, dstPath, src, srcPath, opts); err != nil {
+				return err
+			}
+			return preserveAttributes(dst, dstPath, fi, opts)
+		}
+
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+
+	if err := copyContent(dst, dstPath, src, srcPath, fi.Mode().Perm(), opts.bytesProgress); err != nil {
+		if !exists {
+			_ = dst.Remove(dstPath)
+		}
+		return err
+	}
+
+	if err := detectMimeType(dst, dstPath, src, srcPath, opts); err != nil {
+		return err
+	}
+	return preserveAttributes(dst, dstPath, fi, opts)
+}
+
+// CopyAll copies the tree rooted at srcRoot in src to dstRoot in dst.
+//
+// Directories are created in dst as needed, including dstRoot and its parents, and files are copied using CopyFile, so
+// the overwrite policy set using WithOverwrite applies to each file. If srcRoot is a file, it is copied to dstRoot.
+// Symbolic links are skipped unless a different policy is set using WithSymlinks, and if attributes are preserved,
+// the attributes of directories are applied once their content has been copied.
+//
+// Files are copied one at a time unless WithWorkers is used, and a file that could not be copied is retried according
+// to the policy set using WithRetry. If a file still could not be copied, the copy stops, unless WithContinueOnError
+// is used, and the files that could not be copied are returned as CopyErrors once the files being copied are complete.
+// Other errors, such as an error creating a directory, are returned as is.
+func CopyAll(dst FS, dstRoot string, src gofs.FS, srcRoot string, options ...CopyOption) error {
+	if dst == nil || src == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &copyOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	fi, err := gofs.Stat(src, srcRoot)
+	if err != nil {
+		return err
+	}
+
+	c := &treeCopy{dst: dst, opts: opts, options: options, src: src}
+	if opts.workers > 1 {
+		c.sem = make(chan struct{}, opts.workers)
+	}
+
+	if !fi.IsDir() {
+		if dir := gopath.Dir(dstRoot); dir != "." && dir != "/" {
+			if err := dst.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		return c.result(c.copyFile(dstRoot, srcRoot, fi))
+	}
+
+	if err := dst.MkdirAll(dstRoot, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return c.result(c.copyTree(dstRoot, srcRoot, 0))
+}
+
+// WithContinueOnError sets whether CopyAll continues copying the remaining files once a file could not be copied. The
+// files that could not be copied are returned as CopyErrors once the copy is complete. The default is false.
+func WithContinueOnError(continueOnError bool) CopyOption {
+	return func(o *copyOptions) {
+		o.continueOnError = continueOnError
+	}
+}
+
+// WithCopyProgress sets a callback that is called by CopyAll after each file or directory is copied.
+func WithCopyProgress(fn func(CopyProgress)) CopyOption {
+	return func(o *copyOptions) {
+		o.progress = fn
+	}
+}
+
+// WithSymlinks sets the policy for copying symbolic links using CopyAll. The default is SymlinkSkip.
+func WithSymlinks(policy SymlinkPolicy) CopyOption {
+	return func(o *copyOptions) {
+		o.symlinks = policy
+	}
+}
+
+// WithOverwrite sets the policy for copying over an existing file. The default is OverwriteNever.
+func WithOverwrite(policy OverwritePolicy) CopyOption {
+	return func(o *copyOptions) {
+		o.overwrite = policy
+	}
+}
+
+// WithRetry sets the number of times CopyAll retries copying a file that could not be copied, waiting for backoff
+// before the first retry and doubling the wait before each subsequent retry. Errors that are not transient, such as
+// errors wrapping gofs.ErrExist, gofs.ErrNotExist, gofs.ErrPermission, ErrIsDir, or ErrQuotaExceeded, are not retried.
+// By default, files are not retried.
+func WithRetry(retries int, backoff time.Duration) CopyOption {
+	return func(o *copyOptions) {
+		o.retries = max(retries, 0)
+		o.retryBackoff = max(backoff, 0)
+	}
+}
+
+// WithWorkers sets the maximum number of files copied concurrently by CopyAll, so that copies of many small files to
+// file systems with a high latency per request are not dominated by round trips. Directories are still created in the
+// order they are visited, before the files they contain are copied. Callbacks set using WithProgress and
+// WithCopyProgress are never called concurrently, but files may be reported in any order. The default is 1.
+func WithWorkers(n int) CopyOption {
+	return func(o *copyOptions) {
+		o.workers = max(n, 1)
+	}
+}
+
+// WithPreserveAttributes sets whether the mode and modification time of the source are applied to the destination.
+// Attributes are only preserved if the destination file system implements ChmodFS and ChtimesFS respectively.
+func WithPreserveAttributes(preserve bool) CopyOption {
+	return func(o *copyOptions) {
+		o.preserve = preserve
+	}
+}
+
+// treeCopy holds the state of a tree copied by CopyAll.
+type treeCopy struct {
+	bytes       int64
+	dirs        []treeDir
+	dst         FS
+	errs        CopyErrors
+	files       int
+	mutex       sync.Mutex
+	options     []CopyOption
+	opts        *copyOptions
+	sem         chan struct{}
+	src         gofs.FS
+	stopped     atomic.Bool
+	transferred int64
+	wg          sync.WaitGroup
+}
+
+// treeDir is a directory created by CopyAll, whose attributes are applied once the copy is complete.
+type treeDir struct {
+	dst  string
+	info gofs.FileInfo
+}
+
+// copyFile copies the file srcPath to dstPath, using a worker if WithWorkers is used.
+func (c *treeCopy) copyFile(dstPath string, srcPath string, fi gofs.FileInfo) error {
+	if c.sem == nil {
+		attempts, err := c.retry(dstPath, srcPath, fi)
+		return c.fail(srcPath, attempts, err)
+	}
+
+	c.sem <- struct{}{}
+	if c.stopped.Load() {
+		<-c.sem
+		return errCopyStopped
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() { <-c.sem }()
+
+		attempts, err := c.retry(dstPath, srcPath, fi)
+		_ = c.fail(srcPath, attempts, err)
+	}()
+	return nil
+}
+
+// copyFileOnce performs a single attempt to copy the file srcPath to dstPath.
+func (c *treeCopy) copyFileOnce(dstPath string, srcPath string, fi gofs.FileInfo) error {
+	var reported int64
+	options := c.options
+	if c.opts.bytesProgress != nil {
+		options = append(options[:len(options):len(options)], WithProgress(func(n int64) {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			c.transferred += n - reported
+			reported = n
+			c.opts.bytesProgress(c.transferred)
+		}))
+	}
+
+	err := CopyFile(c.dst, dstPath, c.src, srcPath, options...)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err != nil {
+		c.transferred -= reported
+		return err
+	}
+
+	c.transferred += fi.Size() - reported
+	c.files++
+	c.bytes += fi.Size()
+	c.report(srcPath, false)
+	return nil
+}
+
+func (c *treeCopy) copyTree(dstRoot string, srcRoot string, depth int) error {
+	return gofs.WalkDir(c.src, srcRoot, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dstPath := joinPath(c.dst, dstRoot, relPath(srcRoot, p))
+		if d.Type()&gofs.ModeSymlink != 0 {
+			return c.copySymlink(dstPath, p, depth)
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return c.copyFile(dstPath, p, fi)
+		}
+
+		if p != srcRoot {
+			if err := c.mkdir(dstPath, fi.Mode().Perm()); err != nil {
+				return err
+			}
+
+			c.mutex.Lock()
+			c.report(p, true)
+			c.mutex.Unlock()
+		}
+		c.dirs = append(c.dirs, treeDir{dst: dstPath, info: fi})
+		return nil
+	})
+}
+
+func (c *treeCopy) copySymlink(dstPath string, srcPath string, depth int) error {
+	switch c.opts.symlinks {
+	case SymlinkSkip:
+		return nil
+	case SymlinkError:
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: srcPath, Err: ErrInvalidEntryType})
+	}
+
+	fi, err := gofs.Stat(c.src, srcPath)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return c.copyFile(dstPath, srcPath, fi)
+	}
+
+	if depth >= maxSymlinkDepth {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: srcPath, Err: ErrInvalidEntryType})
+	}
+
+	if err := c.mkdir(dstPath, fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.report(srcPath, true)
+	c.mutex.Unlock()
+	return c.copyTree(dstPath, srcPath, depth+1)
+}
+
+// fail records that the file srcPath could not be copied, if err is not nil, and returns errCopyStopped unless
+// WithContinueOnError is used.
+func (c *treeCopy) fail(srcPath string, attempts int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	c.errs = append(c.errs, &CopyError{Path: srcPath, Attempts: attempts, Err: err})
+	c.mutex.Unlock()
+
+	if c.opts.continueOnError {
+		return nil
+	}
+	c.stopped.Store(true)
+	return errCopyStopped
+}
+
+// mkdir creates the directory dstPath, if it does not already exist.
+func (c *treeCopy) mkdir(dstPath string, perm gofs.FileMode) error {
+	err := c.dst.Mkdir(dstPath, perm)
+	if err == nil || !errors.Is(err, gofs.ErrExist) {
+		return err
+	}
+
+	fi, err := c.dst.Stat(dstPath)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath, Err: ErrNotDir})
+	}
+	return nil
+}
+
+// report calls the callback set using WithCopyProgress. The caller must hold the mutex.
+func (c *treeCopy) report(srcPath string, dir bool) {
+	if c.opts.progress != nil {
+		c.opts.progress(CopyProgress{Path: srcPath, Dir: dir, Files: c.files, Bytes: c.bytes})
+	}
+}
+
+// result waits for the files being copied, applies the attributes of the directories that were created, and returns
+// the result of the copy given the error returned by walking the tree.
+func (c *treeCopy) result(err error) error {
+	c.wg.Wait()
+	if err != nil && !errors.Is(err, errCopyStopped) {
+		return err
+	}
+
+	if len(c.errs) > 0 {
+		slices.SortStableFunc(c.errs, func(a *CopyError, b *CopyError) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		return c.errs
+	}
+
+	// Directories are visited before their content, so attributes are applied in reverse order, once the content of
+	// each directory has been copied.
+	for i := len(c.dirs) - 1; i >= 0; i-- {
+		if err := preserveAttributes(c.dst, c.dirs[i].dst, c.dirs[i].info, c.opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retry copies the file srcPath to dstPath, retrying according to the policy set using WithRetry, and returns the
+// number of attempts with the error returned by the last attempt.
+func (c *treeCopy) retry(dstPath string, srcPath string, fi gofs.FileInfo) (int, error) {
+	backoff := c.opts.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.copyFileOnce(dstPath, srcPath, fi)
+		if err == nil || attempt > c.opts.retries || !retryable(err) || c.stopped.Load() {
+			return attempt, err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func copyContent(dst FS, dstPath string, src gofs.FS, srcPath string, perm gofs.FileMode, progress func(int64)) error {
+	f, err := src.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if progress != nil {
+		r = NewProgressReader(f, progress)
+	}
+
+	w, err := dst.OpenFile(dstPath, O_WRONLY|O_CREATE|O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.ReadFrom(r); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func preserveAttributes(dst FS, dstPath string, fi gofs.FileInfo, opts *copyOptions) error {
+	if !opts.preserve {
+		return nil
+	}
+
+	if c, ok := dst.(ChmodFS); ok {
+		if err := c.Chmod(dstPath, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := dst.(ChtimesFS); ok {
+		if err := c.Chtimes(dstPath, fi.ModTime(), fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinPath joins elem into a single path using the path separator of fsys.
+func joinPath(fsys FS, elem ...string) string {
+	p := gopath.Join(elem...)
+	if sep := fsys.PathSeparator(); sep != "" && sep != "/" {
+		p = strings.ReplaceAll(p, "/", sep)
+	}
+	return p
+}
+
+// relPath returns the path of p, which is within root, relative to root.
+func relPath(root string, p string) string {
+	switch {
+	case p == root:
+		return ""
+	case root == ".":
+		return p
+	}
+	return strings.TrimPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
+
+// retryable reports whether a copy that failed with err may succeed if it is retried.
+func retryable(err error) bool {
+	for _, target := range []error{
+		gofs.ErrExist,
+		gofs.ErrInvalid,
+		gofs.ErrNotExist,
+		gofs.ErrPermission,
+		ErrInvalidEntryType,
+		ErrIsDir,
+		ErrNotDir,
+		ErrNotFile,
+		ErrQuotaExceeded,
+		ErrTooLarge,
+	} {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameFS reports whether dst and src refer to the same file system.
+func sameFS(dst FS, src gofs.FS) bool {
+	if reflect.TypeOf(dst) != reflect.TypeOf(src) || !reflect.TypeOf(dst).Comparable() {
+		return false
+	}
+	return any(dst) == any(src)
+}
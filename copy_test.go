@@ -0,0 +1,312 @@
+package fs_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestCopyFile(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dst.MkdirAll("copy", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, fs.CopyFile(dst, "copy/fox.txt", src, "doc/fox.txt"))
+
+	b, err := dst.ReadFile("copy/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	assert.ErrorIs(t, fs.CopyFile(dst, "copy/fox.txt", src, "doc/fox.txt"), gofs.ErrExist)
+	assert.ErrorIs(t, fs.CopyFile(dst, "copy/dir", src, "doc"), fs.ErrIsDir)
+	assert.ErrorIs(t, fs.CopyFile(dst, "copy/missing.txt", src, "doc/missing.txt"), gofs.ErrNotExist)
+	assert.ErrorIs(t, fs.CopyFile(dst, "copy", src, "doc/fox.txt", fs.WithOverwrite(fs.OverwriteAlways)), fs.ErrIsDir)
+
+	if err := dst.WriteFile("copy/dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, fs.CopyFile(dst, "copy/dog.txt", src, "doc/fox.txt", fs.WithOverwrite(fs.OverwriteSkip)))
+	fi, err := dst.Stat("copy/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(23), fi.Size())
+
+	assert.NoError(t, fs.CopyFile(dst, "copy/dog.txt", src, "doc/fox.txt", fs.WithOverwrite(fs.OverwriteIfNewer)))
+	fi, err = dst.Stat("copy/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(23), fi.Size())
+
+	assert.NoError(t, fs.CopyFile(dst, "copy/dog.txt", src, "doc/fox.txt", fs.WithOverwrite(fs.OverwriteAlways)))
+	b, err = dst.ReadFile("copy/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+}
+
+func TestCopyFileOS(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fox.txt")
+	if err := os.WriteFile(src, []byte("the quick brown fox"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "copy.txt")
+	assert.NoError(t, fs.CopyFile(osfs, dst, osfs, src, fs.WithPreserveAttributes(true)))
+
+	b, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	fi, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0600), fi.Mode().Perm())
+	assert.True(t, mtime.Equal(fi.ModTime()))
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst = filepath.Join(dir, "dog.txt")
+	assert.NoError(t, fs.CopyFile(osfs, dst, mfs, "dog.txt"))
+
+	b, err = os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "jumps over the lazy dog", string(b))
+}
+
+func TestCopyAll(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"doc/fox.txt":       "the quick brown fox",
+		"doc/animals/dog":   "jumps over the lazy dog",
+		"doc/animals/cat":   "the cat sat",
+		"doc/.hidden/notes": "notes",
+	}
+	for name, content := range files {
+		if err := src.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var progress []fs.CopyProgress
+	assert.NoError(t, fs.CopyAll(dst, "backup/doc", src, "doc", fs.WithCopyProgress(func(p fs.CopyProgress) {
+		progress = append(progress, p)
+	})))
+
+	for name, content := range files {
+		b, err := dst.ReadFile("backup/" + name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+
+	assert.Len(t, progress, 6)
+	assert.Equal(t, 4, progress[len(progress)-1].Files)
+	assert.Equal(t, int64(58), progress[len(progress)-1].Bytes)
+
+	assert.ErrorIs(t, fs.CopyAll(dst, "backup/doc", src, "doc"), gofs.ErrExist)
+	assert.NoError(t, fs.CopyAll(dst, "backup/doc", src, "doc", fs.WithOverwrite(fs.OverwriteSkip)))
+
+	assert.NoError(t, fs.CopyAll(dst, "single/fox.txt", src, "doc/fox.txt"))
+	b, err := dst.ReadFile("single/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	assert.ErrorIs(t, fs.CopyAll(dst, "backup", src, "missing"), gofs.ErrNotExist)
+}
+
+func TestCopyAllSymlinks(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	srcRoot := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(srcRoot, "doc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(srcRoot, "doc", "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("doc", filepath.Join(srcRoot, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, fs.CopyAll(mfs, "skip", osfs, srcRoot))
+	_, err = mfs.Stat("skip/link")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	assert.NoError(t, fs.CopyAll(mfs, "follow", osfs, srcRoot, fs.WithSymlinks(fs.SymlinkFollow)))
+	b, err := mfs.ReadFile("follow/link/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	assert.ErrorIs(t, fs.CopyAll(mfs, "error", osfs, srcRoot, fs.WithSymlinks(fs.SymlinkError)), fs.ErrInvalidEntryType)
+
+	dstRoot := filepath.Join(dir, "dst")
+	assert.NoError(t, fs.CopyAll(osfs, dstRoot, osfs, srcRoot, fs.WithPreserveAttributes(true)))
+	b, err = os.ReadFile(filepath.Join(dstRoot, "doc", "fox.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+}
+
+func TestCopyAllWorkers(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 100 {
+		if err := src.WriteFile(fmt.Sprintf("doc/%d/fox-%d.txt", i%10, i), []byte("the quick brown fox"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		bytes    int64
+		progress []fs.CopyProgress
+	)
+	assert.NoError(t, fs.CopyAll(dst, "backup", src, "doc",
+		fs.WithWorkers(8),
+		fs.WithProgress(func(n int64) { bytes = n }),
+		fs.WithCopyProgress(func(p fs.CopyProgress) { progress = append(progress, p) })))
+
+	for i := range 100 {
+		b, err := dst.ReadFile(fmt.Sprintf("backup/%d/fox-%d.txt", i%10, i))
+		assert.NoError(t, err)
+		assert.Equal(t, "the quick brown fox", string(b))
+	}
+
+	assert.Equal(t, int64(1900), bytes)
+	assert.Len(t, progress, 110)
+	assert.Equal(t, 100, progress[len(progress)-1].Files)
+	assert.Equal(t, int64(1900), progress[len(progress)-1].Bytes)
+
+	err = fs.CopyAll(dst, "backup", src, "doc", fs.WithWorkers(8), fs.WithContinueOnError(true))
+	assert.ErrorIs(t, err, gofs.ErrExist)
+
+	var errs fs.CopyErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 100)
+	assert.Equal(t, "doc/0/fox-0.txt", errs[0].Path)
+	assert.Equal(t, 1, errs[0].Attempts)
+}
+
+func TestCopyAllRetry(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"doc/fox.txt", "doc/dog.txt", "doc/cat.txt"} {
+		if err := mfs.WriteFile(name, []byte("the quick brown fox"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := &failingFS{FS: mfs, failures: map[string]int{"doc/fox.txt": 2, "doc/dog.txt": 5}}
+	err = fs.CopyAll(dst, "backup", src, "doc", fs.WithRetry(2, time.Millisecond), fs.WithContinueOnError(true))
+	assert.ErrorIs(t, err, errTransient)
+
+	var errs fs.CopyErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, fs.CopyErrors{{Path: "doc/dog.txt", Attempts: 3, Err: errTransient}}, errs)
+
+	for _, name := range []string{"backup/fox.txt", "backup/cat.txt"} {
+		b, err := dst.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "the quick brown fox", string(b))
+	}
+
+	_, err = dst.Stat("backup/dog.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	src.failures["doc/dog.txt"] = 1
+	err = fs.CopyAll(dst, "backup", src, "doc", fs.WithOverwrite(fs.OverwriteSkip))
+	assert.Equal(t, fs.CopyErrors{{Path: "doc/dog.txt", Attempts: 1, Err: errTransient}}, err)
+}
+
+var errTransient = errors.New("transient error")
+
+// failingFS is a gofs.FS that fails to open each file the number of times recorded in failures.
+type failingFS struct {
+	gofs.FS
+	failures map[string]int
+	mutex    sync.Mutex
+}
+
+func (f *failingFS) Open(name string) (gofs.File, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.failures[name] > 0 {
+		f.failures[name]--
+		return nil, errTransient
+	}
+	return f.FS.Open(name)
+}
@@ -0,0 +1,250 @@
+package cryptfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var (
+	_ fs.File = (*dir)(nil)
+	_ fs.File = (*File)(nil)
+)
+
+// File provides access to the decrypted content of a single file provided by CryptFS.
+//
+// Content is buffered in memory, and is encrypted and written to the backing file system when the File is closed if it
+// was modified.
+type File struct {
+	closed bool
+	data   []byte
+	dirty  bool
+	flag   int
+	flush  func([]byte) error
+	info   gofs.FileInfo
+	mutex  sync.Mutex
+	off    int64
+}
+
+func newFile(info gofs.FileInfo, data []byte, flag int, flush func([]byte) error) *File {
+	return &File{data: data, flag: flag, flush: flush, info: info}
+}
+
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "close", Path: f.info.Name(), Err: gofs.ErrClosed})
+	}
+	f.closed = true
+
+	if f.dirty {
+		if err := f.flush(f.data); err != nil {
+			return fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "close", Path: f.info.Name(), Err: err})
+		}
+	}
+	return nil
+}
+
+func (f *File) Read(b []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.check("read", fs.O_WRONLY); err != nil {
+		return 0, err
+	}
+
+	if f.off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.check("readAt", fs.O_WRONLY); err != nil {
+		return 0, err
+	}
+
+	if off < 0 {
+		return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "readAt", Path: f.info.Name(), Err: errors.New("negative offset")})
+	}
+
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, f.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *File) ReadDir(int) ([]gofs.DirEntry, error) {
+	return nil, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "readDir", Path: f.info.Name(), Err: fs.ErrNotDir})
+}
+
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "readFrom", Path: f.info.Name(), Err: err})
+	}
+
+	n, err := f.Write(b)
+	return int64(n), err
+}
+
+func (f *File) Seek(off int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "seek", Path: f.info.Name(), Err: gofs.ErrClosed})
+	}
+
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = off
+	case io.SeekCurrent:
+		abs = f.off + off
+	case io.SeekEnd:
+		abs = int64(len(f.data)) + off
+	default:
+		return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "seek", Path: f.info.Name(), Err: errors.New("invalid whence")})
+	}
+
+	if abs < 0 {
+		return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "seek", Path: f.info.Name(), Err: errors.New("negative position")})
+	}
+	f.off = abs
+	return abs, nil
+}
+
+func (f *File) Stat() (gofs.FileInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return &fileInfo{FileInfo: f.info, name: f.info.Name(), size: int64(len(f.data))}, nil
+}
+
+func (f *File) Write(b []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.check("write", fs.O_RDONLY); err != nil {
+		return 0, err
+	}
+
+	if f.flag&fs.O_APPEND != 0 {
+		f.off = int64(len(f.data))
+	}
+
+	if end := f.off + int64(len(b)); end > int64(len(f.data)) {
+		if end > int64(fs.MaxContentLen) {
+			return 0, fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: "write", Path: f.info.Name(), Err: fs.ErrTooLarge})
+		}
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+
+	n := copy(f.data[f.off:], b)
+	f.off += int64(n)
+	f.dirty = true
+	return n, nil
+}
+
+func (f *File) check(op string, rejected int) error {
+	if f.closed {
+		return fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: op, Path: f.info.Name(), Err: gofs.ErrClosed})
+	}
+
+	if f.flag&(fs.O_WRONLY|fs.O_RDWR) == rejected {
+		return fmt.Errorf("cryptfs_file: %w", &gofs.PathError{Op: op, Path: f.info.Name(), Err: gofs.ErrPermission})
+	}
+	return nil
+}
+
+// dir provides read access to the decrypted entries for a directory provided by CryptFS.
+type dir struct {
+	closed  bool
+	entries []gofs.DirEntry
+	info    gofs.FileInfo
+	mutex   sync.Mutex
+	off     int
+}
+
+func newDir(info gofs.FileInfo, entries []gofs.DirEntry) *dir {
+	return &dir{entries: entries, info: info}
+}
+
+func (d *dir) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.closed {
+		d.closed = true
+		return nil
+	}
+	return fmt.Errorf("cryptfs_dir: %w", &gofs.PathError{Op: "close", Path: d.info.Name(), Err: gofs.ErrClosed})
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, d.isDir("read")
+}
+
+func (d *dir) ReadAt([]byte, int64) (int, error) {
+	return 0, d.isDir("readAt")
+}
+
+func (d *dir) ReadFrom(io.Reader) (int64, error) {
+	return 0, d.isDir("readFrom")
+}
+
+func (d *dir) ReadDir(n int) ([]gofs.DirEntry, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil, fmt.Errorf("cryptfs_dir: %w", &gofs.PathError{Op: "readDir", Path: d.info.Name(), Err: gofs.ErrClosed})
+	}
+
+	remaining := d.entries[d.off:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(remaining) {
+			remaining = remaining[:n]
+		}
+	}
+	d.off += len(remaining)
+	return append([]gofs.DirEntry(nil), remaining...), nil
+}
+
+func (d *dir) Seek(int64, int) (int64, error) {
+	return 0, d.isDir("seek")
+}
+
+func (d *dir) Stat() (gofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Write([]byte) (int, error) {
+	return 0, d.isDir("write")
+}
+
+func (d *dir) isDir(op string) error {
+	return fmt.Errorf("cryptfs_dir: %w", &gofs.PathError{Op: op, Path: d.info.Name(), Err: fs.ErrIsDir})
+}
@@ -0,0 +1,602 @@
+package cryptfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	// KeySize is the required length in bytes for the key used by a CryptFS.
+	KeySize = 32
+
+	pathSeparator = "/"
+)
+
+var _ fs.FS = (*CryptFS)(nil)
+
+// CryptFS transparent encryption provider that implements fs.FS.
+//
+// File content is encrypted with AES-256-GCM using a random nonce before it is written to the backing file system, and
+// decrypted when read. Each file is stored as nonce || ciphertext || tag, so the content of a file is buffered in
+// memory while it is open. The path of a file is authenticated as additional data, so that the content of a file can
+// not be replaced with the content of another file on the backing file system, and files are re-encrypted by Rename.
+//
+// File and directory names may optionally be encrypted. Name encryption is deterministic per path segment (the nonce
+// is derived from the segment using HMAC-SHA256) so that paths can be resolved without an index, which means equal
+// names encrypt to equal ciphertext.
+type CryptFS struct {
+	backing      fs.FS
+	content      cipher.AEAD
+	encryptNames bool
+	logger       fs.Logger
+	nameKey      []byte
+	names        cipher.AEAD
+	root         string
+}
+
+// New creates a new CryptFS that stores encrypted content on backing. The key must be KeySize bytes, and is used to
+// derive separate keys for file content and file names.
+func New(backing fs.FS, key []byte, options ...func(*CryptFS)) (*CryptFS, error) {
+	if backing == nil {
+		return nil, errors.New("cryptfs: backing file system is required")
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("cryptfs: key must be %d bytes", KeySize)
+	}
+
+	contentKey, err := hkdf.Key(sha256.New, key, nil, "cryptfs content", KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", err)
+	}
+
+	nameKey, err := hkdf.Key(sha256.New, key, nil, "cryptfs names", KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", err)
+	}
+
+	content, err := newAEAD(contentKey)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", err)
+	}
+
+	names, err := newAEAD(nameKey)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", err)
+	}
+
+	c := &CryptFS{backing: backing, content: content, logger: fs.NopLogger(), nameKey: nameKey, names: names}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Close closes the backing file system.
+func (c *CryptFS) Close() error {
+	return c.backing.Close()
+}
+
+// Create ...
+func (c *CryptFS) Create(name string) (fs.File, error) {
+	return c.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob ...
+func (c *CryptFS) Glob(pattern string) ([]string, error) {
+	c.logger.Debug("[cryptfs] glob", "pattern", pattern)
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: "glob", Path: pattern, Err: err})
+	}
+
+	var matches []string
+	err := gofs.WalkDir(c, ".", func(path string, entry gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if matched, _ := filepath.Match(pattern, path); matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return matches, err
+	}
+	return matches, nil
+}
+
+// Mkdir ...
+func (c *CryptFS) Mkdir(name string, perm gofs.FileMode) error {
+	p, err := c.encrypt("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return c.backing.Mkdir(p, perm)
+}
+
+// MkdirAll ...
+func (c *CryptFS) MkdirAll(path string, perm gofs.FileMode) error {
+	p, err := c.encrypt("mkdirAll", path)
+	if err != nil {
+		return err
+	}
+	return c.backing.MkdirAll(p, perm)
+}
+
+// Open opens the named file for reading.
+func (c *CryptFS) Open(name string) (gofs.File, error) {
+	c.logger.Debug("[cryptfs] open", "name", name)
+	return c.OpenFile(name, fs.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file using the provided flags. The decrypted content is buffered in memory, and content
+// written to a File is encrypted and written to the backing file system when the File is closed.
+func (c *CryptFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	c.logger.Debug("[cryptfs] openFile", "name", name, "flag", flag)
+
+	name, err := c.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.encrypt("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := c.stat("openFile", name, p)
+	if err != nil {
+		if !errors.Is(err, gofs.ErrNotExist) || flag&fs.O_CREATE == 0 {
+			return nil, err
+		}
+	}
+
+	if fi != nil && fi.IsDir() {
+		if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_TRUNC) != 0 {
+			return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: "openFile", Path: name, Err: fs.ErrIsDir})
+		}
+
+		entries, err := c.readDir("openFile", name, p)
+		if err != nil {
+			return nil, err
+		}
+		return newDir(fi, entries), nil
+	}
+
+	var data []byte
+	if fi != nil && flag&fs.O_TRUNC == 0 {
+		if data, err = c.readFile("openFile", name, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if fi == nil {
+		if err := c.writeFile(name, p, nil, perm); err != nil {
+			return nil, err
+		}
+
+		if fi, err = c.stat("openFile", name, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return newFile(fi, data, flag, func(b []byte) error {
+		return c.writeFile(name, p, b, fi.Mode().Perm())
+	}), nil
+}
+
+// PathSeparator ...
+func (c *CryptFS) PathSeparator() string {
+	return pathSeparator
+}
+
+// Provider ...
+func (c *CryptFS) Provider() string {
+	return c.backing.Provider()
+}
+
+// ReadDir ...
+func (c *CryptFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	c.logger.Debug("[cryptfs] readDir", "name", name)
+
+	name, err := c.clean("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.encrypt("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+	return c.readDir("readDir", name, p)
+}
+
+// ReadFile ...
+func (c *CryptFS) ReadFile(name string) ([]byte, error) {
+	c.logger.Debug("[cryptfs] readFile", "name", name)
+
+	name, err := c.clean("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.encrypt("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+	return c.readFile("readFile", name, p)
+}
+
+// Remove ...
+func (c *CryptFS) Remove(name string) error {
+	p, err := c.encrypt("remove", name)
+	if err != nil {
+		return err
+	}
+	return c.backing.Remove(p)
+}
+
+// RemoveAll ...
+func (c *CryptFS) RemoveAll(path string) error {
+	p, err := c.encrypt("removeAll", path)
+	if err != nil {
+		return err
+	}
+	return c.backing.RemoveAll(p)
+}
+
+// Rename renames oldpath to newpath, and re-encrypts the files at or under newpath, since the path of a file is
+// authenticated with its content.
+func (c *CryptFS) Rename(oldpath string, newpath string) error {
+	oldpath, err := c.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = c.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	o, err := c.encrypt("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	n, err := c.encrypt("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	if err := c.backing.Rename(o, n); err != nil {
+		return err
+	}
+	return c.reencrypt(oldpath, newpath, n)
+}
+
+// Root ...
+func (c *CryptFS) Root() (string, error) {
+	return c.backing.Root()
+}
+
+// Stat ...
+func (c *CryptFS) Stat(name string) (gofs.FileInfo, error) {
+	c.logger.Debug("[cryptfs] stat", "name", name)
+
+	name, err := c.clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.encrypt("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return c.stat("stat", name, p)
+}
+
+// Sub returns a CryptFS for the sub-tree dir of the backing file system if the backing sub-tree is writable, otherwise
+// a read-only view of the CryptFS rooted at dir is returned.
+func (c *CryptFS) Sub(dir string) (gofs.FS, error) {
+	fi, err := c.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotDir})
+	}
+
+	if dir == "." {
+		return c, nil
+	}
+
+	p, err := c.encrypt("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := c.backing.Sub(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		s := *c
+		s.backing = fsys
+		s.root = gopath.Join(c.root, dir)
+		return &s, nil
+	}
+	return &subFS{dir: dir, fsys: c}, nil
+}
+
+// WriteFile ...
+func (c *CryptFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	c.logger.Debug("[cryptfs] writeFile", "name", name, "content_length", len(data))
+
+	name, err := c.clean("writeFile", name)
+	if err != nil {
+		return err
+	}
+
+	p, err := c.encrypt("writeFile", name)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(name, p, data, perm)
+}
+
+// additionalData returns the additional data that is authenticated with the content of the named file, which is the
+// path of the file relative to the CryptFS that was created using New.
+func (c *CryptFS) additionalData(name string) []byte {
+	return []byte(gopath.Join(c.root, name))
+}
+
+func (c *CryptFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(c, name)
+	if err != nil {
+		return name, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+func (c *CryptFS) decryptName(name string) (string, error) {
+	if !c.encryptNames {
+		return name, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", err
+	}
+
+	ns := c.names.NonceSize()
+	if len(b) < ns {
+		return "", gofs.ErrInvalid
+	}
+
+	p, err := c.names.Open(nil, b[:ns], b[ns:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
+
+// encrypt returns the path on the backing file system for name.
+func (c *CryptFS) encrypt(op string, name string) (string, error) {
+	name, err := c.clean(op, name)
+	if err != nil {
+		return name, err
+	}
+
+	if !c.encryptNames || name == "." {
+		return name, nil
+	}
+
+	segments := strings.Split(name, pathSeparator)
+	for i, s := range segments {
+		mac := hmac.New(sha256.New, c.nameKey)
+		mac.Write([]byte(s))
+		nonce := mac.Sum(nil)[:c.names.NonceSize()]
+		segments[i] = base64.RawURLEncoding.EncodeToString(c.names.Seal(nonce, nonce, []byte(s), nil))
+	}
+	return strings.Join(segments, pathSeparator), nil
+}
+
+func (c *CryptFS) info(name string, fi gofs.FileInfo) gofs.FileInfo {
+	size := fi.Size()
+	if fi.Mode().IsRegular() {
+		size = max(size-int64(c.content.NonceSize()+c.content.Overhead()), 0)
+	}
+	return &fileInfo{FileInfo: fi, name: name, size: size}
+}
+
+func (c *CryptFS) readDir(op string, name string, p string) ([]gofs.DirEntry, error) {
+	de, err := c.backing.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]gofs.DirEntry, 0, len(de))
+	for _, e := range de {
+		n, err := c.decryptName(e.Name())
+		if err != nil {
+			c.logger.Warn("[cryptfs] skipping entry with invalid name",
+				"dir", name,
+				"name", e.Name(),
+				"error", err)
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: op, Path: gopath.Join(name, n), Err: err})
+		}
+		entries = append(entries, gofs.FileInfoToDirEntry(c.info(n, fi)))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (c *CryptFS) readFile(op string, name string, p string) ([]byte, error) {
+	b, err := c.backing.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := c.content.NonceSize()
+	if len(b) < ns+c.content.Overhead() {
+		return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+	}
+
+	data, err := c.content.Open(nil, b[:ns], b[ns:], c.additionalData(name))
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return data, nil
+}
+
+// reencrypt re-encrypts the content of the files at or under name, which is stored at p on the backing file system, and
+// was renamed from oldname.
+func (c *CryptFS) reencrypt(oldname string, name string, p string) error {
+	fi, err := c.backing.Stat(p)
+	if err != nil {
+		return fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: "rename", Path: name, Err: err})
+	}
+
+	if fi.IsDir() {
+		de, err := c.backing.ReadDir(p)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range de {
+			n, err := c.decryptName(e.Name())
+			if err != nil {
+				continue
+			}
+
+			if err := c.reencrypt(gopath.Join(oldname, n), gopath.Join(name, n), gopath.Join(p, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	data, err := c.readFile("rename", oldname, p)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(name, p, data, fi.Mode().Perm())
+}
+
+func (c *CryptFS) stat(op string, name string, p string) (gofs.FileInfo, error) {
+	fi, err := c.backing.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("cryptfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return c.info(gopath.Base(name), fi), nil
+}
+
+func (c *CryptFS) writeFile(name string, p string, data []byte, perm gofs.FileMode) error {
+	nonce := make([]byte, c.content.NonceSize(), c.content.NonceSize()+len(data)+c.content.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("cryptfs: %w", err)
+	}
+	return c.backing.WriteFile(p, c.content.Seal(nonce, nonce, data, c.additionalData(name)), perm)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// WithLogger sets the Logger used by a CryptFS to log its operations and the entries that are skipped because their
+// names can not be decrypted. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*CryptFS) {
+	return func(c *CryptFS) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithNameEncryption enables encryption of file and directory names for a CryptFS.
+func WithNameEncryption() func(*CryptFS) {
+	return func(c *CryptFS) {
+		c.encryptNames = true
+	}
+}
+
+// fileInfo reports the decrypted name and plaintext size for an entry on the backing file system.
+type fileInfo struct {
+	gofs.FileInfo
+	name string
+	size int64
+}
+
+func (f *fileInfo) Name() string {
+	return f.name
+}
+
+func (f *fileInfo) Size() int64 {
+	return f.size
+}
+
+// subFS is a read-only view of a CryptFS rooted at a directory.
+type subFS struct {
+	dir  string
+	fsys *CryptFS
+}
+
+func (s *subFS) Open(name string) (gofs.File, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "open", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.Open(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "readDir", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.ReadDir(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) ReadFile(name string) ([]byte, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "readFile", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.ReadFile(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) Stat(name string) (gofs.FileInfo, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "stat", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.Stat(gopath.Join(s.dir, name))
+}
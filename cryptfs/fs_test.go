@@ -0,0 +1,216 @@
+package cryptfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+var testFiles = map[string]string{
+	"doc/fox.txt":      "the quick brown fox",
+	"doc/dog.txt":      "jumps over the lazy dog",
+	"pictures/empty":   "",
+	"secrets/keys.txt": "hunter2",
+}
+
+// CryptFSTestSuite ...
+type CryptFSTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	cfs     *CryptFS
+	key     []byte
+}
+
+func NewCryptFSTestSuite() *CryptFSTestSuite {
+	return &CryptFSTestSuite{}
+}
+
+func (t *CryptFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+
+	t.key = make([]byte, KeySize)
+	if _, err := rand.Read(t.key); err != nil {
+		t.T().Fatal(err)
+	}
+
+	cfs, err := New(backing, t.key, WithNameEncryption())
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.cfs = cfs
+
+	for name, content := range testFiles {
+		if err := cfs.WriteFile(name, []byte(content), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+}
+
+func TestCryptFSTestSuite(t *testing.T) {
+	suite.Run(t, NewCryptFSTestSuite())
+}
+
+func (t *CryptFSTestSuite) TestFS() {
+	var names []string
+	for name := range testFiles {
+		names = append(names, name)
+	}
+	assert.NoError(t.T(), fstest.TestFS(t.cfs, names...))
+}
+
+func (t *CryptFSTestSuite) TestEncrypted() {
+	err := gofs.WalkDir(t.backing, ".", func(path string, d gofs.DirEntry, err error) error {
+		if err != nil || path == "." {
+			return err
+		}
+
+		for name, content := range testFiles {
+			for _, s := range strings.Split(name, "/") {
+				assert.NotEqual(t.T(), s, d.Name())
+			}
+
+			if !d.IsDir() && content != "" {
+				b, err := t.backing.ReadFile(path)
+				assert.NoError(t.T(), err)
+				assert.False(t.T(), bytes.Contains(b, []byte(content)))
+			}
+		}
+		return nil
+	})
+	assert.NoError(t.T(), err)
+
+	for name, content := range testFiles {
+		b, err := t.cfs.ReadFile(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), content, string(b))
+
+		fi, err := t.cfs.Stat(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), int64(len(content)), fi.Size())
+	}
+}
+
+func (t *CryptFSTestSuite) TestWrongKey() {
+	key := bytes.Clone(t.key)
+	key[0] ^= 0xff
+
+	cfs, err := New(t.backing, key)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	entries, err := cfs.ReadDir(".")
+	assert.NoError(t.T(), err)
+
+	for _, e := range entries {
+		_, ok := testFiles[e.Name()]
+		assert.False(t.T(), ok)
+	}
+
+	_, err = New(t.backing, key[:16])
+	assert.Error(t.T(), err)
+}
+
+func (t *CryptFSTestSuite) TestAdditionalData() {
+	cfs, err := New(t.backing, t.key)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), cfs.WriteFile("a.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), cfs.WriteFile("b.txt", []byte("jumps over the lazy dog"), 0644))
+
+	// The content of a file can not be replaced with the content of another file on the backing file system.
+	a, err := t.backing.ReadFile("a.txt")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), t.backing.WriteFile("b.txt", a, 0644))
+
+	_, err = cfs.ReadFile("b.txt")
+	assert.Error(t.T(), err)
+
+	assert.NoError(t.T(), t.backing.Rename("a.txt", "c.txt"))
+	_, err = cfs.ReadFile("c.txt")
+	assert.Error(t.T(), err)
+
+	// Files are re-encrypted when a file or directory is renamed.
+	assert.NoError(t.T(), t.cfs.Rename("doc", "documents"))
+	for _, name := range []string{"fox.txt", "dog.txt"} {
+		b, err := t.cfs.ReadFile("documents/" + name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), testFiles["doc/"+name], string(b))
+	}
+
+	sub, err := t.cfs.Sub("documents")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	b, err := gofs.ReadFile(sub, "fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), testFiles["doc/fox.txt"], string(b))
+
+	assert.NoError(t.T(), sub.(fs.FS).Rename("fox.txt", "dog.txt"))
+	b, err = t.cfs.ReadFile("documents/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), testFiles["doc/fox.txt"], string(b))
+}
+
+func (t *CryptFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfs, err := New(t.backing, t.key, WithNameEncryption(), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), t.backing.WriteFile("invalid", []byte("the quick brown fox"), 0644))
+	_, err = cfs.ReadDir(".")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[cryptfs] skipping entry with invalid name" dir=. name=invalid`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.cfs.logger)
+}
+
+func (t *CryptFSTestSuite) TestOpenFile() {
+	f, err := t.cfs.Create("doc/cat.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = io.WriteString(f, "the cat")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	b, err := t.cfs.ReadFile("doc/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat", string(b))
+
+	f, err = t.cfs.OpenFile("doc/fox.txt", fs.O_RDONLY, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write([]byte("jumps"))
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+	assert.NoError(t.T(), f.Close())
+
+	assert.NoError(t.T(), t.cfs.Rename("doc/cat.txt", "secrets/cat.txt"))
+
+	b, err = t.cfs.ReadFile("secrets/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat", string(b))
+}
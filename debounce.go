@@ -0,0 +1,253 @@
+package fs
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	_ CapabilityFS = (*debouncedFS)(nil)
+	_ WatchFS      = (*debouncedFS)(nil)
+)
+
+// Debounce returns a WatchFS whose Watch method coalesces bursts of events reported by fsys for the same path into a
+// single Event, which is delivered once no event has been reported for the path for the quiet period. Editors commonly
+// write a file several times when saving it, so that watching the file through Debounce produces a single notification
+// for each save.
+//
+// The Op of a coalesced event is the union of the operations reported during the burst, and its Size and Time are
+// those of the last event. An entry that was created and removed during a burst produces no event, and an entry that
+// was removed only reports EventRemove. A rename folds the events pending for its old path into the event for its new
+// path, so that writing a temporary file and renaming it over the watched file produces a single event for the file.
+//
+// Providers that report a rename as two halves deliver both as EventRename with an empty OldPath, the first for the old
+// path and the second for the new path. Consecutive halves reported within the quiet period are paired into a single
+// EventRename with both paths. A half that is not paired is delivered as EventCreate if its path exists in fsys once
+// the quiet period elapses, and as EventRemove otherwise.
+//
+// Coalesced events are delivered in the order their quiet periods elapse, and events that are pending when the stop
+// function returned by Watch is called are delivered before it returns.
+func Debounce(fsys WatchFS, quiet time.Duration) WatchFS {
+	return &debouncedFS{WatchFS: fsys, quiet: quiet}
+}
+
+type debouncedFS struct {
+	WatchFS
+	quiet time.Duration
+}
+
+func (d *debouncedFS) Capabilities() Capability {
+	return Capabilities(d.WatchFS) & CapAtomicRename
+}
+
+// Watch calls handler with a coalesced Event for each burst of changes to the named entry, or to the entries of the
+// named directory, until the returned stop function is called.
+func (d *debouncedFS) Watch(name string, handler func(Event)) (func() error, error) {
+	if handler == nil {
+		return nil, errors.New("fs: handler is required")
+	}
+
+	db := &debouncer{fsys: d.WatchFS, handler: handler, pending: make(map[string]*pendingEvent), quiet: d.quiet}
+	stop, err := d.WatchFS.Watch(name, db.add)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		err := stop()
+		db.stop()
+		return err
+	}, nil
+}
+
+// pendingEvent is an Event that is held by a debouncer until its quiet period elapses.
+type pendingEvent struct {
+	deadline time.Time
+	event    Event
+
+	// half is set if the event is a rename half that has not been paired.
+	half bool
+}
+
+// debouncer coalesces the events delivered to a handler by Watch.
+type debouncer struct {
+	delivery sync.Mutex
+	fsys     WatchFS
+	half     string
+	handler  func(Event)
+	mutex    sync.Mutex
+	pending  map[string]*pendingEvent
+	quiet    time.Duration
+	stopped  bool
+	timer    *time.Timer
+}
+
+// add merges e with the event pending for its path, and restarts the quiet period of the path.
+func (d *debouncer) add(e Event) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	deadline := time.Now().Add(d.quiet)
+	if e.Op == EventRename && e.OldPath == "" {
+		// The first half of a rename is held until the second half is reported, so that they can be paired.
+		if d.half == "" || d.half == e.Path {
+			d.half = e.Path
+			d.merge(e, deadline).half = true
+			d.schedule()
+			return
+		}
+		e.OldPath = d.half
+	} else if e.Path == d.half {
+		d.half = ""
+	}
+
+	if e.Op&EventRename != 0 && e.OldPath != "" {
+		if d.half == e.OldPath {
+			d.half = ""
+		}
+
+		// Events pending for the old path are folded into the event for the new path.
+		if p, ok := d.pending[e.OldPath]; ok {
+			delete(d.pending, e.OldPath)
+			e.Op |= p.event.Op &^ (EventRemove | EventRename)
+			if p.event.OldPath != "" {
+				e.OldPath = p.event.OldPath
+			}
+		}
+	}
+
+	if e.Op&EventRemove != 0 {
+		if p, ok := d.pending[e.Path]; ok && p.event.Op&(EventCreate|EventRemove|EventRename) == EventCreate {
+			// The entry was created and removed during the burst.
+			delete(d.pending, e.Path)
+			d.schedule()
+			return
+		}
+	}
+
+	d.merge(e, deadline)
+	d.schedule()
+}
+
+// merge merges e with the event pending for its path, and returns the pending event.
+func (d *debouncer) merge(e Event, deadline time.Time) *pendingEvent {
+	p, ok := d.pending[e.Path]
+	if !ok {
+		p = &pendingEvent{event: e}
+		d.pending[e.Path] = p
+	}
+	p.deadline = deadline
+	p.half = false
+
+	switch {
+	case !ok:
+	case e.Op&EventRemove != 0:
+		// Changes made before the entry was removed are no longer relevant, unless it was renamed into place.
+		p.event.Op = EventRemove | p.event.Op&EventRename | e.Op&EventRename
+	default:
+		p.event.Op |= e.Op
+	}
+
+	if e.OldPath != "" {
+		p.event.OldPath = e.OldPath
+	}
+	p.event.Size = e.Size
+	p.event.Time = e.Time
+	return p
+}
+
+// schedule sets the timer to fire when the earliest quiet period elapses. The caller must hold the mutex.
+func (d *debouncer) schedule() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	var next time.Time
+	for _, p := range d.pending {
+		if next.IsZero() || p.deadline.Before(next) {
+			next = p.deadline
+		}
+	}
+
+	if !next.IsZero() {
+		d.timer = time.AfterFunc(time.Until(next), d.flush)
+	}
+}
+
+// flush delivers the events whose quiet periods have elapsed.
+func (d *debouncer) flush() {
+	d.delivery.Lock()
+	defer d.delivery.Unlock()
+
+	d.mutex.Lock()
+	if d.stopped {
+		d.mutex.Unlock()
+		return
+	}
+	due := d.due(time.Now())
+	d.schedule()
+	d.mutex.Unlock()
+
+	d.deliver(due)
+}
+
+// stop delivers every pending event, and stops the delivery of events.
+func (d *debouncer) stop() {
+	d.delivery.Lock()
+	defer d.delivery.Unlock()
+
+	d.mutex.Lock()
+	if d.stopped {
+		d.mutex.Unlock()
+		return
+	}
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	due := d.due(time.Time{})
+	d.mutex.Unlock()
+
+	d.deliver(due)
+}
+
+// due removes and returns the events whose quiet periods elapsed at now, in the order they elapsed. If now is zero,
+// every pending event is returned. The caller must hold the mutex.
+func (d *debouncer) due(now time.Time) []*pendingEvent {
+	var due []*pendingEvent
+	for name, p := range d.pending {
+		if now.IsZero() || !p.deadline.After(now) {
+			due = append(due, p)
+			delete(d.pending, name)
+			if name == d.half {
+				d.half = ""
+			}
+		}
+	}
+
+	slices.SortFunc(due, func(a *pendingEvent, b *pendingEvent) int {
+		return a.deadline.Compare(b.deadline)
+	})
+	return due
+}
+
+// deliver calls the handler with each event. Unpaired rename halves are delivered as EventCreate or EventRemove
+// depending on whether their path exists. The caller must hold the delivery mutex.
+func (d *debouncer) deliver(due []*pendingEvent) {
+	for _, p := range due {
+		if p.half {
+			p.event.Op = EventRemove
+			if _, err := d.fsys.Stat(p.event.Path); err == nil {
+				p.event.Op = EventCreate
+			}
+		}
+		d.handler(p.event)
+	}
+}
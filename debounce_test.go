@@ -0,0 +1,134 @@
+package fs_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebounce(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := fs.Observe(mfs)
+	assert.NoError(t, ofs.MkdirAll("doc", 0755))
+	assert.NoError(t, ofs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+
+	var (
+		events []fs.Event
+		mutex  sync.Mutex
+	)
+
+	// The quiet period is longer than the test, so that the pending events are delivered by the stop function.
+	stop, err := fs.Debounce(ofs.(fs.WatchFS), time.Hour).Watch("doc", func(e fs.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		e.Time = time.Time{}
+		events = append(events, e)
+	})
+	assert.NoError(t, err)
+
+	for _, content := range []string{"the", "the quick", "the quick brown fox"} {
+		assert.NoError(t, ofs.WriteFile("doc/fox.txt", []byte(content), 0644))
+	}
+
+	assert.NoError(t, ofs.WriteFile("doc/.cat.txt.swp", []byte("the cat"), 0644))
+	assert.NoError(t, ofs.WriteFile("doc/.cat.txt.swp", []byte("the sleepy cat"), 0644))
+	assert.NoError(t, ofs.Rename("doc/.cat.txt.swp", "doc/cat.txt"))
+
+	assert.NoError(t, ofs.WriteFile("doc/tmp.txt", []byte("temporary"), 0644))
+	assert.NoError(t, ofs.Remove("doc/tmp.txt"))
+
+	assert.NoError(t, ofs.WriteFile("doc/dog.txt", []byte("the very lazy dog"), 0644))
+	assert.NoError(t, ofs.Remove("doc/dog.txt"))
+	assert.NoError(t, stop())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []fs.Event{
+		{Op: fs.EventCreate | fs.EventWrite, Path: "doc/fox.txt", Size: 19},
+		{Op: fs.EventCreate | fs.EventWrite | fs.EventRename, Path: "doc/cat.txt", OldPath: "doc/.cat.txt.swp", Size: 14},
+		{Op: fs.EventRemove, Path: "doc/dog.txt", Size: 17},
+	}, events)
+}
+
+func TestDebounce_QuietPeriod(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := fs.Observe(mfs)
+	events := make(chan fs.Event, 8)
+	stop, err := fs.Debounce(ofs.(fs.WatchFS), 20*time.Millisecond).Watch("fox.txt", func(e fs.Event) {
+		events <- e
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ofs.WriteFile("fox.txt", []byte("the quick"), 0644))
+	assert.NoError(t, ofs.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+
+	select {
+	case e := <-events:
+		assert.Equal(t, fs.EventCreate|fs.EventWrite, e.Op)
+		assert.Equal(t, int64(19), e.Size)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	assert.NoError(t, ofs.WriteFile("fox.txt", []byte("the quick brown fox jumps"), 0644))
+
+	select {
+	case e := <-events:
+		assert.Equal(t, fs.EventWrite, e.Op)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	assert.NoError(t, stop())
+	assert.Empty(t, events)
+}
+
+func TestDebounce_RenameHalves(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, mfs.WriteFile("doc/owl.txt", []byte("the wise owl"), 0644))
+
+	wfs := &halvesFS{FS: mfs}
+
+	var events []fs.Event
+	stop, err := fs.Debounce(wfs, time.Hour).Watch("doc", func(e fs.Event) {
+		events = append(events, fs.Event{Op: e.Op, Path: e.Path, OldPath: e.OldPath})
+	})
+	assert.NoError(t, err)
+
+	wfs.handler(fs.Event{Op: fs.EventRename, Path: "doc/fox.txt"})
+	wfs.handler(fs.Event{Op: fs.EventRename, Path: "doc/animals/fox.txt"})
+	wfs.handler(fs.Event{Op: fs.EventRename, Path: "doc/owl.txt"})
+	assert.NoError(t, stop())
+
+	assert.Equal(t, []fs.Event{
+		{Op: fs.EventRename, Path: "doc/animals/fox.txt", OldPath: "doc/fox.txt"},
+		{Op: fs.EventCreate, Path: "doc/owl.txt"},
+	}, events)
+}
+
+// halvesFS is a WatchFS whose events are reported by calling the handler of the watch directly.
+type halvesFS struct {
+	fs.FS
+	handler func(fs.Event)
+}
+
+func (h *halvesFS) Watch(_ string, handler func(fs.Event)) (func() error, error) {
+	h.handler = handler
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"unsafe"
+)
+
+// DirectIOAlignment is the alignment in bytes of the buffers, offsets, and lengths used to read and write files opened
+// using O_DIRECT. It is the page size of most platforms, which is a multiple of the logical block size of most devices.
+const DirectIOAlignment = 4096
+
+// AlignedBuffer returns a zeroed buffer of size bytes whose address is aligned to DirectIOAlignment, for reading and
+// writing files opened using O_DIRECT. The size should be a multiple of DirectIOAlignment.
+func AlignedBuffer(size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+
+	b := make([]byte, size+DirectIOAlignment)
+	off := 0
+	if r := int(uintptr(unsafe.Pointer(unsafe.SliceData(b))) & (DirectIOAlignment - 1)); r != 0 {
+		off = DirectIOAlignment - r
+	}
+	return b[off : off+size : off+size]
+}
+
+// IsAligned reports whether the address and length of b are aligned to DirectIOAlignment, so that b can be used to read
+// and write files opened using O_DIRECT.
+func IsAligned(b []byte) bool {
+	return uintptr(unsafe.Pointer(unsafe.SliceData(b)))&(DirectIOAlignment-1) == 0 && len(b)%DirectIOAlignment == 0
+}
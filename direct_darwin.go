@@ -0,0 +1,26 @@
+//go:build darwin
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	gofs "io/fs"
+)
+
+// openDirect opens the named file and disables caching of its content using the F_NOCACHE command of fcntl, since
+// the O_DIRECT flag is not supported on this platform.
+func openDirect(name string, flag int, perm gofs.FileMode) (*os.File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_NOCACHE, 1); err != nil {
+		_ = f.Close()
+		return nil, &gofs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
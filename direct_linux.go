@@ -0,0 +1,16 @@
+//go:build linux
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	gofs "io/fs"
+)
+
+// openDirect opens the named file for direct I/O using the O_DIRECT flag.
+func openDirect(name string, flag int, perm gofs.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag|unix.O_DIRECT, perm)
+}
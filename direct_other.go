@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package fs
+
+import (
+	"os"
+
+	gofs "io/fs"
+)
+
+// openDirect is not supported on this platform.
+func openDirect(name string, _ int, _ gofs.FileMode) (*os.File, error) {
+	return nil, &gofs.PathError{Op: "open", Path: name, Err: ErrUnsupported}
+}
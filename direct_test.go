@@ -0,0 +1,72 @@
+package fs_test
+
+import (
+	"errors"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignedBuffer(t *testing.T) {
+	for _, size := range []int{fs.DirectIOAlignment, 3 * fs.DirectIOAlignment} {
+		b := fs.AlignedBuffer(size)
+		assert.Len(t, b, size)
+		assert.True(t, fs.IsAligned(b))
+		assert.False(t, fs.IsAligned(b[1:]))
+	}
+
+	assert.Nil(t, fs.AlignedBuffer(0))
+	assert.False(t, fs.IsAligned(fs.AlignedBuffer(100)))
+}
+
+func TestDirect(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "fox.txt")
+	f, err := osfs.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_DIRECT, 0644)
+	if errors.Is(err, fs.ErrUnsupported) || errors.Is(err, syscall.EINVAL) {
+		t.Skip("direct I/O is not supported")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b := fs.AlignedBuffer(2 * fs.DirectIOAlignment)
+	copy(b, "the quick brown fox")
+	n, err := f.Write(b)
+	assert.NoError(t, err)
+	assert.Equal(t, len(b), n)
+
+	r := fs.AlignedBuffer(fs.DirectIOAlignment)
+	_, err = f.ReadAt(r, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(r[:19]))
+
+	fi, err := osfs.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(b)), fi.Size())
+
+	// Providers that do not use a page cache ignore the flag.
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	mf, err := mfs.OpenFile("fox.txt", fs.O_RDONLY|fs.O_DIRECT, 0)
+	assert.NoError(t, err)
+
+	_, err = mf.Write([]byte("the lazy dog"))
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.NoError(t, mf.Close())
+}
@@ -18,8 +18,23 @@ var (
 	_ gofs.FileInfo = (*Entry)(nil)
 )
 
+// Enumeration of the permissions that can be checked using Entry.CanAccess, which can be combined to check for more
+// than one permission. The values are the same as the permission bits for other users.
+const (
+	AccessExecute gofs.FileMode = 1 << iota
+	AccessWrite
+	AccessRead
+)
+
+// PathValidator reports whether a path is valid for an Entry.
 type PathValidator func(string) bool
 
+// entryJSON is the JSON representation of an Entry used by MarshalJSON and UnmarshalJSON.
+type entryJSON struct {
+	Attributes *Attribute `json:"attributes"`
+	Path       string     `json:"path"`
+}
+
 // Entry is a container for file and directory metadata.
 type Entry struct {
 	attrs         *Attribute
@@ -48,11 +63,57 @@ func NewEntry(path string, options ...func(*Entry)) (*Entry, error) {
 	return entry, nil
 }
 
+// EntryFromFileInfo creates an Entry for the file at path from fi, so that metadata provided by any gofs.FS can be
+// represented as an Entry.
+//
+// The mode, size, and modification time are provided by fi. Where the platform supports it, the owner and group IDs,
+// inode, number of links, device number, and block counts are provided by the platform-specific data returned by
+// fi.Sys(), such as the *syscall.Stat_t provided by the os package on Unix systems. If fi is an *Entry, a copy of fi
+// with the provided path is returned.
+//
+// The path is not validated, so that platform paths, such as absolute paths, can be used.
+func EntryFromFileInfo(path string, fi gofs.FileInfo) (*Entry, error) {
+	if fi == nil {
+		return nil, errors.New("fs: file info is required")
+	}
+
+	if e, ok := fi.(*Entry); ok {
+		c := e.Copy()
+		c.path = path
+		return c, nil
+	}
+	return entryFromFileInfo(path, fi)
+}
+
 // Attributes returns the attributes for the Entry.
 func (e *Entry) Attributes() *Attribute {
 	return e.attrs
 }
 
+// CanAccess reports whether the user with the provided uid and primary group gid is granted every permission in want
+// for the Entry, where want is a combination of AccessRead, AccessWrite and AccessExecute.
+//
+// The permission bits are evaluated in the same way as POSIX systems: the owner bits apply if uid is the owner of the
+// Entry, otherwise the group bits apply if gid is the group of the Entry, and otherwise the bits for other users apply.
+// The superuser (uid 0) is granted read and write access to every Entry, and execute access if the Entry is a directory
+// or any of its execute bits are set.
+func (e *Entry) CanAccess(uid uint32, gid uint32, want gofs.FileMode) bool {
+	want &= AccessRead | AccessWrite | AccessExecute
+
+	perm := e.Mode().Perm()
+	if uid == 0 {
+		return want&AccessExecute == 0 || e.IsDir() || perm&0111 != 0
+	}
+
+	switch {
+	case int64(uid) == int64(e.attrs.UID()):
+		perm >>= 6
+	case int64(gid) == int64(e.attrs.GID()):
+		perm >>= 3
+	}
+	return perm&want == want
+}
+
 // Dir returns the path for the Entry with the last element truncated.
 func (e *Entry) Dir() string {
 	return gopath.Dir(e.path)
@@ -148,6 +209,38 @@ func (e *Entry) Copy() *Entry {
 	}
 }
 
+// MarshalJSON returns the JSON encoding of the path and attributes of the Entry, so that the Entry can be reconstructed
+// exactly using UnmarshalJSON.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(entryJSON{Attributes: e.attrs, Path: e.path})
+}
+
+// UnmarshalJSON sets the path and attributes of the Entry to the properties decoded from the JSON encoding produced by
+// MarshalJSON. The path is validated using the path validator of the Entry, which is gofs.ValidPath unless one has
+// been set using WithPathValidator.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var v entryJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("entry: %w", err)
+	}
+
+	if err := validPath(v.Path, e.pathValidator); err != nil {
+		return err
+	}
+
+	if v.Attributes == nil {
+		attrs, err := NewAttributes()
+		if err != nil {
+			return err
+		}
+		v.Attributes = attrs
+	}
+
+	e.attrs = v.Attributes
+	e.path = v.Path
+	return nil
+}
+
 // ToMap returns a map representation of the Entry properties.
 func (e *Entry) ToMap() (map[string]any, error) {
 	var m map[string]any
@@ -204,3 +297,25 @@ func WithPathValidator(v func(string) bool) func(*Entry) {
 		e.pathValidator = v
 	}
 }
+
+// entryFromFileInfo creates an Entry for the file at path from fi, applying the provided attribute options.
+func entryFromFileInfo(path string, fi gofs.FileInfo, attributes ...func(*Attribute)) (*Entry, error) {
+	mtime := fi.ModTime()
+	if mtime.IsZero() {
+		mtime = time.Now().UTC()
+	}
+
+	attrs := []func(*Attribute){
+		WithCtime(mtime),
+		WithMode(uint32(fi.Mode())),
+		WithMtime(mtime),
+		WithSize(uint64(max(fi.Size(), 0))),
+	}
+	attrs = append(attrs, sysAttributes(fi.Sys())...)
+
+	a, err := NewAttributes(append(attrs, attributes...)...)
+	if err != nil {
+		return nil, err
+	}
+	return NewEntry(path, WithAttributes(a), WithPathValidator(func(string) bool { return true }))
+}
@@ -0,0 +1,96 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestEntryFromFileInfo(t *testing.T) {
+	mtime := time.Now().Add(-time.Hour)
+	mapfs := fstest.MapFS{"doc/fox.txt": {Data: []byte("the quick brown fox"), Mode: 0644, ModTime: mtime}}
+
+	fi, err := mapfs.Stat("doc/fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.EntryFromFileInfo("doc/fox.txt", fi)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", e.Path())
+	assert.Equal(t, "fox.txt", e.Name())
+	assert.Equal(t, gofs.FileMode(0644), e.Mode())
+	assert.Equal(t, int64(19), e.Size())
+	assert.True(t, mtime.Equal(e.ModTime()))
+
+	c, err := fs.EntryFromFileInfo("copy/fox.txt", e)
+	assert.NoError(t, err)
+	assert.Equal(t, "copy/fox.txt", c.Path())
+	assert.Equal(t, "doc/fox.txt", e.Path())
+	assert.Equal(t, e.Attributes(), c.Attributes())
+
+	_, err = fs.EntryFromFileInfo("doc/fox.txt", nil)
+	assert.Error(t, err)
+}
+
+func TestEntryFromFileInfoSys(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("platform attributes are not supported")
+	}
+
+	name := filepath.Join(t.TempDir(), "fox.txt")
+	if err := os.WriteFile(name, []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.EntryFromFileInfo(name, fi)
+	assert.NoError(t, err)
+	assert.Equal(t, name, e.Path())
+	assert.Equal(t, int32(os.Getuid()), e.Attributes().UID())
+	assert.Equal(t, int32(os.Getgid()), e.Attributes().GID())
+	assert.NotZero(t, e.Attributes().Inode())
+	assert.Equal(t, int64(1), e.Attributes().Nlink())
+}
+
+func TestEntryCanAccess(t *testing.T) {
+	attrs, err := fs.NewAttributes(fs.WithMode(0640), fs.WithUID(1000), fs.WithGID(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.NewEntry("doc/fox.txt", fs.WithAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, e.CanAccess(1000, 100, fs.AccessRead|fs.AccessWrite))
+	assert.False(t, e.CanAccess(1000, 100, fs.AccessExecute))
+	assert.True(t, e.CanAccess(1001, 100, fs.AccessRead))
+	assert.False(t, e.CanAccess(1001, 100, fs.AccessWrite))
+	assert.False(t, e.CanAccess(1001, 101, fs.AccessRead))
+	assert.True(t, e.CanAccess(1001, 101, 0))
+
+	// The owner bits apply to the owner even if the group or other bits grant more permissions.
+	assert.NoError(t, attrs.SetMode(0077))
+	assert.False(t, e.CanAccess(1000, 100, fs.AccessRead))
+	assert.True(t, e.CanAccess(1001, 101, fs.AccessRead|fs.AccessWrite|fs.AccessExecute))
+
+	assert.True(t, e.CanAccess(0, 0, fs.AccessRead|fs.AccessWrite|fs.AccessExecute))
+	assert.NoError(t, attrs.SetMode(0600))
+	assert.True(t, e.CanAccess(0, 0, fs.AccessRead|fs.AccessWrite))
+	assert.False(t, e.CanAccess(0, 0, fs.AccessExecute))
+}
@@ -1,16 +1,54 @@
 package fs
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	gofs "io/fs"
+)
+
 // Enumeration of errors that may be returned by file system operations.
 const (
+	ErrConflict         = fsError("generation does not match")
 	ErrCtimeMismatch    = fsError("modification time occurs before creation time")
 	ErrIsDir            = fsError("is a directory")
 	ErrInvalidEntryType = fsError("entry type is invalid")
+	ErrInvalidName      = fsError("name is invalid")
 	ErrMtimeMismatch    = fsError("modification time is invalid")
 	ErrNotDir           = fsError("not a directory")
+	ErrNotEmpty         = fsError("directory not empty")
 	ErrNotFile          = fsError("not a file")
+	ErrQuotaExceeded    = fsError("quota exceeded")
+	ErrTimeout          = fsError("operation timed out")
 	ErrTooLarge         = fsError("too large")
 )
 
+// Errors that may be returned by file system operations, which are the same as the corresponding errors defined by the
+// io/fs and errors packages, so that every error returned by a provider can be tested using errors.Is with the errors
+// defined by this package.
+var (
+	ErrClosed      = gofs.ErrClosed
+	ErrExist       = gofs.ErrExist
+	ErrInvalid     = gofs.ErrInvalid
+	ErrNotExist    = gofs.ErrNotExist
+	ErrPermission  = gofs.ErrPermission
+	ErrUnsupported = errors.ErrUnsupported
+)
+
+// errnoErrors maps system errors to the corresponding errors defined by this package that are not already matched by
+// syscall.Errno.Is.
+var errnoErrors = map[syscall.Errno]error{
+	syscall.EDQUOT:    ErrQuotaExceeded,
+	syscall.EFBIG:     ErrTooLarge,
+	syscall.EISDIR:    ErrIsDir,
+	syscall.ENOTDIR:   ErrNotDir,
+	syscall.ENOTEMPTY: ErrNotEmpty,
+	syscall.ENOTSUP:   ErrUnsupported,
+	syscall.ETIMEDOUT: ErrTimeout,
+}
+
 // fsError defines the type for errors that may be returned by file system operations.
 type fsError string
 
@@ -18,3 +56,146 @@ type fsError string
 func (e fsError) Error() string {
 	return string(e)
 }
+
+// OpError records an error and the provider, operation, and path that caused it.
+//
+// Providers return an *OpError for failed operations, with Err set to the underlying cause, which is one of the errors
+// defined by this package where applicable. System errors are matched by errors.Is with the corresponding errors
+// defined by this package, so that for example an OpError caused by ENOTEMPTY matches ErrNotEmpty.
+type OpError struct {
+	// Provider is the name of the provider that returned the error, as returned by FS.Provider.
+	Provider string
+
+	// Op is the name of the operation that failed (e.g. "open" or "rename").
+	Op string
+
+	// Path is the path of the file that the operation was performed on.
+	Path string
+
+	// NewPath is the second path for operations that have two, such as the destination of "rename".
+	NewPath string
+
+	// Err is the underlying cause of the error.
+	Err error
+}
+
+// NewOpError returns an *OpError for the operation op on path by provider caused by err, or nil if err is nil.
+//
+// Any *OpError, *gofs.PathError, *os.LinkError, or *os.SyscallError that wraps the cause of err is removed, so that
+// the context of an error is not repeated when it is returned by nested operations.
+func NewOpError(provider string, op string, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Provider: provider, Op: op, Path: path, Err: cause(err)}
+}
+
+// NewLinkOpError returns an *OpError for the operation op from oldpath to newpath by provider caused by err, or nil if
+// err is nil. The cause of err is determined in the same way as for NewOpError.
+func NewLinkOpError(provider string, op string, oldpath string, newpath string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &OpError{Provider: provider, Op: op, Path: oldpath, NewPath: newpath, Err: cause(err)}
+}
+
+// Error returns a string representation of the OpError.
+func (e *OpError) Error() string {
+	s := e.Op
+	if e.Provider != "" {
+		s = e.Provider + ": " + s
+	}
+
+	if e.Path != "" {
+		s += " " + e.Path
+	}
+
+	if e.NewPath != "" {
+		s += " " + e.NewPath
+	}
+
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// As sets target to a *gofs.PathError with the same operation, path, and cause as the OpError if target is a
+// **gofs.PathError, for compatibility with code that inspects errors returned by providers as a *gofs.PathError.
+func (e *OpError) As(target any) bool {
+	if pe, ok := target.(**gofs.PathError); ok {
+		*pe = &gofs.PathError{Op: e.Op, Path: e.Path, Err: e.Err}
+		return true
+	}
+	return false
+}
+
+// Is reports whether the system error that caused the OpError corresponds to target.
+func (e *OpError) Is(target error) bool {
+	var errno syscall.Errno
+	if errors.As(e.Err, &errno) {
+		err, ok := errnoErrors[errno]
+		return ok && err == target
+	}
+	return false
+}
+
+// Unwrap returns the underlying cause of the OpError.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// NameError records a name that violates a rule of a provider, as returned by the validator of a PathValidatorFS.
+//
+// A NameError matches ErrInvalidName using errors.Is, and also matches ErrInvalid, so that it is handled in the same
+// way as other invalid paths.
+type NameError struct {
+	// Name is the path, or the element of the path, that violates the rule.
+	Name string
+
+	// Rule describes the rule that was violated.
+	Rule string
+}
+
+// Error returns a string representation of the NameError.
+func (e *NameError) Error() string {
+	return fmt.Sprintf("%s: %q: %s", ErrInvalidName, e.Name, e.Rule)
+}
+
+// Is reports whether target is ErrInvalid.
+func (e *NameError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Unwrap returns ErrInvalidName.
+func (e *NameError) Unwrap() error {
+	return ErrInvalidName
+}
+
+// cause returns the cause of err without the context added by *OpError, *gofs.PathError, *os.LinkError and
+// *os.SyscallError, including when one of them is wrapped with a prefix using fmt.Errorf.
+func cause(err error) error {
+	for {
+		switch e := err.(type) {
+		case *OpError:
+			err = e.Err
+			continue
+		case *gofs.PathError:
+			err = e.Err
+			continue
+		case *os.LinkError:
+			err = e.Err
+			continue
+		case *os.SyscallError:
+			err = e.Err
+			continue
+		}
+
+		switch errors.Unwrap(err).(type) {
+		case *OpError, *gofs.PathError, *os.LinkError, *os.SyscallError:
+			err = errors.Unwrap(err)
+			continue
+		}
+		return err
+	}
+}
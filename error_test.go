@@ -0,0 +1,110 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestOpError(t *testing.T) {
+	assert.NoError(t, fs.NewOpError("memfs", "open", "fox.txt", nil))
+
+	err := fs.NewOpError("linux", "remove", "doc", &gofs.PathError{Op: "remove", Path: "/doc", Err: syscall.ENOTEMPTY})
+	assert.EqualError(t, err, "linux: remove doc: "+syscall.ENOTEMPTY.Error())
+	assert.ErrorIs(t, err, fs.ErrNotEmpty)
+	assert.ErrorIs(t, err, syscall.ENOTEMPTY)
+	assert.NotErrorIs(t, err, fs.ErrNotDir)
+	assert.ErrorIs(t, fs.NewOpError("linux", "read", "doc", syscall.ETIMEDOUT), fs.ErrTimeout)
+
+	var oe *fs.OpError
+	if assert.ErrorAs(t, err, &oe) {
+		assert.Equal(t, "linux", oe.Provider)
+		assert.Equal(t, "remove", oe.Op)
+		assert.Equal(t, "doc", oe.Path)
+	}
+
+	var pe *gofs.PathError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, "doc", pe.Path)
+	}
+
+	err = fs.NewLinkOpError("memfs", "rename", "a", "b", fs.NewOpError("memfs", "stat", "b", fs.ErrExist))
+	assert.EqualError(t, err, "memfs: rename a b: file already exists")
+	assert.ErrorIs(t, err, fs.ErrExist)
+	assert.ErrorIs(t, err, os.ErrExist)
+}
+
+func TestErrorsMemFS(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assertOpError(t, mfs.Remove("doc"), mfs.Provider(), fs.ErrNotEmpty)
+	assertOpError(t, mfs.Mkdir("doc", 0755), mfs.Provider(), fs.ErrExist)
+
+	_, err = mfs.Open("missing.txt")
+	assertOpError(t, err, mfs.Provider(), fs.ErrNotExist)
+
+	_, err = mfs.ReadDir("doc/fox.txt")
+	assertOpError(t, err, mfs.Provider(), fs.ErrNotDir)
+}
+
+func TestErrorsOSFS(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assertOpError(t, osfs.Remove(dir), osfs.Provider(), fs.ErrNotEmpty)
+	assertOpError(t, osfs.Mkdir(dir, 0755), osfs.Provider(), fs.ErrExist)
+
+	_, err = osfs.Open(filepath.Join(dir, "missing.txt"))
+	assertOpError(t, err, osfs.Provider(), fs.ErrNotExist)
+
+	_, err = osfs.ReadDir(filepath.Join(dir, "fox.txt"))
+	assertOpError(t, err, osfs.Provider(), fs.ErrNotDir)
+
+	cfs, err := fs.Chroot(osfs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cfs.Stat("missing.txt")
+	assertOpError(t, err, osfs.Provider(), fs.ErrNotExist)
+	assert.NotContains(t, err.Error(), dir)
+}
+
+func assertOpError(t *testing.T, err error, provider string, target error) {
+	t.Helper()
+
+	assert.ErrorIs(t, err, target)
+
+	var oe *fs.OpError
+	if assert.ErrorAs(t, err, &oe) {
+		assert.Equal(t, provider, oe.Provider)
+		assert.Equal(t, 1, strings.Count(err.Error(), provider+":"))
+	}
+
+	var pe *gofs.PathError
+	assert.True(t, errors.As(err, &pe))
+}
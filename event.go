@@ -0,0 +1,61 @@
+package fs
+
+import (
+	"strings"
+	"time"
+)
+
+// EventOp describes the operation that produced an Event. Operations are bit flags so that a set of operations can be
+// used as a filter.
+type EventOp uint32
+
+// Enumeration of operations that may be reported by an Event.
+const (
+	EventCreate EventOp = 1 << iota
+	EventWrite
+	EventRemove
+	EventRename
+	EventEvict
+)
+
+// String returns a string representation of the EventOp.
+func (o EventOp) String() string {
+	var ops []string
+	for _, op := range []struct {
+		op   EventOp
+		name string
+	}{
+		{EventCreate, "create"},
+		{EventWrite, "write"},
+		{EventRemove, "remove"},
+		{EventRename, "rename"},
+		{EventEvict, "evict"},
+	} {
+		if o&op.op != 0 {
+			ops = append(ops, op.name)
+		}
+	}
+
+	if len(ops) == 0 {
+		return "unknown"
+	}
+	return strings.Join(ops, "|")
+}
+
+// Event describes a change to an entry in a file system.
+type Event struct {
+	// Op is the operation that produced the event.
+	Op EventOp
+
+	// Path is the path of the entry that changed. For EventRename, Path is the new path of the entry.
+	Path string
+
+	// OldPath is the previous path of the entry for EventRename, and is otherwise empty.
+	OldPath string
+
+	// Size is the size in bytes of the entry affected by the event, if known.
+	Size int64
+
+	// Time is when the event occurred.
+	Time time.Time
+}
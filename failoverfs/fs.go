@@ -0,0 +1,347 @@
+package failoverfs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const (
+	// DefaultCooldown is the default duration a backend is skipped once it has been marked unhealthy.
+	DefaultCooldown = 30 * time.Second
+
+	// DefaultFailureThreshold is the default number of consecutive transient failures after which a backend is marked
+	// unhealthy.
+	DefaultFailureThreshold = 3
+)
+
+var _ fs.FS = (*FailoverFS)(nil)
+
+// Health describes the health of a backend for a FailoverFS.
+type Health struct {
+	// Index is the position of the backend, where 0 is the primary and fallbacks follow in order.
+	Index int
+
+	// Healthy reports whether the backend is used for read operations.
+	Healthy bool
+
+	// Failures is the number of consecutive transient failures for the backend.
+	Failures int
+
+	// LastError is the most recent transient error returned by the backend.
+	LastError error
+
+	// Until is the time after which an unhealthy backend is retried.
+	Until time.Time
+}
+
+// FailoverFS failover provider that implements fs.FS.
+//
+// A FailoverFS serves read operations from a primary file system and an ordered list of fallbacks. A read operation
+// that fails with a transient error is retried against the next healthy backend, in order. A backend that fails with
+// consecutive transient errors is marked unhealthy and skipped until a cooldown has elapsed, after which it is retried
+// and, if the retry succeeds, is used again. Since backends are always tried in order, reads fail back to the primary
+// automatically once it recovers.
+//
+// Write operations are only performed on the primary, and reads from a File that has already been opened are not
+// retried against another backend.
+type FailoverFS struct {
+	backends  []*backend
+	cooldown  time.Duration
+	logger    fs.Logger
+	mutex     sync.Mutex
+	now       func() time.Time
+	threshold int
+	transient func(error) bool
+}
+
+// New creates a new FailoverFS that reads from primary and falls back to fallbacks in order.
+func New(primary fs.FS, fallbacks []fs.FS, options ...func(*FailoverFS)) (*FailoverFS, error) {
+	if primary == nil {
+		return nil, errors.New("failoverfs: primary file system is required")
+	}
+
+	f := &FailoverFS{
+		cooldown:  DefaultCooldown,
+		logger:    fs.NopLogger(),
+		now:       time.Now,
+		threshold: DefaultFailureThreshold,
+		transient: IsTransient,
+	}
+	for _, opt := range options {
+		opt(f)
+	}
+
+	for i, fsys := range append([]fs.FS{primary}, fallbacks...) {
+		if fsys == nil {
+			return nil, fmt.Errorf("failoverfs: fallback %d is nil", i-1)
+		}
+		f.backends = append(f.backends, &backend{fsys: fsys, index: i})
+	}
+	return f, nil
+}
+
+// Close closes the primary and fallback file systems.
+func (f *FailoverFS) Close() error {
+	var errs []error
+	for _, b := range f.backends {
+		if err := b.fsys.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failoverfs: %w", err)
+	}
+	return nil
+}
+
+// Create ...
+func (f *FailoverFS) Create(name string) (fs.File, error) {
+	return f.primary().Create(name)
+}
+
+// Glob ...
+func (f *FailoverFS) Glob(pattern string) ([]string, error) {
+	return read(f, func(fsys fs.FS) ([]string, error) { return fsys.Glob(pattern) })
+}
+
+// Health returns the health of the primary and fallback file systems, in order.
+func (f *FailoverFS) Health() []Health {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	health := make([]Health, len(f.backends))
+	for i, b := range f.backends {
+		health[i] = Health{
+			Index:     b.index,
+			Healthy:   b.until.IsZero(),
+			Failures:  b.failures,
+			LastError: b.err,
+			Until:     b.until,
+		}
+	}
+	return health
+}
+
+// Mkdir ...
+func (f *FailoverFS) Mkdir(name string, perm gofs.FileMode) error {
+	return f.primary().Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (f *FailoverFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return f.primary().MkdirAll(path, perm)
+}
+
+// Open ...
+func (f *FailoverFS) Open(name string) (gofs.File, error) {
+	return read(f, func(fsys fs.FS) (gofs.File, error) { return fsys.Open(name) })
+}
+
+// OpenFile opens the named file. Files opened read-only are opened using the first healthy backend, and files opened
+// for writing are opened using the primary.
+func (f *FailoverFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) != 0 {
+		return f.primary().OpenFile(name, flag, perm)
+	}
+	return read(f, func(fsys fs.FS) (fs.File, error) { return fsys.OpenFile(name, flag, perm) })
+}
+
+// PathSeparator ...
+func (f *FailoverFS) PathSeparator() string {
+	return f.primary().PathSeparator()
+}
+
+// Provider ...
+func (f *FailoverFS) Provider() string {
+	return f.primary().Provider()
+}
+
+// ReadDir ...
+func (f *FailoverFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return read(f, func(fsys fs.FS) ([]gofs.DirEntry, error) { return fsys.ReadDir(name) })
+}
+
+// ReadFile ...
+func (f *FailoverFS) ReadFile(name string) ([]byte, error) {
+	return read(f, func(fsys fs.FS) ([]byte, error) { return fsys.ReadFile(name) })
+}
+
+// Remove ...
+func (f *FailoverFS) Remove(name string) error {
+	return f.primary().Remove(name)
+}
+
+// RemoveAll ...
+func (f *FailoverFS) RemoveAll(path string) error {
+	return f.primary().RemoveAll(path)
+}
+
+// Rename ...
+func (f *FailoverFS) Rename(oldpath string, newpath string) error {
+	return f.primary().Rename(oldpath, newpath)
+}
+
+// Root ...
+func (f *FailoverFS) Root() (string, error) {
+	return f.primary().Root()
+}
+
+// Stat ...
+func (f *FailoverFS) Stat(name string) (gofs.FileInfo, error) {
+	return read(f, func(fsys fs.FS) (gofs.FileInfo, error) { return fsys.Stat(name) })
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot, so that read operations on the sub-tree fail over in the
+// same way as the FailoverFS.
+func (f *FailoverFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(f, dir)
+}
+
+// WriteFile ...
+func (f *FailoverFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	return f.primary().WriteFile(name, data, perm)
+}
+
+// candidates returns the backends to attempt for a read operation, in order. Unhealthy backends whose cooldown has
+// not elapsed are skipped unless every backend is unhealthy.
+func (f *FailoverFS) candidates() []*backend {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := f.now()
+
+	var candidates []*backend
+	for _, b := range f.backends {
+		if b.until.IsZero() || !now.Before(b.until) {
+			candidates = append(candidates, b)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return f.backends
+	}
+	return candidates
+}
+
+func (f *FailoverFS) primary() fs.FS {
+	return f.backends[0].fsys
+}
+
+// record updates the health of b using the result of an operation.
+func (f *FailoverFS) record(b *backend, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err == nil || !f.transient(err) {
+		if !b.until.IsZero() {
+			f.logger.Info("[failoverfs] backend recovered", "index", b.index)
+		}
+		b.failures = 0
+		b.until = time.Time{}
+		return
+	}
+
+	b.err = err
+	b.failures++
+	if b.failures >= f.threshold {
+		if b.until.IsZero() {
+			f.logger.Warn("[failoverfs] backend unhealthy", "index", b.index, "error", err)
+		}
+		b.until = f.now().Add(f.cooldown)
+	}
+}
+
+// read performs op using each candidate backend in order until it succeeds or fails with an error that is not
+// transient.
+func read[T any](f *FailoverFS, op func(fs.FS) (T, error)) (T, error) {
+	var (
+		result T
+		err    error
+	)
+
+	for _, b := range f.candidates() {
+		result, err = op(b.fsys)
+		f.record(b, err)
+		if err == nil || !f.transient(err) {
+			return result, err
+		}
+		f.logger.Debug("[failoverfs] failing over", "index", b.index, "error", err)
+	}
+	return result, err
+}
+
+// IsTransient reports whether err is a transient error that may succeed if retried against another backend. Errors
+// that describe the state of a file, such as gofs.ErrNotExist or gofs.ErrPermission, are not transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, target := range []error{
+		gofs.ErrClosed,
+		gofs.ErrExist,
+		gofs.ErrInvalid,
+		gofs.ErrNotExist,
+		gofs.ErrPermission,
+		fs.ErrIsDir,
+		fs.ErrNotDir,
+		fs.ErrNotEmpty,
+		fs.ErrNotFile,
+	} {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithCooldown sets the duration a backend is skipped once it has been marked unhealthy.
+func WithCooldown(cooldown time.Duration) func(*FailoverFS) {
+	return func(f *FailoverFS) {
+		f.cooldown = cooldown
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive transient failures after which a backend is marked unhealthy.
+func WithFailureThreshold(threshold int) func(*FailoverFS) {
+	return func(f *FailoverFS) {
+		if threshold > 0 {
+			f.threshold = threshold
+		}
+	}
+}
+
+// WithLogger sets the Logger used by a FailoverFS to log the backends that become unhealthy or recover, and the
+// operations that fail over. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*FailoverFS) {
+	return func(f *FailoverFS) {
+		if logger != nil {
+			f.logger = logger
+		}
+	}
+}
+
+// WithTransient sets the function used to determine whether an error is transient. The default is IsTransient.
+func WithTransient(transient func(error) bool) func(*FailoverFS) {
+	return func(f *FailoverFS) {
+		if transient != nil {
+			f.transient = transient
+		}
+	}
+}
+
+// backend tracks the health of a single file system.
+type backend struct {
+	err      error
+	failures int
+	fsys     fs.FS
+	index    int
+	until    time.Time
+}
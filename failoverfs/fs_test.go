@@ -0,0 +1,164 @@
+package failoverfs
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// flakyFS fails read operations with a transient error while it is down.
+type flakyFS struct {
+	fs.FS
+	down  bool
+	reads int
+}
+
+func (f *flakyFS) ReadFile(name string) ([]byte, error) {
+	f.reads++
+	if f.down {
+		return nil, &gofs.PathError{Op: "readFile", Path: name, Err: errUnavailable}
+	}
+	return f.FS.ReadFile(name)
+}
+
+func (f *flakyFS) Stat(name string) (gofs.FileInfo, error) {
+	f.reads++
+	if f.down {
+		return nil, &gofs.PathError{Op: "stat", Path: name, Err: errUnavailable}
+	}
+	return f.FS.Stat(name)
+}
+
+// FailoverFSTestSuite ...
+type FailoverFSTestSuite struct {
+	suite.Suite
+	ffs      *FailoverFS
+	now      time.Time
+	primary  *flakyFS
+	fallback *flakyFS
+}
+
+func NewFailoverFSTestSuite() *FailoverFSTestSuite {
+	return &FailoverFSTestSuite{}
+}
+
+func (t *FailoverFSTestSuite) SetupTest() {
+	t.primary = &flakyFS{FS: t.memfs("primary")}
+	t.fallback = &flakyFS{FS: t.memfs("fallback")}
+
+	ffs, err := New(t.primary, []fs.FS{t.fallback}, WithFailureThreshold(2), WithCooldown(time.Minute))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.now = time.Now()
+	ffs.now = func() time.Time { return t.now }
+	t.ffs = ffs
+}
+
+func TestFailoverFSTestSuite(t *testing.T) {
+	suite.Run(t, NewFailoverFSTestSuite())
+}
+
+func (t *FailoverFSTestSuite) memfs(content string) *memfs.MemFS {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte(content), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	return mfs
+}
+
+func (t *FailoverFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.ffs, "doc/fox.txt"))
+}
+
+func (t *FailoverFSTestSuite) TestFailover() {
+	b, err := t.ffs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "primary", string(b))
+
+	t.primary.down = true
+
+	b, err = t.ffs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "fallback", string(b))
+
+	_, err = t.ffs.ReadFile("doc/missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	t.fallback.down = true
+
+	_, err = t.ffs.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, errUnavailable)
+}
+
+func (t *FailoverFSTestSuite) TestHealth() {
+	t.primary.down = true
+
+	for i := 0; i < 2; i++ {
+		_, err := t.ffs.ReadFile("doc/fox.txt")
+		assert.NoError(t.T(), err)
+	}
+
+	health := t.ffs.Health()
+	assert.False(t.T(), health[0].Healthy)
+	assert.ErrorIs(t.T(), health[0].LastError, errUnavailable)
+	assert.True(t.T(), health[1].Healthy)
+
+	reads := t.primary.reads
+	_, err := t.ffs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), reads, t.primary.reads)
+
+	t.primary.down = false
+	t.now = t.now.Add(2 * time.Minute)
+
+	b, err := t.ffs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "primary", string(b))
+	assert.True(t.T(), t.ffs.Health()[0].Healthy)
+}
+
+func (t *FailoverFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ffs, err := New(t.primary, []fs.FS{t.fallback}, WithFailureThreshold(1), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.primary.down = true
+	_, err = ffs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `level=WARN msg="[failoverfs] backend unhealthy" index=0`)
+	assert.Contains(t.T(), buf.String(), `level=DEBUG msg="[failoverfs] failing over" index=0`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.ffs.logger)
+}
+
+func (t *FailoverFSTestSuite) TestWrite() {
+	assert.NoError(t.T(), t.ffs.WriteFile("doc/cat.txt", []byte("the cat"), 0644))
+
+	_, err := t.primary.FS.Stat("doc/cat.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = t.fallback.FS.Stat("doc/cat.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
@@ -0,0 +1,145 @@
+package fs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// Predicate reports whether the entry at path matches a condition.
+type Predicate func(path string, entry *Entry) bool
+
+// Query is a set of conditions for Find, built by chaining methods on the Query returned by Where. An entry matches a
+// Query if it matches every condition.
+type Query struct {
+	err        error
+	predicates []Predicate
+}
+
+// Where returns an empty Query, which matches every entry.
+func Where() *Query {
+	return &Query{}
+}
+
+// Dirs adds the condition that the entry is a directory.
+func (q *Query) Dirs() *Query {
+	return q.Match(func(_ string, e *Entry) bool { return e.IsDir() })
+}
+
+// Files adds the condition that the entry is a regular file.
+func (q *Query) Files() *Query {
+	return q.Match(func(_ string, e *Entry) bool { return e.Mode().IsRegular() })
+}
+
+// LargerThan adds the condition that the size of the entry is greater than n bytes.
+func (q *Query) LargerThan(n int64) *Query {
+	return q.Match(func(_ string, e *Entry) bool { return e.Size() > n })
+}
+
+// Match adds the condition that the entry matches fn.
+func (q *Query) Match(fn Predicate) *Query {
+	q.predicates = append(q.predicates, fn)
+	return q
+}
+
+// Matches reports whether the entry at path matches every condition of the Query.
+func (q *Query) Matches(path string, entry *Entry) bool {
+	for _, p := range q.predicates {
+		if !p(path, entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// NameMatches adds the condition that the name of the entry matches pattern, using the syntax of path.Match.
+func (q *Query) NameMatches(pattern string) *Query {
+	q.checkPattern(pattern)
+	return q.Match(func(p string, _ *Entry) bool {
+		ok, _ := gopath.Match(pattern, gopath.Base(p))
+		return ok
+	})
+}
+
+// NewerThan adds the condition that the entry was modified within the duration d before it is evaluated.
+func (q *Query) NewerThan(d time.Duration) *Query {
+	return q.Match(func(_ string, e *Entry) bool { return time.Since(e.ModTime()) < d })
+}
+
+// OlderThan adds the condition that the entry was last modified more than the duration d before it is evaluated.
+func (q *Query) OlderThan(d time.Duration) *Query {
+	return q.Match(func(_ string, e *Entry) bool { return time.Since(e.ModTime()) > d })
+}
+
+// PathMatches adds the condition that the path of the entry, as provided by Walk, matches pattern, using the syntax
+// of path.Match.
+func (q *Query) PathMatches(pattern string) *Query {
+	q.checkPattern(pattern)
+	return q.Match(func(p string, _ *Entry) bool {
+		ok, _ := gopath.Match(pattern, p)
+		return ok
+	})
+}
+
+// SmallerThan adds the condition that the size of the entry is less than n bytes.
+func (q *Query) SmallerThan(n int64) *Query {
+	return q.Match(func(_ string, e *Entry) bool { return e.Size() < n })
+}
+
+func (q *Query) checkPattern(pattern string) {
+	if _, err := gopath.Match(pattern, ""); err != nil && q.err == nil {
+		q.err = err
+	}
+}
+
+// Find walks the tree rooted at root using Walk, and returns the entries that match q, sorted by path. If q is nil,
+// every entry matches. The options are passed to Walk, so the traversal can be limited using options such as
+// WithMaxDepth and WithSkipPatterns.
+//
+// If a condition of q uses an invalid pattern, an error wrapping path.ErrBadPattern is returned.
+func Find(fsys gofs.FS, root string, q *Query, options ...WalkOption) ([]*Entry, error) {
+	if q == nil {
+		q = Where()
+	}
+
+	if q.err != nil {
+		return nil, fmt.Errorf("fs: invalid query: %w", q.err)
+	}
+
+	type match struct {
+		entry *Entry
+		path  string
+	}
+
+	var (
+		matches []match
+		mutex   sync.Mutex
+	)
+	err := Walk(fsys, root, func(p string, e *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if q.Matches(p, e) {
+			mutex.Lock()
+			matches = append(matches, match{entry: e, path: p})
+			mutex.Unlock()
+		}
+		return nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].path < matches[j].path })
+
+	entries := make([]*Entry, len(matches))
+	for i, m := range matches {
+		entries[i] = m.entry
+	}
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+package fs_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	files := []struct {
+		name  string
+		size  int
+		mtime time.Time
+	}{
+		{name: "logs/app.log", size: 2048, mtime: old},
+		{name: "logs/app.1.log", size: 16, mtime: old},
+		{name: "logs/current.log", size: 4096, mtime: time.Now()},
+		{name: "logs/archive/2020.log", size: 8192, mtime: old},
+		{name: "logs/notes.txt", size: 4096, mtime: old},
+	}
+	for _, f := range files {
+		name := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, []byte(strings.Repeat("x", f.size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(name, f.mtime, f.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(entries []*fs.Entry) []string {
+		var n []string
+		for _, e := range entries {
+			n = append(n, e.Name())
+		}
+		return n
+	}
+
+	entries, err := fs.Find(osfs, dir, fs.Where().NameMatches("*.log").OlderThan(30*24*time.Hour).LargerThan(1<<10))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.log", "2020.log"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, fs.Where().NameMatches("*.log").NewerThan(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"current.log"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, fs.Where().Files().SmallerThan(1<<10))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.1.log"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, fs.Where().Dirs(), fs.WithMaxDepth(1))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path.Base(dir), "logs"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, fs.Where().NameMatches("*.log"), fs.WithSkipPatterns("archive"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.1.log", "app.log", "current.log"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, fs.Where().PathMatches(filepath.Join(dir, "logs", "*.txt")))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"notes.txt"}, names(entries))
+
+	entries, err = fs.Find(osfs, dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 8)
+
+	_, err = fs.Find(osfs, dir, fs.Where().NameMatches("[").Files())
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
@@ -5,9 +5,9 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/transientvariable/hold"
-	"github.com/transientvariable/log-go"
 
 	gofs "io/fs"
 )
@@ -15,22 +15,13 @@ import (
 var (
 	defaultFS FS
 	mutex     sync.Mutex
-	once      sync.Once
 )
 
-// Initialize the default file system provider (e.g. memfs.MemFS).
+// Register the os/platform file system providers. The default file system is created when it is first used, so that
+// providers registered by other packages can be selected using EnvDefaultProvider.
 func init() {
-	once.Do(func() {
-		// Use osfs as opposed provider from config to ensure we have a working file system.
-		fsys, err := New()
-		if err != nil {
-			panic(err)
-		}
-
-		if err := SetDefault(fsys); err != nil {
-			panic(err)
-		}
-	})
+	Register("file", newOSFS)
+	Register("os", newOSFS)
 }
 
 const (
@@ -39,8 +30,22 @@ const (
 	O_RDWR   = os.O_RDWR
 	O_APPEND = os.O_APPEND
 	O_CREATE = os.O_CREATE
+	O_EXCL   = os.O_EXCL
 	O_TRUNC  = os.O_TRUNC
 
+	// O_IFMATCH opens a file for writing using optimistic concurrency control: writes using the File return an error
+	// wrapping ErrConflict if the generation of the content of the file, which is provided by the Attribute of the Entry
+	// returned by Stat, was changed by another writer since the file was opened. The flag does not correspond to a flag
+	// of the operating system, and providers that do not track generations return an error wrapping ErrUnsupported.
+	O_IFMATCH = 1 << 30
+
+	// O_DIRECT opens a file for direct I/O, so that reads and writes bypass the page cache of the operating system, for
+	// workloads that manage their own caching and must avoid buffering the content twice. Reads and writes of a file
+	// opened using O_DIRECT require buffers, offsets, and lengths that are aligned to DirectIOAlignment, which can be
+	// allocated using AlignedBuffer. Providers that do not use a page cache ignore the flag, and OSFS returns an error
+	// wrapping ErrUnsupported on platforms that do not support direct I/O.
+	O_DIRECT = 1 << 29
+
 	// MaxContentLen defines the maximum size in bytes for a File.
 	MaxContentLen = int(^uint(0) >> 1)
 )
@@ -55,6 +60,75 @@ type DirIterator interface {
 	NextN(n int) ([]*Entry, error)
 }
 
+// DirIteratorFS defines the behavior for iterating over the entries of a directory lazily, so that directories with a
+// large number of entries can be processed without reading every entry into memory. See IterateDir.
+type DirIteratorFS interface {
+	FS
+
+	// IterateDir returns a DirIterator over the entries of the named directory. Entries that are added or removed while
+	// the directory is iterated may or may not be returned.
+	IterateDir(name string) (DirIterator, error)
+}
+
+// ReadDirPager defines the behavior for listing the entries of a directory in pages, so that directories with a large
+// number of entries can be listed without loading every entry into memory.
+type ReadDirPager interface {
+	// ReadDirPage returns at most pageSize entries of the named directory sorted by filename, starting after the entries
+	// returned by the call that returned token. An empty token returns the first page.
+	//
+	// The returned nextToken is empty if there are no remaining entries, and is otherwise passed to the next call to
+	// retrieve the following page. Tokens are opaque and are only valid for the file system that returned them.
+	ReadDirPage(name string, pageSize int, token string) (entries []gofs.DirEntry, nextToken string, err error)
+}
+
+// PrefixReadDirPager defines the behavior for listing the entries of a directory whose names start with a prefix in
+// pages, so that a range of a directory with a large number of entries can be listed without reading the entries
+// outside of it, as object stores list keys by prefix.
+type PrefixReadDirPager interface {
+	ReadDirPager
+
+	// ReadDirPrefix returns at most pageSize entries of the named directory whose names start with prefix, sorted by
+	// filename and starting after the entries returned by the call that returned token, in the same way as ReadDirPage.
+	ReadDirPrefix(name string, prefix string, pageSize int, token string) (entries []gofs.DirEntry, nextToken string, err error)
+}
+
+// BatchWriteFS defines the behavior for writing many files in a single operation, so that providers can amortize the
+// cost of locking and resolving directories across the files, for example when seeding fixtures.
+type BatchWriteFS interface {
+	FS
+
+	// WriteFiles writes the data of each named file in files in the same way as WriteFile, creating missing parent
+	// directories. The files are written in lexical order of their names, and the first error is returned.
+	WriteFiles(files map[string][]byte, perm gofs.FileMode) error
+}
+
+// ConditionalWriteFS defines the behavior for writing files using optimistic concurrency control, so that concurrent
+// writers can detect that the content of a file was changed since they last read it.
+type ConditionalWriteFS interface {
+	FS
+
+	// WriteFileIf writes data to the named file in the same way as WriteFile, but only if the generation of the content
+	// of the file, which is provided by the Attribute of the Entry returned by Stat, is ifGeneration. If ifGeneration is
+	// 0, the file is only written if it does not exist. Otherwise, an error wrapping ErrConflict is returned and the file
+	// is not changed.
+	WriteFileIf(name string, data []byte, mode gofs.FileMode, ifGeneration int64) error
+
+	// WriteFileIfUnmodified replaces the content of the named file with data, but only if the modification time of the
+	// file is not after since. Otherwise, an error wrapping ErrConflict is returned and the file is not changed. An error
+	// wrapping ErrNotExist is returned if the file does not exist.
+	WriteFileIfUnmodified(name string, data []byte, since time.Time) error
+}
+
+// SizedReader defines the behavior for a reader that knows the number of bytes remaining to be read, so that the
+// space for the content can be allocated once before it is copied, for example by the io.ReaderFrom implementation of
+// a File. The *bytes.Buffer, *bytes.Reader, and *strings.Reader types implement SizedReader.
+type SizedReader interface {
+	io.Reader
+
+	// Len returns the number of bytes remaining to be read.
+	Len() int
+}
+
 // File defines the behavior for providing access to a single file. This interface is an extension of the fs.Name
 // interface and defines additional behavior for read/write operations.
 type File interface {
@@ -131,14 +205,28 @@ func SetDefault(fs FS) error {
 	defer mutex.Unlock()
 
 	if defaultFS != nil {
-		log.Info("[fs] setting default file system", log.String("provider", fs.Provider()))
+		log().Info("[fs] setting default file system", "provider", fs.Provider())
 	}
 	defaultFS = fs
 	return nil
 }
 
 // Default returns the current default for the file system backend.
+//
+// If a default has not been set using SetDefault, the default is created on first use from the provider selected
+// using the EnvDefaultProvider environment variable, or is an OSFS if the variable is not set. Default panics if the
+// selected provider cannot be created.
 func Default() FS {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if defaultFS == nil {
+		fsys, err := defaultProvider()
+		if err != nil {
+			panic(err)
+		}
+		defaultFS = fsys
+	}
 	return defaultFS
 }
 
@@ -167,6 +255,11 @@ func Open(name string) (gofs.File, error) {
 	return Default().Open(name)
 }
 
+// OpenAppend opens the named file for appending using the default file system, creating it if it does not exist.
+func OpenAppend(name string) (File, error) {
+	return Default().OpenFile(name, O_WRONLY|O_CREATE|O_APPEND, 0666)
+}
+
 // OpenFile ...
 func OpenFile(name string, flag int, perm gofs.FileMode) (File, error) {
 	return Default().OpenFile(name, flag, perm)
@@ -0,0 +1,419 @@
+// Package fstestsuite provides the behavioral tests that the providers of this module pass, so that any implementation
+// of fs.FS can verify that it conforms to the same semantics.
+//
+// A provider is tested by calling Run from a test with a function that returns an empty, writable file system:
+//
+//	func TestConformance(t *testing.T) {
+//		fstestsuite.Run(t, func(t *testing.T) fs.FS {
+//			fsys, err := myfs.New()
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return fsys
+//		})
+//	}
+package fstestsuite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+// Test names that can be passed to WithSkip.
+const (
+	TestConcurrentAccess = "ConcurrentAccess"
+	TestErrors           = "Errors"
+	TestFSTest           = "FSTest"
+	TestMkdir            = "Mkdir"
+	TestOpenFileFlags    = "OpenFileFlags"
+	TestReadDirOrder     = "ReadDirOrder"
+	TestRemove           = "Remove"
+	TestRename           = "Rename"
+	TestSeek             = "Seek"
+	TestWriteFile        = "WriteFile"
+)
+
+// NewFS returns an empty, writable file system for a single test. Any cleanup of the file system, such as removing a
+// temporary directory, should be registered using t.Cleanup.
+type NewFS func(t *testing.T) fs.FS
+
+// Option defines an option for Run.
+type Option func(*options)
+
+type options struct {
+	concurrency int
+	skip        []string
+}
+
+// Run runs the conformance tests as subtests of t, calling newFS to create the file system for each test.
+func Run(t *testing.T, newFS NewFS, opts ...Option) {
+	o := &options{concurrency: 8}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, fsys fs.FS, o *options)
+	}{
+		{TestConcurrentAccess, testConcurrentAccess},
+		{TestErrors, testErrors},
+		{TestFSTest, testFSTest},
+		{TestMkdir, testMkdir},
+		{TestOpenFileFlags, testOpenFileFlags},
+		{TestReadDirOrder, testReadDirOrder},
+		{TestRemove, testRemove},
+		{TestRename, testRename},
+		{TestSeek, testSeek},
+		{TestWriteFile, testWriteFile},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if slices.Contains(o.skip, tc.name) {
+				t.Skip("skipped using fstestsuite.WithSkip")
+			}
+
+			fsys := newFS(t)
+			if fsys == nil {
+				t.Fatal("fstestsuite: file system is required")
+			}
+			tc.fn(t, fsys, o)
+		})
+	}
+}
+
+// WithConcurrency sets the number of goroutines used by the tests of concurrent access. The default is 8.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// WithSkip skips the named tests, such as TestSeek, for behavior that a provider does not support.
+func WithSkip(names ...string) Option {
+	return func(o *options) {
+		o.skip = append(o.skip, names...)
+	}
+}
+
+func testConcurrentAccess(t *testing.T, fsys fs.FS, o *options) {
+	noError(t, fsys.MkdirAll("shared", 0755))
+	noError(t, fsys.WriteFile("shared/fox.txt", []byte("the quick brown fox"), 0644))
+
+	var wg sync.WaitGroup
+	for i := range o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			name := fmt.Sprintf("shared/%d.txt", i)
+			for j := range 10 {
+				data := []byte(fmt.Sprintf("writer %d write %d", i, j))
+				if !assert.NoError(t, fsys.WriteFile(name, data, 0644)) {
+					return
+				}
+
+				b, err := fsys.ReadFile(name)
+				if assert.NoError(t, err) {
+					assert.Equal(t, string(data), string(b))
+				}
+
+				b, err = fsys.ReadFile("shared/fox.txt")
+				if assert.NoError(t, err) {
+					assert.Equal(t, "the quick brown fox", string(b))
+				}
+
+				_, err = fsys.Stat("shared")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := fsys.ReadDir("shared")
+	noError(t, err)
+	assert.Len(t, entries, o.concurrency+1)
+}
+
+func testErrors(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	noError(t, fsys.MkdirAll("doc", 0755))
+
+	_, err := fsys.Stat("missing.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist, "Stat of a missing file")
+
+	_, err = fsys.Open("missing.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist, "Open of a missing file")
+
+	_, err = fsys.ReadFile("missing.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist, "ReadFile of a missing file")
+
+	_, err = fsys.ReadDir("missing")
+	assert.ErrorIs(t, err, gofs.ErrNotExist, "ReadDir of a missing directory")
+
+	_, err = fsys.ReadFile("doc")
+	assert.Error(t, err, "ReadFile of a directory")
+
+	assert.ErrorIs(t, fsys.Mkdir("doc", 0755), gofs.ErrExist, "Mkdir of an existing directory")
+	assert.ErrorIs(t, fsys.Remove("missing.txt"), gofs.ErrNotExist, "Remove of a missing file")
+
+	var pathErr *gofs.PathError
+	_, err = fsys.Stat("missing.txt")
+	assert.ErrorAs(t, err, &pathErr, "errors are *gofs.PathError")
+}
+
+func testFSTest(t *testing.T, fsys fs.FS, _ *options) {
+	files := map[string]string{
+		"fox.txt":           "the quick brown fox",
+		"doc/dog.txt":       "the lazy dog",
+		"doc/nested/cat.md": "the cat",
+		"empty.txt":         "",
+	}
+
+	var names []string
+	for name, data := range files {
+		noError(t, fsys.MkdirAll(fs.Dir(fsys, name), 0755))
+		noError(t, fsys.WriteFile(name, []byte(data), 0644))
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	assert.NoError(t, fstest.TestFS(fsys, names...))
+}
+
+func testMkdir(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.Mkdir("doc", 0755))
+	noError(t, fsys.MkdirAll("doc/a/b/c", 0755))
+	assert.NoError(t, fsys.MkdirAll("doc/a/b/c", 0755), "MkdirAll of an existing directory")
+
+	for _, name := range []string{"doc", "doc/a", "doc/a/b", "doc/a/b/c"} {
+		fi, err := fsys.Stat(name)
+		if assert.NoError(t, err) {
+			assert.True(t, fi.IsDir(), name)
+			assert.Equal(t, fs.Base(fsys, name), fi.Name())
+		}
+	}
+
+	assert.Error(t, fsys.Mkdir("missing/doc", 0755), "Mkdir with a missing parent")
+}
+
+func testOpenFileFlags(t *testing.T, fsys fs.FS, _ *options) {
+	_, err := fsys.OpenFile("fox.txt", fs.O_RDONLY, 0)
+	assert.ErrorIs(t, err, gofs.ErrNotExist, "O_RDONLY of a missing file")
+
+	// O_CREATE creates a missing file.
+	f, err := fsys.OpenFile("fox.txt", fs.O_RDWR|fs.O_CREATE, 0644)
+	noError(t, err)
+	_, err = f.Write([]byte("the quick brown fox"))
+	assert.NoError(t, err)
+	noError(t, f.Close())
+
+	b, err := fsys.ReadFile("fox.txt")
+	noError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	// O_TRUNC discards the content of an existing file.
+	f, err = fsys.OpenFile("fox.txt", fs.O_WRONLY|fs.O_TRUNC, 0644)
+	noError(t, err)
+	_, err = f.Write([]byte("the lazy dog"))
+	assert.NoError(t, err)
+	noError(t, f.Close())
+
+	b, err = fsys.ReadFile("fox.txt")
+	noError(t, err)
+	assert.Equal(t, "the lazy dog", string(b))
+
+	// Files opened for reading can not be written.
+	f, err = fsys.OpenFile("fox.txt", fs.O_RDONLY, 0)
+	noError(t, err)
+	_, err = f.Write([]byte("the cat"))
+	assert.Error(t, err, "Write to a file opened with O_RDONLY")
+	noError(t, f.Close())
+
+	// Files opened for writing can not be read.
+	f, err = fsys.OpenFile("fox.txt", fs.O_WRONLY, 0)
+	noError(t, err)
+	_, err = f.Read(make([]byte, 8))
+	assert.Error(t, err, "Read from a file opened with O_WRONLY")
+	noError(t, f.Close())
+
+	b, err = fsys.ReadFile("fox.txt")
+	noError(t, err)
+	assert.Equal(t, "the lazy dog", string(b))
+
+	// Create truncates an existing file.
+	f, err = fsys.Create("fox.txt")
+	noError(t, err)
+	noError(t, f.Close())
+
+	fi, err := fsys.Stat("fox.txt")
+	noError(t, err)
+	assert.Zero(t, fi.Size())
+}
+
+func testReadDirOrder(t *testing.T, fsys fs.FS, _ *options) {
+	names := []string{"b.txt", "a.txt", "c", "B.txt", "_x.txt", "aa.txt", "a0.txt"}
+	noError(t, fsys.MkdirAll("doc", 0755))
+	for _, name := range names {
+		if name == "c" {
+			noError(t, fsys.Mkdir("doc/c", 0755))
+			continue
+		}
+		noError(t, fsys.WriteFile("doc/"+name, []byte(name), 0644))
+	}
+
+	entries, err := fsys.ReadDir("doc")
+	noError(t, err)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+		assert.Equal(t, e.Name() == "c", e.IsDir(), e.Name())
+	}
+
+	slices.Sort(names)
+	assert.Equal(t, names, got, "ReadDir returns entries sorted by filename")
+}
+
+func testRemove(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.MkdirAll("doc/a", 0755))
+	noError(t, fsys.WriteFile("doc/a/fox.txt", []byte("the quick brown fox"), 0644))
+
+	assert.Error(t, fsys.Remove("doc/a"), "Remove of a directory that is not empty")
+
+	noError(t, fsys.Remove("doc/a/fox.txt"))
+	_, err := fsys.Stat("doc/a/fox.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	noError(t, fsys.Remove("doc/a"))
+	_, err = fsys.Stat("doc/a")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	noError(t, fsys.MkdirAll("doc/b/c", 0755))
+	noError(t, fsys.WriteFile("doc/b/c/dog.txt", []byte("the lazy dog"), 0644))
+	noError(t, fsys.RemoveAll("doc"))
+	_, err = fsys.Stat("doc")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	assert.NoError(t, fsys.RemoveAll("missing"), "RemoveAll of a missing path")
+}
+
+func testRename(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.MkdirAll("doc", 0755))
+	noError(t, fsys.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	noError(t, fsys.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+
+	// Renaming a file over an existing file replaces it.
+	noError(t, fsys.Rename("doc/fox.txt", "doc/dog.txt"))
+	_, err := fsys.Stat("doc/fox.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	b, err := fsys.ReadFile("doc/dog.txt")
+	noError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	// Renaming a directory moves its content.
+	noError(t, fsys.MkdirAll("archive", 0755))
+	noError(t, fsys.Rename("doc", "archive/doc"))
+	b, err = fsys.ReadFile("archive/doc/dog.txt")
+	noError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	_, err = fsys.Stat("doc")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	assert.ErrorIs(t, fsys.Rename("missing.txt", "other.txt"), gofs.ErrNotExist, "Rename of a missing file")
+}
+
+func testSeek(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+
+	f, err := fsys.Open("fox.txt")
+	noError(t, err)
+	defer f.Close()
+
+	s, ok := f.(io.ReadSeeker)
+	if !ok {
+		t.Skip("files do not implement io.Seeker")
+	}
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+		read   string
+	}{
+		{offset: 4, whence: io.SeekStart, want: 4, read: "quick"},
+		{offset: 1, whence: io.SeekCurrent, want: 10, read: "brown"},
+		{offset: -3, whence: io.SeekEnd, want: 16, read: "fox"},
+		{offset: 0, whence: io.SeekStart, want: 0, read: "the"},
+	}
+
+	for _, tc := range tests {
+		n, err := s.Seek(tc.offset, tc.whence)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, tc.want, n)
+
+		b := make([]byte, len(tc.read))
+		_, err = io.ReadFull(s, b)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.read, string(b))
+	}
+
+	_, err = s.Seek(-1, io.SeekStart)
+	assert.Error(t, err, "Seek to a negative offset")
+
+	// Reading at the end of the file returns io.EOF.
+	_, err = s.Seek(0, io.SeekEnd)
+	noError(t, err)
+	_, err = s.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, io.EOF), "Read at the end of the file returns io.EOF")
+}
+
+func testWriteFile(t *testing.T, fsys fs.FS, _ *options) {
+	noError(t, fsys.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+
+	fi, err := fsys.Stat("fox.txt")
+	noError(t, err)
+	assert.Equal(t, "fox.txt", fi.Name())
+	assert.Equal(t, int64(19), fi.Size())
+	assert.True(t, fi.Mode().IsRegular())
+	assert.False(t, fi.ModTime().IsZero())
+
+	// WriteFile replaces the content of an existing file.
+	noError(t, fsys.WriteFile("fox.txt", []byte("the dog"), 0644))
+	b, err := fsys.ReadFile("fox.txt")
+	noError(t, err)
+	assert.Equal(t, "the dog", string(b))
+
+	fi, err = fsys.Stat("fox.txt")
+	noError(t, err)
+	assert.Equal(t, int64(7), fi.Size())
+
+	noError(t, fsys.WriteFile("empty.txt", nil, 0644))
+	b, err = fsys.ReadFile("empty.txt")
+	noError(t, err)
+	assert.Empty(t, b)
+}
+
+// noError stops the test if err is not nil, for steps that the remaining assertions of the test depend on.
+func noError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,33 @@
+package fstestsuite
+
+import (
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+)
+
+func TestMemFS(t *testing.T) {
+	Run(t, func(t *testing.T) fs.FS {
+		mfs, err := memfs.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mfs
+	})
+}
+
+func TestOSFS(t *testing.T) {
+	Run(t, func(t *testing.T) fs.FS {
+		osfs, err := fs.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fsys, err := fs.Chroot(osfs, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fsys
+	})
+}
@@ -0,0 +1,114 @@
+//go:build linux
+
+package fuse
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// FUSETestSuite ...
+type FUSETestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	server  *Server
+}
+
+func NewFUSETestSuite() *FUSETestSuite {
+	return &FUSETestSuite{}
+}
+
+func (t *FUSETestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+
+	server, err := Mount(backing, t.T().TempDir(), WithTimeout(0))
+	if err != nil {
+		t.T().Skipf("FUSE is not available: %v", err)
+	}
+	t.server = server
+}
+
+func (t *FUSETestSuite) TearDownTest() {
+	if t.server != nil {
+		assert.NoError(t.T(), t.server.Unmount())
+		t.server = nil
+	}
+}
+
+func TestFUSETestSuite(t *testing.T) {
+	suite.Run(t, NewFUSETestSuite())
+}
+
+func (t *FUSETestSuite) path(name string) string {
+	return filepath.Join(t.server.Dir(), name)
+}
+
+func (t *FUSETestSuite) TestRead() {
+	b, err := os.ReadFile(t.path("doc/fox.txt"))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	entries, err := os.ReadDir(t.path("doc"))
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+	assert.Equal(t.T(), "fox.txt", entries[0].Name())
+
+	fi, err := os.Stat(t.path("doc/fox.txt"))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(19), fi.Size())
+
+	_, err = os.Stat(t.path("doc/missing.txt"))
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *FUSETestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server, err := Mount(t.backing, t.T().TempDir(), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), server.Unmount())
+	assert.Contains(t.T(), buf.String(), `msg="[fuse] mount" dir=`+server.Dir()+` provider=memfs`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.server.logger)
+}
+
+func (t *FUSETestSuite) TestWrite() {
+	assert.NoError(t.T(), os.WriteFile(t.path("doc/dog.txt"), []byte("jumps over the lazy dog"), 0644))
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	assert.NoError(t.T(), os.Mkdir(t.path("tmp"), 0755))
+	assert.NoError(t.T(), os.Rename(t.path("doc/dog.txt"), t.path("tmp/dog.txt")))
+
+	_, err = t.backing.Stat("tmp/dog.txt")
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), os.Remove(t.path("tmp/dog.txt")))
+	assert.NoError(t.T(), os.Remove(t.path("tmp")))
+
+	_, err = t.backing.Stat("tmp")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
@@ -0,0 +1,110 @@
+// Package fuse mounts an fs.FS as a file system in user space (FUSE), so that it can be inspected and modified using
+// standard tools.
+//
+// The FUSE kernel protocol is implemented directly on top of /dev/fuse and is only supported on Linux. When the
+// calling process is not permitted to mount file systems, the mount is delegated to fusermount3 or fusermount if one
+// is available on the PATH.
+//
+// macOS is not supported, since macFUSE mounts file systems using its own mount helper and device rather than
+// /dev/fuse and fusermount. On macOS and every other platform, Mount returns an error wrapping errors.ErrUnsupported,
+// so that callers can fall back to another way of exposing a file system, such as the webdav or sftp packages.
+//
+// File contents, directories and renames are mapped to the corresponding fs.FS operations. Since fs.FS does not
+// provide operations for changing the mode, ownership or times of an entry, such changes are accepted but are not
+// persisted, and all entries are reported as being owned by the user that mounted the file system.
+package fuse
+
+import (
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const (
+	// DefaultFSName is the name of the mounted file system reported by the kernel, for example in /proc/mounts.
+	DefaultFSName = "fsgo"
+
+	// DefaultTimeout is the duration for which the kernel may cache entries and attributes.
+	DefaultTimeout = time.Second
+)
+
+// Server serves an fs.FS at a mount point.
+type Server struct {
+	allowOther bool
+	dir        string
+	done       chan struct{}
+	err        error
+	fsName     string
+	fsys       fs.FS
+	fusermount bool
+	gid        uint32
+	handles    map[uint64]*handle
+	logger     fs.Logger
+	nextHandle uint64
+	nextNode   uint64
+	nodes      map[uint64]*node
+	paths      map[string]uint64
+	polled     bool
+	ready      chan struct{}
+	timeout    time.Duration
+	uid        uint32
+}
+
+// Dir returns the absolute path of the mount point.
+func (s *Server) Dir() string {
+	return s.dir
+}
+
+// Wait blocks until the file system is unmounted, and returns the error that caused the Server to stop, if any.
+func (s *Server) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// WithAllowOther allows users other than the user that mounted the file system to access it.
+func WithAllowOther() func(*Server) {
+	return func(s *Server) {
+		s.allowOther = true
+	}
+}
+
+// WithFSName sets the name of the mounted file system.
+func WithFSName(name string) func(*Server) {
+	return func(s *Server) {
+		s.fsName = name
+	}
+}
+
+// WithLogger sets the Logger used by a Server to log the file system that is mounted, and the errors that occur while
+// serving it, which can not be returned to a caller. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*Server) {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithTimeout sets the duration for which the kernel may cache entries and attributes. A timeout of zero disables
+// caching, which is useful when the fs.FS is also modified directly.
+func WithTimeout(timeout time.Duration) func(*Server) {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// handle is an open file or directory.
+type handle struct {
+	entries []gofs.DirEntry
+	file    fs.File
+	flag    int
+	off     int64
+}
+
+// node is an entry that the kernel has looked up. A node with an empty path has been removed.
+type node struct {
+	lookups uint64
+	path    string
+}
@@ -0,0 +1,229 @@
+//go:build linux
+
+package fuse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Mount mounts fsys at the directory dir and serves it until the file system is unmounted.
+//
+// Mount returns once the kernel has completed the FUSE handshake, at which point the mounted file system can be used.
+func Mount(fsys fs.FS, dir string, options ...func(*Server)) (*Server, error) {
+	if fsys == nil {
+		return nil, errors.New("fuse: file system is required")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fuse: %w", err)
+	}
+
+	s := &Server{
+		dir:      dir,
+		done:     make(chan struct{}),
+		fsName:   DefaultFSName,
+		fsys:     fsys,
+		gid:      uint32(os.Getgid()),
+		handles:  make(map[uint64]*handle),
+		logger:   fs.NopLogger(),
+		nextNode: rootNode + 1,
+		nodes:    map[uint64]*node{rootNode: {path: "."}},
+		paths:    map[string]uint64{".": rootNode},
+		ready:    make(chan struct{}),
+		timeout:  DefaultTimeout,
+		uid:      uint32(os.Getuid()),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	dev, err := s.mount()
+	if err != nil {
+		return nil, fmt.Errorf("fuse: %w", &gofs.PathError{Op: "mount", Path: dir, Err: err})
+	}
+
+	go s.serve(dev)
+
+	select {
+	case <-s.ready:
+		if err := s.pollHack(); err != nil {
+			s.logger.Error("[fuse] mount", "dir", dir, "error", err)
+		}
+		s.logger.Debug("[fuse] mount", "dir", dir, "provider", fsys.Provider())
+		return s, nil
+	case <-s.done:
+		if err := s.unmount(); err != nil {
+			s.logger.Error("[fuse] mount", "dir", dir, "error", err)
+		}
+		return nil, fmt.Errorf("fuse: %w", &gofs.PathError{Op: "mount", Path: dir, Err: s.err})
+	}
+}
+
+// Unmount unmounts the file system and waits for the Server to stop.
+func (s *Server) Unmount() error {
+	if err := s.unmount(); err != nil {
+		return fmt.Errorf("fuse: %w", &gofs.PathError{Op: "unmount", Path: s.dir, Err: err})
+	}
+	return s.Wait()
+}
+
+// pollHack adds a file on the mounted file system to an epoll instance, so that the kernel learns that the Server does
+// not support polling. Otherwise, the kernel sends a poll request when the Go runtime adds a file opened from the mount
+// to its network poller, which deadlocks the process when GOMAXPROCS is 1.
+func (s *Server) pollHack() error {
+	fd, err := syscall.Open(filepath.Join(s.dir, pollHackName), syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(epfd)
+
+	// syscall.EpollCtl does not release the P while in the kernel, which would prevent the Server from replying.
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN}
+	_, _, errno := syscall.Syscall6(syscall.SYS_EPOLL_CTL, uintptr(epfd), syscall.EPOLL_CTL_ADD, uintptr(fd),
+		uintptr(unsafe.Pointer(&event)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (s *Server) mount() (int, error) {
+	dev, err := syscall.Open("/dev/fuse", syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	opts := []string{
+		fmt.Sprintf("fd=%d", dev),
+		"rootmode=40000",
+		fmt.Sprintf("user_id=%d", s.uid),
+		fmt.Sprintf("group_id=%d", s.gid),
+	}
+	if s.allowOther {
+		opts = append(opts, "allow_other")
+	}
+
+	err = syscall.Mount(s.fsName, s.dir, "fuse", syscall.MS_NOSUID|syscall.MS_NODEV, strings.Join(opts, ","))
+	if err == nil {
+		return dev, nil
+	}
+	syscall.Close(dev)
+
+	if !errors.Is(err, syscall.EPERM) {
+		return -1, err
+	}
+
+	opts = []string{"fsname=" + s.fsName, "nosuid", "nodev"}
+	if s.allowOther {
+		opts = append(opts, "allow_other")
+	}
+
+	dev, ferr := fusermount(s.dir, strings.Join(opts, ","))
+	if ferr != nil {
+		return -1, fmt.Errorf("%w: %w", err, ferr)
+	}
+	s.fusermount = true
+	return dev, nil
+}
+
+func (s *Server) unmount() error {
+	err := syscall.Unmount(s.dir, 0)
+	if err == nil || !s.fusermount || !errors.Is(err, syscall.EPERM) {
+		return err
+	}
+
+	bin, err := fusermountPath()
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "-u", s.dir)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// fusermount mounts dir using the setuid fusermount helper, which passes the opened /dev/fuse descriptor back over a
+// socket.
+func fusermount(dir string, opts string) (int, error) {
+	bin, err := fusermountPath()
+	if err != nil {
+		return -1, err
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	local := os.NewFile(uintptr(fds[0]), "fusermount")
+	defer local.Close()
+
+	remote := os.NewFile(uintptr(fds[1]), "fusermount")
+	defer remote.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "-o", opts, "--", dir)
+	cmd.Env = append(os.Environ(), "_FUSE_COMMFD=3")
+	cmd.ExtraFiles = []*os.File{remote}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return -1, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := syscall.Recvmsg(int(local.Fd()), buf, oob, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return -1, err
+	}
+
+	if len(msgs) != 1 {
+		return -1, errors.New("fusermount did not return a file descriptor")
+	}
+
+	rights, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return -1, err
+	}
+
+	if len(rights) != 1 {
+		return -1, errors.New("fusermount did not return a file descriptor")
+	}
+	return rights[0], nil
+}
+
+func fusermountPath() (string, error) {
+	bin, err := exec.LookPath("fusermount3")
+	if err != nil {
+		return exec.LookPath("fusermount")
+	}
+	return bin, nil
+}
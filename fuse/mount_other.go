@@ -0,0 +1,30 @@
+//go:build !linux
+
+package fuse
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Mount mounts fsys at the directory dir and serves it until the file system is unmounted.
+//
+// Mount is only supported on Linux, and returns an error wrapping errors.ErrUnsupported on other platforms, including
+// macOS.
+func Mount(fsys fs.FS, dir string, options ...func(*Server)) (*Server, error) {
+	return nil, fmt.Errorf("fuse: %w", &gofs.PathError{
+		Op:   "mount",
+		Path: dir,
+		Err:  fmt.Errorf("%w on %s", errors.ErrUnsupported, runtime.GOOS),
+	})
+}
+
+// Unmount unmounts the file system and waits for the Server to stop.
+func (s *Server) Unmount() error {
+	return fmt.Errorf("fuse: %w", &gofs.PathError{Op: "unmount", Path: s.dir, Err: errors.ErrUnsupported})
+}
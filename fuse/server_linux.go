@@ -0,0 +1,853 @@
+//go:build linux
+
+package fuse
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// Operation codes defined by the FUSE kernel protocol.
+const (
+	opLookup      = 1
+	opForget      = 2
+	opGetattr     = 3
+	opSetattr     = 4
+	opMknod       = 8
+	opMkdir       = 9
+	opUnlink      = 10
+	opRmdir       = 11
+	opRename      = 12
+	opOpen        = 14
+	opRead        = 15
+	opWrite       = 16
+	opStatfs      = 17
+	opRelease     = 18
+	opFsync       = 20
+	opFlush       = 25
+	opInit        = 26
+	opOpendir     = 27
+	opReaddir     = 28
+	opReleasedir  = 29
+	opFsyncdir    = 30
+	opAccess      = 34
+	opCreate      = 35
+	opInterrupt   = 36
+	opPoll        = 40
+	opDestroy     = 38
+	opBatchForget = 42
+	opRename2     = 45
+)
+
+const (
+	protocolMajor    = 7
+	protocolMinor    = 31
+	protocolMinMinor = 13
+
+	initAtomicOTrunc = 1 << 3
+	initBigWrites    = 1 << 5
+
+	getattrFH = 1 << 0
+
+	setattrSize = 1 << 3
+	setattrFH   = 1 << 6
+
+	inHeaderSize  = 40
+	outHeaderSize = 16
+	maxWrite      = 128 * 1024
+	blockSize     = 4096
+	unknownIno    = 0xffffffff
+	rootNode      = 1
+	pollHackNode  = ^uint64(0)
+	pollHackName  = ".fuse-poll-hack"
+	openFlags     = syscall.O_ACCMODE | syscall.O_APPEND | syscall.O_TRUNC | syscall.O_EXCL
+)
+
+var endian = binary.NativeEndian
+
+// request is a request read from the kernel.
+type request struct {
+	body   []byte
+	node   uint64
+	opcode uint32
+	unique uint64
+}
+
+func (r *request) u32(off int) uint32 {
+	if off+4 > len(r.body) {
+		return 0
+	}
+	return endian.Uint32(r.body[off:])
+}
+
+func (r *request) u64(off int) uint64 {
+	if off+8 > len(r.body) {
+		return 0
+	}
+	return endian.Uint64(r.body[off:])
+}
+
+// names returns the NUL-terminated names that start at off in the body of the request.
+func (r *request) names(off int) []string {
+	if off > len(r.body) {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(r.body[off:]), "\x00"), "\x00")
+}
+
+func (s *Server) serve(dev int) {
+	defer close(s.done)
+	defer syscall.Close(dev)
+
+	buf := make([]byte, maxWrite+blockSize)
+	for {
+		n, err := syscall.Read(dev, buf)
+		if err != nil {
+			switch {
+			case errors.Is(err, syscall.EINTR), errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.ENOENT):
+				continue
+			case errors.Is(err, syscall.ENODEV):
+				s.closeHandles()
+				return
+			}
+			s.logger.Error("[fuse] read", "dir", s.dir, "error", err)
+			s.err = err
+			return
+		}
+
+		if n == 0 {
+			s.closeHandles()
+			return
+		}
+
+		if n < inHeaderSize {
+			continue
+		}
+
+		r := &request{
+			body:   buf[inHeaderSize:n],
+			node:   endian.Uint64(buf[16:]),
+			opcode: endian.Uint32(buf[4:]),
+			unique: endian.Uint64(buf[8:]),
+		}
+		if !s.dispatch(dev, r) {
+			s.closeHandles()
+			return
+		}
+	}
+}
+
+// dispatch handles a single request, and returns false if the Server should stop.
+func (s *Server) dispatch(dev int, r *request) bool {
+	var (
+		out []byte
+		err error
+	)
+
+	switch r.opcode {
+	case opForget:
+		s.forget(r.node, r.u64(0))
+		return true
+	case opBatchForget:
+		for i := 0; i < int(r.u32(0)); i++ {
+			s.forget(r.u64(8+i*16), r.u64(16+i*16))
+		}
+		return true
+	case opInterrupt:
+		return true
+	case opInit:
+		out, err = s.init(r)
+	case opDestroy:
+		s.reply(dev, r, nil, nil)
+		return false
+	case opLookup:
+		out, err = s.lookup(r)
+	case opGetattr:
+		out, err = s.getattr(r)
+	case opSetattr:
+		out, err = s.setattr(r)
+	case opMknod:
+		out, err = s.mknod(r)
+	case opMkdir:
+		out, err = s.mkdir(r)
+	case opUnlink:
+		err = s.unlink(r)
+	case opRmdir:
+		err = s.rmdir(r)
+	case opRename:
+		err = s.rename(r, r.u64(0), r.names(8))
+	case opRename2:
+		if r.u32(8) != 0 {
+			err = syscall.EINVAL
+			break
+		}
+		err = s.rename(r, r.u64(0), r.names(16))
+	case opOpen:
+		out, err = s.open(r)
+	case opCreate:
+		out, err = s.create(r)
+	case opRead:
+		out, err = s.read(r)
+	case opWrite:
+		out, err = s.write(r)
+	case opFlush, opFsync:
+		err = s.sync(r.u64(0))
+	case opRelease, opReleasedir:
+		err = s.release(r.u64(0))
+	case opOpendir:
+		out, err = s.opendir(r)
+	case opReaddir:
+		out, err = s.readdir(r)
+	case opStatfs:
+		out = s.statfs()
+	case opPoll:
+		s.polled = true
+		err = syscall.ENOSYS
+	case opAccess, opFsyncdir:
+	default:
+		err = syscall.ENOSYS
+	}
+
+	s.reply(dev, r, out, err)
+
+	if r.opcode == opInit {
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		select {
+		case <-s.ready:
+		default:
+			close(s.ready)
+		}
+	}
+	return true
+}
+
+func (s *Server) reply(dev int, r *request, out []byte, err error) {
+	b := make([]byte, outHeaderSize, outHeaderSize+len(out))
+	endian.PutUint32(b[0:], uint32(outHeaderSize+len(out)))
+	if err != nil {
+		endian.PutUint32(b[4:], uint32(-int32(errno(err))))
+		b = b[:outHeaderSize]
+	} else {
+		b = append(b, out...)
+	}
+	endian.PutUint64(b[8:], r.unique)
+
+	if _, err := syscall.Write(dev, b); err != nil && !errors.Is(err, syscall.ENOENT) {
+		s.logger.Error("[fuse] reply", "opcode", int(r.opcode), "error", err)
+	}
+}
+
+func (s *Server) init(r *request) ([]byte, error) {
+	major, minor := r.u32(0), r.u32(4)
+	if major < protocolMajor || (major == protocolMajor && minor < protocolMinMinor) {
+		return nil, syscall.EPROTO
+	}
+	minor = min(minor, protocolMinor)
+
+	out := make([]byte, 0, 64)
+	out = endian.AppendUint32(out, protocolMajor)
+	out = endian.AppendUint32(out, minor)
+	out = endian.AppendUint32(out, r.u32(8))
+	out = endian.AppendUint32(out, r.u32(12)&(initAtomicOTrunc|initBigWrites))
+	out = endian.AppendUint16(out, 0)
+	out = endian.AppendUint16(out, 0)
+	out = endian.AppendUint32(out, maxWrite)
+	out = endian.AppendUint32(out, 1)
+	return append(out, make([]byte, 64-len(out))...), nil
+}
+
+func (s *Server) lookup(r *request) ([]byte, error) {
+	name, err := s.child(r.node, r.names(0))
+	if err != nil {
+		return nil, err
+	}
+
+	if name == pollHackName && !s.polled {
+		out := endian.AppendUint64(nil, pollHackNode)
+		out = append(out, make([]byte, 32)...)
+		return s.appendAttr(out, pollHackNode, pollHackInfo{}), nil
+	}
+	return s.entry(name)
+}
+
+func (s *Server) getattr(r *request) ([]byte, error) {
+	if r.node == pollHackNode {
+		return s.attrOut(r.node, pollHackInfo{}), nil
+	}
+
+	if r.u32(0)&getattrFH != 0 {
+		if h, ok := s.handles[r.u64(8)]; ok && h.file != nil {
+			fi, err := h.file.Stat()
+			if err != nil {
+				return nil, err
+			}
+			return s.attrOut(r.node, fi), nil
+		}
+	}
+
+	name, err := s.path(r.node)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.attrOut(r.node, fi), nil
+}
+
+func (s *Server) setattr(r *request) ([]byte, error) {
+	name, err := s.path(r.node)
+	if err != nil {
+		return nil, err
+	}
+
+	if valid := r.u32(0); valid&setattrSize != 0 {
+		var h *handle
+		if valid&setattrFH != 0 {
+			h = s.handles[r.u64(8)]
+		}
+
+		if err := s.truncate(name, h, int64(r.u64(16))); err != nil {
+			return nil, err
+		}
+	}
+
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.attrOut(r.node, fi), nil
+}
+
+func (s *Server) truncate(name string, h *handle, size int64) error {
+	if h != nil && h.file != nil {
+		if t, ok := h.file.(interface{ Truncate(size int64) error }); ok {
+			return t.Truncate(size)
+		}
+	}
+
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.IsDir():
+		return syscall.EISDIR
+	case fi.Size() == size:
+		return nil
+	case size != 0:
+		return syscall.ENOTSUP
+	}
+
+	f, err := s.fsys.OpenFile(name, fs.O_WRONLY|fs.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Server) mknod(r *request) ([]byte, error) {
+	name, err := s.child(r.node, r.names(16))
+	if err != nil {
+		return nil, err
+	}
+
+	mode := r.u32(0)
+	if mode&syscall.S_IFMT != syscall.S_IFREG {
+		return nil, syscall.ENOTSUP
+	}
+
+	if err := s.fsys.WriteFile(name, nil, gofs.FileMode(mode).Perm()); err != nil {
+		return nil, err
+	}
+	return s.entry(name)
+}
+
+func (s *Server) mkdir(r *request) ([]byte, error) {
+	name, err := s.child(r.node, r.names(8))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fsys.Mkdir(name, gofs.FileMode(r.u32(0)).Perm()); err != nil {
+		return nil, err
+	}
+	return s.entry(name)
+}
+
+func (s *Server) unlink(r *request) error {
+	name, err := s.child(r.node, r.names(0))
+	if err != nil {
+		return err
+	}
+
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		return syscall.EISDIR
+	}
+	return s.remove(name)
+}
+
+func (s *Server) rmdir(r *request) error {
+	name, err := s.child(r.node, r.names(0))
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.fsys.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	return s.remove(name)
+}
+
+func (s *Server) remove(name string) error {
+	if err := s.fsys.Remove(name); err != nil {
+		return err
+	}
+
+	if id, ok := s.paths[name]; ok {
+		s.nodes[id].path = ""
+		delete(s.paths, name)
+	}
+	return nil
+}
+
+func (s *Server) rename(r *request, newdir uint64, names []string) error {
+	if len(names) != 2 {
+		return syscall.EINVAL
+	}
+
+	oldpath, err := s.child(r.node, names[:1])
+	if err != nil {
+		return err
+	}
+
+	newpath, err := s.child(newdir, names[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := s.fsys.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	if id, ok := s.paths[newpath]; ok {
+		s.nodes[id].path = ""
+		delete(s.paths, newpath)
+	}
+
+	for id, n := range s.nodes {
+		if n.path == oldpath || strings.HasPrefix(n.path, oldpath+"/") {
+			delete(s.paths, n.path)
+			n.path = newpath + strings.TrimPrefix(n.path, oldpath)
+			s.paths[n.path] = id
+		}
+	}
+	return nil
+}
+
+func (s *Server) open(r *request) ([]byte, error) {
+	if r.node == pollHackNode {
+		return openOut(0), nil
+	}
+
+	name, err := s.path(r.node)
+	if err != nil {
+		return nil, err
+	}
+
+	flag := int(r.u32(0)) & openFlags
+	f, err := s.fsys.OpenFile(name, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	return openOut(s.addHandle(&handle{file: f, flag: flag})), nil
+}
+
+func (s *Server) create(r *request) ([]byte, error) {
+	name, err := s.child(r.node, r.names(16))
+	if err != nil {
+		return nil, err
+	}
+
+	flag := int(r.u32(0))&openFlags | fs.O_CREATE
+	f, err := s.fsys.OpenFile(name, flag, gofs.FileMode(r.u32(4)).Perm())
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.entry(name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return append(out, openOut(s.addHandle(&handle{file: f, flag: flag &^ fs.O_TRUNC}))...), nil
+}
+
+func (s *Server) read(r *request) ([]byte, error) {
+	h, ok := s.handles[r.u64(0)]
+	if !ok || h.file == nil {
+		return nil, syscall.EBADF
+	}
+
+	b := make([]byte, r.u32(16))
+	n, err := h.file.ReadAt(b, int64(r.u64(8)))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return b[:n], nil
+}
+
+func (s *Server) write(r *request) ([]byte, error) {
+	h, ok := s.handles[r.u64(0)]
+	if !ok || h.file == nil {
+		return nil, syscall.EBADF
+	}
+
+	off := int64(r.u64(8))
+	size := int(r.u32(16))
+	if 40+size > len(r.body) {
+		return nil, syscall.EINVAL
+	}
+	data := r.body[40 : 40+size]
+
+	var (
+		n   int
+		err error
+	)
+	if w, ok := h.file.(io.WriterAt); ok && h.flag&fs.O_APPEND == 0 {
+		n, err = w.WriteAt(data, off)
+	} else {
+		if off != h.off && h.flag&fs.O_APPEND == 0 {
+			if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		n, err = h.file.Write(data)
+	}
+	h.off = off + int64(n)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := endian.AppendUint32(nil, uint32(n))
+	return endian.AppendUint32(out, 0), nil
+}
+
+func (s *Server) sync(fh uint64) error {
+	if h, ok := s.handles[fh]; ok && h.file != nil {
+		if f, ok := h.file.(interface{ Sync() error }); ok {
+			return f.Sync()
+		}
+	}
+	return nil
+}
+
+func (s *Server) release(fh uint64) error {
+	h, ok := s.handles[fh]
+	if !ok {
+		return nil
+	}
+	delete(s.handles, fh)
+
+	if h.file != nil {
+		return h.file.Close()
+	}
+	return nil
+}
+
+func (s *Server) opendir(r *request) ([]byte, error) {
+	name, err := s.path(r.node)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.fsys.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return openOut(s.addHandle(&handle{entries: entries})), nil
+}
+
+func (s *Server) readdir(r *request) ([]byte, error) {
+	h, ok := s.handles[r.u64(0)]
+	if !ok || h.file != nil {
+		return nil, syscall.EBADF
+	}
+
+	size := int(r.u32(16))
+	out := make([]byte, 0, size)
+	for i := int(r.u64(8)); i < len(h.entries)+2; i++ {
+		name, typ := ".", uint32(syscall.S_IFDIR)
+		switch i {
+		case 0:
+		case 1:
+			name = ".."
+		default:
+			name, typ = h.entries[i-2].Name(), mode(h.entries[i-2].Type())
+		}
+
+		n := 24 + len(name)
+		n += (8 - n%8) % 8
+		if len(out)+n > size {
+			break
+		}
+
+		out = endian.AppendUint64(out, unknownIno)
+		out = endian.AppendUint64(out, uint64(i+1))
+		out = endian.AppendUint32(out, uint32(len(name)))
+		out = endian.AppendUint32(out, typ>>12)
+		out = append(out, name...)
+		out = append(out, make([]byte, n-24-len(name))...)
+	}
+	return out, nil
+}
+
+func (s *Server) addHandle(h *handle) uint64 {
+	s.nextHandle++
+	s.handles[s.nextHandle] = h
+	return s.nextHandle
+}
+
+func (s *Server) closeHandles() {
+	for fh, h := range s.handles {
+		if h.file != nil {
+			if err := h.file.Close(); err != nil {
+				s.logger.Error("[fuse] close", "dir", s.dir, "error", err)
+			}
+		}
+		delete(s.handles, fh)
+	}
+}
+
+// child returns the path of the first of names in the directory with the node id parent.
+func (s *Server) child(parent uint64, names []string) (string, error) {
+	if len(names) == 0 || names[0] == "" || strings.Contains(names[0], "/") {
+		return "", syscall.EINVAL
+	}
+
+	dir, err := s.path(parent)
+	if err != nil {
+		return "", err
+	}
+	return gopath.Join(dir, names[0]), nil
+}
+
+func (s *Server) path(id uint64) (string, error) {
+	n, ok := s.nodes[id]
+	if !ok || n.path == "" {
+		return "", syscall.ENOENT
+	}
+	return n.path, nil
+}
+
+// entry returns an entry reply for the named file, and increments the lookup count of its node.
+func (s *Server) entry(name string) ([]byte, error) {
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := s.paths[name]
+	if !ok {
+		id = s.nextNode
+		s.nextNode++
+		s.nodes[id] = &node{path: name}
+		s.paths[name] = id
+	}
+	s.nodes[id].lookups++
+
+	sec, nsec := timeout(s.timeout)
+	out := make([]byte, 0, 128)
+	out = endian.AppendUint64(out, id)
+	out = endian.AppendUint64(out, 0)
+	out = endian.AppendUint64(out, sec)
+	out = endian.AppendUint64(out, sec)
+	out = endian.AppendUint32(out, nsec)
+	out = endian.AppendUint32(out, nsec)
+	return s.appendAttr(out, id, fi), nil
+}
+
+func (s *Server) forget(id uint64, lookups uint64) {
+	n, ok := s.nodes[id]
+	if !ok || id == rootNode {
+		return
+	}
+
+	n.lookups -= min(lookups, n.lookups)
+	if n.lookups == 0 {
+		delete(s.nodes, id)
+		if s.paths[n.path] == id {
+			delete(s.paths, n.path)
+		}
+	}
+}
+
+func (s *Server) attrOut(id uint64, fi gofs.FileInfo) []byte {
+	sec, nsec := timeout(s.timeout)
+	out := make([]byte, 0, 104)
+	out = endian.AppendUint64(out, sec)
+	out = endian.AppendUint32(out, nsec)
+	out = endian.AppendUint32(out, 0)
+	return s.appendAttr(out, id, fi)
+}
+
+func (s *Server) appendAttr(out []byte, id uint64, fi gofs.FileInfo) []byte {
+	nlink := uint32(1)
+	if fi.IsDir() {
+		nlink = 2
+	}
+
+	size := uint64(max(fi.Size(), 0))
+	mtime := fi.ModTime()
+	out = endian.AppendUint64(out, id)
+	out = endian.AppendUint64(out, size)
+	out = endian.AppendUint64(out, (size+511)/512)
+	for range 3 {
+		out = endian.AppendUint64(out, uint64(max(mtime.Unix(), 0)))
+	}
+	for range 3 {
+		out = endian.AppendUint32(out, uint32(mtime.Nanosecond()))
+	}
+	out = endian.AppendUint32(out, mode(fi.Mode()))
+	out = endian.AppendUint32(out, nlink)
+	out = endian.AppendUint32(out, s.uid)
+	out = endian.AppendUint32(out, s.gid)
+	out = endian.AppendUint32(out, 0)
+	out = endian.AppendUint32(out, blockSize)
+	return endian.AppendUint32(out, 0)
+}
+
+// pollHackInfo describes the file used by Server.pollHack.
+type pollHackInfo struct{}
+
+func (pollHackInfo) IsDir() bool         { return false }
+func (pollHackInfo) ModTime() time.Time  { return time.Time{} }
+func (pollHackInfo) Mode() gofs.FileMode { return 0444 }
+func (pollHackInfo) Name() string        { return pollHackName }
+func (pollHackInfo) Size() int64         { return 0 }
+func (pollHackInfo) Sys() any            { return nil }
+
+func openOut(fh uint64) []byte {
+	out := endian.AppendUint64(nil, fh)
+	out = endian.AppendUint32(out, 0)
+	return endian.AppendUint32(out, 0)
+}
+
+// statfs returns the capacity and usage of the file system if it implements fs.StatFSer, and reports an empty file
+// system otherwise.
+func (s *Server) statfs() []byte {
+	var total, used, free, files uint64
+	if sfs, ok := s.fsys.(fs.StatFSer); ok {
+		if t, u, f, n, err := sfs.Usage(); err == nil {
+			total, used, free, files = t, u, f, n
+		}
+	}
+
+	out := make([]byte, 0, 80)
+	out = endian.AppendUint64(out, total/blockSize)
+	out = endian.AppendUint64(out, (total-min(used, total))/blockSize)
+	out = endian.AppendUint64(out, free/blockSize)
+	out = endian.AppendUint64(out, files)
+	out = endian.AppendUint64(out, 0)
+	out = endian.AppendUint32(out, blockSize)
+	out = endian.AppendUint32(out, 255)
+	out = endian.AppendUint32(out, blockSize)
+	return append(out, make([]byte, 28)...)
+}
+
+func timeout(d time.Duration) (uint64, uint32) {
+	return uint64(d / time.Second), uint32(d % time.Second)
+}
+
+// mode converts m to the mode bits used by the kernel.
+func mode(m gofs.FileMode) uint32 {
+	var t uint32
+	switch {
+	case m.IsDir():
+		t = syscall.S_IFDIR
+	case m&gofs.ModeSymlink != 0:
+		t = syscall.S_IFLNK
+	case m&gofs.ModeNamedPipe != 0:
+		t = syscall.S_IFIFO
+	case m&gofs.ModeSocket != 0:
+		t = syscall.S_IFSOCK
+	case m&gofs.ModeCharDevice != 0:
+		t = syscall.S_IFCHR
+	case m&gofs.ModeDevice != 0:
+		t = syscall.S_IFBLK
+	default:
+		t = syscall.S_IFREG
+	}
+
+	if m&gofs.ModeSetuid != 0 {
+		t |= syscall.S_ISUID
+	}
+
+	if m&gofs.ModeSetgid != 0 {
+		t |= syscall.S_ISGID
+	}
+
+	if m&gofs.ModeSticky != 0 {
+		t |= syscall.S_ISVTX
+	}
+	return t | uint32(m.Perm())
+}
+
+// errno converts err to the error number returned to the kernel.
+func errno(err error) syscall.Errno {
+	var e syscall.Errno
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, gofs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, gofs.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, gofs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, gofs.ErrClosed):
+		return syscall.EBADF
+	case errors.Is(err, gofs.ErrInvalid):
+		return syscall.EINVAL
+	case errors.Is(err, fs.ErrIsDir):
+		return syscall.EISDIR
+	case errors.Is(err, fs.ErrNotDir):
+		return syscall.ENOTDIR
+	case errors.Is(err, fs.ErrNotEmpty):
+		return syscall.ENOTEMPTY
+	case errors.Is(err, fs.ErrQuotaExceeded):
+		return syscall.EDQUOT
+	case errors.Is(err, fs.ErrTooLarge):
+		return syscall.EFBIG
+	case errors.Is(err, errors.ErrUnsupported):
+		return syscall.ENOTSUP
+	}
+	return syscall.EIO
+}
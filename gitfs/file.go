@@ -0,0 +1,128 @@
+package gitfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*File)(nil)
+
+// File provides read-only access to a single file or directory provided by GitFS.
+type File struct {
+	closed  bool
+	entries []*fs.Entry
+	entry   *fs.Entry
+	mutex   sync.Mutex
+	off     int
+	reader  *bytes.Reader
+}
+
+func newFile(entry *fs.Entry, b []byte) *File {
+	return &File{entry: entry, reader: bytes.NewReader(b)}
+}
+
+func newDirFile(entry *fs.Entry, entries []*fs.Entry) *File {
+	return &File{entry: entry, entries: entries}
+}
+
+func (f *File) Close() error {
+	if f == nil {
+		return gofs.ErrInvalid
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		return nil
+	}
+	return fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: "close", Path: f.entry.Path(), Err: gofs.ErrClosed})
+}
+
+func (f *File) Read(b []byte) (int, error) {
+	if err := f.checkRead("read"); err != nil {
+		return 0, err
+	}
+	return f.reader.Read(b)
+}
+
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	if err := f.checkRead("readAt"); err != nil {
+		return 0, err
+	}
+	return f.reader.ReadAt(b, off)
+}
+
+func (f *File) ReadFrom(io.Reader) (int64, error) {
+	return 0, fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: "readFrom", Path: f.entry.Path(), Err: gofs.ErrPermission})
+}
+
+func (f *File) ReadDir(n int) ([]gofs.DirEntry, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil, fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: "readDir", Path: f.entry.Path(), Err: gofs.ErrClosed})
+	}
+
+	if !f.entry.IsDir() {
+		return nil, fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: "readDir", Path: f.entry.Path(), Err: fs.ErrNotDir})
+	}
+
+	remaining := f.entries[f.off:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(remaining) {
+			remaining = remaining[:n]
+		}
+	}
+	f.off += len(remaining)
+
+	entries := make([]gofs.DirEntry, len(remaining))
+	for i, e := range remaining {
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+func (f *File) Seek(off int64, whence int) (int64, error) {
+	if err := f.checkRead("seek"); err != nil {
+		return 0, err
+	}
+	return f.reader.Seek(off, whence)
+}
+
+func (f *File) Stat() (gofs.FileInfo, error) {
+	if f == nil {
+		return nil, gofs.ErrInvalid
+	}
+	return f.entry, nil
+}
+
+func (f *File) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: "write", Path: f.entry.Path(), Err: gofs.ErrPermission})
+}
+
+func (f *File) checkRead(op string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: op, Path: f.entry.Path(), Err: gofs.ErrClosed})
+	}
+
+	if f.entry.IsDir() {
+		return fmt.Errorf("gitfs_file: %w", &gofs.PathError{Op: op, Path: f.entry.Path(), Err: fs.ErrIsDir})
+	}
+	return nil
+}
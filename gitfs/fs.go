@@ -0,0 +1,461 @@
+package gitfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	pathSeparator   = "/"
+	defaultRevision = "HEAD"
+)
+
+var _ fs.FS = (*GitFS)(nil)
+
+// GitFS read-only git repository provider that implements fs.FS.
+//
+// The file system exposes the tree of a single commit, resolved from a branch, tag, or commit hash when the GitFS is
+// created. All Writable operations return an error that wraps fs.ErrPermission.
+type GitFS struct {
+	closed   bool
+	commit   *object.Commit
+	logger   fs.Logger
+	mutex    sync.Mutex
+	path     string
+	repo     *git.Repository
+	revision string
+	tree     *object.Tree
+}
+
+// New creates a new GitFS for the git repository (bare or work tree) located at path.
+func New(path string, options ...func(*GitFS)) (*GitFS, error) {
+	g := &GitFS{logger: fs.NopLogger(), path: path, revision: defaultRevision}
+	for _, opt := range options {
+		opt(g)
+	}
+
+	if g.repo == nil {
+		repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+		if err != nil {
+			return nil, fmt.Errorf("gitfs: %w", err)
+		}
+		g.repo = repo
+	}
+
+	h, err := g.repo.ResolveRevision(plumbing.Revision(g.revision))
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: revision %s: %w", g.revision, err)
+	}
+
+	commit, err := g.repo.CommitObject(*h)
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: revision %s: %w", g.revision, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: revision %s: %w", g.revision, err)
+	}
+
+	g.commit = commit
+	g.tree = tree
+
+	g.logger.Debug("[gitfs] opened repository", "path", path, "revision", g.revision, "commit", commit.Hash.String())
+	return g, nil
+}
+
+// Close ...
+func (g *GitFS) Close() error {
+	if g == nil {
+		return gofs.ErrInvalid
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.closed {
+		g.closed = true
+		return nil
+	}
+	return fmt.Errorf("gitfs: %w", gofs.ErrClosed)
+}
+
+// Commit returns the hash of the commit exposed by the GitFS.
+func (g *GitFS) Commit() string {
+	return g.commit.Hash.String()
+}
+
+// Create ...
+func (g *GitFS) Create(name string) (fs.File, error) {
+	return nil, readOnly("create", name)
+}
+
+// Glob ...
+func (g *GitFS) Glob(pattern string) ([]string, error) {
+	g.logger.Debug("[gitfs] glob", "pattern", pattern)
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "glob", Path: pattern, Err: err})
+	}
+
+	var matches []string
+	err := gofs.WalkDir(g, ".", func(path string, entry gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if matched, _ := filepath.Match(pattern, path); matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return matches, err
+	}
+	return matches, nil
+}
+
+// Mkdir ...
+func (g *GitFS) Mkdir(name string, _ gofs.FileMode) error {
+	return readOnly("mkdir", name)
+}
+
+// MkdirAll ...
+func (g *GitFS) MkdirAll(path string, _ gofs.FileMode) error {
+	return readOnly("mkdirAll", path)
+}
+
+// Open opens the named File.
+func (g *GitFS) Open(name string) (gofs.File, error) {
+	g.logger.Debug("[gitfs] open", "name", name)
+	return g.open("open", name)
+}
+
+// OpenFile opens the named File. Only fs.O_RDONLY is supported.
+func (g *GitFS) OpenFile(name string, flag int, _ gofs.FileMode) (fs.File, error) {
+	g.logger.Debug("[gitfs] openFile", "name", name, "flag", flag)
+
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) != 0 {
+		return nil, readOnly("openFile", name)
+	}
+	return g.open("openFile", name)
+}
+
+// PathSeparator ...
+func (g *GitFS) PathSeparator() string {
+	return pathSeparator
+}
+
+// Provider ...
+func (g *GitFS) Provider() string {
+	return "gitfs"
+}
+
+// ReadDir ...
+func (g *GitFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	g.logger.Debug("[gitfs] readDir", "name", name)
+
+	de, err := g.readDir("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]gofs.DirEntry, len(de))
+	for i, e := range de {
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// ReadFile ...
+func (g *GitFS) ReadFile(name string) ([]byte, error) {
+	g.logger.Debug("[gitfs] readFile", "name", name)
+
+	name, e, err := g.find("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil || !e.Mode.IsFile() {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: fs.ErrIsDir})
+	}
+
+	b, err := g.content(e)
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: err})
+	}
+	return b, nil
+}
+
+// Remove ...
+func (g *GitFS) Remove(name string) error {
+	return readOnly("remove", name)
+}
+
+// RemoveAll ...
+func (g *GitFS) RemoveAll(path string) error {
+	return readOnly("removeAll", path)
+}
+
+// Rename ...
+func (g *GitFS) Rename(oldpath string, _ string) error {
+	return readOnly("rename", oldpath)
+}
+
+// Revision returns the revision (branch, tag, or commit hash) used for resolving the commit exposed by the GitFS.
+func (g *GitFS) Revision() string {
+	return g.revision
+}
+
+// Root ...
+func (g *GitFS) Root() (string, error) {
+	return pathSeparator, nil
+}
+
+// Stat ...
+func (g *GitFS) Stat(name string) (gofs.FileInfo, error) {
+	g.logger.Debug("[gitfs] stat", "name", name)
+
+	name, e, err := g.find("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := g.entry(name, e)
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "stat", Path: name, Err: err})
+	}
+	return entry, nil
+}
+
+// Sub returns a GitFS rooted at the tree for dir.
+func (g *GitFS) Sub(dir string) (gofs.FS, error) {
+	g.logger.Debug("[gitfs] sub", "dir", dir)
+
+	dir, e, err := g.find("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return g, nil
+	}
+
+	if e.Mode != filemode.Dir {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotDir})
+	}
+
+	g.mutex.Lock()
+	tree, err := g.repo.TreeObject(e.Hash)
+	g.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: err})
+	}
+
+	return &GitFS{
+		commit:   g.commit,
+		logger:   g.logger,
+		path:     g.path,
+		repo:     g.repo,
+		revision: g.revision,
+		tree:     tree,
+	}, nil
+}
+
+// WriteFile ...
+func (g *GitFS) WriteFile(name string, _ []byte, _ gofs.FileMode) error {
+	return readOnly("writeFile", name)
+}
+
+func (g *GitFS) content(e *object.TreeEntry) ([]byte, error) {
+	g.mutex.Lock()
+	blob, err := g.repo.BlobObject(e.Hash)
+	g.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func(r io.ReadCloser) {
+		if err := r.Close(); err != nil {
+			g.logger.Error("[gitfs] content", "error", err)
+		}
+	}(r)
+	return io.ReadAll(r)
+}
+
+func (g *GitFS) entry(path string, e *object.TreeEntry) (*fs.Entry, error) {
+	mode := gofs.ModeDir | 0555
+	var size int64
+	if e != nil {
+		m, err := e.Mode.ToOSFileMode()
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Mode == filemode.Submodule {
+			m = gofs.ModeDir | 0555
+		}
+		mode = m
+
+		if e.Mode.IsFile() {
+			g.mutex.Lock()
+			s, err := g.repo.Storer.EncodedObjectSize(e.Hash)
+			g.mutex.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			size = s
+		}
+	}
+
+	when := g.commit.Committer.When
+	attrs, err := fs.NewAttributes(
+		fs.WithCtime(when),
+		fs.WithMode(uint32(mode)),
+		fs.WithMtime(when),
+		fs.WithOwner(g.commit.Committer.Name),
+		fs.WithSize(uint64(size)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return fs.NewEntry(path, fs.WithAttributes(attrs))
+}
+
+// find returns the cleaned name and the tree entry for the named file. The returned entry is nil for the root tree.
+func (g *GitFS) find(op string, name string) (string, *object.TreeEntry, error) {
+	name, err := fs.CleanPath(g, name)
+	if err != nil {
+		return name, nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+
+	if name == "." {
+		return name, nil, nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	e, err := g.tree.FindEntry(name)
+	if err != nil {
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			err = gofs.ErrNotExist
+		}
+		return name, nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, e, nil
+}
+
+func (g *GitFS) open(op string, name string) (*File, error) {
+	name, e, err := g.find(op, name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := g.entry(name, e)
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+
+	if entry.IsDir() {
+		de, err := g.readDir(op, name)
+		if err != nil {
+			return nil, err
+		}
+		return newDirFile(entry, de), nil
+	}
+
+	b, err := g.content(e)
+	if err != nil {
+		return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return newFile(entry, b), nil
+}
+
+func (g *GitFS) readDir(op string, name string) ([]*fs.Entry, error) {
+	name, e, err := g.find(op, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := g.tree
+	if e != nil {
+		if e.Mode == filemode.Submodule {
+			return nil, nil
+		}
+
+		if e.Mode != filemode.Dir {
+			return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: fs.ErrNotDir})
+		}
+
+		g.mutex.Lock()
+		tree, err = g.repo.TreeObject(e.Hash)
+		g.mutex.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+		}
+	}
+
+	entries := make([]*fs.Entry, 0, len(tree.Entries))
+	for i := range tree.Entries {
+		p := tree.Entries[i].Name
+		if name != "." {
+			p = gopath.Join(name, p)
+		}
+
+		entry, err := g.entry(p, &tree.Entries[i])
+		if err != nil {
+			return entries, fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: p, Err: err})
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func readOnly(op string, name string) error {
+	return fmt.Errorf("gitfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrPermission})
+}
+
+// WithLogger sets the Logger used by a GitFS to log the operations that it performs. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*GitFS) {
+	return func(g *GitFS) {
+		if logger != nil {
+			g.logger = logger
+		}
+	}
+}
+
+// WithRepository sets the already opened git repository to use for a GitFS. The path provided to New is ignored.
+func WithRepository(repo *git.Repository) func(*GitFS) {
+	return func(g *GitFS) {
+		g.repo = repo
+	}
+}
+
+// WithRevision sets the revision (branch, tag, or commit hash) exposed by a GitFS. Defaults to HEAD.
+func WithRevision(revision string) func(*GitFS) {
+	return func(g *GitFS) {
+		if revision != "" {
+			g.revision = revision
+		}
+	}
+}
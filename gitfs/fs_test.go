@@ -0,0 +1,170 @@
+package gitfs
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+const (
+	testDataDir = "../testdata"
+)
+
+// GitFSTestSuite ...
+type GitFSTestSuite struct {
+	suite.Suite
+	filePaths []string
+	gfs       *GitFS
+	repoDir   string
+}
+
+func NewGitFSTestSuite() *GitFSTestSuite {
+	return &GitFSTestSuite{}
+}
+
+func (t *GitFSTestSuite) SetupTest() {
+	t.repoDir = t.T().TempDir()
+
+	repo, err := git.PlainInit(t.repoDir, false)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	dir, err := filepath.Abs(testDataDir)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.filePaths = nil
+	err = filepath.Walk(dir, func(path string, fi gofs.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		filePath := strings.TrimPrefix(path, dir+"/")
+		if err := os.MkdirAll(filepath.Join(t.repoDir, filepath.Dir(filePath)), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(t.repoDir, filePath), b, 0644); err != nil {
+			return err
+		}
+
+		if _, err := wt.Add(filePath); err != nil {
+			return err
+		}
+		t.filePaths = append(t.filePaths, filePath)
+		return nil
+	})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = wt.Commit("test data", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	gfs, err := New(t.repoDir)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.gfs = gfs
+}
+
+func TestGitFSTestSuite(t *testing.T) {
+	suite.Run(t, NewGitFSTestSuite())
+}
+
+func (t *GitFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.gfs, t.filePaths...))
+}
+
+func (t *GitFSTestSuite) TestReadFile() {
+	for _, p := range t.filePaths {
+		expected, err := os.ReadFile(filepath.Join(t.repoDir, p))
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		b, err := t.gfs.ReadFile(p)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), expected, b)
+	}
+}
+
+func (t *GitFSTestSuite) TestRevision() {
+	if err := os.WriteFile(filepath.Join(t.repoDir, "doc", "fox.txt"), []byte("changed"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	head, err := New(t.repoDir, WithRevision(t.gfs.Commit()))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b, err := head.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.NotEqual(t.T(), "changed", string(b))
+
+	_, err = New(t.repoDir, WithRevision("does-not-exist"))
+	assert.Error(t.T(), err)
+}
+
+func (t *GitFSTestSuite) TestWritable() {
+	assert.ErrorIs(t.T(), t.gfs.WriteFile("new.txt", []byte("new"), 0644), gofs.ErrPermission)
+	assert.ErrorIs(t.T(), t.gfs.Mkdir("new", 0755), gofs.ErrPermission)
+	assert.ErrorIs(t.T(), t.gfs.Remove("doc/fox.txt"), gofs.ErrPermission)
+
+	_, err := t.gfs.OpenFile("doc/fox.txt", fs.O_RDWR, 0)
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+}
+
+func (t *GitFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	gfs, err := New(t.repoDir, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Contains(t.T(), buf.String(), `msg="[gitfs] opened repository" path=`+t.repoDir+` revision=HEAD`)
+
+	dir := path.Dir(t.filePaths[0])
+	sub, err := gfs.Sub(dir)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = sub.(*GitFS).Stat(".")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[gitfs] stat" name=.`)
+	assert.Equal(t.T(), fs.NopLogger(), t.gfs.logger)
+}
@@ -0,0 +1,113 @@
+package fs
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// HashFile returns the digest of the content of the named file computed using hash.
+//
+// The hash function must be available, which requires the package that implements it to be linked into the binary
+// (e.g. by importing crypto/sha256). Otherwise, an error wrapping errors.ErrUnsupported is returned.
+func HashFile(fsys gofs.FS, name string, hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("fs: %w", &gofs.PathError{Op: "hash", Path: name, Err: errUnsupportedHash(hash)})
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return nil, fmt.Errorf("fs: %w", &gofs.PathError{Op: "hash", Path: name, Err: ErrIsDir})
+	}
+
+	h := hash.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// HashTree returns a deterministic digest of the tree rooted at root computed using hash.
+//
+// The digest is computed in the style of a Merkle tree: the digest of a file is the digest of its content, and the
+// digest of a directory is the digest of a record for each of its entries, in lexical order, consisting of the mode,
+// name, and digest of the entry. The digest of the tree is the digest of the record for root without its name, so the
+// digest changes if the name, mode (type and permissions), or content of any entry in the tree changes. Symbolic links
+// are not followed, and are included by their mode and name only.
+//
+// The name of root, modification times, and other attributes are not included, so trees with the same structure and
+// content have the same digest regardless of their location and file system.
+func HashTree(fsys gofs.FS, root string, hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("fs: %w", &gofs.PathError{Op: "hash", Path: root, Err: errUnsupportedHash(hash)})
+	}
+
+	fi, err := gofs.Stat(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := hashEntry(fsys, root, fi.Mode(), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	writeTreeRecord(h, "", fi.Mode(), digest)
+	return h.Sum(nil), nil
+}
+
+// hashEntry returns the digest of the named entry with the provided mode.
+func hashEntry(fsys gofs.FS, name string, mode gofs.FileMode, hash crypto.Hash) ([]byte, error) {
+	switch {
+	case mode.IsDir():
+		entries, err := gofs.ReadDir(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		h := hash.New()
+		for _, e := range entries {
+			fi, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+
+			digest, err := hashEntry(fsys, gopath.Join(name, e.Name()), fi.Mode(), hash)
+			if err != nil {
+				return nil, err
+			}
+			writeTreeRecord(h, e.Name(), fi.Mode(), digest)
+		}
+		return h.Sum(nil), nil
+	case mode.IsRegular():
+		return HashFile(fsys, name, hash)
+	}
+	return nil, nil
+}
+
+// writeTreeRecord writes the record for an entry of a directory to w.
+func writeTreeRecord(w io.Writer, name string, mode gofs.FileMode, digest []byte) {
+	mode &= gofs.ModeType | gofs.ModePerm
+	_, _ = io.WriteString(w, strconv.FormatUint(uint64(mode), 8)+" "+name+"\x00")
+	_, _ = w.Write(digest)
+}
+
+func errUnsupportedHash(hash crypto.Hash) error {
+	return fmt.Errorf("hash function %s is unavailable: %w", hash, errors.ErrUnsupported)
+}
@@ -0,0 +1,92 @@
+package fs_test
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashFile(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := fs.HashFile(mfs, "doc/fox.txt", crypto.SHA256)
+	assert.NoError(t, err)
+
+	want := sha256.Sum256([]byte("the quick brown fox"))
+	assert.Equal(t, hex.EncodeToString(want[:]), hex.EncodeToString(sum))
+
+	_, err = fs.HashFile(mfs, "doc", crypto.SHA256)
+	assert.ErrorIs(t, err, fs.ErrIsDir)
+
+	_, err = fs.HashFile(mfs, "doc/fox.txt", crypto.MD4)
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+}
+
+func TestHashTree(t *testing.T) {
+	tree := func(files map[string]string) *memfs.MemFS {
+		mfs, err := memfs.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, content := range files {
+			if err := mfs.WriteFile(name, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return mfs
+	}
+
+	files := map[string]string{
+		"a/doc/fox.txt": "the quick brown fox",
+		"a/doc/dog.txt": "jumps over the lazy dog",
+		"b/doc/fox.txt": "the quick brown fox",
+		"b/doc/dog.txt": "jumps over the lazy dog",
+		"c/doc/fox.txt": "the quick brown fox",
+		"c/doc/cat.txt": "jumps over the lazy dog",
+		"d/doc/fox.txt": "the quick brown fox",
+		"d/doc/dog.txt": "jumps over the lazy cat",
+	}
+	mfs := tree(files)
+
+	a, err := fs.HashTree(mfs, "a", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.Len(t, a, sha256.Size)
+
+	b, err := fs.HashTree(mfs, "b", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	c, err := fs.HashTree(mfs, "c", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, c)
+
+	d, err := fs.HashTree(mfs, "d", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, d)
+
+	other, err := fs.HashTree(tree(map[string]string{
+		"x/doc/fox.txt": "the quick brown fox",
+		"x/doc/dog.txt": "jumps over the lazy dog",
+	}), "x", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, a, other)
+
+	file, err := fs.HashTree(mfs, "a/doc/fox.txt", crypto.SHA256)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, file)
+}
@@ -0,0 +1,278 @@
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var (
+	_ http.File       = (*httpDir)(nil)
+	_ http.File       = (*httpFile)(nil)
+	_ http.FileSystem = (*HTTPFileSystem)(nil)
+	_ http.Handler    = (*HTTPFileSystem)(nil)
+)
+
+// HTTPFileSystem provides access to an FS as an http.FileSystem.
+//
+// An HTTPFileSystem can be used with http.FileServer directly, or can be used as an http.Handler, which serves files
+// using http.FileServer and additionally sets an ETag for files and renders directory listings using the template set
+// with WithListingTemplate.
+type HTTPFileSystem struct {
+	etag    bool
+	fsys    FS
+	index   []string
+	listing *template.Template
+}
+
+// HTTPListing is the data provided to a directory-listing template.
+type HTTPListing struct {
+	// Path is the URL path of the directory, which ends with a slash.
+	Path string
+
+	// Entries contains the entries in the directory, sorted by name.
+	Entries []gofs.FileInfo
+}
+
+// HTTP returns an HTTPFileSystem that serves fsys.
+//
+// Files are read using ReadAt if it is supported by the files provided by fsys, and using Seek otherwise, so that
+// range requests are served without reading the content that precedes the range.
+func HTTP(fsys FS, options ...func(*HTTPFileSystem)) *HTTPFileSystem {
+	h := &HTTPFileSystem{etag: true, fsys: fsys, index: []string{"index.html"}}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h
+}
+
+// Open opens the named file for serving.
+//
+// Since http.FileServer resolves the index of a directory by opening "index.html" in the directory, opening a file
+// named "index.html" that does not exist opens the first of the index files set using WithIndexFiles that exists in
+// the directory instead.
+func (h *HTTPFileSystem) Open(name string) (http.File, error) {
+	fi, p, err := h.stat(httpPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return &httpDir{fsys: h.fsys, info: fi, path: p}, nil
+	}
+	return h.openFile(p, fi)
+}
+
+// ServeHTTP serves the file or directory for the request path using http.FileServer.
+func (h *HTTPFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upath := r.URL.Path
+	if !strings.HasPrefix(upath, "/") {
+		upath = "/" + upath
+	}
+
+	p := httpPath(upath)
+	if fi, err := h.fsys.Stat(p); err == nil {
+		if fi.IsDir() {
+			if ifi, _, err := h.stat(gopath.Join(p, "index.html")); err == nil && !ifi.IsDir() {
+				fi = ifi
+			} else if h.listing != nil && strings.HasSuffix(upath, "/") {
+				h.serveListing(w, upath, p)
+				return
+			}
+		}
+
+		if h.etag && !fi.IsDir() {
+			w.Header().Set("Etag", ETag(fi))
+		}
+	}
+	http.FileServer(h).ServeHTTP(w, r)
+}
+
+func (h *HTTPFileSystem) indexFile(dir string) (gofs.FileInfo, string, error) {
+	for _, name := range h.index {
+		p := gopath.Join(dir, name)
+		if fi, err := h.fsys.Stat(p); err == nil && !fi.IsDir() {
+			return fi, p, nil
+		}
+	}
+	return nil, "", &gofs.PathError{Op: "open", Path: gopath.Join(dir, "index.html"), Err: gofs.ErrNotExist}
+}
+
+func (h *HTTPFileSystem) openFile(name string, fi gofs.FileInfo) (http.File, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	hf := &httpFile{File: f, name: name}
+	switch r := f.(type) {
+	case io.ReaderAt:
+		hf.r = io.NewSectionReader(r, 0, fi.Size())
+	case io.ReadSeeker:
+		hf.r = r
+	}
+	return hf, nil
+}
+
+func (h *HTTPFileSystem) serveListing(w http.ResponseWriter, upath string, name string) {
+	entries, err := h.fsys.ReadDir(name)
+	if err != nil {
+		http.Error(w, "Error reading directory", http.StatusInternalServerError)
+		return
+	}
+
+	listing := HTTPListing{Path: upath, Entries: make([]gofs.FileInfo, 0, len(entries))}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		listing.Entries = append(listing.Entries, fi)
+	}
+
+	var buf bytes.Buffer
+	if err := h.listing.Execute(&buf, listing); err != nil {
+		http.Error(w, "Error rendering directory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// stat returns the file info and path of the file that is served for the named file, which is the first of the index
+// files that exists in the directory if the name is "index.html" and it does not exist.
+func (h *HTTPFileSystem) stat(name string) (gofs.FileInfo, string, error) {
+	fi, err := h.fsys.Stat(name)
+	if errors.Is(err, gofs.ErrNotExist) && gopath.Base(name) == "index.html" {
+		return h.indexFile(gopath.Dir(name))
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+	return fi, name, nil
+}
+
+// WithETags sets whether the ETag returned by ETag for a file is set when the file is served by an HTTPFileSystem.
+// ETags are enabled by default.
+func WithETags(enabled bool) func(*HTTPFileSystem) {
+	return func(h *HTTPFileSystem) {
+		h.etag = enabled
+	}
+}
+
+// WithIndexFiles sets the names of the files that are served, in order of preference, for a directory that contains
+// one of them. If no names are provided, index files are not resolved. The default is "index.html".
+func WithIndexFiles(names ...string) func(*HTTPFileSystem) {
+	return func(h *HTTPFileSystem) {
+		h.index = names
+	}
+}
+
+// WithListingTemplate sets the template used to render directory listings when an HTTPFileSystem is used as an
+// http.Handler. The template is executed with an HTTPListing.
+func WithListingTemplate(t *template.Template) func(*HTTPFileSystem) {
+	return func(h *HTTPFileSystem) {
+		h.listing = t
+	}
+}
+
+// httpPath converts the slash-separated absolute name used by net/http to a path for an FS.
+func httpPath(name string) string {
+	p := strings.TrimPrefix(gopath.Clean("/"+name), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// httpDir provides access to a directory as an http.File.
+type httpDir struct {
+	entries []gofs.DirEntry
+	fsys    FS
+	info    gofs.FileInfo
+	path    string
+	read    bool
+}
+
+func (d *httpDir) Close() error {
+	return nil
+}
+
+func (d *httpDir) Read(p []byte) (int, error) {
+	return 0, &gofs.PathError{Op: "read", Path: d.path, Err: ErrIsDir}
+}
+
+func (d *httpDir) Readdir(count int) ([]gofs.FileInfo, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.path)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	if count > 0 && len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := len(d.entries)
+	if count > 0 && count < n {
+		n = count
+	}
+
+	infos := make([]gofs.FileInfo, 0, n)
+	for _, e := range d.entries[:n] {
+		fi, err := e.Info()
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return infos, err
+		}
+		infos = append(infos, fi)
+	}
+	d.entries = d.entries[n:]
+	return infos, nil
+}
+
+func (d *httpDir) Seek(offset int64, whence int) (int64, error) {
+	return 0, &gofs.PathError{Op: "seek", Path: d.path, Err: ErrIsDir}
+}
+
+func (d *httpDir) Stat() (gofs.FileInfo, error) {
+	return d.info, nil
+}
+
+// httpFile provides access to a file as an http.File.
+type httpFile struct {
+	gofs.File
+	name string
+	r    io.ReadSeeker
+}
+
+func (f *httpFile) Read(p []byte) (int, error) {
+	if f.r != nil {
+		return f.r.Read(p)
+	}
+	return f.File.Read(p)
+}
+
+func (f *httpFile) Readdir(count int) ([]gofs.FileInfo, error) {
+	return nil, &gofs.PathError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+}
+
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	if f.r == nil {
+		return 0, &gofs.PathError{Op: "seek", Path: f.name, Err: errors.ErrUnsupported}
+	}
+	return f.r.Seek(offset, whence)
+}
@@ -0,0 +1,118 @@
+package fs_test
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestHTTP(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"doc/fox.txt":     "the quick brown fox",
+		"site/index.htm":  "<p>home</p>",
+		"site/about.html": "<p>about</p>",
+		"www/index.htm":   "<p>htm</p>",
+		"www/index.html":  "<p>html</p>",
+	}
+	for name, content := range files {
+		if err := mfs.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listing := template.Must(template.New("listing").Parse(`{{.Path}}:{{range .Entries}} {{.Name}}{{end}}`))
+	server := httptest.NewServer(fs.HTTP(mfs, fs.WithIndexFiles("index.html", "index.htm"), fs.WithListingTemplate(listing)))
+	defer server.Close()
+
+	get := func(path string, header map[string]string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(b)
+	}
+
+	resp, body := get("/doc/fox.txt", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "the quick brown fox", body)
+
+	etag := resp.Header.Get("Etag")
+	assert.NotEmpty(t, etag)
+
+	resp, _ = get("/doc/fox.txt", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, body = get("/doc/fox.txt", map[string]string{"Range": "bytes=10-14"})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "brown", body)
+
+	resp, body = get("/doc/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/doc/: fox.txt", body)
+
+	resp, body = get("/site/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "<p>home</p>", body)
+
+	resp, _ = get("/doc/missing.txt", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	hfs := fs.HTTP(mfs, fs.WithIndexFiles("index.htm", "index.html"))
+	for name, expected := range map[string]string{
+		"/site/index.html": "<p>home</p>",
+		"/www/index.html":  "<p>html</p>",
+		"/www/index.htm":   "<p>htm</p>",
+	} {
+		f, err := hfs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := io.ReadAll(f)
+		f.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(b), name)
+	}
+
+	_, err = fs.HTTP(mfs, fs.WithIndexFiles()).Open("/site/index.html")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	fileServer := httptest.NewServer(http.FileServer(fs.HTTP(mfs, fs.WithIndexFiles())))
+	defer fileServer.Close()
+
+	resp, err = http.Get(fileServer.URL + "/site/")
+	assert.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `<a href="about.html">about.html</a>`)
+}
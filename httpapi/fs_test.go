@@ -0,0 +1,283 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// HTTPAPITestSuite ...
+type HTTPAPITestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	server  *httptest.Server
+}
+
+func NewHTTPAPITestSuite() *HTTPAPITestSuite {
+	return &HTTPAPITestSuite{}
+}
+
+func (t *HTTPAPITestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+
+	h, err := New(backing, WithPrefix("/api/"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.server = httptest.NewServer(h)
+}
+
+func (t *HTTPAPITestSuite) TearDownTest() {
+	t.server.Close()
+}
+
+func TestHTTPAPITestSuite(t *testing.T) {
+	suite.Run(t, NewHTTPAPITestSuite())
+}
+
+func (t *HTTPAPITestSuite) do(method string, path string, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, t.server.URL+"/api"+path, strings.NewReader(body))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func (t *HTTPAPITestSuite) TestGet() {
+	resp, body := t.do(http.MethodGet, "/doc/fox.txt", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(t.T(), "the quick brown fox", body)
+
+	resp, body = t.do(http.MethodHead, "/doc/fox.txt", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(t.T(), int64(19), resp.ContentLength)
+	assert.Empty(t.T(), body)
+
+	resp, body = t.do(http.MethodGet, "/doc/missing.txt", "")
+	assert.Equal(t.T(), http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t.T(), "application/json", resp.Header.Get("Content-Type"))
+
+	var e map[string]string
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &e))
+	assert.NotEmpty(t.T(), e["error"])
+}
+
+func (t *HTTPAPITestSuite) TestGetContentType() {
+	resp, _ := t.do(http.MethodGet, "/doc/fox.txt", "")
+	assert.Equal(t.T(), "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	assert.NoError(t.T(), t.backing.SetMimeType("doc/fox.txt", "text/x-fox"))
+
+	resp, body := t.do(http.MethodGet, "/doc/fox.txt", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(t.T(), "text/x-fox", resp.Header.Get("Content-Type"))
+	assert.Equal(t.T(), "the quick brown fox", body)
+}
+
+func (t *HTTPAPITestSuite) TestStat() {
+	resp, body := t.do(http.MethodGet, "/doc/fox.txt?stat", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+
+	var m map[string]any
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &m))
+	assert.Equal(t.T(), "fox.txt", m["name"])
+	assert.Equal(t.T(), false, m["is_dir"])
+	assert.Equal(t.T(), float64(19), m["size"])
+
+	resp, body = t.do(http.MethodGet, "/doc?stat", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &m))
+	assert.Equal(t.T(), true, m["is_dir"])
+}
+
+func (t *HTTPAPITestSuite) TestList() {
+	if err := t.backing.WriteFile("doc/dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	resp, body := t.do(http.MethodGet, "/doc", "")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+
+	var list []map[string]any
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &list))
+
+	var names []string
+	for _, m := range list {
+		names = append(names, m["name"].(string))
+	}
+	assert.ElementsMatch(t.T(), []string{"dog.txt", "fox.txt"}, names)
+}
+
+func (t *HTTPAPITestSuite) TestWrite() {
+	resp, body := t.do(http.MethodPut, "/tmp/a/dog.txt", "jumps over the lazy dog")
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	var m map[string]any
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &m))
+	assert.Equal(t.T(), "dog.txt", m["name"])
+	assert.Equal(t.T(), float64(23), m["size"])
+
+	b, err := t.backing.ReadFile("tmp/a/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	resp, _ = t.do(http.MethodPut, "/doc/fox.txt", "the quick red fox")
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+
+	b, err = t.backing.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick red fox", string(b))
+
+	resp, _ = t.do(http.MethodPut, "/doc", "data")
+	assert.Equal(t.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (t *HTTPAPITestSuite) TestWriteConditional() {
+	put := func(path string, header string, value string, body string) int {
+		req, err := http.NewRequest(http.MethodPut, t.server.URL+"/api"+path, strings.NewReader(body))
+		if err != nil {
+			t.T().Fatal(err)
+		}
+		req.Header.Set(header, value)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	fi, err := t.backing.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	gen := fi.(*fs.Entry).Attributes().Generation()
+
+	assert.Equal(t.T(), http.StatusPreconditionFailed, put("/doc/fox.txt", "If-Match", fmt.Sprintf(`"%d"`, gen+1), "the red fox"))
+	assert.Equal(t.T(), http.StatusOK, put("/doc/fox.txt", "If-Match", fmt.Sprintf(`"%d"`, gen), "the red fox"))
+	assert.Equal(t.T(), http.StatusPreconditionFailed, put("/doc/fox.txt", "If-Match", fmt.Sprintf(`"%d"`, gen), "the fox"))
+	assert.Equal(t.T(), http.StatusBadRequest, put("/doc/fox.txt", "If-Match", "*", "the fox"))
+
+	assert.Equal(t.T(), http.StatusCreated, put("/doc/dog.txt", "X-Goog-If-Generation-Match", "0", "the lazy dog"))
+	assert.Equal(t.T(), http.StatusPreconditionFailed, put("/doc/dog.txt", "X-Goog-If-Generation-Match", "0", "the dog"))
+
+	since := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	assert.Equal(t.T(), http.StatusPreconditionFailed, put("/doc/dog.txt", "If-Unmodified-Since", since, "the dog"))
+
+	since = time.Now().UTC().Format(http.TimeFormat)
+	assert.Equal(t.T(), http.StatusOK, put("/doc/dog.txt", "If-Unmodified-Since", since, "the dog"))
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the dog", string(b))
+
+	b, err = t.backing.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the red fox", string(b))
+}
+
+func (t *HTTPAPITestSuite) TestMkdir() {
+	resp, body := t.do(http.MethodPost, "/tmp?mkdir", "")
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	var m map[string]any
+	assert.NoError(t.T(), json.Unmarshal([]byte(body), &m))
+	assert.Equal(t.T(), true, m["is_dir"])
+
+	resp, _ = t.do(http.MethodPost, "/tmp?mkdir", "")
+	assert.Equal(t.T(), http.StatusConflict, resp.StatusCode)
+
+	resp, _ = t.do(http.MethodPost, "/a/b/c?mkdir&all", "")
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	fi, err := t.backing.Stat("a/b/c")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+
+	resp, _ = t.do(http.MethodPost, "/tmp", "")
+	assert.Equal(t.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (t *HTTPAPITestSuite) TestDelete() {
+	resp, _ := t.do(http.MethodDelete, "/doc", "")
+	assert.Equal(t.T(), http.StatusConflict, resp.StatusCode)
+
+	resp, _ = t.do(http.MethodDelete, "/doc/fox.txt", "")
+	assert.Equal(t.T(), http.StatusNoContent, resp.StatusCode)
+
+	_, err := t.backing.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.backing.WriteFile("doc/dog.txt", []byte("jumps over the lazy dog"), 0644))
+	resp, _ = t.do(http.MethodDelete, "/doc?recursive", "")
+	assert.Equal(t.T(), http.StatusNoContent, resp.StatusCode)
+
+	_, err = t.backing.Stat("doc")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	resp, _ = t.do(http.MethodDelete, "/doc", "")
+	assert.Equal(t.T(), http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = t.do(http.MethodDelete, "/", "")
+	assert.Equal(t.T(), http.StatusForbidden, resp.StatusCode)
+}
+
+func (t *HTTPAPITestSuite) TestMethodNotAllowed() {
+	resp, _ := t.do(http.MethodPatch, "/doc/fox.txt", "")
+	assert.Equal(t.T(), http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.NotEmpty(t.T(), resp.Header.Get("Allow"))
+}
+
+func (t *HTTPAPITestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	h, err := New(t.backing, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing.txt", nil))
+	assert.Equal(t.T(), http.StatusNotFound, w.Code)
+	assert.Contains(t.T(), buf.String(), `msg="[httpapi] request" method=GET path=missing.txt error=`)
+
+	h, err = New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), h.logger)
+}
@@ -0,0 +1,430 @@
+// Package httpapi serves a JSON/HTTP API for the operations of an fs.FS, so that lightweight tooling and clients such
+// as curl can inspect and manipulate a file system.
+//
+// Requests address entries by URL path, relative to the root of the file system:
+//
+//	GET    /path/to/file          reads the content of a file
+//	GET    /path/to/dir           lists the entries in a directory
+//	GET    /path?stat             returns the metadata of an entry
+//	HEAD   /path                  returns the headers for the content of a file
+//	PUT    /path/to/file          writes the request body to a file, creating parent directories as needed
+//	POST   /path/to/dir?mkdir     creates a directory, and its parents if the "all" parameter is present
+//	DELETE /path                  removes an entry, and its children if the "recursive" parameter is present
+//
+// Metadata is returned as JSON built from fs.Entry.ToMap, with directory listings returned as an array of entries.
+// Errors are returned as a JSON object with an "error" field and a status code that corresponds to the error.
+//
+// Writes are conditional if the file system implements fs.ConditionalWriteFS and the request has one of the following
+// headers, in which case a 412 (Precondition Failed) response is returned if the condition is not met:
+//
+//	If-Match                      the quoted generation of the file, as returned in its metadata
+//	X-Goog-If-Generation-Match    the generation of the file, or 0 if the file must not exist
+//	If-Unmodified-Since           the time the file must not have been modified after
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var _ http.Handler = (*Handler)(nil)
+
+// Handler is an http.Handler that serves the operations of an fs.FS as a JSON/HTTP API.
+type Handler struct {
+	dirPerm  gofs.FileMode
+	filePerm gofs.FileMode
+	fsys     fs.FS
+	logger   fs.Logger
+	prefix   string
+}
+
+// New creates a new Handler that serves fsys.
+func New(fsys fs.FS, options ...func(*Handler)) (*Handler, error) {
+	if fsys == nil {
+		return nil, errors.New("httpapi: file system is required")
+	}
+
+	h := &Handler{dirPerm: 0755, filePerm: 0644, fsys: fsys, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h, nil
+}
+
+// FS returns the fs.FS served by the Handler.
+func (h *Handler) FS() fs.FS {
+	return h.fsys
+}
+
+// ServeHTTP ...
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := h.path(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.logger.Debug("[httpapi] request", "method", r.Method, "path", name)
+
+	var err error
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		err = h.get(w, r, name)
+	case http.MethodPut:
+		err = h.put(w, r, name)
+	case http.MethodPost:
+		err = h.post(w, r, name)
+	case http.MethodDelete:
+		err = h.delete(w, r, name)
+	default:
+		w.Header().Set("Allow", "DELETE, GET, HEAD, POST, PUT")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	if err != nil {
+		h.logger.Debug("[httpapi] request", "method", r.Method, "path", name, "error", err)
+		writeError(w, statusCode(err), err)
+	}
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, name string) error {
+	if name == "." {
+		return &gofs.PathError{Op: "remove", Path: name, Err: gofs.ErrPermission}
+	}
+
+	if _, err := h.fsys.Stat(name); err != nil {
+		return err
+	}
+
+	var err error
+	if r.URL.Query().Has("recursive") {
+		err = h.fsys.RemoveAll(name)
+	} else {
+		err = h.fsys.Remove(name)
+	}
+
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, name string) error {
+	fi, err := h.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	if r.URL.Query().Has("stat") {
+		m, err := toMap(name, fi)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, r, http.StatusOK, m)
+		return nil
+	}
+
+	if fi.IsDir() {
+		return h.list(w, r, name)
+	}
+
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			h.logger.Error("[httpapi] get", "path", name, "error", err)
+		}
+	}()
+
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return &gofs.PathError{Op: "read", Path: name, Err: errors.ErrUnsupported}
+	}
+	if e, ok := fi.(*fs.Entry); ok && e.Attributes().MimeType() != "" {
+		w.Header().Set("Content-Type", e.Attributes().MimeType())
+	}
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+	return nil
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, name string) error {
+	entries, err := h.fsys.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	list := make([]map[string]any, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+
+		m, err := toMap(gopath.Join(name, e.Name()), fi)
+		if err != nil {
+			return err
+		}
+		list = append(list, m)
+	}
+	writeJSON(w, r, http.StatusOK, list)
+	return nil
+}
+
+func (h *Handler) post(w http.ResponseWriter, r *http.Request, name string) error {
+	if !r.URL.Query().Has("mkdir") {
+		writeError(w, http.StatusBadRequest, errors.New("unsupported operation"))
+		return nil
+	}
+
+	var err error
+	if r.URL.Query().Has("all") {
+		err = h.fsys.MkdirAll(name, h.dirPerm)
+	} else {
+		err = h.fsys.Mkdir(name, h.dirPerm)
+	}
+
+	if err != nil {
+		return err
+	}
+	return h.writeEntry(w, r, http.StatusCreated, name)
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, name string) error {
+	if name == "." {
+		return &gofs.PathError{Op: "write", Path: name, Err: fs.ErrIsDir}
+	}
+
+	code := http.StatusOK
+	fi, err := h.fsys.Stat(name)
+	switch {
+	case err == nil && fi.IsDir():
+		return &gofs.PathError{Op: "write", Path: name, Err: fs.ErrIsDir}
+	case errors.Is(err, gofs.ErrNotExist):
+		code = http.StatusCreated
+	case err != nil:
+		return err
+	}
+
+	if dir := gopath.Dir(name); dir != "." {
+		if err := h.fsys.MkdirAll(dir, h.dirPerm); err != nil {
+			return err
+		}
+	}
+
+	if cond, ok, err := precondition(r); ok || err != nil {
+		if err != nil {
+			return &gofs.PathError{Op: "write", Path: name, Err: err}
+		}
+
+		cw, ok := h.fsys.(fs.ConditionalWriteFS)
+		if !ok {
+			return &gofs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+
+		if cond.since.IsZero() {
+			err = cw.WriteFileIf(name, data, h.filePerm, cond.generation)
+		} else {
+			err = cw.WriteFileIfUnmodified(name, data, cond.since)
+		}
+
+		if err != nil {
+			return err
+		}
+		return h.writeEntry(w, r, code, name)
+	}
+
+	f, err := h.fsys.OpenFile(name, fs.O_WRONLY|fs.O_CREATE|fs.O_TRUNC, h.filePerm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.ReadFrom(r.Body); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return h.writeEntry(w, r, code, name)
+}
+
+// path converts the URL path of a request to a path for the fs.FS served by the Handler. The path is resolved using
+// fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a location outside of the fs.FS.
+func (h *Handler) path(urlPath string) (string, bool) {
+	if h.prefix != "" {
+		p, ok := strings.CutPrefix(urlPath, h.prefix)
+		if !ok {
+			return "", false
+		}
+		urlPath = p
+	}
+
+	p, err := fs.SecureJoin(h.fsys, ".", urlPath)
+	if err != nil {
+		return "", false
+	}
+	return p, true
+}
+
+func (h *Handler) writeEntry(w http.ResponseWriter, r *http.Request, code int, name string) error {
+	fi, err := h.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	m, err := toMap(name, fi)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, r, code, m)
+	return nil
+}
+
+// WithDirPerm sets the permission bits used for directories created by the Handler. The default is 0755.
+func WithDirPerm(perm gofs.FileMode) func(*Handler) {
+	return func(h *Handler) {
+		h.dirPerm = perm.Perm()
+	}
+}
+
+// WithFilePerm sets the permission bits used for files created by the Handler. The default is 0644.
+func WithFilePerm(perm gofs.FileMode) func(*Handler) {
+	return func(h *Handler) {
+		h.filePerm = perm.Perm()
+	}
+}
+
+// WithLogger sets the Logger used by a Handler to log the requests that it serves. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*Handler) {
+	return func(h *Handler) {
+		if logger != nil {
+			h.logger = logger
+		}
+	}
+}
+
+// WithPrefix sets the URL path prefix that is stripped from request paths, for when the Handler is not mounted at the
+// root of a server.
+func WithPrefix(prefix string) func(*Handler) {
+	return func(h *Handler) {
+		h.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// condition is the condition for a conditional write requested using the headers of a request.
+type condition struct {
+	generation int64
+	since      time.Time
+}
+
+// precondition returns the condition requested using the If-Match, X-Goog-If-Generation-Match, or If-Unmodified-Since
+// header of r, and whether one was requested. An error wrapping fs.ErrInvalid is returned if the header is malformed.
+func precondition(r *http.Request) (condition, bool, error) {
+	if v := r.Header.Get("If-Match"); v != "" {
+		gen, err := strconv.ParseInt(strings.Trim(v, `"`), 10, 64)
+		if err != nil || gen <= 0 {
+			return condition{}, false, fmt.Errorf("invalid If-Match header %q: %w", v, fs.ErrInvalid)
+		}
+		return condition{generation: gen}, true, nil
+	}
+
+	if v := r.Header.Get("X-Goog-If-Generation-Match"); v != "" {
+		gen, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || gen < 0 {
+			return condition{}, false, fmt.Errorf("invalid X-Goog-If-Generation-Match header %q: %w", v, fs.ErrInvalid)
+		}
+		return condition{generation: gen}, true, nil
+	}
+
+	if v := r.Header.Get("If-Unmodified-Since"); v != "" {
+		t, err := http.ParseTime(v)
+		if err != nil {
+			return condition{}, false, fmt.Errorf("invalid If-Unmodified-Since header %q: %w", v, fs.ErrInvalid)
+		}
+
+		// HTTP dates have a resolution of one second, so a file modified within the second is considered unmodified.
+		return condition{since: t.Add(time.Second - time.Nanosecond)}, true, nil
+	}
+	return condition{}, false, nil
+}
+
+// statusCode returns the HTTP status code that corresponds to err.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, gofs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, gofs.ErrExist), errors.Is(err, fs.ErrNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, gofs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, gofs.ErrInvalid), errors.Is(err, fs.ErrIsDir), errors.Is(err, fs.ErrNotDir):
+		return http.StatusBadRequest
+	case errors.Is(err, fs.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, fs.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errors.ErrUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// toMap returns the JSON representation of the metadata for the named entry. Metadata that is not provided as an
+// fs.Entry is converted to one.
+func toMap(name string, fi gofs.FileInfo) (map[string]any, error) {
+	e, ok := fi.(*fs.Entry)
+	if !ok {
+		var err error
+		if e, err = fs.EntryFromFileInfo(name, fi); err != nil {
+			return nil, err
+		}
+	}
+	return e.ToMap()
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,160 @@
+package fs
+
+import (
+	"errors"
+	"io"
+	"iter"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// iterateDirBatchSize is the number of entries read at a time by IterateDir from directories of file systems that do
+// not implement DirIteratorFS.
+const iterateDirBatchSize = 256
+
+// IterateDir returns an iterator over the entries of the named directory, so that directories with a large number of
+// entries can be processed without reading every entry into memory. Dot entries are skipped, and the path of each
+// entry is the name of the entry joined to name.
+//
+// If fsys implements DirIteratorFS, its IterateDir method is used. Otherwise, if the directory opened by fsys
+// implements gofs.ReadDirFile, the entries are read in batches, and otherwise gofs.ReadDir is used. The entries are
+// returned in the order provided by fsys, which is not necessarily sorted.
+//
+// If sort options such as By and Desc are provided, the entries are returned in the same order as by ReadDirSorted. If
+// fsys implements SortedDirIteratorFS, the entries are still retrieved as they are iterated, and otherwise every entry
+// is read before the first entry is yielded.
+//
+// If an error occurs, it is yielded with a nil *Entry and iteration stops.
+func IterateDir(fsys gofs.FS, name string, options ...SortOption) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		if fsys == nil {
+			yield(nil, errors.New("fs: file system is required"))
+			return
+		}
+
+		if len(options) > 0 {
+			iterateDirSorted(fsys, name, options, yield)
+			return
+		}
+
+		if d, ok := fsys.(DirIteratorFS); ok {
+			it, err := d.IterateDir(name)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			iterateDir(it, name, yield)
+			return
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+
+		rdf, ok := f.(gofs.ReadDirFile)
+		if !ok {
+			entries, err := gofs.ReadDir(fsys, name)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, d := range entries {
+				if !yieldDirEntry(name, d, yield) {
+					return
+				}
+			}
+			return
+		}
+
+		for {
+			entries, err := rdf.ReadDir(iterateDirBatchSize)
+			for _, d := range entries {
+				if !yieldDirEntry(name, d, yield) {
+					return
+				}
+			}
+
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// iterateDir yields the entries of the named directory returned by the DirIterator it.
+func iterateDir(it DirIterator, name string, yield func(*Entry, error) bool) {
+	for it.HasNext() {
+		e, err := it.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				yield(nil, err)
+			}
+			return
+		}
+
+		c, err := EntryFromFileInfo(gopath.Join(name, e.Name()), e)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		if !yield(c, nil) {
+			return
+		}
+	}
+}
+
+// iterateDirSorted yields the entries of the named directory sorted using the sort options.
+func iterateDirSorted(fsys gofs.FS, name string, options []SortOption, yield func(*Entry, error) bool) {
+	opts := newSortOptions(options...)
+	if s, ok := fsys.(SortedDirIteratorFS); ok {
+		it, err := s.IterateDirSorted(name, opts.key, opts.desc)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		iterateDir(it, name, yield)
+		return
+	}
+
+	var entries []*Entry
+	for e, err := range IterateDir(fsys, name) {
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	SortEntries(entries, options...)
+	for _, e := range entries {
+		if !yield(e, nil) {
+			return
+		}
+	}
+}
+
+// yieldDirEntry yields the Entry for the directory entry d in the directory name, and reports whether iteration should
+// continue.
+func yieldDirEntry(name string, d gofs.DirEntry, yield func(*Entry, error) bool) bool {
+	fi, err := d.Info()
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+
+	e, err := EntryFromFileInfo(gopath.Join(name, d.Name()), fi)
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+	return yield(e, nil)
+}
@@ -0,0 +1,71 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterateDir(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	var want []string
+	for i := range 300 {
+		name := filepath.Join(root, "f"+string(rune('a'+i%26))+string(rune('a'+i/26)))
+		assert.NoError(t, os.WriteFile(name, []byte("x"), 0644))
+		want = append(want, name)
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0755))
+	want = append(want, filepath.Join(root, "dir"))
+
+	var got []string
+	for e, err := range fs.IterateDir(osfs, root) {
+		if !assert.NoError(t, err) {
+			break
+		}
+		assert.Equal(t, filepath.Base(e.Path()), e.Name())
+		assert.Equal(t, e.Name() == "dir", e.IsDir())
+		got = append(got, e.Path())
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	assert.Equal(t, want, got)
+
+	// Iteration stops when the caller stops consuming entries.
+	n := 0
+	for range fs.IterateDir(osfs, root) {
+		if n++; n == 10 {
+			break
+		}
+	}
+	assert.Equal(t, 10, n)
+
+	for _, err := range fs.IterateDir(osfs, filepath.Join(root, "missing")) {
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
+
+func TestIterateDirFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"doc/fox.txt": {Data: []byte("the quick brown fox")},
+		"doc/dog.txt": {Data: []byte("the lazy dog")},
+		"doc/a/b.txt": {Data: []byte("b")},
+	}
+
+	var got []string
+	for e, err := range fs.IterateDir(fsys, "doc") {
+		assert.NoError(t, err)
+		got = append(got, e.Path())
+	}
+	assert.Equal(t, []string{"doc/a", "doc/dog.txt", "doc/fox.txt"}, got)
+}
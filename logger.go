@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"sync/atomic"
+)
+
+var logger atomic.Value
+
+// Logger defines the logging used by the fs package and by providers that accept a logger, such as memfs.
+//
+// The arguments following the message are alternating keys and values, in the same way as for slog.Logger, which
+// implements Logger. A Logger must be safe for concurrent use. By default, nothing is logged.
+type Logger interface {
+	// Debug logs a message describing the internal operation of a file system.
+	Debug(msg string, args ...any)
+
+	// Error logs a message describing an error that could not be returned to the caller.
+	Error(msg string, args ...any)
+
+	// Info logs a message describing a change to the configuration of a file system.
+	Info(msg string, args ...any)
+
+	// Warn logs a message describing a condition that was recovered from, such as a fallback.
+	Warn(msg string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Error(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+
+// NopLogger returns a Logger that discards all messages.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+// SetLogger sets the Logger used by the fs package, such as by SetDefault. If l is nil, nothing is logged.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger()
+	}
+	logger.Store(&l)
+}
+
+// log returns the Logger set using SetLogger.
+func log() Logger {
+	if l, ok := logger.Load().(*Logger); ok {
+		return *l
+	}
+	return NopLogger()
+}
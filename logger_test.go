@@ -0,0 +1,26 @@
+package fs_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	fs.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer fs.SetLogger(nil)
+
+	fsys := fs.Default()
+	assert.NoError(t, fs.SetDefault(fsys))
+	assert.Contains(t, buf.String(), `msg="[fs] setting default file system" provider=`+fsys.Provider())
+
+	buf.Reset()
+	fs.SetLogger(nil)
+	assert.NoError(t, fs.SetDefault(fsys))
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,80 @@
+// Package loggo adapts the default logger of github.com/transientvariable/log-go to fs.Logger, so that providers
+// configured with a Logger log in the same way as before the logger was pluggable:
+//
+//	mfs, err := memfs.New(memfs.WithLogger(loggo.New()))
+package loggo
+
+import (
+	"fmt"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/log-go"
+)
+
+// badKey is the key used for a value that is not preceded by a key, in the same way as slog.
+const badKey = "!BADKEY"
+
+var _ fs.Logger = (*Logger)(nil)
+
+// Logger implements fs.Logger using the default log-go logger.
+type Logger struct{}
+
+// New creates a new Logger.
+func New() *Logger {
+	return &Logger{}
+}
+
+// Debug logs msg at the debug level.
+func (l *Logger) Debug(msg string, args ...any) {
+	log.Debug(msg, attrs(args)...)
+}
+
+// Error logs msg at the error level.
+func (l *Logger) Error(msg string, args ...any) {
+	log.Error(msg, attrs(args)...)
+}
+
+// Info logs msg at the info level.
+func (l *Logger) Info(msg string, args ...any) {
+	log.Info(msg, attrs(args)...)
+}
+
+// Warn logs msg at the warn level.
+func (l *Logger) Warn(msg string, args ...any) {
+	log.Warn(msg, attrs(args)...)
+}
+
+// attrs converts the alternating keys and values in args to log-go attributes.
+func attrs(args []any) []func(*log.Record) {
+	var a []func(*log.Record)
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			a = append(a, attr(badKey, args[0]))
+			args = args[1:]
+			continue
+		}
+		a = append(a, attr(key, args[1]))
+		args = args[2:]
+	}
+	return a
+}
+
+// attr returns the log-go attribute for the key and value.
+func attr(key string, value any) func(*log.Record) {
+	switch v := value.(type) {
+	case error:
+		if key == "error" {
+			return log.Err(v)
+		}
+		return log.String(key, v.Error())
+	case string:
+		return log.String(key, v)
+	case int:
+		return log.Int(key, v)
+	case fmt.Stringer:
+		return log.String(key, v.String())
+	default:
+		return log.Any(key, v)
+	}
+}
@@ -0,0 +1,24 @@
+package loggo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/transientvariable/log-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttrs(t *testing.T) {
+	assert.Len(t, attrs([]any{"name", "fox", "size", 3, "error", errors.New("boom")}), 3)
+	assert.Len(t, attrs([]any{"name"}), 1)
+	assert.Len(t, attrs([]any{3, "name", "fox"}), 2)
+	assert.Empty(t, attrs(nil))
+
+	log.Init()
+	l := New()
+	l.Debug("[loggo] debug", "name", "fox")
+	l.Error("[loggo] error", "error", errors.New("boom"))
+	l.Info("[loggo] info")
+	l.Warn("[loggo] warn", "size", 3)
+}
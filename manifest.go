@@ -0,0 +1,203 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+)
+
+// ManifestOption defines an option for Manifest.
+type ManifestOption func(*manifestOptions)
+
+type manifestOptions struct {
+	checksums []crypto.Hash
+	walk      []WalkOption
+}
+
+// Manifest writes a listing of every entry in the tree rooted at root to w, so that the tree can be audited or
+// recreated using ApplyManifest.
+//
+// The listing is written in the JSON Lines format as each entry is visited, so that large trees can be listed without
+// holding the listing in memory. Each line is the JSON encoding of an Entry, as produced by Entry.MarshalJSON, with a
+// path relative to root. Entries are listed in lexical order, and root itself is not listed.
+func Manifest(w io.Writer, fsys gofs.FS, root string, options ...ManifestOption) error {
+	if w == nil {
+		return errors.New("fs: writer is required")
+	}
+
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &manifestOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	enc := json.NewEncoder(w)
+	return Walk(fsys, root, func(p string, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == root {
+			return nil
+		}
+
+		e := entry.Copy()
+		e.path = p
+		if root != "." {
+			e.path = strings.TrimPrefix(p, prefix)
+		}
+
+		if e.Mode().IsRegular() {
+			for _, hash := range opts.checksums {
+				digest, err := HashFile(fsys, p, hash)
+				if err != nil {
+					return err
+				}
+				e.Attributes().SetChecksum(ChecksumAlgorithm(hash), hex.EncodeToString(digest))
+			}
+		}
+
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("fs: manifest: %w", err)
+		}
+		return nil
+	}, opts.walk...)
+}
+
+// ApplyManifest creates a skeleton of the tree listed by a manifest produced by Manifest under root, for example to
+// generate fixtures with the same structure as an existing tree.
+//
+// Directories are created with the permissions listed in the manifest, and regular files are created empty. Symbolic
+// links are created if fsys implements SymlinkFS. The modification times, user-defined metadata, and MIME types of the
+// entries are applied if fsys implements ChtimesFS, MetadataFS, and MimeTypeFS respectively. An error wrapping
+// ErrUnsupported is returned for entries that can not be created by fsys, such as devices.
+func ApplyManifest(fsys FS, root string, r io.Reader) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	if r == nil {
+		return errors.New("fs: reader is required")
+	}
+
+	// Directory times are applied once the tree has been created, since creating the entries of a directory changes
+	// its modification time.
+	var dirs []*Entry
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("fs: manifest: %w", err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			if err != nil {
+				break
+			}
+			continue
+		}
+
+		e := &Entry{}
+		if err := json.Unmarshal(line, e); err != nil {
+			return fmt.Errorf("fs: manifest: %w", err)
+		}
+
+		p := Join(fsys, root, e.Path())
+		switch mode := e.Mode(); {
+		case mode.IsDir():
+			if err := fsys.MkdirAll(p, mode.Perm()); err != nil {
+				return err
+			}
+			dirs = append(dirs, e)
+			continue
+		case mode.IsRegular():
+			if err := fsys.WriteFile(p, nil, mode.Perm()); err != nil {
+				return err
+			}
+		case mode&gofs.ModeSymlink != 0:
+			sfs, ok := fsys.(SymlinkFS)
+			if !ok {
+				return NewOpError(fsys.Provider(), "applyManifest", p, ErrUnsupported)
+			}
+
+			if err := sfs.Symlink(e.Attributes().LinkTarget(), p); err != nil {
+				return err
+			}
+			continue
+		default:
+			return NewOpError(fsys.Provider(), "applyManifest", p, ErrUnsupported)
+		}
+
+		if err := applyManifestAttributes(fsys, p, e); err != nil {
+			return err
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyManifestAttributes(fsys, Join(fsys, root, dirs[i].Path()), dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithManifestChecksums sets the hash functions used by Manifest to compute the checksums of regular files, which are
+// listed by the Attribute of each entry. By default, only the checksums already known by the file system are listed.
+func WithManifestChecksums(hashes ...crypto.Hash) ManifestOption {
+	return func(o *manifestOptions) {
+		o.checksums = append(o.checksums, hashes...)
+	}
+}
+
+// WithManifestWalkOptions sets the options used by Manifest to walk the tree, such as WithSkipPatterns or
+// WithMaxDepth.
+func WithManifestWalkOptions(options ...WalkOption) ManifestOption {
+	return func(o *manifestOptions) {
+		o.walk = append(o.walk, options...)
+	}
+}
+
+// applyManifestAttributes applies the attributes of the entry e listed in a manifest to the entry at path p.
+func applyManifestAttributes(fsys FS, p string, e *Entry) error {
+	attrs := e.Attributes()
+	if c, ok := fsys.(ChtimesFS); ok {
+		atime := attrs.Atime()
+		if atime.IsZero() {
+			atime = e.ModTime()
+		}
+
+		if mtime := e.ModTime(); !mtime.IsZero() {
+			if err := c.Chtimes(p, atime, mtime); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m, ok := fsys.(MetadataFS); ok {
+		if metadata := attrs.Metadata(); len(metadata) > 0 {
+			if err := m.SetMetadata(p, metadata); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m, ok := fsys.(MimeTypeFS); ok && attrs.MimeType() != "" {
+		if err := m.SetMimeType(p, attrs.MimeType()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package fs_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	_ "crypto/sha256"
+	gofs "io/fs"
+)
+
+func TestManifest(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, src.MkdirAll("tree/pictures", 0700))
+	assert.NoError(t, src.WriteFile("tree/doc/fox.txt", []byte("the quick brown fox"), 0640))
+	assert.NoError(t, src.WriteFile("tree/pictures/seals.png", []byte("seals"), 0644))
+	assert.NoError(t, src.SetMetadata("tree/doc/fox.txt", map[string]string{"color": "brown"}))
+	assert.NoError(t, src.SetMimeType("tree/doc/fox.txt", "text/plain"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, fs.Manifest(&buf, src, "tree", fs.WithManifestChecksums(crypto.SHA256)))
+
+	var paths []string
+	s := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for s.Scan() {
+		var line struct {
+			Path       string `json:"path"`
+			Attributes struct {
+				Checksums map[string]string `json:"checksums"`
+			} `json:"attributes"`
+		}
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &line))
+		paths = append(paths, line.Path)
+
+		if line.Path == "doc/fox.txt" {
+			assert.Equal(t, "9ecb36561341d18eb65484e833efea61edc74b84cf5e6ae1b81c63533e25fc8f",
+				line.Attributes.Checksums["sha256"])
+		}
+	}
+	assert.Equal(t, []string{"doc", "doc/fox.txt", "pictures", "pictures/seals.png"}, paths)
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.ApplyManifest(dst, "copy", bytes.NewReader(buf.Bytes())))
+
+	fi, err := dst.Stat("copy/pictures")
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, gofs.FileMode(0700), fi.Mode().Perm())
+
+	fi, err = dst.Stat("copy/doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0640), fi.Mode().Perm())
+	assert.Equal(t, int64(0), fi.Size())
+
+	attrs := fi.(*fs.Entry).Attributes()
+	assert.Equal(t, map[string]string{"color": "brown"}, attrs.Metadata())
+	assert.Equal(t, "text/plain", attrs.MimeType())
+
+	assert.Error(t, fs.ApplyManifest(dst, ".", bytes.NewReader([]byte(`{"path": "../escape"}`))))
+}
@@ -0,0 +1,144 @@
+package memfs
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// arena sub-allocates the buffers that hold file content from a single slab that is allocated when the MemFS is
+// created, so that writing files does not allocate memory, and the memory used by the MemFS is fixed.
+//
+// Buffers are allocated using a buddy allocator: the slab is divided into blocks whose sizes are powers of 2, from
+// 1<<minChunkShift bytes up to the largest power of 2 that fits in the slab, and a block is split in halves to provide
+// a smaller buffer, and merged with its buddy when both halves are free.
+type arena struct {
+	free  []map[int]struct{}
+	mutex sync.Mutex
+	slab  []byte
+	used  int64
+}
+
+// newArena creates an arena with a slab of n bytes, rounded down to a multiple of 1<<minChunkShift. The slab is backed
+// by an anonymous memory mapping if possible, so that it is not allocated on the Go heap, and is unmapped once the
+// arena is no longer reachable.
+func newArena(n int) *arena {
+	n &^= 1<<minChunkShift - 1
+
+	a := &arena{}
+	if b, err := mmap(n); err == nil {
+		a.slab = b
+		runtime.AddCleanup(a, func(b []byte) { _ = munmap(b) }, b)
+	} else {
+		a.slab = make([]byte, n)
+	}
+
+	a.free = make([]map[int]struct{}, max(bits.Len(uint(n))-minChunkShift, 0))
+	for i := range a.free {
+		a.free[i] = make(map[int]struct{})
+	}
+
+	// The slab is divided into the largest blocks possible, from largest to smallest, so that each block is aligned to
+	// its size and the buddy of a block is always within the same top-level block.
+	for off := 0; off < n; {
+		order := bits.Len(uint(n-off)) - 1 - minChunkShift
+		a.free[order][off] = struct{}{}
+		off += 1 << (order + minChunkShift)
+	}
+	return a
+}
+
+// alloc returns a buffer of at least n bytes from the slab, and whether the arena has a free block that is large
+// enough. The length and capacity of the buffer are the size of the block. The content of the buffer is undefined.
+func (a *arena) alloc(n int) ([]byte, bool) {
+	order := a.order(n)
+	if order >= len(a.free) {
+		return nil, false
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// The smallest free block that is large enough is split until it has the requested size.
+	o := order
+	for o < len(a.free) && len(a.free[o]) == 0 {
+		o++
+	}
+
+	if o == len(a.free) {
+		return nil, false
+	}
+
+	var off int
+	for off = range a.free[o] {
+		break
+	}
+	delete(a.free[o], off)
+
+	for ; o > order; o-- {
+		a.free[o-1][off+1<<(o-1+minChunkShift)] = struct{}{}
+	}
+
+	size := 1 << (order + minChunkShift)
+	a.used += int64(size)
+	return a.slab[off : off+size : off+size], true
+}
+
+// capacity returns the size of the block allocated for n bytes.
+func (a *arena) capacity(n int) int {
+	return 1 << (a.order(n) + minChunkShift)
+}
+
+// owns returns whether b is a buffer allocated from the slab.
+func (a *arena) owns(b []byte) bool {
+	if cap(b) == 0 || len(a.slab) == 0 {
+		return false
+	}
+
+	p := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(a.slab)))
+	return p >= base && p < base+uintptr(len(a.slab))
+}
+
+// release returns a buffer allocated using alloc to the arena, merging its block with its buddy while both are free.
+func (a *arena) release(b []byte) {
+	off := int(uintptr(unsafe.Pointer(unsafe.SliceData(b))) - uintptr(unsafe.Pointer(unsafe.SliceData(a.slab))))
+	order := a.order(cap(b))
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.used -= int64(cap(b))
+	for ; order < len(a.free)-1; order++ {
+		buddy := off ^ 1<<(order+minChunkShift)
+		if _, ok := a.free[order][buddy]; !ok {
+			break
+		}
+		delete(a.free[order], buddy)
+		off = min(off, buddy)
+	}
+	a.free[order][off] = struct{}{}
+}
+
+// size returns the size of the slab.
+func (a *arena) size() int64 {
+	return int64(len(a.slab))
+}
+
+// usage returns the number of bytes of the slab allocated to buffers.
+func (a *arena) usage() int64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.used
+}
+
+// order returns the order of the smallest block that holds n bytes, where a block of order k holds
+// 1<<(k+minChunkShift) bytes.
+func (a *arena) order(n int) int {
+	if n <= 1<<minChunkShift {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - minChunkShift
+}
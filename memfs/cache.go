@@ -0,0 +1,78 @@
+package memfs
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedLookups is the maximum number of paths held by a lookupCache. The cache is cleared once it is full, so that
+// the memory used by the cache is bounded without tracking the use of each path.
+const maxCachedLookups = 4096
+
+// lookupKey identifies the path of an entry relative to a directory.
+type lookupKey struct {
+	dir  *MemFS
+	name string
+}
+
+// lookupCache maps the paths of entries in nested directories to the entries, so that repeated lookups of paths in
+// deep trees do not descend the tree one directory at a time.
+//
+// Only entries that exist are cached. Since adding an entry does not change the entries that exist, the cache is only
+// invalidated when entries are removed or renamed, which clears the entire cache.
+type lookupCache struct {
+	entries    map[lookupKey]*fsEntry
+	generation uint64
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	mutex      sync.RWMutex
+}
+
+// get returns the cached entry for the named path relative to dir, and the generation of the cache that must be
+// passed to put if the entry is not cached.
+func (c *lookupCache) get(dir *MemFS, name string) (*fsEntry, uint64, bool) {
+	c.mutex.RLock()
+	e, ok := c.entries[lookupKey{dir: dir, name: name}]
+	gen := c.generation
+	c.mutex.RUnlock()
+
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return e, gen, ok
+}
+
+// put caches the entry e for the named path relative to dir, unless the cache was invalidated since generation was
+// returned by get.
+func (c *lookupCache) put(dir *MemFS, name string, e *fsEntry, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if c.entries == nil || len(c.entries) >= maxCachedLookups {
+		c.entries = make(map[lookupKey]*fsEntry)
+	}
+	c.entries[lookupKey{dir: dir, name: name}] = e
+}
+
+// invalidate clears the cache after entries were removed or renamed.
+func (c *lookupCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.entries = nil
+}
+
+// cache returns the lookup cache shared by the directories of the MemFS, or nil if the MemFS was not created by New.
+func (m *MemFS) cache() *lookupCache {
+	if m.opts == nil {
+		return nil
+	}
+	return &m.opts.cache
+}
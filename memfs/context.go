@@ -0,0 +1,90 @@
+package memfs
+
+import (
+	"context"
+	"io"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// ctxCheckInterval is the number of entries that are processed by an operation between checks of whether its context
+// is done.
+const ctxCheckInterval = 1024
+
+var (
+	_ fs.FS                 = (*contextFS)(nil)
+	_ fs.PrefixReadDirPager = (*contextFS)(nil)
+)
+
+// contextFS is the view of a MemFS returned by MemFS.WithContext.
+type contextFS struct {
+	*MemFS
+	ctx context.Context
+}
+
+func (c *contextFS) Create(name string) (fs.File, error) {
+	return c.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, modePerm)
+}
+
+func (c *contextFS) Glob(pattern string) ([]string, error) {
+	return c.MemFS.glob(c.ctx, pattern)
+}
+
+func (c *contextFS) Open(name string) (gofs.File, error) {
+	return c.open("open", name, fs.O_RDONLY, 0)
+}
+
+func (c *contextFS) OpenFile(name string, flag int, mode gofs.FileMode) (fs.File, error) {
+	return c.open("openFile", name, flag, mode)
+}
+
+func (c *contextFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return c.MemFS.readDir(c.ctx, name)
+}
+
+func (c *contextFS) ReadDirPage(name string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	return c.MemFS.readDirPage(c.ctx, name, pageSize, token)
+}
+
+func (c *contextFS) ReadDirPrefix(name string, prefix string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	return c.MemFS.readDirRange(c.ctx, "readDirPrefix", name, prefix, pageSize, token)
+}
+
+// Sub returns the sub-tree for dir bound to the context of the view.
+func (c *contextFS) Sub(dir string) (gofs.FS, error) {
+	if c.wal() != nil {
+		return fs.Chroot(c, dir)
+	}
+
+	sub, err := c.MemFS.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+	return sub.(*MemFS).WithContext(c.ctx), nil
+}
+
+// open opens the named file in the same way as MemFS.open, and binds the File to the context of the view.
+func (c *contextFS) open(op string, name string, flag int, mode gofs.FileMode) (fs.File, error) {
+	f, err := c.MemFS.open(op, name, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	f.ctx = c.ctx
+	return f, nil
+}
+
+// contextWriter writes to a File, returning the cause of ctx once ctx is done, so that copies using io.Copy check ctx
+// between chunks.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(b []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return 0, context.Cause(w.ctx)
+	}
+	return w.w.Write(b)
+}
@@ -3,7 +3,6 @@ package memfs
 import (
 	"github.com/transientvariable/anchor"
 	"github.com/transientvariable/fs-go"
-	"github.com/transientvariable/log-go"
 
 	gofs "io/fs"
 )
@@ -13,10 +12,11 @@ type fsEntry struct {
 	data  any
 }
 
-// Stat returns the FileInfo.
+// Stat returns a copy of the fs.Entry of the File or MemFS, so that the returned FileInfo is not changed by later
+// operations, and changes made using it do not affect the MemFS. The caller must hold the read lock of the MemFS.
 func (f *fsEntry) Stat() (gofs.FileInfo, error) {
 	if f.entry != nil {
-		return f.entry, nil
+		return snapshot(f.data, f.entry), nil
 	}
 	return nil, gofs.ErrInvalid
 }
@@ -47,7 +47,7 @@ func (f *fsEntry) String() string {
 
 		a, err := f.entry.Attributes().ToMap()
 		if err != nil {
-			log.Error("[memfs:entry]", log.Err(err))
+			s["error"] = err.Error()
 		}
 
 		s["entry"] = map[string]any{
@@ -57,3 +57,26 @@ func (f *fsEntry) String() string {
 	}
 	return ""
 }
+
+// lockMeta locks the metadata of the file descriptor if the entry is the entry of a file, so that its attributes are
+// not changed while they are copied by Stat, and returns a function that unlocks it.
+func (f *fsEntry) lockMeta() func() {
+	d, ok := f.data.(*fd)
+	if !ok {
+		return func() {}
+	}
+
+	d.meta.Lock()
+	return d.meta.Unlock
+}
+
+// snapshot returns a copy of entry, which is the fs.Entry of data. The entry of a file is copied while the metadata
+// lock of its file descriptor is held, since its attributes are changed by reads and writes without locking the MemFS.
+// The caller must hold the read lock of the MemFS, which is held for writing when the entry of a directory is changed.
+func snapshot(data any, entry *fs.Entry) *fs.Entry {
+	if d, ok := data.(*fd); ok {
+		d.meta.Lock()
+		defer d.meta.Unlock()
+	}
+	return entry.Copy()
+}
@@ -1,33 +1,52 @@
 package memfs
 
 import (
+	"crypto/sha256"
 	"errors"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/transientvariable/fs-go"
-	"github.com/transientvariable/log-go"
 
 	gofs "io/fs"
 )
 
 // fd (file descriptor) represents File content and its associated metadata.
+//
+// The directory of the file and the path of its entry are changed when the file is renamed, while the MemFS is locked
+// for writing. Since they are read by an open File without locking the MemFS, the directory is held atomically, and
+// the path is changed and read while the metadata lock is held.
 type fd struct {
-	data  []byte
-	dir   *MemFS
-	entry *fs.Entry
-	mutex sync.RWMutex
+	data      []byte
+	digest    []byte
+	digestGen int64
+	dir       atomic.Pointer[MemFS]
+	entry     *fs.Entry
+	meta      sync.Mutex
+	mutex     sync.RWMutex
+	observed  int64
+	pins      int
+	pipe      *pipe
+	refs      int
+	removed   bool
+	retired   [][]byte
+	unmap     runtime.Cleanup
 }
 
 func newfd(dir *MemFS, name string, flag int, mode gofs.FileMode) (*fd, error) {
 	e, err := entry(dir, name)
 	if err != nil {
 		if errors.Is(err, gofs.ErrNotExist) && flag&fs.O_CREATE != 0 {
-			log.Trace("[memfs:fd] creating new file descriptor",
-				log.String("directory", dir.entry.Name()),
-				log.String("name", name),
+			dir.logger().Debug("[memfs:fd] creating new file descriptor",
+				"directory", dir.entry.Name(),
+				"name", name,
 			)
 
-			attrs, err := fs.NewAttributes(fs.WithMode(uint32(mode)))
+			now := time.Now()
+			attrs, err := fs.NewAttributes(fs.WithAtime(now), fs.WithBtime(now), fs.WithMode(uint32(mode)))
 			if err != nil {
 				return nil, err
 			}
@@ -37,8 +56,9 @@ func newfd(dir *MemFS, name string, flag int, mode gofs.FileMode) (*fd, error) {
 				return nil, err
 			}
 
-			fd := &fd{entry: e, dir: dir}
-			if err := dir.entries.AddEntry(&fsEntry{entry: e, data: fd}); err != nil {
+			fd := &fd{entry: e}
+			fd.dir.Store(dir)
+			if err := dir.addEntry(&fsEntry{entry: e, data: fd}); err != nil {
 				return nil, err
 			}
 			return fd, nil
@@ -60,12 +80,303 @@ func newfd(dir *MemFS, name string, flag int, mode gofs.FileMode) (*fd, error) {
 	}
 }
 
-func (d *fd) bytes() []byte {
+// readAt copies the content of the file starting at off into b, and returns the number of bytes copied. The content is
+// copied directly from the file data while the read lock is held, so that concurrent readers do not block each other
+// and never observe a partial write.
+func (d *fd) readAt(b []byte, off int64) int {
 	d.mutex.RLock()
-	defer d.mutex.RLock()
+	defer d.mutex.RUnlock()
 
-	if d.entry.Size() > 0 {
-		return d.data[:d.entry.Size()]
+	size := min(d.entry.Size(), int64(len(d.data)))
+	if off >= size {
+		return 0
 	}
-	return d.data
+	return copy(b, d.data[off:size])
+}
+
+// readAtMulti returns the content of each range of the file in ranges, and whether a range extends beyond the end of
+// the file. The content is returned as slices of the buffer for the content of the file with a capacity limited to
+// their length, unless the buffer is reused or released once it is replaced, as for buffers provided by the buffer
+// pool, in which case the content is returned as copies, so that the slices are not changed once the buffer is reused.
+func (d *fd) readAtMulti(ranges []fs.Range) ([][]byte, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var (
+		eof  bool
+		size = min(d.entry.Size(), int64(len(d.data)))
+	)
+
+	p := d.buffers()
+	recycled := p != nil && p.recycles(d.data)
+
+	bufs := make([][]byte, len(ranges))
+	for i, r := range ranges {
+		start := min(r.Off, size)
+		end := min(r.Off+r.Len, size)
+		if end-start < r.Len {
+			eof = true
+		}
+
+		b := d.data[start:end:end]
+		if recycled {
+			b = slices.Clone(b)
+		}
+		bufs[i] = b
+	}
+	return bufs, eof
+}
+
+// pin returns the content of the file as a slice of the buffer that holds it, and keeps the buffer from being returned
+// to the buffer pool until unpin is called, so that the slice remains valid if the file is changed, truncated, or
+// removed. If the buffer is allocated from an arena, a copy of the content is returned instead and pinned is false,
+// since the memory of the arena is released once the arena is no longer reachable.
+func (d *fd) pin() (b []byte, pinned bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	size := min(d.entry.Size(), int64(len(d.data)))
+	b = d.data[:size:size]
+	if p := d.buffers(); p != nil && p.arena != nil {
+		return slices.Clone(b), false
+	}
+	d.pins++
+	return b, true
+}
+
+// unpin releases a buffer pinned using pin, and returns the buffers that were replaced while the buffer was pinned to
+// the buffer pool once no buffer is pinned.
+func (d *fd) unpin() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.pins--; d.pins > 0 {
+		return
+	}
+
+	if p := d.buffers(); p != nil {
+		for _, b := range d.retired {
+			p.put(b)
+		}
+	}
+	d.retired = nil
+}
+
+// compact replaces the buffer that holds the content of the file with the smallest buffer that holds the content, and
+// returns the number of bytes reclaimed.
+func (d *fd) compact() int64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.shrink()
+}
+
+// content returns a copy of the content of the file, which is allocated with the exact size of the content.
+func (d *fd) content() []byte {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	b := make([]byte, min(d.entry.Size(), int64(len(d.data))))
+	copy(b, d.data)
+	return b
+}
+
+// buffers returns the pool that provides the buffers for the content of the file, or nil if the file does not belong
+// to a MemFS created using New.
+func (d *fd) buffers() *bufferPool {
+	if d.parent().opts == nil {
+		return nil
+	}
+	return &d.parent().opts.buffers
+}
+
+// open records that a File was opened for the file descriptor using flag, and returns the generation of the content of
+// the file. If flag includes fs.O_IFMATCH, the generation is recorded as observed, so that a File that changed the
+// content before it was observed increments the generation again when it next changes it.
+func (d *fd) open(flag int) int64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.refs++
+	gen := d.entry.Attributes().Generation()
+	if flag&fs.O_IFMATCH != 0 {
+		d.observed = gen
+	}
+	return gen
+}
+
+// close records that a File opened for the file descriptor was closed, and releases the content of the file if it was
+// removed and no other File is open.
+func (d *fd) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.refs--; d.refs == 0 && d.removed {
+		d.release()
+	}
+}
+
+// remove records that the file was removed from its directory, and releases the content of the file if no File is
+// open. Otherwise, the content is released once every open File is closed.
+func (d *fd) remove() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.removed = true
+	if d.refs == 0 {
+		d.release()
+	}
+}
+
+// release returns the buffer that holds the content of the file to the buffer pool. The caller must hold the write
+// lock.
+func (d *fd) release() {
+	d.setData(nil)
+}
+
+// shrink replaces the buffer that holds the content of the file with the smallest buffer that holds the content if it
+// is smaller than the current buffer, and returns the number of bytes reclaimed. The caller must hold the write lock.
+func (d *fd) shrink() int64 {
+	size := int(min(d.entry.Size(), int64(len(d.data))))
+	n := len(d.data)
+	if size == 0 {
+		d.setData(nil)
+		return d.reclaimed(n)
+	}
+
+	p := d.buffers()
+	if p == nil {
+		if size == n {
+			return 0
+		}
+
+		b := make([]byte, size)
+		copy(b, d.data)
+		d.setData(b)
+		return d.reclaimed(n - size)
+	}
+
+	if p.capacity(size) >= n {
+		return 0
+	}
+
+	// The pool may provide a buffer that is not smaller than the current buffer if a mapping could not be created, in
+	// which case the buffer is returned to the pool, or no buffer if the arena is exhausted.
+	b := p.get(size)
+	if b == nil {
+		return 0
+	}
+
+	if len(b) >= n {
+		p.put(b)
+		return 0
+	}
+	copy(b, d.data[:size])
+	d.setData(b)
+	return d.reclaimed(n - len(b))
+}
+
+// reclaimed records that n bytes were reclaimed by compacting the buffer of the file, and returns n.
+func (d *fd) reclaimed(n int) int64 {
+	if p := d.buffers(); p != nil {
+		p.reclaimed.Add(uint64(n))
+	}
+	return int64(n)
+}
+
+// setData replaces the buffer that holds the content of the file with b, and returns the previous buffer to the buffer
+// pool, or, if a buffer is pinned using pin, once every buffer is unpinned. The caller must hold the write lock.
+//
+// If b is backed by a memory mapping, it is unmapped once the file descriptor is no longer reachable, so that the
+// mappings of files that are never removed are not leaked when the MemFS is no longer used.
+func (d *fd) setData(b []byte) {
+	d.unmap.Stop()
+	d.unmap = runtime.Cleanup{}
+
+	p := d.buffers()
+	if p != nil {
+		p.account(len(b) - len(d.data))
+		if d.data != nil {
+			if d.pins > 0 {
+				d.retired = append(d.retired, d.data)
+			} else {
+				p.put(d.data)
+			}
+		}
+	}
+	d.data = b
+
+	if p != nil && p.isMapped(b) {
+		d.unmap = runtime.AddCleanup(d, p.put, b)
+	}
+}
+
+// sum returns the SHA-256 digest of the content of the file. The digest is cached with the generation of the file if
+// no File is open, and the cached digest is returned until the generation changes.
+func (d *fd) sum() []byte {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	gen := d.entry.Attributes().Generation()
+	if d.digest != nil && d.digestGen == gen {
+		if d.parent().opts != nil {
+			d.parent().opts.digestHits.Add(1)
+		}
+		return slices.Clone(d.digest)
+	}
+
+	sum := sha256.Sum256(d.data[:min(d.entry.Size(), int64(len(d.data)))])
+	if d.refs == 0 {
+		d.digest, d.digestGen = slices.Clone(sum[:]), gen
+	}
+	return sum[:]
+}
+
+// parent returns the directory that contains the file.
+func (d *fd) parent() *MemFS {
+	return d.dir.Load()
+}
+
+// move records that the file was renamed to name in the directory dir.
+func (d *fd) move(dir *MemFS, name string) error {
+	d.meta.Lock()
+	defer d.meta.Unlock()
+
+	if err := d.entry.SetPath(name); err != nil {
+		return err
+	}
+	d.dir.Store(dir)
+	return nil
+}
+
+// resize sets the size of the file to size, and its modification time to mtime unless it is zero. The attributes are
+// changed while the metadata lock is held, since they are copied by Stat without locking the file descriptor.
+func (d *fd) resize(size int64, mtime time.Time) error {
+	d.meta.Lock()
+	defer d.meta.Unlock()
+
+	if !mtime.IsZero() {
+		if err := d.entry.SetModTime(mtime); err != nil {
+			return err
+		}
+	}
+	d.entry.SetSize(uint64(size))
+	return nil
+}
+
+// touch updates the access time of the file after it was read. If relatime is enabled for the MemFS, the access time
+// is only updated if it is not after the modification time, or if it was last updated more than relatimeInterval ago.
+func (d *fd) touch() {
+	now := time.Now()
+
+	d.meta.Lock()
+	defer d.meta.Unlock()
+
+	attrs := d.entry.Attributes()
+	if d.parent().opts != nil && d.parent().opts.relatime {
+		if atime := attrs.Atime(); atime.After(d.entry.ModTime()) && now.Sub(atime) < relatimeInterval {
+			return
+		}
+	}
+	attrs.SetAtime(now)
 }
@@ -2,10 +2,11 @@ package memfs
 
 import (
 	"bytes"
-	"errors"
-	"fmt"
+	"context"
+	"encoding/hex"
 	"io"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/transientvariable/fs-go"
@@ -16,11 +17,17 @@ import (
 
 const (
 	growthFactor = float32(1.618)
+
+	// maxReadFromHint is the largest number of bytes preallocated by File.ReadFrom for a reader that only provides an
+	// upper bound for its length, such as an io.LimitedReader.
+	maxReadFromHint = 1 << maxChunkShift
 )
 
 var (
-	_ fs.File     = (*File)(nil)
-	_ gohttp.File = (*File)(nil)
+	_ fs.File          = (*File)(nil)
+	_ fs.MmapFile      = (*File)(nil)
+	_ fs.MultiReaderAt = (*File)(nil)
+	_ gohttp.File      = (*File)(nil)
 )
 
 // File provides access to a single file or directory provided by MemFS.
@@ -28,21 +35,35 @@ var (
 // Implements the behavior defined by the fs.File and http.File interfaces.
 type File struct {
 	closed  bool
+	ctx     context.Context
 	dirIter fs.DirIterator
+	dirty   bool
 	fd      *fd
 	flag    int
+	gen     int64
 	mutex   sync.RWMutex
+	name    string
 	rOff    int64
 	wOff    int64
 }
 
 func newFile(fd *fd, flag int) (*File, error) {
+	f := &File{fd: fd, flag: flag}
+	f.gen = fd.open(flag)
+	if fd.pipe != nil {
+		fd.pipe.open(flag)
+		return f, nil
+	}
+
 	db := bytes.NewBuffer(fd.data)
 	if flag&fs.O_TRUNC > 0 {
 		db.Reset()
-		fd.entry.SetSize(0)
+		if err := fd.resize(0, time.Time{}); err != nil {
+			return nil, err
+		}
+		f.modified()
 	}
-	return &File{fd: fd, flag: flag}, nil
+	return f, nil
 }
 
 func (f *File) Close() error {
@@ -55,9 +76,24 @@ func (f *File) Close() error {
 
 	if !f.closed {
 		f.closed = true
-		return nil
+		if f.fd.pipe != nil {
+			f.fd.pipe.close(f.flag)
+		}
+
+		if f.dirty {
+			f.checksum()
+			f.detectMimeType()
+			f.compact()
+		}
+
+		var err error
+		if f.dirty {
+			err = f.log()
+		}
+		f.fd.close()
+		return err
 	}
-	return fmt.Errorf("memfs_file: %w", &gofs.PathError{Op: "close", Err: gofs.ErrClosed})
+	return fs.NewOpError(providerName, "close", f.fd.entry.Path(), gofs.ErrClosed)
 }
 
 func (f *File) Read(b []byte) (int, error) {
@@ -70,36 +106,98 @@ func (f *File) Read(b []byte) (int, error) {
 		return 0, nil
 	}
 
+	// The File is not locked while reading from a named pipe, since the read may block until the pipe is written.
+	if f.fd.pipe != nil {
+		n, err := f.fd.pipe.read(b)
+		if err != nil && err != io.EOF {
+			return n, fs.NewOpError(providerName, "read", fi.Name(), err)
+		}
+		return n, err
+	}
+
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
-	if f.rOff >= fi.Size() {
+	n := f.fd.readAt(b, f.rOff)
+	if n == 0 {
 		return 0, io.EOF
 	}
-	n := copy(b, f.fd.bytes()[f.rOff:])
 	f.rOff += int64(n)
+	f.fd.touch()
 	return n, nil
 }
 
 func (f *File) ReadAt(b []byte, off int64) (int, error) {
-	if _, err := f.checkRead("readAt"); err != nil {
+	fi, err := f.checkSeekable("readAt")
+	if err != nil {
 		return 0, err
 	}
 
+	if off < 0 {
+		return 0, fs.NewOpError(providerName, "readAt", fi.Name(), gofs.ErrInvalid)
+	}
+
 	if len(b) == 0 {
 		return 0, nil
 	}
 
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
-
-	n := copy(b, f.fd.bytes()[off:])
+	n := f.fd.readAt(b, off)
+	f.fd.touch()
 	if n < len(b) {
 		return n, io.EOF
 	}
 	return n, nil
 }
 
+// Mmap returns the content of the file in the same way as fs.MmapFile. The content refers to the buffer that holds the
+// content of the file, so that it is accessed without copying it, unless the buffer is allocated from an arena set
+// using WithArena. The buffer is not reused or unmapped until the returned release function is called, even if the file
+// is changed or removed, so release must be called once the content is no longer used.
+func (f *File) Mmap() ([]byte, func() error, error) {
+	if _, err := f.checkSeekable("mmap"); err != nil {
+		return nil, nil, err
+	}
+
+	b, pinned := f.fd.pin()
+	f.fd.touch()
+	if !pinned {
+		return b, func() error { return nil }, nil
+	}
+
+	var once sync.Once
+	return b, func() error {
+		once.Do(f.fd.unpin)
+		return nil
+	}, nil
+}
+
+// ReadAtMulti returns the content of each range in ranges, in the same way as fs.MultiReaderAt. The returned slices
+// refer to the buffer that holds the content of the file, so that ranges are read without copying them, unless the
+// buffer is provided by the buffer pool of the MemFS, which reuses or releases it once it is replaced, in which case
+// the ranges are copied.
+func (f *File) ReadAtMulti(ranges []fs.Range) ([][]byte, error) {
+	fi, err := f.checkSeekable("readAtMulti")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range ranges {
+		if r.Off < 0 || r.Len < 0 {
+			return nil, fs.NewOpError(providerName, "readAtMulti", fi.Name(), gofs.ErrInvalid)
+		}
+	}
+
+	bufs, eof := f.fd.readAtMulti(ranges)
+	f.fd.touch()
+	if eof {
+		return bufs, io.EOF
+	}
+	return bufs, nil
+}
+
+// ReadFrom writes the content read from r to the File until io.EOF is reached. If the number of bytes to be read from r
+// is known, because r implements fs.SizedReader or is an io.LimitedReader, the space for the content is allocated once
+// before it is copied.
 func (f *File) ReadFrom(r io.Reader) (int64, error) {
 	fi, err := f.checkWrite("readFrom")
 	if err != nil {
@@ -107,20 +205,27 @@ func (f *File) ReadFrom(r io.Reader) (int64, error) {
 	}
 
 	if r == nil {
-		return 0, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "readFrom",
-			Path: fi.Name(),
-			Err:  errors.New("reader is nil"),
-		})
+		return 0, fs.NewOpError(providerName, "readFrom", fi.Name(), gofs.ErrInvalid)
 	}
 
-	n, err := io.Copy(f, r)
+	if n, ok := sizeHint(r); ok && n > 0 && f.fd.pipe == nil {
+		f.fd.mutex.Lock()
+		err := f.grow(int(n))
+		f.fd.mutex.Unlock()
+
+		if err != nil {
+			return 0, fs.NewOpError(providerName, "readFrom", fi.Name(), err)
+		}
+	}
+
+	var w io.Writer = struct{ io.Writer }{f}
+	if f.ctx != nil {
+		w = &contextWriter{ctx: f.ctx, w: w}
+	}
+
+	n, err := io.Copy(w, r)
 	if err != nil {
-		return n, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "readFrom",
-			Path: fi.Name(),
-			Err:  err,
-		})
+		return n, fs.NewOpError(providerName, "readFrom", fi.Name(), err)
 	}
 	return n, nil
 }
@@ -144,7 +249,7 @@ func (f *File) ReadDir(n int) ([]gofs.DirEntry, error) {
 }
 
 func (f *File) Seek(off int64, whence int) (int64, error) {
-	fi, err := f.checkRead("seek")
+	fi, err := f.checkSeekable("seek")
 	if err != nil {
 		return 0, err
 	}
@@ -161,19 +266,11 @@ func (f *File) Seek(off int64, whence int) (int64, error) {
 	case io.SeekEnd:
 		abs = fi.Size() + off
 	default:
-		return 0, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "seek",
-			Path: fi.Name(),
-			Err:  errors.New("invalid whence"),
-		})
+		return 0, fs.NewOpError(providerName, "seek", fi.Name(), gofs.ErrInvalid)
 	}
 
 	if abs < 0 {
-		return 0, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "seek",
-			Path: fi.Name(),
-			Err:  errors.New("negative position"),
-		})
+		return 0, fs.NewOpError(providerName, "seek", fi.Name(), gofs.ErrInvalid)
 	}
 	f.rOff = abs
 	return abs, nil
@@ -184,49 +281,227 @@ func (f *File) Stat() (gofs.FileInfo, error) {
 		return nil, gofs.ErrInvalid
 	}
 
+	f.fd.meta.Lock()
+	defer f.fd.meta.Unlock()
+
 	if f.closed {
-		return nil, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "stat",
-			Path: f.fd.entry.Path(),
-			Err:  gofs.ErrClosed,
-		})
+		return nil, fs.NewOpError(providerName, "stat", f.fd.entry.Path(), gofs.ErrClosed)
 	}
 
 	if f.fd.entry.Name() == "." {
-		return f.fd.dir.entry, nil
+		return f.fd.parent().entry.Copy(), nil
 	}
-	return f.fd.entry, nil
+	return f.fd.entry.Copy(), nil
 }
 
 func (f *File) Sync() error {
 	return nil
 }
 
+// Truncate changes the size of the file. If the file is extended, the added content is zeroed. The write offset is not
+// changed.
+func (f *File) Truncate(size int64) error {
+	fi, err := f.checkWrite("truncate")
+	if err != nil {
+		return err
+	}
+
+	if f.fd.pipe != nil {
+		return fs.NewOpError(providerName, "truncate", fi.Name(), fs.ErrInvalidEntryType)
+	}
+
+	if size < 0 {
+		return fs.NewOpError(providerName, "truncate", fi.Name(), gofs.ErrInvalid)
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	if err := f.checkGeneration("truncate"); err != nil {
+		return err
+	}
+
+	if n := f.fd.entry.Size(); size > n {
+		if err := f.grow(int(size - f.wOff)); err != nil {
+			return fs.NewOpError(providerName, "truncate", fi.Name(), err)
+		}
+		clear(f.fd.data[n:size])
+	}
+	f.modified()
+	return f.fd.resize(size, time.Now())
+}
+
 func (f *File) Write(p []byte) (int, error) {
-	if _, err := f.checkWrite("write"); err != nil {
+	fi, err := f.checkWrite("write")
+	if err != nil {
 		return 0, err
 	}
 
+	// The File is not locked while writing to a named pipe, since the write may block until the pipe is read.
+	if f.fd.pipe != nil {
+		n, err := f.fd.pipe.write(p)
+		if err != nil {
+			return n, fs.NewOpError(providerName, "write", fi.Name(), err)
+		}
+		return n, nil
+	}
+
 	f.fd.mutex.Lock()
 	defer f.fd.mutex.Unlock()
 
-	if err := f.grow(len(p)); err != nil {
+	return f.write(p)
+}
+
+// String returns a string representation of a File.
+func (f *File) String() string {
+	return ""
+}
+
+// checksum updates the checksums of a file that was written using the hash functions set using WithChecksum.
+func (f *File) checksum() {
+	if f.fd.parent().opts == nil || len(f.fd.parent().opts.checksums) == 0 {
+		return
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	f.fd.meta.Lock()
+	defer f.fd.meta.Unlock()
+
+	data := f.fd.data[:f.fd.entry.Size()]
+	for _, hash := range f.fd.parent().opts.checksums {
+		h := hash.New()
+		h.Write(data)
+		f.fd.entry.Attributes().SetChecksum(fs.ChecksumAlgorithm(hash), hex.EncodeToString(h.Sum(nil)))
+	}
+}
+
+// compact compacts the buffer that holds the content of a file that was changed if automatic compaction is enabled
+// using WithAutoCompact, and more than the configured ratio of the buffer is unused.
+func (f *File) compact() {
+	if f.fd.parent().opts == nil || !f.fd.parent().opts.autoCompact {
+		return
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	if n := len(f.fd.data); n > 0 && float64(int64(n)-f.fd.entry.Size()) > f.fd.parent().opts.compactRatio*float64(n) {
+		f.fd.shrink()
+	}
+}
+
+// detectMimeType updates the MIME type of a file that was written if MIME type detection is enabled using
+// WithMimeDetection.
+func (f *File) detectMimeType() {
+	if f.fd.parent().opts == nil || !f.fd.parent().opts.mimeDetection {
+		return
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	f.fd.meta.Lock()
+	defer f.fd.meta.Unlock()
+
+	mt := fs.DetectMimeType(f.fd.entry.Name(), f.fd.data[:f.fd.entry.Size()])
+	if err := f.fd.entry.Attributes().SetMimeType(mt); err != nil {
+		f.fd.parent().logger().Error("[memfs:file] detectMimeType", "error", err)
+	}
+}
+
+// log records the content of the file in the write-ahead log of the MemFS the File was opened from, if it was created
+// using WithWAL. The content is recorded for the current path of the file, which differs from the path the File was
+// opened with if the file was renamed, and is not recorded if the file was removed.
+func (f *File) log() error {
+	w := f.fd.parent().wal()
+	if w == nil || f.name == "" {
+		return nil
+	}
+
+	w.root.mutex.RLock()
+	defer w.root.mutex.RUnlock()
+
+	name, ok := w.root.pathOf(f.name, f.fd)
+	if !ok {
+		return nil
+	}
+
+	return w.root.log(walRecord{
+		op:    walWrite,
+		name:  name,
+		mode:  f.fd.entry.Mode(),
+		mtime: f.fd.entry.ModTime(),
+		data:  f.fd.content(),
+	})
+}
+
+// modified records that the content of the file was changed using the File. The generation of the content is
+// incremented once for each File that changes it, so that a file written using a sequence of writes has a single new
+// generation. The generation is incremented again if another File changed the content since, or if the generation was
+// observed by a File opened using fs.O_IFMATCH, so that the change is detected by that File.
+func (f *File) modified() {
+	f.fd.meta.Lock()
+	defer f.fd.meta.Unlock()
+
+	attrs := f.fd.entry.Attributes()
+	if !f.dirty || attrs.Generation() != f.gen || f.fd.observed == f.gen {
+		f.dirty = true
+		attrs.SetGeneration(attrs.Generation() + 1)
+	}
+	f.gen = attrs.Generation()
+}
+
+// write writes p at the write offset of the File, or at the end of the file if it was opened using fs.O_APPEND. The
+// caller must hold the write lock for the file descriptor.
+func (f *File) write(p []byte) (int, error) {
+	if f.fd.pipe != nil {
+		return 0, fs.NewOpError(providerName, "write", f.fd.entry.Name(), fs.ErrInvalidEntryType)
+	}
+
+	if err := f.checkGeneration("write"); err != nil {
 		return 0, err
 	}
 
+	if f.flag&fs.O_APPEND != 0 {
+		f.wOff = f.fd.entry.Size()
+	}
+
+	if err := f.grow(len(p)); err != nil {
+		return 0, fs.NewOpError(providerName, "write", f.fd.entry.Name(), err)
+	}
+
+	// Truncating the file does not clear the content beyond its size, and pooled buffers are not zeroed, so any gap
+	// between the end of the content and the write offset is cleared, even if the buffer was not replaced.
+	if size := min(f.fd.entry.Size(), int64(len(f.fd.data))); f.wOff > size {
+		clear(f.fd.data[size:f.wOff])
+	}
+
 	n := copy(f.fd.data[f.wOff:], p)
 	f.wOff += int64(n)
+	f.modified()
 
-	if err := f.fd.entry.SetModTime(time.Now()); err != nil {
+	if err := f.fd.resize(f.wOff, time.Now()); err != nil {
 		return n, err
 	}
-	f.fd.entry.SetSize(uint64(f.wOff))
 	return n, nil
 }
 
-// String returns a string representation of a File.
-func (f *File) String() string {
-	return ""
+// checkGeneration returns an error wrapping fs.ErrConflict if the File was opened using fs.O_IFMATCH, and the content
+// of the file was changed by another File since it was opened or last changed using the File. The caller must hold the
+// write lock for the file descriptor.
+func (f *File) checkGeneration(op string) error {
+	if f.flag&fs.O_IFMATCH != 0 && f.fd.entry.Attributes().Generation() != f.gen {
+		return fs.NewOpError(providerName, op, f.fd.entry.Name(), fs.ErrConflict)
+	}
+	return nil
+}
+
+// openFlag returns the flag the File was opened with, without the flags defined by the fs package that do not affect
+// the access mode of the File.
+func (f *File) openFlag() int {
+	return f.flag &^ (fs.O_IFMATCH | fs.O_DIRECT)
 }
 
 func (f *File) checkRegularFile(op string) (gofs.FileInfo, error) {
@@ -236,11 +511,7 @@ func (f *File) checkRegularFile(op string) (gofs.FileInfo, error) {
 	}
 
 	if fi.IsDir() {
-		return fi, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   op,
-			Path: fi.Name(),
-			Err:  errors.New("is a directory"),
-		})
+		return fi, fs.NewOpError(providerName, op, fi.Name(), fs.ErrIsDir)
 	}
 	return fi, nil
 }
@@ -251,46 +522,102 @@ func (f *File) checkRead(op string) (gofs.FileInfo, error) {
 		return fi, err
 	}
 
-	if f.flag == fs.O_WRONLY {
-		return fi, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   op,
-			Path: fi.Name(),
-			Err:  errors.New("file is write-only"),
-		})
+	if f.openFlag() == fs.O_WRONLY {
+		return fi, fs.NewOpError(providerName, op, fi.Name(), gofs.ErrPermission)
 	}
 	return fi, nil
 }
 
+// checkSeekable returns an error wrapping syscall.ESPIPE if the File is a named pipe, whose content can only be read in
+// the order in which it was written, and otherwise returns the same result as checkRead.
+func (f *File) checkSeekable(op string) (gofs.FileInfo, error) {
+	fi, err := f.checkRegularFile(op)
+	if err != nil {
+		return fi, err
+	}
+
+	if f.fd.pipe != nil {
+		return fi, fs.NewOpError(providerName, op, fi.Name(), syscall.ESPIPE)
+	}
+	return f.checkRead(op)
+}
+
 func (f *File) checkWrite(op string) (gofs.FileInfo, error) {
 	fi, err := f.checkRegularFile(op)
 	if err != nil {
 		return fi, err
 	}
 
-	if f.flag == fs.O_RDONLY {
-		return fi, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   op,
-			Path: fi.Name(),
-			Err:  errors.New("file is read-only"),
-		})
+	if f.openFlag() == fs.O_RDONLY {
+		return fi, fs.NewOpError(providerName, op, fi.Name(), gofs.ErrPermission)
 	}
 	return fi, nil
 }
 
+// grow ensures that the buffer for the content of the file can hold n bytes written at the write offset, replacing it
+// with a larger buffer from the buffer pool if required. The caller must hold the write lock for the file descriptor.
 func (f *File) grow(n int) error {
-	currentCap := cap(f.fd.data)
-	if len(f.fd.data)+n >= currentCap {
-		c := int(growthFactor * float32(currentCap+n))
-		if c > fs.MaxContentLen-c-n {
-			return fs.ErrTooLarge
+	need := f.wOff + int64(n)
+	if need <= int64(len(f.fd.data)) {
+		return nil
+	}
+
+	if need > int64(fs.MaxContentLen) {
+		return fs.ErrTooLarge
+	}
+
+	c := max(int(need), int(growthFactor*float32(len(f.fd.data))))
+
+	var b []byte
+	if p := f.fd.buffers(); p != nil {
+		// If growing the buffer by the growth factor would exceed the quota set using WithQuota, the buffer is only grown
+		// to the required size.
+		r := p.capacity(c) - len(f.fd.data)
+		if !p.reserve(r) {
+			c = int(need)
+			if r = p.capacity(c) - len(f.fd.data); !p.reserve(r) {
+				return fs.ErrQuotaExceeded
+			}
+		}
+		defer p.unreserve(r)
+
+		// An arena may not have a free block for the grown buffer, but may have one for the required size.
+		if b = p.get(c); b == nil && c > int(need) {
+			b = p.get(int(need))
+		}
+
+		if b == nil {
+			return fs.ErrQuotaExceeded
 		}
-		n := make([]byte, c)
-		copy(n, f.fd.data)
-		f.fd.data = n
+	} else {
+		b = make([]byte, c)
+	}
+
+	// Pooled buffers are not zeroed, so only the content of the file is copied, and any gap between the end of the
+	// content and the write offset is cleared.
+	size := min(f.fd.entry.Size(), int64(len(f.fd.data)))
+	copy(b, f.fd.data[:size])
+	if f.wOff > size {
+		clear(b[size:f.wOff])
 	}
+	f.fd.setData(b)
 	return nil
 }
 
+// sizeHint returns the number of bytes remaining to be read from r, if it is known.
+func sizeHint(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case fs.SizedReader:
+		return int64(r.Len()), true
+	case *io.LimitedReader:
+		if n, ok := sizeHint(r.R); ok {
+			return min(n, r.N), true
+		}
+		return r.N, r.N <= maxReadFromHint
+	}
+	return 0, false
+}
+
 func (f *File) readDir(n int) ([]*fs.Entry, error) {
 	fi, err := f.Stat()
 	if err != nil {
@@ -301,15 +628,11 @@ func (f *File) readDir(n int) ([]*fs.Entry, error) {
 	defer f.mutex.Unlock()
 
 	if !fi.IsDir() {
-		return nil, fmt.Errorf("memfs_file: %w", &gofs.PathError{
-			Op:   "readDir",
-			Path: fi.Name(),
-			Err:  fs.ErrNotDir,
-		})
+		return nil, fs.NewOpError(providerName, "readDir", fi.Name(), fs.ErrNotDir)
 	}
 
 	if f.dirIter == nil {
-		f.dirIter = newDirIterator(f.fd.dir)
+		f.dirIter = newDirIterator(f.fd.parent())
 	}
 	return f.dirIter.NextN(n)
 }
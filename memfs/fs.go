@@ -1,44 +1,226 @@
 package memfs
 
 import (
+	"context"
+	"crypto"
 	"errors"
-	"fmt"
 	"io"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/transientvariable/anchor"
 	"github.com/transientvariable/fs-go"
 	"github.com/transientvariable/hold"
 	"github.com/transientvariable/hold/trie"
-	"github.com/transientvariable/log-go"
 
 	gofs "io/fs"
+	gopath "path"
 )
 
 const (
 	pathSeparator = string(os.PathSeparator)
 	modePerm      = 0664
+	providerName  = "memfs"
+
+	// globMeta are the characters that make an element of a pattern match more than a single name.
+	globMeta = `*?[\`
+
+	// relatimeInterval is the interval after which the access time of a file is updated on read if relatime is
+	// enabled, regardless of its modification time.
+	relatimeInterval = 24 * time.Hour
 )
 
-var _ fs.FS = (*MemFS)(nil)
+var (
+	_ fs.BatchWriteFS        = (*MemFS)(nil)
+	_ fs.CapabilityFS        = (*MemFS)(nil)
+	_ fs.ChmodFS             = (*MemFS)(nil)
+	_ fs.ChtimesFS           = (*MemFS)(nil)
+	_ fs.ConditionalWriteFS  = (*MemFS)(nil)
+	_ fs.ContextFS           = (*MemFS)(nil)
+	_ fs.DigestFS            = (*MemFS)(nil)
+	_ fs.DirIteratorFS       = (*MemFS)(nil)
+	_ fs.FS                  = (*MemFS)(nil)
+	_ fs.MetadataFS          = (*MemFS)(nil)
+	_ fs.MimeTypeFS          = (*MemFS)(nil)
+	_ fs.PathValidatorFS     = (*MemFS)(nil)
+	_ fs.PrefixReadDirPager  = (*MemFS)(nil)
+	_ fs.ReadDirPager        = (*MemFS)(nil)
+	_ fs.SortedDirIteratorFS = (*MemFS)(nil)
+	_ fs.StatFSer            = (*MemFS)(nil)
+)
+
+// Register the provider, so that an empty MemFS can be selected using the "mem" scheme (e.g. as the default file system
+// using fs.EnvDefaultProvider).
+func init() {
+	fs.Register("mem", func(_ *url.URL) (fs.FS, error) {
+		return New()
+	})
+}
 
 // MemFS in-memory file system provider that implements fs.FS.
 //
-// Unless otherwise specified, all operations are transient and will be lost when the runtime exits.
+// Unless otherwise specified, all operations are transient and will be lost when the runtime exits. Operations that
+// only look up entries, such as Stat, Open, and ReadDir, may run concurrently with each other, while operations that
+// change the structure of the tree, such as Mkdir, Remove, and Rename, run exclusively.
 type MemFS struct {
 	closed  bool
 	entry   *fs.Entry
 	entries trie.Trie
-	mutex   sync.Mutex
+	index   dirIndex
+	mutex   sync.RWMutex
+	opts    *options
+}
+
+// options holds the configuration of a MemFS, which is shared by all of its directories.
+type options struct {
+	arena         int
+	autoCompact   bool
+	buffers       bufferPool
+	cache         lookupCache
+	checksums     []crypto.Hash
+	compactRatio  float64
+	digestHits    atomic.Uint64
+	logger        fs.Logger
+	mimeDetection bool
+	names         fs.NameRules
+	relatime      bool
+	wal           *wal
+}
+
+// Stats holds statistics for a MemFS, which are shared by all of its directories.
+type Stats struct {
+	// ArenaBytes is the number of bytes of the arena that are allocated to the buffers that hold file content (see
+	// WithArena).
+	ArenaBytes uint64
+
+	// BufferGets is the number of buffers for file content that were requested from the buffer pool.
+	BufferGets uint64
+
+	// BufferHits is the number of buffers requested from the buffer pool that were reused instead of allocated.
+	BufferHits uint64
+
+	// BufferPuts is the number of buffers that were returned to the buffer pool when files were grown or removed.
+	BufferPuts uint64
+
+	// CompactedBytes is the number of bytes of unused buffer capacity that were reclaimed by compacting the buffers of
+	// files (see Compact and WithAutoCompact).
+	CompactedBytes uint64
+
+	// DigestHits is the number of digests of file content requested using Digest that were cached instead of computed.
+	DigestHits uint64
+
+	// MappedBytes is the number of bytes of file content that are currently backed by memory mappings instead of the
+	// Go heap (see WithMmapThreshold).
+	MappedBytes uint64
+
+	// LookupHits is the number of lookups of nested paths that were resolved by the lookup cache.
+	LookupHits uint64
+
+	// LookupMisses is the number of lookups of nested paths that were resolved by descending the tree.
+	LookupMisses uint64
 }
 
 // New creates a new MemFS.
-func New() (*MemFS, error) {
-	return newDir(pathSeparator, modePerm, fs.WithPathValidator(func(p string) bool { return true }))
+func New(opts ...func(*MemFS)) (*MemFS, error) {
+	mfs, err := newDir(pathSeparator, modePerm, fs.WithPathValidator(func(p string) bool { return true }))
+	if err != nil {
+		return nil, err
+	}
+
+	mfs.opts = &options{logger: fs.NopLogger()}
+	for _, opt := range opts {
+		opt(mfs)
+	}
+	mfs.opts.buffers.logger = mfs.opts.logger
+
+	if mfs.opts.arena > 0 {
+		a := newArena(mfs.opts.arena)
+		if q := mfs.opts.buffers.quota; q <= 0 || q > a.size() {
+			mfs.opts.buffers.quota = a.size()
+		}
+		mfs.opts.buffers.arena = a
+	}
+
+	if w := mfs.opts.wal; w != nil {
+		if err := w.open(); err != nil {
+			return nil, fs.NewOpError(providerName, "wal", w.name, err)
+		}
+	}
+	return mfs, nil
+}
+
+// Capabilities returns the capabilities of MemFS that are not detected from the methods it implements.
+func (m *MemFS) Capabilities() fs.Capability {
+	return fs.CapAtomicRename
+}
+
+// Stats returns the statistics for the MemFS.
+func (m *MemFS) Stats() Stats {
+	if m.opts == nil {
+		return Stats{}
+	}
+
+	var arenaBytes uint64
+	if a := m.opts.buffers.arena; a != nil {
+		arenaBytes = uint64(a.usage())
+	}
+
+	return Stats{
+		ArenaBytes:     arenaBytes,
+		BufferGets:     m.opts.buffers.gets.Load(),
+		BufferHits:     m.opts.buffers.hits.Load(),
+		BufferPuts:     m.opts.buffers.puts.Load(),
+		CompactedBytes: m.opts.buffers.reclaimed.Load(),
+		DigestHits:     m.opts.digestHits.Load(),
+		LookupHits:     m.opts.cache.hits.Load(),
+		LookupMisses:   m.opts.cache.misses.Load(),
+		MappedBytes:    uint64(m.opts.buffers.mappedBytes.Load()),
+	}
+}
+
+// Chmod changes the permission bits and the setuid, setgid and sticky bits of the named file to those of mode. The
+// type bits of mode are ignored.
+func (m *MemFS) Chmod(name string, mode gofs.FileMode) error {
+	f, err := m.entryOf("chmod", name)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	defer f.lockMeta()()
+
+	e := f.entry
+
+	if err := e.Attributes().SetMode(e.Mode().Type() | mode&^gofs.ModeType); err != nil {
+		return fs.NewOpError(providerName, "chmod", name, err)
+	}
+	return m.log(walRecord{op: walChmod, name: name, mode: mode})
+}
+
+// Chtimes changes the access and modification times of the named file. Unlike fs.Entry.SetModTime, the modification
+// time can be set to a time before the current modification time.
+func (m *MemFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	f, err := m.entryOf("chtimes", name)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	defer f.lockMeta()()
+
+	e := f.entry
+
+	e.Attributes().SetAtime(atime)
+	fs.WithMtime(mtime)(e.Attributes())
+	return m.log(walRecord{op: walChtimes, name: name, atime: atime, mtime: mtime})
 }
 
 // Close ...
@@ -56,58 +238,70 @@ func (m *MemFS) Close() error {
 
 	if !m.closed {
 		m.closed = true
+		if w := m.wal(); w != nil {
+			if err := w.close(); err != nil {
+				return fs.NewOpError(providerName, "close", w.name, err)
+			}
+		}
 		return nil
 	}
-	return fmt.Errorf("memfs: %w", gofs.ErrClosed)
+	return fs.NewOpError(providerName, "close", "", gofs.ErrClosed)
+}
+
+// Compact shrinks the buffer that holds the content of each file in the MemFS to the smallest buffer that holds the
+// content, and returns the number of bytes reclaimed.
+//
+// Buffers are over-allocated as files grow, and are not shrunk when files are truncated, so files that were written and
+// truncated repeatedly can retain much more memory than their content requires. Compaction can be performed
+// automatically when files are closed using WithAutoCompact.
+func (m *MemFS) Compact() int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return compact(m)
 }
 
 // Create ...
 func (m *MemFS) Create(name string) (fs.File, error) {
-	log.Debug("[memfs] create", log.String("name", name))
 	return m.open("create", name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, modePerm)
 }
 
 // Glob ...
-func (m *MemFS) Glob(pattern string) ([]string, error) {
-	log.Debug("[memfs] glob", log.String("pattern", pattern))
-
-	var matches []string
-	err := gofs.WalkDir(m, ".", func(path string, entry gofs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		matched, err := filepath.Match(pattern, path)
-		if err != nil {
-			return err
-		}
+// Digest returns the SHA-256 digest of the content of the named file. The digest is cached until the file is modified,
+// so that fs.MerkleTree does not read the content of files that have not changed. Digests are not cached while the file
+// is open, since its content may be changed without changing its generation.
+func (m *MemFS) Digest(name string) ([]byte, error) {
+	name, err := fs.CleanPath(m, name)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, "digest", name, err)
+	}
 
-		if matched {
-			matches = append(matches, path)
-		}
-		return nil
-	})
+	e, err := m.lookup(name)
 	if err != nil {
-		if !errors.Is(err, &gofs.PathError{}) {
-			return matches, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "glob", Err: err})
-		}
-		return matches, err
+		return nil, fs.NewOpError(providerName, "digest", name, err)
+	}
+
+	fd, ok := e.Data().(*fd)
+	if !ok {
+		return nil, fs.NewOpError(providerName, "digest", name, fs.ErrIsDir)
 	}
-	return matches, nil
+	return fd.sum(), nil
+}
+
+func (m *MemFS) Glob(pattern string) ([]string, error) {
+	return m.glob(context.Background(), pattern)
 }
 
 // Mkdir ...
 func (m *MemFS) Mkdir(name string, perm gofs.FileMode) error {
-	log.Debug("[memfs] mkdir", log.String("name", name))
-
 	name, err := fs.CleanPath(m, name)
 	if err != nil {
-		return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: err})
+		return fs.NewOpError(providerName, "mkdir", name, err)
 	}
 
 	if _, err := m.Stat(name); err != nil {
 		if !errors.Is(err, gofs.ErrNotExist) {
-			return fmt.Errorf("memfs: %w", err)
+			return fs.NewOpError(providerName, "mkdir", name, err)
 		}
 	}
 
@@ -115,38 +309,80 @@ func (m *MemFS) Mkdir(name string, perm gofs.FileMode) error {
 	defer m.mutex.Unlock()
 
 	if _, err := mkdir(m, name, perm); err != nil {
-		return fmt.Errorf("memfs: %w", err)
+		return fs.NewOpError(providerName, "mkdir", name, err)
 	}
-	return nil
+	return m.log(walRecord{op: walMkdir, name: name, mode: perm})
 }
 
 // MkdirAll ...
 func (m *MemFS) MkdirAll(path string, mode gofs.FileMode) error {
-	log.Debug("[memfs] mkdirAll", log.String("path", path), log.String("mode", mode.String()))
-
 	path, err := fs.CleanPath(m, path)
 	if err != nil {
-		return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "mkdirAll", Path: path, Err: err})
+		return fs.NewOpError(providerName, "mkdirAll", path, err)
 	}
 
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	if _, err := mkdirAll(m, path, mode); err != nil {
-		return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "mkdirAll", Path: path, Err: err})
+		return fs.NewOpError(providerName, "mkdirAll", path, err)
 	}
-	return nil
+	return m.log(walRecord{op: walMkdir, name: path, mode: mode})
+}
+
+// Mkfifo creates a named pipe with the specified name and permission bits, whose entry has the mode
+// gofs.ModeNamedPipe. The parent directory must exist.
+//
+// Opening the pipe returns a File that transfers the bytes written to it to its readers, so that software that
+// communicates using named pipes can be tested in memory. As with named pipes on POSIX systems, opening the pipe for
+// reading blocks until it is opened for writing, and opening it for writing blocks until it is opened for reading,
+// unless it is opened using fs.O_RDWR. A read blocks until content is written, and returns io.EOF once every writer is
+// closed, while a write blocks while the pipe is full, and fails with an error wrapping syscall.EPIPE once every reader
+// is closed. Seeking or reading at an offset fails with an error wrapping syscall.ESPIPE.
+func (m *MemFS) Mkfifo(name string, perm gofs.FileMode) error {
+	name, err := fs.CleanPath(m, name)
+	if err != nil {
+		return fs.NewOpError(providerName, "mkfifo", name, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, err := stat(m, name); err == nil {
+		return fs.NewOpError(providerName, "mkfifo", name, gofs.ErrExist)
+	} else if !errors.Is(err, gofs.ErrNotExist) {
+		return fs.NewOpError(providerName, "mkfifo", name, err)
+	}
+
+	dir := m
+	if d := fs.Dir(m, name); d != "." {
+		e, err := stat(m, d)
+		if err != nil {
+			return fs.NewOpError(providerName, "mkfifo", name, err)
+		}
+
+		mfs, ok := e.Data().(*MemFS)
+		if !ok {
+			return fs.NewOpError(providerName, "mkfifo", name, fs.ErrNotDir)
+		}
+		dir = mfs
+	}
+
+	fd, err := newfd(dir, fs.Base(m, name), fs.O_CREATE, gofs.ModeNamedPipe|perm.Perm())
+	if err != nil {
+		return fs.NewOpError(providerName, "mkfifo", name, err)
+	}
+	fd.pipe = newPipe()
+	return m.log(walRecord{op: walMkfifo, name: name, mode: perm})
 }
 
 // Open opens the named File.
 func (m *MemFS) Open(name string) (gofs.File, error) {
-	log.Debug("[memfs] open", log.String("name", name))
 	return m.open("open", name, fs.O_RDONLY, 0)
 }
 
 // OpenFile ...
 func (m *MemFS) OpenFile(name string, flag int, mode gofs.FileMode) (fs.File, error) {
-	log.Debug("[memfs] openFile", log.String("name", name), log.Int("flag", flag), log.String("mode", mode.String()))
 	return m.open("openFile", name, flag, mode)
 }
 
@@ -157,12 +393,34 @@ func (m *MemFS) PathSeparator() string {
 
 // Provider ...
 func (m *MemFS) Provider() string {
-	return "memfs"
+	return providerName
 }
 
 // ReadDir ...
 func (m *MemFS) ReadDir(name string) ([]gofs.DirEntry, error) {
-	log.Debug("[memfs] readDir", log.String("name", name))
+	return m.readDir(context.Background(), name)
+}
+
+// IterateDir returns a fs.DirIterator over the entries of the named directory, which retrieves each entry from the
+// directory as it is iterated. The entries are returned sorted by filename.
+func (m *MemFS) IterateDir(name string) (fs.DirIterator, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sub, err := sub(m, name)
+	if err != nil {
+		return nil, err
+	}
+	return newLockedDirIterator(sub.(*MemFS), &m.mutex), nil
+}
+
+// IterateDirSorted returns a fs.DirIterator over the entries of the named directory sorted by key. Since the entries
+// of a directory are kept sorted by filename, entries sorted by filename in ascending order are retrieved from the
+// directory as they are iterated, in the same way as IterateDir, and are otherwise iterated from a sorted snapshot of
+// the directory.
+func (m *MemFS) IterateDirSorted(name string, key fs.SortKey, desc bool) (fs.DirIterator, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
 	sub, err := sub(m, name)
 	if err != nil {
@@ -170,55 +428,134 @@ func (m *MemFS) ReadDir(name string) ([]gofs.DirEntry, error) {
 	}
 
 	mfs := sub.(*MemFS)
-	de, err := newDirIterator(mfs).NextN(-1)
+	if key == fs.SortByName && !desc {
+		return newLockedDirIterator(mfs, &m.mutex), nil
+	}
+
+	entries, err := newDirIterator(mfs).NextN(-1)
 	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "readDir", Path: mfs.entry.Path(), Err: err})
+		return nil, fs.NewOpError(providerName, "iterateDirSorted", mfs.entry.Path(), err)
 	}
 
-	entries := make([]gofs.DirEntry, len(de))
-	for i, e := range de {
-		entries[i] = e
+	options := []fs.SortOption{fs.By(key)}
+	if desc {
+		options = append(options, fs.Desc())
 	}
-	return entries, nil
+	fs.SortEntries(entries, options...)
+	return &sortedDirIterator{entries: entries}, nil
+}
+
+// ReadDirPage returns at most pageSize entries of the named directory sorted by filename, starting after the entries
+// returned by the call that returned token. The token is the name of the last entry of the previous page, so listing
+// continues in order if entries are added or removed between calls.
+func (m *MemFS) ReadDirPage(name string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	return m.readDirPage(context.Background(), name, pageSize, token)
+}
+
+// ReadDirPrefix returns at most pageSize entries of the named directory whose names start with prefix, in the same way
+// as ReadDirPage. The first entry is found using the index of the names of the directory, so that listing a range of a
+// directory with a large number of entries does not read the entries before it.
+func (m *MemFS) ReadDirPrefix(name string, prefix string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	return m.readDirRange(context.Background(), "readDirPrefix", name, prefix, pageSize, token)
 }
 
 // ReadFile ...
 func (m *MemFS) ReadFile(name string) ([]byte, error) {
-	log.Debug("[memfs] readFile", log.String("name", name))
-
 	f, err := m.Open(name)
 	if err != nil {
 		return nil, err
 	}
 	defer func(f gofs.File) {
 		if err := f.Close(); err != nil {
-			log.Error("[memfs] readFile", log.Err(err))
+			m.logger().Error("[memfs] readFile", "error", err)
 		}
 	}(f)
 
-	b, err := io.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: err})
+	file, ok := f.(*File)
+	if !ok || file.fd.pipe != nil {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fs.NewOpError(providerName, "readFile", name, err)
+		}
+		return b, nil
 	}
+
+	if _, err := file.checkRead("readFile"); err != nil {
+		return nil, err
+	}
+
+	b := file.fd.content()
+	file.fd.touch()
 	return b, nil
 }
 
-// Remove ...
+// Remove removes the named file or empty directory.
 func (m *MemFS) Remove(name string) error {
-	log.Debug("[memfs] remove", log.String("name", name))
-	return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "remove", Path: name, Err: errors.New("not implemented")})
+	name, err := fs.CleanPath(m, name)
+	if err != nil {
+		return fs.NewOpError(providerName, "remove", name, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := remove(m, name, false); err != nil {
+		return fs.NewOpError(providerName, "remove", name, err)
+	}
+	return m.log(walRecord{op: walRemove, name: name})
 }
 
-// RemoveAll ...
+// RemoveAll removes path and any children it contains. A nil error is returned if the path does not exist.
 func (m *MemFS) RemoveAll(path string) error {
-	log.Debug("[memfs] removeAll", log.String("path", path))
-	return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "removeAll", Path: path, Err: errors.New("not implemented")})
+	path, err := fs.CleanPath(m, path)
+	if err != nil {
+		return fs.NewOpError(providerName, "removeAll", path, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if path == "." {
+		for _, v := range m.entries.Values() {
+			if v == "." {
+				continue
+			}
+
+			if err := remove(m, v, true); err != nil {
+				return fs.NewOpError(providerName, "removeAll", v, err)
+			}
+		}
+		return m.log(walRecord{op: walRemove, name: path})
+	}
+
+	if err := remove(m, path, true); err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return nil
+		}
+		return fs.NewOpError(providerName, "removeAll", path, err)
+	}
+	return m.log(walRecord{op: walRemove, name: path})
 }
 
-// Rename ...
+// Rename renames (moves) oldpath to newpath. If newpath already exists and is not a directory, Rename replaces it.
 func (m *MemFS) Rename(oldpath string, newpath string) error {
-	log.Debug("[memfs] rename", log.String("old_path", oldpath), log.String("new_path", newpath))
-	return fmt.Errorf("memfs: %w", &gofs.PathError{Op: "rename", Err: errors.New("not implemented")})
+	oldpath, err := fs.CleanPath(m, oldpath)
+	if err != nil {
+		return fs.NewLinkOpError(providerName, "rename", oldpath, newpath, err)
+	}
+
+	newpath, err = fs.CleanPath(m, newpath)
+	if err != nil {
+		return fs.NewLinkOpError(providerName, "rename", oldpath, newpath, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := rename(m, oldpath, newpath); err != nil {
+		return fs.NewLinkOpError(providerName, "rename", oldpath, newpath, err)
+	}
+	return m.log(walRecord{op: walRename, name: oldpath, newName: newpath})
 }
 
 // Root ...
@@ -226,124 +563,552 @@ func (m *MemFS) Root() (string, error) {
 	return pathSeparator, nil
 }
 
+// SetMetadata replaces the user-defined metadata of the named file or directory with metadata, which is provided by the
+// fs.Attribute of the fs.Entry returned by Stat.
+func (m *MemFS) SetMetadata(name string, metadata map[string]string) error {
+	f, err := m.entryOf("setMetadata", name)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	defer f.lockMeta()()
+
+	e := f.entry
+
+	fs.WithMetadata(metadata)(e.Attributes())
+	return m.log(walRecord{op: walMetadata, name: name, metadata: metadata})
+}
+
+// SetMimeType sets the MIME type of the named file, which is provided by the fs.Attribute of the fs.Entry returned by
+// Stat.
+func (m *MemFS) SetMimeType(name string, mimeType string) error {
+	f, err := m.entryOf("setMimeType", name)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	defer f.lockMeta()()
+
+	e := f.entry
+
+	if err := e.Attributes().SetMimeType(mimeType); err != nil {
+		return fs.NewOpError(providerName, "setMimeType", name, err)
+	}
+	return m.log(walRecord{op: walMimeType, name: name, mimeType: mimeType})
+}
+
 // Stat ...
 func (m *MemFS) Stat(name string) (gofs.FileInfo, error) {
-	log.Debug("[memfs] stat", log.String("name", name))
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
 	e, err := stat(m, name)
 	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "stat", Path: name, Err: err})
+		return nil, fs.NewOpError(providerName, "stat", name, err)
 	}
 	return e.Stat()
 }
 
-// Sub ...
-func (m *MemFS) Sub(dir string) (gofs.FS, error) {
-	log.Debug("[memfs] sub", log.String("current", m.entry.Name()), log.String("dir", dir))
+// Sub ...
+func (m *MemFS) Sub(dir string) (gofs.FS, error) {
+	if m.wal() != nil {
+		return fs.Chroot(m, dir)
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sub, err := sub(m, dir)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, "sub", dir, err)
+	}
+	return sub, nil
+}
+
+// Usage returns the quota set using WithQuota as the total number of bytes, the memory used by the buffers that hold
+// the content of files, the number of bytes remaining before the quota is exceeded, and the number of files and
+// directories in the MemFS. If a quota is not set, the total is math.MaxUint64.
+func (m *MemFS) Usage() (uint64, uint64, uint64, uint64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	files := count(m)
+	if m.opts == nil {
+		return math.MaxUint64, 0, math.MaxUint64, files, nil
+	}
+
+	total := uint64(math.MaxUint64)
+	if m.opts.buffers.quota > 0 {
+		total = uint64(m.opts.buffers.quota)
+	}
+
+	used := uint64(max(m.opts.buffers.usage(), 0))
+	return total, used, total - min(used, total), files, nil
+}
+
+// ValidatePath returns a *fs.NameError if the named path violates the rules set using WithNameRules. By default, every
+// path accepted by gofs.ValidPath is valid.
+func (m *MemFS) ValidatePath(name string) error {
+	return m.opts.names.Validate(name, pathSeparator)
+}
+
+// WithContext returns a view of the MemFS whose operations that take time proportional to the size of the tree or of
+// the content, which are Glob, ReadDir, ReadDirPage, and ReadFrom for files opened using the view, check ctx between
+// chunks of entries or content, and return an error wrapping the cause of ctx once ctx is done. Other operations are
+// performed as by the MemFS.
+func (m *MemFS) WithContext(ctx context.Context) fs.FS {
+	return &contextFS{MemFS: m, ctx: ctx}
+}
+
+// WriteFile ...
+func (m *MemFS) WriteFile(name string, data []byte, mode gofs.FileMode) error {
+	f, err := m.open("writeFile", name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer func(f *File) {
+		if err := f.Close(); err != nil {
+			m.logger().Error("[memfs] writeFile", "error", err)
+		}
+	}(f)
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteFileIf writes data to the named file in the same way as WriteFile, but only if the generation of the content of
+// the file is ifGeneration, or if ifGeneration is 0 and the file does not exist. The generation of a file is
+// incremented each time it is written, and is provided by the fs.Attribute of the fs.Entry returned by Stat.
+func (m *MemFS) WriteFileIf(name string, data []byte, mode gofs.FileMode, ifGeneration int64) error {
+	if _, err := m.entryOf("writeFileIf", name); err != nil {
+		if !errors.Is(err, gofs.ErrNotExist) {
+			return err
+		}
+
+		if ifGeneration != 0 {
+			return fs.NewOpError(providerName, "writeFileIf", name, fs.ErrConflict)
+		}
+	} else if ifGeneration == 0 {
+		return fs.NewOpError(providerName, "writeFileIf", name, fs.ErrConflict)
+	}
+
+	f, err := m.open("writeFileIf", name, fs.O_RDWR|fs.O_CREATE, mode)
+	if err != nil {
+		return err
+	}
+	defer func(f *File) {
+		if err := f.Close(); err != nil {
+			m.logger().Error("[memfs] writeFileIf", "error", err)
+		}
+	}(f)
+
+	if _, err := f.checkWrite("writeFileIf"); err != nil {
+		return err
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	// The file may have been created or written by another writer since it was checked above, so the generation is
+	// checked again while the file descriptor is locked. A file that was created by the call to open has not been
+	// written, and has a generation of 0.
+	if f.fd.entry.Attributes().Generation() != ifGeneration {
+		return fs.NewOpError(providerName, "writeFileIf", name, fs.ErrConflict)
+	}
+
+	if err := f.fd.resize(0, time.Time{}); err != nil {
+		return err
+	}
+	if _, err := f.write(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteFileIfUnmodified replaces the content of the named file with data, but only if the modification time of the
+// file is not after since. The mode of the file is not changed.
+func (m *MemFS) WriteFileIfUnmodified(name string, data []byte, since time.Time) error {
+	f, err := m.open("writeFileIfUnmodified", name, fs.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer func(f *File) {
+		if err := f.Close(); err != nil {
+			m.logger().Error("[memfs] writeFileIfUnmodified", "error", err)
+		}
+	}(f)
+
+	if _, err := f.checkWrite("writeFileIfUnmodified"); err != nil {
+		return err
+	}
+
+	f.fd.mutex.Lock()
+	defer f.fd.mutex.Unlock()
+
+	if f.fd.entry.ModTime().After(since) {
+		return fs.NewOpError(providerName, "writeFileIfUnmodified", name, fs.ErrConflict)
+	}
+
+	if err := f.fd.resize(0, time.Time{}); err != nil {
+		return err
+	}
+	if _, err := f.write(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteFiles writes the data of each named file in files in the same way as WriteFile, creating missing parent
+// directories with the permissions returned by fs.DirPerm for perm. The files are written while the MemFS is locked,
+// and the directory of the files is only resolved once for all files in the same directory.
+func (m *MemFS) WriteFiles(files map[string][]byte, perm gofs.FileMode) error {
+	type file struct {
+		name string
+		path string
+	}
+
+	batch := make([]file, 0, len(files))
+	for name := range files {
+		p, err := fs.CleanPath(m, name)
+		if err != nil {
+			return fs.NewOpError(providerName, "writeFiles", name, err)
+		}
+		batch = append(batch, file{name: name, path: p})
+	}
+	slices.SortFunc(batch, func(a, b file) int { return strings.Compare(a.path, b.path) })
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	dirs := map[string]*MemFS{".": m}
+	for _, f := range batch {
+		dir, ok := dirs[fs.Dir(m, f.path)]
+		if !ok {
+			d, err := mkdirAll(m, fs.Dir(m, f.path), fs.DirPerm(perm))
+			if err != nil {
+				return fs.NewOpError(providerName, "writeFiles", f.name, err)
+			}
+			dirs[fs.Dir(m, f.path)] = d
+			dir = d
+
+			if err := m.log(walRecord{op: walMkdir, name: fs.Dir(m, f.path), mode: fs.DirPerm(perm)}); err != nil {
+				return err
+			}
+		}
+
+		if err := writeFile(dir, fs.Base(m, f.path), files[f.name], perm); err != nil {
+			return fs.NewOpError(providerName, "writeFiles", f.name, err)
+		}
+
+		if w := m.wal(); w != nil {
+			e, err := entry(dir, fs.Base(m, f.path))
+			if err != nil {
+				return fs.NewOpError(providerName, "writeFiles", f.name, err)
+			}
+
+			if err := m.log(walRecord{
+				op:    walWrite,
+				name:  f.path,
+				mode:  e.entry.Mode(),
+				mtime: e.entry.ModTime(),
+				data:  files[f.name],
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String returns a rendering of the tree of the MemFS, including the size of each file (see fs.Tree).
+func (m *MemFS) String() string {
+	return fs.Tree(m, ".", fs.WithTreeSizes())
+}
+
+// entryOf returns the entry for the named file or directory, whose fs.Entry is changed by operations such as Chmod.
+func (m *MemFS) entryOf(op string, name string) (*fsEntry, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, op, name, err)
+	}
+
+	if e.entry == nil {
+		return nil, fs.NewOpError(providerName, op, name, fs.ErrInvalidEntryType)
+	}
+	return e, nil
+}
+
+// logger returns the Logger of the MemFS, which discards all messages if the MemFS was not created by New.
+func (m *MemFS) logger() fs.Logger {
+	if m.opts == nil || m.opts.logger == nil {
+		return fs.NopLogger()
+	}
+	return m.opts.logger
+}
+
+// lookup returns the named entry while the MemFS is locked for reading, so that lookups do not observe changes to the
+// structure of the tree while they are in progress.
+func (m *MemFS) lookup(name string) (*fsEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return stat(m, name)
+}
+
+// open opens the named file using flag, creating it with mode if it does not exist and flag includes fs.O_CREATE. The
+// returned File records its path, so that its content can be recorded in the write-ahead log when it is closed.
+func (m *MemFS) open(op string, name string, flag int, mode gofs.FileMode) (f *File, err error) {
+	name, err = fs.CleanPath(m, name)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, op, name, err)
+	}
+
+	defer func() {
+		if f != nil {
+			f.name = name
+		}
+	}()
+
+	s, err := m.lookup(name)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) && flag&fs.O_CREATE != 0 {
+			f, err := create(m, name, flag, mode)
+			if err != nil {
+				return nil, err
+			}
+
+			// The file is recorded when it is created, so that the mutations of its path that are logged before it is
+			// closed can be replayed.
+			f.name = name
+			if err := f.log(); err != nil {
+				f.fd.close()
+				return nil, fs.NewOpError(providerName, op, name, err)
+			}
+			return f, nil
+		}
+		return nil, fs.NewOpError(providerName, op, name, err)
+	}
+
+	if s != nil {
+		switch s.Data().(type) {
+		case *fd:
+			fd := s.Data().(*fd)
+			if !fd.entry.IsDir() {
+				return newFile(fd, flag)
+			}
+			return newFile(fd, fs.O_RDONLY)
+		case *MemFS:
+			mfs := s.Data().(*MemFS)
+			fd, err := newfd(mfs, ".", fs.O_RDONLY, mfs.entry.Mode())
+			if err != nil {
+				return nil, fs.NewOpError(providerName, op, name, err)
+			}
+			return newFile(fd, fs.O_RDONLY)
+		default:
+			return nil, fs.NewOpError(providerName, op, name, gofs.ErrInvalid)
+		}
+	}
+
+	p, err := fs.SplitPath(m, name)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, op, name, err)
+	}
+
+	if len(p) > 1 {
+		e, err := m.lookup(fs.Dir(m, name))
+		if err != nil {
+			return nil, fs.NewOpError(providerName, op, name, err)
+		}
+
+		fd, err := newfd(e.Data().(*MemFS), fs.Base(m, name), flag, mode)
+		if err != nil {
+			return nil, fs.NewOpError(providerName, op, name, err)
+		}
+		return newFile(fd, flag)
+	}
+
+	fd, err := newfd(m, name, flag, mode)
+	if err != nil {
+		return nil, fs.NewOpError(providerName, op, name, err)
+	}
+	return newFile(fd, flag)
+}
+
+// readDir returns the entries of the named directory, checking ctx between chunks of entries.
+func (m *MemFS) readDir(ctx context.Context, name string) ([]gofs.DirEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sub, err := sub(m, name)
+	if err != nil {
+		return nil, err
+	}
+
+	mfs := sub.(*MemFS)
+	iter := newDirIterator(mfs)
+
+	var entries []gofs.DirEntry
+	for {
+		if ctx.Err() != nil {
+			return nil, fs.NewOpError(providerName, "readDir", mfs.entry.Path(), context.Cause(ctx))
+		}
+
+		de, err := iter.NextN(ctxCheckInterval)
+		for _, e := range de {
+			entries = append(entries, e)
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fs.NewOpError(providerName, "readDir", mfs.entry.Path(), err)
+		}
+	}
+}
+
+// readDirPage returns a page of the entries of the named directory in the same way as ReadDirPage, checking ctx between
+// chunks of entries.
+func (m *MemFS) readDirPage(ctx context.Context, name string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	return m.readDirRange(ctx, "readDirPage", name, "", pageSize, token)
+}
+
+// readDirRange returns at most pageSize entries of the named directory whose names start with prefix and follow token.
+// The first entry is found using the index of the directory, so that the entries before it are not read.
+func (m *MemFS) readDirRange(ctx context.Context, op string, name string, prefix string, pageSize int, token string) ([]gofs.DirEntry, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fs.NewOpError(providerName, op, name, gofs.ErrInvalid)
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sub, err := sub(m, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mfs := sub.(*MemFS)
+
+	var entries []gofs.DirEntry
+	for c, i := mfs.index.seekPrefix(prefix, token), 0; mfs.index.hasPrefix(c, prefix); c, i = mfs.index.next(c), i+1 {
+		if i%ctxCheckInterval == 0 && ctx.Err() != nil {
+			return nil, "", fs.NewOpError(providerName, op, mfs.entry.Path(), context.Cause(ctx))
+		}
+
+		v, _ := mfs.index.at(c)
+		if v == "." {
+			continue
+		}
+
+		if len(entries) == pageSize {
+			return entries, entries[len(entries)-1].Name(), nil
+		}
 
-	sub, err := sub(m, dir)
-	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: err})
+		e, err := dirEntry(mfs, v)
+		if err != nil {
+			return nil, "", fs.NewOpError(providerName, op, mfs.entry.Path(), err)
+		}
+		entries = append(entries, e)
 	}
-	return sub, nil
+	return entries, "", nil
 }
 
-// WriteFile ...
-func (m *MemFS) WriteFile(name string, data []byte, mode gofs.FileMode) error {
-	log.Debug("[memfs] writeFile",
-		log.String("name", name),
-		log.Int("content_length", len(data)),
-		log.String("mode", mode.String()),
-	)
-
-	f, err := m.open("writeFile", name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, mode)
-	if err != nil {
-		return err
+// glob returns the names of the entries matching pattern, checking ctx between chunks of entries. The pattern is
+// matched one element at a time, so that only the directories matching the elements before the last are read, and only
+// the entries of a directory whose names start with the literal prefix of an element are read using the index of the
+// directory.
+func (m *MemFS) glob(ctx context.Context, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fs.NewOpError(providerName, "glob", pattern, err)
 	}
-	defer func(f *File) {
-		if err := f.Close(); err != nil {
-			log.Error("[memfs] writeFile", log.Err(err))
-		}
-	}(f)
 
-	if _, err := f.Write(data); err != nil {
-		return err
+	if pattern == "." {
+		return []string{"."}, nil
 	}
-	return nil
-}
 
-// String returns a string representation of MemFS.
-func (m *MemFS) String() string {
-	s := make(map[string]any)
-	s["mode"] = m.entry.Mode().String()
-	s["mod_time"] = m.entry.ModTime()
-	s["Name"] = m.entry.Name()
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	entries, err := list(m)
-	if err != nil {
-		entries = append(entries, err.Error())
+	g := &globber{ctx: ctx}
+	if err := g.match(m, "", strings.Split(pattern, "/")); err != nil {
+		return g.matches, fs.NewOpError(providerName, "glob", pattern, err)
 	}
-	s["list"] = entries
-	return string(anchor.ToJSONFormatted(s))
+	return g.matches, nil
 }
 
-func (m *MemFS) open(op string, name string, flag int, mode gofs.FileMode) (*File, error) {
-	name, err := fs.CleanPath(m, name)
-	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
-	}
+// globber holds the state of a call to MemFS.glob.
+type globber struct {
+	ctx     context.Context
+	matches []string
+	n       int
+}
 
-	s, err := stat(m, name)
-	if err != nil {
-		if errors.Is(err, gofs.ErrNotExist) && flag&fs.O_CREATE != 0 {
-			return create(m, name, flag, mode)
-		}
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+// match adds the paths of the entries of dir matching the pattern elements elems to the matches, where dir is at path.
+func (g *globber) match(dir *MemFS, path string, elems []string) error {
+	elem := elems[0]
+	if elem == "" || elem == "." || elem == ".." {
+		return nil
 	}
 
-	if s != nil {
-		switch s.Data().(type) {
-		case *fd:
-			fd := s.Data().(*fd)
-			if !fd.entry.IsDir() {
-				return newFile(fd, flag)
-			}
-			return newFile(fd, fs.O_RDONLY)
-		case *MemFS:
-			mfs := s.Data().(*MemFS)
-			fd, err := newfd(mfs, ".", fs.O_RDONLY, mfs.entry.Mode())
-			if err != nil {
-				return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	i := strings.IndexAny(elem, globMeta)
+	if i < 0 {
+		e, err := entry(dir, elem)
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				return nil
 			}
-			return newFile(fd, fs.O_RDONLY)
-		default:
-			return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+			return err
 		}
+		return g.visit(e, gopath.Join(path, elem), elems[1:])
 	}
 
-	p, err := fs.SplitPath(m, name)
-	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
-	}
+	prefix := elem[:i]
+	for c := dir.index.seekPrefix(prefix, ""); dir.index.hasPrefix(c, prefix); c = dir.index.next(c) {
+		if g.n%ctxCheckInterval == 0 && g.ctx.Err() != nil {
+			return context.Cause(g.ctx)
+		}
+		g.n++
 
-	if len(p) > 1 {
-		e, err := stat(m, filepath.Dir(name))
-		if err != nil {
-			return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+		v, _ := dir.index.at(c)
+		if v == "." {
+			continue
 		}
 
-		fd, err := newfd(e.Data().(*MemFS), filepath.Base(name), flag, mode)
+		if matched, _ := filepath.Match(elem, v); !matched {
+			continue
+		}
+
+		e, err := entry(dir, v)
 		if err != nil {
-			return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+			return err
+		}
+
+		if err := g.visit(e, gopath.Join(path, v), elems[1:]); err != nil {
+			return err
 		}
-		return newFile(fd, flag)
 	}
+	return nil
+}
 
-	fd, err := newfd(m, name, flag, mode)
-	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+// visit adds path to the matches if no pattern elements remain, and otherwise matches the remaining elements against
+// the entries of e if it is a directory.
+func (g *globber) visit(e *fsEntry, path string, elems []string) error {
+	if len(elems) == 0 {
+		g.matches = append(g.matches, path)
+		return nil
 	}
-	return newFile(fd, flag)
+
+	if d, ok := e.Data().(*MemFS); ok {
+		return g.match(d, path, elems)
+	}
+	return nil
 }
 
 func create(mfs *MemFS, name string, flag int, mode gofs.FileMode) (*File, error) {
@@ -351,7 +1116,7 @@ func create(mfs *MemFS, name string, flag int, mode gofs.FileMode) (*File, error
 	defer mfs.mutex.Unlock()
 
 	if mode&gofs.ModeDir != 0 {
-		log.Trace("[memfs:create] directory mode bits set, creating path as directory", log.String("name", name))
+		mfs.logger().Debug("[memfs:create] directory mode bits set, creating path as directory", "name", name)
 
 		dir, err := mkdirAll(mfs, name, mode)
 		if err != nil {
@@ -383,22 +1148,81 @@ func create(mfs *MemFS, name string, flag int, mode gofs.FileMode) (*File, error
 		return newFile(fd, flag)
 	}
 
-	log.Trace("[memfs:create] creating directory for file", log.String("directory", filepath.Dir(name)))
+	mfs.logger().Debug("[memfs:create] creating directory for file", "directory", fs.Dir(mfs, name))
 
-	dir, err := mkdirAll(mfs, filepath.Dir(name), mode)
+	dir, err := mkdirAll(mfs, fs.Dir(mfs, name), mode)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Trace("[memfs:create]", log.String("directory", dir.entry.Name()), log.String("name", filepath.Base(name)))
+	mfs.logger().Debug("[memfs:create]", "directory", dir.entry.Name(), "name", fs.Base(mfs, name))
 
-	fd, err := newfd(dir, filepath.Base(name), flag, mode)
+	fd, err := newfd(dir, fs.Base(mfs, name), flag, mode)
 	if err != nil {
 		return nil, err
 	}
 	return newFile(fd, flag)
 }
 
+// compact compacts the buffers of the files in mfs and its subdirectories, and returns the number of bytes reclaimed.
+func compact(mfs *MemFS) int64 {
+	var n int64
+	for _, v := range mfs.entries.Values() {
+		if v == "." {
+			continue
+		}
+
+		e, err := entry(mfs, v)
+		if err != nil {
+			continue
+		}
+
+		switch d := e.Data().(type) {
+		case *fd:
+			n += d.compact()
+		case *MemFS:
+			n += compact(d)
+		}
+	}
+	return n
+}
+
+// count returns the number of files and directories in mfs and its subdirectories.
+func count(mfs *MemFS) uint64 {
+	var n uint64
+	for _, v := range mfs.entries.Values() {
+		if v == "." {
+			continue
+		}
+		n++
+
+		if e, err := entry(mfs, v); err == nil {
+			if d, ok := e.Data().(*MemFS); ok {
+				n += count(d)
+			}
+		}
+	}
+	return n
+}
+
+// addEntry adds e to the entries of the directory m and to the index of their names.
+func (m *MemFS) addEntry(e *fsEntry) error {
+	if err := m.entries.AddEntry(e); err != nil {
+		return err
+	}
+	m.index.insert(e.Value())
+	return nil
+}
+
+// removeEntry removes the named entry from the entries of the directory m and from the index of their names.
+func (m *MemFS) removeEntry(name string) error {
+	if _, err := m.entries.Remove(name); err != nil {
+		return err
+	}
+	m.index.remove(name)
+	return nil
+}
+
 func entry(mfs *MemFS, name string) (*fsEntry, error) {
 	e, err := mfs.entries.Entry(name)
 	if err != nil {
@@ -425,39 +1249,43 @@ func find(mfs *MemFS, name string) (*fsEntry, error) {
 		return nil, err
 	}
 
-	if len(n) > 1 {
-		e, err := entry(mfs, n[0])
-		if err != nil {
-			return nil, err
-		}
+	if len(n) == 1 {
+		return entry(mfs, name)
+	}
 
-		if e.entry.IsDir() {
-			return find(e.Data().(*MemFS), strings.Join(n[1:], pathSeparator))
+	cache := mfs.cache()
+	var gen uint64
+	if cache != nil {
+		e, g, ok := cache.get(mfs, name)
+		if ok {
+			return e, nil
 		}
-		return nil, gofs.ErrNotExist
+		gen = g
 	}
-	return entry(mfs, name)
-}
 
-func list(mfs *MemFS) ([]string, error) {
-	var entries []string
-	err := gofs.WalkDir(mfs, ".", func(path string, entry gofs.DirEntry, err error) error {
+	dir := mfs
+	for _, s := range n[:len(n)-1] {
+		e, err := entry(dir, s)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		fi, err := entry.Info()
-		if err != nil {
-			return err
+		d, ok := e.Data().(*MemFS)
+		if !ok {
+			return nil, gofs.ErrNotExist
 		}
+		dir = d
+	}
 
-		entries = append(entries, fmt.Sprintf("%s: size: %d, mode: %s, mode_type: %s", path, fi.Size(), fi.Mode(), fi.Mode().Type()))
-		return nil
-	})
+	e, err := entry(dir, n[len(n)-1])
 	if err != nil {
-		return entries, err
+		return nil, err
+	}
+
+	if cache != nil {
+		cache.put(mfs, name, e, gen)
 	}
-	return entries, nil
+	return e, nil
 }
 
 func mkdir(mfs *MemFS, name string, mode gofs.FileMode) (*MemFS, error) {
@@ -471,28 +1299,29 @@ func mkdir(mfs *MemFS, name string, mode gofs.FileMode) (*MemFS, error) {
 	}
 
 	if len(p) > 1 {
-		dir := filepath.Dir(name)
+		dir := fs.Dir(mfs, name)
 		e, err := stat(mfs, dir)
 		if err != nil {
-			return nil, &gofs.PathError{Op: "mkdir", Path: dir, Err: gofs.ErrInvalid}
+			return nil, &gofs.PathError{Op: "mkdir", Path: dir, Err: err}
 		}
 		mfs = e.Data().(*MemFS)
 	}
 
 	if !mfs.entry.IsDir() {
-		return mfs, &gofs.PathError{Op: "mkdir", Path: filepath.Dir(name), Err: fs.ErrNotDir}
+		return mfs, &gofs.PathError{Op: "mkdir", Path: fs.Dir(mfs, name), Err: fs.ErrNotDir}
 	}
 
 	// TODO: Check writable permission of parent?
 
-	if _, err := entry(mfs, filepath.Base(name)); err != nil {
+	if _, err := entry(mfs, fs.Base(mfs, name)); err != nil {
 		if errors.Is(err, gofs.ErrNotExist) {
-			n, err := newDir(filepath.Base(name), mode)
+			n, err := newDir(fs.Base(mfs, name), mode)
 			if err != nil {
 				return nil, &gofs.PathError{Op: "mkdir", Path: name, Err: err}
 			}
+			n.opts = mfs.opts
 
-			if err = mfs.entries.AddEntry(&fsEntry{
+			if err = mfs.addEntry(&fsEntry{
 				entry: n.entry,
 				data:  n,
 			}); err != nil {
@@ -509,7 +1338,36 @@ func mkdir(mfs *MemFS, name string, mode gofs.FileMode) (*MemFS, error) {
 	return mfs, &gofs.PathError{Op: "mkdir", Path: name, Err: gofs.ErrExist}
 }
 
+// writeFile writes data to the named file in the directory mfs, creating the file with permissions perm if it does not
+// exist.
+func writeFile(mfs *MemFS, name string, data []byte, perm gofs.FileMode) error {
+	flag := fs.O_RDWR | fs.O_CREATE | fs.O_TRUNC
+	fd, err := newfd(mfs, name, flag, perm)
+	if err != nil {
+		return err
+	}
+
+	if fd.entry.IsDir() {
+		return fs.ErrIsDir
+	}
+
+	f, err := newFile(fd, flag)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func mkdirAll(mfs *MemFS, path string, mode gofs.FileMode) (*MemFS, error) {
+	if path == "." {
+		return mfs, nil
+	}
+
 	p, err := fs.SplitPath(mfs, path)
 	if err != nil {
 		return nil, err
@@ -524,7 +1382,11 @@ func mkdirAll(mfs *MemFS, path string, mode gofs.FileMode) (*MemFS, error) {
 		}
 
 		if s != nil {
-			mfs = s.Data().(*MemFS)
+			d, ok := s.Data().(*MemFS)
+			if !ok {
+				return nil, fs.ErrNotDir
+			}
+			mfs = d
 			continue
 		}
 
@@ -538,7 +1400,8 @@ func mkdirAll(mfs *MemFS, path string, mode gofs.FileMode) (*MemFS, error) {
 }
 
 func newDir(name string, mode gofs.FileMode, entryOptions ...func(*fs.Entry)) (*MemFS, error) {
-	attrs, err := fs.NewAttributes(fs.WithMode(uint32(mode | gofs.ModeDir)))
+	now := time.Now()
+	attrs, err := fs.NewAttributes(fs.WithAtime(now), fs.WithBtime(now), fs.WithMode(uint32(mode|gofs.ModeDir)))
 	if err != nil {
 		return nil, err
 	}
@@ -561,6 +1424,138 @@ func newDir(name string, mode gofs.FileMode, entryOptions ...func(*fs.Entry)) (*
 	return mfs, nil
 }
 
+func parent(mfs *MemFS, name string) (*MemFS, string, error) {
+	p, err := fs.SplitPath(mfs, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(p) > 1 {
+		e, err := stat(mfs, fs.Dir(mfs, name))
+		if err != nil {
+			return nil, "", err
+		}
+
+		dir, ok := e.Data().(*MemFS)
+		if !ok {
+			return nil, "", fs.ErrNotDir
+		}
+		return dir, p[len(p)-1], nil
+	}
+	return mfs, name, nil
+}
+
+func remove(mfs *MemFS, name string, all bool) error {
+	if name == "." {
+		return gofs.ErrInvalid
+	}
+
+	dir, base, err := parent(mfs, name)
+	if err != nil {
+		return err
+	}
+
+	e, err := entry(dir, base)
+	if err != nil {
+		return err
+	}
+
+	if d, ok := e.Data().(*MemFS); ok && !all && d.entries.Len() > 1 {
+		return fs.ErrNotEmpty
+	}
+
+	if err := dir.removeEntry(base); err != nil {
+		return err
+	}
+
+	if c := mfs.cache(); c != nil {
+		c.invalidate()
+	}
+
+	if fd, ok := e.Data().(*fd); ok {
+		fd.remove()
+	}
+	return dir.entry.SetModTime(time.Now())
+}
+
+func rename(mfs *MemFS, oldpath string, newpath string) error {
+	if oldpath == "." || newpath == "." {
+		return gofs.ErrInvalid
+	}
+
+	if oldpath == newpath {
+		return nil
+	}
+
+	if strings.HasPrefix(newpath, oldpath+pathSeparator) {
+		return gofs.ErrInvalid
+	}
+
+	oldDir, oldBase, err := parent(mfs, oldpath)
+	if err != nil {
+		return err
+	}
+
+	e, err := entry(oldDir, oldBase)
+	if err != nil {
+		return err
+	}
+
+	newDir, newBase, err := parent(mfs, newpath)
+	if err != nil {
+		return err
+	}
+
+	existing, err := entry(newDir, newBase)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	if c := mfs.cache(); c != nil {
+		defer c.invalidate()
+	}
+
+	if existing != nil {
+		if existing.entry.IsDir() {
+			if !e.entry.IsDir() {
+				return fs.ErrIsDir
+			}
+
+			if existing.Data().(*MemFS).entries.Len() > 1 {
+				return fs.ErrNotEmpty
+			}
+		} else if e.entry.IsDir() {
+			return fs.ErrNotDir
+		}
+
+		if err := newDir.removeEntry(newBase); err != nil {
+			return err
+		}
+	}
+
+	if err := oldDir.removeEntry(oldBase); err != nil {
+		return err
+	}
+
+	if fd, ok := e.Data().(*fd); ok {
+		if err := fd.move(newDir, newBase); err != nil {
+			return err
+		}
+	} else if err := e.entry.SetPath(newBase); err != nil {
+		return err
+	}
+
+	if err := newDir.addEntry(e); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := oldDir.entry.SetModTime(now); err != nil {
+		return err
+	}
+	return newDir.entry.SetModTime(now)
+}
+
 func stat(mfs *MemFS, name string) (*fsEntry, error) {
 	name, err := fs.CleanPath(mfs, name)
 	if err != nil {
@@ -586,7 +1581,104 @@ func sub(mfs *MemFS, dir string) (gofs.SubFS, error) {
 
 	e, err := find(mfs, dir)
 	if err != nil {
-		return nil, fmt.Errorf("memfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: err})
+		return nil, fs.NewOpError(providerName, "sub", dir, err)
+	}
+
+	d, ok := e.Data().(*MemFS)
+	if !ok {
+		return nil, fs.NewOpError(providerName, "sub", dir, fs.ErrNotDir)
+	}
+	return d, nil
+}
+
+// WithArena allocates the buffers that hold the content of files from a single arena of n bytes, which is allocated
+// when the MemFS is created, instead of allocating a buffer for each file. Writing files then does not allocate memory
+// or create work for the garbage collector, which suits latency-sensitive services whose dataset has a known bound.
+//
+// Buffers are allocated from the arena in blocks whose sizes are powers of 2, of at least 4 KiB, so the memory used by
+// a file can be up to twice its size. A write for which the arena has no free block that is large enough returns an
+// error wrapping fs.ErrQuotaExceeded, and the quota reported by Usage is at most n. WithMmapThreshold has no effect if
+// an arena is used, and the arena itself is backed by an anonymous memory mapping on Unix platforms.
+func WithArena(n int) func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.arena = max(n, 0)
+	}
+}
+
+// WithAutoCompact enables compacting the buffer that holds the content of a file when a File that changed the content
+// is closed, if more than ratio of the buffer is unused. For example, a ratio of 0.5 compacts the buffers of files
+// whose content uses less than half of the buffer. Ratios are limited to the range [0, 1), where 0 compacts every
+// buffer with unused capacity. See Compact.
+func WithAutoCompact(ratio float64) func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.autoCompact = true
+		m.opts.compactRatio = min(max(ratio, 0), 0.99)
+	}
+}
+
+// WithChecksum enables computing the digest of the content of a file using hash when the file is closed after it was
+// written. The digest is stored hex-encoded in the Attribute of the file, using the name returned by
+// fs.ChecksumAlgorithm for hash. The hash function must be available (see crypto.Hash.Available).
+func WithChecksum(hash crypto.Hash) func(*MemFS) {
+	return func(m *MemFS) {
+		if hash.Available() {
+			m.opts.checksums = append(m.opts.checksums, hash)
+		}
+	}
+}
+
+// WithLogger sets the Logger used by the MemFS to log its internal operation and errors that can not be returned to
+// the caller. By default, nothing is logged. Use loggo.New to log using the default log-go logger.
+func WithLogger(logger fs.Logger) func(*MemFS) {
+	return func(m *MemFS) {
+		if logger != nil {
+			m.opts.logger = logger
+		}
+	}
+}
+
+// WithMimeDetection enables detecting the MIME type of a file using fs.DetectMimeType when the file is closed after it
+// was written. The type is stored in the fs.Attribute of the file.
+func WithMimeDetection() func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.mimeDetection = true
+	}
+}
+
+// WithMmapThreshold enables backing the content of files of at least n bytes with anonymous memory mappings instead of
+// the Go heap, so that large files do not increase the heap size and the time spent by the garbage collector. The
+// mapping of a file is released when the file is removed, when the content is moved to a larger mapping as the file
+// grows, or once the file is no longer reachable. Memory mappings are only supported on Unix platforms, and the option
+// has no effect on other platforms.
+func WithMmapThreshold(n int) func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.buffers.mmapThreshold = max(n, 0)
+	}
+}
+
+// WithNameRules sets the rules that the names of entries in the MemFS must follow, so that the MemFS can be used in
+// place of a provider with restricted names, such as fs.S3NameRules for an object store. Operations on a path that
+// violates the rules return an error wrapping a *fs.NameError.
+func WithNameRules(rules fs.NameRules) func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.names = rules
+	}
+}
+
+// WithQuota limits the memory used by the buffers that hold the content of files in the MemFS to n bytes. A write that
+// would exceed the quota returns an error wrapping fs.ErrQuotaExceeded. Since buffers are allocated in size classes,
+// the memory used by a file can be larger than its size. By default, the memory used is not limited.
+func WithQuota(n int64) func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.buffers.quota = max(n, 0)
+	}
+}
+
+// WithRelatime enables updating the access time of a file on read only if it is not after the modification time of the
+// file, or if it was last updated more than 24 hours ago, in the same way as the relatime mount option on Linux. By
+// default, the access time is updated each time a file is read.
+func WithRelatime() func(*MemFS) {
+	return func(m *MemFS) {
+		m.opts.relatime = true
 	}
-	return e.Data().(*MemFS), nil
 }
@@ -1,12 +1,25 @@
 package memfs
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/transientvariable/anchor"
 	"github.com/transientvariable/fs-go"
@@ -101,3 +114,1655 @@ func TestMemFSTestSuite(t *testing.T) {
 func (t *MemFSTestSuite) TestFS() {
 	assert.NoError(t.T(), fstest.TestFS(t.mfs, t.filePaths...))
 }
+
+func (t *MemFSTestSuite) TestRemove() {
+	for _, p := range t.filePaths {
+		assert.NoError(t.T(), t.mfs.Remove(p))
+
+		_, err := t.mfs.Stat(p)
+		assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	}
+
+	assert.ErrorIs(t.T(), t.mfs.Remove("does-not-exist"), gofs.ErrNotExist)
+	assert.NoError(t.T(), t.mfs.Remove("doc"))
+}
+
+func (t *MemFSTestSuite) TestRemoveAll() {
+	assert.ErrorIs(t.T(), t.mfs.Remove("pictures"), fs.ErrNotEmpty)
+	assert.NoError(t.T(), t.mfs.RemoveAll("pictures"))
+	assert.NoError(t.T(), t.mfs.RemoveAll("pictures"))
+
+	_, err := t.mfs.Stat("pictures")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.mfs.RemoveAll("."))
+
+	entries, err := t.mfs.ReadDir(".")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+}
+
+func (t *MemFSTestSuite) TestRename() {
+	expected, err := t.mfs.ReadFile("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), t.mfs.Rename("doc/fox.txt", "pictures/fox.txt"))
+
+	_, err = t.mfs.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	b, err := t.mfs.ReadFile("pictures/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), expected, b)
+
+	assert.NoError(t.T(), t.mfs.Rename("pictures", "images"))
+
+	fi, err := t.mfs.Stat("images/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "fox.txt", fi.Name())
+
+	assert.Error(t.T(), t.mfs.Rename("images", "images/sub"))
+	assert.ErrorIs(t.T(), t.mfs.Rename("doc", "images"), fs.ErrNotEmpty)
+}
+
+func (t *MemFSTestSuite) TestRenameReplace() {
+	assert.NoError(t.T(), t.mfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), modePerm))
+	assert.NoError(t.T(), t.mfs.Rename("doc/dog.txt", "doc/fox.txt"))
+
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+
+	_, err = t.mfs.Stat("doc/dog.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.mfs.Mkdir("empty", modePerm))
+	assert.ErrorIs(t.T(), t.mfs.Rename("doc/fox.txt", "empty"), fs.ErrIsDir)
+	assert.ErrorIs(t.T(), t.mfs.Rename("empty", "doc/fox.txt"), fs.ErrNotDir)
+	assert.ErrorIs(t.T(), t.mfs.Rename("missing.txt", "doc/missing.txt"), gofs.ErrNotExist)
+	assert.NoError(t.T(), t.mfs.Rename("doc", "empty"))
+
+	_, err = t.mfs.Stat("empty/fox.txt")
+	assert.NoError(t.T(), err)
+}
+
+func (t *MemFSTestSuite) TestRenameRemoveOpenFile() {
+	f, err := t.mfs.OpenFile("doc/fox.txt", fs.O_RDWR, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	// A File that is open follows the file when it is renamed.
+	assert.NoError(t.T(), t.mfs.Rename("doc/fox.txt", "pictures/fox.txt"))
+
+	fi, err := f.Stat()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "fox.txt", fi.Name())
+
+	_, err = f.Seek(0, io.SeekEnd)
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte(" jumps"))
+	assert.NoError(t.T(), err)
+
+	b, err := t.mfs.ReadFile("pictures/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), strings.HasSuffix(string(b), " jumps"))
+
+	// The content of a file that is removed while it is open remains readable until the File is closed.
+	assert.NoError(t.T(), t.mfs.Remove("pictures/fox.txt"))
+
+	_, err = f.Seek(0, io.SeekStart)
+	assert.NoError(t.T(), err)
+
+	content, err := io.ReadAll(f)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), b, content)
+	assert.NoError(t.T(), f.Close())
+
+	_, err = t.mfs.Stat("pictures/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestRenameConcurrentStat() {
+	f, err := t.mfs.OpenFile("doc/fox.txt", fs.O_RDONLY, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := range 100 {
+			from, to := "doc/fox.txt", "doc/dog.txt"
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			assert.NoError(t.T(), t.mfs.Rename(from, to))
+		}
+	}()
+
+	for range 100 {
+		_, err := f.Stat()
+		assert.NoError(t.T(), err)
+
+		_, err = f.Read(make([]byte, 4))
+		if err != nil {
+			assert.ErrorIs(t.T(), err, io.EOF)
+		}
+	}
+	wg.Wait()
+	assert.NoError(t.T(), f.Close())
+}
+
+func (t *MemFSTestSuite) TestReadDirPage() {
+	pager := t.mfs.(fs.ReadDirPager)
+
+	expected, err := t.mfs.ReadDir("pictures")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	var (
+		names []string
+		token string
+	)
+	for {
+		entries, next, err := pager.ReadDirPage("pictures", 2, token)
+		assert.NoError(t.T(), err)
+		assert.LessOrEqual(t.T(), len(entries), 2)
+
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	assert.Len(t.T(), names, len(expected))
+	for i, e := range expected {
+		assert.Equal(t.T(), e.Name(), names[i])
+	}
+
+	entries, next, err := pager.ReadDirPage("pictures", 2, "seals.png")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+	assert.Empty(t.T(), next)
+
+	_, _, err = pager.ReadDirPage("pictures", 0, "")
+	assert.ErrorIs(t.T(), err, gofs.ErrInvalid)
+
+	_, _, err = pager.ReadDirPage("does-not-exist", 2, "")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestChecksum() {
+	mfs, err := New(WithChecksum(crypto.SHA256), WithChecksum(crypto.MD5))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	content := []byte("the quick brown fox")
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", content, modePerm))
+
+	fi, err := mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	attrs := fi.(*fs.Entry).Attributes()
+	sha := sha256.Sum256(content)
+	digest, ok := attrs.Checksum(fs.ChecksumAlgorithm(crypto.SHA256))
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), hex.EncodeToString(sha[:]), digest)
+
+	sum := md5.Sum(content)
+	digest, ok = attrs.Checksum("MD5")
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), hex.EncodeToString(sum[:]), digest)
+
+	fi, err = t.mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), fi.(*fs.Entry).Attributes().Checksums())
+}
+
+func (t *MemFSTestSuite) TestSetMetadata() {
+	assert.NoError(t.T(), t.mfs.(fs.MetadataFS).SetMetadata("doc/fox.txt", map[string]string{"color": "brown", "empty": ""}))
+
+	fi, err := t.mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), map[string]string{"color": "brown"}, fi.(*fs.Entry).Attributes().Metadata())
+
+	assert.NoError(t.T(), t.mfs.(fs.MetadataFS).SetMetadata("doc", map[string]string{"owner": "fox"}))
+	fi, err = t.mfs.Stat("doc")
+	assert.NoError(t.T(), err)
+
+	v, ok := fi.(*fs.Entry).Attributes().MetadataValue("owner")
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), "fox", v)
+
+	err = t.mfs.(fs.MetadataFS).SetMetadata("does-not-exist", nil)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestChmodChtimes() {
+	assert.NoError(t.T(), t.mfs.(fs.ChmodFS).Chmod("doc", gofs.ModeDir|0700))
+	assert.NoError(t.T(), t.mfs.(fs.ChmodFS).Chmod("doc/fox.txt", 0600))
+
+	fi, err := t.mfs.Stat("doc")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+	assert.Equal(t.T(), gofs.FileMode(0700), fi.Mode().Perm())
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	assert.NoError(t.T(), t.mfs.(fs.ChtimesFS).Chtimes("doc/fox.txt", mtime, mtime))
+
+	fi, err = t.mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.FileMode(0600), fi.Mode().Perm())
+	assert.Equal(t.T(), mtime, fi.ModTime().UTC())
+	assert.Equal(t.T(), mtime, fi.(*fs.Entry).Attributes().Atime().UTC())
+
+	assert.ErrorIs(t.T(), t.mfs.(fs.ChtimesFS).Chtimes("does-not-exist", mtime, mtime), gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestStatCopy() {
+	fi, err := t.mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	fi.(*fs.Entry).Attributes().SetMode(0777)
+
+	entries, err := gofs.ReadDir(t.mfs, "doc")
+	assert.NoError(t.T(), err)
+	for _, e := range entries {
+		info, err := e.Info()
+		assert.NoError(t.T(), err)
+		info.(*fs.Entry).Attributes().SetMode(0777)
+	}
+
+	fi, err = t.mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.FileMode(modePerm), fi.Mode().Perm())
+}
+
+func (t *MemFSTestSuite) TestStatConcurrentWrite() {
+	fi, err := t.mfs.Stat("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	f, err := t.mfs.OpenFile("doc/fox.txt", fs.O_RDWR, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for range 1000 {
+			_, err := f.Write([]byte("the quick brown fox"))
+			assert.NoError(t.T(), err)
+			assert.NoError(t.T(), f.(*File).Truncate(0))
+		}
+	}()
+
+	for range 1000 {
+		_ = fi.ModTime()
+		_ = fi.(*fs.Entry).Attributes().Atime()
+
+		info, err := t.mfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		_ = info.ModTime()
+		_ = info.(*fs.Entry).Attributes().Atime()
+	}
+	wg.Wait()
+	assert.NoError(t.T(), f.Close())
+}
+
+func (t *MemFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	mfs, err := New(WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+	assert.Contains(t.T(), buf.String(), `msg="[memfs:create] creating directory for file" directory=doc`)
+
+	mfs, err = New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), mfs.logger())
+}
+
+func (t *MemFSTestSuite) TestString() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	want := `[         0]  .
+└── [         0]  doc
+    └── [        19]  fox.txt
+
+1 directory, 1 file
+`
+	assert.Equal(t.T(), want, mfs.String())
+}
+
+func (t *MemFSTestSuite) TestAtime() {
+	for _, relatime := range []bool{false, true} {
+		var opts []func(*MemFS)
+		if relatime {
+			opts = append(opts, WithRelatime())
+		}
+
+		mfs, err := New(opts...)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+		fi, err := mfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+
+		attrs := fi.(*fs.Entry).Attributes()
+		assert.False(t.T(), attrs.Btime().IsZero())
+		assert.False(t.T(), attrs.Atime().After(fi.ModTime()))
+
+		readAtime := func() time.Time {
+			_, err := mfs.ReadFile("doc/fox.txt")
+			assert.NoError(t.T(), err)
+
+			fi, err := mfs.Stat("doc/fox.txt")
+			assert.NoError(t.T(), err)
+			return fi.(*fs.Entry).Attributes().Atime()
+		}
+
+		atime := readAtime()
+		assert.True(t.T(), atime.After(fi.ModTime()))
+		assert.False(t.T(), attrs.Atime().After(fi.ModTime()))
+
+		time.Sleep(time.Millisecond)
+		assert.Equal(t.T(), !relatime, readAtime().After(atime))
+	}
+}
+
+func (t *MemFSTestSuite) TestMimeDetection() {
+	mfs, err := New(WithMimeDetection())
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b, err := t.mfs.ReadFile("pictures/seals.png")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.WriteFile("seals", b, modePerm))
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	for name, expected := range map[string]string{"seals": "image/png", "doc/fox.txt": "text/plain; charset=utf-8"} {
+		fi, err := mfs.Stat(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), expected, fi.(*fs.Entry).Attributes().MimeType())
+	}
+
+	assert.NoError(t.T(), mfs.SetMimeType("seals", "image/x-seal"))
+	fi, err := mfs.Stat("seals")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "image/x-seal", fi.(*fs.Entry).Attributes().MimeType())
+}
+
+func (t *MemFSTestSuite) TestWriteFileIf() {
+	generation := func(name string) int64 {
+		fi, err := t.mfs.Stat(name)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+		return fi.(*fs.Entry).Attributes().Generation()
+	}
+
+	cfs := t.mfs.(fs.ConditionalWriteFS)
+
+	assert.NoError(t.T(), cfs.WriteFileIf("doc/wolf.txt", []byte("the big bad wolf"), modePerm, 0))
+	assert.Equal(t.T(), int64(1), generation("doc/wolf.txt"))
+	assert.ErrorIs(t.T(), cfs.WriteFileIf("doc/wolf.txt", []byte("the big bad wolf"), modePerm, 0), fs.ErrConflict)
+	assert.ErrorIs(t.T(), cfs.WriteFileIf("doc/missing.txt", []byte("missing"), modePerm, 1), fs.ErrConflict)
+
+	gen := generation("doc/fox.txt")
+	assert.NoError(t.T(), cfs.WriteFileIf("doc/fox.txt", []byte("the lazy dog"), modePerm, gen))
+	assert.Equal(t.T(), gen+1, generation("doc/fox.txt"))
+
+	err := cfs.WriteFileIf("doc/fox.txt", []byte("the quick brown fox"), modePerm, gen)
+	assert.ErrorIs(t.T(), err, fs.ErrConflict)
+
+	f, err := t.mfs.OpenFile("doc/wolf.txt", fs.O_WRONLY, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	_, err = f.Write([]byte("s"))
+	assert.NoError(t.T(), err)
+	_, err = f.Write([]byte("!"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+	assert.Equal(t.T(), int64(2), generation("doc/wolf.txt"))
+
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+}
+
+func (t *MemFSTestSuite) TestWriteFileIfUnmodified() {
+	cfs := t.mfs.(fs.ConditionalWriteFS)
+
+	mtime := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t.T(), t.mfs.(*MemFS).Chtimes("doc/fox.txt", mtime, mtime))
+
+	err := cfs.WriteFileIfUnmodified("doc/fox.txt", []byte("the lazy dog"), mtime.Add(-time.Second))
+	assert.ErrorIs(t.T(), err, fs.ErrConflict)
+	assert.NoError(t.T(), cfs.WriteFileIfUnmodified("doc/fox.txt", []byte("the lazy dog"), mtime))
+
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+
+	// The file was modified by the previous write, so writing it again with the same time fails.
+	assert.ErrorIs(t.T(), cfs.WriteFileIfUnmodified("doc/fox.txt", []byte("the fox"), mtime), fs.ErrConflict)
+	assert.ErrorIs(t.T(), cfs.WriteFileIfUnmodified("doc/missing.txt", nil, mtime), gofs.ErrNotExist)
+	assert.ErrorIs(t.T(), cfs.WriteFileIfUnmodified("doc", nil, time.Now()), fs.ErrIsDir)
+}
+
+func (t *MemFSTestSuite) TestOpenFileIfMatch() {
+	f, err := t.mfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_IFMATCH, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	// Writes using the same File do not conflict with each other.
+	_, err = f.Write([]byte("the"))
+	assert.NoError(t.T(), err)
+	_, err = f.Write([]byte(" lazy"))
+	assert.NoError(t.T(), err)
+
+	// A write by another writer changes the generation, so that subsequent writes using the File conflict.
+	assert.NoError(t.T(), t.mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+	_, err = f.Write([]byte(" dog"))
+	assert.ErrorIs(t.T(), err, fs.ErrConflict)
+	assert.ErrorIs(t.T(), f.(*File).Truncate(0), fs.ErrConflict)
+	assert.NoError(t.T(), f.Close())
+
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	// A File that has already written the file is detected when another File writes it again.
+	w, err := t.mfs.OpenFile("doc/fox.txt", fs.O_WRONLY, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	_, err = w.Write([]byte("a"))
+	assert.NoError(t.T(), err)
+
+	f, err = t.mfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_IFMATCH, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	_, err = w.Write([]byte("b"))
+	assert.NoError(t.T(), err)
+	_, err = f.Write([]byte("c"))
+	assert.ErrorIs(t.T(), err, fs.ErrConflict)
+	assert.NoError(t.T(), f.Close())
+	assert.NoError(t.T(), w.Close())
+
+	f, err = t.mfs.OpenFile("doc/fox.txt", fs.O_RDONLY|fs.O_IFMATCH, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	_, err = f.Write([]byte("c"))
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+	assert.NoError(t.T(), f.Close())
+}
+
+func (t *MemFSTestSuite) TestReadAtMulti() {
+	for _, options := range [][]func(*MemFS){nil, {WithArena(1 << 16)}} {
+		mfs, err := New(options...)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		assert.NoError(t.T(), mfs.WriteFile("fox.txt", []byte("the quick brown fox"), modePerm))
+
+		f, err := mfs.OpenFile("fox.txt", fs.O_RDWR, modePerm)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		bufs, err := f.(*File).ReadAtMulti([]fs.Range{{Off: 16, Len: 3}, {Off: 4, Len: 5}, {Off: 0, Len: 3}})
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), []string{"fox", "quick", "the"}, []string{string(bufs[0]), string(bufs[1]), string(bufs[2])})
+
+		// The ranges are copies of the content of buffers that are reused once the file is removed.
+		_, err = f.Write([]byte("one"))
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the", string(bufs[2]))
+
+		_, err = f.(*File).ReadAtMulti([]fs.Range{{Off: 16, Len: 4}})
+		assert.ErrorIs(t.T(), err, io.EOF)
+		assert.NoError(t.T(), f.Close())
+
+		f, err = mfs.OpenFile("fox.txt", fs.O_WRONLY, modePerm)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+		_, err = f.(*File).ReadAtMulti([]fs.Range{{Off: 0, Len: 3}})
+		assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+		assert.NoError(t.T(), f.Close())
+
+		assert.NoError(t.T(), mfs.WriteFile("a.txt", []byte("AAAAAAAA"), modePerm))
+		f, err = mfs.OpenFile("a.txt", fs.O_RDONLY, 0)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		bufs, err = f.(*File).ReadAtMulti([]fs.Range{{Off: 0, Len: 8}})
+		assert.NoError(t.T(), err)
+		assert.NoError(t.T(), f.Close())
+		assert.NoError(t.T(), mfs.Remove("a.txt"))
+
+		for i := range 5 {
+			assert.NoError(t.T(), mfs.WriteFile(fmt.Sprintf("b%d.txt", i), []byte("BBBBBBBB"), modePerm))
+		}
+		assert.Equal(t.T(), "AAAAAAAA", string(bufs[0]))
+	}
+}
+
+func (t *MemFSTestSuite) TestReadWriteInterleaved() {
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.NotEmpty(t.T(), b)
+
+	assert.NoError(t.T(), t.mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	f, err := t.mfs.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer f.Close()
+
+	p := make([]byte, 5)
+	n, err := f.(fs.File).ReadAt(p, 16)
+	assert.ErrorIs(t.T(), err, io.EOF)
+	assert.Equal(t.T(), "fox", string(p[:n]))
+
+	n, err = f.(fs.File).ReadAt(p, 100)
+	assert.ErrorIs(t.T(), err, io.EOF)
+	assert.Equal(t.T(), 0, n)
+
+	_, err = f.(fs.File).ReadAt(p, -1)
+	assert.ErrorIs(t.T(), err, fs.ErrInvalid)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := t.mfs.ReadFile("doc/fox.txt")
+			assert.NoError(t.T(), err)
+			assert.Equal(t.T(), "the quick brown fox", string(b))
+		}()
+	}
+	wg.Wait()
+
+	assert.NoError(t.T(), t.mfs.WriteFile("doc/fox.txt", nil, modePerm))
+	b, err = t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), b)
+}
+
+func (t *MemFSTestSuite) TestBufferPool() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	content := []byte(strings.Repeat("the quick brown fox ", 1000))
+	for i := range 10 {
+		name := fmt.Sprintf("doc/fox-%d.txt", i)
+		assert.NoError(t.T(), mfs.WriteFile(name, content, modePerm))
+
+		b, err := mfs.ReadFile(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), content, b)
+		assert.NoError(t.T(), mfs.Remove(name))
+	}
+
+	stats := mfs.Stats()
+	assert.Equal(t.T(), uint64(10), stats.BufferGets)
+	assert.Equal(t.T(), uint64(10), stats.BufferPuts)
+	assert.Positive(t.T(), stats.BufferHits)
+
+	// The content of a file that is removed while it is open is released once the file is closed.
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", content, modePerm))
+	f, err := mfs.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), mfs.Remove("doc/fox.txt"))
+	assert.Equal(t.T(), uint64(10), mfs.Stats().BufferPuts)
+
+	b := make([]byte, 9)
+	_, err = f.Read(b)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick", string(b))
+	assert.NoError(t.T(), f.Close())
+	assert.Equal(t.T(), uint64(11), mfs.Stats().BufferPuts)
+
+	// Files grown by several writes do not expose the previous content of pooled buffers.
+	w, err := mfs.Create("doc/wolf.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	for range 3 {
+		_, err := w.Write([]byte("the big bad wolf "))
+		assert.NoError(t.T(), err)
+	}
+	assert.NoError(t.T(), w.Close())
+
+	b, err = mfs.ReadFile("doc/wolf.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), strings.Repeat("the big bad wolf ", 3), string(b))
+}
+
+func (t *MemFSTestSuite) TestLookupCache() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	dir := strings.Repeat("dir/", 12) + "deep"
+	name := dir + "/fox.txt"
+	assert.NoError(t.T(), mfs.MkdirAll(dir, modePerm))
+	assert.NoError(t.T(), mfs.WriteFile(name, []byte("the quick brown fox"), modePerm))
+
+	for range 3 {
+		fi, err := mfs.Stat(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "fox.txt", fi.Name())
+	}
+	assert.Positive(t.T(), mfs.Stats().LookupHits)
+
+	// Renaming an ancestor invalidates the cached entries below it.
+	moved := "moved/" + strings.TrimPrefix(name, "dir/")
+	assert.NoError(t.T(), mfs.MkdirAll("moved", modePerm))
+	assert.NoError(t.T(), mfs.Rename("dir/dir", "moved/dir"))
+
+	_, err = mfs.Stat(name)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	b, err := mfs.ReadFile(moved)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	// Removing an ancestor invalidates the cached entries below it.
+	assert.NoError(t.T(), mfs.RemoveAll("moved/dir"))
+
+	_, err = mfs.Stat(moved)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	// Entries created at a removed path are found instead of the removed entries.
+	assert.NoError(t.T(), mfs.MkdirAll(fs.Dir(mfs, moved), modePerm))
+	assert.NoError(t.T(), mfs.WriteFile(moved, []byte("the lazy dog"), modePerm))
+
+	b, err = mfs.ReadFile(moved)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+}
+
+func (t *MemFSTestSuite) TestWriteFiles() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := make(map[string][]byte)
+	for i := range 100 {
+		files[fmt.Sprintf("fixtures/%d/%d.txt", i%10, i)] = []byte(fmt.Sprintf("fixture %d", i))
+	}
+	files["fox.txt"] = []byte("the quick brown fox")
+	assert.NoError(t.T(), fs.WriteAll(mfs, files, 0644))
+
+	for name, data := range files {
+		b, err := mfs.ReadFile(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), data, b)
+	}
+
+	fi, err := mfs.Stat("fixtures/3")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+	assert.Equal(t.T(), gofs.FileMode(0755), fi.Mode().Perm())
+
+	// Existing files are replaced, and files can not be written over directories or below files.
+	assert.NoError(t.T(), mfs.WriteFiles(map[string][]byte{"fox.txt": []byte("the lazy dog")}, 0644))
+	b, err := mfs.ReadFile("fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+
+	assert.ErrorIs(t.T(), mfs.WriteFiles(map[string][]byte{"fixtures": nil}, 0644), fs.ErrIsDir)
+	assert.ErrorIs(t.T(), mfs.WriteFiles(map[string][]byte{"fox.txt/dog.txt": nil}, 0644), fs.ErrNotDir)
+}
+
+func (t *MemFSTestSuite) TestConcurrentLookups() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				_, err := mfs.Stat("doc/fox.txt")
+				assert.NoError(t.T(), err)
+
+				_, err = mfs.ReadDir("doc")
+				assert.NoError(t.T(), err)
+
+				b, err := mfs.ReadFile("doc/fox.txt")
+				assert.NoError(t.T(), err)
+				assert.Equal(t.T(), "the quick brown fox", string(b))
+			}
+		}()
+	}
+
+	// Lookups of entries that are not changed are not affected by concurrent changes to the structure of the tree.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := range 100 {
+			dir := fmt.Sprintf("doc/tmp-%d/a/b", i)
+			assert.NoError(t.T(), mfs.MkdirAll(dir, modePerm))
+			assert.NoError(t.T(), mfs.Rename(fmt.Sprintf("doc/tmp-%d", i), fmt.Sprintf("doc/old-%d", i)))
+			assert.NoError(t.T(), mfs.RemoveAll(fmt.Sprintf("doc/old-%d", i)))
+		}
+	}()
+	wg.Wait()
+
+	entries, err := mfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+}
+
+func (t *MemFSTestSuite) TestIterateDir() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := make(map[string][]byte)
+	var want []string
+	for i := range 50 {
+		name := fmt.Sprintf("doc/%02d.txt", i)
+		files[name] = []byte(name)
+		want = append(want, name)
+	}
+	assert.NoError(t.T(), mfs.WriteFiles(files, modePerm))
+
+	var got []string
+	for e, err := range fs.IterateDir(mfs, "doc") {
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), int64(len(e.Path())), e.Size())
+		got = append(got, e.Path())
+	}
+	assert.Equal(t.T(), want, got)
+
+	// Entries can be changed while the directory is iterated.
+	n := 0
+	for e, err := range fs.IterateDir(mfs, "doc") {
+		assert.NoError(t.T(), err)
+		assert.NoError(t.T(), mfs.Remove(e.Path()))
+		n++
+	}
+	assert.Equal(t.T(), 50, n)
+
+	_, err = mfs.IterateDir("missing")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestIterateDirSorted() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), mfs.WriteFiles(map[string][]byte{
+		"doc/a.txt": []byte("aaa"),
+		"doc/b.txt": []byte("b"),
+		"doc/c.txt": []byte("cc"),
+	}, modePerm))
+
+	it, err := mfs.IterateDirSorted("doc", fs.SortByName, false)
+	assert.NoError(t.T(), err)
+	assert.IsType(t.T(), &dirIterator{}, it)
+
+	it, err = mfs.IterateDirSorted("doc", fs.SortBySize, true)
+	assert.NoError(t.T(), err)
+
+	entries, err := it.NextN(2)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "a.txt", entries[0].Name())
+	assert.Equal(t.T(), "c.txt", entries[1].Name())
+
+	entries, err = it.NextN(2)
+	assert.ErrorIs(t.T(), err, io.EOF)
+	assert.Equal(t.T(), "b.txt", entries[0].Name())
+	assert.False(t.T(), it.HasNext())
+
+	_, err = mfs.IterateDirSorted("missing", fs.SortBySize, false)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestReadFromPreallocates() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	content := []byte(strings.Repeat("the quick brown fox ", 50000))
+	readFrom := func(name string, r io.Reader) uint64 {
+		gets := mfs.Stats().BufferGets
+
+		f, err := mfs.Create(name)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		n, err := f.ReadFrom(r)
+		assert.NoError(t.T(), err)
+		assert.NoError(t.T(), f.Close())
+
+		b, err := mfs.ReadFile(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), int64(len(b)), n)
+		assert.True(t.T(), bytes.HasPrefix(content, b))
+		return mfs.Stats().BufferGets - gets
+	}
+
+	// Readers with a known length are copied into a single buffer.
+	assert.Equal(t.T(), uint64(1), readFrom("sized.txt", bytes.NewReader(content)))
+	assert.Equal(t.T(), uint64(1), readFrom("limited.txt", io.LimitReader(bytes.NewReader(content), 1000)))
+	assert.Equal(t.T(), uint64(1), readFrom("bounded.txt", io.LimitReader(struct{ io.Reader }{bytes.NewReader(content)}, 1<<20)))
+
+	// Readers with an unknown length grow the buffer as the content is copied.
+	assert.Greater(t.T(), readFrom("unsized.txt", struct{ io.Reader }{bytes.NewReader(content)}), uint64(1))
+}
+
+func (t *MemFSTestSuite) TestMmapThreshold() {
+	mfs, err := New(WithMmapThreshold(1 << 20))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.WriteFile("small.txt", []byte("the quick brown fox"), modePerm))
+	assert.Zero(t.T(), mfs.Stats().MappedBytes)
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 200000)
+	assert.NoError(t.T(), mfs.WriteFile("large.txt", content, modePerm))
+
+	mapped := mfs.Stats().MappedBytes
+	if mapped == 0 {
+		t.T().Skip("memory mappings are not supported on this platform")
+	}
+	assert.GreaterOrEqual(t.T(), mapped, uint64(len(content)))
+
+	b, err := mfs.ReadFile("large.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), content, b)
+
+	// Files that grow are moved to a larger mapping, and the previous mapping is released.
+	f, err := mfs.Create("grown.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	for range 8 {
+		_, err := f.Write(content[:1<<20])
+		assert.NoError(t.T(), err)
+	}
+	assert.NoError(t.T(), f.Close())
+	assert.Less(t.T(), mfs.Stats().MappedBytes, mapped+2*(8<<20))
+
+	b, err = mfs.ReadFile("grown.txt")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), b, 8<<20)
+
+	// A mapped buffer returned by Mmap is not unmapped until it is released, even if the file is removed.
+	mf, err := mfs.OpenFile("large.txt", fs.O_RDONLY, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	data, release, err := mf.(fs.MmapFile).Mmap()
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), mf.Close())
+
+	mapped = mfs.Stats().MappedBytes
+	assert.NoError(t.T(), mfs.Remove("large.txt"))
+	assert.Equal(t.T(), content, data)
+	assert.Equal(t.T(), mapped, mfs.Stats().MappedBytes)
+
+	assert.NoError(t.T(), release())
+	assert.NoError(t.T(), release())
+	assert.Less(t.T(), mfs.Stats().MappedBytes, mapped)
+	assert.NoError(t.T(), mfs.Remove("grown.txt"))
+	assert.Zero(t.T(), mfs.Stats().MappedBytes)
+}
+
+func (t *MemFSTestSuite) TestCompact() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 1<<15)
+	for _, name := range []string{"fox.txt", "doc/fox.txt", "empty.txt"} {
+		assert.NoError(t.T(), mfs.WriteFile(name, content, modePerm))
+	}
+
+	// Truncated files retain the buffer that held their previous content until they are compacted.
+	assert.NoError(t.T(), mfs.WriteFile("fox.txt", []byte("the quick brown fox"), modePerm))
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", content[:5000], modePerm))
+	assert.NoError(t.T(), mfs.WriteFile("empty.txt", nil, modePerm))
+
+	assert.Equal(t.T(), int64(3*(1<<20)-(1<<12)-(1<<13)), mfs.Compact())
+	assert.Equal(t.T(), uint64(3*(1<<20)-(1<<12)-(1<<13)), mfs.Stats().CompactedBytes)
+	assert.Zero(t.T(), mfs.Compact())
+
+	b, err := mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), content[:5000], b)
+
+	b, err = mfs.ReadFile("fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	assert.NoError(t.T(), mfs.WriteFile("empty.txt", []byte("the lazy dog"), modePerm))
+
+	b, err = mfs.ReadFile("empty.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+}
+
+func (t *MemFSTestSuite) TestAutoCompact() {
+	mfs, err := New(WithAutoCompact(0.5))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 1<<15)
+	assert.NoError(t.T(), mfs.WriteFile("fox.txt", content, modePerm))
+	assert.NoError(t.T(), mfs.WriteFile("fox.txt", content[:600000], modePerm))
+	assert.Zero(t.T(), mfs.Stats().CompactedBytes)
+
+	assert.NoError(t.T(), mfs.WriteFile("fox.txt", content[:5000], modePerm))
+	assert.Equal(t.T(), uint64(1<<20-1<<13), mfs.Stats().CompactedBytes)
+	assert.Zero(t.T(), mfs.Compact())
+
+	b, err := mfs.ReadFile("fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), content[:5000], b)
+}
+
+func (t *MemFSTestSuite) TestUsage() {
+	mfs, err := New(WithQuota(1 << 20))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	total, used, free, files, err := mfs.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []uint64{1 << 20, 0, 1 << 20, 0}, []uint64{total, used, free, files})
+
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", bytes.Repeat([]byte("the quick brown fox "), 1000), modePerm))
+
+	total, used, free, files, err = mfs.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []uint64{1 << 20, 1 << 15, 1<<20 - 1<<15, 2}, []uint64{total, used, free, files})
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 30000)
+	assert.NoError(t.T(), mfs.WriteFile("large.txt", content[:200000], modePerm))
+
+	f, err := mfs.OpenFile("large.txt", fs.O_WRONLY|fs.O_APPEND, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write(content[:300000])
+	assert.NoError(t.T(), err)
+
+	_, used, _, _, err = mfs.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(1<<15+1<<19), used)
+
+	_, err = f.Write(content)
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+	assert.NoError(t.T(), f.Close())
+
+	assert.NoError(t.T(), mfs.Remove("large.txt"))
+
+	_, used, _, files, err = mfs.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []uint64{1 << 15, 2}, []uint64{used, files})
+
+	unlimited, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	total, _, free, _, err = unlimited.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(math.MaxUint64), total)
+	assert.Equal(t.T(), uint64(math.MaxUint64), free)
+}
+
+func (t *MemFSTestSuite) TestAppendTruncate() {
+	assert.NoError(t.T(), t.mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	f, err := t.mfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_APPEND, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write([]byte(" jumps"))
+	assert.NoError(t.T(), err)
+
+	b, err := t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox jumps", string(b))
+
+	assert.NoError(t.T(), f.(*File).Truncate(9))
+	_, err = f.Write([]byte(" dog"))
+	assert.NoError(t.T(), err)
+
+	b, err = t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick dog", string(b))
+
+	assert.NoError(t.T(), f.(*File).Truncate(16))
+	b, err = t.mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick dog\x00\x00\x00", string(b))
+
+	assert.ErrorIs(t.T(), f.(*File).Truncate(-1), fs.ErrInvalid)
+	assert.NoError(t.T(), f.Close())
+
+	// Content removed by truncating the file is not exposed by a write beyond the end of the file.
+	f, err = t.mfs.Create("doc/hello.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write([]byte("hello world"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.(*File).Truncate(0))
+
+	_, err = f.(*File).Seek(11, io.SeekStart)
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte("x"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	b, err = t.mfs.ReadFile("doc/hello.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), strings.Repeat("\x00", 11)+"x", string(b))
+}
+
+func (t *MemFSTestSuite) TestDigest() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), modePerm))
+
+	want := sha256.Sum256([]byte("the quick brown fox"))
+	for range 2 {
+		digest, err := mfs.Digest("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), want[:], digest)
+	}
+	assert.Equal(t.T(), uint64(1), mfs.Stats().DigestHits)
+
+	f, err := mfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_APPEND, modePerm)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write([]byte(" jumps"))
+	assert.NoError(t.T(), err)
+
+	want = sha256.Sum256([]byte("the quick brown fox jumps"))
+	digest, err := mfs.Digest("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), want[:], digest)
+
+	_, err = f.Write([]byte(" over"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	want = sha256.Sum256([]byte("the quick brown fox jumps over"))
+	digest, err = mfs.Digest("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), want[:], digest)
+	assert.Equal(t.T(), uint64(1), mfs.Stats().DigestHits)
+
+	_, err = mfs.Digest("doc")
+	assert.ErrorIs(t.T(), err, fs.ErrIsDir)
+
+	_, err = mfs.Digest("doc/missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *MemFSTestSuite) TestArena() {
+	mfs, err := New(WithArena(1<<20 + 1<<14))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	total, used, _, _, err := mfs.Usage()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []uint64{1<<20 + 1<<14, 0}, []uint64{total, used})
+
+	assert.NoError(t.T(), mfs.WriteFile("fox.txt", []byte("the quick brown fox"), modePerm))
+	assert.Equal(t.T(), uint64(1<<12), mfs.Stats().ArenaBytes)
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 25000)
+	assert.NoError(t.T(), mfs.WriteFile("large.txt", content, modePerm))
+	assert.Equal(t.T(), uint64(1<<12+1<<19), mfs.Stats().ArenaBytes)
+
+	b, err := mfs.ReadFile("large.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), content, b)
+
+	// The arena has no free block for a second large file.
+	assert.ErrorIs(t.T(), mfs.WriteFile("large2.txt", append(content, content...), modePerm), fs.ErrQuotaExceeded)
+
+	// Blocks are merged with their buddies when they are released, so that the whole arena can be allocated again.
+	assert.NoError(t.T(), mfs.Remove("fox.txt"))
+	assert.NoError(t.T(), mfs.Remove("large.txt"))
+	assert.NoError(t.T(), mfs.Remove("large2.txt"))
+	assert.Zero(t.T(), mfs.Stats().ArenaBytes)
+
+	assert.NoError(t.T(), mfs.WriteFile("large.txt", bytes.Repeat([]byte("the quick brown fox "), 50000), modePerm))
+	assert.Equal(t.T(), uint64(1<<20), mfs.Stats().ArenaBytes)
+
+	// Files that grow are moved to larger blocks of the arena, which requires a free block while the current block is
+	// still allocated, so the arena is exhausted before the quota it reports.
+	f, err := mfs.Create("grown.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for range 2 {
+		_, err := f.Write(content[:1<<12])
+		assert.NoError(t.T(), err)
+	}
+
+	_, err = f.Write(content[:1<<12])
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+	assert.NoError(t.T(), f.Close())
+
+	fi, err := mfs.Stat("grown.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(1<<13), fi.Size())
+	assert.Equal(t.T(), uint64(1<<20+1<<13), mfs.Stats().ArenaBytes)
+}
+
+func (t *MemFSTestSuite) TestMapFS() {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fixtures := fstest.MapFS{
+		"doc":             {Mode: gofs.ModeDir | 0750, ModTime: mtime},
+		"doc/fox.txt":     {Data: []byte("the quick brown fox"), Mode: 0640, ModTime: mtime},
+		"doc/a/b/dog.txt": {Data: []byte("the lazy dog"), Mode: 0600, ModTime: mtime},
+		"empty.txt":       {Mode: 0644, ModTime: mtime},
+	}
+
+	mfs, err := FromMapFS(fixtures)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), fstest.TestFS(mfs, "doc/fox.txt", "doc/a/b/dog.txt", "empty.txt"))
+
+	fi, err := mfs.Stat("doc")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.ModeDir|0750, fi.Mode())
+	assert.True(t.T(), mtime.Equal(fi.ModTime()))
+
+	fi, err = mfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.FileMode(0640), fi.Mode())
+	assert.True(t.T(), mtime.Equal(fi.ModTime()))
+
+	// Snapshots list every entry, including directories that were created implicitly.
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0640))
+
+	snapshot, err := ToMapFS(mfs)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	var names []string
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t.T(), []string{"doc", "doc/a", "doc/a/b", "doc/fox.txt", "doc/a/b/dog.txt", "empty.txt"}, names)
+	assert.Equal(t.T(), "the quick brown fox jumps", string(snapshot["doc/fox.txt"].Data))
+	assert.Equal(t.T(), gofs.FileMode(0600), snapshot["doc/a/b/dog.txt"].Mode)
+	assert.True(t.T(), snapshot["doc/a"].Mode.IsDir())
+
+	_, err = FromMapFS(fstest.MapFS{"link": {Mode: gofs.ModeSymlink}})
+	assert.ErrorIs(t.T(), err, fs.ErrUnsupported)
+}
+
+func (t *MemFSTestSuite) TestMkfifo() {
+	mfs := t.mfs.(*MemFS)
+	assert.NoError(t.T(), mfs.MkdirAll("run", 0755))
+	assert.NoError(t.T(), mfs.Mkfifo("run/fox.fifo", 0600))
+	assert.ErrorIs(t.T(), mfs.Mkfifo("run/fox.fifo", 0600), gofs.ErrExist)
+	assert.ErrorIs(t.T(), mfs.Mkfifo("missing/fox.fifo", 0600), gofs.ErrNotExist)
+
+	fi, err := mfs.Stat("run/fox.fifo")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.ModeNamedPipe|0600, fi.Mode())
+
+	// Opening the pipe for reading blocks until it is opened for writing.
+	content := bytes.Repeat([]byte("the quick brown fox "), 8192)
+	done := make(chan []byte)
+	go func() {
+		b, err := mfs.ReadFile("run/fox.fifo")
+		assert.NoError(t.T(), err)
+		done <- b
+	}()
+
+	w, err := mfs.OpenFile("run/fox.fifo", fs.O_WRONLY, 0)
+	assert.NoError(t.T(), err)
+
+	n, err := w.Write(content)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), len(content), n)
+
+	_, err = w.Seek(0, io.SeekStart)
+	assert.ErrorIs(t.T(), err, syscall.ESPIPE)
+	assert.NoError(t.T(), w.Close())
+	assert.Equal(t.T(), content, <-done)
+
+	// Opening the pipe for reading and writing does not block.
+	rw, err := mfs.OpenFile("run/fox.fifo", fs.O_RDWR, 0)
+	assert.NoError(t.T(), err)
+
+	go func() {
+		r, err := mfs.Open("run/fox.fifo")
+		assert.NoError(t.T(), err)
+
+		b := make([]byte, 9)
+		_, err = io.ReadFull(r, b)
+		assert.NoError(t.T(), err)
+		assert.NoError(t.T(), r.Close())
+		done <- b
+	}()
+
+	_, err = rw.Write([]byte("the lazy dog"))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy ", string(<-done))
+
+	b := make([]byte, 16)
+	n, err = rw.Read(b)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "dog", string(b[:n]))
+	assert.ErrorIs(t.T(), rw.(*File).Truncate(0), fs.ErrInvalidEntryType)
+
+	// Writing to a pipe that is no longer open for reading fails.
+	w, err = mfs.OpenFile("run/fox.fifo", fs.O_WRONLY, 0)
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), rw.Close())
+
+	_, err = w.Write([]byte("the lazy dog"))
+	assert.ErrorIs(t.T(), err, syscall.EPIPE)
+	assert.NoError(t.T(), w.Close())
+
+	snapshot, err := ToMapFS(mfs)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.ModeNamedPipe|0600, snapshot["run/fox.fifo"].Mode)
+
+	cfs, err := FromMapFS(snapshot)
+	assert.NoError(t.T(), err)
+
+	fi, err = cfs.Stat("run/fox.fifo")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), gofs.ModeNamedPipe|0600, fi.Mode())
+}
+
+func (t *MemFSTestSuite) TestWAL() {
+	osfs, err := fs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	log := filepath.Join(t.T().TempDir(), "memfs.wal")
+
+	mfs, err := New(WithWAL(osfs, log))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), mfs.MkdirAll("doc/pets", 0750))
+	assert.NoError(t.T(), mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), mfs.WriteFiles(map[string][]byte{"tmp/a.txt": []byte("a"), "tmp/b.txt": []byte("b")}, 0600))
+	assert.NoError(t.T(), mfs.Chmod("doc/fox.txt", 0600))
+	assert.NoError(t.T(), mfs.SetMetadata("doc/fox.txt", map[string]string{"color": "brown"}))
+	assert.NoError(t.T(), mfs.Mkfifo("doc/pets/cat.fifo", 0600))
+	assert.NoError(t.T(), mfs.RemoveAll("tmp"))
+
+	f, err := mfs.OpenFile("empty.txt", fs.O_WRONLY|fs.O_CREATE, 0644)
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	// The content of a file that was renamed while it was open is recorded for its new path.
+	f, err = mfs.Create("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), mfs.Rename("doc/dog.txt", "doc/pets/dog.txt"))
+
+	_, err = f.Write([]byte("the lazy dog"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	// Changes made using a File that is not closed are not recorded.
+	f, err = mfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_APPEND, 0)
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte(" jumps"))
+	assert.NoError(t.T(), err)
+
+	expected := map[string]string{
+		"doc/fox.txt":      "the quick brown fox",
+		"doc/pets/dog.txt": "the lazy dog",
+		"empty.txt":        "",
+	}
+
+	verify := func(mfs *MemFS) {
+		snapshot, err := ToMapFS(mfs)
+		if err != nil {
+			t.T().Fatal(err)
+		}
+
+		var names []string
+		for name := range snapshot {
+			names = append(names, name)
+		}
+		assert.ElementsMatch(t.T(), []string{"doc", "doc/fox.txt", "doc/pets", "doc/pets/cat.fifo", "doc/pets/dog.txt",
+			"empty.txt"}, names)
+
+		for name, content := range expected {
+			assert.Equal(t.T(), content, string(snapshot[name].Data), name)
+		}
+		assert.Equal(t.T(), gofs.FileMode(0600), snapshot["doc/fox.txt"].Mode)
+		assert.Equal(t.T(), gofs.ModeDir|0750, snapshot["doc/pets"].Mode)
+		assert.Equal(t.T(), gofs.ModeNamedPipe|0600, snapshot["doc/pets/cat.fifo"].Mode)
+
+		fi, err := mfs.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), map[string]string{"color": "brown"}, fi.(*fs.Entry).Attributes().Metadata())
+	}
+
+	replayed, err := New(WithWAL(osfs, log))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	verify(replayed)
+	assert.NoError(t.T(), replayed.Close())
+
+	// Records that were only partially written are removed from the log.
+	assert.NoError(t.T(), f.Close())
+	assert.NoError(t.T(), mfs.Close())
+	expected["doc/fox.txt"] = "the quick brown fox jumps"
+
+	fi, err := os.Stat(log)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := os.Truncate(log, fi.Size()-1); err != nil {
+		t.T().Fatal(err)
+	}
+
+	replayed, err = New(WithWAL(osfs, log))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	expected["doc/fox.txt"] = "the quick brown fox"
+	verify(replayed)
+
+	// A checkpoint replaces the log with the current content.
+	for i := range 100 {
+		assert.NoError(t.T(), replayed.WriteFile("doc/fox.txt", []byte(fmt.Sprintf("the quick brown fox %d", i)), 0600))
+	}
+	expected["doc/fox.txt"] = "the quick brown fox 99"
+
+	sub, err := replayed.Sub("doc")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), sub.(fs.FS).WriteFile("pets/dog.txt", []byte("the lazy dog sleeps"), 0644))
+	expected["doc/pets/dog.txt"] = "the lazy dog sleeps"
+
+	before, err := os.Stat(log)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), replayed.Checkpoint())
+	assert.NoError(t.T(), replayed.Close())
+
+	after, err := os.Stat(log)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Less(t.T(), after.Size(), before.Size())
+
+	replayed, err = New(WithWAL(osfs, log))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	verify(replayed)
+	assert.NoError(t.T(), replayed.Close())
+
+	assert.ErrorIs(t.T(), t.mfs.(*MemFS).Checkpoint(), fs.ErrUnsupported)
+}
+
+func (t *MemFSTestSuite) TestWithContext() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := make(map[string][]byte)
+	for i := range 3 * ctxCheckInterval {
+		files[fmt.Sprintf("doc/%05d.txt", i)] = nil
+	}
+	assert.NoError(t.T(), mfs.WriteFiles(files, 0644))
+
+	// A view bound to a context that is not done behaves as the MemFS.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	view := fs.WithContext(ctx, mfs)
+
+	matches, err := view.Glob("doc/*.txt")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), matches, len(files))
+
+	entries, err := view.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, len(files))
+
+	page, token, err := view.(fs.ReadDirPager).ReadDirPage("doc", 10, "")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), page, 10)
+	assert.Equal(t.T(), "00009.txt", token)
+
+	f, err := view.Create("doc/zeros.bin")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	// The copy stops once the context is canceled while content is read.
+	errStop := errors.New("stopped")
+	r := &cancelingReader{Reader: bytes.NewReader(make([]byte, 1<<20)), cancel: func() { cancel(errStop) }}
+	n, err := f.ReadFrom(r)
+	assert.ErrorIs(t.T(), err, errStop)
+	assert.Less(t.T(), n, int64(1<<20))
+	assert.NoError(t.T(), f.Close())
+
+	_, err = view.Glob("doc/*.txt")
+	assert.ErrorIs(t.T(), err, errStop)
+
+	_, err = view.ReadDir("doc")
+	assert.ErrorIs(t.T(), err, errStop)
+
+	_, _, err = view.(fs.ReadDirPager).ReadDirPage("doc", 10, token)
+	assert.ErrorIs(t.T(), err, errStop)
+
+	sub, err := view.Sub("doc")
+	assert.NoError(t.T(), err)
+
+	_, err = sub.(fs.FS).ReadDir(".")
+	assert.ErrorIs(t.T(), err, errStop)
+
+	// Operations of the MemFS are not bound to the context.
+	entries, err = mfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, len(files)+1)
+}
+
+func (t *MemFSTestSuite) TestIndexedDir() {
+	mfs, err := New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	const n = 10 * dirIndexBlockSize
+	files := make(map[string][]byte)
+	for i := range n {
+		files[fmt.Sprintf("doc/%05d.txt", i)] = nil
+	}
+	files["doc/sub/01000.txt"] = nil
+	assert.NoError(t.T(), mfs.WriteFiles(files, modePerm))
+
+	doc, err := mfs.Sub("doc")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	index := &doc.(*MemFS).index
+	assert.Greater(t.T(), len(index.blocks), 1)
+
+	// Pages of the entries starting with a prefix.
+	var (
+		names []string
+		token string
+	)
+	for {
+		entries, next, err := mfs.ReadDirPrefix("doc", "012", 30, token)
+		assert.NoError(t.T(), err)
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Len(t.T(), names, 100)
+	assert.Equal(t.T(), "01200.txt", names[0])
+	assert.Equal(t.T(), "01299.txt", names[99])
+
+	entries, next, err := mfs.ReadDirPrefix("doc", "s", 10, "")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+	assert.True(t.T(), entries[0].IsDir())
+	assert.Empty(t.T(), next)
+
+	entries, _, err = mfs.ReadDirPrefix("doc", "012", 10, "01300.txt")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+
+	// Pages continue after the token without reading the entries before it.
+	entries, next, err = mfs.ReadDirPage("doc", 2, "04998.txt")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 2)
+	assert.Equal(t.T(), "04999.txt", entries[0].Name())
+	assert.Equal(t.T(), "05000.txt", next)
+
+	// Glob reads only the entries starting with the literal prefix of each element.
+	matches, err := mfs.Glob("doc/0100?.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{
+		"doc/01000.txt", "doc/01001.txt", "doc/01002.txt", "doc/01003.txt", "doc/01004.txt",
+		"doc/01005.txt", "doc/01006.txt", "doc/01007.txt", "doc/01008.txt", "doc/01009.txt",
+	}, matches)
+
+	matches, err = mfs.Glob("*/*/01000.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/sub/01000.txt"}, matches)
+
+	matches, err = mfs.Glob("doc/00000.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/00000.txt"}, matches)
+
+	_, err = mfs.Glob("doc/[")
+	assert.ErrorIs(t.T(), err, filepath.ErrBadPattern)
+
+	// The index follows the entries that are removed and renamed.
+	for i := range n / 2 {
+		assert.NoError(t.T(), mfs.Remove(fmt.Sprintf("doc/%05d.txt", 2*i)))
+	}
+	assert.NoError(t.T(), mfs.Rename("doc/sub/01000.txt", "doc/01000.txt"))
+
+	var indexed []string
+	for c := index.seek("", false); ; c = index.next(c) {
+		name, ok := index.at(c)
+		if !ok {
+			break
+		}
+		indexed = append(indexed, name)
+	}
+	assert.Equal(t.T(), doc.(*MemFS).entries.Values(), indexed)
+
+	entries, err = mfs.ReadDir("doc")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, n/2+2)
+	assert.Equal(t.T(), "00001.txt", entries[0].Name())
+	assert.Equal(t.T(), "01000.txt", entries[500].Name())
+}
+
+// cancelingReader calls cancel once it has been read.
+type cancelingReader struct {
+	io.Reader
+	cancel func()
+}
+
+func (r *cancelingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.cancel()
+	return n, err
+}
@@ -0,0 +1,130 @@
+package memfs
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// dirIndexBlockSize is the maximum number of names held by a block of a dirIndex, which bounds the number of names
+// that are moved when a name is added to or removed from a directory with a large number of entries.
+const dirIndexBlockSize = 512
+
+// dirIndex is a sorted index of the names of the entries of a directory, which finds the position of a name in
+// logarithmic time, so that the entries following a name or starting with a prefix are listed without scanning the
+// entries before them.
+//
+// Names are held in sorted blocks of at most dirIndexBlockSize names. The version is incremented each time the index
+// changes, so that cursors held across changes can be found again.
+type dirIndex struct {
+	blocks  [][]string
+	version uint64
+}
+
+// indexCursor is the position of a name in a dirIndex.
+type indexCursor struct {
+	block int
+	pos   int
+}
+
+// insert adds name to the index, if it is not already present.
+func (x *dirIndex) insert(name string) {
+	if len(x.blocks) == 0 {
+		x.blocks = [][]string{{name}}
+		x.version++
+		return
+	}
+
+	b := min(x.block(name), len(x.blocks)-1)
+	i, found := slices.BinarySearch(x.blocks[b], name)
+	if found {
+		return
+	}
+
+	blk := slices.Insert(x.blocks[b], i, name)
+	if len(blk) > dirIndexBlockSize {
+		half := len(blk) / 2
+		x.blocks = slices.Insert(x.blocks, b+1, slices.Clone(blk[half:]))
+		blk = blk[:half]
+	}
+	x.blocks[b] = blk
+	x.version++
+}
+
+// remove removes name from the index, if it is present.
+func (x *dirIndex) remove(name string) {
+	b := x.block(name)
+	if b == len(x.blocks) {
+		return
+	}
+
+	i, found := slices.BinarySearch(x.blocks[b], name)
+	if !found {
+		return
+	}
+
+	if x.blocks[b] = slices.Delete(x.blocks[b], i, i+1); len(x.blocks[b]) == 0 {
+		x.blocks = slices.Delete(x.blocks, b, b+1)
+	}
+	x.version++
+}
+
+// seek returns the cursor of the first name that is not less than name, or, if after is true, of the first name that
+// is greater than name.
+func (x *dirIndex) seek(name string, after bool) indexCursor {
+	b := x.block(name)
+	if b == len(x.blocks) {
+		return indexCursor{block: b}
+	}
+
+	i, found := slices.BinarySearch(x.blocks[b], name)
+	if found && after {
+		i++
+	}
+	return x.normalize(indexCursor{block: b, pos: i})
+}
+
+// seekPrefix returns the cursor of the first name that starts with prefix and is greater than token, or the first name
+// that starts with prefix if token is empty.
+func (x *dirIndex) seekPrefix(prefix string, token string) indexCursor {
+	if token != "" && token >= prefix {
+		return x.seek(token, true)
+	}
+	return x.seek(prefix, false)
+}
+
+// at returns the name at c, and whether c is the position of a name.
+func (x *dirIndex) at(c indexCursor) (string, bool) {
+	if c.block >= len(x.blocks) {
+		return "", false
+	}
+	return x.blocks[c.block][c.pos], true
+}
+
+// next returns the cursor of the name following the name at c.
+func (x *dirIndex) next(c indexCursor) indexCursor {
+	c.pos++
+	return x.normalize(c)
+}
+
+// hasPrefix reports whether the name at c starts with prefix.
+func (x *dirIndex) hasPrefix(c indexCursor, prefix string) bool {
+	name, ok := x.at(c)
+	return ok && strings.HasPrefix(name, prefix)
+}
+
+// block returns the index of the first block whose last name is not less than name, or the number of blocks if every
+// name is less than name.
+func (x *dirIndex) block(name string) int {
+	return sort.Search(len(x.blocks), func(i int) bool {
+		return x.blocks[i][len(x.blocks[i])-1] >= name
+	})
+}
+
+// normalize moves a cursor past the end of a block to the first name of the following block.
+func (x *dirIndex) normalize(c indexCursor) indexCursor {
+	if c.block < len(x.blocks) && c.pos >= len(x.blocks[c.block]) {
+		return indexCursor{block: c.block + 1}
+	}
+	return c
+}
@@ -5,60 +5,88 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 
-	"github.com/transientvariable/hold"
 	"github.com/transientvariable/fs-go"
 )
 
+// dirIterator iterates over the entries of a directory in the order of the index of their names. The cursor is found
+// again after the name of the last entry returned if the directory changed since, so that iteration continues in order
+// when entries are added or removed.
 type dirIterator struct {
-	iter hold.Iterator[string]
-	mfs  *MemFS
+	cursor  indexCursor
+	last    string
+	mfs     *MemFS
+	mutex   *sync.RWMutex
+	started bool
+	version uint64
 }
 
 func newDirIterator(mfs *MemFS) fs.DirIterator {
 	return &dirIterator{
-		iter: mfs.entries.Iterate(),
-		mfs:  mfs,
+		cursor:  mfs.index.seek("", false),
+		mfs:     mfs,
+		version: mfs.index.version,
+	}
+}
+
+// newLockedDirIterator creates a dirIterator that locks mutex for reading while each entry is retrieved, for iterators
+// that are used after the lookup of the directory has completed.
+func newLockedDirIterator(mfs *MemFS, mutex *sync.RWMutex) fs.DirIterator {
+	return &dirIterator{
+		cursor:  mfs.index.seek("", false),
+		mfs:     mfs,
+		mutex:   mutex,
+		version: mfs.index.version,
 	}
 }
 
 // HasNext returns whether the directory has remaining entries.
 func (i *dirIterator) HasNext() bool {
-	return i.iter.HasNext()
+	if i.mutex != nil {
+		i.mutex.RLock()
+		defer i.mutex.RUnlock()
+	}
+
+	i.sync()
+	_, ok := i.mfs.index.at(i.cursor)
+	return ok
 }
 
 // Next returns the next directory fs.Entry. Dot entries "." are skipped.
 //
 // The error io.EOF is returned if there are no remaining entries left to iterate.
 func (i *dirIterator) Next() (*fs.Entry, error) {
-	if !i.HasNext() {
-		return nil, io.EOF
+	if i.mutex != nil {
+		i.mutex.RLock()
+		defer i.mutex.RUnlock()
 	}
+	return i.next()
+}
 
-	v, err := i.iter.Next()
-	if err != nil {
-		if errors.Is(err, hold.ErrNotFound) {
-			return nil, io.EOF
-		}
-		return nil, err
+func (i *dirIterator) next() (*fs.Entry, error) {
+	i.sync()
+	name, ok := i.mfs.index.at(i.cursor)
+	if !ok {
+		return nil, io.EOF
 	}
 
-	if v == "." {
-		return i.Next()
-	}
+	i.cursor = i.mfs.index.next(i.cursor)
+	i.last = name
+	i.started = true
+	return dirEntry(i.mfs, name)
+}
 
-	e, err := i.mfs.entries.Entry(v)
-	if err != nil {
-		return nil, err
+// sync finds the cursor again after the last entry returned if the directory changed since the cursor was found, and
+// moves it past the dot entry.
+func (i *dirIterator) sync() {
+	if i.version != i.mfs.index.version {
+		i.cursor = i.mfs.index.seek(i.last, i.started)
+		i.version = i.mfs.index.version
 	}
 
-	switch e.Data().(type) {
-	case *MemFS:
-		return e.Data().(*MemFS).entry, nil
-	case *fd:
-		return e.Data().(*fd).entry, nil
-	default:
-		return nil, fmt.Errorf("dir_iterator: %s: %w", reflect.ValueOf(e.Data()).Type(), fs.ErrInvalidEntryType)
+	if name, ok := i.mfs.index.at(i.cursor); ok && name == "." {
+		i.cursor = i.mfs.index.next(i.cursor)
 	}
 }
 
@@ -81,9 +109,74 @@ func (i *dirIterator) NextN(n int) ([]*fs.Entry, error) {
 	for i.HasNext() {
 		e, err := i.Next()
 		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
 			return entries, err
 		}
 		entries = append(entries, e)
 	}
 	return entries, nil
 }
+
+// dirEntry returns a copy of the fs.Entry for the named entry of the directory mfs. The caller must hold the read lock
+// of the MemFS.
+func dirEntry(mfs *MemFS, name string) (*fs.Entry, error) {
+	e, err := mfs.entries.Entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Data().(type) {
+	case *MemFS:
+		return snapshot(e.Data(), e.Data().(*MemFS).entry), nil
+	case *fd:
+		return snapshot(e.Data(), e.Data().(*fd).entry), nil
+	default:
+		return nil, fmt.Errorf("dir_iterator: %s: %w", reflect.ValueOf(e.Data()).Type(), fs.ErrInvalidEntryType)
+	}
+}
+
+// sortedDirIterator iterates over a sorted snapshot of the entries of a directory.
+type sortedDirIterator struct {
+	entries []*fs.Entry
+}
+
+// HasNext returns whether the snapshot has remaining entries.
+func (i *sortedDirIterator) HasNext() bool {
+	return len(i.entries) > 0
+}
+
+// Next returns the next fs.Entry of the snapshot.
+//
+// The error io.EOF is returned if there are no remaining entries left to iterate.
+func (i *sortedDirIterator) Next() (*fs.Entry, error) {
+	if len(i.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	e := i.entries[0]
+	i.entries = i.entries[1:]
+	return e, nil
+}
+
+// NextN returns a slice containing the next n entries of the snapshot, or every remaining entry if n is not positive.
+//
+// The error io.EOF is returned if fewer than n entries remain.
+func (i *sortedDirIterator) NextN(n int) ([]*fs.Entry, error) {
+	if n <= 0 {
+		entries := i.entries
+		i.entries = nil
+		return entries, nil
+	}
+
+	if n > len(i.entries) {
+		entries := i.entries
+		i.entries = nil
+		return entries, io.EOF
+	}
+
+	entries := i.entries[:n:n]
+	i.entries = i.entries[n:]
+	return entries, nil
+}
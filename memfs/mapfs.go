@@ -0,0 +1,132 @@
+package memfs
+
+import (
+	"errors"
+	"slices"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// FromMapFS creates a MemFS with the content of m, so that fixtures defined using fstest.MapFS can be used where a
+// writable file system is required. The options are applied to the MemFS in the same way as for New.
+//
+// The mode and modification time of each file and directory in m are preserved. Parent directories that are not
+// listed in m are created with the permissions returned by fs.DirPerm for the default file permissions. An error
+// wrapping fs.ErrUnsupported is returned if m contains an entry that is not a regular file, a named pipe, or a
+// directory. Named pipes are created using Mkfifo, and their data is ignored.
+func FromMapFS(m fstest.MapFS, opts ...func(*MemFS)) (*MemFS, error) {
+	mfs, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// Directory attributes are applied once the tree has been created, since creating the entries of a directory
+	// changes its modification time.
+	var dirs []string
+	for _, name := range names {
+		f := m[name]
+		if f == nil {
+			return nil, fs.NewOpError(providerName, "fromMapFS", name, gofs.ErrInvalid)
+		}
+
+		switch {
+		case f.Mode.IsDir():
+			if err := mfs.MkdirAll(name, fs.DirPerm(modePerm)); err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, name)
+		case f.Mode.IsRegular(), f.Mode&gofs.ModeNamedPipe != 0:
+			if dir := fs.Dir(mfs, name); dir != "." {
+				if err := mfs.MkdirAll(dir, fs.DirPerm(modePerm)); err != nil {
+					return nil, err
+				}
+			}
+
+			if f.Mode.IsRegular() {
+				err = mfs.WriteFile(name, f.Data, f.Mode.Perm())
+			} else {
+				err = mfs.Mkfifo(name, f.Mode.Perm())
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err := applyMapFile(mfs, name, f); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fs.NewOpError(providerName, "fromMapFS", name, fs.ErrUnsupported)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyMapFile(mfs, dirs[i], m[dirs[i]]); err != nil {
+			return nil, err
+		}
+	}
+	return mfs, nil
+}
+
+// ToMapFS returns a snapshot of the content of m as an fstest.MapFS, so that the content can be compared with the
+// expected content of a test, or used with functions that require a read-only file system.
+//
+// Every file and directory in m is listed with its mode and modification time, and the content of each file is
+// copied, so that later changes to m do not change the snapshot.
+func ToMapFS(m *MemFS) (fstest.MapFS, error) {
+	if m == nil {
+		return nil, errors.New("memfs: file system is required")
+	}
+
+	snapshot := make(fstest.MapFS)
+	err := fs.Walk(m, ".", func(p string, entry *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == "." {
+			return nil
+		}
+
+		f := &fstest.MapFile{Mode: entry.Mode(), ModTime: entry.ModTime()}
+		if entry.Mode().IsRegular() {
+			if f.Data, err = m.ReadFile(p); err != nil {
+				return err
+			}
+		}
+		snapshot[p] = f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// applyMapFile applies the mode and modification time of f to the named file or directory.
+func applyMapFile(mfs *MemFS, name string, f *fstest.MapFile) error {
+	fe, err := mfs.entryOf("fromMapFS", name)
+	if err != nil {
+		return err
+	}
+
+	e := fe.entry
+
+	if err := e.Attributes().SetMode(e.Mode().Type() | f.Mode&^gofs.ModeType); err != nil {
+		return fs.NewOpError(providerName, "fromMapFS", name, err)
+	}
+
+	if !f.ModTime.IsZero() {
+		fs.WithMtime(f.ModTime)(e.Attributes())
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package memfs
+
+import (
+	"errors"
+)
+
+// mmap is not supported on this platform, so buffers are always allocated on the Go heap.
+func mmap(_ int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// munmap is not supported on this platform.
+func munmap(_ []byte) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package memfs
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// mmap returns a buffer of n bytes backed by an anonymous private memory mapping, which is not allocated on the Go
+// heap.
+func mmap(n int) ([]byte, error) {
+	return unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+}
+
+// munmap releases a buffer returned by mmap.
+func munmap(b []byte) error {
+	return unix.Munmap(b)
+}
@@ -0,0 +1,141 @@
+package memfs
+
+import (
+	"io"
+	"sync"
+	"syscall"
+
+	"github.com/transientvariable/fs-go"
+)
+
+// pipeCapacity is the number of bytes that can be written to a named pipe before a write blocks until the content is
+// read, which is the default capacity of pipes on Linux.
+const pipeCapacity = 64 << 10
+
+// pipe is the content of a named pipe created using MemFS.Mkfifo, which transfers the bytes written by its writers to
+// its readers in the order in which they were written, without storing them in the file.
+//
+// As with named pipes on POSIX systems, opening a pipe for reading blocks until it is opened for writing, and opening
+// it for writing blocks until it is opened for reading, unless it is opened for both.
+type pipe struct {
+	buf     []byte
+	cond    *sync.Cond
+	mutex   sync.Mutex
+	readers int
+	ropens  uint64
+	writers int
+	wopens  uint64
+}
+
+func newPipe() *pipe {
+	p := &pipe{}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+// open records that the pipe was opened using flag, and waits until the other end of the pipe is opened, unless it is
+// already open or the pipe was opened for both reading and writing.
+func (p *pipe) open(flag int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	read, write := pipeAccess(flag)
+	if read {
+		p.readers++
+		p.ropens++
+	}
+
+	if write {
+		p.writers++
+		p.wopens++
+	}
+	p.cond.Broadcast()
+
+	switch {
+	case read && !write:
+		for opens := p.wopens; p.writers == 0 && p.wopens == opens; {
+			p.cond.Wait()
+		}
+	case write && !read:
+		for opens := p.ropens; p.readers == 0 && p.ropens == opens; {
+			p.cond.Wait()
+		}
+	}
+}
+
+// close records that a File opened for the pipe using flag was closed, so that blocked readers observe the end of the
+// content once every writer is closed, and blocked writers fail once every reader is closed.
+func (p *pipe) close(flag int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	read, write := pipeAccess(flag)
+	if read {
+		p.readers--
+	}
+
+	if write {
+		p.writers--
+	}
+
+	// The content that was not read is discarded once the pipe is no longer open, as for named pipes on POSIX systems.
+	if p.readers == 0 && p.writers == 0 {
+		p.buf = nil
+	}
+	p.cond.Broadcast()
+}
+
+// read reads up to len(b) bytes from the pipe, waiting until content is written if the pipe is empty. io.EOF is
+// returned if the pipe is empty and is not open for writing.
+func (p *pipe) read(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.buf) == 0 && p.writers > 0 {
+		p.cond.Wait()
+	}
+
+	if len(p.buf) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// write writes b to the pipe, waiting until content is read while the pipe is full. An error wrapping syscall.EPIPE is
+// returned if the pipe is not open for reading.
+func (p *pipe) write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var n int
+	for n < len(b) {
+		for len(p.buf) >= pipeCapacity && p.readers > 0 {
+			p.cond.Wait()
+		}
+
+		if p.readers == 0 {
+			return n, syscall.EPIPE
+		}
+
+		c := min(len(b)-n, pipeCapacity-len(p.buf))
+		p.buf = append(p.buf, b[n:n+c]...)
+		n += c
+		p.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// pipeAccess reports whether a pipe opened using flag is opened for reading and for writing.
+func pipeAccess(flag int) (bool, bool) {
+	switch flag & (fs.O_RDONLY | fs.O_WRONLY | fs.O_RDWR) {
+	case fs.O_WRONLY:
+		return false, true
+	case fs.O_RDWR:
+		return true, true
+	}
+	return true, false
+}
@@ -0,0 +1,256 @@
+package memfs
+
+import (
+	"math/bits"
+	"os"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/transientvariable/fs-go"
+)
+
+const (
+	// minChunkShift is the base 2 logarithm of the size of the smallest buffer provided by a bufferPool (4 KiB).
+	minChunkShift = 12
+
+	// maxChunkShift is the base 2 logarithm of the size of the largest buffer provided by a bufferPool (64 MiB). Larger
+	// buffers are allocated and released without being pooled.
+	maxChunkShift = 26
+)
+
+// bufferPool provides the buffers that hold file content, so that the buffers of files that are removed or grown can
+// be reused instead of being collected. Buffers are pooled by size class, where each class is a power of 2.
+//
+// If a mapping threshold is set, buffers of at least that many bytes are backed by anonymous memory mappings instead,
+// so that large files are not allocated on the Go heap. Mapped buffers are not pooled, and are unmapped when they are
+// returned to the pool.
+//
+// If an arena is set, every buffer is allocated from the arena instead, and is returned to the arena when it is
+// returned to the pool.
+type bufferPool struct {
+	arena         *arena
+	classes       [maxChunkShift - minChunkShift + 1]sync.Pool
+	gets          atomic.Uint64
+	hits          atomic.Uint64
+	logger        fs.Logger
+	mapped        map[*byte]struct{}
+	mappedBytes   atomic.Int64
+	mmapThreshold int
+	mutex         sync.Mutex
+	puts          atomic.Uint64
+	quota         int64
+	reclaimed     atomic.Uint64
+	reserved      int64
+	used          int64
+}
+
+// get returns a buffer with a length of at least n bytes. The content of the buffer is undefined. If the pool allocates
+// buffers from an arena, nil is returned if the arena has no free block that is large enough.
+func (p *bufferPool) get(n int) []byte {
+	if p.arena != nil {
+		p.gets.Add(1)
+		if b, ok := p.arena.alloc(n); ok {
+			return b
+		}
+		return nil
+	}
+
+	if p.mmapThreshold > 0 && n >= p.mmapThreshold {
+		if b, ok := p.mmap(n); ok {
+			return b
+		}
+	}
+
+	class, size := sizeClass(n)
+	if class < 0 {
+		return make([]byte, n)
+	}
+
+	p.gets.Add(1)
+	if b, ok := p.classes[class].Get().(*[]byte); ok {
+		p.hits.Add(1)
+		return *b
+	}
+	return make([]byte, size)
+}
+
+// put returns b to the pool. Buffers that were not provided by get are discarded.
+func (p *bufferPool) put(b []byte) {
+	if p.arena != nil && p.arena.owns(b) {
+		p.puts.Add(1)
+		p.arena.release(b)
+		return
+	}
+
+	if p.munmap(b) {
+		return
+	}
+
+	class, size := sizeClass(cap(b))
+	if class < 0 || size != cap(b) {
+		return
+	}
+
+	p.puts.Add(1)
+	b = b[:size]
+	p.classes[class].Put(&b)
+}
+
+// account records that the total length of the buffers that hold file content changed by n bytes.
+func (p *bufferPool) account(n int) {
+	if n == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.used += int64(n)
+}
+
+// capacity returns the length of the buffer provided by get for n bytes, if the buffer can be provided.
+func (p *bufferPool) capacity(n int) int {
+	if p.arena != nil {
+		return p.arena.capacity(n)
+	}
+
+	if p.mmapThreshold > 0 && n >= p.mmapThreshold {
+		page := os.Getpagesize()
+		return (n + page - 1) / page * page
+	}
+
+	_, size := sizeClass(n)
+	return size
+}
+
+// recycles returns whether b is reused or released once it is returned to the pool, in which case slices of b must not
+// be retained after b is returned.
+func (p *bufferPool) recycles(b []byte) bool {
+	if p.arena != nil && p.arena.owns(b) {
+		return true
+	}
+
+	if p.isMapped(b) {
+		return true
+	}
+
+	class, size := sizeClass(cap(b))
+	return class >= 0 && size == cap(b)
+}
+
+// isMapped returns whether b is backed by a memory mapping created by the pool.
+func (p *bufferPool) isMapped(b []byte) bool {
+	if cap(b) == 0 {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	_, ok := p.mapped[unsafe.SliceData(b)]
+	return ok
+}
+
+// log returns the Logger of the MemFS that owns the pool.
+func (p *bufferPool) log() fs.Logger {
+	if p.logger == nil {
+		return fs.NopLogger()
+	}
+	return p.logger
+}
+
+// mmap returns a mapped buffer with a length of at least n bytes, rounded up to a multiple of the page size, and
+// whether the buffer could be mapped.
+func (p *bufferPool) mmap(n int) ([]byte, bool) {
+	page := os.Getpagesize()
+	b, err := mmap((n + page - 1) / page * page)
+	if err != nil {
+		p.log().Warn("[memfs:pool] could not map buffer, allocating on heap", "size", n, "error", err)
+		return nil, false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.mapped == nil {
+		p.mapped = make(map[*byte]struct{})
+	}
+	p.mapped[unsafe.SliceData(b)] = struct{}{}
+	p.mappedBytes.Add(int64(len(b)))
+	return b, true
+}
+
+// munmap unmaps b and returns true if b is backed by a memory mapping created by the pool.
+func (p *bufferPool) munmap(b []byte) bool {
+	if cap(b) == 0 {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.mapped[unsafe.SliceData(b)]; !ok {
+		return false
+	}
+	delete(p.mapped, unsafe.SliceData(b))
+
+	b = b[:cap(b)]
+	if err := munmap(b); err != nil {
+		p.log().Error("[memfs:pool] could not unmap buffer", "size", len(b), "error", err)
+		return true
+	}
+	p.mappedBytes.Add(-int64(len(b)))
+	return true
+}
+
+// reserve reserves n bytes of the quota for a buffer that is about to replace a buffer that holds file content, and
+// returns false if the quota would be exceeded. Reserved bytes are released using unreserve once the buffer is in use.
+func (p *bufferPool) reserve(n int) bool {
+	if p.quota <= 0 || n <= 0 {
+		return true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.used+p.reserved+int64(n) > p.quota {
+		return false
+	}
+	p.reserved += int64(n)
+	return true
+}
+
+// unreserve releases n bytes of the quota that were reserved using reserve.
+func (p *bufferPool) unreserve(n int) {
+	if p.quota <= 0 || n <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.reserved -= int64(n)
+}
+
+// usage returns the total length of the buffers that hold file content.
+func (p *bufferPool) usage() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.used
+}
+
+// sizeClass returns the index of the smallest size class that holds n bytes and its size, or -1 if n is larger than
+// the largest size class.
+func sizeClass(n int) (int, int) {
+	shift := minChunkShift
+	if n > 1<<minChunkShift {
+		shift = bits.Len(uint(n - 1))
+	}
+
+	if shift > maxChunkShift {
+		return -1, n
+	}
+	return shift - minChunkShift, 1 << shift
+}
@@ -0,0 +1,534 @@
+package memfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Enumeration of the operations recorded in the write-ahead log of a MemFS.
+const (
+	walChmod byte = iota + 1
+	walChtimes
+	walMetadata
+	walMimeType
+	walMkdir
+	walMkfifo
+	walRemove
+	walRename
+	walWrite
+)
+
+// walHeaderLen is the length of the header of each record in the log, which holds the length of the payload of the
+// record and its CRC-32C checksum.
+const walHeaderLen = 8
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walRecord is a mutation of a MemFS recorded in its write-ahead log. The fields that are used depend on the operation.
+type walRecord struct {
+	atime    time.Time
+	data     []byte
+	metadata map[string]string
+	mimeType string
+	mode     gofs.FileMode
+	mtime    time.Time
+	name     string
+	newName  string
+	op       byte
+}
+
+// wal is the write-ahead log of a MemFS created using WithWAL, which is a file of the backing file system to which
+// each mutation of the MemFS is appended.
+type wal struct {
+	file  fs.File
+	fsys  fs.FS
+	mutex sync.Mutex
+	name  string
+	root  *MemFS
+}
+
+// WithWAL enables persisting the MemFS to the named write-ahead log in fsys, such as a file of the fs.OSFS, so that the
+// content of the MemFS survives restarts and crashes, while reads are still served from memory.
+//
+// When the MemFS is created, the mutations recorded in the log are replayed to restore its content, and each mutation
+// is then appended to the log and synced before the operation returns. A file is recorded when it is created, and its
+// content is recorded when a File that changed it is closed, so that changes made using a File that is not closed
+// before a crash are lost, as are records that were only partially written, which are removed from the log. Access
+// times are not recorded, and named pipes are restored without their content.
+//
+// Since the log grows with every mutation, it can be replaced by a log that records only the current content of the
+// MemFS using Checkpoint. The log is closed when the MemFS is closed, and Sub returns a file system that delegates to
+// the MemFS, so that mutations using the returned file system are also recorded.
+func WithWAL(fsys fs.FS, name string) func(*MemFS) {
+	return func(m *MemFS) {
+		if fsys != nil {
+			m.opts.wal = &wal{fsys: fsys, name: name, root: m}
+		}
+	}
+}
+
+// Checkpoint replaces the write-ahead log set using WithWAL with a log that records only the current content of the
+// MemFS, which bounds the size of the log and the time to replay it. The new log is written to a temporary file that
+// replaces the log using Rename, so that the log is never incomplete if the backing file system renames atomically.
+// Structural changes to the MemFS are blocked until the checkpoint is complete.
+func (m *MemFS) Checkpoint() error {
+	w := m.wal()
+	if w == nil {
+		return fs.NewOpError(providerName, "checkpoint", "", fs.ErrUnsupported)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var b []byte
+	for _, r := range checkpoint(m, ".") {
+		b = appendRecord(b, r)
+	}
+
+	if err := w.checkpoint(b); err != nil {
+		return fs.NewOpError(providerName, "checkpoint", w.name, err)
+	}
+	return nil
+}
+
+// wal returns the write-ahead log of the MemFS, or nil if the MemFS was not created using WithWAL.
+func (m *MemFS) wal() *wal {
+	if m.opts == nil {
+		return nil
+	}
+	return m.opts.wal
+}
+
+// log appends r to the write-ahead log of the MemFS, if it was created using WithWAL. Structural changes must be logged
+// while the MemFS is locked, so that they are recorded in the order in which they were applied.
+func (m *MemFS) log(r walRecord) error {
+	w := m.wal()
+	if w == nil {
+		return nil
+	}
+
+	if err := w.append(appendRecord(nil, r)); err != nil {
+		return fs.NewOpError(providerName, "wal", r.name, err)
+	}
+	return nil
+}
+
+// pathOf returns the path of the file descriptor d, which is name unless the file was renamed since name was resolved,
+// and whether the file exists. The caller must hold the read lock for the MemFS.
+func (m *MemFS) pathOf(name string, d *fd) (string, bool) {
+	if e, err := stat(m, name); err == nil && e.Data() == d {
+		return name, true
+	}
+	return findfd(m, ".", d)
+}
+
+// findfd returns the path of the file descriptor d in the directory mfs at path p, and whether it was found.
+func findfd(mfs *MemFS, p string, d *fd) (string, bool) {
+	for _, v := range mfs.entries.Values() {
+		if v == "." {
+			continue
+		}
+
+		e, err := entry(mfs, v)
+		if err != nil {
+			continue
+		}
+
+		name := v
+		if p != "." {
+			name = p + pathSeparator + v
+		}
+
+		switch data := e.Data().(type) {
+		case *fd:
+			if data == d {
+				return name, true
+			}
+		case *MemFS:
+			if name, ok := findfd(data, name, d); ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// open replays the log and opens it for appending. Records that were only partially written, or whose checksum does
+// not match, are removed from the end of the log.
+func (w *wal) open() error {
+	b, err := w.fsys.ReadFile(w.name)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	n, err := w.replay(b)
+	if err != nil {
+		return err
+	}
+
+	if n < len(b) {
+		w.root.logger().Warn("[memfs:wal] removing incomplete records", "name", w.name, "size", len(b)-n)
+		if err := w.fsys.WriteFile(w.name, b[:n], 0600); err != nil {
+			return err
+		}
+	}
+
+	f, err := w.fsys.OpenFile(w.name, fs.O_WRONLY|fs.O_CREATE|fs.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	return nil
+}
+
+// append writes b to the log and syncs it, if the log supports syncing. Nothing is written while the log is replayed,
+// or once it is closed.
+func (w *wal) append(b []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	if _, err := w.file.Write(b); err != nil {
+		return err
+	}
+	return syncFile(w.file)
+}
+
+// checkpoint replaces the log with b. The caller must hold the lock for the log.
+func (w *wal) checkpoint(b []byte) error {
+	if w.file == nil {
+		return gofs.ErrClosed
+	}
+
+	tmp := w.name + ".tmp"
+	f, err := w.fsys.OpenFile(tmp, fs.O_WRONLY|fs.O_CREATE|fs.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := syncFile(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := w.fsys.Rename(tmp, w.name); err != nil {
+		return err
+	}
+
+	f, err = w.fsys.OpenFile(w.name, fs.O_WRONLY|fs.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		w.root.logger().Warn("[memfs:wal] close", "name", w.name, "error", err)
+	}
+	w.file = f
+	return nil
+}
+
+// close closes the log, so that no further mutations are recorded.
+func (w *wal) close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// replay applies the records in b to the MemFS, and returns the length of the complete records in b.
+func (w *wal) replay(b []byte) (int, error) {
+	var n int
+	for len(b)-n >= walHeaderLen {
+		size := int(binary.LittleEndian.Uint32(b[n:]))
+		sum := binary.LittleEndian.Uint32(b[n+4:])
+		if size > len(b)-n-walHeaderLen {
+			break
+		}
+
+		payload := b[n+walHeaderLen : n+walHeaderLen+size]
+		if crc32.Checksum(payload, walTable) != sum {
+			break
+		}
+
+		r, err := decodeRecord(payload)
+		if err != nil {
+			return n, fmt.Errorf("memfs: wal record at offset %d: %w", n, err)
+		}
+
+		if err := w.root.apply(r); err != nil {
+			return n, fmt.Errorf("memfs: replay wal record at offset %d: %w", n, err)
+		}
+		n += walHeaderLen + size
+	}
+	return n, nil
+}
+
+// apply applies the mutation recorded by r to the MemFS.
+func (m *MemFS) apply(r walRecord) error {
+	switch r.op {
+	case walChmod:
+		return m.Chmod(r.name, r.mode)
+	case walChtimes:
+		return m.Chtimes(r.name, r.atime, r.mtime)
+	case walMetadata:
+		return m.SetMetadata(r.name, r.metadata)
+	case walMimeType:
+		return m.SetMimeType(r.name, r.mimeType)
+	case walMkdir:
+		return m.MkdirAll(r.name, r.mode.Perm())
+	case walMkfifo:
+		return m.Mkfifo(r.name, r.mode.Perm())
+	case walRemove:
+		return m.RemoveAll(r.name)
+	case walRename:
+		return m.Rename(r.name, r.newName)
+	case walWrite:
+		if err := m.WriteFile(r.name, r.data, r.mode.Perm()); err != nil {
+			return err
+		}
+
+		f, err := m.entryOf("wal", r.name)
+		if err != nil {
+			return err
+		}
+
+		e := f.entry
+
+		if err := e.Attributes().SetMode(e.Mode().Type() | r.mode&^gofs.ModeType); err != nil {
+			return err
+		}
+		fs.WithMtime(r.mtime)(e.Attributes())
+		return nil
+	}
+	return fmt.Errorf("unknown operation %d: %w", r.op, gofs.ErrInvalid)
+}
+
+// checkpoint returns the records that restore the content of the directory mfs at path p. The caller must hold the
+// write lock for the MemFS.
+func checkpoint(mfs *MemFS, p string) []walRecord {
+	var records []walRecord
+	for _, v := range mfs.entries.Values() {
+		if v == "." {
+			continue
+		}
+
+		e, err := entry(mfs, v)
+		if err != nil {
+			continue
+		}
+
+		name := v
+		if p != "." {
+			name = p + pathSeparator + v
+		}
+
+		attrs := e.entry.Attributes()
+		switch d := e.Data().(type) {
+		case *MemFS:
+			records = append(records, walRecord{op: walMkdir, name: name, mode: e.entry.Mode()})
+			records = append(records, checkpoint(d, name)...)
+		case *fd:
+			if d.pipe != nil {
+				records = append(records, walRecord{op: walMkfifo, name: name, mode: e.entry.Mode()})
+			} else {
+				records = append(records, walRecord{
+					op:    walWrite,
+					name:  name,
+					mode:  e.entry.Mode(),
+					mtime: e.entry.ModTime(),
+					data:  d.content(),
+				})
+			}
+
+			if mt := attrs.MimeType(); mt != "" {
+				records = append(records, walRecord{op: walMimeType, name: name, mimeType: mt})
+			}
+		default:
+			continue
+		}
+
+		if md := attrs.Metadata(); len(md) > 0 {
+			records = append(records, walRecord{op: walMetadata, name: name, metadata: md})
+		}
+		records = append(records, walRecord{op: walChtimes, name: name, atime: attrs.Atime(), mtime: e.entry.ModTime()})
+	}
+	return records
+}
+
+// appendRecord appends the encoding of r to b, which is a header with the length and checksum of the payload, followed
+// by the payload holding the operation and each field of r.
+func appendRecord(b []byte, r walRecord) []byte {
+	start := len(b)
+	b = append(b, make([]byte, walHeaderLen)...)
+
+	b = append(b, r.op)
+	b = appendBytes(b, []byte(r.name))
+	b = appendBytes(b, []byte(r.newName))
+	b = binary.AppendUvarint(b, uint64(r.mode))
+	b = binary.AppendVarint(b, unixNano(r.atime))
+	b = binary.AppendVarint(b, unixNano(r.mtime))
+	b = appendBytes(b, []byte(r.mimeType))
+
+	b = binary.AppendUvarint(b, uint64(len(r.metadata)))
+	for _, k := range slices.Sorted(maps.Keys(r.metadata)) {
+		b = appendBytes(b, []byte(k))
+		b = appendBytes(b, []byte(r.metadata[k]))
+	}
+	b = appendBytes(b, r.data)
+
+	payload := b[start+walHeaderLen:]
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[start+4:], crc32.Checksum(payload, walTable))
+	return b
+}
+
+// appendBytes appends the length of v followed by v to b.
+func appendBytes(b []byte, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// decodeRecord decodes the payload of a record encoded using appendRecord.
+func decodeRecord(b []byte) (walRecord, error) {
+	d := &walDecoder{b: b}
+
+	r := walRecord{op: d.byte()}
+	r.name = string(d.bytes())
+	r.newName = string(d.bytes())
+	r.mode = gofs.FileMode(d.uvarint())
+	r.atime = fromUnixNano(d.varint())
+	r.mtime = fromUnixNano(d.varint())
+	r.mimeType = string(d.bytes())
+
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		r.metadata = make(map[string]string, min(n, uint64(len(b))))
+		for range n {
+			k := string(d.bytes())
+			r.metadata[k] = string(d.bytes())
+			if d.err != nil {
+				break
+			}
+		}
+	}
+	r.data = d.bytes()
+	return r, d.err
+}
+
+// walDecoder decodes the fields of a record, recording the first error.
+type walDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *walDecoder) byte() byte {
+	if d.err != nil || len(d.b) == 0 {
+		d.fail()
+		return 0
+	}
+
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *walDecoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.b)) {
+		d.fail()
+		return nil
+	}
+
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *walDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *walDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *walDecoder) fail() {
+	if d.err == nil {
+		d.err = io.ErrUnexpectedEOF
+	}
+}
+
+// syncFile commits the content of f to stable storage if f implements a Sync method, as do files of the fs.OSFS.
+func syncFile(f fs.File) error {
+	if s, ok := f.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// unixNano returns t as the number of nanoseconds since the Unix epoch, or 0 if t is the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano returns the time for the number of nanoseconds since the Unix epoch returned by unixNano.
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,262 @@
+package fs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"slices"
+	"strings"
+	"sync"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// DigestFS is implemented by file systems that can provide the SHA-256 digest of the content of a file without the
+// caller reading the content, for example from a cache or from metadata stored with the file.
+type DigestFS interface {
+	FS
+
+	// Digest returns the SHA-256 digest of the content of the named regular file.
+	Digest(name string) ([]byte, error)
+}
+
+// MerkleNode is an entry of a tree whose digest is computed by MerkleTree.
+//
+// The children and digest of a node are computed lazily when they are first requested, and are retained by the node,
+// so a node reflects the state of the tree at that time.
+type MerkleNode struct {
+	children []*MerkleNode
+	digest   []byte
+	fsys     gofs.FS
+	hashed   bool
+	listed   bool
+	mode     gofs.FileMode
+	mutex    sync.Mutex
+	name     string
+	path     string
+}
+
+// MerkleTree returns the node for the entry at root in fsys, whose digest is the digest of the tree rooted at root.
+//
+// Digests are computed using SHA-256 in the same way as HashTree: the digest of a regular file is the digest of its
+// content, the digest of a directory is the digest of a record for each of its entries, in lexical order, consisting
+// of the mode, name, and digest of the entry, and other entries have no digest. Subtrees with equal digests therefore
+// have the same structure and content, so that callers comparing two trees, such as Sync, can skip unchanged subtrees
+// by comparing the digests of their roots, and only descend into the children of directories whose digests differ.
+//
+// Nothing is read from fsys until the digest or children of a node are requested. If fsys implements the Digest method
+// of DigestFS, the digests of files are provided by the file system, such as the digests cached by MemFS, instead of
+// being computed from their content.
+func MerkleTree(fsys gofs.FS, root string) (*MerkleNode, error) {
+	fi, err := gofs.Stat(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleNode{fsys: fsys, mode: fi.Mode(), name: gopath.Base(root), path: root}, nil
+}
+
+// MerkleDiff returns the paths of the entries that differ between the trees rooted at a and b, relative to their roots
+// and sorted in lexical order.
+//
+// Subtrees whose digests are equal are skipped. An entry is reported if it only exists in one of the trees, if its type
+// or permissions differ, or if it is a file whose content differs, and the roots are reported as "." if their types or
+// permissions differ. The children of a directory that only exists in one of the trees, or whose type or permissions
+// differ, are not reported.
+func MerkleDiff(a *MerkleNode, b *MerkleNode) ([]string, error) {
+	var paths []string
+	if err := merkleDiff(a, b, "", &paths); err != nil {
+		return nil, err
+	}
+
+	// If the roots differ by type or permissions, the path of the roots is the only path reported.
+	if len(paths) == 1 && paths[0] == "" {
+		paths[0] = "."
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// Child returns the child of the node with the provided name, or nil if the node is not a directory or has no such
+// child.
+func (n *MerkleNode) Child(name string) (*MerkleNode, error) {
+	children, err := n.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	i, ok := slices.BinarySearchFunc(children, name, func(c *MerkleNode, name string) int {
+		return strings.Compare(c.name, name)
+	})
+	if !ok {
+		return nil, nil
+	}
+	return children[i], nil
+}
+
+// Children returns the children of the node in lexical order of their names. Only directories have children.
+func (n *MerkleNode) Children() ([]*MerkleNode, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return n.list()
+}
+
+// Digest returns the digest of the entry, which is nil if the entry is neither a regular file nor a directory.
+func (n *MerkleNode) Digest() ([]byte, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.hashed {
+		return n.digest, nil
+	}
+
+	switch {
+	case n.mode.IsDir():
+		children, err := n.list()
+		if err != nil {
+			return nil, err
+		}
+
+		h := sha256.New()
+		for _, c := range children {
+			digest, err := c.Digest()
+			if err != nil {
+				return nil, err
+			}
+			writeTreeRecord(h, c.name, c.mode, digest)
+		}
+		n.digest = h.Sum(nil)
+	case n.mode.IsRegular():
+		var (
+			digest []byte
+			err    error
+		)
+		if d, ok := n.fsys.(interface{ Digest(string) ([]byte, error) }); ok {
+			digest, err = d.Digest(n.path)
+		} else {
+			digest, err = HashFile(n.fsys, n.path, crypto.SHA256)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		n.digest = digest
+	}
+	n.hashed = true
+	return n.digest, nil
+}
+
+// Mode returns the mode of the entry.
+func (n *MerkleNode) Mode() gofs.FileMode {
+	return n.mode
+}
+
+// Name returns the base name of the entry.
+func (n *MerkleNode) Name() string {
+	return n.name
+}
+
+// Path returns the path of the entry in the file system.
+func (n *MerkleNode) Path() string {
+	return n.path
+}
+
+// list returns the children of the node, reading them from the file system if they were not read. The caller must hold
+// the mutex.
+func (n *MerkleNode) list() ([]*MerkleNode, error) {
+	if n.listed || !n.mode.IsDir() {
+		return n.children, nil
+	}
+
+	entries, err := gofs.ReadDir(n.fsys, n.path)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]*MerkleNode, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, &MerkleNode{
+			fsys: n.fsys,
+			mode: fi.Mode(),
+			name: e.Name(),
+			path: gopath.Join(n.path, e.Name()),
+		})
+	}
+	slices.SortFunc(children, func(a *MerkleNode, b *MerkleNode) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	n.children = children
+	n.listed = true
+	return n.children, nil
+}
+
+// merkleDiff appends the paths of the entries that differ between a and b, whose path relative to the roots of the
+// trees is p, to paths. The path of the roots is empty.
+func merkleDiff(a *MerkleNode, b *MerkleNode, p string, paths *[]string) error {
+	name := func(c *MerkleNode) string {
+		if p == "" {
+			return c.name
+		}
+		return p + "/" + c.name
+	}
+
+	if a.mode&(gofs.ModeType|gofs.ModePerm) != b.mode&(gofs.ModeType|gofs.ModePerm) {
+		*paths = append(*paths, p)
+		return nil
+	}
+
+	da, err := a.Digest()
+	if err != nil {
+		return err
+	}
+
+	db, err := b.Digest()
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(da, db) {
+		return nil
+	}
+
+	if !a.mode.IsDir() {
+		*paths = append(*paths, p)
+		return nil
+	}
+
+	ca, err := a.Children()
+	if err != nil {
+		return err
+	}
+
+	cb, err := b.Children()
+	if err != nil {
+		return err
+	}
+
+	i, j := 0, 0
+	for i < len(ca) || j < len(cb) {
+		switch {
+		case j == len(cb) || (i < len(ca) && ca[i].name < cb[j].name):
+			*paths = append(*paths, name(ca[i]))
+			i++
+		case i == len(ca) || cb[j].name < ca[i].name:
+			*paths = append(*paths, name(cb[j]))
+			j++
+		default:
+			if err := merkleDiff(ca[i], cb[j], name(ca[i]), paths); err != nil {
+				return err
+			}
+			i++
+			j++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package fs_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleTree(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, mfs.WriteFile("doc/animals/dog.txt", []byte("the lazy dog"), 0644))
+
+	root, err := fs.MerkleTree(mfs, "doc")
+	assert.NoError(t, err)
+	assert.Equal(t, "doc", root.Name())
+	assert.True(t, root.Mode().IsDir())
+
+	children, err := root.Children()
+	assert.NoError(t, err)
+	assert.Len(t, children, 2)
+	assert.Equal(t, "doc/animals", children[0].Path())
+	assert.Equal(t, "doc/fox.txt", children[1].Path())
+
+	fox, err := root.Child("fox.txt")
+	assert.NoError(t, err)
+
+	digest, err := fox.Digest()
+	assert.NoError(t, err)
+	want := sha256.Sum256([]byte("the quick brown fox"))
+	assert.Equal(t, want[:], digest)
+
+	missing, err := root.Child("missing.txt")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	digest, err = root.Digest()
+	assert.NoError(t, err)
+
+	copied, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.CopyAll(copied, "backup", mfs, "doc"))
+
+	other, err := fs.MerkleTree(copied, "backup")
+	assert.NoError(t, err)
+
+	otherDigest, err := other.Digest()
+	assert.NoError(t, err)
+	assert.Equal(t, digest, otherDigest)
+
+	assert.NoError(t, copied.WriteFile("backup/animals/dog.txt", []byte("the lazy cat"), 0644))
+	other, err = fs.MerkleTree(copied, "backup")
+	assert.NoError(t, err)
+
+	otherDigest, err = other.Digest()
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, otherDigest)
+}
+
+func TestMerkleDiff(t *testing.T) {
+	a, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mfs := range []*memfs.MemFS{a, b} {
+		assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+		assert.NoError(t, mfs.WriteFile("doc/animals/dog.txt", []byte("the lazy dog"), 0644))
+		assert.NoError(t, mfs.WriteFile("pictures/seals.png", []byte("seals"), 0644))
+	}
+
+	diff := func() []string {
+		ta, err := fs.MerkleTree(a, ".")
+		assert.NoError(t, err)
+
+		tb, err := fs.MerkleTree(b, ".")
+		assert.NoError(t, err)
+
+		paths, err := fs.MerkleDiff(ta, tb)
+		assert.NoError(t, err)
+		return paths
+	}
+	assert.Empty(t, diff())
+
+	assert.NoError(t, b.WriteFile("doc/animals/dog.txt", []byte("the lazy cat"), 0644))
+	assert.NoError(t, b.WriteFile("doc/animals/bird.txt", []byte("the bird"), 0644))
+	assert.NoError(t, b.Chmod("doc/fox.txt", 0600))
+	assert.NoError(t, b.RemoveAll("pictures"))
+	assert.NoError(t, b.WriteFile("pictures", []byte("seals"), 0644))
+	assert.Equal(t, []string{"doc/animals/bird.txt", "doc/animals/dog.txt", "doc/fox.txt", "pictures"}, diff())
+}
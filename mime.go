@@ -0,0 +1,66 @@
+package fs
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// sniffLen is the maximum number of bytes of content used by DetectMimeType.
+const sniffLen = 512
+
+// MimeTypeFS is implemented by a file system that stores the MIME type of its files, which is provided by the
+// Attribute of the Entry returned by Stat.
+type MimeTypeFS interface {
+	FS
+
+	// SetMimeType sets the MIME type of the named file.
+	SetMimeType(name string, mimeType string) error
+}
+
+// DetectMimeType returns the MIME type of the named file with the provided content. The type is determined from the
+// extension of name if it is known, and is otherwise determined by sniffing the first 512 bytes of data using
+// http.DetectContentType, which returns "application/octet-stream" if no other type matches.
+func DetectMimeType(name string, data []byte) string {
+	if t := mime.TypeByExtension(gopath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data[:min(len(data), sniffLen)])
+}
+
+// WithMimeDetection enables detecting the MIME type of a file copied by CopyFile or CopyAll using DetectMimeType. The
+// type is stored if the destination file system implements MimeTypeFS.
+func WithMimeDetection() CopyOption {
+	return func(o *copyOptions) {
+		o.mimeDetection = true
+	}
+}
+
+// detectMimeType sets the MIME type of dstPath in dst, which was copied from srcPath in src, if MIME type detection is
+// enabled and dst implements MimeTypeFS.
+func detectMimeType(dst FS, dstPath string, src gofs.FS, srcPath string, opts *copyOptions) error {
+	if !opts.mimeDetection {
+		return nil
+	}
+
+	m, ok := dst.(MimeTypeFS)
+	if !ok {
+		return nil
+	}
+
+	f, err := src.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	return m.SetMimeType(dstPath, DetectMimeType(dstPath, head[:n]))
+}
@@ -0,0 +1,45 @@
+package fs_test
+
+import (
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMimeType(t *testing.T) {
+	assert.Equal(t, "image/png", fs.DetectMimeType("seals.png", nil))
+	assert.Equal(t, "text/plain; charset=utf-8", fs.DetectMimeType("fox", []byte("the quick brown fox")))
+	assert.Equal(t, "image/gif", fs.DetectMimeType("hulkbuster", []byte("GIF89a...")))
+	assert.Equal(t, "application/octet-stream", fs.DetectMimeType("blob", []byte{0x00, 0x01, 0x02}))
+}
+
+func TestCopyFileMimeDetection(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.WriteFile("doc/fox", []byte("<html><body>the quick brown fox</body></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, fs.CopyFile(dst, "fox", src, "doc/fox", fs.WithMimeDetection()))
+
+	fi, err := dst.Stat("fox")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", fi.(*fs.Entry).Attributes().MimeType())
+
+	assert.NoError(t, fs.CopyFile(dst, "plain", src, "doc/fox"))
+
+	fi, err = dst.Stat("plain")
+	assert.NoError(t, err)
+	assert.Empty(t, fi.(*fs.Entry).Attributes().MimeType())
+}
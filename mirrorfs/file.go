@@ -0,0 +1,72 @@
+package mirrorfs
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file mirrors the content of a file opened for writing through a MirrorFS when it is closed.
+type file struct {
+	fs.File
+	dirty bool
+	mfs   *MirrorFS
+	mutex sync.Mutex
+	name  string
+}
+
+func (f *file) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.dirty {
+		return nil
+	}
+	f.dirty = false
+
+	fi, err := f.mfs.primary.Stat(f.name)
+	if err != nil {
+		return fmt.Errorf("mirrorfs: %w", &gofs.PathError{Op: "close", Path: f.name, Err: err})
+	}
+
+	data, err := f.mfs.primary.ReadFile(f.name)
+	if err != nil {
+		return fmt.Errorf("mirrorfs: %w", &gofs.PathError{Op: "close", Path: f.name, Err: err})
+	}
+
+	name := f.name
+	perm := fi.Mode().Perm()
+	return f.mfs.mirror(&op{name: "writeFile", path: name, apply: func(fsys fs.FS) error {
+		return fsys.WriteFile(name, data, perm)
+	}})
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	n, err := f.File.ReadFrom(r)
+	f.markDirty(n)
+	return n, err
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b)
+	f.markDirty(int64(n))
+	return n, err
+}
+
+func (f *file) markDirty(n int64) {
+	if n > 0 {
+		f.mutex.Lock()
+		f.dirty = true
+		f.mutex.Unlock()
+	}
+}
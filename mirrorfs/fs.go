@@ -0,0 +1,318 @@
+package mirrorfs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const (
+	// DefaultQueueSize is the default number of pending operations queued for each replica in asynchronous mode.
+	DefaultQueueSize = 1024
+
+	// DefaultRetryInterval is the default interval between attempts to replay a failed operation in asynchronous
+	// mode.
+	DefaultRetryInterval = time.Second
+)
+
+var _ fs.FS = (*MirrorFS)(nil)
+
+// MirrorFS replicating provider that implements fs.FS.
+//
+// A MirrorFS applies every write operation to a primary file system and mirrors it to one or more replicas, while
+// read operations are served from the primary only. Content written using a File opened for writing is mirrored when
+// the File is closed.
+//
+// By default, operations are mirrored synchronously and an error is returned if any replica fails to apply an
+// operation. In asynchronous mode, operations are queued for each replica once they have been applied to the primary,
+// and an operation that fails is replayed until it succeeds, preserving the order of operations for the replica.
+type MirrorFS struct {
+	async         bool
+	logger        fs.Logger
+	primary       fs.FS
+	queueSize     int
+	replicas      []*replica
+	retryInterval time.Duration
+}
+
+// New creates a new MirrorFS that reads from primary and mirrors writes to replicas.
+func New(primary fs.FS, replicas []fs.FS, options ...func(*MirrorFS)) (*MirrorFS, error) {
+	if primary == nil {
+		return nil, errors.New("mirrorfs: primary file system is required")
+	}
+
+	m := &MirrorFS{logger: fs.NopLogger(), primary: primary, queueSize: DefaultQueueSize, retryInterval: DefaultRetryInterval}
+	for _, opt := range options {
+		opt(m)
+	}
+
+	for i, r := range replicas {
+		if r == nil {
+			return nil, fmt.Errorf("mirrorfs: replica %d is nil", i)
+		}
+		m.replicas = append(m.replicas, newReplica(m, i, r))
+	}
+	return m, nil
+}
+
+// Close stops replicating operations and closes the primary and replica file systems. In asynchronous mode, queued
+// operations are applied before the replicas are closed, and an error is returned for operations that could not be
+// applied.
+func (m *MirrorFS) Close() error {
+	var errs []error
+	for _, r := range m.replicas {
+		if err := r.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := m.primary.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("mirrorfs: %w", err)
+	}
+	return nil
+}
+
+// Create ...
+func (m *MirrorFS) Create(name string) (fs.File, error) {
+	return m.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Flush blocks until the operations queued for every replica have been applied. Flush returns immediately in
+// synchronous mode.
+func (m *MirrorFS) Flush() {
+	for _, r := range m.replicas {
+		r.flush()
+	}
+}
+
+// Glob ...
+func (m *MirrorFS) Glob(pattern string) ([]string, error) {
+	return m.primary.Glob(pattern)
+}
+
+// Mkdir ...
+func (m *MirrorFS) Mkdir(name string, perm gofs.FileMode) error {
+	if err := m.primary.Mkdir(name, perm); err != nil {
+		return err
+	}
+
+	return m.mirror(&op{name: "mkdir", path: name, apply: func(fsys fs.FS) error {
+		return fsys.MkdirAll(name, perm)
+	}})
+}
+
+// MkdirAll ...
+func (m *MirrorFS) MkdirAll(path string, perm gofs.FileMode) error {
+	if err := m.primary.MkdirAll(path, perm); err != nil {
+		return err
+	}
+
+	return m.mirror(&op{name: "mkdirAll", path: path, apply: func(fsys fs.FS) error {
+		return fsys.MkdirAll(path, perm)
+	}})
+}
+
+// Open ...
+func (m *MirrorFS) Open(name string) (gofs.File, error) {
+	return m.primary.Open(name)
+}
+
+// OpenFile opens the named file. If the file is opened for writing, its content is mirrored when it is closed.
+func (m *MirrorFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	f, err := m.primary.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		return f, nil
+	}
+	return &file{File: f, mfs: m, name: name, dirty: flag&(fs.O_CREATE|fs.O_TRUNC) != 0}, nil
+}
+
+// PathSeparator ...
+func (m *MirrorFS) PathSeparator() string {
+	return m.primary.PathSeparator()
+}
+
+// Pending returns the number of operations that are queued or being replayed for each replica.
+func (m *MirrorFS) Pending() []int {
+	pending := make([]int, len(m.replicas))
+	for i, r := range m.replicas {
+		pending[i] = r.pending()
+	}
+	return pending
+}
+
+// Primary returns the primary file system.
+func (m *MirrorFS) Primary() fs.FS {
+	return m.primary
+}
+
+// Provider ...
+func (m *MirrorFS) Provider() string {
+	return m.primary.Provider()
+}
+
+// ReadDir ...
+func (m *MirrorFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return m.primary.ReadDir(name)
+}
+
+// ReadFile ...
+func (m *MirrorFS) ReadFile(name string) ([]byte, error) {
+	return m.primary.ReadFile(name)
+}
+
+// Remove ...
+func (m *MirrorFS) Remove(name string) error {
+	if err := m.primary.Remove(name); err != nil {
+		return err
+	}
+
+	return m.mirror(&op{name: "remove", path: name, apply: func(fsys fs.FS) error {
+		if err := fsys.Remove(name); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}})
+}
+
+// RemoveAll ...
+func (m *MirrorFS) RemoveAll(path string) error {
+	if err := m.primary.RemoveAll(path); err != nil {
+		return err
+	}
+
+	return m.mirror(&op{name: "removeAll", path: path, apply: func(fsys fs.FS) error {
+		return fsys.RemoveAll(path)
+	}})
+}
+
+// Rename ...
+func (m *MirrorFS) Rename(oldpath string, newpath string) error {
+	if err := m.primary.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	return m.mirror(&op{name: "rename", path: oldpath, apply: func(fsys fs.FS) error {
+		return fsys.Rename(oldpath, newpath)
+	}})
+}
+
+// Replicas returns the replica file systems.
+func (m *MirrorFS) Replicas() []fs.FS {
+	replicas := make([]fs.FS, len(m.replicas))
+	for i, r := range m.replicas {
+		replicas[i] = r.fsys
+	}
+	return replicas
+}
+
+// Root ...
+func (m *MirrorFS) Root() (string, error) {
+	return m.primary.Root()
+}
+
+// Stat ...
+func (m *MirrorFS) Stat(name string) (gofs.FileInfo, error) {
+	return m.primary.Stat(name)
+}
+
+// Sub returns the sub-tree for dir from the primary file system. Since writes to the returned file system would not
+// be mirrored, a writable sub-tree is wrapped with fs.ReadOnly.
+func (m *MirrorFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := m.primary.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		return fs.ReadOnly(fsys), nil
+	}
+	return sub, nil
+}
+
+// WriteFile ...
+func (m *MirrorFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	if err := m.primary.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+
+	data = append([]byte(nil), data...)
+	return m.mirror(&op{name: "writeFile", path: name, apply: func(fsys fs.FS) error {
+		return fsys.WriteFile(name, data, perm)
+	}})
+}
+
+// mirror applies o to every replica, or queues o for every replica in asynchronous mode.
+func (m *MirrorFS) mirror(o *op) error {
+	var errs []error
+	for _, r := range m.replicas {
+		if err := r.submit(o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("mirrorfs: %w", err)
+	}
+	return nil
+}
+
+// WithAsync enables asynchronous mode for a MirrorFS, where operations are queued for each replica and replayed until
+// they succeed. If queueSize is less than or equal to zero, DefaultQueueSize is used. Once a queue is full, write
+// operations block until space is available.
+func WithAsync(queueSize int) func(*MirrorFS) {
+	return func(m *MirrorFS) {
+		m.async = true
+		if queueSize > 0 {
+			m.queueSize = queueSize
+		}
+	}
+}
+
+// WithLogger sets the Logger used by a MirrorFS to log the operations that are replayed or dropped by a replica in
+// asynchronous mode. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*MirrorFS) {
+	return func(m *MirrorFS) {
+		if logger != nil {
+			m.logger = logger
+		}
+	}
+}
+
+// WithRetryInterval sets the interval between attempts to replay a failed operation in asynchronous mode.
+func WithRetryInterval(interval time.Duration) func(*MirrorFS) {
+	return func(m *MirrorFS) {
+		if interval > 0 {
+			m.retryInterval = interval
+		}
+	}
+}
+
+// ReplicaError records an operation that could not be applied to a replica.
+type ReplicaError struct {
+	Replica int
+	Op      string
+	Path    string
+	Err     error
+}
+
+// Error returns the cause of the replica error.
+func (e *ReplicaError) Error() string {
+	return fmt.Sprintf("replica %d: %s %s: %s", e.Replica, e.Op, e.Path, e.Err)
+}
+
+// Unwrap returns the error returned by the replica.
+func (e *ReplicaError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,185 @@
+package mirrorfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// flakyFS fails the first failures calls to WriteFile.
+type flakyFS struct {
+	fs.FS
+	failures atomic.Int64
+}
+
+func (f *flakyFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	if f.failures.Add(-1) >= 0 {
+		return &gofs.PathError{Op: "writeFile", Path: name, Err: errors.New("unavailable")}
+	}
+	return f.FS.WriteFile(name, data, perm)
+}
+
+// MirrorFSTestSuite ...
+type MirrorFSTestSuite struct {
+	suite.Suite
+	primary  *memfs.MemFS
+	replicas []fs.FS
+}
+
+func NewMirrorFSTestSuite() *MirrorFSTestSuite {
+	return &MirrorFSTestSuite{}
+}
+
+func (t *MirrorFSTestSuite) SetupTest() {
+	t.primary = t.memfs()
+	t.replicas = []fs.FS{t.memfs(), t.memfs()}
+}
+
+func TestMirrorFSTestSuite(t *testing.T) {
+	suite.Run(t, NewMirrorFSTestSuite())
+}
+
+func (t *MirrorFSTestSuite) memfs() *memfs.MemFS {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return mfs
+}
+
+func (t *MirrorFSTestSuite) TestFS() {
+	m, err := New(t.primary, t.replicas)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), m.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), fstest.TestFS(m, "doc/fox.txt"))
+}
+
+func (t *MirrorFSTestSuite) TestSync() {
+	m, err := New(t.primary, t.replicas)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), m.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), m.Mkdir("pictures", 0755))
+	assert.NoError(t.T(), m.Rename("doc/fox.txt", "doc/dog.txt"))
+
+	f, err := m.Create("doc/cat.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = io.WriteString(f, "the cat")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	for _, r := range m.Replicas() {
+		b, err := r.ReadFile("doc/dog.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the quick brown fox", string(b))
+
+		b, err = r.ReadFile("doc/cat.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the cat", string(b))
+
+		fi, err := r.Stat("pictures")
+		assert.NoError(t.T(), err)
+		assert.True(t.T(), fi.IsDir())
+
+		_, err = r.Stat("doc/fox.txt")
+		assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	}
+
+	assert.NoError(t.T(), m.RemoveAll("doc"))
+	for _, r := range m.Replicas() {
+		_, err = r.Stat("doc")
+		assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	}
+}
+
+func (t *MirrorFSTestSuite) TestSyncError() {
+	m, err := New(t.primary, []fs.FS{t.replicas[0], fs.ReadOnly(t.replicas[1])})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	err = m.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644)
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+
+	var rerr *ReplicaError
+	assert.True(t.T(), errors.As(err, &rerr))
+	assert.Equal(t.T(), 1, rerr.Replica)
+
+	_, err = t.primary.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = t.replicas[0].Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+}
+
+func (t *MirrorFSTestSuite) TestAsync() {
+	flaky := &flakyFS{FS: t.replicas[1]}
+	flaky.failures.Store(3)
+
+	m, err := New(t.primary, []fs.FS{t.replicas[0], flaky}, WithAsync(8), WithRetryInterval(time.Millisecond))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), m.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), m.Rename("doc/fox.txt", "doc/dog.txt"))
+
+	m.Flush()
+	assert.Equal(t.T(), []int{0, 0}, m.Pending())
+
+	for _, r := range t.replicas {
+		b, err := r.ReadFile("doc/dog.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the quick brown fox", string(b))
+	}
+
+	assert.NoError(t.T(), m.Close())
+	assert.ErrorIs(t.T(), m.WriteFile("doc/cat.txt", nil, 0644), gofs.ErrClosed)
+}
+
+func (t *MirrorFSTestSuite) TestLogger() {
+	flaky := &flakyFS{FS: t.replicas[1]}
+	flaky.failures.Store(1)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m, err := New(t.primary, []fs.FS{t.replicas[0], flaky},
+		WithAsync(8),
+		WithLogger(logger),
+		WithRetryInterval(time.Millisecond))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), m.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	m.Flush()
+	assert.Contains(t.T(), buf.String(), `level=WARN msg="[mirrorfs] replaying operation" replica=1`)
+	assert.NoError(t.T(), m.Close())
+
+	m, err = New(t.primary, nil)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), m.logger)
+}
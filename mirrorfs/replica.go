@@ -0,0 +1,152 @@
+package mirrorfs
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// op defines an operation to mirror to a replica.
+type op struct {
+	apply func(fs.FS) error
+	name  string
+	path  string
+}
+
+// replica applies mirrored operations to a single replica file system.
+type replica struct {
+	closed   bool
+	done     chan struct{}
+	dropped  error
+	fsys     fs.FS
+	index    int
+	logger   fs.Logger
+	mutex    sync.Mutex
+	queued   atomic.Int64
+	queue    chan *op
+	retry    time.Duration
+	stop     chan struct{}
+	unsynced sync.WaitGroup
+}
+
+// newReplica creates a replica for fsys, which is the replica at index for m, using the configuration of m.
+func newReplica(m *MirrorFS, index int, fsys fs.FS) *replica {
+	r := &replica{fsys: fsys, index: index, logger: m.logger, retry: m.retryInterval}
+	if m.async {
+		r.done = make(chan struct{})
+		r.queue = make(chan *op, m.queueSize)
+		r.stop = make(chan struct{})
+		go r.run()
+	}
+	return r
+}
+
+func (r *replica) apply(o *op) error {
+	if err := o.apply(r.fsys); err != nil {
+		return &ReplicaError{Replica: r.index, Op: o.name, Path: o.path, Err: err}
+	}
+	return nil
+}
+
+func (r *replica) close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mutex.Unlock()
+
+	var errs []error
+	if r.queue != nil {
+		close(r.stop)
+		<-r.done
+
+		if r.dropped != nil {
+			errs = append(errs, r.dropped)
+		}
+
+		close(r.queue)
+		for o := range r.queue {
+			if err := r.apply(o); err != nil {
+				errs = append(errs, err)
+			}
+			r.dequeue()
+		}
+	}
+
+	if err := r.fsys.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// dequeue records that a queued operation has been applied or dropped.
+func (r *replica) dequeue() {
+	r.queued.Add(-1)
+	r.unsynced.Done()
+}
+
+func (r *replica) flush() {
+	r.unsynced.Wait()
+}
+
+func (r *replica) pending() int {
+	return int(r.queued.Load())
+}
+
+// run applies queued operations in order, replaying an operation that fails until it succeeds or the replica is
+// closed.
+func (r *replica) run() {
+	defer close(r.done)
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case o := <-r.queue:
+			for {
+				err := r.apply(o)
+				if err == nil {
+					break
+				}
+
+				r.logger.Warn("[mirrorfs] replaying operation", "replica", r.index, "error", err)
+				select {
+				case <-r.stop:
+					if err := r.apply(o); err != nil {
+						r.logger.Error("[mirrorfs] dropping operation", "replica", r.index, "error", err)
+						r.dropped = err
+					}
+					r.dequeue()
+					return
+				case <-time.After(r.retry):
+				}
+			}
+			r.dequeue()
+		}
+	}
+}
+
+func (r *replica) submit(o *op) error {
+	if r.queue == nil {
+		return r.apply(o)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return &ReplicaError{Replica: r.index, Op: o.name, Path: o.path, Err: gofs.ErrClosed}
+	}
+
+	r.queued.Add(1)
+	r.unsynced.Add(1)
+	r.queue <- o
+	return nil
+}
@@ -0,0 +1,75 @@
+package fs
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	gofs "io/fs"
+)
+
+var _ MmapFile = (*osFile)(nil)
+
+// MmapFile defines the behavior for accessing the content of a file as a byte slice without copying it, for example by
+// mapping the file into memory, so that search and indexing code can operate on the content of large files directly.
+type MmapFile interface {
+	// Mmap returns the content of the file, and a function that releases it. The content must not be modified, and must
+	// not be used once it is released. Changes to the content of the file made after Mmap is called may or may not be
+	// reflected in the returned slice.
+	Mmap() (data []byte, release func() error, err error)
+}
+
+// osFile is a file opened by OSFS.
+type osFile struct {
+	*os.File
+}
+
+// Mmap maps the content of the file into memory as read-only on platforms that support memory mappings, and returns an
+// error wrapping ErrUnsupported otherwise. The mapping remains valid after the file is closed, until it is released.
+func (f *osFile) Mmap() ([]byte, func() error, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fi.IsDir() {
+		return nil, nil, &gofs.PathError{Op: "mmap", Path: f.Name(), Err: ErrIsDir}
+	}
+
+	if fi.Size() == 0 {
+		return []byte{}, func() error { return nil }, nil
+	}
+
+	if fi.Size() > int64(MaxContentLen) {
+		return nil, nil, &gofs.PathError{Op: "mmap", Path: f.Name(), Err: ErrTooLarge}
+	}
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil, nil, &gofs.PathError{Op: "mmap", Path: f.Name(), Err: err}
+	}
+
+	var (
+		data []byte
+		merr error
+	)
+	if err := rc.Control(func(fd uintptr) {
+		data, merr = mmapFile(fd, fi.Size())
+	}); err != nil {
+		return nil, nil, &gofs.PathError{Op: "mmap", Path: f.Name(), Err: ErrClosed}
+	}
+
+	if merr != nil {
+		return nil, nil, &gofs.PathError{Op: "mmap", Path: f.Name(), Err: merr}
+	}
+	return data, sync.OnceValue(func() error { return munmapFile(data) }), nil
+}
+
+// ReadFrom reads from r until io.EOF is reached, unwrapping r if it is a file opened by OSFS, so that the content can
+// be copied by the operating system where supported (e.g. using copy_file_range on Linux).
+func (f *osFile) ReadFrom(r io.Reader) (int64, error) {
+	if o, ok := r.(*osFile); ok {
+		r = o.File
+	}
+	return f.File.ReadFrom(r)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package fs
+
+// mmapFile is not supported on this platform.
+func mmapFile(_ uintptr, _ int64) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+// munmapFile is not supported on this platform.
+func munmapFile(_ []byte) error {
+	return ErrUnsupported
+}
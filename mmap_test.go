@@ -0,0 +1,82 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMmapFile(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"fox.txt": "the quick brown fox", "empty.txt": ""} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := osfs.Open(filepath.Join(dir, "fox.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, release, err := f.(fs.MmapFile).Mmap()
+	if runtime.GOOS == "windows" {
+		assert.ErrorIs(t, err, fs.ErrUnsupported)
+		return
+	}
+	assert.NoError(t, err)
+
+	// The mapping remains valid once the file is closed.
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "the quick brown fox", string(data))
+	assert.NoError(t, release())
+	assert.NoError(t, release())
+
+	f, err = osfs.Open(filepath.Join(dir, "empty.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, release, err = f.(fs.MmapFile).Mmap()
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	assert.NoError(t, release())
+
+	d, err := osfs.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	_, _, err = d.(fs.MmapFile).Mmap()
+	assert.ErrorIs(t, err, fs.ErrIsDir)
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	mf, err := mfs.Open("fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mf.Close()
+
+	data, release, err = mf.(fs.MmapFile).Mmap()
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(data))
+	assert.NoError(t, release())
+}
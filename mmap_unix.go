@@ -0,0 +1,17 @@
+//go:build unix
+
+package fs
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// mmapFile maps the first size bytes of the file with the file descriptor fd into memory as read-only.
+func mmapFile(fd uintptr, size int64) ([]byte, error) {
+	return unix.Mmap(int(fd), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+}
+
+// munmapFile releases a mapping returned by mmapFile.
+func munmapFile(b []byte) error {
+	return unix.Munmap(b)
+}
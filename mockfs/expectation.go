@@ -0,0 +1,203 @@
+package mockfs
+
+import (
+	"fmt"
+	"reflect"
+
+	gofs "io/fs"
+)
+
+// Operation names used to record calls and set expectations. The names match the operation names used in the errors
+// returned by providers, such as "readFile" for fs.FS.ReadFile.
+const (
+	OpClose     = "close"
+	OpCreate    = "create"
+	OpGlob      = "glob"
+	OpMkdir     = "mkdir"
+	OpMkdirAll  = "mkdirAll"
+	OpOpen      = "open"
+	OpOpenFile  = "openFile"
+	OpReadDir   = "readDir"
+	OpReadFile  = "readFile"
+	OpRemove    = "remove"
+	OpRemoveAll = "removeAll"
+	OpRename    = "rename"
+	OpRoot      = "root"
+	OpStat      = "stat"
+	OpSub       = "sub"
+	OpWriteFile = "writeFile"
+)
+
+// Any matches any value of an argument of an expectation.
+var Any = anyArg{}
+
+type anyArg struct{}
+
+// Matcher is an argument of an expectation that matches the arguments of a call for which it returns true.
+type Matcher func(arg any) bool
+
+// Call records a single call made to a MockFS.
+type Call struct {
+	// Op is the name of the operation, such as OpStat.
+	Op string
+
+	// Args are the arguments passed to the operation, in the order of the parameters of the method.
+	Args []any
+}
+
+// String returns a string representation of the Call.
+func (c Call) String() string {
+	return fmt.Sprintf("%s%v", c.Op, c.Args)
+}
+
+// Result defines the canned values returned by a call that matches an expectation. Only the fields for the return
+// values of the called method are used, for example Info for Stat and Data for ReadFile.
+type Result struct {
+	// Data is returned by ReadFile.
+	Data []byte
+
+	// Entries is returned by ReadDir.
+	Entries []gofs.DirEntry
+
+	// Err is the error returned by any method.
+	Err error
+
+	// File is returned by Create, Open, and OpenFile.
+	File gofs.File
+
+	// FS is returned by Sub.
+	FS gofs.FS
+
+	// Info is returned by Stat.
+	Info gofs.FileInfo
+
+	// Matches is returned by Glob.
+	Matches []string
+
+	// Root is returned by Root.
+	Root string
+}
+
+// Expectation defines a call that is expected to be made to a MockFS, and the Result returned by the call.
+//
+// By default, an expectation is satisfied by a single call. Use Times to change the number of expected calls.
+type Expectation struct {
+	args   []any
+	calls  int
+	do     func(args []any)
+	mfs    *MockFS
+	op     string
+	result Result
+	times  int
+}
+
+// Do sets a function that is called with the arguments of each call that matches the expectation, before the Result is
+// returned, for example to block the call or to change the state of a fixture.
+func (e *Expectation) Do(fn func(args []any)) *Expectation {
+	e.mfs.mutex.Lock()
+	defer e.mfs.mutex.Unlock()
+
+	e.do = fn
+	return e
+}
+
+// Return sets the Result returned by the calls that match the expectation.
+func (e *Expectation) Return(result Result) *Expectation {
+	e.mfs.mutex.Lock()
+	defer e.mfs.mutex.Unlock()
+
+	e.result = result
+	return e
+}
+
+// ReturnError sets the error returned by the calls that match the expectation.
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.mfs.mutex.Lock()
+	defer e.mfs.mutex.Unlock()
+
+	e.result.Err = err
+	return e
+}
+
+// Times sets the number of calls expected to match the expectation. If n is not positive, the expectation matches any
+// number of calls, including none.
+func (e *Expectation) Times(n int) *Expectation {
+	e.mfs.mutex.Lock()
+	defer e.mfs.mutex.Unlock()
+
+	e.times = n
+	return e
+}
+
+// String returns a string representation of the Expectation.
+func (e *Expectation) String() string {
+	if e.args == nil {
+		return e.op + "(any)"
+	}
+	return fmt.Sprintf("%s%v", e.op, e.args)
+}
+
+// exhausted returns whether the expectation can not match further calls.
+func (e *Expectation) exhausted() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+// matches returns whether the call of op with args matches the expectation.
+func (e *Expectation) matches(op string, args []any) bool {
+	if e.op != op {
+		return false
+	}
+
+	if e.args == nil {
+		return true
+	}
+
+	if len(e.args) != len(args) {
+		return false
+	}
+
+	for i, want := range e.args {
+		if !argMatches(want, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// satisfied returns whether the expectation was matched by the expected number of calls.
+func (e *Expectation) satisfied() bool {
+	return e.times <= 0 || e.calls == e.times
+}
+
+// argMatches returns whether the argument got of a call matches the argument want of an expectation. Integer values
+// match if they are numerically equal, so that untyped constants such as 0644 match a gofs.FileMode.
+func argMatches(want any, got any) bool {
+	switch w := want.(type) {
+	case anyArg:
+		return true
+	case Matcher:
+		return w(got)
+	case func(any) bool:
+		return w(got)
+	}
+
+	wv, gv := reflect.ValueOf(want), reflect.ValueOf(got)
+	if (wv.CanInt() || wv.CanUint()) && (gv.CanInt() || gv.CanUint()) {
+		return intEqual(wv, gv)
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// intEqual returns whether the integer values a and b are numerically equal.
+func intEqual(a reflect.Value, b reflect.Value) bool {
+	switch {
+	case a.CanInt() && b.CanInt():
+		return a.Int() == b.Int()
+	case a.CanUint() && b.CanUint():
+		return a.Uint() == b.Uint()
+	case a.CanInt():
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint()
+	default:
+		return b.Int() >= 0 && uint64(b.Int()) == a.Uint()
+	}
+}
@@ -0,0 +1,404 @@
+package mockfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const providerName = "mockfs"
+
+// ErrUnexpectedCall is returned by a MockFS for a call that does not match an expectation.
+var ErrUnexpectedCall = errors.New("mockfs: unexpected call")
+
+var _ fs.FS = (*MockFS)(nil)
+
+// MockFS scriptable provider that implements fs.FS, for testing code that accepts an fs.FS without performing IO.
+//
+// A MockFS records every call made to it, and returns the Result of the first expectation set using Expect that
+// matches the call. Calls that do not match an expectation return an error wrapping ErrUnexpectedCall, or are passed
+// to a fallback file system if one is set using WithFallback. Once the code under test has run, Verify reports the
+// expectations that were not satisfied and the calls that were not expected.
+//
+// The PathSeparator and Provider methods are not recorded, and return "/" and "mockfs" respectively.
+type MockFS struct {
+	calls        []Call
+	expectations []*Expectation
+	fallback     fs.FS
+	mutex        sync.Mutex
+	ordered      bool
+	unexpected   []Call
+}
+
+// New creates a new MockFS.
+func New(options ...func(*MockFS)) *MockFS {
+	m := &MockFS{}
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
+}
+
+// Calls returns the calls made to the MockFS in the order they were made.
+func (m *MockFS) Calls() []Call {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := make([]Call, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// Expect adds an expectation for a call of the operation op, such as OpStat, with args. The arguments are matched in
+// the order of the parameters of the method, using Any or a Matcher for arguments that can have any value or must
+// satisfy a condition. If no arguments are provided, the expectation matches a call of op with any arguments.
+//
+// The returned Expectation is used to set the Result returned by the call, which has no values and a nil error by
+// default.
+func (m *MockFS) Expect(op string, args ...any) *Expectation {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e := &Expectation{args: args, mfs: m, op: op, times: 1}
+	if len(args) == 0 {
+		e.args = nil
+	}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Reset removes every expectation and recorded call.
+func (m *MockFS) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls = nil
+	m.expectations = nil
+	m.unexpected = nil
+}
+
+// Verify returns an error describing each expectation that was not matched by the expected number of calls, and each
+// call that did not match an expectation and was not passed to a fallback file system. A nil error is returned if every
+// expectation was satisfied.
+func (m *MockFS) Verify() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var errs []error
+	for _, e := range m.expectations {
+		if !e.satisfied() {
+			errs = append(errs, fmt.Errorf("mockfs: expected %s to be called %d time(s), called %d time(s)", e, e.times, e.calls))
+		}
+	}
+
+	for _, c := range m.unexpected {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnexpectedCall, c))
+	}
+	return errors.Join(errs...)
+}
+
+// Close ...
+func (m *MockFS) Close() error {
+	r, ok := m.call(OpClose)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Close()
+		}
+		return unexpectedCall(OpClose, "")
+	}
+	return r.Err
+}
+
+// Create ...
+func (m *MockFS) Create(name string) (fs.File, error) {
+	r, ok := m.call(OpCreate, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Create(name)
+		}
+		return nil, unexpectedCall(OpCreate, name)
+	}
+	return file(OpCreate, name, r)
+}
+
+// Glob ...
+func (m *MockFS) Glob(pattern string) ([]string, error) {
+	r, ok := m.call(OpGlob, pattern)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Glob(pattern)
+		}
+		return nil, unexpectedCall(OpGlob, pattern)
+	}
+	return r.Matches, r.Err
+}
+
+// Mkdir ...
+func (m *MockFS) Mkdir(name string, perm gofs.FileMode) error {
+	r, ok := m.call(OpMkdir, name, perm)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Mkdir(name, perm)
+		}
+		return unexpectedCall(OpMkdir, name)
+	}
+	return r.Err
+}
+
+// MkdirAll ...
+func (m *MockFS) MkdirAll(path string, perm gofs.FileMode) error {
+	r, ok := m.call(OpMkdirAll, path, perm)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.MkdirAll(path, perm)
+		}
+		return unexpectedCall(OpMkdirAll, path)
+	}
+	return r.Err
+}
+
+// Open ...
+func (m *MockFS) Open(name string) (gofs.File, error) {
+	r, ok := m.call(OpOpen, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Open(name)
+		}
+		return nil, unexpectedCall(OpOpen, name)
+	}
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.File, nil
+}
+
+// OpenFile ...
+func (m *MockFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	r, ok := m.call(OpOpenFile, name, flag, perm)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.OpenFile(name, flag, perm)
+		}
+		return nil, unexpectedCall(OpOpenFile, name)
+	}
+	return file(OpOpenFile, name, r)
+}
+
+// PathSeparator ...
+func (m *MockFS) PathSeparator() string {
+	return "/"
+}
+
+// Provider ...
+func (m *MockFS) Provider() string {
+	return providerName
+}
+
+// ReadDir ...
+func (m *MockFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	r, ok := m.call(OpReadDir, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.ReadDir(name)
+		}
+		return nil, unexpectedCall(OpReadDir, name)
+	}
+	return r.Entries, r.Err
+}
+
+// ReadFile ...
+func (m *MockFS) ReadFile(name string) ([]byte, error) {
+	r, ok := m.call(OpReadFile, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.ReadFile(name)
+		}
+		return nil, unexpectedCall(OpReadFile, name)
+	}
+	return r.Data, r.Err
+}
+
+// Remove ...
+func (m *MockFS) Remove(name string) error {
+	r, ok := m.call(OpRemove, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Remove(name)
+		}
+		return unexpectedCall(OpRemove, name)
+	}
+	return r.Err
+}
+
+// RemoveAll ...
+func (m *MockFS) RemoveAll(path string) error {
+	r, ok := m.call(OpRemoveAll, path)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.RemoveAll(path)
+		}
+		return unexpectedCall(OpRemoveAll, path)
+	}
+	return r.Err
+}
+
+// Rename ...
+func (m *MockFS) Rename(oldpath string, newpath string) error {
+	r, ok := m.call(OpRename, oldpath, newpath)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Rename(oldpath, newpath)
+		}
+		return unexpectedCall(OpRename, oldpath)
+	}
+	return r.Err
+}
+
+// Root ...
+func (m *MockFS) Root() (string, error) {
+	r, ok := m.call(OpRoot)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Root()
+		}
+		return "", unexpectedCall(OpRoot, "")
+	}
+	return r.Root, r.Err
+}
+
+// Stat ...
+func (m *MockFS) Stat(name string) (gofs.FileInfo, error) {
+	r, ok := m.call(OpStat, name)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Stat(name)
+		}
+		return nil, unexpectedCall(OpStat, name)
+	}
+	return r.Info, r.Err
+}
+
+// Sub ...
+func (m *MockFS) Sub(dir string) (gofs.FS, error) {
+	r, ok := m.call(OpSub, dir)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.Sub(dir)
+		}
+		return nil, unexpectedCall(OpSub, dir)
+	}
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.FS, nil
+}
+
+// WriteFile ...
+func (m *MockFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	r, ok := m.call(OpWriteFile, name, data, perm)
+	if !ok {
+		if m.fallback != nil {
+			return m.fallback.WriteFile(name, data, perm)
+		}
+		return unexpectedCall(OpWriteFile, name)
+	}
+	return r.Err
+}
+
+// String returns a string representation of the calls made to the MockFS.
+func (m *MockFS) String() string {
+	calls := m.Calls()
+
+	s := make([]string, len(calls))
+	for i, c := range calls {
+		s[i] = c.String()
+	}
+	return strings.Join(s, "\n")
+}
+
+// call records the call of op with args, and returns the Result of the first expectation that matches the call, or
+// false if the call does not match an expectation.
+func (m *MockFS) call(op string, args ...any) (Result, bool) {
+	m.mutex.Lock()
+
+	c := Call{Op: op, Args: args}
+	m.calls = append(m.calls, c)
+
+	var match *Expectation
+	for _, e := range m.expectations {
+		if e.exhausted() {
+			continue
+		}
+
+		if e.matches(op, args) {
+			match = e
+			break
+		}
+
+		// Expectations are matched in the order they were added, so only the first expectation that has not been
+		// satisfied can match.
+		if m.ordered && !e.satisfied() {
+			break
+		}
+	}
+
+	if match == nil {
+		if m.fallback == nil {
+			m.unexpected = append(m.unexpected, c)
+		}
+		m.mutex.Unlock()
+		return Result{}, false
+	}
+
+	match.calls++
+	r, do := match.result, match.do
+	m.mutex.Unlock()
+
+	// The function is called without holding the lock, so that it can block the call or make calls to the MockFS.
+	if do != nil {
+		do(args)
+	}
+	return r, true
+}
+
+// unexpectedCall returns the error for a call of op for path that did not match an expectation.
+func unexpectedCall(op string, path string) error {
+	return fs.NewOpError(providerName, op, path, ErrUnexpectedCall)
+}
+
+// file returns the fs.File in the Result r of a call of op that opens the named file.
+func file(op string, name string, r Result) (fs.File, error) {
+	if r.Err != nil || r.File == nil {
+		return nil, r.Err
+	}
+
+	f, ok := r.File.(fs.File)
+	if !ok {
+		return nil, fs.NewOpError(providerName, op, name, fs.ErrInvalidEntryType)
+	}
+	return f, nil
+}
+
+// WithFallback sets the file system that calls which do not match an expectation are passed to, so that a MockFS can
+// override selected calls to a real file system, such as injecting an error for a single file.
+func WithFallback(fsys fs.FS) func(*MockFS) {
+	return func(m *MockFS) {
+		m.fallback = fsys
+	}
+}
+
+// WithOrder enables matching expectations in the order they were added, so that the exact sequence of calls can be
+// verified. A call can only match an expectation once every expectation added before it was called the expected number
+// of times, except for expectations that match any number of calls.
+func WithOrder() func(*MockFS) {
+	return func(m *MockFS) {
+		m.ordered = true
+	}
+}
@@ -0,0 +1,171 @@
+package mockfs
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// MockFSTestSuite ...
+type MockFSTestSuite struct {
+	suite.Suite
+}
+
+func NewMockFSTestSuite() *MockFSTestSuite {
+	return &MockFSTestSuite{}
+}
+
+func TestMockFSTestSuite(t *testing.T) {
+	suite.Run(t, NewMockFSTestSuite())
+}
+
+func (t *MockFSTestSuite) TestExpect() {
+	m := New()
+	entry, err := fs.NewEntry("fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	m.Expect(OpStat, "doc/fox.txt").Return(Result{Info: entry})
+	m.Expect(OpReadFile, "doc/fox.txt").Return(Result{Data: []byte("the quick brown fox")}).Times(2)
+	m.Expect(OpWriteFile, "doc/dog.txt", Any, 0644)
+
+	fi, err := m.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "fox.txt", fi.Name())
+
+	for range 2 {
+		b, err := m.ReadFile("doc/fox.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the quick brown fox", string(b))
+	}
+	assert.NoError(t.T(), m.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t.T(), m.Verify())
+
+	assert.Equal(t.T(), []Call{
+		{Op: OpStat, Args: []any{"doc/fox.txt"}},
+		{Op: OpReadFile, Args: []any{"doc/fox.txt"}},
+		{Op: OpReadFile, Args: []any{"doc/fox.txt"}},
+		{Op: OpWriteFile, Args: []any{"doc/dog.txt", []byte("the lazy dog"), gofs.FileMode(0644)}},
+	}, m.Calls())
+
+	// Exhausted expectations do not match further calls.
+	_, err = m.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, ErrUnexpectedCall)
+	assert.ErrorIs(t.T(), m.Verify(), ErrUnexpectedCall)
+}
+
+func (t *MockFSTestSuite) TestReturnError() {
+	m := New()
+	m.Expect(OpRemove, Matcher(func(arg any) bool { return strings.HasPrefix(arg.(string), "tmp/") })).
+		ReturnError(gofs.ErrPermission).
+		Times(0)
+
+	assert.ErrorIs(t.T(), m.Remove("tmp/a"), gofs.ErrPermission)
+	assert.ErrorIs(t.T(), m.Remove("tmp/b"), gofs.ErrPermission)
+	assert.ErrorIs(t.T(), m.Remove("doc/fox.txt"), ErrUnexpectedCall)
+
+	_, err := m.Open("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, ErrUnexpectedCall)
+	assert.Len(t.T(), m.Calls(), 4)
+}
+
+func (t *MockFSTestSuite) TestVerify() {
+	m := New()
+	m.Expect(OpMkdirAll, "doc", gofs.FileMode(0755))
+	m.Expect(OpRename, "a", "b").Times(2)
+
+	assert.NoError(t.T(), m.MkdirAll("doc", 0755))
+	assert.NoError(t.T(), m.Rename("a", "b"))
+
+	err := m.Verify()
+	assert.Error(t.T(), err)
+	assert.Contains(t.T(), err.Error(), "rename[a b]")
+	assert.NotErrorIs(t.T(), err, ErrUnexpectedCall)
+
+	m.Reset()
+	assert.NoError(t.T(), m.Verify())
+	assert.Empty(t.T(), m.Calls())
+}
+
+func (t *MockFSTestSuite) TestOrder() {
+	m := New(WithOrder())
+	m.Expect(OpMkdir, "doc", Any)
+	m.Expect(OpWriteFile, "doc/fox.txt", Any, Any)
+
+	// The file can not be written before the directory is created.
+	assert.ErrorIs(t.T(), m.WriteFile("doc/fox.txt", nil, 0644), ErrUnexpectedCall)
+
+	m.Reset()
+	m.Expect(OpMkdir, "doc", Any)
+	m.Expect(OpStat).Times(0)
+	m.Expect(OpWriteFile, "doc/fox.txt", Any, Any)
+
+	assert.NoError(t.T(), m.Mkdir("doc", 0755))
+	assert.NoError(t.T(), m.WriteFile("doc/fox.txt", nil, 0644))
+	assert.NoError(t.T(), m.Verify())
+}
+
+func (t *MockFSTestSuite) TestFallback() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+
+	failure := errors.New("disk full")
+	m := New(WithFallback(backing))
+	m.Expect(OpWriteFile, "doc/dog.txt", Any, Any).ReturnError(failure)
+
+	b, err := gofs.ReadFile(m, "doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	assert.ErrorIs(t.T(), m.WriteFile("doc/dog.txt", nil, 0644), failure)
+	assert.NoError(t.T(), m.WriteFile("doc/cat.txt", []byte("the cat"), 0644))
+
+	b, err = backing.ReadFile("doc/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat", string(b))
+	assert.NoError(t.T(), m.Verify())
+}
+
+func (t *MockFSTestSuite) TestDo() {
+	m := New()
+
+	var wg sync.WaitGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	m.Expect(OpStat, "slow").
+		Do(func(args []any) {
+			close(started)
+			<-release
+		}).
+		ReturnError(gofs.ErrNotExist)
+	m.Expect(OpStat, "fast").ReturnError(gofs.ErrExist)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := m.Stat("slow")
+		assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	}()
+
+	// Calls are not blocked by a call that is blocked by its function.
+	<-started
+	_, err := m.Stat("fast")
+	assert.ErrorIs(t.T(), err, gofs.ErrExist)
+
+	close(release)
+	wg.Wait()
+	assert.NoError(t.T(), m.Verify())
+}
@@ -0,0 +1,60 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	gofs "io/fs"
+)
+
+// Move moves the file or directory srcPath in src to dstPath in dst.
+//
+// If src and dst are the same file system, the entry is moved using Rename. Otherwise, or if the file system is unable
+// to rename the entry (e.g. across devices), the entry is copied using CopyAll and then removed from src. The options
+// are applied to the copy, except that symbolic links are rejected unless a policy is set using WithSymlinks, so that
+// links are not silently lost when src is removed.
+//
+// If the copy fails, src is left unchanged, and the partially copied destination is removed if dstPath did not exist
+// before the move. If the copy succeeds but src cannot be removed, the destination is kept and the error is returned.
+func Move(dst FS, dstPath string, src FS, srcPath string, options ...CopyOption) error {
+	if dst == nil || src == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &copyOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	if _, err := src.Stat(srcPath); err != nil {
+		return err
+	}
+
+	exists := false
+	if _, err := dst.Stat(dstPath); err == nil {
+		if opts.overwrite == OverwriteNever {
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "move", Path: dstPath, Err: gofs.ErrExist})
+		}
+		exists = true
+	} else if !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	if sameFS(dst, src) && (opts.overwrite == OverwriteNever || opts.overwrite == OverwriteAlways) {
+		err := src.Rename(srcPath, dstPath)
+		if err == nil || !errors.Is(err, syscall.EXDEV) && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+
+	if err := CopyAll(dst, dstPath, src, srcPath, append([]CopyOption{WithSymlinks(SymlinkError)}, options...)...); err != nil {
+		if !exists {
+			if rerr := dst.RemoveAll(dstPath); rerr != nil {
+				return errors.Join(err, rerr)
+			}
+		}
+		return err
+	}
+	return src.RemoveAll(srcPath)
+}
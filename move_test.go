@@ -0,0 +1,103 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestMove(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"doc/fox.txt":     "the quick brown fox",
+		"doc/animals/dog": "jumps over the lazy dog",
+	}
+	for name, content := range files {
+		if err := src.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.NoError(t, fs.Move(src, "renamed", src, "doc"))
+	_, err = src.Stat("doc")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	assert.NoError(t, fs.Move(dst, "moved", src, "renamed"))
+	_, err = src.Stat("renamed")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	for name, content := range files {
+		b, err := dst.ReadFile("moved/" + name[len("doc/"):])
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+
+	assert.ErrorIs(t, fs.Move(dst, "moved", dst, "missing"), gofs.ErrNotExist)
+
+	if err := src.WriteFile("cat.txt", []byte("the cat sat"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.ErrorIs(t, fs.Move(dst, "moved/fox.txt", src, "cat.txt"), gofs.ErrExist)
+
+	_, err = src.Stat("cat.txt")
+	assert.NoError(t, err)
+}
+
+func TestMoveCleanup(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	srcRoot := filepath.Join(dir, "src")
+	if err := os.MkdirAll(srcRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(srcRoot, "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("fox.txt", filepath.Join(srcRoot, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.ErrorIs(t, fs.Move(mfs, "moved", osfs, srcRoot), fs.ErrInvalidEntryType)
+
+	_, err = mfs.Stat("moved")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	_, err = os.Stat(filepath.Join(srcRoot, "fox.txt"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, fs.Move(mfs, "moved", osfs, srcRoot, fs.WithSymlinks(fs.SymlinkFollow)))
+
+	b, err := mfs.ReadFile("moved/link")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	_, err = os.Stat(srcRoot)
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+}
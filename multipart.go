@@ -0,0 +1,235 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+
+	gofs "io/fs"
+)
+
+// defaultMultipartPerm is the default permissions for files saved by SaveMultipart.
+const defaultMultipartPerm = 0644
+
+// MultipartOption defines an option for SaveMultipart.
+type MultipartOption func(*multipartOptions)
+
+type multipartOptions struct {
+	fields      []string
+	maxFileSize int64
+	maxFiles    int
+	maxSize     int64
+	overwrite   bool
+	perm        gofs.FileMode
+}
+
+// SaveMultipart saves the files of the multipart/form-data request r to the directory dir in fsys, and returns the
+// Entry of each saved file in the order the files were sent.
+//
+// Files are streamed from the request body to fsys without being buffered in memory or in temporary files. The name of
+// each file is the filename sent by the client, which is resolved under dir using SecureJoin, so that a client can not
+// write outside of dir. The directory is created if it does not exist. Form fields that are not files are ignored, and
+// existing files are not replaced unless enabled using WithMultipartOverwrite. If the part of a file has a Content-Type
+// header and fsys implements MimeTypeFS, the type is stored for the file.
+//
+// The number and size of the files can be limited using WithMultipartMaxFiles, WithMultipartMaxFileSize, and
+// WithMultipartMaxSize, which return an error wrapping ErrTooLarge once a limit is exceeded. If an error occurs, the
+// files saved by SaveMultipart are removed, including the file that was being saved.
+func SaveMultipart(fsys FS, dir string, r *http.Request, options ...MultipartOption) ([]*Entry, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	if r == nil {
+		return nil, errors.New("fs: request is required")
+	}
+
+	opts := &multipartOptions{perm: defaultMultipartPerm}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("fs: multipart: %w", err)
+	}
+
+	if err := fsys.MkdirAll(dir, DirPerm(opts.perm)); err != nil {
+		return nil, err
+	}
+
+	var (
+		entries []*Entry
+		total   int64
+	)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, removeSaved(fsys, entries, fmt.Errorf("fs: multipart: %w", err))
+		}
+
+		if part.FileName() == "" || (len(opts.fields) > 0 && !slices.Contains(opts.fields, part.FormName())) {
+			part.Close()
+			continue
+		}
+
+		if opts.maxFiles > 0 && len(entries) == opts.maxFiles {
+			part.Close()
+			return nil, removeSaved(fsys, entries, fmt.Errorf("fs: multipart: more than %d files: %w", opts.maxFiles,
+				ErrTooLarge))
+		}
+
+		e, err := saveMultipartFile(fsys, dir, part, opts, &total)
+		part.Close()
+		if err != nil {
+			return nil, removeSaved(fsys, entries, err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// WithMultipartFields sets the names of the form fields whose files are saved by SaveMultipart. By default, the files
+// of every field are saved.
+func WithMultipartFields(names ...string) MultipartOption {
+	return func(o *multipartOptions) {
+		o.fields = append(o.fields, names...)
+	}
+}
+
+// WithMultipartMaxFileSize sets the maximum size in bytes of each file saved by SaveMultipart. By default, the size of
+// files is not limited.
+func WithMultipartMaxFileSize(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxFileSize = n
+	}
+}
+
+// WithMultipartMaxFiles sets the maximum number of files saved by SaveMultipart. By default, the number of files is not
+// limited.
+func WithMultipartMaxFiles(n int) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxFiles = n
+	}
+}
+
+// WithMultipartMaxSize sets the maximum total size in bytes of the files saved by SaveMultipart. By default, the total
+// size of files is not limited.
+func WithMultipartMaxSize(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxSize = n
+	}
+}
+
+// WithMultipartOverwrite sets whether SaveMultipart replaces existing files. By default, an error wrapping ErrExist is
+// returned for a file that already exists.
+func WithMultipartOverwrite(overwrite bool) MultipartOption {
+	return func(o *multipartOptions) {
+		o.overwrite = overwrite
+	}
+}
+
+// WithMultipartPerm sets the permissions of the files saved by SaveMultipart. The default is 0644.
+func WithMultipartPerm(perm gofs.FileMode) MultipartOption {
+	return func(o *multipartOptions) {
+		o.perm = perm
+	}
+}
+
+// saveMultipartFile saves the file of part to dir, and adds its size to total.
+func saveMultipartFile(fsys FS, dir string, part *multipart.Part, opts *multipartOptions, total *int64) (*Entry, error) {
+	name := part.FileName()
+	if name == "." || name == ".." || strings.ContainsRune(name, 0) {
+		return nil, fmt.Errorf("fs: multipart: filename %q is invalid: %w", name, ErrInvalid)
+	}
+
+	p, err := SecureJoin(fsys, dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are only saved directly in dir, so filenames that resolve to dir itself or to a subdirectory are rejected,
+	// such as "/" or names containing the path separator of fsys.
+	if p == dir || Dir(fsys, p) != Dir(fsys, Join(fsys, dir, "x")) {
+		return nil, fmt.Errorf("fs: multipart: filename %q is invalid: %w", name, ErrInvalid)
+	}
+
+	flag := O_WRONLY | O_CREATE | O_TRUNC
+	if !opts.overwrite {
+		// Not every provider supports O_EXCL, so the file is also checked before it is opened.
+		if _, err := fsys.Stat(p); err == nil {
+			return nil, NewOpError(fsys.Provider(), "saveMultipart", p, ErrExist)
+		}
+		flag = O_WRONLY | O_CREATE | O_EXCL
+	}
+
+	f, err := fsys.OpenFile(p, flag, opts.perm)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int64(-1)
+	if opts.maxFileSize > 0 {
+		limit = opts.maxFileSize
+	}
+
+	if opts.maxSize > 0 && (limit < 0 || opts.maxSize-*total < limit) {
+		limit = opts.maxSize - *total
+	}
+
+	var r io.Reader = part
+	if limit >= 0 {
+		r = io.LimitReader(part, limit+1)
+	}
+
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil && limit >= 0 && n > limit {
+		err = fmt.Errorf("fs: multipart: %s: %w", name, ErrTooLarge)
+	}
+
+	if err != nil {
+		_ = fsys.Remove(p)
+		return nil, err
+	}
+	*total += n
+
+	if m, ok := fsys.(MimeTypeFS); ok {
+		if mt := part.Header.Get("Content-Type"); mt != "" {
+			if err := m.SetMimeType(p, mt); err != nil {
+				_ = fsys.Remove(p)
+				return nil, err
+			}
+		}
+	}
+
+	fi, err := fsys.Stat(p)
+	if err != nil {
+		_ = fsys.Remove(p)
+		return nil, err
+	}
+
+	if e, ok := fi.(*Entry); ok {
+		c := e.Copy()
+		c.path = p
+		return c, nil
+	}
+	return EntryFromFileInfo(p, fi)
+}
+
+// removeSaved removes the files of entries that were saved before err occurred, and returns err.
+func removeSaved(fsys FS, entries []*Entry, err error) error {
+	for _, e := range entries {
+		_ = fsys.Remove(e.Path())
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package fs_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+type multipartFile struct {
+	field       string
+	name        string
+	contentType string
+	content     string
+}
+
+func multipartRequest(t *testing.T, files ...multipartFile) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	assert.NoError(t, mw.WriteField("comment", "not a file"))
+
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.field+`"; filename="`+f.name+`"`)
+		if f.contentType != "" {
+			h.Set("Content-Type", f.contentType)
+		}
+
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(f.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestSaveMultipart(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := multipartRequest(t,
+		multipartFile{field: "file", name: "fox.txt", contentType: "text/x-fox", content: "the quick brown fox"},
+		multipartFile{field: "file", name: "../../escape.txt", content: "escape"},
+		multipartFile{field: "other", name: "other.txt", content: "other"},
+	)
+
+	entries, err := fs.SaveMultipart(mfs, "uploads", r, fs.WithMultipartFields("file"))
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "uploads/fox.txt", entries[0].Path())
+		assert.Equal(t, int64(19), entries[0].Size())
+		assert.Equal(t, "text/x-fox", entries[0].Attributes().MimeType())
+		assert.Equal(t, "uploads/escape.txt", entries[1].Path())
+	}
+
+	b, err := mfs.ReadFile("uploads/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	_, err = mfs.Stat("uploads/other.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	// Existing files are only replaced if overwrite is enabled.
+	_, err = fs.SaveMultipart(mfs, "uploads", multipartRequest(t, multipartFile{field: "file", name: "fox.txt", content: "fox"}))
+	assert.ErrorIs(t, err, fs.ErrExist)
+
+	_, err = fs.SaveMultipart(mfs, "uploads", multipartRequest(t, multipartFile{field: "file", name: "fox.txt", content: "fox"}),
+		fs.WithMultipartOverwrite(true))
+	assert.NoError(t, err)
+
+	b, err = mfs.ReadFile("uploads/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "fox", string(b))
+
+	// Files saved by a request that exceeds a limit are removed.
+	for _, opt := range []fs.MultipartOption{
+		fs.WithMultipartMaxFileSize(4),
+		fs.WithMultipartMaxSize(6),
+		fs.WithMultipartMaxFiles(1),
+	} {
+		r = multipartRequest(t,
+			multipartFile{field: "file", name: "a.txt", content: "aaa"},
+			multipartFile{field: "file", name: "b.txt", content: strings.Repeat("b", 5)},
+		)
+
+		_, err = fs.SaveMultipart(mfs, "limits", r, opt)
+		assert.ErrorIs(t, err, fs.ErrTooLarge)
+
+		entries, err := mfs.ReadDir("limits")
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	}
+
+	_, err = fs.SaveMultipart(mfs, "uploads", multipartRequest(t, multipartFile{field: "file", name: "/", content: "root"}))
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = fs.SaveMultipart(mfs, "uploads", httptest.NewRequest(http.MethodPost, "/upload", nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package fs
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PathValidatorFS is implemented by a file system whose provider restricts the names of entries beyond the paths
+// accepted by gofs.ValidPath, such as the key constraints of object stores or the reserved names of Windows.
+//
+// CleanPath consults the validator of a file system that implements PathValidatorFS, so that providers using CleanPath
+// reject invalid names before an operation reaches the backend.
+type PathValidatorFS interface {
+	FS
+
+	// ValidatePath returns a *NameError if the named path violates a rule of the provider.
+	ValidatePath(name string) error
+}
+
+// NameRules are the rules that the names of entries must follow for a provider. The zero value accepts every name.
+type NameRules struct {
+	// InvalidChars is the set of characters that can not appear in an element of a path.
+	InvalidChars string
+
+	// MaxNameLen is the maximum length in bytes of an element of a path, or unlimited if 0.
+	MaxNameLen int
+
+	// MaxPathLen is the maximum length in bytes of a path, or unlimited if 0.
+	MaxPathLen int
+
+	// NoControlChars rejects elements that contain ASCII control characters.
+	NoControlChars bool
+
+	// NoTrailingDots rejects elements that end with a dot or a space, which are removed from names by Windows.
+	NoTrailingDots bool
+
+	// ReservedNames are the names that can not be used for an element, such as the device names of Windows. Names are
+	// compared ignoring case and any extension, so that "con.txt" matches the reserved name "CON".
+	ReservedNames []string
+
+	// UTF8 rejects paths that are not valid UTF-8.
+	UTF8 bool
+}
+
+// POSIXNameRules returns the rules for names on typical POSIX file systems, which limit elements to 255 bytes and paths
+// to 4096 bytes.
+func POSIXNameRules() NameRules {
+	return NameRules{MaxNameLen: 255, MaxPathLen: 4096}
+}
+
+// S3NameRules returns the rules for the keys of objects in Amazon S3 and compatible object stores, which must be valid
+// UTF-8 of at most 1024 bytes. Control characters are rejected, since they can not be represented in the XML listings
+// returned by the service.
+func S3NameRules() NameRules {
+	return NameRules{MaxPathLen: 1024, NoControlChars: true, UTF8: true}
+}
+
+// WindowsNameRules returns the rules for names on Windows, which reserve the names of devices such as CON and NUL,
+// reject the characters <>:"\|?* and control characters, remove trailing dots and spaces, and limit elements to 255
+// bytes.
+func WindowsNameRules() NameRules {
+	reserved := []string{"CON", "PRN", "AUX", "NUL"}
+	for i := range 10 {
+		reserved = append(reserved, fmt.Sprintf("COM%d", i), fmt.Sprintf("LPT%d", i))
+	}
+
+	return NameRules{
+		InvalidChars:   `<>:"\|?*`,
+		MaxNameLen:     255,
+		NoControlChars: true,
+		NoTrailingDots: true,
+		ReservedNames:  reserved,
+	}
+}
+
+// Validate returns a *NameError for the first rule violated by the path p, whose elements are separated by sep, or nil
+// if p follows every rule.
+func (r NameRules) Validate(p string, sep string) error {
+	if r.MaxPathLen > 0 && len(p) > r.MaxPathLen {
+		return &NameError{Name: p, Rule: fmt.Sprintf("path is longer than %d bytes", r.MaxPathLen)}
+	}
+
+	if r.UTF8 && !utf8.ValidString(p) {
+		return &NameError{Name: p, Rule: "path is not valid UTF-8"}
+	}
+
+	for _, e := range strings.Split(p, sep) {
+		if e == "" || e == "." || e == ".." {
+			continue
+		}
+
+		if err := r.validateElem(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r NameRules) validateElem(e string) error {
+	if r.MaxNameLen > 0 && len(e) > r.MaxNameLen {
+		return &NameError{Name: e, Rule: fmt.Sprintf("name is longer than %d bytes", r.MaxNameLen)}
+	}
+
+	if i := strings.IndexAny(e, r.InvalidChars); i >= 0 {
+		return &NameError{Name: e, Rule: fmt.Sprintf("name contains the character %q", e[i])}
+	}
+
+	if r.NoControlChars && strings.IndexFunc(e, func(c rune) bool { return c < 0x20 || c == 0x7f }) >= 0 {
+		return &NameError{Name: e, Rule: "name contains a control character"}
+	}
+
+	if r.NoTrailingDots && strings.TrimRight(e, ". ") != e {
+		return &NameError{Name: e, Rule: "name ends with a dot or a space"}
+	}
+
+	base, _, _ := strings.Cut(e, ".")
+	for _, name := range r.ReservedNames {
+		if strings.EqualFold(strings.TrimRight(base, " "), name) {
+			return &NameError{Name: e, Rule: fmt.Sprintf("name %s is reserved", name)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package fs_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameRules(t *testing.T) {
+	windows := fs.WindowsNameRules()
+	for name, rule := range map[string]string{
+		"doc/fox.txt":            "",
+		"doc/CON":                "name CON is reserved",
+		"doc/nul.txt":            "name NUL is reserved",
+		"Com1 .tar.gz":           "name COM1 is reserved",
+		"doc/console.txt":        "",
+		"doc/fox?.txt":           `name contains the character '?'`,
+		"doc/fox\x01.txt":        "name contains a control character",
+		"doc./fox.txt":           "name ends with a dot or a space",
+		"doc/fox.txt ":           "name ends with a dot or a space",
+		strings.Repeat("a", 256): "name is longer than 255 bytes",
+	} {
+		err := windows.Validate(name, "/")
+		if rule == "" {
+			assert.NoError(t, err, name)
+			continue
+		}
+
+		var ne *fs.NameError
+		if assert.ErrorAs(t, err, &ne, name) {
+			assert.Equal(t, rule, ne.Rule, name)
+		}
+		assert.ErrorIs(t, err, fs.ErrInvalidName)
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	}
+
+	s3 := fs.S3NameRules()
+	assert.NoError(t, s3.Validate("doc/CON?.txt", "/"))
+	assert.ErrorIs(t, s3.Validate("doc/\xff.txt", "/"), fs.ErrInvalidName)
+	assert.ErrorIs(t, s3.Validate(strings.Repeat("a/", 513), "/"), fs.ErrInvalidName)
+	assert.NoError(t, fs.NameRules{}.Validate("doc/CON\x01", "/"))
+}
+
+func TestPathValidatorFS(t *testing.T) {
+	mfs, err := memfs.New(memfs.WithNameRules(fs.WindowsNameRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fs.CleanPath(mfs, "doc/aux.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalidName)
+
+	_, err = mfs.Create("doc/fox:dog.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalidName)
+
+	var ne *fs.NameError
+	assert.ErrorAs(t, mfs.Mkdir("prn", 0755), &ne)
+	assert.Equal(t, "prn", ne.Name)
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+
+	osfs, err := fs.New(fs.WithNameRules(fs.WindowsNameRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	_, err = osfs.Create(filepath.Join(dir, "fox.txt."))
+	assert.ErrorIs(t, err, fs.ErrInvalidName)
+	assert.ErrorIs(t, osfs.MkdirAll(filepath.Join(dir, "doc", "LPT1"), 0755), fs.ErrInvalidName)
+	assert.ErrorIs(t, osfs.Rename(filepath.Join(dir, "fox.txt"), filepath.Join(dir, "fox|dog.txt")), fs.ErrInvalidName)
+	assert.NoError(t, osfs.WriteFile(filepath.Join(dir, "fox.txt"), []byte("the quick brown fox"), 0644))
+}
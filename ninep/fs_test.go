@@ -0,0 +1,383 @@
+package ninep
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+const rootFid = 1
+
+// linkFS is a MemFS with symbolic links, whose destinations are resolved by the server rather than by the MemFS.
+type linkFS struct {
+	*memfs.MemFS
+	links map[string]string
+}
+
+func (l *linkFS) Readlink(name string) (string, error) {
+	if dest, ok := l.links[name]; ok {
+		return dest, nil
+	}
+	return "", &gofs.PathError{Op: "readlink", Path: name, Err: gofs.ErrInvalid}
+}
+
+func (l *linkFS) Symlink(oldname string, newname string) error {
+	return &gofs.PathError{Op: "symlink", Path: newname, Err: fs.ErrUnsupported}
+}
+
+// pipeListener is a net.Listener that accepts the connections sent on conns.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: "pipe", Net: "pipe"}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// NinePTestSuite ...
+type NinePTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	client  net.Conn
+	done    chan error
+	links   map[string]string
+	tag     uint16
+}
+
+func NewNinePTestSuite() *NinePTestSuite {
+	return &NinePTestSuite{}
+}
+
+func (t *NinePTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+	t.links = make(map[string]string)
+
+	s, err := New(&linkFS{MemFS: backing, links: t.links}, WithMaxMessageSize(8192), WithOwner(1000, 1000))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	client, conn := net.Pipe()
+	t.client = client
+	t.done = make(chan error, 1)
+	go func() {
+		t.done <- s.ServeConn(conn)
+		conn.Close()
+	}()
+
+	typ, d := t.request(tversion, uint32(65536), Version)
+	assert.Equal(t.T(), byte(rversion), typ)
+	assert.Equal(t.T(), uint32(8192), d.uint32())
+	assert.Equal(t.T(), Version, d.string())
+
+	typ, d = t.request(tattach, uint32(rootFid), uint32(0xffffffff), "test", "", uint32(1000))
+	assert.Equal(t.T(), byte(rattach), typ)
+	assert.Equal(t.T(), byte(qtDir), d.qid().typ)
+}
+
+func (t *NinePTestSuite) TearDownTest() {
+	t.client.Close()
+	assert.NoError(t.T(), <-t.done)
+}
+
+func TestNinePTestSuite(t *testing.T) {
+	suite.Run(t, NewNinePTestSuite())
+}
+
+// request sends a request with the provided fields and returns the type of the response and a decoder for the rest of
+// the response.
+func (t *NinePTestSuite) request(typ byte, fields ...any) (byte, *decoder) {
+	t.tag++
+	e := newEncoder(typ, t.tag)
+	for _, f := range fields {
+		switch v := f.(type) {
+		case string:
+			e.string(v)
+		case []byte:
+			e.data(v)
+		case byte:
+			e.uint8(v)
+		case uint16:
+			e.uint16(v)
+		case uint32:
+			e.uint32(v)
+		case uint64:
+			e.uint64(v)
+		}
+	}
+
+	if _, err := t.client.Write(e.message()); err != nil {
+		t.T().Fatal(err)
+	}
+
+	m, err := readMessage(t.client, make([]byte, 1024), 1<<20)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	d := &decoder{b: m}
+	rt := d.uint8()
+	assert.Equal(t.T(), t.tag, d.uint16())
+	return rt, d
+}
+
+// ok sends a request and asserts that the response has the expected type.
+func (t *NinePTestSuite) ok(typ byte, fields ...any) *decoder {
+	rt, d := t.request(typ, fields...)
+	if rt == rlerror {
+		t.T().Fatalf("request %d failed with errno %d", typ, d.uint32())
+	}
+	assert.Equal(t.T(), typ+1, rt)
+	return d
+}
+
+// errno sends a request and returns the error number of the Rlerror response.
+func (t *NinePTestSuite) errno(typ byte, fields ...any) uint32 {
+	rt, d := t.request(typ, fields...)
+	assert.Equal(t.T(), byte(rlerror), rt)
+	return d.uint32()
+}
+
+// walk walks from the root to a new fid.
+func (t *NinePTestSuite) walk(newid uint32, names ...string) {
+	fields := []any{uint32(rootFid), newid, uint16(len(names))}
+	for _, name := range names {
+		fields = append(fields, name)
+	}
+
+	d := t.ok(twalk, fields...)
+	assert.Equal(t.T(), uint16(len(names)), d.uint16())
+}
+
+func (t *NinePTestSuite) TestVersion() {
+	typ, d := t.request(tversion, uint32(8192), "9P2000")
+	assert.Equal(t.T(), byte(rversion), typ)
+	d.uint32()
+	assert.Equal(t.T(), "unknown", d.string())
+}
+
+func (t *NinePTestSuite) TestWalk() {
+	t.walk(2, "doc", "fox.txt")
+
+	rt, d := t.request(twalk, uint32(rootFid), uint32(3), uint16(2), "doc", "missing.txt")
+	assert.Equal(t.T(), byte(rwalk), rt)
+	assert.Equal(t.T(), uint16(1), d.uint16())
+	assert.Equal(t.T(), uint32(eBADF), t.errno(tclunk, uint32(3)))
+
+	assert.Equal(t.T(), uint32(eNOENT), t.errno(twalk, uint32(rootFid), uint32(3), uint16(1), "missing"))
+
+	t.walk(4, "..", "doc", "..", "doc")
+	t.ok(tclunk, uint32(2))
+	t.ok(tclunk, uint32(4))
+}
+
+func (t *NinePTestSuite) TestSecurePath() {
+	assert.NoError(t.T(), t.backing.WriteFile("pictures/seal.txt", []byte("the happy seal"), 0644))
+	t.links["doc/escape"] = "../../../pictures"
+	t.links["doc/absolute"] = "/pictures"
+	t.links["loop"] = "loop"
+
+	// Symbolic links are resolved as if the root of the file system were the root, so that they can not refer to a
+	// location outside of it.
+	for i, link := range []string{"escape", "absolute"} {
+		fid := uint32(2 + i)
+		t.walk(fid, "doc", link, "seal.txt")
+		t.ok(tlopen, fid, uint32(0))
+		d := t.ok(tread, fid, uint64(0), uint32(64))
+		assert.Equal(t.T(), []byte("the happy seal"), d.data())
+		t.ok(tclunk, fid)
+	}
+
+	t.walk(2, "doc", "escape")
+	t.ok(tmkdir, uint32(2), "otters", uint32(0755), uint32(1000))
+	t.ok(tclunk, uint32(2))
+
+	fi, err := t.backing.Stat("pictures/otters")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+
+	assert.Equal(t.T(), uint32(eINVAL), t.errno(twalk, uint32(rootFid), uint32(2), uint16(1), "loop"))
+}
+
+func (t *NinePTestSuite) TestRead() {
+	t.walk(2, "doc", "fox.txt")
+	d := t.ok(tlopen, uint32(2), uint32(0))
+	assert.Equal(t.T(), byte(qtFile), d.qid().typ)
+	assert.Equal(t.T(), uint32(8192-ioHeaderSize), d.uint32())
+
+	d = t.ok(tread, uint32(2), uint64(4), uint32(5))
+	assert.Equal(t.T(), []byte("quick"), d.data())
+
+	d = t.ok(tread, uint32(2), uint64(19), uint32(5))
+	assert.Empty(t.T(), d.data())
+
+	d = t.ok(tgetattr, uint32(2), uint64(getattrBasic))
+	assert.Equal(t.T(), uint64(getattrBasic), d.uint64())
+	d.qid()
+	assert.Equal(t.T(), uint32(modeRegular|0644), d.uint32())
+	assert.Equal(t.T(), uint32(1000), d.uint32())
+	assert.Equal(t.T(), uint32(1000), d.uint32())
+	d.uint64()
+	d.uint64()
+	assert.Equal(t.T(), uint64(19), d.uint64())
+
+	t.ok(tclunk, uint32(2))
+}
+
+func (t *NinePTestSuite) TestWrite() {
+	t.walk(2, "doc")
+	d := t.ok(tlcreate, uint32(2), "dog.txt", uint32(lORdwr|lOCreat|lOTrunc), uint32(0600), uint32(0))
+	assert.Equal(t.T(), byte(qtFile), d.qid().typ)
+
+	d = t.ok(twrite, uint32(2), uint64(0), []byte("jumps over "))
+	assert.Equal(t.T(), uint32(11), d.uint32())
+	d = t.ok(twrite, uint32(2), uint64(11), []byte("the lazy dog"))
+	assert.Equal(t.T(), uint32(12), d.uint32())
+	t.ok(tclunk, uint32(2))
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	t.walk(3, "doc", "dog.txt")
+	t.ok(tsetattr, uint32(3), uint32(setattrSize), uint32(0), uint32(0), uint32(0), uint64(0), uint64(0), uint64(0),
+		uint64(0), uint64(0))
+	t.ok(tclunk, uint32(3))
+
+	fi, err := t.backing.Stat("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(0), fi.Size())
+}
+
+func (t *NinePTestSuite) TestReadDir() {
+	t.walk(2, "doc")
+	d := t.ok(tlopen, uint32(2), uint32(0))
+	assert.Equal(t.T(), byte(qtDir), d.qid().typ)
+
+	d = t.ok(treaddir, uint32(2), uint64(0), uint32(4096))
+	d = &decoder{b: d.data()}
+
+	var names []string
+	var next uint64
+	for len(d.b) > 0 {
+		d.qid()
+		next = d.uint64()
+		d.uint8()
+		names = append(names, d.string())
+	}
+	assert.NoError(t.T(), d.err)
+	assert.Equal(t.T(), []string{".", "..", "fox.txt"}, names)
+
+	d = t.ok(treaddir, uint32(2), next, uint32(4096))
+	assert.Empty(t.T(), d.data())
+
+	assert.Equal(t.T(), uint32(eISDIR), t.errno(tread, uint32(2), uint64(0), uint32(10)))
+	t.ok(tclunk, uint32(2))
+}
+
+func (t *NinePTestSuite) TestDirs() {
+	d := t.ok(tmkdir, uint32(rootFid), "tmp", uint32(0755), uint32(0))
+	assert.Equal(t.T(), byte(qtDir), d.qid().typ)
+
+	t.walk(2, "doc", "fox.txt")
+	t.walk(3, "tmp")
+	t.ok(trename, uint32(2), uint32(3), "fox.txt")
+
+	_, err := t.backing.Stat("tmp/fox.txt")
+	assert.NoError(t.T(), err)
+
+	d = t.ok(tgetattr, uint32(2), uint64(getattrBasic))
+	d.uint64()
+	d.qid()
+	d.uint32()
+
+	assert.Equal(t.T(), uint32(eNOTEMPTY), t.errno(tunlinkat, uint32(rootFid), "tmp", uint32(atRemoveDir)))
+	assert.Equal(t.T(), uint32(eISDIR), t.errno(tunlinkat, uint32(rootFid), "tmp", uint32(0)))
+
+	t.ok(trenameat, uint32(3), "fox.txt", uint32(rootFid), "fox.txt")
+	t.ok(tunlinkat, uint32(rootFid), "tmp", uint32(atRemoveDir))
+
+	_, err = t.backing.Stat("tmp")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	t.ok(tremove, uint32(2))
+	_, err = t.backing.Stat("fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	assert.Equal(t.T(), uint32(eBADF), t.errno(tclunk, uint32(2)))
+}
+
+func (t *NinePTestSuite) TestUnsupported() {
+	assert.Equal(t.T(), uint32(eNOTSUP), t.errno(30, uint32(rootFid), uint32(2), "user.test"))
+	t.ok(tstatfs, uint32(rootFid))
+	t.ok(tflush, uint16(1))
+}
+
+func (t *NinePTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	s, err := New(t.backing, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	client, conn := net.Pipe()
+	l := &pipeListener{conns: make(chan net.Conn, 1), done: make(chan struct{})}
+	l.conns <- conn
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(l)
+	}()
+
+	_, err = client.Write([]byte{0, 0, 0, 0})
+	assert.NoError(t.T(), err)
+
+	_, err = io.ReadAll(client)
+	assert.NoError(t.T(), err)
+
+	l.Close()
+	assert.NoError(t.T(), <-done)
+	assert.Contains(t.T(), buf.String(), `msg="[ninep] serve" remote=pipe`)
+
+	s, err = New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), s.logger)
+}
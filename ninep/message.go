@@ -0,0 +1,260 @@
+package ninep
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Message types defined by the 9P2000.L protocol.
+const (
+	rlerror      = 7
+	tstatfs      = 8
+	rstatfs      = 9
+	tlopen       = 12
+	rlopen       = 13
+	tlcreate     = 14
+	rlcreate     = 15
+	trename      = 20
+	rrename      = 21
+	tgetattr     = 24
+	rgetattr     = 25
+	tsetattr     = 26
+	rsetattr     = 27
+	treaddir     = 40
+	rreaddir     = 41
+	tfsync       = 50
+	rfsync       = 51
+	tlock        = 52
+	rlock        = 53
+	tgetlock     = 54
+	rgetlock     = 55
+	tmkdir       = 72
+	rmkdir       = 73
+	trenameat    = 74
+	rrenameat    = 75
+	tunlinkat    = 76
+	runlinkat    = 77
+	tversion     = 100
+	rversion     = 101
+	tattach      = 104
+	rattach      = 105
+	tflush       = 108
+	rflush       = 109
+	twalk        = 110
+	rwalk        = 111
+	tread        = 116
+	rread        = 117
+	twrite       = 118
+	rwrite       = 119
+	tclunk       = 120
+	rclunk       = 121
+	tremove      = 122
+	rremove      = 123
+	headerSize   = 7
+	maxWalkElems = 16
+)
+
+// Qid types.
+const (
+	qtDir  = 0x80
+	qtFile = 0x00
+)
+
+// Linux open flags used by Tlopen and Tlcreate, which are defined independently of the host platform.
+const (
+	lOAccMode = 0x3
+	lOWronly  = 0x1
+	lORdwr    = 0x2
+	lOCreat   = 0x40
+	lOExcl    = 0x80
+	lOTrunc   = 0x200
+	lOAppend  = 0x400
+)
+
+// Flags used by Tgetattr, Tsetattr and Tunlinkat.
+const (
+	getattrBasic   = 0x000007ff
+	setattrSize    = 0x00000008
+	atRemoveDir    = 0x200
+	lockSuccess    = 0
+	lockTypeUnlock = 2
+	direntTypeDir  = 4
+	direntTypeReg  = 8
+)
+
+// POSIX file type bits used in the mode of an entry.
+const (
+	modeDir     = 0040000
+	modeRegular = 0100000
+)
+
+// Linux error numbers returned in Rlerror messages, which are defined independently of the host platform.
+const (
+	eNOENT    = 2
+	eIO       = 5
+	eBADF     = 9
+	eACCES    = 13
+	eEXIST    = 17
+	eNOTDIR   = 20
+	eISDIR    = 21
+	eINVAL    = 22
+	eFBIG     = 27
+	eNOTEMPTY = 39
+	eNOTSUP   = 95
+	eDQUOT    = 122
+)
+
+var errShortMessage = errors.New("ninep: message is too short")
+
+// qid is the server's unique identification for an entry.
+type qid struct {
+	typ     byte
+	version uint32
+	path    uint64
+}
+
+// decoder reads the fields of a message.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) uint8() byte {
+	if d.err != nil || len(d.b) < 1 {
+		d.err = errShortMessage
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.b) < 2 {
+		d.err = errShortMessage
+		return 0
+	}
+	v := binary.LittleEndian.Uint16(d.b)
+	d.b = d.b[2:]
+	return v
+}
+
+func (d *decoder) uint32() uint32 {
+	if d.err != nil || len(d.b) < 4 {
+		d.err = errShortMessage
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(d.b)
+	d.b = d.b[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil || len(d.b) < 8 {
+		d.err = errShortMessage
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(d.b)
+	d.b = d.b[8:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uint16()
+	if d.err != nil || len(d.b) < int(n) {
+		d.err = errShortMessage
+		return ""
+	}
+	v := string(d.b[:n])
+	d.b = d.b[n:]
+	return v
+}
+
+// data reads count-prefixed data, as sent in Twrite.
+func (d *decoder) data() []byte {
+	n := d.uint32()
+	if d.err != nil || uint32(len(d.b)) < n {
+		d.err = errShortMessage
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) qid() qid {
+	return qid{typ: d.uint8(), version: d.uint32(), path: d.uint64()}
+}
+
+// encoder writes the fields of a message.
+type encoder struct {
+	b []byte
+}
+
+func newEncoder(typ byte, tag uint16) *encoder {
+	e := &encoder{b: make([]byte, 4, 64)}
+	e.uint8(typ)
+	e.uint16(tag)
+	return e
+}
+
+func (e *encoder) uint8(v byte) {
+	e.b = append(e.b, v)
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.b = binary.LittleEndian.AppendUint16(e.b, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.b = binary.LittleEndian.AppendUint32(e.b, v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.b = binary.LittleEndian.AppendUint64(e.b, v)
+}
+
+func (e *encoder) string(v string) {
+	e.uint16(uint16(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) data(v []byte) {
+	e.uint32(uint32(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) qid(q qid) {
+	e.uint8(q.typ)
+	e.uint32(q.version)
+	e.uint64(q.path)
+}
+
+// message returns the encoded message with its size prefix.
+func (e *encoder) message() []byte {
+	binary.LittleEndian.PutUint32(e.b, uint32(len(e.b)))
+	return e.b
+}
+
+// readMessage reads a single message from r, and returns the message without its size prefix.
+func readMessage(r io.Reader, buf []byte, msize uint32) ([]byte, error) {
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(buf)
+	if n < headerSize || n > msize {
+		return nil, fmt.Errorf("ninep: invalid message size: %d", n)
+	}
+
+	if int(n) > len(buf) {
+		buf = make([]byte, n)
+	}
+
+	if _, err := io.ReadFull(r, buf[:n-4]); err != nil {
+		return nil, err
+	}
+	return buf[:n-4], nil
+}
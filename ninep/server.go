@@ -0,0 +1,1024 @@
+// Package ninep serves an fs.FS using the 9P2000.L protocol, so that it can be mounted by the Linux v9fs client, WSL,
+// plan9port and QEMU virtfs guests without FUSE.
+//
+// A Server can accept connections from a net.Listener, for example a TCP or Unix domain socket listener, or can serve
+// a single connection over any stream, such as a virtio or file descriptor transport. For example, a Server listening
+// on TCP port 5640 can be mounted using:
+//
+//	mount -t 9p -o trans=tcp,port=5640,version=9p2000.L 127.0.0.1 /mnt
+//
+// Paths provided by clients are resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer
+// to a location outside of the fs.FS. Since fs.FS does not provide operations for changing the mode, ownership or times
+// of an entry, such changes are accepted but are not persisted. Symbolic links, hard links, device nodes and extended
+// attributes are not supported.
+package ninep
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const (
+	// DefaultMaxMessageSize is the default maximum size of a message, which bounds the amount of data transferred by a
+	// single read or write.
+	DefaultMaxMessageSize = 1024 * 1024
+
+	// Version is the protocol version supported by a Server.
+	Version = "9P2000.L"
+
+	// ioHeaderSize is the size of the header of Rread and Twrite messages, which is excluded from the I/O unit.
+	ioHeaderSize = 24
+
+	// minMessageSize is the smallest maximum message size that can be negotiated.
+	minMessageSize = 4096
+
+	// v9fsMagic is the file system type reported by Rstatfs.
+	v9fsMagic = 0x01021997
+)
+
+// Server serves an fs.FS using 9P2000.L.
+type Server struct {
+	fsys   fs.FS
+	gid    uint32
+	logger fs.Logger
+	msize  uint32
+	mutex  sync.Mutex
+	next   uint64
+	paths  map[string]uint64
+	uid    uint32
+}
+
+// New creates a new Server that serves fsys.
+func New(fsys fs.FS, options ...func(*Server)) (*Server, error) {
+	if fsys == nil {
+		return nil, errors.New("ninep: file system is required")
+	}
+
+	s := &Server{
+		fsys:   fsys,
+		logger: fs.NopLogger(),
+		msize:  DefaultMaxMessageSize,
+		paths:  make(map[string]uint64),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	if s.msize < minMessageSize {
+		return nil, fmt.Errorf("ninep: maximum message size must be at least %d", minMessageSize)
+	}
+	return s, nil
+}
+
+// Serve accepts connections on l and serves each of them on its own goroutine. Serve returns nil once l is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("ninep: %w", err)
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := s.ServeConn(conn); err != nil {
+				s.logger.Error("[ninep] serve", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+		}()
+	}
+}
+
+// ServeConn serves requests read from rw until rw returns io.EOF.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	c := &conn{fids: make(map[uint32]*fid), msize: s.msize, server: s}
+	defer c.clunkAll()
+
+	buf := make([]byte, 64*1024)
+	for {
+		m, err := readMessage(rw, buf, c.msize)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("ninep: %w", err)
+		}
+
+		if _, err := rw.Write(c.handle(m)); err != nil {
+			return fmt.Errorf("ninep: %w", err)
+		}
+	}
+}
+
+// qid returns the qid for the entry with the provided name and file info.
+func (s *Server) qid(name string, fi gofs.FileInfo) qid {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.paths[name]
+	if !ok {
+		s.next++
+		p = s.next
+		s.paths[name] = p
+	}
+
+	if fi.IsDir() {
+		return qid{typ: qtDir, path: p}
+	}
+	return qid{typ: qtFile, path: p}
+}
+
+// rename moves the qid paths of oldpath and its descendants to newpath, so that renamed entries keep their identity.
+func (s *Server) rename(oldpath string, newpath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sep := s.fsys.PathSeparator()
+	moved := make(map[string]uint64)
+	for name, p := range s.paths {
+		switch {
+		case name == oldpath:
+			moved[newpath] = p
+		case strings.HasPrefix(name, oldpath+sep):
+			moved[newpath+name[len(oldpath):]] = p
+		case name != newpath && !strings.HasPrefix(name, newpath+sep):
+			continue
+		}
+		delete(s.paths, name)
+	}
+
+	for name, p := range moved {
+		s.paths[name] = p
+	}
+}
+
+// WithLogger sets the Logger used by a Server to log the connections that fail and the files that can not be closed.
+// By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*Server) {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a message, which is negotiated with each client. The default is
+// DefaultMaxMessageSize.
+func WithMaxMessageSize(n uint32) func(*Server) {
+	return func(s *Server) {
+		s.msize = n
+	}
+}
+
+// WithOwner sets the user and group ids reported as the owner of entries. The default is 0 for both.
+func WithOwner(uid uint32, gid uint32) func(*Server) {
+	return func(s *Server) {
+		s.uid = uid
+		s.gid = gid
+	}
+}
+
+// dirent is an entry in a directory listing.
+type dirent struct {
+	name string
+	path string
+	info gofs.FileInfo
+}
+
+// fid is a reference to an entry held by a client.
+type fid struct {
+	entries []dirent
+	file    fs.File
+	flag    int
+	name    string
+	open    bool
+}
+
+// conn contains the state of a single connection.
+type conn struct {
+	fids   map[uint32]*fid
+	msize  uint32
+	server *Server
+}
+
+func (c *conn) handle(m []byte) []byte {
+	d := &decoder{b: m}
+	typ, tag := d.uint8(), d.uint16()
+	if d.err != nil {
+		return rerror(tag, d.err)
+	}
+
+	var resp []byte
+	switch typ {
+	case tversion:
+		resp = c.version(tag, d)
+	case tattach:
+		resp = c.attach(tag, d)
+	case tflush:
+		d.uint16()
+		resp = newEncoder(rflush, tag).message()
+	case twalk:
+		resp = c.walk(tag, d)
+	case tlopen:
+		resp = c.lopen(tag, d)
+	case tlcreate:
+		resp = c.lcreate(tag, d)
+	case tread:
+		resp = c.read(tag, d)
+	case twrite:
+		resp = c.write(tag, d)
+	case tclunk:
+		resp = c.clunk(tag, d)
+	case tremove:
+		resp = c.remove(tag, d)
+	case tgetattr:
+		resp = c.getattr(tag, d)
+	case tsetattr:
+		resp = c.setattr(tag, d)
+	case treaddir:
+		resp = c.readdir(tag, d)
+	case tstatfs:
+		resp = c.statfs(tag, d)
+	case tmkdir:
+		resp = c.mkdir(tag, d)
+	case trename:
+		resp = c.renameFid(tag, d)
+	case trenameat:
+		resp = c.renameat(tag, d)
+	case tunlinkat:
+		resp = c.unlinkat(tag, d)
+	case tfsync:
+		resp = c.fsync(tag, d)
+	case tlock:
+		resp = c.lock(tag, d)
+	case tgetlock:
+		resp = c.getlock(tag, d)
+	default:
+		resp = rlerrorErrno(tag, eNOTSUP)
+	}
+
+	if d.err != nil {
+		return rerror(tag, d.err)
+	}
+	return resp
+}
+
+func (c *conn) version(tag uint16, d *decoder) []byte {
+	msize, version := d.uint32(), d.string()
+	if d.err != nil {
+		return nil
+	}
+
+	c.clunkAll()
+	c.msize = max(min(msize, c.server.msize), minMessageSize)
+
+	e := newEncoder(rversion, tag)
+	e.uint32(c.msize)
+	if version != Version {
+		e.string("unknown")
+	} else {
+		e.string(Version)
+	}
+	return e.message()
+}
+
+func (c *conn) attach(tag uint16, d *decoder) []byte {
+	id, _, _, _, _ := d.uint32(), d.uint32(), d.string(), d.string(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	if _, ok := c.fids[id]; ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	fi, err := c.server.fsys.Stat(".")
+	if err != nil {
+		return rerror(tag, err)
+	}
+	c.fids[id] = &fid{name: "."}
+
+	e := newEncoder(rattach, tag)
+	e.qid(c.server.qid(".", fi))
+	return e.message()
+}
+
+func (c *conn) walk(tag uint16, d *decoder) []byte {
+	id, newid, n := d.uint32(), d.uint32(), d.uint16()
+	names := make([]string, 0, min(n, maxWalkElems))
+	for i := uint16(0); i < n && d.err == nil; i++ {
+		names = append(names, d.string())
+	}
+
+	if d.err != nil {
+		return nil
+	}
+
+	if n > maxWalkElems {
+		return rlerrorErrno(tag, eINVAL)
+	}
+
+	f, ok := c.fids[id]
+	if !ok || f.open {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if _, ok := c.fids[newid]; ok && newid != id {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	name := f.name
+	qids := make([]qid, 0, len(names))
+	for i, elem := range names {
+		if elem == "" || strings.Contains(elem, "/") {
+			return rlerrorErrno(tag, eINVAL)
+		}
+
+		p, err := c.join(name, elem)
+		var fi gofs.FileInfo
+		if err == nil {
+			fi, err = c.server.fsys.Stat(p)
+		}
+		if err != nil {
+			if i == 0 {
+				return rerror(tag, err)
+			}
+			break
+		}
+		name = p
+		qids = append(qids, c.server.qid(p, fi))
+	}
+
+	if len(qids) == len(names) {
+		c.fids[newid] = &fid{name: name}
+	}
+
+	e := newEncoder(rwalk, tag)
+	e.uint16(uint16(len(qids)))
+	for _, q := range qids {
+		e.qid(q)
+	}
+	return e.message()
+}
+
+func (c *conn) lopen(tag uint16, d *decoder) []byte {
+	id, flags := d.uint32(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok || f.open {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	fi, err := c.server.fsys.Stat(f.name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if !fi.IsDir() {
+		f.flag = flag(flags)
+		file, err := c.server.fsys.OpenFile(f.name, f.flag, 0)
+		if err != nil {
+			return rerror(tag, err)
+		}
+		f.file = file
+	}
+	f.open = true
+	return c.ropen(rlopen, tag, f.name, fi)
+}
+
+func (c *conn) lcreate(tag uint16, d *decoder) []byte {
+	id, name, flags, mode, _ := d.uint32(), d.string(), d.uint32(), d.uint32(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok || f.open {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if name == "" || strings.Contains(name, "/") {
+		return rlerrorErrno(tag, eINVAL)
+	}
+
+	p, err := c.join(f.name, name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	flag := flag(flags) | fs.O_CREATE
+	file, err := c.server.fsys.OpenFile(p, flag, gofs.FileMode(mode).Perm())
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return rerror(tag, err)
+	}
+
+	f.file, f.flag, f.name, f.open = file, flag, p, true
+	return c.ropen(rlcreate, tag, p, fi)
+}
+
+func (c *conn) ropen(typ byte, tag uint16, name string, fi gofs.FileInfo) []byte {
+	e := newEncoder(typ, tag)
+	e.qid(c.server.qid(name, fi))
+	e.uint32(c.msize - ioHeaderSize)
+	return e.message()
+}
+
+func (c *conn) read(tag uint16, d *decoder) []byte {
+	id, off, count := d.uint32(), d.uint64(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok || !f.open {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if f.file == nil {
+		return rlerrorErrno(tag, eISDIR)
+	}
+
+	b := make([]byte, min(count, c.msize-ioHeaderSize))
+	n, err := f.file.ReadAt(b, int64(off))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return rerror(tag, err)
+	}
+
+	e := newEncoder(rread, tag)
+	e.data(b[:n])
+	return e.message()
+}
+
+func (c *conn) write(tag uint16, d *decoder) []byte {
+	id, off, data := d.uint32(), int64(d.uint64()), d.data()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok || f.file == nil {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if w, ok := f.file.(io.WriterAt); ok && f.flag&fs.O_APPEND == 0 {
+		n, err = w.WriteAt(data, off)
+	} else {
+		if f.flag&fs.O_APPEND == 0 {
+			if _, err := f.file.Seek(off, io.SeekStart); err != nil {
+				return rerror(tag, err)
+			}
+		}
+		n, err = f.file.Write(data)
+	}
+
+	if err != nil && n == 0 {
+		return rerror(tag, err)
+	}
+
+	e := newEncoder(rwrite, tag)
+	e.uint32(uint32(n))
+	return e.message()
+}
+
+func (c *conn) clunk(tag uint16, d *decoder) []byte {
+	id := d.uint32()
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+	delete(c.fids, id)
+
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return rerror(tag, err)
+		}
+	}
+	return newEncoder(rclunk, tag).message()
+}
+
+// remove removes the entry referenced by a fid, which is clunked even if the removal fails.
+func (c *conn) remove(tag uint16, d *decoder) []byte {
+	id := d.uint32()
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+	delete(c.fids, id)
+
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			c.server.logger.Debug("[ninep] remove", "name", f.name, "error", err)
+		}
+	}
+
+	fi, err := c.server.fsys.Stat(f.name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if err := c.unlink(f.name, fi.IsDir()); err != nil {
+		return rerror(tag, err)
+	}
+	return newEncoder(rremove, tag).message()
+}
+
+func (c *conn) getattr(tag uint16, d *decoder) []byte {
+	id, _ := d.uint32(), d.uint64()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	fi, err := c.server.fsys.Stat(f.name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	nlink, mode := uint64(1), uint32(modeRegular)
+	if fi.IsDir() {
+		nlink, mode = 2, modeDir
+	}
+
+	mtime := fi.ModTime()
+	size := uint64(max(fi.Size(), 0))
+
+	e := newEncoder(rgetattr, tag)
+	e.uint64(getattrBasic)
+	e.qid(c.server.qid(f.name, fi))
+	e.uint32(mode | uint32(fi.Mode().Perm()))
+	e.uint32(c.server.uid)
+	e.uint32(c.server.gid)
+	e.uint64(nlink)
+	e.uint64(0)
+	e.uint64(size)
+	e.uint64(4096)
+	e.uint64((size + 511) / 512)
+	for range 3 {
+		e.uint64(uint64(max(mtime.Unix(), 0)))
+		e.uint64(uint64(mtime.Nanosecond()))
+	}
+
+	// btime, gen and data_version are not part of the basic attributes.
+	for range 4 {
+		e.uint64(0)
+	}
+	return e.message()
+}
+
+func (c *conn) setattr(tag uint16, d *decoder) []byte {
+	id, valid, _, _, _, size := d.uint32(), d.uint32(), d.uint32(), d.uint32(), d.uint32(), d.uint64()
+	d.uint64()
+	d.uint64()
+	d.uint64()
+	d.uint64()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if valid&setattrSize != 0 {
+		if err := c.truncate(f, int64(size)); err != nil {
+			return rerror(tag, err)
+		}
+	} else if _, err := c.server.fsys.Stat(f.name); err != nil {
+		return rerror(tag, err)
+	}
+	return newEncoder(rsetattr, tag).message()
+}
+
+func (c *conn) truncate(f *fid, size int64) error {
+	if t, ok := f.file.(interface{ Truncate(size int64) error }); ok {
+		return t.Truncate(size)
+	}
+
+	fi, err := c.server.fsys.Stat(f.name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.IsDir():
+		return &gofs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrIsDir}
+	case fi.Size() == size:
+		return nil
+	case size != 0:
+		return &gofs.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	file, err := c.server.fsys.OpenFile(f.name, fs.O_WRONLY|fs.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func (c *conn) readdir(tag uint16, d *decoder) []byte {
+	id, off, count := d.uint32(), d.uint64(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok || !f.open || f.file != nil {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if off == 0 || f.entries == nil {
+		entries, err := c.entries(f.name)
+		if err != nil {
+			return rerror(tag, err)
+		}
+		f.entries = entries
+	}
+
+	count = min(count, c.msize-ioHeaderSize)
+	e := newEncoder(rreaddir, tag)
+	e.uint32(0)
+	start := len(e.b)
+	for i := off; i < uint64(len(f.entries)); i++ {
+		entry := f.entries[i]
+		if uint32(len(e.b)-start+24+len(entry.name)) > count {
+			break
+		}
+
+		typ := byte(direntTypeReg)
+		if entry.info.IsDir() {
+			typ = direntTypeDir
+		}
+
+		e.qid(c.server.qid(entry.path, entry.info))
+		e.uint64(i + 1)
+		e.uint8(typ)
+		e.string(entry.name)
+	}
+
+	binary.LittleEndian.PutUint32(e.b[start-4:], uint32(len(e.b)-start))
+	return e.message()
+}
+
+// entries returns a snapshot of the entries in the named directory, including "." and "..".
+func (c *conn) entries(name string) ([]dirent, error) {
+	fi, err := c.server.fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := c.join(name, "..")
+	if err != nil {
+		return nil, err
+	}
+
+	pfi, err := c.server.fsys.Stat(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := c.server.fsys.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]dirent, 0, len(dirEntries)+2)
+	entries = append(entries, dirent{name: ".", path: name, info: fi}, dirent{name: "..", path: parent, info: pfi})
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+
+		p, err := c.join(name, de.Name())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, dirent{name: de.Name(), path: p, info: info})
+	}
+	return entries, nil
+}
+
+func (c *conn) statfs(tag uint16, d *decoder) []byte {
+	if _, ok := c.fids[d.uint32()]; !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	e := newEncoder(rstatfs, tag)
+	e.uint32(v9fsMagic)
+	e.uint32(4096)
+	for range 6 {
+		e.uint64(0)
+	}
+	e.uint32(255)
+	return e.message()
+}
+
+func (c *conn) mkdir(tag uint16, d *decoder) []byte {
+	id, name, mode, _ := d.uint32(), d.string(), d.uint32(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if name == "" || strings.Contains(name, "/") {
+		return rlerrorErrno(tag, eINVAL)
+	}
+
+	p, err := c.join(f.name, name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if err := c.server.fsys.Mkdir(p, gofs.FileMode(mode).Perm()); err != nil {
+		return rerror(tag, err)
+	}
+
+	fi, err := c.server.fsys.Stat(p)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	e := newEncoder(rmkdir, tag)
+	e.qid(c.server.qid(p, fi))
+	return e.message()
+}
+
+func (c *conn) renameFid(tag uint16, d *decoder) []byte {
+	id, dirid, name := d.uint32(), d.uint32(), d.string()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	dir, dok := c.fids[dirid]
+	if !ok || !dok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	p, err := c.join(dir.name, name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if err := c.rename(f.name, p); err != nil {
+		return rerror(tag, err)
+	}
+	f.name = p
+	return newEncoder(rrename, tag).message()
+}
+
+func (c *conn) renameat(tag uint16, d *decoder) []byte {
+	oldid, oldname, newid, newname := d.uint32(), d.string(), d.uint32(), d.string()
+	if d.err != nil {
+		return nil
+	}
+
+	oldDir, ok := c.fids[oldid]
+	newDir, nok := c.fids[newid]
+	if !ok || !nok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	oldpath, err := c.join(oldDir.name, oldname)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	newpath, err := c.join(newDir.name, newname)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if err := c.rename(oldpath, newpath); err != nil {
+		return rerror(tag, err)
+	}
+	return newEncoder(rrenameat, tag).message()
+}
+
+func (c *conn) rename(oldpath string, newpath string) error {
+	if err := c.server.fsys.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	c.server.rename(oldpath, newpath)
+
+	for _, f := range c.fids {
+		switch {
+		case f.name == oldpath:
+			f.name = newpath
+		case strings.HasPrefix(f.name, oldpath+c.server.fsys.PathSeparator()):
+			f.name = newpath + f.name[len(oldpath):]
+		}
+	}
+	return nil
+}
+
+func (c *conn) unlinkat(tag uint16, d *decoder) []byte {
+	id, name, flags := d.uint32(), d.string(), d.uint32()
+	if d.err != nil {
+		return nil
+	}
+
+	f, ok := c.fids[id]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	p, err := c.join(f.name, name)
+	if err != nil {
+		return rerror(tag, err)
+	}
+
+	if err := c.unlink(p, flags&atRemoveDir != 0); err != nil {
+		return rerror(tag, err)
+	}
+	return newEncoder(runlinkat, tag).message()
+}
+
+// unlink removes the named entry, which must be an empty directory if dir is true, and must not be a directory
+// otherwise.
+func (c *conn) unlink(name string, dir bool) error {
+	fi, err := c.server.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dir && !fi.IsDir():
+		return &gofs.PathError{Op: "unlink", Path: name, Err: fs.ErrNotDir}
+	case !dir && fi.IsDir():
+		return &gofs.PathError{Op: "unlink", Path: name, Err: fs.ErrIsDir}
+	case dir:
+		entries, err := c.server.fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			return &gofs.PathError{Op: "unlink", Path: name, Err: fs.ErrNotEmpty}
+		}
+	}
+	return c.server.fsys.Remove(name)
+}
+
+func (c *conn) fsync(tag uint16, d *decoder) []byte {
+	f, ok := c.fids[d.uint32()]
+	if !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	if s, ok := f.file.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return rerror(tag, err)
+		}
+	}
+	return newEncoder(rfsync, tag).message()
+}
+
+// lock grants all advisory locks, since locks are not shared with other clients of the fs.FS.
+func (c *conn) lock(tag uint16, d *decoder) []byte {
+	if _, ok := c.fids[d.uint32()]; !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	e := newEncoder(rlock, tag)
+	e.uint8(lockSuccess)
+	return e.message()
+}
+
+func (c *conn) getlock(tag uint16, d *decoder) []byte {
+	id, _, start, length, procID, clientID := d.uint32(), d.uint8(), d.uint64(), d.uint64(), d.uint32(), d.string()
+	if d.err != nil {
+		return nil
+	}
+
+	if _, ok := c.fids[id]; !ok {
+		return rlerrorErrno(tag, eBADF)
+	}
+
+	e := newEncoder(rgetlock, tag)
+	e.uint8(lockTypeUnlock)
+	e.uint64(start)
+	e.uint64(length)
+	e.uint32(procID)
+	e.string(clientID)
+	return e.message()
+}
+
+func (c *conn) clunkAll() {
+	for id, f := range c.fids {
+		if f.file != nil {
+			if err := f.file.Close(); err != nil {
+				c.server.logger.Error("[ninep] clunk", "name", f.name, "error", err)
+			}
+		}
+		delete(c.fids, id)
+	}
+}
+
+// flag converts Linux open flags to flags for fs.FS.OpenFile.
+func flag(flags uint32) int {
+	var f int
+	switch flags & lOAccMode {
+	case lOWronly:
+		f = fs.O_WRONLY
+	case lORdwr:
+		f = fs.O_RDWR
+	default:
+		f = fs.O_RDONLY
+	}
+
+	if flags&lOCreat != 0 {
+		f |= fs.O_CREATE
+	}
+
+	if flags&lOExcl != 0 {
+		f |= os.O_EXCL
+	}
+
+	if flags&lOTrunc != 0 {
+		f |= fs.O_TRUNC
+	}
+
+	if flags&lOAppend != 0 {
+		f |= fs.O_APPEND
+	}
+	return f
+}
+
+// join joins a path for the fs.FS and a path element provided by a client. The path is resolved using fs.SecureJoin,
+// so that neither ".." elements nor symbolic links can refer to a location outside of the fs.FS.
+func (c *conn) join(name string, elem string) (string, error) {
+	return fs.SecureJoin(c.server.fsys, ".", name+"/"+elem)
+}
+
+func rlerrorErrno(tag uint16, errno uint32) []byte {
+	e := newEncoder(rlerror, tag)
+	e.uint32(errno)
+	return e.message()
+}
+
+// rerror returns an Rlerror message with the error number that corresponds to err.
+func rerror(tag uint16, err error) []byte {
+	return rlerrorErrno(tag, errno(err))
+}
+
+func errno(err error) uint32 {
+	switch {
+	case errors.Is(err, gofs.ErrNotExist):
+		return eNOENT
+	case errors.Is(err, gofs.ErrExist):
+		return eEXIST
+	case errors.Is(err, gofs.ErrPermission):
+		return eACCES
+	case errors.Is(err, gofs.ErrClosed):
+		return eBADF
+	case errors.Is(err, gofs.ErrInvalid), errors.Is(err, errShortMessage):
+		return eINVAL
+	case errors.Is(err, fs.ErrIsDir):
+		return eISDIR
+	case errors.Is(err, fs.ErrNotDir):
+		return eNOTDIR
+	case errors.Is(err, fs.ErrNotEmpty):
+		return eNOTEMPTY
+	case errors.Is(err, fs.ErrQuotaExceeded):
+		return eDQUOT
+	case errors.Is(err, fs.ErrTooLarge):
+		return eFBIG
+	case errors.Is(err, errors.ErrUnsupported):
+		return eNOTSUP
+	}
+	return eIO
+}
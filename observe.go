@@ -0,0 +1,507 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// defaultSubscriptionBuffer is the default number of events buffered by a Subscription.
+const defaultSubscriptionBuffer = 64
+
+// Enumeration of the policies for delivering events to a Subscription whose buffer is full.
+const (
+	// SlowConsumerDropNewest discards the event that could not be buffered, which is the default policy.
+	SlowConsumerDropNewest SlowConsumerPolicy = iota
+
+	// SlowConsumerDropOldest discards the oldest buffered event to make room for the new event.
+	SlowConsumerDropOldest
+
+	// SlowConsumerBlock blocks the operation that produced the event until the event is buffered, so that no events
+	// are lost at the cost of delaying every operation on the file system.
+	SlowConsumerBlock
+
+	// SlowConsumerClose closes the Subscription, so that the consumer can detect that events were lost and resubscribe.
+	SlowConsumerClose
+)
+
+var (
+	_ CapabilityFS = (*observedFS)(nil)
+	_ ObservedFS   = (*observedFS)(nil)
+	_ WatchFS      = (*observedFS)(nil)
+)
+
+// SlowConsumerPolicy defines how events are delivered to a Subscription whose buffer is full.
+type SlowConsumerPolicy int
+
+// SubscribeOption defines an option for ObservedFS.Subscribe.
+type SubscribeOption func(*Subscription)
+
+// ObservedFS is an FS that publishes an Event for each change made through it to the subscriptions created using
+// Subscribe. See Observe.
+type ObservedFS interface {
+	FS
+
+	// Subscribe returns a Subscription to the events for paths that match pattern. The pattern uses the syntax of
+	// path.Match, and additionally matches every path if it is empty or "**", and every path under a directory if it
+	// ends with "/**". An event for a rename matches if either its old or new path matches.
+	Subscribe(pattern string, options ...SubscribeOption) (*Subscription, error)
+}
+
+// Subscription receives the events published by an ObservedFS for the paths that match its pattern.
+type Subscription struct {
+	ch      chan Event
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Uint64
+	mutex   sync.RWMutex
+	obs     *observedFS
+	ops     EventOp
+	pattern string
+	policy  SlowConsumerPolicy
+	size    int
+}
+
+// Close stops the delivery of events to the Subscription and closes the channel returned by Events.
+func (s *Subscription) Close() error {
+	s.obs.unsubscribe(s)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	close(s.ch)
+	return nil
+}
+
+// Dropped returns the number of events that were discarded because the buffer of the Subscription was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Events returns the channel that receives the events of the Subscription. The channel is closed when the Subscription
+// is closed, including when it is closed by the SlowConsumerClose policy.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// deliver sends e to the Subscription using its slow-consumer policy, and returns false if the Subscription must be
+// closed.
+func (s *Subscription) deliver(e Event) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		return true
+	}
+
+	switch s.policy {
+	case SlowConsumerBlock:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		}
+	case SlowConsumerDropOldest:
+		for {
+			select {
+			case s.ch <- e:
+				return true
+			default:
+			}
+
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	case SlowConsumerClose:
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+			return false
+		}
+	default:
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	return true
+}
+
+// matches returns whether e matches the pattern and operations of the Subscription.
+func (s *Subscription) matches(e Event) bool {
+	if s.ops != 0 && s.ops&e.Op == 0 {
+		return false
+	}
+	return matchEventPath(s.pattern, e.Path) || (e.OldPath != "" && matchEventPath(s.pattern, e.OldPath))
+}
+
+// Observe returns an ObservedFS that passes every operation through to fsys, and publishes an Event for each entry that
+// is created, written, removed, or renamed through it, so that multiple components can react to the changes made to
+// the same file system independently.
+//
+// Events are published synchronously once the operation that produced them has completed, in the order the operations
+// completed. An EventWrite is published when a file opened for writing is closed, if it was written or truncated. The
+// paths of events are the paths passed to the operations. Changes made to fsys directly, or through the sub-trees
+// returned by Sub, are not observed.
+//
+// The returned FS also implements WatchFS. Closing it closes every Subscription, but does not close fsys.
+func Observe(fsys FS) ObservedFS {
+	if o, ok := fsys.(*observedFS); ok {
+		return o
+	}
+	return &observedFS{fsys: fsys}
+}
+
+// WithSubscriptionBuffer sets the number of events buffered by a Subscription before its slow-consumer policy applies.
+// The default is 64.
+func WithSubscriptionBuffer(n int) SubscribeOption {
+	return func(s *Subscription) {
+		s.size = max(n, 0)
+	}
+}
+
+// WithSubscriptionOps sets the operations of the events received by a Subscription, such as EventCreate|EventRemove.
+// By default, events for every operation are received.
+func WithSubscriptionOps(ops EventOp) SubscribeOption {
+	return func(s *Subscription) {
+		s.ops = ops
+	}
+}
+
+// WithSlowConsumerPolicy sets how events are delivered to a Subscription whose buffer is full. The default is
+// SlowConsumerDropNewest.
+func WithSlowConsumerPolicy(policy SlowConsumerPolicy) SubscribeOption {
+	return func(s *Subscription) {
+		s.policy = policy
+	}
+}
+
+type observedFS struct {
+	fsys  FS
+	mutex sync.RWMutex
+	subs  []*Subscription
+}
+
+// Capabilities returns the capabilities of the underlying file system that are retained by the ObservedFS.
+func (o *observedFS) Capabilities() Capability {
+	return Capabilities(o.fsys) & CapAtomicRename
+}
+
+func (o *observedFS) Close() error {
+	o.mutex.RLock()
+	subs := append([]*Subscription(nil), o.subs...)
+	o.mutex.RUnlock()
+
+	for _, s := range subs {
+		_ = s.Close()
+	}
+	return nil
+}
+
+func (o *observedFS) Create(name string) (File, error) {
+	return o.OpenFile(name, O_RDWR|O_CREATE|O_TRUNC, 0666)
+}
+
+func (o *observedFS) Glob(pattern string) ([]string, error) {
+	return o.fsys.Glob(pattern)
+}
+
+func (o *observedFS) Mkdir(name string, perm gofs.FileMode) error {
+	if err := o.fsys.Mkdir(name, perm); err != nil {
+		return err
+	}
+	o.publish(Event{Op: EventCreate, Path: name})
+	return nil
+}
+
+func (o *observedFS) MkdirAll(path string, perm gofs.FileMode) error {
+	// The directories that do not exist are found before they are created, so that an event is published for each.
+	var missing []string
+	for p := path; ; p = Dir(o.fsys, p) {
+		if _, err := o.fsys.Stat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+
+		if Dir(o.fsys, p) == p {
+			break
+		}
+	}
+
+	if err := o.fsys.MkdirAll(path, perm); err != nil {
+		return err
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if missing[i] != "." {
+			o.publish(Event{Op: EventCreate, Path: missing[i]})
+		}
+	}
+	return nil
+}
+
+func (o *observedFS) Open(name string) (gofs.File, error) {
+	return o.fsys.Open(name)
+}
+
+func (o *observedFS) OpenFile(name string, flag int, perm gofs.FileMode) (File, error) {
+	if flag&(O_WRONLY|O_RDWR|O_APPEND|O_CREATE|O_TRUNC) == 0 {
+		return o.fsys.OpenFile(name, flag, perm)
+	}
+
+	created := false
+	if flag&O_CREATE != 0 {
+		_, err := o.fsys.Stat(name)
+		created = errors.Is(err, gofs.ErrNotExist)
+	}
+
+	f, err := o.fsys.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		o.publish(Event{Op: EventCreate, Path: name})
+	}
+	return &observedFile{File: f, name: name, obs: o, written: flag&O_TRUNC != 0 && !created}, nil
+}
+
+func (o *observedFS) PathSeparator() string {
+	return o.fsys.PathSeparator()
+}
+
+func (o *observedFS) Provider() string {
+	return o.fsys.Provider()
+}
+
+func (o *observedFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return o.fsys.ReadDir(name)
+}
+
+func (o *observedFS) ReadFile(name string) ([]byte, error) {
+	return o.fsys.ReadFile(name)
+}
+
+func (o *observedFS) Remove(name string) error {
+	fi, _ := o.fsys.Stat(name)
+	if err := o.fsys.Remove(name); err != nil {
+		return err
+	}
+	o.publish(Event{Op: EventRemove, Path: name, Size: sizeOf(fi)})
+	return nil
+}
+
+func (o *observedFS) RemoveAll(path string) error {
+	fi, err := o.fsys.Stat(path)
+	if err := o.fsys.RemoveAll(path); err != nil {
+		return err
+	}
+
+	if err == nil {
+		o.publish(Event{Op: EventRemove, Path: path, Size: sizeOf(fi)})
+	}
+	return nil
+}
+
+func (o *observedFS) Rename(oldpath string, newpath string) error {
+	if err := o.fsys.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	fi, _ := o.fsys.Stat(newpath)
+	o.publish(Event{Op: EventRename, Path: newpath, OldPath: oldpath, Size: sizeOf(fi)})
+	return nil
+}
+
+func (o *observedFS) Root() (string, error) {
+	return o.fsys.Root()
+}
+
+func (o *observedFS) Stat(name string) (gofs.FileInfo, error) {
+	return o.fsys.Stat(name)
+}
+
+// Sub returns the sub-tree for dir from the underlying file system. Changes made through the sub-tree are not observed.
+func (o *observedFS) Sub(dir string) (gofs.FS, error) {
+	return o.fsys.Sub(dir)
+}
+
+// Subscribe returns a Subscription to the events for paths that match pattern.
+func (o *observedFS) Subscribe(pattern string, options ...SubscribeOption) (*Subscription, error) {
+	if _, err := gopath.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+		return nil, fmt.Errorf("fs: pattern %q is invalid: %w", pattern, err)
+	}
+
+	s := &Subscription{done: make(chan struct{}), obs: o, pattern: pattern, size: defaultSubscriptionBuffer}
+	for _, opt := range options {
+		opt(s)
+	}
+	s.ch = make(chan Event, s.size)
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.subs = append(o.subs, s)
+	return s, nil
+}
+
+// Watch calls handler with an Event for each change made through the ObservedFS to the named entry, or to the entries
+// of the named directory, until the returned stop function is called. The handler is called from a separate goroutine,
+// in the order the events were published, and events are not discarded if the handler is slow.
+func (o *observedFS) Watch(name string, handler func(Event)) (func() error, error) {
+	if handler == nil {
+		return nil, errors.New("fs: handler is required")
+	}
+
+	s, err := o.Subscribe("", WithSlowConsumerPolicy(SlowConsumerBlock))
+	if err != nil {
+		return nil, err
+	}
+
+	watched := func(p string) bool {
+		return p != "" && (p == name || Dir(o.fsys, p) == name)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for e := range s.Events() {
+			if watched(e.Path) || watched(e.OldPath) {
+				handler(e)
+			}
+		}
+	}()
+
+	return func() error {
+		err := s.Close()
+		wg.Wait()
+		return err
+	}, nil
+}
+
+func (o *observedFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	_, err := o.fsys.Stat(name)
+	created := errors.Is(err, gofs.ErrNotExist)
+
+	if err := o.fsys.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+
+	if created {
+		o.publish(Event{Op: EventCreate, Path: name})
+	}
+	o.publish(Event{Op: EventWrite, Path: name, Size: int64(len(data))})
+	return nil
+}
+
+// publish delivers e to every Subscription that matches it.
+func (o *observedFS) publish(e Event) {
+	e.Time = time.Now()
+
+	o.mutex.RLock()
+	subs := append([]*Subscription(nil), o.subs...)
+	o.mutex.RUnlock()
+
+	for _, s := range subs {
+		if s.matches(e) && !s.deliver(e) {
+			_ = s.Close()
+		}
+	}
+}
+
+// unsubscribe removes s from the subscriptions of the ObservedFS.
+func (o *observedFS) unsubscribe(s *Subscription) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i, sub := range o.subs {
+		if sub == s {
+			o.subs = append(o.subs[:i], o.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// observedFile publishes an EventWrite when a file that was written or truncated is closed.
+type observedFile struct {
+	File
+	name    string
+	obs     *observedFS
+	once    sync.Once
+	written bool
+}
+
+func (f *observedFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(func() {
+		if err == nil && f.written {
+			fi, _ := f.obs.fsys.Stat(f.name)
+			f.obs.publish(Event{Op: EventWrite, Path: f.name, Size: sizeOf(fi)})
+		}
+	})
+	return err
+}
+
+func (f *observedFile) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(f.File, r)
+	if n > 0 {
+		f.written = true
+	}
+	return n, err
+}
+
+func (f *observedFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if n > 0 {
+		f.written = true
+	}
+	return n, err
+}
+
+// matchEventPath returns whether the path p of an event matches pattern.
+func matchEventPath(pattern string, p string) bool {
+	switch {
+	case pattern == "" || pattern == "**":
+		return true
+	case strings.HasSuffix(pattern, "/**"):
+		prefix := strings.TrimSuffix(pattern, "/**")
+		if ok, _ := gopath.Match(prefix, p); ok {
+			return true
+		}
+
+		for d := gopath.Dir(p); d != "." && d != "/"; d = gopath.Dir(d) {
+			if ok, _ := gopath.Match(prefix, d); ok {
+				return true
+			}
+		}
+		return false
+	default:
+		ok, _ := gopath.Match(pattern, p)
+		return ok
+	}
+}
+
+// sizeOf returns the size of the entry described by fi, or 0 if fi is nil.
+func sizeOf(fi gofs.FileInfo) int64 {
+	if fi == nil {
+		return 0
+	}
+	return fi.Size()
+}
@@ -0,0 +1,174 @@
+package fs_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gopath "path"
+)
+
+func TestObserve(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := fs.Observe(mfs)
+	assert.Same(t, ofs, fs.Observe(ofs))
+	assert.True(t, fs.Capabilities(ofs).Has(fs.CapWatch|fs.CapAtomicRename))
+
+	all, err := ofs.Subscribe("")
+	assert.NoError(t, err)
+
+	docs, err := ofs.Subscribe("doc/**")
+	assert.NoError(t, err)
+
+	txt, err := ofs.Subscribe("doc/*.txt", fs.WithSubscriptionOps(fs.EventWrite))
+	assert.NoError(t, err)
+
+	_, err = ofs.Subscribe("[")
+	assert.ErrorIs(t, err, gopath.ErrBadPattern)
+
+	assert.NoError(t, ofs.MkdirAll("doc/animals", 0755))
+	assert.NoError(t, ofs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+
+	f, err := ofs.Create("doc/animals/dog.md")
+	assert.NoError(t, err)
+
+	_, err = f.Write([]byte("the lazy dog"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	assert.NoError(t, ofs.Rename("doc/fox.txt", "fox.txt"))
+	assert.NoError(t, ofs.RemoveAll("doc"))
+	assert.NoError(t, ofs.Remove("fox.txt"))
+	assert.Error(t, ofs.Remove("fox.txt"))
+
+	assert.Equal(t, []fs.Event{
+		{Op: fs.EventCreate, Path: "doc"},
+		{Op: fs.EventCreate, Path: "doc/animals"},
+		{Op: fs.EventCreate, Path: "doc/fox.txt"},
+		{Op: fs.EventWrite, Path: "doc/fox.txt", Size: 19},
+		{Op: fs.EventCreate, Path: "doc/animals/dog.md"},
+		{Op: fs.EventWrite, Path: "doc/animals/dog.md", Size: 12},
+		{Op: fs.EventRename, Path: "fox.txt", OldPath: "doc/fox.txt", Size: 19},
+		{Op: fs.EventRemove, Path: "doc"},
+		{Op: fs.EventRemove, Path: "fox.txt", Size: 19},
+	}, drain(t, all))
+
+	assert.Len(t, drain(t, docs), 8)
+	assert.Equal(t, []fs.Event{{Op: fs.EventWrite, Path: "doc/fox.txt", Size: 19}}, drain(t, txt))
+
+	assert.NoError(t, ofs.Close())
+
+	_, ok := <-all.Events()
+	assert.False(t, ok)
+}
+
+func TestObserve_SlowConsumerPolicy(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := fs.Observe(mfs)
+
+	newest, err := ofs.Subscribe("*", fs.WithSubscriptionBuffer(2))
+	assert.NoError(t, err)
+
+	oldest, err := ofs.Subscribe("*", fs.WithSubscriptionBuffer(2), fs.WithSlowConsumerPolicy(fs.SlowConsumerDropOldest))
+	assert.NoError(t, err)
+
+	closed, err := ofs.Subscribe("*", fs.WithSubscriptionBuffer(2), fs.WithSlowConsumerPolicy(fs.SlowConsumerClose))
+	assert.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, ofs.Mkdir(name, 0755))
+	}
+
+	assert.Equal(t, []string{"a", "b"}, paths(drain(t, newest)))
+	assert.Equal(t, uint64(2), newest.Dropped())
+
+	assert.Equal(t, []string{"c", "d"}, paths(drain(t, oldest)))
+	assert.Equal(t, uint64(2), oldest.Dropped())
+
+	var events []fs.Event
+	for e := range closed.Events() {
+		events = append(events, e)
+	}
+	assert.Equal(t, []string{"a", "b"}, paths(events))
+	assert.Equal(t, uint64(1), closed.Dropped())
+	assert.NoError(t, closed.Close())
+
+	assert.NoError(t, newest.Close())
+	assert.NoError(t, oldest.Close())
+}
+
+func TestObserve_Watch(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := fs.Observe(mfs)
+	assert.NoError(t, ofs.MkdirAll("doc/animals", 0755))
+
+	var (
+		events []fs.Event
+		mutex  sync.Mutex
+	)
+	stop, err := ofs.(fs.WatchFS).Watch("doc", func(e fs.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, e)
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ofs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, ofs.WriteFile("doc/animals/dog.md", []byte("the lazy dog"), 0644))
+	assert.NoError(t, ofs.WriteFile("cat.txt", []byte("the cat"), 0644))
+	assert.NoError(t, ofs.Rename("cat.txt", "doc/cat.txt"))
+	assert.NoError(t, stop())
+
+	assert.NoError(t, ofs.Remove("doc/fox.txt"))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"doc/fox.txt", "doc/fox.txt", "doc/cat.txt"}, paths(events))
+}
+
+// drain returns the events buffered by the Subscription s without blocking.
+func drain(t *testing.T, s *fs.Subscription) []fs.Event {
+	t.Helper()
+
+	var events []fs.Event
+	for {
+		select {
+		case e, ok := <-s.Events():
+			if !ok {
+				return events
+			}
+			// The time of each event is cleared, so that events can be compared.
+			assert.False(t, e.Time.IsZero())
+			e.Time = time.Time{}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+// paths returns the path of each event.
+func paths(events []fs.Event) []string {
+	var p []string
+	for _, e := range events {
+		p = append(p, e.Path)
+	}
+	return p
+}
@@ -1,67 +1,199 @@
 package fs
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	gofs "io/fs"
 )
 
 var (
-	_ FS = (*OSFS)(nil)
+	_ CapabilityFS    = (*OSFS)(nil)
+	_ ChmodFS         = (*OSFS)(nil)
+	_ ChtimesFS       = (*OSFS)(nil)
+	_ CopyFS          = (*OSFS)(nil)
+	_ FS              = (*OSFS)(nil)
+	_ PathValidatorFS = (*OSFS)(nil)
+	_ StatFSer        = (*OSFS)(nil)
+	_ SymlinkFS       = (*OSFS)(nil)
 )
 
+// OSFSOption defines an option for New.
+type OSFSOption func(*OSFS)
+
 // OSFS os/platform file system provider that implements FS.
-type OSFS struct{}
+type OSFS struct {
+	names       NameRules
+	ring        *uring
+	ringEntries int
+}
 
 // New creates a new OSFS.
-func New() (*OSFS, error) {
-	return &OSFS{}, nil
+//
+// The names of entries created by the OSFS are validated using WindowsNameRules on Windows, and POSIXNameRules on
+// other platforms, unless other rules are set using WithNameRules.
+func New(options ...OSFSOption) (*OSFS, error) {
+	o := &OSFS{names: POSIXNameRules()}
+	if runtime.GOOS == "windows" {
+		o.names = WindowsNameRules()
+	}
+
+	for _, opt := range options {
+		opt(o)
+	}
+
+	if o.ringEntries != 0 {
+		r, err := newURing(o.ringEntries)
+		if err != nil {
+			return nil, err
+		}
+		o.ring = r
+	}
+	return o, nil
+}
+
+// Capabilities returns the capabilities of OSFS that are not detected from the methods it implements.
+func (o *OSFS) Capabilities() Capability {
+	return CapAtomicRename
+}
+
+func (o *OSFS) Chmod(name string, mode gofs.FileMode) error {
+	return o.wrap(os.Chmod(name, mode))
+}
+
+func (o *OSFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return o.wrap(os.Chtimes(name, atime, mtime))
 }
 
+// Close releases the io_uring instance of the OSFS if it was created using WithIOUring, once the reads and writes in
+// progress are complete. Files opened by the OSFS must not be read or written once it is closed.
 func (o *OSFS) Close() error {
+	if o.ring != nil {
+		return o.ring.close()
+	}
 	return nil
 }
 
+// CopyFile copies src to dst by cloning the file where the platform and file system support it (e.g. a reflink on
+// Btrfs or XFS). An error wrapping errors.ErrUnsupported is returned if the file cannot be cloned.
+func (o *OSFS) CopyFile(dst string, src string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return o.wrap(err)
+	}
+	defer r.Close()
+
+	fi, err := r.Stat()
+	if err != nil {
+		return o.wrap(err)
+	}
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return o.wrap(err)
+	}
+
+	if err := reflink(w, r); err != nil {
+		_ = w.Close()
+		return NewLinkOpError(o.Provider(), "copy", src, dst, ErrUnsupported)
+	}
+	return o.wrap(w.Close())
+}
+
 func (o *OSFS) Open(name string) (gofs.File, error) {
-	return os.Open(name)
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, o.wrap(err)
+	}
+	return o.file(f), nil
 }
 
 func (o *OSFS) Glob(pattern string) ([]string, error) {
-	return filepath.Glob(pattern)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, NewOpError(o.Provider(), "glob", pattern, err)
+	}
+	return matches, nil
 }
 
 func (o *OSFS) ReadFile(name string) ([]byte, error) {
-	return os.ReadFile(name)
+	b, err := os.ReadFile(name)
+	return b, o.wrap(err)
 }
 
 func (o *OSFS) ReadDir(name string) ([]gofs.DirEntry, error) {
-	return os.ReadDir(name)
+	entries, err := os.ReadDir(name)
+	return entries, o.wrap(err)
 }
 
+// Stat returns an *Entry describing the named file, including its access time and birth time where they are supported
+// by the platform and file system.
 func (o *OSFS) Stat(name string) (gofs.FileInfo, error) {
-	return os.Stat(name)
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, o.wrap(err)
+	}
+	return toEntry(name, fi, fileTimes(name, fi)...)
 }
 
 func (o *OSFS) Sub(dir string) (gofs.FS, error) {
-	return gofs.Sub(o, dir)
+	return Chroot(o, dir)
 }
 
 func (o *OSFS) Create(name string) (File, error) {
-	return os.Create(name)
+	if err := o.ValidatePath(name); err != nil {
+		return nil, NewOpError(o.Provider(), "open", name, err)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, o.wrap(err)
+	}
+	return o.file(f), nil
 }
 
 func (o *OSFS) Mkdir(name string, perm gofs.FileMode) error {
-	return os.Mkdir(name, perm)
+	if err := o.ValidatePath(name); err != nil {
+		return NewOpError(o.Provider(), "mkdir", name, err)
+	}
+	return o.wrap(os.Mkdir(name, perm))
 }
 
 func (o *OSFS) MkdirAll(path string, perm gofs.FileMode) error {
-	return os.MkdirAll(path, perm)
+	if err := o.ValidatePath(path); err != nil {
+		return NewOpError(o.Provider(), "mkdir", path, err)
+	}
+	return o.wrap(os.MkdirAll(path, perm))
 }
 
 func (o *OSFS) OpenFile(name string, flag int, perm gofs.FileMode) (File, error) {
-	return os.OpenFile(name, flag, perm)
+	if flag&O_IFMATCH != 0 {
+		return nil, NewOpError(o.Provider(), "open", name, ErrUnsupported)
+	}
+
+	if flag&O_CREATE != 0 {
+		if err := o.ValidatePath(name); err != nil {
+			return nil, NewOpError(o.Provider(), "open", name, err)
+		}
+	}
+
+	var (
+		f   *os.File
+		err error
+	)
+	if flag&O_DIRECT != 0 {
+		f, err = openDirect(name, flag&^O_DIRECT, perm)
+	} else {
+		f, err = os.OpenFile(name, flag, perm)
+	}
+
+	if err != nil {
+		return nil, o.wrap(err)
+	}
+	return o.file(f), nil
 }
 
 func (o *OSFS) PathSeparator() string {
@@ -73,22 +205,107 @@ func (o *OSFS) Provider() string {
 	return runtime.GOOS
 }
 
+func (o *OSFS) Readlink(name string) (string, error) {
+	dest, err := os.Readlink(name)
+	return dest, o.wrap(err)
+}
+
 func (o *OSFS) Remove(name string) error {
-	return os.Remove(name)
+	return o.wrap(os.Remove(name))
 }
 
 func (o *OSFS) RemoveAll(path string) error {
-	return os.RemoveAll(path)
+	return o.wrap(os.RemoveAll(path))
 }
 
 func (o *OSFS) Rename(oldpath string, newpath string) error {
-	return os.Rename(oldpath, newpath)
+	if err := o.ValidatePath(newpath); err != nil {
+		return NewLinkOpError(o.Provider(), "rename", oldpath, newpath, err)
+	}
+	return o.wrap(os.Rename(oldpath, newpath))
 }
 
 func (o *OSFS) Root() (string, error) {
 	return o.PathSeparator(), nil
 }
 
+func (o *OSFS) Symlink(oldname string, newname string) error {
+	if err := o.ValidatePath(newname); err != nil {
+		return NewLinkOpError(o.Provider(), "symlink", oldname, newname, err)
+	}
+	return o.wrap(os.Symlink(oldname, newname))
+}
+
+// Usage returns the capacity and usage of the file system that contains the root directory, using statfs on Unix
+// platforms and GetDiskFreeSpaceEx on Windows. The number of files is not reported on Windows, and an error wrapping
+// ErrUnsupported is returned on other platforms.
+func (o *OSFS) Usage() (uint64, uint64, uint64, uint64, error) {
+	root, err := o.Root()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	total, used, free, files, err := statfs(root)
+	if err != nil {
+		return 0, 0, 0, 0, NewOpError(o.Provider(), "usage", root, err)
+	}
+	return total, used, free, files, nil
+}
+
+// ValidatePath returns a *NameError if the named path violates the rules for the names of entries created by the OSFS.
+// The volume name of the path is not validated.
+func (o *OSFS) ValidatePath(name string) error {
+	return o.names.Validate(filepath.ToSlash(name[len(filepath.VolumeName(name)):]), "/")
+}
+
 func (o *OSFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
-	return os.WriteFile(name, data, perm)
+	if err := o.ValidatePath(name); err != nil {
+		return NewOpError(o.Provider(), "open", name, err)
+	}
+	return o.wrap(os.WriteFile(name, data, perm))
+}
+
+// file returns f as a File that implements MmapFile, and whose reads and writes are performed using io_uring if the
+// OSFS was created using WithIOUring.
+func (o *OSFS) file(f *os.File) File {
+	if o.ring != nil {
+		return o.ring.file(f)
+	}
+	return &osFile{File: f}
+}
+
+// wrap returns err as an *OpError with the operation and paths of the *os.PathError or *os.LinkError returned by the
+// os package, so that errors returned by OSFS match the errors defined by this package using errors.Is.
+func (o *OSFS) wrap(err error) error {
+	var le *os.LinkError
+	if errors.As(err, &le) {
+		return NewLinkOpError(o.Provider(), le.Op, le.Old, le.New, le.Err)
+	}
+
+	var pe *gofs.PathError
+	if errors.As(err, &pe) {
+		return NewOpError(o.Provider(), pe.Op, pe.Path, pe.Err)
+	}
+	return err
+}
+
+// WithIOUring sets the OSFS to perform the reads and writes of the files it opens using an io_uring instance with a
+// submission queue of the given number of entries, which reduces the number of system calls for workloads that perform
+// many small reads, such as reading many ranges of a file using ReadAtMulti, which submits the reads for every range
+// at once. The completions are dispatched by a poller goroutine until the OSFS is closed.
+//
+// New returns an error wrapping ErrUnsupported if io_uring is not supported by the platform or is disabled, for
+// example by a seccomp policy, so that callers can fall back to an OSFS created without the option.
+func WithIOUring(entries int) OSFSOption {
+	return func(o *OSFS) {
+		o.ringEntries = entries
+	}
+}
+
+// WithNameRules sets the rules used by the OSFS to validate the names of the entries it creates, for example to reject
+// names that are invalid on Windows when the files are shared with Windows clients.
+func WithNameRules(rules NameRules) OSFSOption {
+	return func(o *OSFS) {
+		o.names = rules
+	}
 }
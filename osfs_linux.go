@@ -0,0 +1,34 @@
+//go:build linux
+
+package fs
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	gofs "io/fs"
+)
+
+// fileTimes returns the options for the access time of the named file provided by fi, and its birth time if it is
+// supported by the file system.
+func fileTimes(name string, fi gofs.FileInfo) []func(*Attribute) {
+	var attrs []func(*Attribute)
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		attrs = append(attrs, WithAtime(time.Unix(st.Atim.Unix())))
+	}
+
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, name, unix.AT_STATX_DONT_SYNC, unix.STATX_BTIME, &stx); err == nil &&
+		stx.Mask&unix.STATX_BTIME != 0 {
+		attrs = append(attrs, WithBtime(time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))))
+	}
+	return attrs
+}
+
+// reflink clones the content of src into dst using the FICLONE ioctl.
+func reflink(dst *os.File, src *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package fs
+
+import (
+	"errors"
+	"os"
+
+	gofs "io/fs"
+)
+
+// fileTimes returns no options, since access and birth times are not supported on this platform.
+func fileTimes(_ string, _ gofs.FileInfo) []func(*Attribute) {
+	return nil
+}
+
+// reflink is not supported on this platform.
+func reflink(_ *os.File, _ *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,131 @@
+package overlayfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var (
+	_ fs.File = (*dir)(nil)
+	_ fs.File = (*file)(nil)
+)
+
+// dir provides read access to the merged entries for a directory provided by OverlayFS.
+type dir struct {
+	closed  bool
+	entries []gofs.DirEntry
+	info    gofs.FileInfo
+	mutex   sync.Mutex
+	off     int
+}
+
+func newDir(info gofs.FileInfo, entries []gofs.DirEntry) *dir {
+	return &dir{entries: entries, info: info}
+}
+
+func (d *dir) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.closed {
+		d.closed = true
+		return nil
+	}
+	return fmt.Errorf("overlayfs_dir: %w", &gofs.PathError{Op: "close", Path: d.info.Name(), Err: gofs.ErrClosed})
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, d.isDir("read")
+}
+
+func (d *dir) ReadAt([]byte, int64) (int, error) {
+	return 0, d.isDir("readAt")
+}
+
+func (d *dir) ReadFrom(io.Reader) (int64, error) {
+	return 0, d.isDir("readFrom")
+}
+
+func (d *dir) ReadDir(n int) ([]gofs.DirEntry, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil, fmt.Errorf("overlayfs_dir: %w", &gofs.PathError{Op: "readDir", Path: d.info.Name(), Err: gofs.ErrClosed})
+	}
+
+	remaining := d.entries[d.off:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(remaining) {
+			remaining = remaining[:n]
+		}
+	}
+	d.off += len(remaining)
+	return append([]gofs.DirEntry(nil), remaining...), nil
+}
+
+func (d *dir) Seek(int64, int) (int64, error) {
+	return 0, d.isDir("seek")
+}
+
+func (d *dir) Stat() (gofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Write([]byte) (int, error) {
+	return 0, d.isDir("write")
+}
+
+func (d *dir) isDir(op string) error {
+	return fmt.Errorf("overlayfs_dir: %w", &gofs.PathError{Op: op, Path: d.info.Name(), Err: fs.ErrIsDir})
+}
+
+// file adapts a gofs.File opened from a lower layer to fs.File. Write operations are rejected with
+// gofs.ErrPermission.
+type file struct {
+	gofs.File
+	name string
+}
+
+func newFile(name string, f gofs.File) *file {
+	return &file{File: f, name: name}
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	if r, ok := f.File.(io.ReaderAt); ok {
+		return r.ReadAt(b, off)
+	}
+	return 0, fmt.Errorf("overlayfs_file: %w", &gofs.PathError{Op: "readAt", Path: f.name, Err: errors.ErrUnsupported})
+}
+
+func (f *file) ReadDir(n int) ([]gofs.DirEntry, error) {
+	if d, ok := f.File.(gofs.ReadDirFile); ok {
+		return d.ReadDir(n)
+	}
+	return nil, fmt.Errorf("overlayfs_file: %w", &gofs.PathError{Op: "readDir", Path: f.name, Err: fs.ErrNotDir})
+}
+
+func (f *file) ReadFrom(io.Reader) (int64, error) {
+	return 0, fmt.Errorf("overlayfs_file: %w", &gofs.PathError{Op: "readFrom", Path: f.name, Err: gofs.ErrPermission})
+}
+
+func (f *file) Seek(off int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(off, whence)
+	}
+	return 0, fmt.Errorf("overlayfs_file: %w", &gofs.PathError{Op: "seek", Path: f.name, Err: errors.ErrUnsupported})
+}
+
+func (f *file) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("overlayfs_file: %w", &gofs.PathError{Op: "write", Path: f.name, Err: gofs.ErrPermission})
+}
@@ -0,0 +1,885 @@
+package overlayfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	pathSeparator  = "/"
+	whiteoutPrefix = ".wh."
+	opaqueMarker   = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+var _ fs.FS = (*OverlayFS)(nil)
+
+// OverlayFS union file system provider that implements fs.FS.
+//
+// An OverlayFS layers a single writable upper fs.FS on top of zero or more read-only lower file systems. Reads are
+// resolved top-down: the upper layer first, followed by the lower layers in the order they were provided. Writes are
+// always applied to the upper layer, copying files and directories up from the lower layers as needed.
+//
+// Removing an entry that exists in a lower layer records a whiteout file (".wh.<name>") in the upper layer, and a
+// directory recreated over a whiteout is marked opaque (".wh..wh..opq") so the lower contents do not reappear. Names
+// using the whiteout prefix are reserved.
+type OverlayFS struct {
+	closed bool
+	logger fs.Logger
+	lower  []gofs.FS
+	mutex  sync.RWMutex
+	upper  fs.FS
+}
+
+// New creates a new OverlayFS using upper as the writable layer and lower as the read-only layers, in order of
+// precedence.
+func New(upper fs.FS, lower []gofs.FS, options ...func(*OverlayFS)) (*OverlayFS, error) {
+	if upper == nil {
+		return nil, errors.New("overlayfs: upper layer is required")
+	}
+
+	for i, l := range lower {
+		if l == nil {
+			return nil, fmt.Errorf("overlayfs: lower layer %d is nil", i)
+		}
+	}
+
+	o := &OverlayFS{logger: fs.NopLogger(), lower: lower, upper: upper}
+	for _, opt := range options {
+		opt(o)
+	}
+	return o, nil
+}
+
+// Close ...
+func (o *OverlayFS) Close() error {
+	if o == nil {
+		return gofs.ErrInvalid
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if !o.closed {
+		o.closed = true
+		return nil
+	}
+	return fmt.Errorf("overlayfs: %w", gofs.ErrClosed)
+}
+
+// Create ...
+func (o *OverlayFS) Create(name string) (fs.File, error) {
+	o.logger.Debug("[overlayfs] create", "name", name)
+	return o.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob ...
+func (o *OverlayFS) Glob(pattern string) ([]string, error) {
+	o.logger.Debug("[overlayfs] glob", "pattern", pattern)
+
+	if _, err := gopath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "glob", Path: pattern, Err: err})
+	}
+
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+
+	// Directories deeper than the pattern can not contain matches, so only the levels it spans are walked.
+	depth := strings.Count(pattern, pathSeparator)
+
+	var matches []string
+	err := gofs.WalkDir(o, ".", func(path string, entry gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == "." {
+			return nil
+		}
+
+		if matched, _ := gopath.Match(pattern, path); matched {
+			matches = append(matches, path)
+		}
+
+		if entry.IsDir() && strings.Count(path, pathSeparator) >= depth {
+			return gofs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return matches, err
+	}
+	return matches, nil
+}
+
+// Layers returns the upper layer followed by the lower layers for the OverlayFS.
+func (o *OverlayFS) Layers() []gofs.FS {
+	return append([]gofs.FS{o.upper}, o.lower...)
+}
+
+// Mkdir ...
+func (o *OverlayFS) Mkdir(name string, perm gofs.FileMode) error {
+	o.logger.Debug("[overlayfs] mkdir", "name", name)
+
+	name, err := o.clean("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := o.mkdir(name, perm); err != nil {
+		return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "mkdir", Path: name, Err: err})
+	}
+	return nil
+}
+
+// MkdirAll ...
+func (o *OverlayFS) MkdirAll(path string, perm gofs.FileMode) error {
+	o.logger.Debug("[overlayfs] mkdirAll", "path", path)
+
+	path, err := o.clean("mkdirAll", path)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	p := "."
+	for _, s := range strings.Split(path, pathSeparator) {
+		if s == "." {
+			continue
+		}
+
+		p = gopath.Join(p, s)
+		if _, fi, err := o.resolve(p); err == nil {
+			if !fi.IsDir() {
+				return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "mkdirAll", Path: p, Err: fs.ErrNotDir})
+			}
+			continue
+		}
+
+		if err := o.mkdir(p, perm); err != nil {
+			return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "mkdirAll", Path: p, Err: err})
+		}
+	}
+	return nil
+}
+
+// Open opens the named file for reading from the top-most layer that provides it. Directories are opened as a merged
+// view of every layer.
+func (o *OverlayFS) Open(name string) (gofs.File, error) {
+	o.logger.Debug("[overlayfs] open", "name", name)
+
+	name, err := o.clean("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	f, err := o.open(name)
+	if err != nil {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "open", Path: name, Err: err})
+	}
+	return f, nil
+}
+
+// OpenFile opens the named file using the provided flags. Files that are opened for writing are copied up to the upper
+// layer if they only exist in a lower layer.
+func (o *OverlayFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	o.logger.Debug("[overlayfs] openFile", "name", name, "flag", flag)
+
+	name, err := o.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		o.mutex.RLock()
+		defer o.mutex.RUnlock()
+
+		f, err := o.open(name)
+		if err != nil {
+			return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "openFile", Path: name, Err: err})
+		}
+		return f, nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := o.prepareWrite(name, flag&fs.O_TRUNC == 0); err != nil {
+		if !errors.Is(err, gofs.ErrNotExist) || flag&fs.O_CREATE == 0 {
+			return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "openFile", Path: name, Err: err})
+		}
+	}
+	return o.upper.OpenFile(name, flag|fs.O_CREATE, perm)
+}
+
+// PathSeparator ...
+func (o *OverlayFS) PathSeparator() string {
+	return pathSeparator
+}
+
+// Provider ...
+func (o *OverlayFS) Provider() string {
+	return "overlayfs"
+}
+
+// ReadDir returns the merged, name-sorted directory entries from every layer that provides the named directory.
+func (o *OverlayFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	o.logger.Debug("[overlayfs] readDir", "name", name)
+
+	name, err := o.clean("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	entries, err := o.readDir(name)
+	if err != nil {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "readDir", Path: name, Err: err})
+	}
+	return entries, nil
+}
+
+// ReadFile ...
+func (o *OverlayFS) ReadFile(name string) ([]byte, error) {
+	o.logger.Debug("[overlayfs] readFile", "name", name)
+
+	name, err := o.clean("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	layer, fi, err := o.resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: err})
+	}
+
+	if fi.IsDir() {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "readFile", Path: name, Err: fs.ErrIsDir})
+	}
+	return gofs.ReadFile(layer, name)
+}
+
+// Remove removes the named file or empty directory. A whiteout is recorded in the upper layer if the entry exists in
+// a lower layer.
+func (o *OverlayFS) Remove(name string) error {
+	o.logger.Debug("[overlayfs] remove", "name", name)
+
+	name, err := o.clean("remove", name)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := o.remove(name, false); err != nil {
+		return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "remove", Path: name, Err: err})
+	}
+	return nil
+}
+
+// RemoveAll removes path and any children it contains. A nil error is returned if the path does not exist.
+func (o *OverlayFS) RemoveAll(path string) error {
+	o.logger.Debug("[overlayfs] removeAll", "path", path)
+
+	path, err := o.clean("removeAll", path)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if path == "." {
+		entries, err := o.readDir(path)
+		if err != nil {
+			return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "removeAll", Path: path, Err: err})
+		}
+
+		for _, e := range entries {
+			if err := o.remove(e.Name(), true); err != nil {
+				return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "removeAll", Path: e.Name(), Err: err})
+			}
+		}
+		return nil
+	}
+
+	if err := o.remove(path, true); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "removeAll", Path: path, Err: err})
+	}
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath. Entries that exist in a lower layer are copied up to newpath and
+// whited-out at oldpath.
+func (o *OverlayFS) Rename(oldpath string, newpath string) error {
+	o.logger.Debug("[overlayfs] rename", "old_path", oldpath, "new_path", newpath)
+
+	oldpath, err := o.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = o.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := o.rename(oldpath, newpath); err != nil {
+		return fmt.Errorf("overlayfs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err})
+	}
+	return nil
+}
+
+// Root ...
+func (o *OverlayFS) Root() (string, error) {
+	return o.upper.Root()
+}
+
+// Stat ...
+func (o *OverlayFS) Stat(name string) (gofs.FileInfo, error) {
+	o.logger.Debug("[overlayfs] stat", "name", name)
+
+	name, err := o.clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	_, fi, err := o.resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "stat", Path: name, Err: err})
+	}
+	return fi, nil
+}
+
+// Sub returns a read-only view of the OverlayFS rooted at dir.
+func (o *OverlayFS) Sub(dir string) (gofs.FS, error) {
+	o.logger.Debug("[overlayfs] sub", "dir", dir)
+
+	fi, err := o.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotDir})
+	}
+
+	if dir == "." {
+		return o, nil
+	}
+	return &subFS{dir: dir, fsys: o}, nil
+}
+
+// WriteFile ...
+func (o *OverlayFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	o.logger.Debug("[overlayfs] writeFile", "name", name, "content_length", len(data))
+
+	name, err := o.clean("writeFile", name)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := o.prepareWrite(name, false); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: "writeFile", Path: name, Err: err})
+	}
+	return o.upper.WriteFile(name, data, perm)
+}
+
+func (o *OverlayFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(o, name)
+	if err != nil {
+		return name, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+
+	for _, s := range strings.Split(name, pathSeparator) {
+		if strings.HasPrefix(s, whiteoutPrefix) {
+			return name, fmt.Errorf("overlayfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+		}
+	}
+	return name, nil
+}
+
+// clearWhiteout removes the whiteout for name from the upper layer and reports whether one was present.
+func (o *OverlayFS) clearWhiteout(name string) (bool, error) {
+	wh := whiteout(name)
+	if _, err := gofs.Stat(o.upper, wh); err != nil {
+		return false, nil
+	}
+	return true, o.upper.Remove(wh)
+}
+
+func (o *OverlayFS) copyTree(src string, dst string) error {
+	layer, fi, err := o.resolve(src)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		if err := o.mkdir(dst, fi.Mode().Perm()); err != nil {
+			return err
+		}
+
+		entries, err := o.readDir(src)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if err := o.copyTree(gopath.Join(src, e.Name()), gopath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b, err := gofs.ReadFile(layer, src)
+	if err != nil {
+		return err
+	}
+
+	if _, err := o.clearWhiteout(dst); err != nil {
+		return err
+	}
+	return o.upper.WriteFile(dst, b, fi.Mode().Perm())
+}
+
+// ensureUpperDir copies the directory dir (and its parents) up to the upper layer if it only exists in a lower layer.
+func (o *OverlayFS) ensureUpperDir(dir string) error {
+	if dir == "." {
+		return nil
+	}
+
+	if fi, err := gofs.Stat(o.upper, dir); err == nil {
+		if !fi.IsDir() {
+			return fs.ErrNotDir
+		}
+		return nil
+	}
+
+	_, fi, err := o.resolve(dir)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fs.ErrNotDir
+	}
+
+	if err := o.ensureUpperDir(gopath.Dir(dir)); err != nil {
+		return err
+	}
+	return o.upper.Mkdir(dir, fi.Mode().Perm())
+}
+
+// inLower reports whether name is visible from any of the lower layers.
+func (o *OverlayFS) inLower(name string) bool {
+	if !o.lowerVisible(name) {
+		return false
+	}
+
+	for _, l := range o.lower {
+		if _, err := gofs.Stat(l, name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerVisible reports whether name may be resolved from the lower layers. Lower layers are hidden beneath whiteouts,
+// opaque directories, and non-directory entries in the upper layer.
+func (o *OverlayFS) lowerVisible(name string) bool {
+	if name == "." {
+		return true
+	}
+
+	p := "."
+	segments := strings.Split(name, pathSeparator)
+	for i, s := range segments {
+		p = gopath.Join(p, s)
+		if _, err := gofs.Stat(o.upper, whiteout(p)); err == nil {
+			return false
+		}
+
+		if i == len(segments)-1 {
+			break
+		}
+
+		if fi, err := gofs.Stat(o.upper, p); err == nil {
+			if !fi.IsDir() {
+				return false
+			}
+
+			if _, err := gofs.Stat(o.upper, gopath.Join(p, opaqueMarker)); err == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (o *OverlayFS) mkdir(name string, perm gofs.FileMode) error {
+	if name == "." {
+		return gofs.ErrInvalid
+	}
+
+	if _, _, err := o.resolve(name); err == nil {
+		return gofs.ErrExist
+	}
+
+	if err := o.ensureUpperDir(gopath.Dir(name)); err != nil {
+		return err
+	}
+
+	wh, err := o.clearWhiteout(name)
+	if err != nil {
+		return err
+	}
+
+	if err := o.upper.Mkdir(name, perm); err != nil {
+		return err
+	}
+
+	if wh {
+		return o.upper.WriteFile(gopath.Join(name, opaqueMarker), nil, 0)
+	}
+	return nil
+}
+
+func (o *OverlayFS) open(name string) (fs.File, error) {
+	layer, fi, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		entries, err := o.readDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return newDir(fi, entries), nil
+	}
+
+	if layer == o.upper {
+		return o.upper.OpenFile(name, fs.O_RDONLY, 0)
+	}
+
+	f, err := layer.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return newFile(name, f), nil
+}
+
+// prepareWrite ensures the parent directory for name exists in the upper layer and clears any whiteout for name. If
+// copyUp is true and name only exists in a lower layer, its content is copied to the upper layer.
+//
+// The returned error wraps gofs.ErrNotExist if name does not exist in any layer.
+func (o *OverlayFS) prepareWrite(name string, copyUp bool) error {
+	layer, fi, err := o.resolve(name)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	if fi != nil && fi.IsDir() {
+		return fs.ErrIsDir
+	}
+
+	if err := o.ensureUpperDir(gopath.Dir(name)); err != nil {
+		return err
+	}
+
+	if _, err := o.clearWhiteout(name); err != nil {
+		return err
+	}
+
+	if fi == nil {
+		return gofs.ErrNotExist
+	}
+
+	if copyUp && layer != o.upper {
+		b, err := gofs.ReadFile(layer, name)
+		if err != nil {
+			return err
+		}
+		return o.upper.WriteFile(name, b, fi.Mode().Perm())
+	}
+	return nil
+}
+
+func (o *OverlayFS) readDir(name string) ([]gofs.DirEntry, error) {
+	var (
+		entries   []gofs.DirEntry
+		found     bool
+		opaque    bool
+		seen      = make(map[string]bool)
+		whiteouts = make(map[string]bool)
+	)
+
+	if fi, err := gofs.Stat(o.upper, name); err == nil {
+		if !fi.IsDir() {
+			return nil, fs.ErrNotDir
+		}
+		found = true
+
+		de, err := gofs.ReadDir(o.upper, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range de {
+			switch n := e.Name(); {
+			case n == opaqueMarker:
+				opaque = true
+			case strings.HasPrefix(n, whiteoutPrefix):
+				whiteouts[strings.TrimPrefix(n, whiteoutPrefix)] = true
+			default:
+				seen[n] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	if !opaque && o.lowerVisible(name) {
+		for _, l := range o.lower {
+			fi, err := gofs.Stat(l, name)
+			if err != nil {
+				continue
+			}
+
+			if !fi.IsDir() {
+				if !found {
+					return nil, fs.ErrNotDir
+				}
+				continue
+			}
+			found = true
+
+			de, err := gofs.ReadDir(l, name)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range de {
+				if n := e.Name(); !seen[n] && !whiteouts[n] {
+					seen[n] = true
+					entries = append(entries, e)
+				}
+			}
+		}
+	}
+
+	if !found {
+		return nil, gofs.ErrNotExist
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (o *OverlayFS) remove(name string, all bool) error {
+	if name == "." {
+		return gofs.ErrInvalid
+	}
+
+	_, fi, err := o.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() && !all {
+		entries, err := o.readDir(name)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			return fs.ErrNotEmpty
+		}
+	}
+
+	lower := o.inLower(name)
+	if ufi, err := gofs.Stat(o.upper, name); err == nil {
+		if ufi.IsDir() {
+			// Directories in the upper layer may still contain whiteouts.
+			err = o.upper.RemoveAll(name)
+		} else {
+			err = o.upper.Remove(name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if lower {
+		if err := o.ensureUpperDir(gopath.Dir(name)); err != nil {
+			return err
+		}
+		return o.upper.WriteFile(whiteout(name), nil, 0)
+	}
+	return nil
+}
+
+func (o *OverlayFS) rename(oldpath string, newpath string) error {
+	if oldpath == "." || newpath == "." {
+		return gofs.ErrInvalid
+	}
+
+	if oldpath == newpath {
+		return nil
+	}
+
+	if strings.HasPrefix(newpath, oldpath+pathSeparator) {
+		return gofs.ErrInvalid
+	}
+
+	_, fi, err := o.resolve(oldpath)
+	if err != nil {
+		return err
+	}
+
+	if _, nfi, err := o.resolve(newpath); err == nil {
+		if nfi.IsDir() {
+			if !fi.IsDir() {
+				return fs.ErrIsDir
+			}
+
+			entries, err := o.readDir(newpath)
+			if err != nil {
+				return err
+			}
+
+			if len(entries) > 0 {
+				return fs.ErrNotEmpty
+			}
+		} else if fi.IsDir() {
+			return fs.ErrNotDir
+		}
+
+		if err := o.remove(newpath, true); err != nil {
+			return err
+		}
+	}
+
+	if err := o.ensureUpperDir(gopath.Dir(newpath)); err != nil {
+		return err
+	}
+
+	if o.inLower(oldpath) {
+		if err := o.copyTree(oldpath, newpath); err != nil {
+			return err
+		}
+		return o.remove(oldpath, true)
+	}
+
+	wh, err := o.clearWhiteout(newpath)
+	if err != nil {
+		return err
+	}
+
+	if err := o.upper.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	if wh && fi.IsDir() {
+		return o.upper.WriteFile(gopath.Join(newpath, opaqueMarker), nil, 0)
+	}
+	return nil
+}
+
+// resolve returns the top-most layer providing name along with its file info.
+func (o *OverlayFS) resolve(name string) (gofs.FS, gofs.FileInfo, error) {
+	if fi, err := gofs.Stat(o.upper, name); err == nil {
+		return o.upper, fi, nil
+	}
+
+	if !o.lowerVisible(name) {
+		return nil, nil, gofs.ErrNotExist
+	}
+
+	for _, l := range o.lower {
+		if fi, err := gofs.Stat(l, name); err == nil {
+			return l, fi, nil
+		}
+	}
+	return nil, nil, gofs.ErrNotExist
+}
+
+func whiteout(name string) string {
+	return gopath.Join(gopath.Dir(name), whiteoutPrefix+gopath.Base(name))
+}
+
+// WithLogger sets the Logger used by an OverlayFS to log its operations. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*OverlayFS) {
+	return func(o *OverlayFS) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// subFS is a read-only view of an OverlayFS rooted at a directory.
+type subFS struct {
+	dir  string
+	fsys *OverlayFS
+}
+
+func (s *subFS) Open(name string) (gofs.File, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "open", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.Open(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "readDir", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.ReadDir(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) ReadFile(name string) ([]byte, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "readFile", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.ReadFile(gopath.Join(s.dir, name))
+}
+
+func (s *subFS) Stat(name string) (gofs.FileInfo, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: "stat", Path: name, Err: gofs.ErrInvalid}
+	}
+	return s.fsys.Stat(gopath.Join(s.dir, name))
+}
@@ -0,0 +1,197 @@
+package overlayfs
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// OverlayFSTestSuite ...
+type OverlayFSTestSuite struct {
+	suite.Suite
+	defaults  fstest.MapFS
+	overrides fstest.MapFS
+	ofs       *OverlayFS
+	upper     *memfs.MemFS
+}
+
+func NewOverlayFSTestSuite() *OverlayFSTestSuite {
+	return &OverlayFSTestSuite{}
+}
+
+func (t *OverlayFSTestSuite) SetupTest() {
+	t.defaults = fstest.MapFS{
+		"config/app.yaml":   {Data: []byte("name: default")},
+		"config/log.yaml":   {Data: []byte("level: info")},
+		"static/index.html": {Data: []byte("<html></html>")},
+	}
+
+	t.overrides = fstest.MapFS{
+		"config/app.yaml": {Data: []byte("name: override")},
+	}
+
+	upper, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.upper = upper
+
+	ofs, err := New(upper, []gofs.FS{t.overrides, t.defaults})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.ofs = ofs
+}
+
+func TestOverlayFSTestSuite(t *testing.T) {
+	suite.Run(t, NewOverlayFSTestSuite())
+}
+
+func (t *OverlayFSTestSuite) TestFS() {
+	if err := t.ofs.WriteFile("scratch/tmp.txt", []byte("scratch"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := t.ofs.Remove("config/log.yaml"); err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), fstest.TestFS(t.ofs, "config/app.yaml", "scratch/tmp.txt", "static/index.html"))
+}
+
+func (t *OverlayFSTestSuite) TestGlob() {
+	if err := t.ofs.WriteFile("scratch/tmp.txt", []byte("scratch"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := t.ofs.Remove("config/log.yaml"); err != nil {
+		t.T().Fatal(err)
+	}
+
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for _, name := range []string{"config/app.yaml", "scratch/tmp.txt", "static/index.html"} {
+		if err := mfs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+
+	for _, pattern := range []string{".", "*", "*/*", "config/*.yaml", "*/index.html", "static", "missing/*"} {
+		expected, err := mfs.Glob(pattern)
+		assert.NoError(t.T(), err)
+
+		matches, err := t.ofs.Glob(pattern)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), expected, matches, pattern)
+	}
+
+	_, err = t.ofs.Glob("[")
+	assert.ErrorIs(t.T(), err, gopath.ErrBadPattern)
+}
+
+func (t *OverlayFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ofs, err := New(t.upper, []gofs.FS{t.defaults}, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = ofs.ReadFile("config/app.yaml")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[overlayfs] readFile" name=config/app.yaml`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.ofs.logger)
+}
+
+func (t *OverlayFSTestSuite) TestReadTopDown() {
+	b, err := t.ofs.ReadFile("config/app.yaml")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "name: override", string(b))
+
+	assert.NoError(t.T(), t.ofs.WriteFile("config/app.yaml", []byte("name: upper"), 0644))
+
+	b, err = t.ofs.ReadFile("config/app.yaml")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "name: upper", string(b))
+	assert.Equal(t.T(), "name: override", string(t.overrides["config/app.yaml"].Data))
+
+	entries, err := t.ofs.ReadDir("config")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 2)
+}
+
+func (t *OverlayFSTestSuite) TestRemove() {
+	assert.ErrorIs(t.T(), t.ofs.Remove("config"), fs.ErrNotEmpty)
+	assert.NoError(t.T(), t.ofs.Remove("config/log.yaml"))
+
+	_, err := t.ofs.Stat("config/log.yaml")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.upper.Stat("config/.wh.log.yaml")
+	assert.NoError(t.T(), err)
+
+	entries, err := t.ofs.ReadDir("config")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+
+	_, ok := t.defaults["config/log.yaml"]
+	assert.True(t.T(), ok)
+
+	_, err = t.ofs.Stat(".wh.config")
+	assert.ErrorIs(t.T(), err, gofs.ErrInvalid)
+}
+
+func (t *OverlayFSTestSuite) TestRemoveAll() {
+	assert.NoError(t.T(), t.ofs.RemoveAll("config"))
+
+	_, err := t.ofs.Stat("config/app.yaml")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.ofs.Mkdir("config", 0755))
+
+	entries, err := t.ofs.ReadDir("config")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+}
+
+func (t *OverlayFSTestSuite) TestRename() {
+	assert.NoError(t.T(), t.ofs.Rename("static", "public"))
+
+	_, err := t.ofs.Stat("static/index.html")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	b, err := t.ofs.ReadFile("public/index.html")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "<html></html>", string(b))
+
+	assert.NoError(t.T(), t.ofs.Rename("public/index.html", "index.html"))
+
+	_, err = t.ofs.Stat("index.html")
+	assert.NoError(t.T(), err)
+}
+
+func (t *OverlayFSTestSuite) TestOpenFileCopyUp() {
+	f, err := t.ofs.OpenFile("config/log.yaml", fs.O_WRONLY|fs.O_APPEND, 0)
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	fi, err := t.upper.Stat("config/log.yaml")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(len("level: info")), fi.Size())
+
+	_, err = t.ofs.OpenFile("config", fs.O_RDWR, 0)
+	assert.ErrorIs(t.T(), err, fs.ErrIsDir)
+}
@@ -7,9 +7,95 @@ import (
 	"strings"
 
 	gofs "io/fs"
+	gopath "path"
 )
 
+// Base returns the last element of the path p using the path separator from the provided file system. Trailing
+// separators are removed before extracting the last element. If p is empty, Base returns ".". If p consists entirely
+// of separators, Base returns a single separator.
+func Base(fsys FS, p string) string {
+	return fromSlash(fsys, gopath.Base(toSlash(fsys, p)))
+}
+
+// Dir returns all but the last element of the path p using the path separator from the provided file system. The
+// returned path is cleaned in the same way as Join.
+func Dir(fsys FS, p string) string {
+	return fromSlash(fsys, gopath.Dir(toSlash(fsys, p)))
+}
+
+// Ext returns the file name extension of the path p using the path separator from the provided file system. The
+// extension is the suffix beginning at the final dot in the final element of p, and is empty if there is no dot.
+func Ext(fsys FS, p string) string {
+	return gopath.Ext(toSlash(fsys, p))
+}
+
+// HasPrefix reports whether the path p is prefix, or is under prefix, using the path separator from the provided file
+// system. Unlike strings.HasPrefix, the paths are compared by element after they are cleaned, so "doc/fox.txt" has the
+// prefix "doc" but not "do". Every relative path has the prefix ".".
+func HasPrefix(fsys FS, p string, prefix string) bool {
+	p, prefix = gopath.Clean(toSlash(fsys, p)), gopath.Clean(toSlash(fsys, prefix))
+	switch {
+	case p == prefix:
+		return true
+	case prefix == ".":
+		return !strings.HasPrefix(p, "/")
+	case prefix == "/":
+		return strings.HasPrefix(p, "/")
+	}
+	return strings.HasPrefix(p, prefix+"/")
+}
+
+// Join joins any number of path elements into a single path using the path separator from the provided file system.
+// Empty elements are ignored, and the result is cleaned. If every element is empty, Join returns an empty string.
+func Join(fsys FS, elem ...string) string {
+	s := make([]string, len(elem))
+	for i, e := range elem {
+		s[i] = toSlash(fsys, e)
+	}
+	return fromSlash(fsys, gopath.Join(s...))
+}
+
+// Rel returns a relative path that is lexically equivalent to targpath when joined to basepath using Join, using the
+// path separator from the provided file system. An error is returned if targpath can not be made relative to basepath,
+// such as when only one of the paths is absolute.
+func Rel(fsys FS, basepath string, targpath string) (string, error) {
+	base, targ := gopath.Clean(toSlash(fsys, basepath)), gopath.Clean(toSlash(fsys, targpath))
+	if base == targ {
+		return ".", nil
+	}
+
+	if strings.HasPrefix(base, "/") != strings.HasPrefix(targ, "/") {
+		return "", fmt.Errorf("fs: can not make %s relative to %s: %w", targpath, basepath, gofs.ErrInvalid)
+	}
+
+	var be, te []string
+	if base != "." && base != "/" {
+		be = strings.Split(strings.TrimPrefix(base, "/"), "/")
+	}
+
+	if targ != "." && targ != "/" {
+		te = strings.Split(strings.TrimPrefix(targ, "/"), "/")
+	}
+
+	i := 0
+	for i < len(be) && i < len(te) && be[i] == te[i] {
+		i++
+	}
+
+	rel := make([]string, 0, len(be)-i+len(te)-i)
+	for _, e := range be[i:] {
+		if e == ".." {
+			return "", fmt.Errorf("fs: can not make %s relative to %s: %w", targpath, basepath, gofs.ErrInvalid)
+		}
+		rel = append(rel, "..")
+	}
+	return fromSlash(fsys, strings.Join(append(rel, te[i:]...), "/")), nil
+}
+
 // CleanPath cleans the path p returns a lexically valid path.
+//
+// If fsys implements PathValidatorFS, the path is also validated using the rules of its provider, and the *NameError
+// for the violated rule is returned if the path is invalid.
 func CleanPath(fsys FS, p string) (string, error) {
 	if fsys == nil {
 		return p, errors.New("file system is required")
@@ -19,6 +105,12 @@ func CleanPath(fsys FS, p string) (string, error) {
 		return p, fmt.Errorf("%s: %w", p, gofs.ErrInvalid)
 	}
 
+	if v, ok := fsys.(PathValidatorFS); ok {
+		if err := v.ValidatePath(p); err != nil {
+			return p, err
+		}
+	}
+
 	if strings.HasSuffix(p, fsys.PathSeparator()) {
 		p = p[:len(p)-1]
 	}
@@ -29,6 +121,74 @@ func CleanPath(fsys FS, p string) (string, error) {
 	return p, nil
 }
 
+// SecureJoin joins the untrusted path unsafe to base, using the path separator from fsys, so that the result is
+// guaranteed to be base or a path under base.
+//
+// The path unsafe is resolved as if base were the root of the file system: ".." elements can not move above base, and
+// absolute paths are resolved relative to base. Both "/" and the path separator of fsys are treated as separators in
+// unsafe. If fsys implements SymlinkFS, symbolic links in the path are resolved in the same way, so that a link can
+// not refer to a location outside of base. Elements that do not exist are joined lexically.
+//
+// SecureJoin is intended for servers that map paths provided by clients to paths for a file system. Since the file
+// system is not locked while the path is resolved, the result may refer to a location outside of base if the entries
+// under base are changed concurrently.
+func SecureJoin(fsys FS, base string, unsafe string) (string, error) {
+	if fsys == nil {
+		return "", errors.New("fs: file system is required")
+	}
+
+	sep := fsys.PathSeparator()
+	sfs, _ := fsys.(SymlinkFS)
+	join := func(elem []string) string {
+		if len(elem) == 0 {
+			return base
+		}
+
+		p := strings.Join(elem, sep)
+		if base == "" || base == "." {
+			return p
+		}
+		return strings.TrimSuffix(base, sep) + sep + p
+	}
+
+	var (
+		resolved []string
+		links    int
+	)
+	pending := splitUnsafe(unsafe, sep)
+	for len(pending) > 0 {
+		e := pending[0]
+		pending = pending[1:]
+
+		switch e {
+		case ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+
+		if sfs != nil {
+			if dest, err := sfs.Readlink(join(append(resolved, e))); err == nil {
+				if links++; links > maxSymlinkDepth {
+					return "", fmt.Errorf("fs: too many links in %s: %w", unsafe, gofs.ErrInvalid)
+				}
+
+				if strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, sep) || filepath.IsAbs(dest) {
+					resolved = nil
+					dest = dest[len(filepath.VolumeName(dest)):]
+				}
+				pending = append(splitUnsafe(dest, sep), pending...)
+				continue
+			}
+		}
+		resolved = append(resolved, e)
+	}
+	return join(resolved), nil
+}
+
 // SplitPath splits a path using the path separator from the provided file system.
 //
 // The returned slice will have empty substrings removed.
@@ -47,6 +207,41 @@ func SplitPath(fsys FS, p string) ([]string, error) {
 	return e, nil
 }
 
+// splitUnsafe splits the untrusted path p into its non-empty elements, treating both "/" and sep as separators.
+func splitUnsafe(p string, sep string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool {
+		return r == '/' || strings.ContainsRune(sep, r)
+	})
+}
+
+// toSlash returns p with each path separator from the provided file system replaced by a slash, so that p can be used
+// with the path package.
+func toSlash(fsys FS, p string) string {
+	if sep := separator(fsys); sep != "/" {
+		return strings.ReplaceAll(p, sep, "/")
+	}
+	return p
+}
+
+// fromSlash returns p with each slash replaced by the path separator from the provided file system.
+func fromSlash(fsys FS, p string) string {
+	if sep := separator(fsys); sep != "/" {
+		return strings.ReplaceAll(p, "/", sep)
+	}
+	return p
+}
+
+// separator returns the path separator from the provided file system, which is "/" if fsys is nil or does not
+// provide one.
+func separator(fsys FS) string {
+	if fsys != nil {
+		if sep := fsys.PathSeparator(); sep != "" {
+			return sep
+		}
+	}
+	return "/"
+}
+
 // EndsWithDot reports whether the final component of the path is ".".
 func EndsWithDot(fsys FS, path string) bool {
 	if path == "." {
@@ -0,0 +1,140 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecureJoin(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for unsafe, expected := range map[string]string{
+		"":                     "doc",
+		"fox.txt":              "doc/fox.txt",
+		"/fox.txt":             "doc/fox.txt",
+		"../../etc/passwd":     "doc/etc/passwd",
+		"a/./b/../../fox.txt":  "doc/fox.txt",
+		"a//b/":                "doc/a/b",
+		"..\\..\\fox.txt":      "doc/..\\..\\fox.txt",
+		"pictures/../../../..": "doc",
+	} {
+		p, err := fs.SecureJoin(mfs, "doc", unsafe)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, p, unsafe)
+	}
+
+	p, err := fs.SecureJoin(mfs, ".", "../fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "fox.txt", p)
+
+	_, err = fs.SecureJoin(nil, ".", "fox.txt")
+	assert.Error(t, err)
+}
+
+func TestSecureJoinSymlinks(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "doc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for link, target := range map[string]string{
+		"escape":   "../../..",
+		"absolute": "/etc",
+		"doc/up":   "..",
+		"loop":     "loop",
+	} {
+		if err := os.Symlink(target, filepath.Join(base, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for unsafe, expected := range map[string]string{
+		"escape/passwd":       base + "/passwd",
+		"absolute/passwd":     base + "/etc/passwd",
+		"doc/up/up/fox.txt":   base + "/up/fox.txt",
+		"doc/up/doc/fox.txt":  base + "/doc/fox.txt",
+		"../escape/../../etc": base + "/etc",
+	} {
+		p, err := fs.SecureJoin(osfs, base, unsafe)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash(expected), p, unsafe)
+	}
+
+	_, err = fs.SecureJoin(osfs, base, "loop/fox.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+// backslashFS is an fs.FS that uses a backslash as its path separator.
+type backslashFS struct {
+	fs.FS
+}
+
+func (b backslashFS) PathSeparator() string {
+	return "\\"
+}
+
+func TestPathHelpers(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bfs := backslashFS{FS: mfs}
+
+	assert.Equal(t, "doc/fox.txt", fs.Join(mfs, "doc", "", "pictures/..", "fox.txt"))
+	assert.Equal(t, "doc\\fox.txt", fs.Join(bfs, "doc", "", "pictures\\..", "fox.txt"))
+	assert.Equal(t, "", fs.Join(mfs))
+
+	assert.Equal(t, "fox.txt", fs.Base(mfs, "doc/fox.txt/"))
+	assert.Equal(t, "fox.txt", fs.Base(bfs, "doc\\fox.txt"))
+	assert.Equal(t, "doc", fs.Dir(mfs, "doc/fox.txt"))
+	assert.Equal(t, "a\\doc", fs.Dir(bfs, "a\\doc\\fox.txt"))
+	assert.Equal(t, ".txt", fs.Ext(mfs, "doc/fox.txt"))
+	assert.Equal(t, "", fs.Ext(bfs, "doc.d\\fox"))
+
+	assert.True(t, fs.HasPrefix(mfs, "doc/fox.txt", "doc"))
+	assert.True(t, fs.HasPrefix(mfs, "doc/fox.txt", "doc/"))
+	assert.True(t, fs.HasPrefix(mfs, "doc", "doc"))
+	assert.False(t, fs.HasPrefix(mfs, "doc/fox.txt", "do"))
+	assert.True(t, fs.HasPrefix(mfs, "doc/fox.txt", "."))
+	assert.False(t, fs.HasPrefix(mfs, "/doc/fox.txt", "."))
+	assert.True(t, fs.HasPrefix(mfs, "/doc/fox.txt", "/"))
+	assert.True(t, fs.HasPrefix(bfs, "doc\\fox.txt", "doc"))
+
+	for _, tc := range []struct {
+		fsys     fs.FS
+		base     string
+		targ     string
+		expected string
+	}{
+		{mfs, "doc", "doc/fox.txt", "fox.txt"},
+		{mfs, "doc/a", "pictures/seals.png", "../../pictures/seals.png"},
+		{mfs, "/doc", "/doc", "."},
+		{mfs, ".", "doc", "doc"},
+		{mfs, "doc", "../fox.txt", "../../fox.txt"},
+		{bfs, "doc\\a", "doc\\fox.txt", "..\\fox.txt"},
+	} {
+		rel, err := fs.Rel(tc.fsys, tc.base, tc.targ)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, rel)
+	}
+
+	_, err = fs.Rel(mfs, "/doc", "doc")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = fs.Rel(mfs, "../doc", "doc")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
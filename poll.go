@@ -0,0 +1,227 @@
+package fs
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// Default intervals used by Poll.
+const (
+	defaultPollInterval    = time.Second
+	defaultMaxPollInterval = 30 * time.Second
+)
+
+var (
+	_ CapabilityFS = (*polledFS)(nil)
+	_ WatchFS      = (*polledFS)(nil)
+)
+
+// PollOption defines an option for Poll and Watcher.
+type PollOption func(*pollOptions)
+
+type pollOptions struct {
+	interval    time.Duration
+	maxInterval time.Duration
+}
+
+// Poll returns a WatchFS whose Watch method detects changes to fsys by periodically comparing the metadata returned by
+// Stat and ReadDir for the watched entry, for providers that lack native change notification, such as object stores,
+// SFTP, and HTTP.
+//
+// The entry is polled every interval set using WithPollInterval. While no change is detected, the interval is doubled
+// after each poll, up to the maximum set using WithMaxPollInterval, and it is reset once a change is detected, so that
+// idle entries are polled less often. An entry is reported as written if its size or modification time changed, so
+// that changes made within the resolution of the modification time that do not change the size are not detected, and
+// renames are reported as a removal and a creation. Changes to the metadata of directories are not reported.
+func Poll(fsys FS, options ...PollOption) WatchFS {
+	opts := &pollOptions{interval: defaultPollInterval, maxInterval: defaultMaxPollInterval}
+	for _, opt := range options {
+		opt(opts)
+	}
+	opts.maxInterval = max(opts.maxInterval, opts.interval)
+	return &polledFS{FS: fsys, opts: opts}
+}
+
+// Watcher returns fsys if it supports CapWatch, so that its native change notification is used, and otherwise returns
+// a WatchFS that polls fsys using Poll with options.
+func Watcher(fsys FS, options ...PollOption) WatchFS {
+	if w, ok := fsys.(WatchFS); ok && Capabilities(fsys).Has(CapWatch) {
+		return w
+	}
+	return Poll(fsys, options...)
+}
+
+// WithMaxPollInterval sets the maximum interval between polls of an entry for which no change was detected. The
+// default is 30 seconds, and the maximum is never less than the interval set using WithPollInterval.
+func WithMaxPollInterval(interval time.Duration) PollOption {
+	return func(o *pollOptions) {
+		o.maxInterval = interval
+	}
+}
+
+// WithPollInterval sets the interval between polls of an entry once a change was detected, which is also the interval
+// used when the entry is first watched. The default is 1 second.
+func WithPollInterval(interval time.Duration) PollOption {
+	return func(o *pollOptions) {
+		if interval > 0 {
+			o.interval = interval
+		}
+	}
+}
+
+type polledFS struct {
+	FS
+	opts *pollOptions
+}
+
+func (p *polledFS) Capabilities() Capability {
+	return Capabilities(p.FS) & CapAtomicRename
+}
+
+// Watch calls handler with an Event for each change detected to the named entry, or to the entries of the named
+// directory, until the returned stop function is called. The entry does not need to exist when it is watched. The
+// handler is called from a separate goroutine, and the stop function waits for a poll in progress to complete.
+func (p *polledFS) Watch(name string, handler func(Event)) (func() error, error) {
+	if handler == nil {
+		return nil, errors.New("fs: handler is required")
+	}
+
+	prev, err := p.snapshot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		done = make(chan struct{})
+		once sync.Once
+		wg   sync.WaitGroup
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		interval := p.opts.interval
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+			}
+
+			cur, err := p.snapshot(name)
+			if err != nil {
+				log().Warn("[fs:poll] snapshot", "path", name, "error", err)
+				timer.Reset(interval)
+				continue
+			}
+
+			if events := diffSnapshots(prev, cur); len(events) > 0 {
+				for _, e := range events {
+					handler(e)
+				}
+				interval = p.opts.interval
+			} else {
+				interval = min(2*interval, p.opts.maxInterval)
+			}
+			prev = cur
+			timer.Reset(interval)
+		}
+	}()
+
+	return func() error {
+		once.Do(func() {
+			close(done)
+		})
+		wg.Wait()
+		return nil
+	}, nil
+}
+
+// snapshot returns the metadata of the named entry, and of its entries if it is a directory, keyed by path. An empty
+// snapshot is returned if the entry does not exist.
+func (p *polledFS) snapshot(name string) (map[string]pollState, error) {
+	fi, err := p.FS.Stat(name)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return map[string]pollState{}, nil
+		}
+		return nil, err
+	}
+
+	s := map[string]pollState{name: newPollState(fi)}
+	if !fi.IsDir() {
+		return s, nil
+	}
+
+	entries, err := p.FS.ReadDir(name)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return map[string]pollState{}, nil
+		}
+		return nil, err
+	}
+
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			// The entry was removed since the directory was read.
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		s[gopath.Join(name, e.Name())] = newPollState(fi)
+	}
+	return s, nil
+}
+
+// pollState is the metadata of an entry that is compared by Poll to detect changes.
+type pollState struct {
+	dir     bool
+	modTime time.Time
+	size    int64
+}
+
+func newPollState(fi gofs.FileInfo) pollState {
+	return pollState{dir: fi.IsDir(), modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// diffSnapshots returns the events for the changes from the snapshot prev to cur. Removals are reported before
+// creations and writes, and events of the same kind are sorted by path.
+func diffSnapshots(prev map[string]pollState, cur map[string]pollState) []Event {
+	now := time.Now()
+
+	var removed, changed []Event
+	for path, s := range prev {
+		if c, ok := cur[path]; !ok || c.dir != s.dir {
+			removed = append(removed, Event{Op: EventRemove, Path: path, Size: s.size, Time: now})
+		}
+	}
+
+	for path, c := range cur {
+		s, ok := prev[path]
+		switch {
+		case !ok || c.dir != s.dir:
+			changed = append(changed, Event{Op: EventCreate, Path: path, Size: c.size, Time: now})
+		case !c.dir && (c.size != s.size || !c.modTime.Equal(s.modTime)):
+			changed = append(changed, Event{Op: EventWrite, Path: path, Size: c.size, Time: now})
+		}
+	}
+
+	byPath := func(a Event, b Event) int {
+		return strings.Compare(a.Path, b.Path)
+	}
+	slices.SortFunc(removed, byPath)
+	slices.SortFunc(changed, byPath)
+	return append(removed, changed...)
+}
@@ -0,0 +1,74 @@
+package fs_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoll(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		events []fs.Event
+		mutex  sync.Mutex
+	)
+	received := func() []fs.Event {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]fs.Event(nil), events...)
+	}
+
+	wfs := fs.Watcher(mfs, fs.WithPollInterval(5*time.Millisecond), fs.WithMaxPollInterval(20*time.Millisecond))
+	assert.True(t, fs.Capabilities(wfs).Has(fs.CapWatch))
+
+	stop, err := wfs.Watch("doc", func(e fs.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		events = append(events, e)
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.Eventually(t, func() bool {
+		return len(received()) == 1
+	}, 5*time.Second, time.Millisecond)
+
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0644))
+	assert.NoError(t, mfs.Rename("doc/dog.txt", "doc/cat.txt"))
+	assert.Eventually(t, func() bool {
+		return len(received()) == 4
+	}, 5*time.Second, time.Millisecond)
+	assert.NoError(t, stop())
+	assert.NoError(t, stop())
+
+	// Renames are reported as a removal and a creation.
+	var ops []string
+	for _, e := range received() {
+		ops = append(ops, fmt.Sprintf("%s %s %d", e.Op, e.Path, e.Size))
+	}
+	assert.Equal(t, "create doc/dog.txt 12", ops[0])
+	assert.ElementsMatch(t, []string{"remove doc/dog.txt 12", "create doc/cat.txt 12", "write doc/fox.txt 25"}, ops[1:])
+
+	// A file system that supports native change notification is used as is.
+	ofs := fs.Observe(mfs)
+	assert.Same(t, ofs, fs.Watcher(ofs))
+
+	_, err = wfs.Watch("doc", nil)
+	assert.Error(t, err)
+}
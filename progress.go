@@ -0,0 +1,119 @@
+package fs
+
+import (
+	"errors"
+	"io"
+
+	gofs "io/fs"
+)
+
+// progressChunkSize is the size of the chunks in which data is written by WriteFileProgress, so that progress is
+// reported while a large buffer is written.
+const progressChunkSize = 32 * 1024
+
+// ProgressReader is an io.Reader that reports the total number of bytes read from the underlying reader after each
+// read.
+type ProgressReader struct {
+	fn    func(bytes int64)
+	r     io.Reader
+	total int64
+}
+
+// NewProgressReader creates a new ProgressReader that reads from r and calls fn with the total number of bytes read.
+func NewProgressReader(r io.Reader, fn func(bytes int64)) *ProgressReader {
+	return &ProgressReader{fn: fn, r: r}
+}
+
+// Read reads from the underlying reader and reports progress if any bytes were read.
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.total += int64(n)
+		if p.fn != nil {
+			p.fn(p.total)
+		}
+	}
+	return n, err
+}
+
+// Total returns the total number of bytes read.
+func (p *ProgressReader) Total() int64 {
+	return p.total
+}
+
+// ProgressWriter is an io.Writer that reports the total number of bytes written to the underlying writer after each
+// write.
+type ProgressWriter struct {
+	fn    func(bytes int64)
+	total int64
+	w     io.Writer
+}
+
+// NewProgressWriter creates a new ProgressWriter that writes to w and calls fn with the total number of bytes written.
+func NewProgressWriter(w io.Writer, fn func(bytes int64)) *ProgressWriter {
+	return &ProgressWriter{fn: fn, w: w}
+}
+
+// Total returns the total number of bytes written.
+func (p *ProgressWriter) Total() int64 {
+	return p.total
+}
+
+// Write writes to the underlying writer and reports progress if any bytes were written.
+func (p *ProgressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		p.total += int64(n)
+		if p.fn != nil {
+			p.fn(p.total)
+		}
+	}
+	return n, err
+}
+
+// WithProgress sets a callback that is called with the total number of bytes transferred as the content of a file is
+// copied by CopyFile or CopyFileResumable, or written by WriteFileProgress. For CopyAll, the total includes the bytes
+// of every file copied so far. If a file is copied by the file system using CopyFS, progress is reported once the copy
+// is complete.
+func WithProgress(fn func(bytes int64)) CopyOption {
+	return func(o *copyOptions) {
+		o.bytesProgress = fn
+	}
+}
+
+// WriteFileProgress writes data to the named file in fsys, creating it if necessary, in the same way as WriteFile. The
+// data is written in chunks, and the callback set using WithProgress is called after each chunk is written. Other
+// options are ignored.
+func WriteFileProgress(fsys FS, name string, data []byte, perm gofs.FileMode, options ...CopyOption) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &copyOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	if opts.bytesProgress == nil {
+		return fsys.WriteFile(name, data, perm)
+	}
+
+	f, err := fsys.OpenFile(name, O_WRONLY|O_CREATE|O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	w := NewProgressWriter(f, opts.bytesProgress)
+	for len(data) > 0 {
+		n := min(len(data), progressChunkSize)
+		if _, err = w.Write(data[:n]); err != nil {
+			break
+		}
+		data = data[n:]
+	}
+
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package fs_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressReader(t *testing.T) {
+	var reported []int64
+	r := fs.NewProgressReader(strings.NewReader("the quick brown fox"), func(n int64) {
+		reported = append(reported, n)
+	})
+
+	b := make([]byte, 10)
+	_, err := io.ReadFull(r, b)
+	assert.NoError(t, err)
+
+	rest, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "brown fox", string(rest))
+	assert.Equal(t, int64(19), r.Total())
+	assert.Equal(t, []int64{10, 19}, reported)
+}
+
+func TestProgressWriter(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		reported []int64
+	)
+	w := fs.NewProgressWriter(&buf, func(n int64) {
+		reported = append(reported, n)
+	})
+
+	_, err := io.WriteString(w, "the quick ")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "brown fox")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "the quick brown fox", buf.String())
+	assert.Equal(t, int64(19), w.Total())
+	assert.Equal(t, []int64{10, 19}, reported)
+}
+
+func TestWithProgress(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("x"), 100*1024)
+
+	var last int64
+	calls := 0
+	assert.NoError(t, fs.WriteFileProgress(mfs, "doc/large.bin", data, 0644, fs.WithProgress(func(n int64) {
+		assert.Greater(t, n, last)
+		last = n
+		calls++
+	})))
+	assert.Equal(t, int64(len(data)), last)
+	assert.Greater(t, calls, 1)
+
+	fi, err := mfs.Stat("doc/large.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), fi.Size())
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last = 0
+	assert.NoError(t, fs.CopyFile(dst, "fox.txt", mfs, "doc/fox.txt", fs.WithProgress(func(n int64) {
+		last = n
+	})))
+	assert.Equal(t, int64(19), last)
+
+	last = 0
+	assert.NoError(t, fs.CopyAll(dst, "copy", mfs, "doc", fs.WithProgress(func(n int64) {
+		assert.GreaterOrEqual(t, n, last)
+		last = n
+	})))
+	assert.Equal(t, int64(len(data)+19), last)
+}
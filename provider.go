@@ -0,0 +1,121 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvDefaultProvider is the environment variable used to select the default file system. The value is either the name
+// of a registered provider (e.g. "os" or "mem"), or a URI whose scheme is the name of a registered provider (e.g.
+// "file:///srv/data").
+const EnvDefaultProvider = "FS_DEFAULT_PROVIDER"
+
+var (
+	providers     = make(map[string]ProviderFunc)
+	providerMutex sync.RWMutex
+)
+
+// ProviderFunc creates a file system for the URI used to select a provider. The URI contains only the scheme if a
+// provider is selected by name.
+type ProviderFunc func(uri *url.URL) (FS, error)
+
+// Register makes a file system provider available by scheme for NewFromURI and for selecting the default file system
+// using EnvDefaultProvider. Providers typically register themselves in an init function, so that importing the package
+// that implements a provider is sufficient to make it available.
+//
+// Register panics if fn is nil or if a provider is already registered for scheme.
+func Register(scheme string, fn ProviderFunc) {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if fn == nil {
+		panic("fs: provider is required: " + scheme)
+	}
+
+	if _, ok := providers[scheme]; ok {
+		panic("fs: provider is already registered: " + scheme)
+	}
+	providers[scheme] = fn
+}
+
+// Providers returns the sorted schemes of the registered providers.
+func Providers() []string {
+	providerMutex.RLock()
+	defer providerMutex.RUnlock()
+
+	schemes := make([]string, 0, len(providers))
+	for s := range providers {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewFromURI creates a file system using the provider registered for the scheme of uri. If uri does not contain a
+// scheme separator, it is used as the name of the provider, so "mem" and "mem://" are equivalent.
+//
+// The "os" and "file" providers create an OSFS, which is rooted at the path of the URI if the path is not empty, as in
+// "file:///srv/data".
+func NewFromURI(uri string) (FS, error) {
+	uri = strings.TrimSpace(uri)
+	if uri == "" {
+		return nil, errors.New("fs: provider URI is required")
+	}
+
+	u := &url.URL{Scheme: uri}
+	if strings.Contains(uri, "://") {
+		var err error
+		if u, err = url.Parse(uri); err != nil {
+			return nil, fmt.Errorf("fs: invalid provider URI: %w", err)
+		}
+	}
+
+	providerMutex.RLock()
+	fn, ok := providers[strings.ToLower(u.Scheme)]
+	providerMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("fs: provider is not registered: %s", u.Scheme)
+	}
+	return fn(u)
+}
+
+// SetDefaultURI sets the default file system to the file system created by NewFromURI for uri.
+func SetDefaultURI(uri string) error {
+	fsys, err := NewFromURI(uri)
+	if err != nil {
+		return err
+	}
+	return SetDefault(fsys)
+}
+
+// defaultProvider creates the default file system selected using EnvDefaultProvider, or an OSFS if it is not set.
+func defaultProvider() (FS, error) {
+	if uri := os.Getenv(EnvDefaultProvider); uri != "" {
+		fsys, err := NewFromURI(uri)
+		if err != nil {
+			return nil, fmt.Errorf("fs: %s: %w", EnvDefaultProvider, err)
+		}
+		return fsys, nil
+	}
+	return New()
+}
+
+// newOSFS is the ProviderFunc for the "os" and "file" providers.
+func newOSFS(u *url.URL) (FS, error) {
+	fsys, err := New()
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		return fsys, nil
+	}
+	return Chroot(fsys, u.Path)
+}
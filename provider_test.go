@@ -0,0 +1,60 @@
+package fs_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	_ "github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromURI(t *testing.T) {
+	assert.Subset(t, fs.Providers(), []string{"file", "mem", "os"})
+
+	for _, uri := range []string{"mem", "mem://", "MEM"} {
+		fsys, err := fs.NewFromURI(uri)
+		assert.NoError(t, err)
+		assert.Equal(t, "memfs", fsys.Provider())
+	}
+
+	dir := t.TempDir()
+	fsys, err := fs.NewFromURI("file://" + filepath.ToSlash(dir))
+	assert.NoError(t, err)
+	assert.NoError(t, fsys.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+
+	b, err := os.ReadFile(filepath.Join(dir, "fox.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	_, err = fs.NewFromURI("s3://bucket")
+	assert.Error(t, err)
+
+	_, err = fs.NewFromURI("")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		fs.Register("os", func(*url.URL) (fs.FS, error) { return fs.New() })
+	})
+}
+
+func TestSetDefaultURI(t *testing.T) {
+	prev := fs.Default()
+	defer func() {
+		assert.NoError(t, fs.SetDefault(prev))
+	}()
+
+	assert.NoError(t, fs.SetDefaultURI("mem"))
+	assert.Equal(t, "memfs", fs.Default().Provider())
+
+	assert.NoError(t, fs.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	b, err := fs.ReadFile("fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	assert.Error(t, fs.SetDefaultURI("unknown"))
+	assert.Equal(t, "memfs", fs.Default().Provider())
+}
@@ -0,0 +1,517 @@
+package quotafs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.FS = (*QuotaFS)(nil)
+
+// Quota defines the limits for a subtree. A limit less than or equal to zero is unlimited.
+type Quota struct {
+	Bytes int64
+	Files int64
+}
+
+// Usage defines the consumption of a subtree, where Bytes is the sum of the sizes of the regular files and Files is the
+// number of non-directory entries.
+type Usage struct {
+	Bytes int64
+	Files int64
+}
+
+// QuotaError records the subtree whose quota would be exceeded by an operation. QuotaError wraps
+// fs.ErrQuotaExceeded.
+type QuotaError struct {
+	Dir   string
+	Quota Quota
+	Usage Usage
+}
+
+// Error returns the cause of the quota error.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: %s (bytes: %d/%d, files: %d/%d)",
+		e.Dir, fs.ErrQuotaExceeded, e.Usage.Bytes, e.Quota.Bytes, e.Usage.Files, e.Quota.Files)
+}
+
+// Unwrap returns fs.ErrQuotaExceeded.
+func (e *QuotaError) Unwrap() error {
+	return fs.ErrQuotaExceeded
+}
+
+// QuotaFS quota-enforcing provider that implements fs.FS.
+//
+// A QuotaFS enforces byte and file-count quotas for one or more subtrees of a backing file system. Quotas may be
+// nested, in which case an operation must satisfy every quota that applies to its path. Usage for each subtree is
+// computed when its quota is set and maintained as operations are performed through the QuotaFS, so changes made
+// directly to the backing file system are not reflected until the quota is set again.
+type QuotaFS struct {
+	backing fs.FS
+	logger  fs.Logger
+	mutex   sync.Mutex
+	quotas  map[string]Quota
+	usage   map[string]*Usage
+}
+
+// New creates a new QuotaFS that enforces quotas on backing.
+func New(backing fs.FS, options ...func(*QuotaFS)) (*QuotaFS, error) {
+	if backing == nil {
+		return nil, errors.New("quotafs: backing file system is required")
+	}
+
+	q := &QuotaFS{backing: backing, logger: fs.NopLogger(), quotas: make(map[string]Quota), usage: make(map[string]*Usage)}
+	for _, opt := range options {
+		opt(q)
+	}
+
+	for dir, quota := range q.quotas {
+		if err := q.SetQuota(dir, quota); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// Close closes the backing file system.
+func (q *QuotaFS) Close() error {
+	return q.backing.Close()
+}
+
+// Create ...
+func (q *QuotaFS) Create(name string) (fs.File, error) {
+	return q.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob ...
+func (q *QuotaFS) Glob(pattern string) ([]string, error) {
+	return q.backing.Glob(pattern)
+}
+
+// Mkdir ...
+func (q *QuotaFS) Mkdir(name string, perm gofs.FileMode) error {
+	return q.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (q *QuotaFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return q.backing.MkdirAll(path, perm)
+}
+
+// Open ...
+func (q *QuotaFS) Open(name string) (gofs.File, error) {
+	return q.backing.Open(name)
+}
+
+// OpenFile opens the named file. Writes to a File opened for writing are checked against the applicable quotas using
+// the length of the written content as the worst-case growth of the file.
+func (q *QuotaFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		return q.backing.OpenFile(name, flag, perm)
+	}
+
+	name, err := q.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var delta Usage
+	fi, err := q.backing.Stat(name)
+	switch {
+	case err == nil:
+		if flag&fs.O_TRUNC != 0 && fi.Mode().IsRegular() {
+			delta.Bytes = -fi.Size()
+		}
+	case errors.Is(err, gofs.ErrNotExist) && flag&fs.O_CREATE != 0:
+		delta.Files = 1
+	}
+
+	if err := q.check("openFile", name, delta); err != nil {
+		return nil, err
+	}
+
+	f, err := q.backing.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	q.charge(name, delta)
+
+	var size int64
+	if fi != nil && delta.Bytes == 0 {
+		size = fi.Size()
+	}
+	return &file{File: f, name: name, qfs: q, size: size}, nil
+}
+
+// PathSeparator ...
+func (q *QuotaFS) PathSeparator() string {
+	return q.backing.PathSeparator()
+}
+
+// Provider ...
+func (q *QuotaFS) Provider() string {
+	return q.backing.Provider()
+}
+
+// Quotas returns the quotas for the QuotaFS keyed by subtree.
+func (q *QuotaFS) Quotas() map[string]Quota {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	quotas := make(map[string]Quota, len(q.quotas))
+	for dir, quota := range q.quotas {
+		quotas[dir] = quota
+	}
+	return quotas
+}
+
+// ReadDir ...
+func (q *QuotaFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return q.backing.ReadDir(name)
+}
+
+// ReadFile ...
+func (q *QuotaFS) ReadFile(name string) ([]byte, error) {
+	return q.backing.ReadFile(name)
+}
+
+// Remove ...
+func (q *QuotaFS) Remove(name string) error {
+	name, err := q.clean("remove", name)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	u, err := q.measure(name)
+	if err != nil {
+		return q.backing.Remove(name)
+	}
+
+	if err := q.backing.Remove(name); err != nil {
+		return err
+	}
+	q.charge(name, Usage{Bytes: -u.Bytes, Files: -u.Files})
+	return nil
+}
+
+// RemoveAll ...
+func (q *QuotaFS) RemoveAll(path string) error {
+	path, err := q.clean("removeAll", path)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	u, err := q.measure(path)
+	if err != nil {
+		return q.backing.RemoveAll(path)
+	}
+
+	if err := q.backing.RemoveAll(path); err != nil {
+		return err
+	}
+
+	if path == "." {
+		for _, usage := range q.usage {
+			*usage = Usage{}
+		}
+		return nil
+	}
+	q.charge(path, Usage{Bytes: -u.Bytes, Files: -u.Files})
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath. Quotas that apply to newpath but not oldpath are checked before the
+// rename is performed.
+func (q *QuotaFS) Rename(oldpath string, newpath string) error {
+	oldpath, err := q.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = q.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	moved, err := q.measure(oldpath)
+	if err != nil {
+		return q.backing.Rename(oldpath, newpath)
+	}
+
+	replaced, err := q.measure(newpath)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	for _, dir := range q.matching(newpath) {
+		delta := Usage{Bytes: moved.Bytes - replaced.Bytes, Files: moved.Files - replaced.Files}
+		if contains(dir, oldpath) {
+			delta = Usage{Bytes: -replaced.Bytes, Files: -replaced.Files}
+		}
+
+		if err := q.exceeds(dir, delta); err != nil {
+			return fmt.Errorf("quotafs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err})
+		}
+	}
+
+	if err := q.backing.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	q.charge(oldpath, Usage{Bytes: -moved.Bytes, Files: -moved.Files})
+	q.charge(newpath, Usage{Bytes: moved.Bytes - replaced.Bytes, Files: moved.Files - replaced.Files})
+	return nil
+}
+
+// RemoveQuota removes the quota for the subtree dir.
+func (q *QuotaFS) RemoveQuota(dir string) error {
+	dir, err := q.clean("removeQuota", dir)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.quotas, dir)
+	delete(q.usage, dir)
+	return nil
+}
+
+// Root ...
+func (q *QuotaFS) Root() (string, error) {
+	return q.backing.Root()
+}
+
+// SetQuota sets the quota for the subtree dir and computes its current usage. The directory does not need to exist.
+func (q *QuotaFS) SetQuota(dir string, quota Quota) error {
+	dir, err := q.clean("setQuota", dir)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	u, err := q.measure(dir)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) {
+		return fmt.Errorf("quotafs: %w", &gofs.PathError{Op: "setQuota", Path: dir, Err: err})
+	}
+
+	q.logger.Debug("[quotafs] set quota",
+		"dir", dir,
+		"bytes", quota.Bytes,
+		"files", quota.Files,
+		"used_bytes", u.Bytes,
+		"used_files", u.Files)
+
+	q.quotas[dir] = quota
+	q.usage[dir] = &u
+	return nil
+}
+
+// Stat ...
+func (q *QuotaFS) Stat(name string) (gofs.FileInfo, error) {
+	return q.backing.Stat(name)
+}
+
+// Sub returns the sub-tree for dir from the backing file system. Since quotas are not enforced for the returned file
+// system, writable sub-trees are wrapped with fs.ReadOnly.
+func (q *QuotaFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := q.backing.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		return fs.ReadOnly(fsys), nil
+	}
+	return sub, nil
+}
+
+// Usage returns the usage for the subtree dir. The tracked usage is returned if dir has a quota, otherwise the usage
+// is computed from the backing file system.
+func (q *QuotaFS) Usage(dir string) (Usage, error) {
+	dir, err := q.clean("usage", dir)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if u, ok := q.usage[dir]; ok {
+		return *u, nil
+	}
+	return q.measure(dir)
+}
+
+// WriteFile ...
+func (q *QuotaFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	name, err := q.clean("writeFile", name)
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delta := Usage{Bytes: int64(len(data)), Files: 1}
+	if fi, err := q.backing.Stat(name); err == nil {
+		delta = Usage{Bytes: int64(len(data)) - fi.Size()}
+	}
+
+	if err := q.check("writeFile", name, delta); err != nil {
+		return err
+	}
+
+	if err := q.backing.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	q.charge(name, delta)
+	return nil
+}
+
+// charge applies delta to the usage of every quota that applies to name. The caller must hold the mutex.
+func (q *QuotaFS) charge(name string, delta Usage) {
+	for _, dir := range q.matching(name) {
+		u := q.usage[dir]
+		u.Bytes = max(u.Bytes+delta.Bytes, 0)
+		u.Files = max(u.Files+delta.Files, 0)
+	}
+}
+
+// check returns a QuotaError if applying delta would exceed any quota that applies to name. The caller must hold the
+// mutex.
+func (q *QuotaFS) check(op string, name string, delta Usage) error {
+	for _, dir := range q.matching(name) {
+		if err := q.exceeds(dir, delta); err != nil {
+			return fmt.Errorf("quotafs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+		}
+	}
+	return nil
+}
+
+func (q *QuotaFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(q, name)
+	if err != nil {
+		return name, fmt.Errorf("quotafs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+func (q *QuotaFS) exceeds(dir string, delta Usage) error {
+	quota := q.quotas[dir]
+	u := Usage{Bytes: q.usage[dir].Bytes + delta.Bytes, Files: q.usage[dir].Files + delta.Files}
+	if (quota.Bytes > 0 && delta.Bytes > 0 && u.Bytes > quota.Bytes) ||
+		(quota.Files > 0 && delta.Files > 0 && u.Files > quota.Files) {
+		return &QuotaError{Dir: dir, Quota: quota, Usage: *q.usage[dir]}
+	}
+	return nil
+}
+
+// matching returns the subtrees with a quota that applies to name, sorted from the outermost to the innermost.
+func (q *QuotaFS) matching(name string) []string {
+	var dirs []string
+	for dir := range q.quotas {
+		if contains(dir, name) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// measure computes the usage for path from the backing file system.
+func (q *QuotaFS) measure(path string) (Usage, error) {
+	var u Usage
+	err := gofs.WalkDir(q.backing, path, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() {
+			u.Bytes += fi.Size()
+		}
+		u.Files++
+		return nil
+	})
+	return u, err
+}
+
+func contains(dir string, name string) bool {
+	return dir == "." || name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// WithLogger sets the Logger used by a QuotaFS to log the quotas that are set and the usage measured for them. By
+// default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*QuotaFS) {
+	return func(q *QuotaFS) {
+		if logger != nil {
+			q.logger = logger
+		}
+	}
+}
+
+// WithQuota sets the quota for the subtree dir of a QuotaFS.
+func WithQuota(dir string, quota Quota) func(*QuotaFS) {
+	return func(q *QuotaFS) {
+		q.quotas[dir] = quota
+	}
+}
+
+// file tracks the growth of a file opened for writing through a QuotaFS.
+type file struct {
+	fs.File
+	name string
+	qfs  *QuotaFS
+	size int64
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	f.qfs.mutex.Lock()
+	defer f.qfs.mutex.Unlock()
+
+	if err := f.qfs.check("write", f.name, Usage{Bytes: int64(len(b))}); err != nil {
+		return 0, err
+	}
+
+	n, err := f.File.Write(b)
+	if fi, serr := f.File.Stat(); serr == nil {
+		f.qfs.charge(f.name, Usage{Bytes: fi.Size() - f.size})
+		f.size = fi.Size()
+	}
+	return n, err
+}
@@ -0,0 +1,177 @@
+package quotafs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// QuotaFSTestSuite ...
+type QuotaFSTestSuite struct {
+	suite.Suite
+	qfs *QuotaFS
+}
+
+func NewQuotaFSTestSuite() *QuotaFSTestSuite {
+	return &QuotaFSTestSuite{}
+}
+
+func (t *QuotaFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := map[string]string{
+		"tenants/a/fox.txt": "the quick brown fox",
+		"tenants/b/dog.txt": "jumps over the lazy dog",
+	}
+	for name, content := range files {
+		if err := backing.WriteFile(name, []byte(content), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+
+	qfs, err := New(backing,
+		WithQuota("tenants/a", Quota{Bytes: 32, Files: 2}),
+		WithQuota("tenants", Quota{Bytes: 64}))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.qfs = qfs
+}
+
+func TestQuotaFSTestSuite(t *testing.T) {
+	suite.Run(t, NewQuotaFSTestSuite())
+}
+
+func (t *QuotaFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.qfs, "tenants/a/fox.txt", "tenants/b/dog.txt"))
+}
+
+func (t *QuotaFSTestSuite) TestUsage() {
+	u, err := t.qfs.Usage("tenants/a")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 19, Files: 1}, u)
+
+	u, err = t.qfs.Usage("tenants")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 42, Files: 2}, u)
+
+	u, err = t.qfs.Usage("tenants/b")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 23, Files: 1}, u)
+}
+
+func (t *QuotaFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, err := New(t.qfs.backing, WithQuota("tenants/a", Quota{Bytes: 32}), WithLogger(logger))
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[quotafs] set quota" dir=tenants/a bytes=32 files=0 used_bytes=19 used_files=1`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.qfs.logger)
+}
+
+func (t *QuotaFSTestSuite) TestWriteFile() {
+	assert.NoError(t.T(), t.qfs.WriteFile("tenants/a/cat.txt", []byte("the cat"), 0644))
+
+	err := t.qfs.WriteFile("tenants/a/cow.txt", []byte("moo"), 0644)
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+
+	var qerr *QuotaError
+	assert.True(t.T(), errors.As(err, &qerr))
+	assert.Equal(t.T(), "tenants/a", qerr.Dir)
+
+	err = t.qfs.WriteFile("tenants/a/cat.txt", []byte("the cat sat on the mat"), 0644)
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+
+	assert.NoError(t.T(), t.qfs.WriteFile("tenants/a/cat.txt", []byte("cat"), 0644))
+
+	u, err := t.qfs.Usage("tenants/a")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 22, Files: 2}, u)
+
+	err = t.qfs.WriteFile("tenants/b/big.txt", make([]byte, 32), 0644)
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+	assert.NoError(t.T(), t.qfs.WriteFile("other/big.txt", make([]byte, 128), 0644))
+}
+
+func (t *QuotaFSTestSuite) TestOpenFile() {
+	f, err := t.qfs.Create("tenants/a/cat.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = io.WriteString(f, "the cat")
+	assert.NoError(t.T(), err)
+
+	_, err = io.WriteString(f, " sat on the mat")
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+	assert.NoError(t.T(), f.Close())
+
+	u, err := t.qfs.Usage("tenants/a")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 26, Files: 2}, u)
+
+	_, err = t.qfs.Create("tenants/a/cow.txt")
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+}
+
+func (t *QuotaFSTestSuite) TestRemove() {
+	assert.NoError(t.T(), t.qfs.Remove("tenants/a/fox.txt"))
+
+	u, err := t.qfs.Usage("tenants/a")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{}, u)
+
+	assert.NoError(t.T(), t.qfs.RemoveAll("tenants/b"))
+
+	u, err = t.qfs.Usage("tenants")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{}, u)
+}
+
+func (t *QuotaFSTestSuite) TestRename() {
+	err := t.qfs.Rename("tenants/b/dog.txt", "tenants/a/dog.txt")
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+
+	_, err = t.qfs.Stat("tenants/b/dog.txt")
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), t.qfs.Rename("tenants/a/fox.txt", "tenants/b/fox.txt"))
+
+	u, err := t.qfs.Usage("tenants/a")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{}, u)
+
+	u, err = t.qfs.Usage("tenants")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), Usage{Bytes: 42, Files: 2}, u)
+}
+
+func (t *QuotaFSTestSuite) TestSub() {
+	sub, err := t.qfs.Sub("tenants/a")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	fsys, ok := sub.(fs.FS)
+	if !ok {
+		t.T().Fatal("expected fs.FS")
+	}
+
+	err = fsys.WriteFile("cow.txt", make([]byte, 64), 0644)
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+}
@@ -0,0 +1,43 @@
+package ratelimitfs
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket implements a token bucket that refills at a fixed rate up to its burst size.
+//
+// Tokens are reserved up front and the bucket may go into debt, so a request larger than the burst size is allowed
+// and the caller waits for the time required to repay it. Subsequent requests wait for the debt to be repaid before
+// they are admitted.
+type bucket struct {
+	burst  float64
+	last   time.Time
+	mutex  sync.Mutex
+	rate   float64
+	tokens float64
+}
+
+func newBucket(rate float64, burst float64, now time.Time) *bucket {
+	if burst <= 0 {
+		burst = max(rate, 1)
+	}
+	return &bucket{burst: burst, last: now, rate: rate, tokens: burst}
+}
+
+// reserve takes n tokens from the bucket and returns the duration the caller must wait before proceeding.
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.burst)
+		b.last = now
+	}
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
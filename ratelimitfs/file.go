@@ -0,0 +1,47 @@
+package ratelimitfs
+
+import (
+	"io"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file throttles the reads and writes performed on a File opened through a RateLimitFS.
+type file struct {
+	fs.File
+	rfs *RateLimitFS
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	f.rfs.wait(0)
+	n, err := f.File.Read(b)
+	f.rfs.charge(int64(n))
+	return n, err
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	f.rfs.wait(0)
+	n, err := f.File.ReadAt(b, off)
+	f.rfs.charge(int64(n))
+	return n, err
+}
+
+func (f *file) ReadDir(n int) ([]gofs.DirEntry, error) {
+	f.rfs.wait(0)
+	return f.File.ReadDir(n)
+}
+
+// ReadFrom copies from r using Write so that the content is throttled in chunks rather than charged in a single
+// request once the copy completes.
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	f.rfs.wait(int64(len(b)))
+	return f.File.Write(b)
+}
@@ -0,0 +1,250 @@
+package ratelimitfs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.FS = (*RateLimitFS)(nil)
+
+// RateLimitFS rate-limiting provider that implements fs.FS.
+//
+// A RateLimitFS throttles the operations performed on a backing file system using token buckets. Every operation that
+// is passed through to the backing file system, including reads and writes on a File, consumes one token from the
+// IOPS bucket. Reads and writes additionally consume one token per byte from the bandwidth bucket. Since the size of
+// a read is not known until it completes, bytes read are charged after the read, which delays subsequent operations
+// rather than the read itself.
+//
+// A limit less than or equal to zero is unlimited.
+type RateLimitFS struct {
+	backing    fs.FS
+	bandwidth  *bucket
+	bytesBurst int64
+	bytesLimit int64
+	iops       *bucket
+	iopsBurst  int
+	iopsLimit  float64
+	logger     fs.Logger
+	now        func() time.Time
+	sleep      func(time.Duration)
+}
+
+// New creates a new RateLimitFS that throttles the operations performed on backing.
+func New(backing fs.FS, options ...func(*RateLimitFS)) (*RateLimitFS, error) {
+	if backing == nil {
+		return nil, errors.New("ratelimitfs: backing file system is required")
+	}
+
+	r := &RateLimitFS{backing: backing, logger: fs.NopLogger(), now: time.Now, sleep: time.Sleep}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	if r.iopsLimit > 0 {
+		r.iops = newBucket(r.iopsLimit, float64(r.iopsBurst), r.now())
+	}
+
+	if r.bytesLimit > 0 {
+		r.bandwidth = newBucket(float64(r.bytesLimit), float64(r.bytesBurst), r.now())
+	}
+
+	r.logger.Debug("[ratelimitfs] new",
+		"provider", backing.Provider(),
+		"iops", r.iopsLimit,
+		"bytes_per_second", r.bytesLimit)
+	return r, nil
+}
+
+// Close closes the backing file system.
+func (r *RateLimitFS) Close() error {
+	return r.backing.Close()
+}
+
+// Create ...
+func (r *RateLimitFS) Create(name string) (fs.File, error) {
+	r.wait(0)
+	f, err := r.backing.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, rfs: r}, nil
+}
+
+// Glob ...
+func (r *RateLimitFS) Glob(pattern string) ([]string, error) {
+	r.wait(0)
+	return r.backing.Glob(pattern)
+}
+
+// Mkdir ...
+func (r *RateLimitFS) Mkdir(name string, perm gofs.FileMode) error {
+	r.wait(0)
+	return r.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (r *RateLimitFS) MkdirAll(path string, perm gofs.FileMode) error {
+	r.wait(0)
+	return r.backing.MkdirAll(path, perm)
+}
+
+// Open ...
+func (r *RateLimitFS) Open(name string) (gofs.File, error) {
+	r.wait(0)
+	f, err := r.backing.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if rf, ok := f.(fs.File); ok {
+		return &file{File: rf, rfs: r}, nil
+	}
+	return f, nil
+}
+
+// OpenFile ...
+func (r *RateLimitFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	r.wait(0)
+	f, err := r.backing.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, rfs: r}, nil
+}
+
+// PathSeparator ...
+func (r *RateLimitFS) PathSeparator() string {
+	return r.backing.PathSeparator()
+}
+
+// Provider ...
+func (r *RateLimitFS) Provider() string {
+	return r.backing.Provider()
+}
+
+// ReadDir ...
+func (r *RateLimitFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	r.wait(0)
+	return r.backing.ReadDir(name)
+}
+
+// ReadFile ...
+func (r *RateLimitFS) ReadFile(name string) ([]byte, error) {
+	r.wait(0)
+	b, err := r.backing.ReadFile(name)
+	r.charge(int64(len(b)))
+	return b, err
+}
+
+// Remove ...
+func (r *RateLimitFS) Remove(name string) error {
+	r.wait(0)
+	return r.backing.Remove(name)
+}
+
+// RemoveAll ...
+func (r *RateLimitFS) RemoveAll(path string) error {
+	r.wait(0)
+	return r.backing.RemoveAll(path)
+}
+
+// Rename ...
+func (r *RateLimitFS) Rename(oldpath string, newpath string) error {
+	r.wait(0)
+	return r.backing.Rename(oldpath, newpath)
+}
+
+// Root ...
+func (r *RateLimitFS) Root() (string, error) {
+	return r.backing.Root()
+}
+
+// Stat ...
+func (r *RateLimitFS) Stat(name string) (gofs.FileInfo, error) {
+	r.wait(0)
+	return r.backing.Stat(name)
+}
+
+// Sub returns the sub-tree for dir from the backing file system. If the sub-tree implements fs.FS, it shares the
+// limits of the RateLimitFS.
+func (r *RateLimitFS) Sub(dir string) (gofs.FS, error) {
+	r.wait(0)
+	sub, err := r.backing.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		s := *r
+		s.backing = fsys
+		return &s, nil
+	}
+	return sub, nil
+}
+
+// WriteFile ...
+func (r *RateLimitFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	r.wait(int64(len(data)))
+	return r.backing.WriteFile(name, data, perm)
+}
+
+// charge consumes n bytes from the bandwidth bucket, blocking until the bucket is no longer in debt.
+func (r *RateLimitFS) charge(n int64) {
+	if r.bandwidth == nil || n <= 0 {
+		return
+	}
+
+	if d := r.bandwidth.reserve(float64(n), r.now()); d > 0 {
+		r.sleep(d)
+	}
+}
+
+// wait consumes a single operation from the IOPS bucket and n bytes from the bandwidth bucket, blocking until both
+// are available.
+func (r *RateLimitFS) wait(n int64) {
+	var d time.Duration
+	if r.iops != nil {
+		d = r.iops.reserve(1, r.now())
+	}
+
+	if r.bandwidth != nil && n > 0 {
+		d = max(d, r.bandwidth.reserve(float64(n), r.now()))
+	}
+
+	if d > 0 {
+		r.sleep(d)
+	}
+}
+
+// WithBytesPerSecond sets the maximum number of bytes per second that may be read from or written to a RateLimitFS,
+// and the number of bytes that may be transferred in a single burst. If burst is less than or equal to zero, the
+// burst is the same as the limit.
+func WithBytesPerSecond(limit int64, burst int64) func(*RateLimitFS) {
+	return func(r *RateLimitFS) {
+		r.bytesLimit = limit
+		r.bytesBurst = burst
+	}
+}
+
+// WithIOPS sets the maximum number of operations per second that may be performed on a RateLimitFS, and the number of
+// operations that may be performed in a single burst. If burst is less than or equal to zero, the burst is the same
+// as the limit.
+func WithIOPS(limit float64, burst int) func(*RateLimitFS) {
+	return func(r *RateLimitFS) {
+		r.iopsLimit = limit
+		r.iopsBurst = burst
+	}
+}
+
+// WithLogger sets the Logger used by a RateLimitFS to log its configuration. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*RateLimitFS) {
+	return func(r *RateLimitFS) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimitfs
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// RateLimitFSTestSuite ...
+type RateLimitFSTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	now     time.Time
+	slept   time.Duration
+}
+
+func NewRateLimitFSTestSuite() *RateLimitFSTestSuite {
+	return &RateLimitFSTestSuite{}
+}
+
+func (t *RateLimitFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+	t.now = time.Now()
+	t.slept = 0
+}
+
+func TestRateLimitFSTestSuite(t *testing.T) {
+	suite.Run(t, NewRateLimitFSTestSuite())
+}
+
+// limited returns a RateLimitFS that advances a fake clock instead of sleeping.
+func (t *RateLimitFSTestSuite) limited(options ...func(*RateLimitFS)) *RateLimitFS {
+	options = append(options, func(r *RateLimitFS) {
+		r.now = func() time.Time { return t.now }
+		r.sleep = func(d time.Duration) {
+			t.slept += d
+			t.now = t.now.Add(d)
+		}
+	})
+
+	r, err := New(t.backing, options...)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return r
+}
+
+func (t *RateLimitFSTestSuite) TestFS() {
+	r, err := New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.NoError(t.T(), fstest.TestFS(r, "doc/fox.txt"))
+}
+
+func (t *RateLimitFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	t.limited(WithIOPS(2, 2), WithLogger(logger))
+	assert.Contains(t.T(), buf.String(), `msg="[ratelimitfs] new" provider=memfs iops=2 bytes_per_second=0`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.limited().logger)
+}
+
+func (t *RateLimitFSTestSuite) TestIOPS() {
+	r := t.limited(WithIOPS(2, 2))
+
+	for i := 0; i < 5; i++ {
+		_, err := r.Stat("doc/fox.txt")
+		assert.NoError(t.T(), err)
+	}
+	assert.Equal(t.T(), 1500*time.Millisecond, t.slept)
+}
+
+func (t *RateLimitFSTestSuite) TestBytesPerSecond() {
+	r := t.limited(WithBytesPerSecond(10, 10))
+
+	assert.NoError(t.T(), r.WriteFile("doc/zeros.bin", make([]byte, 30), 0644))
+	assert.Equal(t.T(), 2*time.Second, t.slept)
+
+	b, err := r.ReadFile("doc/zeros.bin")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), b, 30)
+	assert.Equal(t.T(), 5*time.Second, t.slept)
+
+	t.now = t.now.Add(time.Minute)
+	t.slept = 0
+
+	f, err := r.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b, err = io.ReadAll(f)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+	assert.NoError(t.T(), f.Close())
+	assert.Equal(t.T(), 900*time.Millisecond, t.slept)
+}
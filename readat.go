@@ -0,0 +1,67 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Range is a range of bytes of a file.
+type Range struct {
+	// Off is the offset of the first byte of the range.
+	Off int64
+
+	// Len is the number of bytes in the range.
+	Len int64
+}
+
+// MultiReaderAt defines the behavior for reading many ranges of a file in a single operation, so that readers of
+// columnar formats such as Parquet, which fetch many small ranges of a file, can avoid the cost of a request per range.
+type MultiReaderAt interface {
+	// ReadAtMulti returns the content of each range in ranges, in the same order as ranges. If a range extends beyond
+	// the end of the file, its content is truncated to the end of the file, and io.EOF is returned along with the content
+	// of every range.
+	//
+	// The returned slices may share memory with the file, so they must not be modified, and their content is undefined
+	// once the file is written.
+	ReadAtMulti(ranges []Range) ([][]byte, error)
+}
+
+// ReadAtMulti returns the content of each range in ranges of r, in the same way as MultiReaderAt. If r does not
+// implement MultiReaderAt, each range is read using ReadAt. An error wrapping ErrInvalid is returned if a range has a
+// negative offset or length.
+func ReadAtMulti(r io.ReaderAt, ranges []Range) ([][]byte, error) {
+	if r == nil {
+		return nil, errors.New("fs: reader is required")
+	}
+
+	for _, rg := range ranges {
+		if rg.Off < 0 || rg.Len < 0 {
+			return nil, fmt.Errorf("fs: invalid range [%d, %d): %w", rg.Off, rg.Off+rg.Len, ErrInvalid)
+		}
+	}
+
+	if m, ok := r.(MultiReaderAt); ok {
+		return m.ReadAtMulti(ranges)
+	}
+
+	var eof bool
+	bufs := make([][]byte, len(ranges))
+	for i, rg := range ranges {
+		b := make([]byte, rg.Len)
+		n, err := r.ReadAt(b, rg.Off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if n < len(b) {
+			eof = true
+		}
+		bufs[i] = b[:n]
+	}
+
+	if eof {
+		return bufs, io.EOF
+	}
+	return bufs, nil
+}
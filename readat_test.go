@@ -0,0 +1,48 @@
+package fs_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAtMulti(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := mfs.Open("fox.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ranges := []fs.Range{{Off: 4, Len: 5}, {Off: 0, Len: 3}, {Off: 16, Len: 0}}
+	for _, r := range []io.ReaderAt{strings.NewReader("the quick brown fox"), f.(io.ReaderAt)} {
+		bufs, err := fs.ReadAtMulti(r, ranges)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("quick"), []byte("the"), {}}, bufs)
+
+		bufs, err = fs.ReadAtMulti(r, []fs.Range{{Off: 10, Len: 5}, {Off: 16, Len: 10}, {Off: 32, Len: 1}})
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, "brown", string(bufs[0]))
+		assert.Equal(t, "fox", string(bufs[1]))
+		assert.Empty(t, bufs[2])
+
+		_, err = fs.ReadAtMulti(r, []fs.Range{{Off: -1, Len: 1}})
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	}
+
+	_, err = fs.ReadAtMulti(nil, ranges)
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+
+	gofs "io/fs"
+)
+
+var (
+	_ FS   = (*readOnlyFS)(nil)
+	_ File = (*readOnlyFile)(nil)
+)
+
+// ReadOnly returns an immutable view of fsys. All Readable operations are passed through to fsys, and every Writable
+// operation is rejected with an error wrapping gofs.ErrPermission.
+//
+// Files opened through the returned FS reject writes, and closing the returned FS does not close fsys.
+func ReadOnly(fsys FS) FS {
+	if r, ok := fsys.(*readOnlyFS); ok {
+		return r
+	}
+	return &readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys FS
+}
+
+func (r *readOnlyFS) Close() error {
+	return nil
+}
+
+func (r *readOnlyFS) Create(name string) (File, error) {
+	return nil, permission("create", name)
+}
+
+func (r *readOnlyFS) Glob(pattern string) ([]string, error) {
+	return r.fsys.Glob(pattern)
+}
+
+func (r *readOnlyFS) Mkdir(name string, _ gofs.FileMode) error {
+	return permission("mkdir", name)
+}
+
+func (r *readOnlyFS) MkdirAll(path string, _ gofs.FileMode) error {
+	return permission("mkdirAll", path)
+}
+
+func (r *readOnlyFS) Open(name string) (gofs.File, error) {
+	f, err := r.fsys.OpenFile(name, O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyFile{File: f, name: name}, nil
+}
+
+func (r *readOnlyFS) OpenFile(name string, flag int, _ gofs.FileMode) (File, error) {
+	if flag&(O_WRONLY|O_RDWR|O_APPEND|O_CREATE|O_TRUNC) != 0 {
+		return nil, permission("openFile", name)
+	}
+
+	f, err := r.fsys.OpenFile(name, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyFile{File: f, name: name}, nil
+}
+
+func (r *readOnlyFS) PathSeparator() string {
+	return r.fsys.PathSeparator()
+}
+
+func (r *readOnlyFS) Provider() string {
+	return r.fsys.Provider()
+}
+
+func (r *readOnlyFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return r.fsys.ReadDir(name)
+}
+
+func (r *readOnlyFS) ReadFile(name string) ([]byte, error) {
+	return r.fsys.ReadFile(name)
+}
+
+func (r *readOnlyFS) Remove(name string) error {
+	return permission("remove", name)
+}
+
+func (r *readOnlyFS) RemoveAll(path string) error {
+	return permission("removeAll", path)
+}
+
+func (r *readOnlyFS) Rename(oldpath string, _ string) error {
+	return permission("rename", oldpath)
+}
+
+func (r *readOnlyFS) Root() (string, error) {
+	return r.fsys.Root()
+}
+
+func (r *readOnlyFS) Stat(name string) (gofs.FileInfo, error) {
+	return r.fsys.Stat(name)
+}
+
+// Sub returns the sub-tree for dir from the underlying file system. If the sub-tree is writable, it is also wrapped
+// with ReadOnly.
+func (r *readOnlyFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := r.fsys.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(FS); ok {
+		return ReadOnly(fsys), nil
+	}
+	return sub, nil
+}
+
+func (r *readOnlyFS) WriteFile(name string, _ []byte, _ gofs.FileMode) error {
+	return permission("writeFile", name)
+}
+
+type readOnlyFile struct {
+	File
+	name string
+}
+
+func (f *readOnlyFile) ReadFrom(io.Reader) (int64, error) {
+	return 0, permission("readFrom", f.name)
+}
+
+func (f *readOnlyFile) Write([]byte) (int, error) {
+	return 0, permission("write", f.name)
+}
+
+func permission(op string, name string) error {
+	return fmt.Errorf("fs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrPermission})
+}
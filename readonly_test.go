@@ -0,0 +1,53 @@
+package fs_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestReadOnly(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rfs := fs.ReadOnly(mfs)
+	assert.NoError(t, fstest.TestFS(rfs, "doc/fox.txt"))
+
+	assert.ErrorIs(t, rfs.WriteFile("doc/fox.txt", nil, 0644), gofs.ErrPermission)
+	assert.ErrorIs(t, rfs.Mkdir("pictures", 0755), gofs.ErrPermission)
+	assert.ErrorIs(t, rfs.MkdirAll("pictures/seals", 0755), gofs.ErrPermission)
+	assert.ErrorIs(t, rfs.Remove("doc/fox.txt"), gofs.ErrPermission)
+	assert.ErrorIs(t, rfs.RemoveAll("doc"), gofs.ErrPermission)
+	assert.ErrorIs(t, rfs.Rename("doc", "docs"), gofs.ErrPermission)
+
+	_, err = rfs.Create("doc/dog.txt")
+	assert.ErrorIs(t, err, gofs.ErrPermission)
+
+	_, err = rfs.OpenFile("doc/fox.txt", fs.O_RDWR, 0)
+	assert.ErrorIs(t, err, gofs.ErrPermission)
+
+	f, err := rfs.OpenFile("doc/fox.txt", fs.O_RDONLY, 0)
+	assert.NoError(t, err)
+
+	_, err = f.Write([]byte("jumps"))
+	assert.ErrorIs(t, err, gofs.ErrPermission)
+
+	sub, err := rfs.Sub("doc")
+	assert.NoError(t, err)
+
+	_, ok := sub.(fs.FS)
+	assert.True(t, ok)
+	assert.ErrorIs(t, sub.(fs.FS).WriteFile("dog.txt", nil, 0644), gofs.ErrPermission)
+}
@@ -0,0 +1,105 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/transientvariable/fs-go"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gofs "io/fs"
+)
+
+// errorDomain is the domain of the google.rpc.ErrorInfo detail attached to status errors.
+const errorDomain = "fs-go"
+
+// reasons maps file system errors to the status code and ErrorInfo reason used to transfer them.
+var reasons = []struct {
+	code   codes.Code
+	err    error
+	reason string
+}{
+	{code: codes.NotFound, err: gofs.ErrNotExist, reason: "NOT_EXIST"},
+	{code: codes.AlreadyExists, err: gofs.ErrExist, reason: "EXIST"},
+	{code: codes.PermissionDenied, err: gofs.ErrPermission, reason: "PERMISSION"},
+	{code: codes.InvalidArgument, err: gofs.ErrInvalid, reason: "INVALID"},
+	{code: codes.FailedPrecondition, err: gofs.ErrClosed, reason: "CLOSED"},
+	{code: codes.FailedPrecondition, err: fs.ErrIsDir, reason: "IS_DIR"},
+	{code: codes.FailedPrecondition, err: fs.ErrNotDir, reason: "NOT_DIR"},
+	{code: codes.FailedPrecondition, err: fs.ErrNotEmpty, reason: "NOT_EMPTY"},
+	{code: codes.Aborted, err: fs.ErrConflict, reason: "CONFLICT"},
+	{code: codes.ResourceExhausted, err: fs.ErrQuotaExceeded, reason: "QUOTA_EXCEEDED"},
+	{code: codes.ResourceExhausted, err: fs.ErrTooLarge, reason: "TOO_LARGE"},
+	{code: codes.Unimplemented, err: errors.ErrUnsupported, reason: "UNSUPPORTED"},
+}
+
+// toStatus converts err to a status error.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			st, derr := status.New(r.code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+				Domain: errorDomain,
+				Reason: r.reason,
+			})
+			if derr != nil {
+				return status.Error(r.code, err.Error())
+			}
+			return st.Err()
+		}
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// fromStatus converts a status error returned for an operation on the named file to an error that wraps the
+// corresponding file system error, if any.
+func fromStatus(op string, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != errorDomain {
+			continue
+		}
+
+		for _, r := range reasons {
+			if r.reason == info.GetReason() {
+				return fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: name, Err: r.err})
+			}
+		}
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		err = gofs.ErrNotExist
+	case codes.AlreadyExists:
+		err = gofs.ErrExist
+	case codes.PermissionDenied:
+		err = gofs.ErrPermission
+	case codes.Unimplemented:
+		err = errors.ErrUnsupported
+	}
+	return fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+}
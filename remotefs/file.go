@@ -0,0 +1,287 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	"google.golang.org/grpc"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*File)(nil)
+
+// File provides access to a single file or directory provided by RemoteFS.
+type File struct {
+	cancelRead  context.CancelFunc
+	cancelWrite context.CancelFunc
+	closed      bool
+	dirOff      int
+	entries     []*fs.Entry
+	flag        int
+	fsys        *RemoteFS
+	info        *fs.Entry
+	mutex       sync.Mutex
+	name        string
+	off         int64
+	pending     []byte
+	reader      grpc.ClientStream
+	writer      grpc.ClientStream
+}
+
+// Close closes the File. If the File was opened for writing, Close waits for the server to close the file, and returns
+// any error that occurred while writing.
+func (f *File) Close() error {
+	if f == nil {
+		return gofs.ErrInvalid
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "close", Path: f.name, Err: gofs.ErrClosed})
+	}
+	f.closed = true
+	f.stopRead()
+
+	if f.writer == nil {
+		return nil
+	}
+	defer f.cancelWrite()
+
+	if err := f.writer.CloseSend(); err != nil {
+		return fromStatus("close", f.name, err)
+	}
+
+	if err := f.writer.RecvMsg(&writeResponse{}); err != nil {
+		return fromStatus("close", f.name, err)
+	}
+	return nil
+}
+
+// Read reads from the File by streaming its content from the current offset.
+func (f *File) Read(b []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.checkRead("read"); err != nil {
+		return 0, err
+	}
+
+	if f.reader == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := f.fsys.read(ctx, f.name, f.off, -1)
+		if err != nil {
+			cancel()
+			return 0, fromStatus("read", f.name, err)
+		}
+		f.cancelRead = cancel
+		f.reader = stream
+	}
+
+	for len(f.pending) == 0 {
+		resp := &readResponse{}
+		if err := f.reader.RecvMsg(resp); err != nil {
+			f.stopRead()
+			if errors.Is(err, io.EOF) {
+				return 0, io.EOF
+			}
+			return 0, fromStatus("read", f.name, err)
+		}
+		f.pending = resp.data
+	}
+
+	n := copy(b, f.pending)
+	f.pending = f.pending[n:]
+	f.off += int64(n)
+	return n, nil
+}
+
+// ReadAt reads len(b) bytes from the File starting at off.
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	f.mutex.Lock()
+	err := f.checkRead("readAt")
+	f.mutex.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if off < 0 {
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "readAt", Path: f.name, Err: gofs.ErrInvalid})
+	}
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := f.fsys.read(ctx, f.name, off, int64(len(b)))
+	if err != nil {
+		return 0, fromStatus("readAt", f.name, err)
+	}
+
+	var n int
+	for n < len(b) {
+		resp := &readResponse{}
+		if err := stream.RecvMsg(resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, io.EOF
+			}
+			return n, fromStatus("readAt", f.name, err)
+		}
+		n += copy(b[n:], resp.data)
+	}
+	return n, nil
+}
+
+// ReadDir ...
+func (f *File) ReadDir(n int) ([]gofs.DirEntry, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "readDir", Path: f.name, Err: gofs.ErrClosed})
+	}
+
+	if f.info == nil || !f.info.IsDir() {
+		return nil, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "readDir", Path: f.name, Err: fs.ErrNotDir})
+	}
+
+	if f.entries == nil {
+		entries, err := f.fsys.readDir("readDir", f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+	}
+
+	remaining := f.entries[f.dirOff:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+
+		if n < len(remaining) {
+			remaining = remaining[:n]
+		}
+	}
+	f.dirOff += len(remaining)
+
+	entries := make([]gofs.DirEntry, len(remaining))
+	for i, e := range remaining {
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// ReadFrom ...
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+// Seek sets the offset for the next Read or Write on the File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "seek", Path: f.name, Err: gofs.ErrClosed})
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		fi, err := f.fsys.stat("seek", f.name)
+		if err != nil {
+			return 0, err
+		}
+		offset += fi.Size()
+	default:
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "seek", Path: f.name, Err: gofs.ErrInvalid})
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "seek", Path: f.name, Err: gofs.ErrInvalid})
+	}
+
+	if offset != f.off {
+		f.stopRead()
+		f.off = offset
+	}
+	return offset, nil
+}
+
+// Stat returns the current file info for the File.
+func (f *File) Stat() (gofs.FileInfo, error) {
+	if f == nil {
+		return nil, gofs.ErrInvalid
+	}
+	return f.fsys.stat("stat", f.name)
+}
+
+// Write writes to the File at the current offset. The data is sent in chunks over the write stream of the File.
+func (f *File) Write(b []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "write", Path: f.name, Err: gofs.ErrClosed})
+	}
+
+	if f.writer == nil {
+		return 0, fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: "write", Path: f.name, Err: gofs.ErrPermission})
+	}
+
+	f.stopRead()
+
+	var n int
+	for n < len(b) {
+		chunk := b[n:min(n+f.fsys.chunkSize, len(b))]
+		if err := f.writer.SendMsg(&writeRequest{data: chunk, offset: f.off}); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The server ended the stream, and the reason is returned by RecvMsg.
+				if err = f.writer.RecvMsg(&writeResponse{}); err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			return n, fromStatus("write", f.name, err)
+		}
+		n += len(chunk)
+		f.off += int64(len(chunk))
+	}
+	return n, nil
+}
+
+func (f *File) checkRead(op string) error {
+	if f.closed {
+		return fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: op, Path: f.name, Err: gofs.ErrClosed})
+	}
+
+	if f.flag&fs.O_WRONLY != 0 {
+		return fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: op, Path: f.name, Err: gofs.ErrPermission})
+	}
+
+	if f.info != nil && f.info.IsDir() {
+		return fmt.Errorf("remotefs_file: %w", &gofs.PathError{Op: op, Path: f.name, Err: fs.ErrIsDir})
+	}
+	return nil
+}
+
+func (f *File) stopRead() {
+	if f.cancelRead != nil {
+		f.cancelRead()
+		f.cancelRead = nil
+	}
+	f.pending = nil
+	f.reader = nil
+}
@@ -0,0 +1,462 @@
+// Package remotefs exports an fs.FS over gRPC, and provides an fs.FS that accesses a remote file system over the wire.
+//
+// The service is defined in remotefs.proto. A Server implements the service for any fs.FS and is registered with a
+// grpc.Server, and a RemoteFS implements fs.FS using a client connection to the service, so that one process can
+// expose its MemFS or OSFS to others:
+//
+//	grpcServer := grpc.NewServer(remotefs.ServerOption())
+//	srv, _ := remotefs.NewServer(mfs)
+//	srv.Register(grpcServer)
+//
+//	rfs, _ := remotefs.New(clientConn)
+//	b, err := rfs.ReadFile("doc/fox.txt")
+//
+// File contents are streamed in both directions. Reading a File streams its content from the current offset, and
+// writing to a File sends the data over a stream that remains open until the File is closed, at which point any error
+// that occurred on the server is returned. Writes are applied by the server in order, but are not guaranteed to be
+// visible to reads until the File is closed.
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/transientvariable/fs-go"
+
+	"google.golang.org/grpc"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const pathSeparator = "/"
+
+var _ fs.FS = (*RemoteFS)(nil)
+
+var (
+	readDesc  = &grpc.StreamDesc{StreamName: "Read", ServerStreams: true}
+	writeDesc = &grpc.StreamDesc{StreamName: "Write", ServerStreams: true, ClientStreams: true}
+
+	// forceCodec is passed to every call, so that the messages of the service are encoded by the codec of the package
+	// regardless of the codecs configured for the connection.
+	forceCodec = grpc.ForceCodecV2(defaultCodec)
+)
+
+// RemoteFS provides access to a file system exported by a Server, and implements fs.FS.
+type RemoteFS struct {
+	chunkSize int
+	closed    bool
+	conn      grpc.ClientConnInterface
+	logger    fs.Logger
+	mutex     sync.Mutex
+}
+
+// New creates a new RemoteFS that uses conn to call the FileSystem service. The connection is owned by the caller and
+// is not closed by Close.
+func New(conn grpc.ClientConnInterface, options ...func(*RemoteFS)) (*RemoteFS, error) {
+	if conn == nil {
+		return nil, errors.New("remotefs: client connection is required")
+	}
+
+	r := &RemoteFS{chunkSize: chunkSize, conn: conn, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	if r.chunkSize <= 0 {
+		return nil, errors.New("remotefs: chunk size must be positive")
+	}
+	return r, nil
+}
+
+// Close ...
+func (r *RemoteFS) Close() error {
+	if r == nil {
+		return gofs.ErrInvalid
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		return nil
+	}
+	return fmt.Errorf("remotefs: %w", gofs.ErrClosed)
+}
+
+// Create ...
+func (r *RemoteFS) Create(name string) (fs.File, error) {
+	return r.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Glob ...
+func (r *RemoteFS) Glob(pattern string) ([]string, error) {
+	r.logger.Debug("[remotefs] glob", "pattern", pattern)
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("remotefs: %w", &gofs.PathError{Op: "glob", Path: pattern, Err: err})
+	}
+
+	var matches []string
+	err := gofs.WalkDir(r, ".", func(path string, entry gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if matched, _ := filepath.Match(pattern, path); matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return matches, err
+	}
+	return matches, nil
+}
+
+// Mkdir ...
+func (r *RemoteFS) Mkdir(name string, perm gofs.FileMode) error {
+	return r.mkdir("mkdir", name, perm, false)
+}
+
+// MkdirAll ...
+func (r *RemoteFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return r.mkdir("mkdirAll", path, perm, true)
+}
+
+// Open opens the named File for reading.
+func (r *RemoteFS) Open(name string) (gofs.File, error) {
+	return r.OpenFile(name, fs.O_RDONLY, 0)
+}
+
+// OpenFile opens the named File. If the file is opened for writing, a write stream is opened on which the server opens
+// the file, so that errors such as a missing parent directory are returned by OpenFile.
+func (r *RemoteFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	r.logger.Debug("[remotefs] openFile", "name", name, "flag", flag)
+
+	name, err := r.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{flag: flag, fsys: r, name: name}
+	if flag&(fs.O_WRONLY|fs.O_RDWR) == 0 {
+		fi, err := r.stat("openFile", name)
+		if err != nil {
+			return nil, err
+		}
+		f.info = fi
+		return f, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := r.conn.NewStream(ctx, writeDesc, method("Write"), forceCodec)
+	if err != nil {
+		cancel()
+		return nil, fromStatus("openFile", name, err)
+	}
+
+	req := &writeRequest{flags: writeFlags(flag), path: name, perm: uint32(perm.Perm())}
+	if err := stream.SendMsg(req); err != nil && !errors.Is(err, io.EOF) {
+		cancel()
+		return nil, fromStatus("openFile", name, err)
+	}
+
+	if err := stream.RecvMsg(&writeResponse{}); err != nil {
+		cancel()
+		return nil, fromStatus("openFile", name, err)
+	}
+
+	f.cancelWrite = cancel
+	f.writer = stream
+	return f, nil
+}
+
+// PathSeparator ...
+func (r *RemoteFS) PathSeparator() string {
+	return pathSeparator
+}
+
+// Provider ...
+func (r *RemoteFS) Provider() string {
+	return "remotefs"
+}
+
+// ReadDir ...
+func (r *RemoteFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	r.logger.Debug("[remotefs] readDir", "name", name)
+
+	de, err := r.readDir("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]gofs.DirEntry, len(de))
+	for i, e := range de {
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// ReadFile ...
+func (r *RemoteFS) ReadFile(name string) ([]byte, error) {
+	r.logger.Debug("[remotefs] readFile", "name", name)
+
+	name, err := r.clean("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := r.read(context.Background(), name, 0, -1)
+	if err != nil {
+		return nil, fromStatus("readFile", name, err)
+	}
+
+	var b []byte
+	for {
+		resp := &readResponse{}
+		if err := stream.RecvMsg(resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				return b, nil
+			}
+			return nil, fromStatus("readFile", name, err)
+		}
+		b = append(b, resp.data...)
+	}
+}
+
+// Remove ...
+func (r *RemoteFS) Remove(name string) error {
+	return r.remove("remove", name, false)
+}
+
+// RemoveAll ...
+func (r *RemoteFS) RemoveAll(path string) error {
+	return r.remove("removeAll", path, true)
+}
+
+// Rename ...
+func (r *RemoteFS) Rename(oldpath string, newpath string) error {
+	r.logger.Debug("[remotefs] rename", "oldpath", oldpath, "newpath", newpath)
+
+	oldpath, err := r.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = r.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	req := &renameRequest{newPath: newpath, oldPath: oldpath}
+	if err := r.conn.Invoke(context.Background(), method("Rename"), req, &empty{}, forceCodec); err != nil {
+		return fromStatus("rename", oldpath, err)
+	}
+	return nil
+}
+
+// Root ...
+func (r *RemoteFS) Root() (string, error) {
+	return pathSeparator, nil
+}
+
+// Stat ...
+func (r *RemoteFS) Stat(name string) (gofs.FileInfo, error) {
+	r.logger.Debug("[remotefs] stat", "name", name)
+
+	name, err := r.clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return r.stat("stat", name)
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot.
+func (r *RemoteFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(r, dir)
+}
+
+// WriteFile ...
+func (r *RemoteFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	r.logger.Debug("[remotefs] writeFile", "name", name, "size", len(data))
+
+	f, err := r.OpenFile(name, fs.O_WRONLY|fs.O_CREATE|fs.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *RemoteFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(r, name)
+	if err != nil {
+		return name, fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+func (r *RemoteFS) mkdir(op string, name string, perm gofs.FileMode, all bool) error {
+	r.logger.Debug("[remotefs] "+op, "name", name)
+
+	name, err := r.clean(op, name)
+	if err != nil {
+		return err
+	}
+
+	req := &mkdirRequest{all: all, path: name, perm: uint32(perm.Perm())}
+	if err := r.conn.Invoke(context.Background(), method("Mkdir"), req, &empty{}, forceCodec); err != nil {
+		return fromStatus(op, name, err)
+	}
+	return nil
+}
+
+// read opens a stream that reads up to length bytes of the named file starting at off, or the remainder of the file if
+// length is negative.
+func (r *RemoteFS) read(ctx context.Context, name string, off int64, length int64) (grpc.ClientStream, error) {
+	stream, err := r.conn.NewStream(ctx, readDesc, method("Read"), forceCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.SendMsg(&readRequest{length: length, offset: off, path: name}); err != nil &&
+		!errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (r *RemoteFS) readDir(op string, name string) ([]*fs.Entry, error) {
+	name, err := r.clean(op, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &readDirResponse{}
+	if err := r.conn.Invoke(context.Background(), method("ReadDir"), &pathRequest{path: name}, resp, forceCodec); err != nil {
+		return nil, fromStatus(op, name, err)
+	}
+
+	entries := make([]*fs.Entry, 0, len(resp.entries))
+	for _, fi := range resp.entries {
+		p := fi.name
+		if name != "." {
+			p = gopath.Join(name, p)
+		}
+
+		entry, err := newEntry(p, fi)
+		if err != nil {
+			return entries, fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: p, Err: err})
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *RemoteFS) remove(op string, name string, all bool) error {
+	r.logger.Debug("[remotefs] "+op, "name", name)
+
+	name, err := r.clean(op, name)
+	if err != nil {
+		return err
+	}
+
+	req := &removeRequest{all: all, path: name}
+	if err := r.conn.Invoke(context.Background(), method("Remove"), req, &empty{}, forceCodec); err != nil {
+		return fromStatus(op, name, err)
+	}
+	return nil
+}
+
+func (r *RemoteFS) stat(op string, name string) (*fs.Entry, error) {
+	resp := &fileInfo{}
+	if err := r.conn.Invoke(context.Background(), method("Stat"), &pathRequest{path: name}, resp, forceCodec); err != nil {
+		return nil, fromStatus(op, name, err)
+	}
+
+	entry, err := newEntry(name, resp)
+	if err != nil {
+		return nil, fmt.Errorf("remotefs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return entry, nil
+}
+
+// WithChunkSize sets the maximum number of bytes sent in a single message when writing to a File.
+func WithChunkSize(n int) func(*RemoteFS) {
+	return func(r *RemoteFS) {
+		r.chunkSize = n
+	}
+}
+
+// WithLogger sets the Logger used by a RemoteFS to log the operations that it performs. By default, nothing is
+// logged.
+func WithLogger(logger fs.Logger) func(*RemoteFS) {
+	return func(r *RemoteFS) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+func method(name string) string {
+	return "/" + serviceName + "/" + name
+}
+
+func newEntry(path string, fi *fileInfo) (*fs.Entry, error) {
+	attrs, err := fs.NewAttributes(
+		fs.WithCtime(fi.modTimeValue()),
+		fs.WithGeneration(uint64(max(fi.generation, 0))),
+		fs.WithMode(fi.mode),
+		fs.WithMtime(fi.modTimeValue()),
+		fs.WithSize(uint64(max(fi.size, 0))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return fs.NewEntry(path, fs.WithAttributes(attrs))
+}
+
+// writeFlags converts flags for fs.FS.OpenFile to open flags defined by OpenFlag in remotefs.proto.
+func writeFlags(flag int) uint32 {
+	var flags uint32
+	switch {
+	case flag&fs.O_RDWR != 0:
+		flags = flagRead | flagWrite
+	case flag&fs.O_WRONLY != 0:
+		flags = flagWrite
+	default:
+		flags = flagRead
+	}
+
+	if flag&fs.O_APPEND != 0 {
+		flags |= flagAppend
+	}
+
+	if flag&fs.O_CREATE != 0 {
+		flags |= flagCreate
+	}
+
+	if flag&fs.O_TRUNC != 0 {
+		flags |= flagTrunc
+	}
+
+	if flag&os.O_EXCL != 0 {
+		flags |= flagExcl
+	}
+	return flags
+}
@@ -0,0 +1,327 @@
+package remotefs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// linkFS is a MemFS with symbolic links, whose destinations are resolved by the server rather than by the MemFS.
+type linkFS struct {
+	*memfs.MemFS
+	links map[string]string
+}
+
+func (l *linkFS) Readlink(name string) (string, error) {
+	if dest, ok := l.links[name]; ok {
+		return dest, nil
+	}
+	return "", &gofs.PathError{Op: "readlink", Path: name, Err: gofs.ErrInvalid}
+}
+
+func (l *linkFS) Symlink(oldname string, newname string) error {
+	return &gofs.PathError{Op: "symlink", Path: newname, Err: fs.ErrUnsupported}
+}
+
+// RemoteFSTestSuite ...
+type RemoteFSTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	conn    *grpc.ClientConn
+	links   map[string]string
+	remote  *RemoteFS
+	server  *grpc.Server
+}
+
+func NewRemoteFSTestSuite() *RemoteFSTestSuite {
+	return &RemoteFSTestSuite{}
+}
+
+func (t *RemoteFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+	t.links = make(map[string]string)
+
+	srv, err := NewServer(&linkFS{MemFS: backing, links: t.links})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	l := bufconn.Listen(1024 * 1024)
+	t.server = grpc.NewServer(ServerOption())
+	srv.Register(t.server)
+	go t.server.Serve(l)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.conn = conn
+
+	remote, err := New(conn, WithChunkSize(4))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.remote = remote
+}
+
+func (t *RemoteFSTestSuite) TearDownTest() {
+	assert.NoError(t.T(), t.remote.Close())
+	assert.NoError(t.T(), t.conn.Close())
+	t.server.Stop()
+}
+
+func TestRemoteFSTestSuite(t *testing.T) {
+	suite.Run(t, NewRemoteFSTestSuite())
+}
+
+func (t *RemoteFSTestSuite) TestStat() {
+	fi, err := t.remote.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "fox.txt", fi.Name())
+	assert.Equal(t.T(), int64(19), fi.Size())
+	assert.False(t.T(), fi.IsDir())
+	assert.Equal(t.T(), int64(1), fi.(*fs.Entry).Attributes().Generation())
+
+	fi, err = t.remote.Stat("doc")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+
+	_, err = t.remote.Stat("missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *RemoteFSTestSuite) TestRead() {
+	b, err := t.remote.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	_, err = t.remote.ReadFile("doc")
+	assert.ErrorIs(t.T(), err, fs.ErrIsDir)
+
+	f, err := t.remote.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer f.Close()
+
+	file := f.(*File)
+	buf := make([]byte, 5)
+	n, err := file.ReadAt(buf, 10)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "brown", string(buf[:n]))
+
+	n, err = file.ReadAt(buf, 16)
+	assert.ErrorIs(t.T(), err, io.EOF)
+	assert.Equal(t.T(), "fox", string(buf[:n]))
+
+	_, err = file.Seek(4, io.SeekStart)
+	assert.NoError(t.T(), err)
+
+	b, err = io.ReadAll(file)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "quick brown fox", string(b))
+}
+
+func (t *RemoteFSTestSuite) TestReadDir() {
+	if err := t.backing.WriteFile("doc/dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	entries, err := t.remote.ReadDir("doc")
+	assert.NoError(t.T(), err)
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t.T(), []string{"dog.txt", "fox.txt"}, names)
+
+	matches, err := t.remote.Glob("doc/*.txt")
+	assert.NoError(t.T(), err)
+	assert.ElementsMatch(t.T(), []string{"doc/dog.txt", "doc/fox.txt"}, matches)
+
+	f, err := t.remote.Open("doc")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer f.Close()
+
+	de, err := f.(*File).ReadDir(1)
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), de, 1)
+}
+
+func (t *RemoteFSTestSuite) TestWrite() {
+	assert.NoError(t.T(), t.remote.WriteFile("doc/dog.txt", []byte("jumps over the lazy dog"), 0600))
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	f, err := t.remote.Create("doc/cat.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	n, err := f.ReadFrom(strings.NewReader("the cat sat"))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(11), n)
+
+	_, err = f.Write([]byte(" down"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+
+	b, err = t.backing.ReadFile("doc/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat sat down", string(b))
+
+	_, err = t.remote.OpenFile("missing.txt", fs.O_WRONLY, 0)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	f, err = t.remote.OpenFile("doc/fox.txt", fs.O_RDONLY, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	_, err = f.Write([]byte("x"))
+	assert.ErrorIs(t.T(), err, gofs.ErrPermission)
+	assert.NoError(t.T(), f.Close())
+	assert.ErrorIs(t.T(), f.Close(), gofs.ErrClosed)
+}
+
+func (t *RemoteFSTestSuite) TestDirs() {
+	assert.NoError(t.T(), t.remote.MkdirAll("a/b/c", 0755))
+	assert.NoError(t.T(), t.remote.Mkdir("a/d", 0755))
+	assert.ErrorIs(t.T(), t.remote.Mkdir("a/d", 0755), gofs.ErrExist)
+
+	assert.NoError(t.T(), t.remote.Rename("doc/fox.txt", "a/b/fox.txt"))
+	_, err := t.backing.Stat("a/b/fox.txt")
+	assert.NoError(t.T(), err)
+
+	sub, err := t.remote.Sub("a/b")
+	assert.NoError(t.T(), err)
+	b, err := gofs.ReadFile(sub, "fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	assert.NoError(t.T(), t.remote.Remove("a/d"))
+	assert.NoError(t.T(), t.remote.RemoveAll("a"))
+
+	_, err = t.backing.Stat("a")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *RemoteFSTestSuite) TestSecurePath() {
+	assert.NoError(t.T(), t.backing.WriteFile("pictures/seal.txt", []byte("the happy seal"), 0644))
+	t.links["doc/escape"] = "../../../pictures"
+	t.links["doc/absolute"] = "/pictures"
+	t.links["loop"] = "loop"
+
+	// Symbolic links are resolved as if the root of the file system were the root, so that they can not refer to a
+	// location outside of it.
+	for _, link := range []string{"doc/escape", "doc/absolute"} {
+		b, err := t.remote.ReadFile(link + "/seal.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), "the happy seal", string(b))
+
+		fi, err := t.remote.Stat(link + "/seal.txt")
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), int64(14), fi.Size())
+
+		entries, err := t.remote.ReadDir(link)
+		assert.NoError(t.T(), err)
+		assert.Len(t.T(), entries, 1)
+	}
+
+	assert.NoError(t.T(), t.remote.WriteFile("doc/escape/otter.txt", []byte("the playful otter"), 0644))
+	assert.NoError(t.T(), t.remote.Mkdir("doc/escape/otters", 0755))
+	assert.NoError(t.T(), t.remote.Rename("doc/escape/otter.txt", "doc/absolute/otters/otter.txt"))
+
+	b, err := t.backing.ReadFile("pictures/otters/otter.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the playful otter", string(b))
+
+	assert.NoError(t.T(), t.remote.RemoveAll("doc/escape/otters"))
+	_, err = t.backing.Stat("pictures/otters")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.remote.Stat("loop")
+	assert.Error(t.T(), err)
+}
+
+func TestMessages(t *testing.T) {
+	req := &writeRequest{data: []byte("data"), flags: flagWrite | flagCreate, offset: 42, path: "doc/fox.txt", perm: 0644}
+	got := &writeRequest{}
+	assert.NoError(t, got.unmarshal(req.marshal(nil)))
+	assert.Equal(t, req, got)
+
+	read := &readRequest{length: -1, offset: 7, path: "doc/fox.txt"}
+	gotRead := &readRequest{}
+	assert.NoError(t, gotRead.unmarshal(read.marshal(nil)))
+	assert.Equal(t, read, gotRead)
+
+	assert.Error(t, (&pathRequest{}).unmarshal([]byte{0x0a, 0x05, 'a'}))
+}
+
+func TestCodec(t *testing.T) {
+	_, ok := encoding.GetCodecV2(proto.Name).(*codec)
+	assert.False(t, ok)
+	assert.Equal(t, defaultCodec, encoding.GetCodecV2(codecName))
+}
+
+func (t *RemoteFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	remote, err := New(t.conn, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = remote.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[remotefs] stat" name=doc/fox.txt`)
+	assert.Equal(t.T(), fs.NopLogger(), t.remote.logger)
+
+	srv, err := NewServer(t.backing, WithServerLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = srv.stat(context.Background(), &pathRequest{path: "doc/fox.txt"})
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[remotefs] stat" path=doc/fox.txt`)
+
+	srv, err = NewServer(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), srv.logger)
+}
@@ -0,0 +1,400 @@
+package remotefs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	gofs "io/fs"
+)
+
+// The messages of the service are encoded using protowire, so that they are wire-compatible with remotefs.proto
+// without requiring generated code. The codec is registered under codecName rather than replacing the "proto" codec
+// of the process, and is forced for the calls made by a RemoteFS and by a grpc.Server created using ServerOption.
+func init() {
+	encoding.RegisterCodecV2(defaultCodec)
+}
+
+// codecName is the name of the codec that encodes the messages of the service.
+const codecName = "remotefs"
+
+// defaultCodec encodes the messages of the service, and delegates any other message to the "proto" codec.
+var defaultCodec = &codec{base: encoding.GetCodecV2(proto.Name)}
+
+// Open flags defined by OpenFlag in remotefs.proto.
+const (
+	flagRead   = 0x01
+	flagWrite  = 0x02
+	flagAppend = 0x04
+	flagCreate = 0x08
+	flagTrunc  = 0x10
+	flagExcl   = 0x20
+)
+
+// message is implemented by the messages of the service.
+type message interface {
+	marshal(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+// codec encodes the messages of the service, and delegates any other message to base.
+type codec struct {
+	base encoding.CodecV2
+}
+
+func (c *codec) Marshal(v any) (mem.BufferSlice, error) {
+	if m, ok := v.(message); ok {
+		return mem.BufferSlice{mem.SliceBuffer(m.marshal(nil))}, nil
+	}
+	return c.base.Marshal(v)
+}
+
+func (c *codec) Unmarshal(data mem.BufferSlice, v any) error {
+	if m, ok := v.(message); ok {
+		return m.unmarshal(data.Materialize())
+	}
+	return c.base.Unmarshal(data, v)
+}
+
+func (c *codec) Name() string {
+	return codecName
+}
+
+// field is the value of a decoded field.
+type field struct {
+	bytes  []byte
+	varint uint64
+}
+
+// decode decodes the fields in b, and calls fn with each field that has a varint or length-delimited value. Fields
+// with other types are skipped.
+func decode(b []byte, fn func(num protowire.Number, f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("remotefs: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var f field
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fmt.Errorf("remotefs: %w", protowire.ParseError(n))
+			}
+			b = b[n:]
+			continue
+		}
+
+		if n < 0 {
+			return fmt.Errorf("remotefs: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(num, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	return appendVarint(b, num, protowire.EncodeBool(v))
+}
+
+type empty struct{}
+
+func (m *empty) marshal(b []byte) []byte {
+	return b
+}
+
+func (m *empty) unmarshal(b []byte) error {
+	return decode(b, func(protowire.Number, field) error { return nil })
+}
+
+type pathRequest struct {
+	path string
+}
+
+func (m *pathRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, m.path)
+}
+
+func (m *pathRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		if num == 1 {
+			m.path = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type fileInfo struct {
+	generation int64
+	modTime    int64
+	mode       uint32
+	name       string
+	size       int64
+}
+
+func newFileInfo(fi gofs.FileInfo) *fileInfo {
+	m := &fileInfo{modTime: fi.ModTime().UnixNano(), mode: uint32(fi.Mode()), name: fi.Name(), size: fi.Size()}
+	if e, ok := fi.(*fs.Entry); ok {
+		m.generation = e.Attributes().Generation()
+	}
+	return m
+}
+
+func (m *fileInfo) modTimeValue() time.Time {
+	return time.Unix(0, m.modTime)
+}
+
+func (m *fileInfo) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.name)
+	b = appendVarint(b, 2, uint64(m.size))
+	b = appendVarint(b, 3, uint64(m.mode))
+	b = appendVarint(b, 4, uint64(m.modTime))
+	return appendVarint(b, 5, uint64(m.generation))
+}
+
+func (m *fileInfo) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.name = string(f.bytes)
+		case 2:
+			m.size = int64(f.varint)
+		case 3:
+			m.mode = uint32(f.varint)
+		case 4:
+			m.modTime = int64(f.varint)
+		case 5:
+			m.generation = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+type readDirResponse struct {
+	entries []*fileInfo
+}
+
+func (m *readDirResponse) marshal(b []byte) []byte {
+	for _, e := range m.entries {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, e.marshal(nil))
+	}
+	return b
+}
+
+func (m *readDirResponse) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		if num == 1 {
+			e := &fileInfo{}
+			if err := e.unmarshal(f.bytes); err != nil {
+				return err
+			}
+			m.entries = append(m.entries, e)
+		}
+		return nil
+	})
+}
+
+type readRequest struct {
+	length int64
+	offset int64
+	path   string
+}
+
+func (m *readRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.path)
+	b = appendVarint(b, 2, uint64(m.offset))
+	return appendVarint(b, 3, uint64(m.length))
+}
+
+func (m *readRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.path = string(f.bytes)
+		case 2:
+			m.offset = int64(f.varint)
+		case 3:
+			m.length = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+type readResponse struct {
+	data []byte
+}
+
+func (m *readResponse) marshal(b []byte) []byte {
+	return appendBytes(b, 1, m.data)
+}
+
+func (m *readResponse) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		if num == 1 {
+			m.data = f.bytes
+		}
+		return nil
+	})
+}
+
+type writeRequest struct {
+	data   []byte
+	flags  uint32
+	offset int64
+	path   string
+	perm   uint32
+}
+
+func (m *writeRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.path)
+	b = appendVarint(b, 2, uint64(m.flags))
+	b = appendVarint(b, 3, uint64(m.perm))
+	b = appendVarint(b, 4, uint64(m.offset))
+	return appendBytes(b, 5, m.data)
+}
+
+func (m *writeRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.path = string(f.bytes)
+		case 2:
+			m.flags = uint32(f.varint)
+		case 3:
+			m.perm = uint32(f.varint)
+		case 4:
+			m.offset = int64(f.varint)
+		case 5:
+			m.data = f.bytes
+		}
+		return nil
+	})
+}
+
+type writeResponse struct {
+	written int64
+}
+
+func (m *writeResponse) marshal(b []byte) []byte {
+	return appendVarint(b, 1, uint64(m.written))
+}
+
+func (m *writeResponse) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		if num == 1 {
+			m.written = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+type mkdirRequest struct {
+	all  bool
+	path string
+	perm uint32
+}
+
+func (m *mkdirRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.path)
+	b = appendVarint(b, 2, uint64(m.perm))
+	return appendBool(b, 3, m.all)
+}
+
+func (m *mkdirRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.path = string(f.bytes)
+		case 2:
+			m.perm = uint32(f.varint)
+		case 3:
+			m.all = protowire.DecodeBool(f.varint)
+		}
+		return nil
+	})
+}
+
+type removeRequest struct {
+	all  bool
+	path string
+}
+
+func (m *removeRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.path)
+	return appendBool(b, 2, m.all)
+}
+
+func (m *removeRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.path = string(f.bytes)
+		case 2:
+			m.all = protowire.DecodeBool(f.varint)
+		}
+		return nil
+	})
+}
+
+type renameRequest struct {
+	newPath string
+	oldPath string
+}
+
+func (m *renameRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.oldPath)
+	return appendString(b, 2, m.newPath)
+}
+
+func (m *renameRequest) unmarshal(b []byte) error {
+	return decode(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.oldPath = string(f.bytes)
+		case 2:
+			m.newPath = string(f.bytes)
+		}
+		return nil
+	})
+}
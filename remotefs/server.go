@@ -0,0 +1,409 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/transientvariable/fs-go"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gofs "io/fs"
+)
+
+const (
+	// serviceName is the fully qualified name of the FileSystem service defined in remotefs.proto.
+	serviceName = "remotefs.v1.FileSystem"
+
+	// chunkSize is the maximum number of bytes sent in a single message by Read.
+	chunkSize = 64 * 1024
+)
+
+// fileSystemServer is the interface implemented by the handler of the FileSystem service.
+type fileSystemServer interface {
+	mkdir(ctx context.Context, req *mkdirRequest) (message, error)
+	read(req *readRequest, stream grpc.ServerStream) error
+	readDir(ctx context.Context, req *pathRequest) (message, error)
+	remove(ctx context.Context, req *removeRequest) (message, error)
+	rename(ctx context.Context, req *renameRequest) (message, error)
+	stat(ctx context.Context, req *pathRequest) (message, error)
+	write(stream grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*fileSystemServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Stat", fileSystemServer.stat),
+		unary("ReadDir", fileSystemServer.readDir),
+		unary("Mkdir", fileSystemServer.mkdir),
+		unary("Remove", fileSystemServer.remove),
+		unary("Rename", fileSystemServer.rename),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Read",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				req := &readRequest{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(fileSystemServer).read(req, stream)
+			},
+			ServerStreams: true,
+		},
+		{
+			StreamName: "Write",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(fileSystemServer).write(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "remotefs.proto",
+}
+
+// unary returns the description of a unary method implemented by fn.
+func unary[T any, P interface {
+	*T
+	message
+}](method string, fn func(fileSystemServer, context.Context, P) (message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := P(new(T))
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+
+			s := srv.(fileSystemServer)
+			if interceptor == nil {
+				return fn(s, ctx, req)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return fn(s, ctx, req.(P))
+			})
+		},
+	}
+}
+
+// Server implements the FileSystem service defined in remotefs.proto for an fs.FS. Paths provided by clients are
+// resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a location outside of the
+// fs.FS.
+type Server struct {
+	fsys   fs.FS
+	logger fs.Logger
+}
+
+// NewServer creates a new Server that exports fsys.
+func NewServer(fsys fs.FS, options ...func(*Server)) (*Server, error) {
+	if fsys == nil {
+		return nil, errors.New("remotefs: file system is required")
+	}
+
+	s := &Server{fsys: fsys, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s, nil
+}
+
+// ServerOption returns the grpc.ServerOption that makes a grpc.Server encode messages using the codec of the package.
+// It is required by clients that use the "proto" content subtype, such as clients generated from remotefs.proto, and
+// other messages are delegated to the "proto" codec.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodecV2(defaultCodec)
+}
+
+// Register registers the FileSystem service with r, which is typically a *grpc.Server created using ServerOption.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&serviceDesc, s)
+}
+
+// path resolves the path provided by a client to a path for the fs.FS using fs.SecureJoin, so that neither ".."
+// elements nor symbolic links can refer to a location outside of the fs.FS.
+func (s *Server) path(name string) (string, error) {
+	p, err := fs.SecureJoin(s.fsys, ".", name)
+	if err != nil {
+		return "", toStatus(err)
+	}
+	return p, nil
+}
+
+func (s *Server) mkdir(_ context.Context, req *mkdirRequest) (message, error) {
+	s.logger.Debug("[remotefs] mkdir", "path", req.path, "all", req.all)
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.all {
+		err = s.fsys.MkdirAll(name, gofs.FileMode(req.perm).Perm())
+	} else {
+		err = s.fsys.Mkdir(name, gofs.FileMode(req.perm).Perm())
+	}
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &empty{}, nil
+}
+
+func (s *Server) read(req *readRequest, stream grpc.ServerStream) error {
+	s.logger.Debug("[remotefs] read", "path", req.path, "offset", req.offset, "length", req.length)
+
+	if req.offset < 0 {
+		return status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return err
+	}
+
+	f, err := s.fsys.OpenFile(name, fs.O_RDONLY, 0)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			s.logger.Error("[remotefs] read", "path", req.path, "error", err)
+		}
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return toStatus(err)
+	}
+
+	if fi.IsDir() {
+		return toStatus(&gofs.PathError{Op: "read", Path: req.path, Err: fs.ErrIsDir})
+	}
+
+	buf := make([]byte, chunkSize)
+	off, remaining := req.offset, req.length
+	for remaining != 0 {
+		if err := stream.Context().Err(); err != nil {
+			return toStatus(err)
+		}
+
+		b := buf
+		if remaining > 0 && remaining < int64(len(b)) {
+			b = b[:remaining]
+		}
+
+		n, err := f.ReadAt(b, off)
+		if n > 0 {
+			if err := stream.SendMsg(&readResponse{data: b[:n]}); err != nil {
+				return err
+			}
+
+			off += int64(n)
+			if remaining > 0 {
+				remaining -= int64(n)
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return toStatus(err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) readDir(_ context.Context, req *pathRequest) (message, error) {
+	s.logger.Debug("[remotefs] readDir", "path", req.path)
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.fsys.ReadDir(name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &readDirResponse{entries: make([]*fileInfo, 0, len(entries))}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return nil, toStatus(err)
+		}
+		resp.entries = append(resp.entries, newFileInfo(fi))
+	}
+	return resp, nil
+}
+
+func (s *Server) remove(_ context.Context, req *removeRequest) (message, error) {
+	s.logger.Debug("[remotefs] remove", "path", req.path, "all", req.all)
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.all {
+		err = s.fsys.RemoveAll(name)
+	} else {
+		err = s.fsys.Remove(name)
+	}
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &empty{}, nil
+}
+
+func (s *Server) rename(_ context.Context, req *renameRequest) (message, error) {
+	s.logger.Debug("[remotefs] rename", "oldPath", req.oldPath, "newPath", req.newPath)
+
+	oldName, err := s.path(req.oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	newName, err := s.path(req.newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fsys.Rename(oldName, newName); err != nil {
+		return nil, toStatus(err)
+	}
+	return &empty{}, nil
+}
+
+func (s *Server) stat(_ context.Context, req *pathRequest) (message, error) {
+	s.logger.Debug("[remotefs] stat", "path", req.path)
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return newFileInfo(fi), nil
+}
+
+func (s *Server) write(stream grpc.ServerStream) error {
+	req := &writeRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	s.logger.Debug("[remotefs] write", "path", req.path, "flags", int(req.flags))
+
+	name, err := s.path(req.path)
+	if err != nil {
+		return err
+	}
+
+	flag := openFlag(req.flags)
+	f, err := s.fsys.OpenFile(name, flag, gofs.FileMode(req.perm).Perm())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	var (
+		off     int64
+		written int64
+	)
+	write := func(req *writeRequest) error {
+		var (
+			n   int
+			err error
+		)
+		if w, ok := f.(io.WriterAt); ok && flag&fs.O_APPEND == 0 {
+			n, err = w.WriteAt(req.data, req.offset)
+		} else {
+			if req.offset != off && flag&fs.O_APPEND == 0 {
+				if _, err := f.Seek(req.offset, io.SeekStart); err != nil {
+					return err
+				}
+			}
+			n, err = f.Write(req.data)
+		}
+		off = req.offset + int64(n)
+		written += int64(n)
+		return err
+	}
+
+	err = stream.SendMsg(&writeResponse{})
+	for err == nil {
+		if len(req.data) > 0 {
+			if err = write(req); err != nil {
+				break
+			}
+		}
+
+		req = &writeRequest{}
+		err = stream.RecvMsg(req)
+	}
+
+	if cerr := f.Close(); cerr != nil && (err == nil || errors.Is(err, io.EOF)) {
+		err = cerr
+	}
+
+	if !errors.Is(err, io.EOF) {
+		return toStatus(err)
+	}
+	return stream.SendMsg(&writeResponse{written: written})
+}
+
+// WithServerLogger sets the Logger used by a Server to log the requests that it serves. By default, nothing is
+// logged.
+func WithServerLogger(logger fs.Logger) func(*Server) {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// openFlag converts open flags defined by OpenFlag in remotefs.proto to flags for fs.FS.OpenFile.
+func openFlag(flags uint32) int {
+	var flag int
+	switch {
+	case flags&flagRead != 0 && flags&flagWrite != 0:
+		flag = fs.O_RDWR
+	case flags&flagWrite != 0:
+		flag = fs.O_WRONLY
+	default:
+		flag = fs.O_RDONLY
+	}
+
+	if flags&flagAppend != 0 {
+		flag |= fs.O_APPEND
+	}
+
+	if flags&flagCreate != 0 {
+		flag |= fs.O_CREATE
+	}
+
+	if flags&flagTrunc != 0 {
+		flag |= fs.O_TRUNC
+	}
+
+	if flags&flagExcl != 0 {
+		flag |= os.O_EXCL
+	}
+	return flag
+}
@@ -0,0 +1,314 @@
+package fs
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+)
+
+// defaultCheckpointInterval is the default number of bytes copied by CopyFileResumable between checkpoints.
+const defaultCheckpointInterval = 8 << 20
+
+// Suffixes of the sidecar files used by CopyFileResumable.
+const (
+	checkpointSuffix = ".checkpoint"
+	partialSuffix    = ".partial"
+)
+
+// copyCheckpoint records the progress of a copy performed by CopyFileResumable.
+type copyCheckpoint struct {
+	// Source is the path of the source file.
+	Source string `json:"source"`
+
+	// Size is the size of the source file when the copy was started.
+	Size int64 `json:"size"`
+
+	// ModTime is the modification time of the source file when the copy was started.
+	ModTime time.Time `json:"mtime"`
+
+	// Offset is the number of bytes of the source file that were copied to the partial file.
+	Offset int64 `json:"offset"`
+
+	// Hash is the state of the SHA-256 digest of the bytes that were copied, as produced by its MarshalBinary method.
+	Hash []byte `json:"hash"`
+}
+
+// CopyFileResumable copies the file srcPath in src to dstPath in dst in the same way as CopyFile, but records the
+// progress of the copy, so that a copy that was interrupted resumes from the last checkpoint when it is performed again
+// instead of restarting.
+//
+// The content is copied to the sidecar file dstPath+".partial", and a checkpoint holding the offset copied so far and
+// the state of the SHA-256 digest of the copied content is written to the sidecar file dstPath+".checkpoint" each time
+// the number of bytes set using WithCheckpointInterval have been copied. A copy is only resumed if the size and
+// modification time of the source have not changed since the copy was started. Once the content has been copied, the
+// digest is compared with the SHA-256 checksum of the source if it is provided by its Attribute, and the partial file
+// is renamed to dstPath.
+//
+// Sources that do not implement io.Seeker are read from the start when a copy is resumed, and the bytes that were
+// already copied are discarded. Content written to the partial file after the last checkpoint is discarded using the
+// Truncate method of the file, and the copy is restarted if the file does not implement it. The sidecar files are
+// removed once the copy is complete, or if the checksum does not match.
+func CopyFileResumable(dst FS, dstPath string, src gofs.FS, srcPath string, options ...CopyOption) error {
+	if dst == nil || src == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &copyOptions{checkpoint: defaultCheckpointInterval}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	fi, err := gofs.Stat(src, srcPath)
+	if err != nil {
+		return err
+	}
+
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: srcPath, Err: ErrNotFile})
+	}
+
+	if dfi, err := dst.Stat(dstPath); err == nil {
+		if dfi.IsDir() {
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath, Err: ErrIsDir})
+		}
+
+		switch opts.overwrite {
+		case OverwriteNever:
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath, Err: gofs.ErrExist})
+		case OverwriteSkip:
+			return nil
+		case OverwriteIfNewer:
+			if !fi.ModTime().After(dfi.ModTime()) {
+				return nil
+			}
+		}
+	} else if !errors.Is(err, gofs.ErrNotExist) {
+		return err
+	}
+
+	r := &resumableCopy{
+		checkpoint: dstPath + checkpointSuffix,
+		dst:        dst,
+		fi:         fi,
+		opts:       opts,
+		partial:    dstPath + partialSuffix,
+		src:        src,
+		srcPath:    srcPath,
+	}
+
+	digest, err := r.copy()
+	if err != nil {
+		return err
+	}
+
+	if e, ok := fi.(*Entry); ok {
+		if want, ok := e.Attributes().Checksum(ChecksumAlgorithm(crypto.SHA256)); ok && want != hex.EncodeToString(digest) {
+			r.discard()
+			return fmt.Errorf("fs: %w", &gofs.PathError{Op: "copy", Path: dstPath,
+				Err: fmt.Errorf("checksum does not match source: %w", ErrInvalid)})
+		}
+	}
+
+	if err := dst.Rename(r.partial, dstPath); err != nil {
+		return err
+	}
+	_ = dst.Remove(r.checkpoint)
+	return preserveAttributes(dst, dstPath, fi, opts)
+}
+
+// WithCheckpointInterval sets the number of bytes copied by CopyFileResumable between checkpoints. Smaller intervals
+// reduce the amount of content copied again when a copy is resumed, at the cost of writing checkpoints more often. The
+// default is 8 MiB.
+func WithCheckpointInterval(n int64) CopyOption {
+	return func(o *copyOptions) {
+		if n > 0 {
+			o.checkpoint = n
+		}
+	}
+}
+
+// resumableCopy holds the state of a copy performed by CopyFileResumable.
+type resumableCopy struct {
+	checkpoint string
+	dst        FS
+	fi         gofs.FileInfo
+	opts       *copyOptions
+	partial    string
+	src        gofs.FS
+	srcPath    string
+}
+
+// copy copies the content of the source to the partial file, starting from the last checkpoint, and returns the
+// SHA-256 digest of the content.
+func (r *resumableCopy) copy() ([]byte, error) {
+	h := sha256.New()
+	w, offset, err := r.open(h)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := r.src.Open(r.srcPath)
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := skip(f, offset); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	for offset < r.fi.Size() {
+		n, err := io.CopyN(io.MultiWriter(w, h), f, min(r.opts.checkpoint, r.fi.Size()-offset))
+		offset += n
+		if err == nil {
+			err = r.save(w, h, offset)
+		}
+
+		if err != nil {
+			_ = w.Close()
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if r.opts.bytesProgress != nil {
+			r.opts.bytesProgress(offset)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// discard removes the sidecar files of the copy.
+func (r *resumableCopy) discard() {
+	_ = r.dst.Remove(r.partial)
+	_ = r.dst.Remove(r.checkpoint)
+}
+
+// open opens the partial file for writing, and returns it with the offset to resume the copy from. If the copy can not
+// be resumed, the partial file is truncated and 0 is returned.
+func (r *resumableCopy) open(h hash.Hash) (File, int64, error) {
+	if offset := r.resume(h); offset > 0 {
+		w, err := r.dst.OpenFile(r.partial, O_WRONLY|O_APPEND, r.fi.Mode().Perm())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Content written after the last checkpoint is discarded, which requires truncating the partial file.
+		fi, err := w.Stat()
+		if err == nil && fi.Size() == offset {
+			return w, offset, nil
+		}
+
+		if t, ok := w.(interface{ Truncate(size int64) error }); ok && err == nil {
+			if err := t.Truncate(offset); err == nil {
+				return w, offset, nil
+			}
+		}
+		_ = w.Close()
+		h.Reset()
+	}
+
+	w, err := r.dst.OpenFile(r.partial, O_WRONLY|O_CREATE|O_TRUNC, r.fi.Mode().Perm())
+	if err != nil {
+		return nil, 0, err
+	}
+	return w, 0, nil
+}
+
+// resume restores the state of h from the checkpoint of the copy, and returns the offset to resume the copy from. If
+// there is no checkpoint, or the checkpoint does not match the source or the partial file, 0 is returned.
+func (r *resumableCopy) resume(h hash.Hash) int64 {
+	b, err := r.dst.ReadFile(r.checkpoint)
+	if err != nil {
+		return 0
+	}
+
+	var cp copyCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return 0
+	}
+
+	if cp.Source != r.srcPath || cp.Size != r.fi.Size() || !cp.ModTime.Equal(r.fi.ModTime()) || cp.Offset > cp.Size {
+		return 0
+	}
+
+	pfi, err := r.dst.Stat(r.partial)
+	if err != nil || pfi.Size() < cp.Offset {
+		return 0
+	}
+
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(cp.Hash); err != nil {
+		h.Reset()
+		return 0
+	}
+	return cp.Offset
+}
+
+// save writes a checkpoint for the content copied to w up to offset, with the state of h.
+func (r *resumableCopy) save(w File, h hash.Hash, offset int64) error {
+	// The content must be stored before the checkpoint refers to it, so it is synced if the file supports it.
+	if s, ok := w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(copyCheckpoint{
+		Source:  r.srcPath,
+		Size:    r.fi.Size(),
+		ModTime: r.fi.ModTime(),
+		Offset:  offset,
+		Hash:    state,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The checkpoint is replaced using a rename, so that an interrupted write never leaves an invalid checkpoint.
+	tmp := r.checkpoint + ".tmp"
+	if err := r.dst.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return r.dst.Rename(tmp, r.checkpoint)
+}
+
+// skip advances f past the first n bytes, seeking if f implements io.Seeker and discarding the bytes otherwise.
+func skip(f gofs.File, n int64) error {
+	if n == 0 {
+		return nil
+	}
+
+	if s, ok := f.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekStart)
+		return err
+	}
+
+	if _, err := io.CopyN(io.Discard, f, n); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
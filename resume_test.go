@@ -0,0 +1,132 @@
+package fs_test
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestCopyFileResumable(t *testing.T) {
+	src, err := memfs.New(memfs.WithChecksum(crypto.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 10)
+	if err := src.WriteFile("doc/fox.txt", content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky := &flakyFS{FS: src, limit: 120}
+	err = fs.CopyFileResumable(dst, "fox.txt", flaky, "doc/fox.txt", fs.WithCheckpointInterval(50))
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, int64(120), flaky.read)
+
+	_, err = dst.Stat("fox.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	var progress []int64
+	flaky.limit, flaky.read = -1, 0
+	assert.NoError(t, fs.CopyFileResumable(dst, "fox.txt", flaky, "doc/fox.txt",
+		fs.WithCheckpointInterval(50),
+		fs.WithProgress(func(n int64) { progress = append(progress, n) })))
+	assert.Equal(t, int64(100), flaky.read)
+	assert.Equal(t, []int64{150, 200}, progress)
+
+	b, err := dst.ReadFile("fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, content, b)
+
+	for _, name := range []string{"fox.txt.partial", "fox.txt.checkpoint"} {
+		_, err := dst.Stat(name)
+		assert.ErrorIs(t, err, gofs.ErrNotExist, name)
+	}
+
+	assert.ErrorIs(t, fs.CopyFileResumable(dst, "fox.txt", src, "doc/fox.txt"), gofs.ErrExist)
+	assert.ErrorIs(t, fs.CopyFileResumable(dst, "dir", src, "doc"), fs.ErrNotFile)
+}
+
+func TestCopyFileResumable_SourceChanged(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.WriteFile("fox.txt", bytes.Repeat([]byte("the quick brown fox "), 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky := &flakyFS{FS: src, limit: 120}
+	assert.ErrorIs(t, fs.CopyFileResumable(dst, "fox.txt", flaky, "fox.txt", fs.WithCheckpointInterval(50)), errFlaky)
+
+	content := bytes.Repeat([]byte("the lazy dog "), 10)
+	if err := src.WriteFile("fox.txt", content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky.limit, flaky.read = -1, 0
+	assert.NoError(t, fs.CopyFileResumable(dst, "fox.txt", flaky, "fox.txt", fs.WithCheckpointInterval(50)))
+	assert.Equal(t, int64(len(content)), flaky.read)
+
+	b, err := dst.ReadFile("fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, content, b)
+}
+
+var errFlaky = errors.New("flaky read")
+
+// flakyFS is a gofs.FS whose files fail to read once limit bytes have been read, unless limit is negative.
+type flakyFS struct {
+	gofs.FS
+	limit int64
+	read  int64
+}
+
+func (f *flakyFS) Open(name string) (gofs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file, fsys: f}, nil
+}
+
+type flakyFile struct {
+	gofs.File
+	fsys *flakyFS
+}
+
+func (f *flakyFile) Read(p []byte) (int, error) {
+	if f.fsys.limit >= 0 {
+		if f.fsys.read >= f.fsys.limit {
+			return 0, errFlaky
+		}
+		p = p[:min(int64(len(p)), f.fsys.limit-f.fsys.read)]
+	}
+
+	n, err := f.File.Read(p)
+	f.fsys.read += int64(n)
+	return n, err
+}
+
+func (f *flakyFile) Seek(offset int64, whence int) (int64, error) {
+	return f.File.(io.Seeker).Seek(offset, whence)
+}
@@ -0,0 +1,183 @@
+package retention
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// RetentionTestSuite ...
+type RetentionTestSuite struct {
+	suite.Suite
+	fsys fs.FS
+	now  time.Time
+}
+
+func NewRetentionTestSuite() *RetentionTestSuite {
+	return &RetentionTestSuite{}
+}
+
+func (t *RetentionTestSuite) SetupTest() {
+	fsys, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.now = time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{
+		"logs/app-1.log",
+		"logs/app-2.log",
+		"logs/app-3.log",
+		"logs/app-4.log",
+		"logs/archive/app-0.log.gz",
+		"logs/readme.txt",
+	} {
+		if err := fsys.WriteFile(name, make([]byte, 10*(i+1)), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+
+		// Files are one day apart, with the last file being the newest.
+		mtime := t.now.Add(-time.Duration(6-i) * 24 * time.Hour)
+		if err := fsys.Chtimes(name, mtime, mtime); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+	t.fsys = fsys
+}
+
+func TestRetentionTestSuite(t *testing.T) {
+	suite.Run(t, NewRetentionTestSuite())
+}
+
+func (t *RetentionTestSuite) TestRun() {
+	p, err := New(t.fsys, "logs",
+		WithRule(Rule{Pattern: "*.log", MaxCount: 2}),
+		WithRule(Rule{Pattern: "archive/*", MaxAge: 24 * time.Hour}),
+		WithRule(Rule{MaxBytes: 100}),
+		WithDryRun(true))
+	assert.NoError(t.T(), err)
+	p.now = func() time.Time { return t.now }
+
+	report, err := p.Run()
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), report.DryRun)
+	assert.Equal(t.T(), 6, report.Scanned)
+
+	// Files removed by a rule are not retained by later rules, so app-3.log is removed by the limit on the total size
+	// of the retained files, but the removed archive does not count towards it.
+	assert.Equal(t.T(), []string{"logs/app-1.log", "logs/app-2.log", "logs/app-3.log", "logs/archive/app-0.log.gz"},
+		paths(report))
+	assert.Equal(t.T(), ReasonCount, report.Removed[0].Reason)
+	assert.Equal(t.T(), ReasonBytes, report.Removed[2].Reason)
+	assert.Equal(t.T(), 2, report.Removed[2].Rule)
+	assert.Equal(t.T(), ReasonAge, report.Removed[3].Reason)
+	assert.Equal(t.T(), 1, report.Removed[3].Rule)
+	assert.Equal(t.T(), int64(110), report.Bytes)
+
+	_, err = t.fsys.Stat("logs/app-1.log")
+	assert.NoError(t.T(), err)
+
+	p.dryRun = false
+	report, err = p.Run()
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), report.Removed, 4)
+
+	for _, r := range report.Removed {
+		_, err := t.fsys.Stat(r.Path)
+		assert.ErrorIs(t.T(), err, gofs.ErrNotExist, r.Path)
+	}
+
+	report, err = p.Run()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 2, report.Scanned)
+	assert.Empty(t.T(), report.Removed)
+}
+
+func (t *RetentionTestSuite) TestMaxBytes() {
+	p, err := New(t.fsys, "logs", WithRule(Rule{Pattern: "app-*.log", MaxBytes: 80}))
+	assert.NoError(t.T(), err)
+
+	report, err := p.Run()
+	assert.NoError(t.T(), err)
+
+	// The newest files are retained until app-2.log exceeds the limit, and the older app-1.log is removed even though
+	// it would fit.
+	assert.Equal(t.T(), []string{"logs/app-1.log", "logs/app-2.log"}, paths(report))
+	for _, r := range report.Removed {
+		assert.Equal(t.T(), ReasonBytes, r.Reason)
+	}
+}
+
+func (t *RetentionTestSuite) TestSchedule() {
+	p, err := New(t.fsys, ".", WithRule(Rule{Pattern: "*.log", MaxCount: 1}))
+	assert.NoError(t.T(), err)
+
+	reports := make(chan *Report, 16)
+	stop := p.Schedule(10*time.Millisecond, func(r *Report, err error) {
+		assert.NoError(t.T(), err)
+		reports <- r
+	})
+
+	select {
+	case r := <-reports:
+		assert.Len(t.T(), r.Removed, 3)
+	case <-time.After(5 * time.Second):
+		t.T().Fatal("policy was not run")
+	}
+	stop()
+	stop()
+
+	entries, err := t.fsys.ReadDir("logs")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 3)
+}
+
+func (t *RetentionTestSuite) TestNew() {
+	_, err := New(nil, ".")
+	assert.Error(t.T(), err)
+
+	_, err = New(t.fsys, "../logs")
+	assert.ErrorIs(t.T(), err, fs.ErrInvalid)
+
+	_, err = New(t.fsys, ".", WithRule(Rule{Pattern: "["}))
+	assert.Error(t.T(), err)
+
+	_, err = New(t.fsys, ".", WithRule(Rule{MaxCount: -1}))
+	assert.ErrorIs(t.T(), err, fs.ErrInvalid)
+}
+
+func (t *RetentionTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	p, err := New(t.fsys, "logs", WithRule(Rule{MaxCount: 2}), WithDryRun(true), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = p.Run()
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[retention] run" root=logs dry_run=true scanned=6 removed=4`)
+
+	p, err = New(t.fsys, "logs")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), p.logger)
+}
+
+func paths(r *Report) []string {
+	var p []string
+	for _, removal := range r.Removed {
+		p = append(p, removal.Path)
+	}
+	return p
+}
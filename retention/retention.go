@@ -0,0 +1,310 @@
+// Package retention removes files from a subtree of a file system according to declared rules, such as keeping only
+// the newest log files, or removing build artifacts once they reach a certain age or total size.
+//
+// Rules are run on demand using Run, or periodically using Schedule:
+//
+//	p, err := retention.New(fsys, "logs",
+//		retention.WithRule(retention.Rule{Pattern: "*.log", MaxAge: 30 * 24 * time.Hour, MaxCount: 100}))
+//	report, err := p.Run()
+//
+// In dry-run mode, the files that would be removed are reported without removing them.
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// Enumeration of the reasons for which a file is removed.
+const (
+	ReasonAge   = "age"
+	ReasonBytes = "bytes"
+	ReasonCount = "count"
+)
+
+// Rule declares the files of the subtree that are retained. Each limit that is zero is not applied.
+type Rule struct {
+	// Pattern selects the files the rule applies to using the syntax of path.Match, and is matched against both the
+	// name and the path of each regular file relative to the root of the Policy. If the pattern is empty, the rule
+	// applies to every regular file.
+	Pattern string
+
+	// MaxAge is the maximum age of a file, based on its modification time.
+	MaxAge time.Duration
+
+	// MaxCount is the maximum number of files that are retained. The newest files are retained.
+	MaxCount int
+
+	// MaxBytes is the maximum total size of the files that are retained. The newest files are retained.
+	MaxBytes int64
+}
+
+// Removal describes a file that was removed by a Policy, or would be removed in dry-run mode.
+type Removal struct {
+	// Path is the path of the file in the file system.
+	Path string
+
+	// Rule is the index of the rule that removed the file.
+	Rule int
+
+	// Reason is the limit of the rule that removed the file, which is one of ReasonAge, ReasonCount, or ReasonBytes.
+	Reason string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// ModTime is the modification time of the file.
+	ModTime time.Time
+}
+
+// Report describes the outcome of running a Policy.
+type Report struct {
+	// Time is the time the policy was run, which is used to determine the age of files.
+	Time time.Time
+
+	// DryRun reports whether the policy was run in dry-run mode, in which case no file was removed.
+	DryRun bool
+
+	// Scanned is the number of regular files in the subtree.
+	Scanned int
+
+	// Removed lists the files that were removed, sorted by path.
+	Removed []Removal
+
+	// Bytes is the total size of the files that were removed.
+	Bytes int64
+}
+
+// Policy applies retention rules to the subtree of a file system.
+type Policy struct {
+	dryRun bool
+	fsys   fs.FS
+	logger fs.Logger
+	mutex  sync.Mutex
+	now    func() time.Time
+	root   string
+	rules  []Rule
+}
+
+// New creates a new Policy for the subtree of fsys rooted at root.
+func New(fsys fs.FS, root string, options ...func(*Policy)) (*Policy, error) {
+	if fsys == nil {
+		return nil, errors.New("retention: file system is required")
+	}
+
+	p := &Policy{fsys: fsys, logger: fs.NopLogger(), now: time.Now, root: gopath.Clean(root)}
+	for _, opt := range options {
+		opt(p)
+	}
+
+	if !gofs.ValidPath(p.root) {
+		return nil, fmt.Errorf("retention: root %q is invalid: %w", root, fs.ErrInvalid)
+	}
+
+	for i, r := range p.rules {
+		if _, err := gopath.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("retention: pattern %q of rule %d is invalid: %w", r.Pattern, i, err)
+		}
+
+		if r.MaxAge < 0 || r.MaxCount < 0 || r.MaxBytes < 0 {
+			return nil, fmt.Errorf("retention: limits of rule %d must not be negative: %w", i, fs.ErrInvalid)
+		}
+	}
+
+	p.logger.Debug("[retention] new",
+		"provider", fsys.Provider(),
+		"root", p.root,
+		"rules", len(p.rules),
+		"dry_run", p.dryRun)
+	return p, nil
+}
+
+// Run applies the rules to the subtree, and returns a report of the files that were removed.
+//
+// Rules are applied in the order they were added. Each rule considers the files it applies to from newest to oldest,
+// and removes a file if it is older than MaxAge, if MaxCount newer files are retained, or if retaining it would exceed
+// MaxBytes, in which case every older file is also removed. Files removed by a rule are not considered by later rules.
+// Directories are never removed. If a file can not be removed, the remaining files are still processed, and the errors
+// are returned with the report of the files that were removed.
+func (p *Policy) Run() (*Report, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	report := &Report{Time: p.now(), DryRun: p.dryRun}
+
+	var files []*fs.Entry
+	err := fs.Walk(p.fsys, p.root, func(path string, e *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !e.Mode().IsRegular() {
+			return nil
+		}
+
+		// The entry is copied with the path it was walked at, which may differ from the path held by the provider.
+		e, err = fs.EntryFromFileInfo(path, e)
+		if err != nil {
+			return err
+		}
+		files = append(files, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Scanned = len(files)
+
+	// Files are considered from newest to oldest, so that the newest files are retained.
+	slices.SortStableFunc(files, func(a *fs.Entry, b *fs.Entry) int {
+		return b.ModTime().Compare(a.ModTime())
+	})
+
+	removals := make(map[string]Removal)
+	for i, r := range p.rules {
+		var (
+			bytes    int64
+			count    int
+			exceeded bool
+		)
+		for _, e := range files {
+			if _, ok := removals[e.Path()]; ok || !p.matches(r, e.Path()) {
+				continue
+			}
+
+			var reason string
+			switch {
+			case r.MaxAge > 0 && report.Time.Sub(e.ModTime()) > r.MaxAge:
+				reason = ReasonAge
+			case r.MaxCount > 0 && count >= r.MaxCount:
+				reason = ReasonCount
+			case r.MaxBytes > 0 && (exceeded || bytes+e.Size() > r.MaxBytes):
+				exceeded = true
+				reason = ReasonBytes
+			default:
+				count++
+				bytes += e.Size()
+				continue
+			}
+
+			removals[e.Path()] = Removal{Path: e.Path(), Rule: i, Reason: reason, Size: e.Size(), ModTime: e.ModTime()}
+		}
+	}
+
+	var errs []error
+	for _, r := range removals {
+		if !p.dryRun {
+			if err := p.fsys.Remove(r.Path); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, r)
+		report.Bytes += r.Size
+	}
+
+	slices.SortFunc(report.Removed, func(a Removal, b Removal) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	p.logger.Debug("[retention] run",
+		"root", p.root,
+		"dry_run", p.dryRun,
+		"scanned", report.Scanned,
+		"removed", len(report.Removed),
+		"bytes", report.Bytes)
+	return report, errors.Join(errs...)
+}
+
+// Schedule runs the policy every interval until the returned stop function is called, calling fn with the report and
+// error returned by each run if fn is not nil. Runs do not overlap: if a run takes longer than the interval, the next
+// run starts once it completes. The stop function waits for a run in progress to complete.
+func (p *Policy) Schedule(interval time.Duration, fn func(*Report, error)) (stop func()) {
+	var (
+		done = make(chan struct{})
+		once sync.Once
+		wg   sync.WaitGroup
+	)
+
+	ticker := time.NewTicker(interval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				report, err := p.Run()
+				if err != nil {
+					p.logger.Warn("[retention] run", "root", p.root, "error", err)
+				}
+
+				if fn != nil {
+					fn(report, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+		wg.Wait()
+	}
+}
+
+// matches reports whether rule r applies to the file at path.
+func (p *Policy) matches(r Rule, path string) bool {
+	if r.Pattern == "" {
+		return true
+	}
+
+	rel := path
+	if p.root != "." {
+		rel = strings.TrimPrefix(path, p.root+"/")
+	}
+
+	if ok, _ := gopath.Match(r.Pattern, gopath.Base(rel)); ok {
+		return true
+	}
+	ok, _ := gopath.Match(r.Pattern, rel)
+	return ok
+}
+
+// WithDryRun sets whether the policy runs in dry-run mode, in which the files that would be removed are reported
+// without removing them.
+func WithDryRun(dryRun bool) func(*Policy) {
+	return func(p *Policy) {
+		p.dryRun = dryRun
+	}
+}
+
+// WithLogger sets the Logger used by the policy to log its runs and the errors of scheduled runs. By default, nothing
+// is logged.
+func WithLogger(logger fs.Logger) func(*Policy) {
+	return func(p *Policy) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
+
+// WithRule adds a rule to the policy. Rules are identified in reports by the order in which they were added.
+func WithRule(r Rule) func(*Policy) {
+	return func(p *Policy) {
+		p.rules = append(p.rules, r)
+	}
+}
@@ -0,0 +1,56 @@
+package retryfs
+
+import (
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file retries the reads and writes performed on a File opened through a RetryFS.
+type file struct {
+	fs.File
+	rfs *RetryFS
+}
+
+// Read retries reads that fail with a transient error without reading any bytes, since the offset of the File is not
+// changed by such a read.
+func (f *file) Read(b []byte) (int, error) {
+	for attempt := 1; ; attempt++ {
+		n, err := f.File.Read(b)
+		if n > 0 || !f.rfs.retryable(attempt, err) {
+			return n, err
+		}
+		f.rfs.wait("read", attempt, err)
+	}
+}
+
+// ReadAt retries reading the bytes that were not read by an attempt that failed with a transient error.
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	var n int
+	for attempt := 1; ; attempt++ {
+		c, err := f.File.ReadAt(b[n:], off+int64(n))
+		n += c
+		if !f.rfs.retryable(attempt, err) {
+			return n, err
+		}
+		f.rfs.wait("readAt", attempt, err)
+	}
+}
+
+func (f *file) Stat() (gofs.FileInfo, error) {
+	return retry(f.rfs, "stat", f.File.Stat)
+}
+
+// Write retries writes that fail with a transient error without writing any bytes, since the offset of the File is not
+// changed by such a write.
+func (f *file) Write(b []byte) (int, error) {
+	for attempt := 1; ; attempt++ {
+		n, err := f.File.Write(b)
+		if n > 0 || !f.rfs.retryable(attempt, err) {
+			return n, err
+		}
+		f.rfs.wait("write", attempt, err)
+	}
+}
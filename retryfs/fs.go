@@ -0,0 +1,357 @@
+package retryfs
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+const (
+	// DefaultAttempts is the default number of times an operation is attempted, including the first attempt.
+	DefaultAttempts = 3
+
+	// DefaultBackoff is the default delay before the first retry of an operation.
+	DefaultBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff is the default maximum delay between retries of an operation.
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+var _ fs.FS = (*RetryFS)(nil)
+
+// RetryFS retrying provider that implements fs.FS.
+//
+// A RetryFS retries the operations performed on a backing file system that fail with a transient error, such as a
+// connection that was reset or an operation that timed out, so that the failures of flaky network file systems are not
+// returned to the application unless they persist. An operation is attempted up to the number of times set using
+// WithAttempts. The delay before each retry doubles from the backoff set using WithBackoff up to its maximum, and is
+// randomized between half the delay and the full delay, so that clients that fail at the same time do not retry in
+// lockstep.
+//
+// Errors are classified as transient by the classifier set for the provider that returned the error using
+// WithClassifier, which is the provider of the *fs.OpError in the error chain or, if there is none, the provider of the
+// backing file system. Errors of other providers are classified using IsTransient, or the function set using
+// WithTransient.
+//
+// Every operation is retried, including operations that are not idempotent. An operation such as Mkdir, Remove, or
+// Rename that took effect but reported a transient error may therefore fail with an error wrapping gofs.ErrExist or
+// gofs.ErrNotExist when it is retried. Reads and writes on a File are retried only if they did not transfer any bytes,
+// except for ReadAt, which is retried for the bytes that were not read.
+type RetryFS struct {
+	attempts    int
+	backing     fs.FS
+	backoff     time.Duration
+	classifiers map[string]func(error) bool
+	logger      fs.Logger
+	maxBackoff  time.Duration
+	random      func(int64) int64
+	sleep       func(time.Duration)
+	transient   func(error) bool
+}
+
+// New creates a new RetryFS that retries the operations performed on backing that fail with a transient error.
+func New(backing fs.FS, options ...func(*RetryFS)) (*RetryFS, error) {
+	if backing == nil {
+		return nil, errors.New("retryfs: backing file system is required")
+	}
+
+	r := &RetryFS{
+		attempts:    DefaultAttempts,
+		backing:     backing,
+		backoff:     DefaultBackoff,
+		classifiers: make(map[string]func(error) bool),
+		logger:      fs.NopLogger(),
+		maxBackoff:  DefaultMaxBackoff,
+		random:      rand.Int64N,
+		sleep:       time.Sleep,
+		transient:   IsTransient,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	r.maxBackoff = max(r.maxBackoff, r.backoff)
+
+	r.logger.Debug("[retryfs] new",
+		"provider", backing.Provider(),
+		"attempts", r.attempts,
+		"backoff", r.backoff)
+	return r, nil
+}
+
+// Close closes the backing file system.
+func (r *RetryFS) Close() error {
+	return r.backing.Close()
+}
+
+// Create ...
+func (r *RetryFS) Create(name string) (fs.File, error) {
+	f, err := retry(r, "create", func() (fs.File, error) { return r.backing.Create(name) })
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, rfs: r}, nil
+}
+
+// Glob ...
+func (r *RetryFS) Glob(pattern string) ([]string, error) {
+	return retry(r, "glob", func() ([]string, error) { return r.backing.Glob(pattern) })
+}
+
+// Mkdir ...
+func (r *RetryFS) Mkdir(name string, perm gofs.FileMode) error {
+	return r.do("mkdir", func() error { return r.backing.Mkdir(name, perm) })
+}
+
+// MkdirAll ...
+func (r *RetryFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return r.do("mkdirAll", func() error { return r.backing.MkdirAll(path, perm) })
+}
+
+// Open ...
+func (r *RetryFS) Open(name string) (gofs.File, error) {
+	f, err := retry(r, "open", func() (gofs.File, error) { return r.backing.Open(name) })
+	if err != nil {
+		return nil, err
+	}
+
+	if rf, ok := f.(fs.File); ok {
+		return &file{File: rf, rfs: r}, nil
+	}
+	return f, nil
+}
+
+// OpenFile ...
+func (r *RetryFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	f, err := retry(r, "open", func() (fs.File, error) { return r.backing.OpenFile(name, flag, perm) })
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, rfs: r}, nil
+}
+
+// PathSeparator ...
+func (r *RetryFS) PathSeparator() string {
+	return r.backing.PathSeparator()
+}
+
+// Provider ...
+func (r *RetryFS) Provider() string {
+	return r.backing.Provider()
+}
+
+// ReadDir ...
+func (r *RetryFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return retry(r, "readDir", func() ([]gofs.DirEntry, error) { return r.backing.ReadDir(name) })
+}
+
+// ReadFile ...
+func (r *RetryFS) ReadFile(name string) ([]byte, error) {
+	return retry(r, "readFile", func() ([]byte, error) { return r.backing.ReadFile(name) })
+}
+
+// Remove ...
+func (r *RetryFS) Remove(name string) error {
+	return r.do("remove", func() error { return r.backing.Remove(name) })
+}
+
+// RemoveAll ...
+func (r *RetryFS) RemoveAll(path string) error {
+	return r.do("removeAll", func() error { return r.backing.RemoveAll(path) })
+}
+
+// Rename ...
+func (r *RetryFS) Rename(oldpath string, newpath string) error {
+	return r.do("rename", func() error { return r.backing.Rename(oldpath, newpath) })
+}
+
+// Root ...
+func (r *RetryFS) Root() (string, error) {
+	return r.backing.Root()
+}
+
+// Stat ...
+func (r *RetryFS) Stat(name string) (gofs.FileInfo, error) {
+	return retry(r, "stat", func() (gofs.FileInfo, error) { return r.backing.Stat(name) })
+}
+
+// Sub returns the sub-tree for dir from the backing file system. If the sub-tree implements fs.FS, its operations are
+// retried in the same way as those of the RetryFS.
+func (r *RetryFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := retry(r, "sub", func() (gofs.FS, error) { return r.backing.Sub(dir) })
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		s := *r
+		s.backing = fsys
+		return &s, nil
+	}
+	return sub, nil
+}
+
+// WriteFile ...
+func (r *RetryFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	return r.do("writeFile", func() error { return r.backing.WriteFile(name, data, perm) })
+}
+
+// delay returns the delay before the retry that follows the given number of failed attempts.
+func (r *RetryFS) delay(attempt int) time.Duration {
+	d := r.backoff
+	for i := 1; i < attempt && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, r.maxBackoff)
+
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(r.random(int64(d/2)+1))
+}
+
+// do performs op, retrying it while it fails with a transient error.
+func (r *RetryFS) do(name string, op func() error) error {
+	_, err := retry(r, name, func() (struct{}, error) { return struct{}{}, op() })
+	return err
+}
+
+// isTransient reports whether err is transient using the classifier for the provider that returned it.
+func (r *RetryFS) isTransient(err error) bool {
+	provider := r.backing.Provider()
+
+	var oe *fs.OpError
+	if errors.As(err, &oe) && oe.Provider != "" {
+		provider = oe.Provider
+	}
+
+	if transient, ok := r.classifiers[provider]; ok {
+		return transient(err)
+	}
+	return r.transient(err)
+}
+
+// retryable reports whether an operation that failed with err after the given number of attempts is retried.
+func (r *RetryFS) retryable(attempt int, err error) bool {
+	return err != nil && attempt < r.attempts && r.isTransient(err)
+}
+
+// wait blocks for the delay before retrying the operation named name, which failed with err after the given number of
+// attempts.
+func (r *RetryFS) wait(name string, attempt int, err error) {
+	d := r.delay(attempt)
+	r.logger.Debug("[retryfs] retrying",
+		"op", name,
+		"attempt", attempt,
+		"delay", d,
+		"error", err)
+	r.sleep(d)
+}
+
+// retry performs the operation named name using op until it succeeds, fails with an error that is not transient, or
+// has been attempted the maximum number of times, and returns the result of the last attempt.
+func retry[T any](r *RetryFS, name string, op func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := op()
+		if !r.retryable(attempt, err) {
+			return result, err
+		}
+		r.wait(name, attempt, err)
+	}
+}
+
+// IsTransient reports whether err is a transient error that may succeed if the operation is retried, which is an error
+// caused by a timeout, an interrupted or busy system call, or a network connection that was refused, reset, or is
+// unreachable. Errors caused by the cancellation or deadline of a context are not transient, since the caller no longer
+// waits for the result.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	for _, errno := range []syscall.Errno{
+		syscall.EAGAIN,
+		syscall.EBUSY,
+		syscall.ECONNABORTED,
+		syscall.ECONNREFUSED,
+		syscall.ECONNRESET,
+		syscall.EHOSTUNREACH,
+		syscall.EINTR,
+		syscall.ENETDOWN,
+		syscall.ENETRESET,
+		syscall.ENETUNREACH,
+		syscall.EPIPE,
+		syscall.ESTALE,
+		syscall.ETIMEDOUT,
+	} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAttempts sets the number of times an operation is attempted, including the first attempt. Operations are not
+// retried if attempts is 1.
+func WithAttempts(attempts int) func(*RetryFS) {
+	return func(r *RetryFS) {
+		if attempts > 0 {
+			r.attempts = attempts
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry of an operation, and the maximum delay between retries. The maximum
+// is never less than the initial delay.
+func WithBackoff(initial time.Duration, maximum time.Duration) func(*RetryFS) {
+	return func(r *RetryFS) {
+		if initial >= 0 {
+			r.backoff = initial
+		}
+		r.maxBackoff = maximum
+	}
+}
+
+// WithClassifier sets the function used to determine whether an error returned by the named provider is transient, for
+// providers whose transient errors are not recognized by IsTransient, such as the status errors of remote providers.
+func WithClassifier(provider string, transient func(error) bool) func(*RetryFS) {
+	return func(r *RetryFS) {
+		if transient != nil {
+			r.classifiers[provider] = transient
+		}
+	}
+}
+
+// WithLogger sets the Logger used by a RetryFS to log the operations it retries. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*RetryFS) {
+	return func(r *RetryFS) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// WithTransient sets the function used to determine whether an error is transient for providers without a classifier
+// set using WithClassifier. The default is IsTransient.
+func WithTransient(transient func(error) bool) func(*RetryFS) {
+	return func(r *RetryFS) {
+		if transient != nil {
+			r.transient = transient
+		}
+	}
+}
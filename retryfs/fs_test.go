@@ -0,0 +1,207 @@
+package retryfs
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// flakyFS fails the next failures operations with err.
+type flakyFS struct {
+	fs.FS
+	calls    int
+	err      error
+	failures int
+}
+
+func (f *flakyFS) fail(op string, name string) error {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return fs.NewOpError(f.Provider(), op, name, f.err)
+	}
+	return nil
+}
+
+func (f *flakyFS) Mkdir(name string, perm gofs.FileMode) error {
+	if err := f.fail("mkdir", name); err != nil {
+		return err
+	}
+	return f.FS.Mkdir(name, perm)
+}
+
+func (f *flakyFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	file, err := f.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file, fsys: f}, nil
+}
+
+func (f *flakyFS) ReadFile(name string) ([]byte, error) {
+	if err := f.fail("readFile", name); err != nil {
+		return nil, err
+	}
+	return f.FS.ReadFile(name)
+}
+
+// flakyFile reads at most 4 bytes using ReadAt, and fails reads that are incomplete as its flakyFS fails operations.
+type flakyFile struct {
+	fs.File
+	fsys *flakyFS
+}
+
+func (f *flakyFile) ReadAt(b []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(b[:min(len(b), 4)], off)
+	if err == nil && n < len(b) {
+		return n, f.fsys.fail("readAt", "")
+	}
+	return n, err
+}
+
+// RetryFSTestSuite ...
+type RetryFSTestSuite struct {
+	suite.Suite
+	backing *flakyFS
+	slept   []time.Duration
+}
+
+func NewRetryFSTestSuite() *RetryFSTestSuite {
+	return &RetryFSTestSuite{}
+}
+
+func (t *RetryFSTestSuite) SetupTest() {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = &flakyFS{FS: mfs, err: syscall.ECONNRESET}
+	t.slept = nil
+}
+
+func TestRetryFSTestSuite(t *testing.T) {
+	suite.Run(t, NewRetryFSTestSuite())
+}
+
+// retrying returns a RetryFS that records the delays between retries instead of sleeping, and that always uses the
+// maximum delay.
+func (t *RetryFSTestSuite) retrying(options ...func(*RetryFS)) *RetryFS {
+	options = append(options, func(r *RetryFS) {
+		r.random = func(n int64) int64 { return n - 1 }
+		r.sleep = func(d time.Duration) { t.slept = append(t.slept, d) }
+	})
+
+	r, err := New(t.backing, options...)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return r
+}
+
+func (t *RetryFSTestSuite) TestFS() {
+	assert.NoError(t.T(), fstest.TestFS(t.retrying(), "doc/fox.txt"))
+}
+
+func (t *RetryFSTestSuite) TestRetry() {
+	r := t.retrying(WithAttempts(4), WithBackoff(100*time.Millisecond, 300*time.Millisecond))
+
+	t.backing.failures = 3
+	b, err := r.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+	assert.Equal(t.T(), 4, t.backing.calls)
+	assert.Equal(t.T(), []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, t.slept)
+
+	// The error of the last attempt is returned once every attempt failed.
+	t.backing.calls = 0
+	t.backing.failures = 5
+	_, err = r.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, syscall.ECONNRESET)
+	assert.Equal(t.T(), 4, t.backing.calls)
+
+	// Errors that are not transient are returned without retrying.
+	t.backing.calls = 0
+	t.backing.failures = 0
+	_, err = r.ReadFile("doc/missing.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	assert.Equal(t.T(), 1, t.backing.calls)
+
+	t.backing.calls = 0
+	t.backing.err = errUnavailable
+	t.backing.failures = 1
+	assert.ErrorIs(t.T(), r.Mkdir("doc/pets", 0755), errUnavailable)
+	assert.Equal(t.T(), 1, t.backing.calls)
+}
+
+func (t *RetryFSTestSuite) TestClassifier() {
+	r := t.retrying(WithClassifier(t.backing.Provider(), func(err error) bool {
+		return errors.Is(err, errUnavailable)
+	}))
+
+	t.backing.err = errUnavailable
+	t.backing.failures = 2
+	assert.NoError(t.T(), r.Mkdir("doc/pets", 0755))
+	assert.Equal(t.T(), 3, t.backing.calls)
+
+	t.backing.calls = 0
+	t.backing.err = syscall.ECONNRESET
+	t.backing.failures = 1
+	assert.ErrorIs(t.T(), r.Mkdir("doc/cats", 0755), syscall.ECONNRESET)
+	assert.Equal(t.T(), 1, t.backing.calls)
+}
+
+func (t *RetryFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	r := t.retrying(WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	t.backing.failures = 1
+	_, err := r.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[retryfs] retrying" op=readFile attempt=1`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.retrying().logger)
+}
+
+func (t *RetryFSTestSuite) TestReadAt() {
+	r := t.retrying(WithAttempts(4))
+
+	f, err := r.OpenFile("doc/fox.txt", fs.O_RDONLY, 0)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	// Each attempt reads 4 bytes and then fails, so the read completes once every byte has been read.
+	t.backing.failures = 3
+	b := make([]byte, 15)
+	n, err := f.ReadAt(b, 4)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 15, n)
+	assert.Equal(t.T(), "quick brown fox", string(b))
+	assert.NoError(t.T(), f.Close())
+}
+
+func (t *RetryFSTestSuite) TestIsTransient() {
+	assert.True(t.T(), IsTransient(fs.NewOpError("mem", "stat", "doc", syscall.ETIMEDOUT)))
+	assert.True(t.T(), IsTransient(&gofs.PathError{Op: "read", Path: "doc", Err: syscall.EAGAIN}))
+	assert.False(t.T(), IsTransient(fs.NewOpError("mem", "stat", "doc", gofs.ErrNotExist)))
+	assert.False(t.T(), IsTransient(errUnavailable))
+	assert.False(t.T(), IsTransient(nil))
+}
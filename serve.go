@@ -0,0 +1,175 @@
+package fs
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// ETag returns the entity tag for the file described by fi, including the quotes.
+//
+// If fi is an *Entry whose Attribute provides an "etag" checksum, such as the ETag of an object in a remote store, or a
+// SHA-256 checksum, the checksum is used as a strong entity tag. Otherwise, the entity tag is derived from the
+// modification time and size of the file.
+func ETag(fi gofs.FileInfo) string {
+	if e, ok := fi.(*Entry); ok {
+		for _, algorithm := range []string{"etag", ChecksumAlgorithm(crypto.SHA256)} {
+			if digest, ok := e.Attributes().Checksum(algorithm); ok {
+				return `"` + digest + `"`
+			}
+		}
+	}
+	return fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size())
+}
+
+// ServeFile replies to the request with the content of the named file in fsys, in the same way as http.ServeFile, but
+// without assuming that fsys is backed by the operating system.
+//
+// Range, If-Match, If-None-Match, If-Modified-Since, and If-Unmodified-Since requests are handled by ServeContent.
+// Directories are not listed, and a 404 (Not Found) response is sent for them. Errors that indicate that the file does
+// not exist or can not be accessed are sent as 404 and 403 (Forbidden) responses respectively, and other errors are
+// sent as 500 (Internal Server Error) responses.
+func ServeFile(w http.ResponseWriter, r *http.Request, fsys gofs.FS, name string) {
+	if fsys == nil {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	if fi.IsDir() {
+		serveError(w, ErrIsDir)
+		return
+	}
+
+	ServeContent(w, r, fi, f)
+}
+
+// ServeContent replies to the request with content, which is the content of the file described by fi, in the same way
+// as http.ServeContent.
+//
+// The Etag header is set to the value returned by ETag unless it is already set, and the Last-Modified header is set
+// from the modification time of fi, so that conditional requests are handled. The Content-Type header is set from the
+// MIME type provided by the Attribute of fi if it is an *Entry, and is otherwise determined from the name of the file
+// or by sniffing the content, unless it is already set.
+//
+// Range requests are served by reading only the requested ranges if content implements io.ReaderAt or io.Seeker.
+// Otherwise, the content preceding each range is read and discarded, and requests for multiple ranges that are not in
+// ascending order are not satisfiable.
+func ServeContent(w http.ResponseWriter, r *http.Request, fi gofs.FileInfo, content io.Reader) {
+	if fi == nil || content == nil {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var rs io.ReadSeeker
+	switch c := content.(type) {
+	case io.ReaderAt:
+		rs = io.NewSectionReader(c, 0, fi.Size())
+	case io.ReadSeeker:
+		rs = c
+	default:
+		rs = &forwardSeeker{r: content, size: fi.Size()}
+	}
+
+	h := w.Header()
+	if _, ok := h["Etag"]; !ok {
+		h.Set("Etag", ETag(fi))
+	}
+
+	if _, ok := h["Content-Type"]; !ok {
+		if t := contentType(fi); t != "" {
+			h.Set("Content-Type", t)
+		} else if _, ok := rs.(*forwardSeeker); ok {
+			// The content must not be sniffed by http.ServeContent, since it can not seek back to the start.
+			h.Set("Content-Type", "application/octet-stream")
+		}
+	}
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+}
+
+// contentType returns the MIME type of the file described by fi from its Attribute or the extension of its name, or an
+// empty string if the type is not known.
+func contentType(fi gofs.FileInfo) string {
+	if e, ok := fi.(*Entry); ok && e.Attributes().MimeType() != "" {
+		return e.Attributes().MimeType()
+	}
+	return mime.TypeByExtension(gopath.Ext(fi.Name()))
+}
+
+// serveError replies to a request with the HTTP status for err.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gofs.ErrNotExist), errors.Is(err, ErrIsDir):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, gofs.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// forwardSeeker provides access to content that can only be read sequentially as an io.ReadSeeker. Seeking only
+// changes the position that is read next, and reading from a position after the current offset discards the content
+// in between, so that http.ServeContent can determine the size of the content and serve ranges in ascending order.
+type forwardSeeker struct {
+	off  int64
+	pos  int64
+	r    io.Reader
+	size int64
+}
+
+func (s *forwardSeeker) Read(p []byte) (int, error) {
+	if s.pos < s.off {
+		return 0, fmt.Errorf("fs: content can not be read from offset %d after offset %d: %w", s.pos, s.off,
+			errors.ErrUnsupported)
+	}
+
+	if s.pos > s.off {
+		n, err := io.CopyN(io.Discard, s.r, s.pos-s.off)
+		s.off += n
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.r.Read(p)
+	s.off += int64(n)
+	s.pos = s.off
+	return n, err
+}
+
+func (s *forwardSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("fs: whence %d is invalid: %w", whence, ErrInvalid)
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("fs: offset %d is negative: %w", offset, ErrInvalid)
+	}
+	s.pos = offset
+	return offset, nil
+}
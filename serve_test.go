@@ -0,0 +1,111 @@
+package fs_test
+
+import (
+	"crypto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestServeFile(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, mfs.WriteFile("doc/fox", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, mfs.SetMimeType("doc/fox", "text/x-fox"))
+
+	serve := func(name string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/"+name, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		fs.ServeFile(w, r, mfs, name)
+		return w
+	}
+
+	w := serve("doc/fox.txt", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "the quick brown fox", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+
+	etag := w.Header().Get("Etag")
+	assert.NotEmpty(t, etag)
+
+	w = serve("doc/fox.txt", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve("doc/fox.txt", map[string]string{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve("doc/fox.txt", map[string]string{"Range": "bytes=10-14"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "brown", w.Body.String())
+	assert.Equal(t, "bytes 10-14/19", w.Header().Get("Content-Range"))
+
+	w = serve("doc/fox", nil)
+	assert.Equal(t, "text/x-fox", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusNotFound, serve("doc/missing.txt", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve("doc", nil).Code)
+}
+
+func TestServeContent(t *testing.T) {
+	mfs, err := memfs.New(memfs.WithChecksum(crypto.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, mfs.WriteFile("fox", []byte("the quick brown fox"), 0644))
+
+	fi, err := mfs.Stat("fox")
+	assert.NoError(t, err)
+
+	digest, ok := fi.(*fs.Entry).Attributes().Checksum("sha256")
+	assert.True(t, ok)
+	assert.Equal(t, `"`+digest+`"`, fs.ETag(fi))
+
+	// Content that can not seek is read sequentially.
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/fox", nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		fs.ServeContent(w, r, fi, struct{ io.Reader }{strings.NewReader("the quick brown fox")})
+		return w
+	}
+
+	w := serve(nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "the quick brown fox", w.Body.String())
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, fs.ETag(fi), w.Header().Get("Etag"))
+
+	w = serve(map[string]string{"Range": "bytes=4-8"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "quick", w.Body.String())
+
+	w = serve(map[string]string{"If-Match": `"other"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	mtime := time.Unix(0, 255)
+	info, err := gofs.Stat(fstest.MapFS{"fox.txt": {Data: []byte("fox"), ModTime: mtime}}, "fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, `"ff-3"`, fs.ETag(info))
+}
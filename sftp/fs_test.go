@@ -0,0 +1,386 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// linkFS is a MemFS with symbolic links, whose destinations are resolved by the server rather than by the MemFS.
+type linkFS struct {
+	*memfs.MemFS
+	links map[string]string
+}
+
+func (l *linkFS) Readlink(name string) (string, error) {
+	if dest, ok := l.links[name]; ok {
+		return dest, nil
+	}
+	return "", &gofs.PathError{Op: "readlink", Path: name, Err: gofs.ErrInvalid}
+}
+
+func (l *linkFS) Symlink(oldname string, newname string) error {
+	return &gofs.PathError{Op: "symlink", Path: newname, Err: fs.ErrUnsupported}
+}
+
+// SFTPTestSuite ...
+type SFTPTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	client  net.Conn
+	done    chan error
+	id      uint32
+	server  *Server
+}
+
+func NewSFTPTestSuite() *SFTPTestSuite {
+	return &SFTPTestSuite{}
+}
+
+func (t *SFTPTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+
+	s, err := New(backing, WithOwner(1000, 1000))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.server = s
+
+	client, conn := net.Pipe()
+	t.client = client
+	t.done = make(chan error, 1)
+	go func() {
+		t.done <- s.Serve(conn)
+		conn.Close()
+	}()
+	t.init(t.client)
+}
+
+func (t *SFTPTestSuite) TearDownTest() {
+	t.client.Close()
+	assert.NoError(t.T(), <-t.done)
+}
+
+func TestSFTPTestSuite(t *testing.T) {
+	suite.Run(t, NewSFTPTestSuite())
+}
+
+func (t *SFTPTestSuite) init(rw io.ReadWriter) {
+	e := &encoder{b: make([]byte, 4)}
+	e.byte(fxpInit)
+	e.uint32(protocolVersion)
+	if _, err := rw.Write(e.packet()); err != nil {
+		t.T().Fatal(err)
+	}
+
+	d := t.recv(rw)
+	assert.Equal(t.T(), byte(fxpVersion), d.byte())
+	assert.Equal(t.T(), uint32(protocolVersion), d.uint32())
+	assert.Equal(t.T(), extPosixRename, d.string())
+}
+
+func (t *SFTPTestSuite) recv(r io.Reader) *decoder {
+	p, err := readPacket(r, make([]byte, 1024))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return &decoder{b: p}
+}
+
+// request sends a request with the provided fields and returns the type of the response and a decoder for the rest of
+// the response.
+func (t *SFTPTestSuite) request(rw io.ReadWriter, typ byte, fields ...any) (byte, *decoder) {
+	t.id++
+	e := newEncoder(typ, t.id)
+	for _, f := range fields {
+		switch v := f.(type) {
+		case string:
+			e.string(v)
+		case []byte:
+			e.bytes(v)
+		case uint32:
+			e.uint32(v)
+		case uint64:
+			e.uint64(v)
+		}
+	}
+
+	if _, err := rw.Write(e.packet()); err != nil {
+		t.T().Fatal(err)
+	}
+
+	d := t.recv(rw)
+	rt := d.byte()
+	assert.Equal(t.T(), t.id, d.uint32())
+	return rt, d
+}
+
+func (t *SFTPTestSuite) status(rw io.ReadWriter, typ byte, fields ...any) uint32 {
+	rt, d := t.request(rw, typ, fields...)
+	assert.Equal(t.T(), byte(fxpStatus), rt)
+	return d.uint32()
+}
+
+func (t *SFTPTestSuite) handle(rw io.ReadWriter, typ byte, fields ...any) string {
+	rt, d := t.request(rw, typ, fields...)
+	if !assert.Equal(t.T(), byte(fxpHandle), rt) {
+		t.T().FailNow()
+	}
+	return d.string()
+}
+
+func (t *SFTPTestSuite) TestRead() {
+	h := t.handle(t.client, fxpOpen, "/doc/fox.txt", uint32(fxfRead), uint32(0))
+
+	rt, d := t.request(t.client, fxpRead, h, uint64(4), uint32(5))
+	assert.Equal(t.T(), byte(fxpData), rt)
+	assert.Equal(t.T(), "quick", d.string())
+
+	assert.Equal(t.T(), uint32(fxEOF), t.status(t.client, fxpRead, h, uint64(19), uint32(5)))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpClose, h))
+	assert.Equal(t.T(), uint32(fxFailure), t.status(t.client, fxpClose, h))
+
+	assert.Equal(t.T(), uint32(fxNoSuchFile), t.status(t.client, fxpOpen, "missing.txt", uint32(fxfRead), uint32(0)))
+}
+
+func (t *SFTPTestSuite) TestWrite() {
+	h := t.handle(t.client, fxpOpen, "doc/dog.txt", uint32(fxfWrite|fxfCreat|fxfTrunc), uint32(attrPermissions),
+		uint32(0600))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpWrite, h, uint64(0), []byte("jumps over ")))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpWrite, h, uint64(11), []byte("the lazy dog")))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpClose, h))
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpSetstat, "doc/dog.txt", uint32(attrSize), uint64(0)))
+
+	fi, err := t.backing.Stat("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(0), fi.Size())
+}
+
+func (t *SFTPTestSuite) TestStat() {
+	rt, d := t.request(t.client, fxpStat, "/doc/fox.txt")
+	assert.Equal(t.T(), byte(fxpAttrs), rt)
+	assert.Equal(t.T(), uint32(attrSize|attrUIDGID|attrPermissions|attrACModTime), d.uint32())
+	assert.Equal(t.T(), uint64(19), d.uint64())
+	assert.Equal(t.T(), uint32(1000), d.uint32())
+	assert.Equal(t.T(), uint32(1000), d.uint32())
+	assert.Equal(t.T(), uint32(modeRegular|0644), d.uint32())
+
+	rt, d = t.request(t.client, fxpRealpath, "doc/../doc/./fox.txt")
+	assert.Equal(t.T(), byte(fxpName), rt)
+	assert.Equal(t.T(), uint32(1), d.uint32())
+	assert.Equal(t.T(), "/doc/fox.txt", d.string())
+
+	rt, d = t.request(t.client, fxpRealpath, ".")
+	assert.Equal(t.T(), byte(fxpName), rt)
+	assert.Equal(t.T(), uint32(1), d.uint32())
+	assert.Equal(t.T(), "/", d.string())
+}
+
+func (t *SFTPTestSuite) TestSecurePath() {
+	assert.NoError(t.T(), t.backing.WriteFile("pictures/seal.txt", []byte("the happy seal"), 0644))
+	assert.NoError(t.T(), t.backing.WriteFile(".hidden", nil, 0644))
+
+	s, err := New(&linkFS{MemFS: t.backing, links: map[string]string{
+		"doc/escape":   "../../../pictures",
+		"doc/absolute": "/pictures",
+		"loop":         "loop",
+	}})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	client, conn := net.Pipe()
+	defer client.Close()
+	go func() {
+		_ = s.Serve(conn)
+		conn.Close()
+	}()
+	t.init(client)
+
+	// Symbolic links are resolved as if the root of the file system were the root, so that they can not refer to a
+	// location outside of it.
+	for _, name := range []string{"/doc/escape/seal.txt", "doc/absolute/seal.txt", "../../doc/escape/seal.txt"} {
+		rt, d := t.request(client, fxpRealpath, name)
+		assert.Equal(t.T(), byte(fxpName), rt)
+		assert.Equal(t.T(), uint32(1), d.uint32())
+		assert.Equal(t.T(), "/pictures/seal.txt", d.string(), name)
+
+		h := t.handle(client, fxpOpen, name, uint32(fxfRead), uint32(0))
+		rt, d = t.request(client, fxpRead, h, uint64(0), uint32(64))
+		assert.Equal(t.T(), byte(fxpData), rt)
+		assert.Equal(t.T(), "the happy seal", d.string())
+		assert.Equal(t.T(), uint32(fxOK), t.status(client, fxpClose, h))
+	}
+
+	rt, d := t.request(client, fxpRealpath, ".hidden")
+	assert.Equal(t.T(), byte(fxpName), rt)
+	assert.Equal(t.T(), uint32(1), d.uint32())
+	assert.Equal(t.T(), "/.hidden", d.string())
+
+	assert.Equal(t.T(), uint32(fxFailure), t.status(client, fxpStat, "loop/fox.txt"))
+}
+
+func (t *SFTPTestSuite) TestReadDir() {
+	h := t.handle(t.client, fxpOpendir, "/doc")
+
+	rt, d := t.request(t.client, fxpReaddir, h)
+	assert.Equal(t.T(), byte(fxpName), rt)
+	assert.Equal(t.T(), uint32(1), d.uint32())
+	assert.Equal(t.T(), "fox.txt", d.string())
+	assert.Contains(t.T(), d.string(), "-rw-r--r--")
+
+	assert.Equal(t.T(), uint32(fxEOF), t.status(t.client, fxpReaddir, h))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpClose, h))
+}
+
+func (t *SFTPTestSuite) TestDirs() {
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpMkdir, "tmp", uint32(0)))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpRename, "doc/fox.txt", "tmp/fox.txt"))
+
+	_, err := t.backing.Stat("tmp/fox.txt")
+	assert.NoError(t.T(), err)
+
+	assert.Equal(t.T(), uint32(fxFailure), t.status(t.client, fxpRmdir, "tmp"))
+	assert.Equal(t.T(), uint32(fxFailure), t.status(t.client, fxpRemove, "tmp"))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpRemove, "tmp/fox.txt"))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpRmdir, "tmp"))
+
+	_, err = t.backing.Stat("tmp")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *SFTPTestSuite) TestRename() {
+	if err := t.backing.WriteFile("doc/dog.txt", []byte("jumps over the lazy dog"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.Equal(t.T(), uint32(fxFailure), t.status(t.client, fxpRename, "doc/dog.txt", "doc/fox.txt"))
+	assert.Equal(t.T(), uint32(fxOK), t.status(t.client, fxpExtended, extPosixRename, "doc/dog.txt", "doc/fox.txt"))
+
+	b, err := t.backing.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+}
+
+func (t *SFTPTestSuite) TestServeSSH() {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- t.server.ServeSSH(l, config)
+	}()
+
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer session.Close()
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	r, err := session.StdoutPipe()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		t.T().Fatal(err)
+	}
+
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{r, w}
+	t.init(rw)
+
+	rt, d := t.request(rw, fxpStat, "doc/fox.txt")
+	assert.Equal(t.T(), byte(fxpAttrs), rt)
+	d.uint32()
+	assert.Equal(t.T(), uint64(19), d.uint64())
+
+	w.Close()
+	b, err := io.ReadAll(r)
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), b)
+
+	l.Close()
+	assert.NoError(t.T(), <-done)
+}
+
+func TestReadPacket(t *testing.T) {
+	b := binary.BigEndian.AppendUint32(nil, maxPacketSize+1)
+	_, err := readPacket(bytes.NewReader(b), make([]byte, 8))
+	assert.Error(t, err)
+}
+
+func (t *SFTPTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	s, err := New(t.backing, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	conn, peer := net.Pipe()
+	peer.Close()
+	s.serveConn(conn, &ssh.ServerConfig{NoClientAuth: true})
+	assert.Contains(t.T(), buf.String(), `msg="[sftp] handshake" remote=pipe`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.server.logger)
+}
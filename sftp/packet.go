@@ -0,0 +1,271 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	gofs "io/fs"
+)
+
+// Packet types defined by version 3 of the SFTP protocol.
+const (
+	fxpInit          = 1
+	fxpVersion       = 2
+	fxpOpen          = 3
+	fxpClose         = 4
+	fxpRead          = 5
+	fxpWrite         = 6
+	fxpLstat         = 7
+	fxpFstat         = 8
+	fxpSetstat       = 9
+	fxpFsetstat      = 10
+	fxpOpendir       = 11
+	fxpReaddir       = 12
+	fxpRemove        = 13
+	fxpMkdir         = 14
+	fxpRmdir         = 15
+	fxpRealpath      = 16
+	fxpStat          = 17
+	fxpRename        = 18
+	fxpReadlink      = 19
+	fxpSymlink       = 20
+	fxpStatus        = 101
+	fxpHandle        = 102
+	fxpData          = 103
+	fxpName          = 104
+	fxpAttrs         = 105
+	fxpExtended      = 200
+	fxpExtendedReply = 201
+)
+
+// Status codes defined by version 3 of the SFTP protocol.
+const (
+	fxOK               = 0
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+	fxFailure          = 4
+	fxBadMessage       = 5
+	fxOpUnsupported    = 8
+)
+
+// Flags for opening files defined by version 3 of the SFTP protocol.
+const (
+	fxfRead   = 0x01
+	fxfWrite  = 0x02
+	fxfAppend = 0x04
+	fxfCreat  = 0x08
+	fxfTrunc  = 0x10
+	fxfExcl   = 0x20
+)
+
+// Flags for file attributes defined by version 3 of the SFTP protocol.
+const (
+	attrSize        = 0x01
+	attrUIDGID      = 0x02
+	attrPermissions = 0x04
+	attrACModTime   = 0x08
+	attrExtended    = 0x80000000
+)
+
+// POSIX file type bits used in the permissions attribute.
+const (
+	modeDir     = 0040000
+	modeRegular = 0100000
+	modeSymlink = 0120000
+)
+
+const (
+	protocolVersion = 3
+	maxPacketSize   = 1 << 20
+)
+
+var errShortPacket = errors.New("sftp: packet is too short")
+
+// attrs contains the file attributes sent by a client.
+type attrs struct {
+	flags uint32
+	size  uint64
+	perm  uint32
+	atime uint32
+	mtime uint32
+}
+
+// decoder reads the fields of a packet.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.b) < 1 {
+		d.err = errShortPacket
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) uint32() uint32 {
+	if d.err != nil || len(d.b) < 4 {
+		d.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.b)
+	d.b = d.b[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil || len(d.b) < 8 {
+		d.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.b)
+	d.b = d.b[8:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	if d.err != nil || uint32(len(d.b)) < n {
+		d.err = errShortPacket
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) attrs() attrs {
+	a := attrs{flags: d.uint32()}
+	if a.flags&attrSize != 0 {
+		a.size = d.uint64()
+	}
+
+	if a.flags&attrUIDGID != 0 {
+		d.uint32()
+		d.uint32()
+	}
+
+	if a.flags&attrPermissions != 0 {
+		a.perm = d.uint32()
+	}
+
+	if a.flags&attrACModTime != 0 {
+		a.atime = d.uint32()
+		a.mtime = d.uint32()
+	}
+
+	if a.flags&attrExtended != 0 {
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			d.string()
+			d.string()
+		}
+	}
+	return a
+}
+
+// encoder writes the fields of a packet.
+type encoder struct {
+	b []byte
+}
+
+func newEncoder(typ byte, id uint32) *encoder {
+	e := &encoder{b: make([]byte, 4, 64)}
+	e.byte(typ)
+	e.uint32(id)
+	return e
+}
+
+func (e *encoder) byte(v byte) {
+	e.b = append(e.b, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.b = binary.BigEndian.AppendUint32(e.b, v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.b = binary.BigEndian.AppendUint64(e.b, v)
+}
+
+func (e *encoder) bytes(v []byte) {
+	e.uint32(uint32(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) string(v string) {
+	e.uint32(uint32(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) fileInfo(fi gofs.FileInfo, uid uint32, gid uint32) {
+	e.uint32(attrSize | attrUIDGID | attrPermissions | attrACModTime)
+	e.uint64(uint64(max(fi.Size(), 0)))
+	e.uint32(uid)
+	e.uint32(gid)
+	e.uint32(mode(fi.Mode()))
+
+	mtime := uint32(max(fi.ModTime().Unix(), 0))
+	e.uint32(mtime)
+	e.uint32(mtime)
+}
+
+// packet returns the encoded packet with its length prefix.
+func (e *encoder) packet() []byte {
+	binary.BigEndian.PutUint32(e.b, uint32(len(e.b)-4))
+	return e.b
+}
+
+// readPacket reads a single packet from r.
+func readPacket(r io.Reader, buf []byte) ([]byte, error) {
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(buf)
+	if n == 0 || n > maxPacketSize {
+		return nil, fmt.Errorf("sftp: invalid packet length: %d", n)
+	}
+
+	if int(n) > len(buf) {
+		buf = make([]byte, n)
+	}
+
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// mode converts m to the POSIX mode bits used in the permissions attribute.
+func mode(m gofs.FileMode) uint32 {
+	perm := uint32(m.Perm())
+	switch {
+	case m.IsDir():
+		return modeDir | perm
+	case m&gofs.ModeSymlink != 0:
+		return modeSymlink | perm
+	}
+	return modeRegular | perm
+}
+
+// longName returns the description of fi in the format of "ls -l", which is displayed by clients in directory
+// listings.
+func longName(fi gofs.FileInfo, uid uint32, gid uint32) string {
+	t := fi.ModTime()
+	date := t.Format("Jan _2 15:04")
+	if t.Before(time.Now().AddDate(0, -6, 0)) {
+		date = t.Format("Jan _2  2006")
+	}
+	return fmt.Sprintf("%s %4d %-8d %-8d %8d %s %s", fi.Mode().String(), 1, uid, gid, fi.Size(), date, fi.Name())
+}
@@ -0,0 +1,654 @@
+// Package sftp serves an fs.FS over version 3 of the SSH File Transfer Protocol (SFTP), which is supported by
+// off-the-shelf clients such as OpenSSH sftp, scp and rclone.
+//
+// A Server can serve SFTP over any stream, which is typically an ssh.Channel for which the "sftp" subsystem has been
+// requested, or can accept SSH connections itself using ServeSSH.
+//
+// Paths are interpreted relative to the root of the fs.FS, which is also the initial working directory of a client, and
+// are resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a location outside of
+// the fs.FS. Symbolic links can not be created or read by clients, and changes to the permissions, ownership and times of files are accepted but are
+// not persisted.
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/transientvariable/fs-go"
+	"golang.org/x/crypto/ssh"
+
+	gofs "io/fs"
+)
+
+const (
+	// extPosixRename is the OpenSSH extension for renaming that replaces the target if it exists.
+	extPosixRename = "posix-rename@openssh.com"
+
+	// readDirBatch is the maximum number of entries returned by a single directory read.
+	readDirBatch = 128
+
+	// maxReadLen is the maximum number of bytes returned by a single read.
+	maxReadLen = 256 * 1024
+)
+
+// Server serves an fs.FS over SFTP.
+type Server struct {
+	fsys   fs.FS
+	gid    uint32
+	logger fs.Logger
+	uid    uint32
+}
+
+// New creates a new Server that serves fsys.
+func New(fsys fs.FS, options ...func(*Server)) (*Server, error) {
+	if fsys == nil {
+		return nil, errors.New("sftp: file system is required")
+	}
+
+	s := &Server{fsys: fsys, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Serve serves SFTP requests read from rw until rw returns io.EOF. rw is typically an ssh.Channel for which the "sftp"
+// subsystem has been requested.
+func (s *Server) Serve(rw io.ReadWriter) error {
+	sess := &session{handles: make(map[string]*handle), server: s}
+	defer sess.closeHandles()
+
+	buf := make([]byte, 64*1024)
+	for {
+		p, err := readPacket(rw, buf)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("sftp: %w", err)
+		}
+
+		if _, err := rw.Write(sess.handle(p)); err != nil {
+			return fmt.Errorf("sftp: %w", err)
+		}
+	}
+}
+
+// ServeSSH accepts SSH connections on l using config, and serves SFTP to sessions that request the "sftp" subsystem.
+// ServeSSH returns nil once l is closed.
+func (s *Server) ServeSSH(l net.Listener, config *ssh.ServerConfig) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("sftp: %w", err)
+		}
+		go s.serveConn(conn, config)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		s.logger.Debug("[sftp] handshake", "remote", conn.RemoteAddr().String(), "error", err)
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			if err := nc.Reject(ssh.UnknownChannelType, "unknown channel type"); err != nil {
+				s.logger.Debug("[sftp] reject", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			continue
+		}
+
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			s.logger.Debug("[sftp] accept", "remote", conn.RemoteAddr().String(), "error", err)
+			continue
+		}
+		go s.serveSession(ch, requests)
+	}
+}
+
+func (s *Server) serveSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer ch.Close()
+
+	for req := range requests {
+		var subsystem struct{ Name string }
+		ok := req.Type == "subsystem" && ssh.Unmarshal(req.Payload, &subsystem) == nil && subsystem.Name == "sftp"
+		if req.WantReply {
+			if err := req.Reply(ok, nil); err != nil {
+				return
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		go ssh.DiscardRequests(requests)
+
+		var status uint32
+		if err := s.Serve(ch); err != nil {
+			s.logger.Error("[sftp] serve", "error", err)
+			status = 1
+		}
+
+		if _, err := ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status})); err != nil {
+			s.logger.Debug("[sftp] exit-status", "error", err)
+		}
+		return
+	}
+}
+
+// WithLogger sets the Logger used by a Server to log the connections and sessions that it serves, and the errors
+// that can not be returned to a client. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*Server) {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithOwner sets the user and group ids reported as the owner of files. The default is 0 for both.
+func WithOwner(uid uint32, gid uint32) func(*Server) {
+	return func(s *Server) {
+		s.uid = uid
+		s.gid = gid
+	}
+}
+
+// handle is an open file or directory.
+type handle struct {
+	entries []gofs.DirEntry
+	file    fs.File
+	flag    int
+	info    gofs.FileInfo
+	name    string
+	off     int64
+}
+
+// session contains the state of a single SFTP session.
+type session struct {
+	handles map[string]*handle
+	next    uint64
+	server  *Server
+}
+
+func (s *session) handle(p []byte) []byte {
+	d := &decoder{b: p}
+	typ := d.byte()
+	if typ == fxpInit {
+		e := &encoder{b: make([]byte, 4, 64)}
+		e.byte(fxpVersion)
+		e.uint32(protocolVersion)
+		e.string(extPosixRename)
+		e.string("1")
+		return e.packet()
+	}
+
+	id := d.uint32()
+	if d.err != nil {
+		return status(id, fxBadMessage, d.err.Error())
+	}
+
+	var resp []byte
+	switch typ {
+	case fxpOpen:
+		resp = s.open(id, d)
+	case fxpClose:
+		resp = s.close(id, d)
+	case fxpRead:
+		resp = s.read(id, d)
+	case fxpWrite:
+		resp = s.write(id, d)
+	case fxpLstat, fxpStat:
+		resp = s.stat(id, d)
+	case fxpFstat:
+		resp = s.fstat(id, d)
+	case fxpSetstat:
+		resp = s.setstat(id, d)
+	case fxpFsetstat:
+		resp = s.fsetstat(id, d)
+	case fxpOpendir:
+		resp = s.opendir(id, d)
+	case fxpReaddir:
+		resp = s.readdir(id, d)
+	case fxpRemove:
+		resp = s.remove(id, d)
+	case fxpMkdir:
+		resp = s.mkdir(id, d)
+	case fxpRmdir:
+		resp = s.rmdir(id, d)
+	case fxpRealpath:
+		resp = s.realpath(id, d)
+	case fxpRename:
+		resp = s.rename(id, d, false)
+	case fxpExtended:
+		if d.string() == extPosixRename {
+			resp = s.rename(id, d, true)
+			break
+		}
+		resp = status(id, fxOpUnsupported, "unsupported extension")
+	default:
+		resp = status(id, fxOpUnsupported, "unsupported operation")
+	}
+
+	if d.err != nil {
+		return status(id, fxBadMessage, d.err.Error())
+	}
+	return resp
+}
+
+func (s *session) open(id uint32, d *decoder) []byte {
+	raw, pflags, a := d.string(), d.uint32(), d.attrs()
+	if d.err != nil {
+		return nil
+	}
+
+	name, err := s.path(raw)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	var flag int
+	switch {
+	case pflags&fxfRead != 0 && pflags&fxfWrite != 0:
+		flag = fs.O_RDWR
+	case pflags&fxfWrite != 0:
+		flag = fs.O_WRONLY
+	default:
+		flag = fs.O_RDONLY
+	}
+
+	if pflags&fxfAppend != 0 {
+		flag |= fs.O_APPEND
+	}
+
+	if pflags&fxfCreat != 0 {
+		flag |= fs.O_CREATE
+	}
+
+	if pflags&fxfTrunc != 0 {
+		flag |= fs.O_TRUNC
+	}
+
+	if pflags&fxfExcl != 0 {
+		flag |= os.O_EXCL
+	}
+
+	if fi, err := s.server.fsys.Stat(name); err == nil && fi.IsDir() {
+		return statusErr(id, &gofs.PathError{Op: "open", Path: name, Err: fs.ErrIsDir})
+	}
+
+	perm := gofs.FileMode(0644)
+	if a.flags&attrPermissions != 0 {
+		perm = gofs.FileMode(a.perm).Perm()
+	}
+
+	f, err := s.server.fsys.OpenFile(name, flag, perm)
+	if err != nil {
+		return statusErr(id, err)
+	}
+	return s.addHandle(id, &handle{file: f, flag: flag, name: name})
+}
+
+func (s *session) close(id uint32, d *decoder) []byte {
+	key := d.string()
+	h, ok := s.handles[key]
+	if !ok {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+	delete(s.handles, key)
+
+	if h.file != nil {
+		return statusErr(id, h.file.Close())
+	}
+	return statusErr(id, nil)
+}
+
+func (s *session) read(id uint32, d *decoder) []byte {
+	h, off, n := s.lookup(d.string()), d.uint64(), d.uint32()
+	if h == nil || h.file == nil {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+
+	b := make([]byte, min(n, maxReadLen))
+	n2, err := h.file.ReadAt(b, int64(off))
+	if n2 == 0 && errors.Is(err, io.EOF) {
+		return status(id, fxEOF, "end of file")
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return statusErr(id, err)
+	}
+
+	e := newEncoder(fxpData, id)
+	e.bytes(b[:n2])
+	return e.packet()
+}
+
+func (s *session) write(id uint32, d *decoder) []byte {
+	h, off, data := s.lookup(d.string()), int64(d.uint64()), d.bytes()
+	if h == nil || h.file == nil {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if w, ok := h.file.(io.WriterAt); ok && h.flag&fs.O_APPEND == 0 {
+		n, err = w.WriteAt(data, off)
+	} else {
+		if off != h.off && h.flag&fs.O_APPEND == 0 {
+			if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+				return statusErr(id, err)
+			}
+		}
+		n, err = h.file.Write(data)
+	}
+	h.off = off + int64(n)
+	return statusErr(id, err)
+}
+
+func (s *session) stat(id uint32, d *decoder) []byte {
+	name, err := s.path(d.string())
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	fi, err := s.server.fsys.Stat(name)
+	if err != nil {
+		return statusErr(id, err)
+	}
+	return s.attrs(id, fi)
+}
+
+func (s *session) fstat(id uint32, d *decoder) []byte {
+	h := s.lookup(d.string())
+	if h == nil {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+
+	if h.file == nil {
+		return s.attrs(id, h.info)
+	}
+
+	fi, err := h.file.Stat()
+	if err != nil {
+		return statusErr(id, err)
+	}
+	return s.attrs(id, fi)
+}
+
+func (s *session) setstat(id uint32, d *decoder) []byte {
+	raw, a := d.string(), d.attrs()
+	name, err := s.path(raw)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	if a.flags&attrSize != 0 {
+		return statusErr(id, s.truncate(name, nil, int64(a.size)))
+	}
+
+	_, err = s.server.fsys.Stat(name)
+	return statusErr(id, err)
+}
+
+func (s *session) fsetstat(id uint32, d *decoder) []byte {
+	h, a := s.lookup(d.string()), d.attrs()
+	if h == nil {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+
+	if a.flags&attrSize != 0 {
+		return statusErr(id, s.truncate(h.name, h.file, int64(a.size)))
+	}
+	return statusErr(id, nil)
+}
+
+func (s *session) truncate(name string, f fs.File, size int64) error {
+	if t, ok := f.(interface{ Truncate(size int64) error }); ok {
+		return t.Truncate(size)
+	}
+
+	fi, err := s.server.fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.IsDir():
+		return &gofs.PathError{Op: "truncate", Path: name, Err: fs.ErrIsDir}
+	case fi.Size() == size:
+		return nil
+	case size != 0:
+		return &gofs.PathError{Op: "truncate", Path: name, Err: errors.ErrUnsupported}
+	}
+
+	tf, err := s.server.fsys.OpenFile(name, fs.O_WRONLY|fs.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	return tf.Close()
+}
+
+func (s *session) opendir(id uint32, d *decoder) []byte {
+	name, err := s.path(d.string())
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	fi, err := s.server.fsys.Stat(name)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	if !fi.IsDir() {
+		return statusErr(id, &gofs.PathError{Op: "opendir", Path: name, Err: fs.ErrNotDir})
+	}
+
+	entries, err := s.server.fsys.ReadDir(name)
+	if err != nil {
+		return statusErr(id, err)
+	}
+	return s.addHandle(id, &handle{entries: entries, info: fi, name: name})
+}
+
+func (s *session) readdir(id uint32, d *decoder) []byte {
+	h := s.lookup(d.string())
+	if h == nil || h.file != nil {
+		return statusErr(id, gofs.ErrInvalid)
+	}
+
+	if len(h.entries) == 0 {
+		return status(id, fxEOF, "end of directory")
+	}
+
+	n := min(len(h.entries), readDirBatch)
+	infos := make([]gofs.FileInfo, 0, n)
+	for _, entry := range h.entries[:n] {
+		if fi, err := entry.Info(); err == nil {
+			infos = append(infos, fi)
+		}
+	}
+	h.entries = h.entries[n:]
+
+	e := newEncoder(fxpName, id)
+	e.uint32(uint32(len(infos)))
+	for _, fi := range infos {
+		e.string(fi.Name())
+		e.string(longName(fi, s.server.uid, s.server.gid))
+		e.fileInfo(fi, s.server.uid, s.server.gid)
+	}
+	return e.packet()
+}
+
+func (s *session) remove(id uint32, d *decoder) []byte {
+	name, err := s.path(d.string())
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	fi, err := s.server.fsys.Stat(name)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	if fi.IsDir() {
+		return statusErr(id, &gofs.PathError{Op: "remove", Path: name, Err: fs.ErrIsDir})
+	}
+	return statusErr(id, s.server.fsys.Remove(name))
+}
+
+func (s *session) mkdir(id uint32, d *decoder) []byte {
+	raw, a := d.string(), d.attrs()
+	name, err := s.path(raw)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	perm := gofs.FileMode(0755)
+	if a.flags&attrPermissions != 0 {
+		perm = gofs.FileMode(a.perm).Perm()
+	}
+	return statusErr(id, s.server.fsys.Mkdir(name, perm))
+}
+
+func (s *session) rmdir(id uint32, d *decoder) []byte {
+	name, err := s.path(d.string())
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	entries, err := s.server.fsys.ReadDir(name)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	if len(entries) > 0 {
+		return statusErr(id, &gofs.PathError{Op: "rmdir", Path: name, Err: fs.ErrNotEmpty})
+	}
+	return statusErr(id, s.server.fsys.Remove(name))
+}
+
+func (s *session) realpath(id uint32, d *decoder) []byte {
+	name, err := s.path(d.string())
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	p := "/"
+	if name != "." {
+		p += strings.ReplaceAll(name, s.server.fsys.PathSeparator(), "/")
+	}
+
+	e := newEncoder(fxpName, id)
+	e.uint32(1)
+	e.string(p)
+	e.string(p)
+	e.uint32(0)
+	return e.packet()
+}
+
+// rename renames an entry. Following version 3 of the SFTP protocol, renaming fails if the target exists, unless
+// replace is true.
+func (s *session) rename(id uint32, d *decoder, replace bool) []byte {
+	rawOld, rawNew := d.string(), d.string()
+	if d.err != nil {
+		return nil
+	}
+
+	oldpath, err := s.path(rawOld)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	newpath, err := s.path(rawNew)
+	if err != nil {
+		return statusErr(id, err)
+	}
+
+	if !replace {
+		if _, err := s.server.fsys.Stat(newpath); err == nil {
+			return statusErr(id, &gofs.PathError{Op: "rename", Path: newpath, Err: gofs.ErrExist})
+		}
+	}
+	return statusErr(id, s.server.fsys.Rename(oldpath, newpath))
+}
+
+func (s *session) attrs(id uint32, fi gofs.FileInfo) []byte {
+	e := newEncoder(fxpAttrs, id)
+	e.fileInfo(fi, s.server.uid, s.server.gid)
+	return e.packet()
+}
+
+func (s *session) addHandle(id uint32, h *handle) []byte {
+	s.next++
+	key := strconv.FormatUint(s.next, 10)
+	s.handles[key] = h
+
+	e := newEncoder(fxpHandle, id)
+	e.string(key)
+	return e.packet()
+}
+
+func (s *session) lookup(key string) *handle {
+	return s.handles[key]
+}
+
+func (s *session) closeHandles() {
+	for key, h := range s.handles {
+		if h.file != nil {
+			if err := h.file.Close(); err != nil {
+				s.server.logger.Error("[sftp] close", "name", h.name, "error", err)
+			}
+		}
+		delete(s.handles, key)
+	}
+}
+
+// path converts a path provided by a client to a path for the fs.FS. Relative paths are resolved against the root, and
+// the path is resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a location
+// outside of the fs.FS.
+func (s *session) path(name string) (string, error) {
+	return fs.SecureJoin(s.server.fsys, ".", name)
+}
+
+func status(id uint32, code uint32, msg string) []byte {
+	e := newEncoder(fxpStatus, id)
+	e.uint32(code)
+	e.string(msg)
+	e.string("en")
+	return e.packet()
+}
+
+// statusErr returns a status packet with the status code that corresponds to err.
+func statusErr(id uint32, err error) []byte {
+	switch {
+	case err == nil:
+		return status(id, fxOK, "ok")
+	case errors.Is(err, gofs.ErrNotExist):
+		return status(id, fxNoSuchFile, err.Error())
+	case errors.Is(err, gofs.ErrPermission):
+		return status(id, fxPermissionDenied, err.Error())
+	case errors.Is(err, errors.ErrUnsupported):
+		return status(id, fxOpUnsupported, err.Error())
+	}
+	return status(id, fxFailure, err.Error())
+}
@@ -0,0 +1,453 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+	gopath "path"
+)
+
+// snapshotMagic identifies the snapshot format written by Export, including its version in the last byte.
+const snapshotMagic = "FSSNAP\x00\x01"
+
+// snapshotHeaderLen is the length of the header of a snapshot, which consists of the magic, the length of the index,
+// and the length of the content, with the lengths encoded as big-endian 64-bit integers.
+const snapshotHeaderLen = len(snapshotMagic) + 16
+
+var (
+	_ gofs.ReadDirFS   = (*Snapshot)(nil)
+	_ gofs.ReadDirFile = (*snapshotDir)(nil)
+	_ gofs.StatFS      = (*Snapshot)(nil)
+)
+
+// Snapshot provides random access to the entries of a snapshot written by Export, without reading the snapshot from
+// the start. It implements gofs.FS, so that a subset of the snapshot can be restored using CopyAll or CopyFile.
+type Snapshot struct {
+	entries map[string]*snapshotEntry
+	r       io.ReaderAt
+}
+
+// snapshotEntry is an entry of a Snapshot, with the offset of its content, and its children if it is a directory.
+type snapshotEntry struct {
+	children []*Entry
+	entry    *Entry
+	offset   int64
+}
+
+// Export writes a snapshot of the tree rooted at root in fsys to w.
+//
+// The snapshot consists of a header, an index, and the content of the regular files in the tree. The index lists every
+// entry in the tree in the same format as Manifest, in lexical order and with paths relative to root, and the content
+// of each regular file follows the content of the previous file in the index. Since the header records the length of
+// the index and content, a snapshot can be restored from a stream using Import, and individual entries can be read
+// using OpenSnapshot without reading the whole snapshot, unlike a tar archive.
+//
+// Symbolic links are listed with their targets and have no content. An error wrapping ErrConflict is returned if the
+// size of a file changes while the snapshot is written.
+func Export(fsys gofs.FS, root string, w io.Writer) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	if w == nil {
+		return errors.New("fs: writer is required")
+	}
+
+	var (
+		files []string
+		index bytes.Buffer
+		sizes []int64
+		size  int64
+	)
+	enc := json.NewEncoder(&index)
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	err := Walk(fsys, root, func(p string, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == root {
+			return nil
+		}
+
+		e := entry.Copy()
+		e.path = p
+		if root != "." {
+			e.path = strings.TrimPrefix(p, prefix)
+		}
+
+		if e.Mode().IsRegular() {
+			files = append(files, p)
+			sizes = append(sizes, e.Size())
+			size += e.Size()
+		}
+
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("fs: snapshot: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, snapshotHeaderLen)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint64(header, uint64(index.Len()))
+	header = binary.BigEndian.AppendUint64(header, uint64(size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := index.WriteTo(w); err != nil {
+		return err
+	}
+
+	for i, name := range files {
+		if err := exportContent(fsys, name, sizes[i], w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import restores the snapshot written by Export that is read from r to fsys, relative to the root of fsys.
+//
+// The snapshot is read as a stream, so r does not need to support seeking. Directories are created if they do not
+// exist, and existing files are replaced. The modification times, user-defined metadata, and MIME types of the entries
+// are applied in the same way as ApplyManifest. Use OpenSnapshot and CopyAll to restore only part of a snapshot.
+func Import(fsys FS, r io.Reader) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	if r == nil {
+		return errors.New("fs: reader is required")
+	}
+
+	header := make([]byte, snapshotHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("fs: snapshot: %w", err)
+	}
+
+	indexLen, contentLen, err := readSnapshotHeader(header)
+	if err != nil {
+		return err
+	}
+
+	entries, err := readSnapshotIndex(io.LimitReader(r, indexLen), contentLen)
+	if err != nil {
+		return err
+	}
+
+	// Directory times are applied once the tree has been created, since creating the entries of a directory changes
+	// its modification time.
+	var dirs []*Entry
+	for _, e := range entries {
+		p := Join(fsys, ".", e.Path())
+		switch mode := e.Mode(); {
+		case mode.IsDir():
+			if err := fsys.MkdirAll(p, mode.Perm()); err != nil {
+				return err
+			}
+			dirs = append(dirs, e)
+			continue
+		case mode.IsRegular():
+			if err := importContent(fsys, p, mode.Perm(), io.LimitReader(r, e.Size()), e.Size()); err != nil {
+				return err
+			}
+		case mode&gofs.ModeSymlink != 0:
+			sfs, ok := fsys.(SymlinkFS)
+			if !ok {
+				return NewOpError(fsys.Provider(), "import", p, ErrUnsupported)
+			}
+
+			if err := sfs.Symlink(e.Attributes().LinkTarget(), p); err != nil {
+				return err
+			}
+			continue
+		default:
+			return NewOpError(fsys.Provider(), "import", p, ErrUnsupported)
+		}
+
+		if err := applyManifestAttributes(fsys, p, e); err != nil {
+			return err
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyManifestAttributes(fsys, Join(fsys, ".", dirs[i].Path()), dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenSnapshot reads the header and index of the snapshot written by Export that is read from r, and returns a
+// Snapshot that reads the content of files from r when they are read.
+func OpenSnapshot(r io.ReaderAt) (*Snapshot, error) {
+	if r == nil {
+		return nil, errors.New("fs: reader is required")
+	}
+
+	header := make([]byte, snapshotHeaderLen)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("fs: snapshot: %w", err)
+	}
+
+	indexLen, contentLen, err := readSnapshotHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := readSnapshotIndex(io.NewSectionReader(r, int64(snapshotHeaderLen), indexLen), contentLen)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := NewAttributes(WithMode(uint32(gofs.ModeDir | 0755)))
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := NewEntry(".", WithAttributes(attrs))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{entries: map[string]*snapshotEntry{".": {entry: root}}, r: r}
+	offset := int64(snapshotHeaderLen) + indexLen
+	for _, e := range entries {
+		parent, ok := s.entries[gopath.Dir(e.Path())]
+		if !ok || !parent.entry.IsDir() {
+			return nil, fmt.Errorf("fs: snapshot: %w", &gofs.PathError{Op: "open", Path: e.Path(), Err: ErrInvalid})
+		}
+		parent.children = append(parent.children, e)
+
+		s.entries[e.Path()] = &snapshotEntry{entry: e, offset: offset}
+		if e.Mode().IsRegular() {
+			offset += e.Size()
+		}
+	}
+	return s, nil
+}
+
+// Entries returns the entries of the snapshot in lexical order of their paths, which are relative to the root of the
+// exported tree.
+func (s *Snapshot) Entries() []*Entry {
+	entries := make([]*Entry, 0, len(s.entries)-1)
+	for p, e := range s.entries {
+		if p != "." {
+			entries = append(entries, e.entry)
+		}
+	}
+	slices.SortFunc(entries, func(a *Entry, b *Entry) int {
+		return strings.Compare(a.Path(), b.Path())
+	})
+	return entries
+}
+
+// Open opens the named entry of the snapshot. The content of a regular file is read from the snapshot as the file is
+// read, and the returned file implements io.ReaderAt and io.Seeker.
+func (s *Snapshot) Open(name string) (gofs.File, error) {
+	e, err := s.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.entry.IsDir() {
+		return &snapshotDir{entry: e}, nil
+	}
+	return &snapshotFile{SectionReader: io.NewSectionReader(s.r, e.offset, e.entry.Size()), entry: e.entry}, nil
+}
+
+// ReadDir returns the entries of the named directory in the snapshot, sorted by name.
+func (s *Snapshot) ReadDir(name string) ([]gofs.DirEntry, error) {
+	e, err := s.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.entry.IsDir() {
+		return nil, &gofs.PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+	return dirEntries(e.children), nil
+}
+
+// Stat returns the entry for the named file in the snapshot.
+func (s *Snapshot) Stat(name string) (gofs.FileInfo, error) {
+	e, err := s.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e.entry, nil
+}
+
+// lookup returns the named entry of the snapshot.
+func (s *Snapshot) lookup(op string, name string) (*snapshotEntry, error) {
+	if !gofs.ValidPath(name) {
+		return nil, &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid}
+	}
+
+	e, ok := s.entries[name]
+	if !ok {
+		return nil, &gofs.PathError{Op: op, Path: name, Err: gofs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// snapshotDir is a directory opened from a Snapshot.
+type snapshotDir struct {
+	entry  *snapshotEntry
+	offset int
+}
+
+func (d *snapshotDir) Close() error {
+	return nil
+}
+
+func (d *snapshotDir) Read([]byte) (int, error) {
+	return 0, &gofs.PathError{Op: "read", Path: d.entry.entry.Path(), Err: ErrIsDir}
+}
+
+func (d *snapshotDir) ReadDir(n int) ([]gofs.DirEntry, error) {
+	children := d.entry.children[d.offset:]
+	if n > 0 {
+		if len(children) == 0 {
+			return nil, io.EOF
+		}
+		children = children[:min(n, len(children))]
+	}
+	d.offset += len(children)
+	return dirEntries(children), nil
+}
+
+func (d *snapshotDir) Stat() (gofs.FileInfo, error) {
+	return d.entry.entry, nil
+}
+
+// snapshotFile is a regular file or symbolic link opened from a Snapshot.
+type snapshotFile struct {
+	*io.SectionReader
+	entry *Entry
+}
+
+func (f *snapshotFile) Close() error {
+	return nil
+}
+
+func (f *snapshotFile) Stat() (gofs.FileInfo, error) {
+	return f.entry, nil
+}
+
+// dirEntries returns entries as a slice of gofs.DirEntry.
+func dirEntries(entries []*Entry) []gofs.DirEntry {
+	de := make([]gofs.DirEntry, len(entries))
+	for i, e := range entries {
+		de[i] = e
+	}
+	return de
+}
+
+// exportContent writes the content of the named file, which must have the provided size, to w.
+func exportContent(fsys gofs.FS, name string, size int64, w io.Writer) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.CopyN(w, f, size)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if n < size {
+		return fmt.Errorf("fs: snapshot: %w", &gofs.PathError{Op: "export", Path: name, Err: ErrConflict})
+	}
+
+	if n, _ := f.Read(make([]byte, 1)); n > 0 {
+		return fmt.Errorf("fs: snapshot: %w", &gofs.PathError{Op: "export", Path: name, Err: ErrConflict})
+	}
+	return nil
+}
+
+// importContent creates or replaces the named file with the provided size in fsys with the content read from r.
+func importContent(fsys FS, name string, perm gofs.FileMode, r io.Reader, size int64) error {
+	f, err := fsys.OpenFile(name, O_WRONLY|O_CREATE|O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	n, err := f.ReadFrom(r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil && n < size {
+		err = fmt.Errorf("fs: snapshot: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// readSnapshotHeader validates the header of a snapshot, and returns the length of the index and content.
+func readSnapshotHeader(header []byte) (int64, int64, error) {
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, 0, fmt.Errorf("fs: snapshot: invalid header: %w", ErrInvalid)
+	}
+
+	indexLen := binary.BigEndian.Uint64(header[len(snapshotMagic):])
+	contentLen := binary.BigEndian.Uint64(header[len(snapshotMagic)+8:])
+	limit := uint64(math.MaxInt64) - uint64(snapshotHeaderLen)
+	if indexLen > limit || contentLen > limit-indexLen {
+		return 0, 0, fmt.Errorf("fs: snapshot: invalid header: %w", ErrInvalid)
+	}
+	return int64(indexLen), int64(contentLen), nil
+}
+
+// readSnapshotIndex reads the entries listed by the index of a snapshot, and validates that the sizes of the regular
+// files add up to the length of the content recorded by the header.
+func readSnapshotIndex(r io.Reader, contentLen int64) ([]*Entry, error) {
+	var (
+		entries []*Entry
+		size    int64
+	)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("fs: snapshot: %w", err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			e := &Entry{}
+			if err := json.Unmarshal(line, e); err != nil {
+				return nil, fmt.Errorf("fs: snapshot: %w", err)
+			}
+
+			if e.Mode().IsRegular() {
+				if e.Size() < 0 || e.Size() > contentLen-size {
+					return nil, fmt.Errorf("fs: snapshot: %w", &gofs.PathError{Op: "open", Path: e.Path(), Err: ErrInvalid})
+				}
+				size += e.Size()
+			}
+			entries = append(entries, e)
+		}
+
+		if err != nil {
+			if size != contentLen {
+				return nil, fmt.Errorf("fs: snapshot: content length does not match index: %w", ErrInvalid)
+			}
+			return entries, nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package fs_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestExportImport(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, src.WriteFile("backup/doc/fox.txt", []byte("the quick brown fox"), 0640))
+	assert.NoError(t, src.WriteFile("backup/doc/animals/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t, src.WriteFile("backup/empty.txt", nil, 0644))
+	assert.NoError(t, src.Chtimes("backup/doc/fox.txt", mtime, mtime))
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, fs.Export(src, "backup", &snapshot))
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.Import(dst, bytes.NewReader(snapshot.Bytes())))
+
+	b, err := dst.ReadFile("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	fi, err := dst.Stat("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0640), fi.Mode())
+	assert.True(t, mtime.Equal(fi.ModTime()))
+
+	b, err = dst.ReadFile("doc/animals/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the lazy dog", string(b))
+
+	fi, err = dst.Stat("empty.txt")
+	assert.NoError(t, err)
+	assert.Zero(t, fi.Size())
+
+	assert.ErrorIs(t, fs.Import(dst, bytes.NewReader([]byte("the quick brown fox jumps over"))), fs.ErrInvalid)
+	assert.ErrorIs(t, fs.Import(dst, bytes.NewReader(snapshot.Bytes()[:snapshot.Len()-1])), io.ErrUnexpectedEOF)
+}
+
+func TestOpenSnapshot(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, src.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, src.WriteFile("doc/animals/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t, src.WriteFile("pictures/seals.png", []byte("seals"), 0644))
+
+	var b bytes.Buffer
+	assert.NoError(t, fs.Export(src, ".", &b))
+
+	snapshot, err := fs.OpenSnapshot(bytes.NewReader(b.Bytes()))
+	assert.NoError(t, err)
+	assert.NoError(t, fstest.TestFS(snapshot, "doc/fox.txt", "doc/animals/dog.txt", "pictures/seals.png"))
+
+	var paths []string
+	for _, e := range snapshot.Entries() {
+		paths = append(paths, e.Path())
+	}
+	assert.Equal(t, []string{"doc", "doc/animals", "doc/animals/dog.txt", "doc/fox.txt", "pictures", "pictures/seals.png"},
+		paths)
+
+	content, err := gofs.ReadFile(snapshot, "doc/animals/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the lazy dog", string(content))
+
+	dst, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.CopyAll(dst, "restored", snapshot, "doc/animals"))
+
+	content, err = dst.ReadFile("restored/dog.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the lazy dog", string(content))
+
+	_, err = snapshot.Stat("missing.txt")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+
+	_, err = fs.OpenSnapshot(bytes.NewReader([]byte("the quick brown fox jumps over")))
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
@@ -0,0 +1,168 @@
+package fs
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	gofs "io/fs"
+)
+
+const (
+	// SortByName sorts directory entries by filename.
+	SortByName SortKey = iota
+
+	// SortBySize sorts directory entries by size, and entries with the same size by filename.
+	SortBySize
+
+	// SortByModTime sorts directory entries by modification time, and entries with the same modification time by
+	// filename.
+	SortByModTime
+)
+
+// SortKey defines the key used to sort the entries of a directory.
+type SortKey int
+
+// String returns the name of the SortKey.
+func (k SortKey) String() string {
+	switch k {
+	case SortByName:
+		return "name"
+	case SortBySize:
+		return "size"
+	case SortByModTime:
+		return "modTime"
+	default:
+		return "unknown"
+	}
+}
+
+// SortOption defines an option for ReadDirSorted and IterateDir.
+type SortOption func(*sortOptions)
+
+type sortOptions struct {
+	desc bool
+	key  SortKey
+}
+
+// SortedDirIteratorFS defines the behavior for iterating over the entries of a directory in a requested order, so that
+// file systems that already maintain the entries of a directory in order do not need to sort them.
+type SortedDirIteratorFS interface {
+	FS
+
+	// IterateDirSorted returns a DirIterator over the entries of the named directory sorted by key, in descending order
+	// if desc is true. Entries with equal keys are sorted by filename in ascending order.
+	IterateDirSorted(name string, key SortKey, desc bool) (DirIterator, error)
+}
+
+// ReadDirSorted reads the named directory and returns its entries sorted by filename, or by the key set using By, so
+// that listings for user interfaces and reports do not need to be sorted by the caller. Entries with equal keys are
+// sorted by filename in ascending order, and the order of the keys is reversed if Desc is set.
+//
+// If fsys implements SortedDirIteratorFS, its IterateDirSorted method is used. Otherwise, the entries are read using
+// gofs.ReadDir and sorted.
+func ReadDirSorted(fsys gofs.FS, name string, options ...SortOption) ([]gofs.DirEntry, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	opts := newSortOptions(options...)
+	if s, ok := fsys.(SortedDirIteratorFS); ok {
+		it, err := s.IterateDirSorted(name, opts.key, opts.desc)
+		if err != nil {
+			return nil, err
+		}
+
+		de, err := it.NextN(-1)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]gofs.DirEntry, len(de))
+		for i, e := range de {
+			entries[i] = e
+		}
+		return entries, nil
+	}
+
+	entries, err := gofs.ReadDir(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.key == SortByName {
+		if opts.desc {
+			slices.Reverse(entries)
+		}
+		return entries, nil
+	}
+
+	infos := make(map[string]gofs.FileInfo, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos[e.Name()] = fi
+	}
+
+	slices.SortStableFunc(entries, func(a gofs.DirEntry, b gofs.DirEntry) int {
+		return opts.compare(infos[a.Name()], infos[b.Name()])
+	})
+	return entries, nil
+}
+
+// SortEntries sorts entries in place in the same order as ReadDirSorted, for example to implement
+// SortedDirIteratorFS.
+func SortEntries(entries []*Entry, options ...SortOption) {
+	opts := newSortOptions(options...)
+	slices.SortStableFunc(entries, func(a *Entry, b *Entry) int {
+		return opts.compare(a, b)
+	})
+}
+
+// By sets the key used to sort the entries of a directory. The default is SortByName.
+func By(key SortKey) SortOption {
+	return func(o *sortOptions) {
+		o.key = key
+	}
+}
+
+// Desc sets the entries of a directory to be sorted in descending order of the key.
+func Desc() SortOption {
+	return func(o *sortOptions) {
+		o.desc = true
+	}
+}
+
+// newSortOptions returns the sortOptions for the provided options.
+func newSortOptions(options ...SortOption) *sortOptions {
+	opts := &sortOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return opts
+}
+
+// compare compares the entries a and b by the key of the sortOptions, and by filename if the keys are equal.
+func (o *sortOptions) compare(a gofs.FileInfo, b gofs.FileInfo) int {
+	var c int
+	switch o.key {
+	case SortBySize:
+		c = cmp.Compare(a.Size(), b.Size())
+	case SortByModTime:
+		c = a.ModTime().Compare(b.ModTime())
+	}
+
+	if o.desc {
+		c = -c
+	}
+
+	if c == 0 {
+		c = cmp.Compare(a.Name(), b.Name())
+		if o.desc && o.key == SortByName {
+			c = -c
+		}
+	}
+	return c
+}
@@ -0,0 +1,71 @@
+package fs_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestReadDirSorted(t *testing.T) {
+	now := time.Now()
+	mapfs := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("aaa"), ModTime: now.Add(-time.Hour)},
+		"dir/b.txt": {Data: []byte("b"), ModTime: now},
+		"dir/c.txt": {Data: []byte("cc"), ModTime: now.Add(-2 * time.Hour)},
+		"dir/d.txt": {Data: []byte("b"), ModTime: now.Add(-3 * time.Hour)},
+	}
+
+	mfs, err := memfs.FromMapFS(mapfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		options []fs.SortOption
+		want    []string
+	}{
+		{want: []string{"a.txt", "b.txt", "c.txt", "d.txt"}},
+		{options: []fs.SortOption{fs.Desc()}, want: []string{"d.txt", "c.txt", "b.txt", "a.txt"}},
+		{options: []fs.SortOption{fs.By(fs.SortBySize)}, want: []string{"b.txt", "d.txt", "c.txt", "a.txt"}},
+		{options: []fs.SortOption{fs.By(fs.SortBySize), fs.Desc()}, want: []string{"a.txt", "c.txt", "b.txt", "d.txt"}},
+		{options: []fs.SortOption{fs.By(fs.SortByModTime)}, want: []string{"d.txt", "c.txt", "a.txt", "b.txt"}},
+		{options: []fs.SortOption{fs.By(fs.SortByModTime), fs.Desc()}, want: []string{"b.txt", "a.txt", "c.txt", "d.txt"}},
+	}
+
+	for _, fsys := range []gofs.FS{mapfs, mfs} {
+		for _, tc := range tests {
+			entries, err := fs.ReadDirSorted(fsys, "dir", tc.options...)
+			assert.NoError(t, err)
+
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			assert.Equal(t, tc.want, names)
+
+			names = nil
+			for e, err := range fs.IterateDir(fsys, "dir", tc.options...) {
+				if !assert.NoError(t, err) {
+					break
+				}
+				assert.Equal(t, "dir/"+e.Name(), e.Path())
+				names = append(names, e.Name())
+			}
+			assert.Equal(t, tc.want, names)
+		}
+
+		_, err := fs.ReadDirSorted(fsys, "missing", fs.By(fs.SortBySize))
+		assert.ErrorIs(t, err, gofs.ErrNotExist)
+	}
+
+	_, err = fs.ReadDirSorted(nil, "dir")
+	assert.Error(t, err)
+	assert.Equal(t, "modTime", fs.SortByModTime.String())
+}
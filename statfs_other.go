@@ -0,0 +1,12 @@
+//go:build !darwin && !freebsd && !linux && !windows
+
+package fs
+
+import (
+	"errors"
+)
+
+// statfs is not supported on this platform.
+func statfs(_ string) (uint64, uint64, uint64, uint64, error) {
+	return 0, 0, 0, 0, errors.ErrUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build darwin || freebsd || linux
+
+package fs
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// statfs returns the capacity and usage of the file system that contains path.
+func statfs(path string) (uint64, uint64, uint64, uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	bsize := uint64(st.Bsize)
+	return uint64(st.Blocks) * bsize, uint64(st.Blocks-st.Bfree) * bsize, uint64(st.Bavail) * bsize,
+		uint64(st.Files) - uint64(st.Ffree), nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package fs
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// statfs returns the capacity and usage of the volume that contains path. The number of files is not reported.
+func statfs(path string) (uint64, uint64, uint64, uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return total, total - totalFree, free, 0, nil
+}
@@ -0,0 +1,192 @@
+package fs
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// defaultStatsLargest is the default number of files and directories reported by TreeStats as the largest.
+const defaultStatsLargest = 10
+
+// StatsOption defines an option for TreeStats.
+type StatsOption func(*statsOptions)
+
+type statsOptions struct {
+	largest int
+	walk    []WalkOption
+}
+
+// SizedPath is the path of a file or directory in a TreeStatistics, and its size in bytes.
+type SizedPath struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// TreeStatistics are the statistics of a tree of files and directories returned by TreeStats.
+type TreeStatistics struct {
+	// Dirs is the number of directories in the tree, including the root.
+	Dirs int64 `json:"dirs"`
+
+	// Files is the number of regular files in the tree.
+	Files int64 `json:"files"`
+
+	// Symlinks is the number of symbolic links in the tree, which are not followed.
+	Symlinks int64 `json:"symlinks"`
+
+	// Other is the number of entries of other types, such as named pipes and devices.
+	Other int64 `json:"other"`
+
+	// Errors is the number of entries that could not be read, and of directories whose entries could not be listed.
+	// The entries that could not be read are excluded from the other statistics.
+	Errors int64 `json:"errors"`
+
+	// Size is the total size in bytes of the regular files in the tree.
+	Size int64 `json:"size"`
+
+	// SizeHistogram counts the regular files by size. SizeHistogram[0] is the number of empty files, and
+	// SizeHistogram[i] for i > 0 is the number of files of at least 2^(i-1) bytes and less than 2^i bytes.
+	SizeHistogram []int64 `json:"size_histogram"`
+
+	// Depths counts the entries by depth, where the root is at depth 0 and its entries are at depth 1.
+	Depths []int64 `json:"depths"`
+
+	// LargestFiles are the largest regular files in the tree, largest first.
+	LargestFiles []SizedPath `json:"largest_files"`
+
+	// LargestDirs are the directories below the root with the largest total size of the regular files they contain,
+	// including the files of their subdirectories, largest first.
+	LargestDirs []SizedPath `json:"largest_dirs"`
+}
+
+// TreeStats walks the file tree rooted at root and returns the number of entries by type, a histogram of the sizes of
+// regular files, the number of entries at each depth, and the largest files and directories, as reported by disk
+// usage tools.
+//
+// Entries that can not be read are counted in Errors and the walk continues, while an error is returned if root can
+// not be read. The tree is walked using Walk, so fsys is read by multiple goroutines if WithParallelism is set using
+// WithStatsWalkOptions. Entries of the same size are reported as the largest in lexical order of their paths.
+func TreeStats(fsys gofs.FS, root string, options ...StatsOption) (*TreeStatistics, error) {
+	opts := &statsOptions{largest: defaultStatsLargest}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	root = gopath.Clean(root)
+	var (
+		dirSizes = make(map[string]int64)
+		files    = &largest{n: opts.largest}
+		mutex    sync.Mutex
+		stats    = &TreeStatistics{}
+	)
+
+	err := Walk(fsys, root, func(path string, entry *Entry, err error) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err != nil {
+			if path == root {
+				return err
+			}
+			stats.Errors++
+			return nil
+		}
+
+		depth := pathDepth(root, path)
+		if depth >= len(stats.Depths) {
+			stats.Depths = append(stats.Depths, make([]int64, depth-len(stats.Depths)+1)...)
+		}
+		stats.Depths[depth]++
+
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			stats.Dirs++
+			// Directories are visited before their entries, so that empty directories are also reported.
+			if path != root {
+				dirSizes[path] = 0
+			}
+		case mode.IsRegular():
+			stats.Files++
+			stats.Size += entry.Size()
+
+			b := bits.Len64(uint64(max(entry.Size(), 0)))
+			if b >= len(stats.SizeHistogram) {
+				stats.SizeHistogram = append(stats.SizeHistogram, make([]int64, b-len(stats.SizeHistogram)+1)...)
+			}
+			stats.SizeHistogram[b]++
+			files.add(path, entry.Size())
+
+			if path != root {
+				for dir := gopath.Dir(path); dir != root; dir = gopath.Dir(dir) {
+					dirSizes[dir] += entry.Size()
+				}
+			}
+		case mode&gofs.ModeSymlink != 0:
+			stats.Symlinks++
+		default:
+			stats.Other++
+		}
+		return nil
+	}, opts.walk...)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := &largest{n: opts.largest}
+	for path, size := range dirSizes {
+		dirs.add(path, size)
+	}
+	stats.LargestFiles = files.entries
+	stats.LargestDirs = dirs.entries
+	return stats, nil
+}
+
+// WithLargest sets the number of files and directories reported by TreeStats as the largest. The default is 10.
+func WithLargest(n int) StatsOption {
+	return func(o *statsOptions) {
+		o.largest = max(n, 0)
+	}
+}
+
+// WithStatsWalkOptions sets the options used by TreeStats to walk the tree, such as WithMaxDepth, WithSkipPatterns,
+// and WithParallelism.
+func WithStatsWalkOptions(options ...WalkOption) StatsOption {
+	return func(o *statsOptions) {
+		o.walk = append(o.walk, options...)
+	}
+}
+
+// largest holds the n largest paths added to it, largest first.
+type largest struct {
+	entries []SizedPath
+	n       int
+}
+
+func (l *largest) add(path string, size int64) {
+	i := sort.Search(len(l.entries), func(i int) bool {
+		e := l.entries[i]
+		return e.Size < size || (e.Size == size && e.Path > path)
+	})
+	if i >= l.n {
+		return
+	}
+
+	if len(l.entries) < l.n {
+		l.entries = append(l.entries, SizedPath{})
+	}
+	copy(l.entries[i+1:], l.entries[i:])
+	l.entries[i] = SizedPath{Path: path, Size: size}
+}
+
+// pathDepth returns the number of elements of path below root, where path is a path below root as provided by Walk.
+func pathDepth(root string, path string) int {
+	depth := 0
+	for p := path; p != root && p != "." && p != "/"; p = gopath.Dir(p) {
+		depth++
+	}
+	return depth
+}
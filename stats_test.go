@@ -0,0 +1,68 @@
+package fs_test
+
+import (
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestTreeStats(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFiles(map[string][]byte{
+		"data/logs/app.log":      make([]byte, 2048),
+		"data/logs/app.1.log":    make([]byte, 16),
+		"data/logs/old/2020.log": make([]byte, 8192),
+		"data/doc/fox.txt":       []byte("the quick brown fox"),
+		"data/doc/empty.txt":     nil,
+	}, 0644))
+	assert.NoError(t, mfs.Mkdir("data/tmp", 0755))
+	assert.NoError(t, mfs.Mkfifo("data/tmp/pipe", 0644))
+
+	stats, err := fs.TreeStats(mfs, "data", fs.WithLargest(2))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), stats.Dirs)
+	assert.Equal(t, int64(5), stats.Files)
+	assert.Equal(t, int64(0), stats.Symlinks)
+	assert.Equal(t, int64(1), stats.Other)
+	assert.Equal(t, int64(0), stats.Errors)
+	assert.Equal(t, int64(2048+16+8192+19), stats.Size)
+	assert.Equal(t, []int64{1, 3, 6, 1}, stats.Depths)
+
+	// Files are counted by the number of bits of their size.
+	histogram := make([]int64, 15)
+	histogram[0] = 1
+	histogram[5] = 2
+	histogram[12] = 1
+	histogram[14] = 1
+	assert.Equal(t, histogram, stats.SizeHistogram)
+
+	assert.Equal(t, []fs.SizedPath{
+		{Path: "data/logs/old/2020.log", Size: 8192},
+		{Path: "data/logs/app.log", Size: 2048},
+	}, stats.LargestFiles)
+	assert.Equal(t, []fs.SizedPath{
+		{Path: "data/logs", Size: 2048 + 16 + 8192},
+		{Path: "data/logs/old", Size: 8192},
+	}, stats.LargestDirs)
+
+	// The tree can be walked using the options of Walk.
+	stats, err = fs.TreeStats(mfs, ".", fs.WithStatsWalkOptions(fs.WithMaxDepth(2), fs.WithParallelism(4)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), stats.Dirs)
+	assert.Equal(t, int64(0), stats.Files)
+	assert.Equal(t, []int64{1, 1, 3}, stats.Depths)
+	assert.Len(t, stats.LargestDirs, 4)
+	assert.Empty(t, stats.LargestFiles)
+
+	_, err = fs.TreeStats(mfs, "missing")
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+}
@@ -0,0 +1,219 @@
+package sync
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// SyncTestSuite ...
+type SyncTestSuite struct {
+	suite.Suite
+	dir string
+	dst fs.FS
+	src fs.FS
+}
+
+func NewSyncTestSuite() *SyncTestSuite {
+	return &SyncTestSuite{}
+}
+
+func (t *SyncTestSuite) SetupTest() {
+	t.dir = t.T().TempDir()
+
+	osfs, err := fs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for _, d := range []string{"src/doc", "dst"} {
+		if err := os.MkdirAll(filepath.Join(t.dir, d), 0755); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+
+	t.write("src/doc/fox.txt", "the quick brown fox")
+	t.write("src/doc/dog.txt", "jumps over the lazy dog")
+
+	if t.src, err = fs.Chroot(osfs, filepath.Join(t.dir, "src")); err != nil {
+		t.T().Fatal(err)
+	}
+
+	if t.dst, err = fs.Chroot(osfs, filepath.Join(t.dir, "dst")); err != nil {
+		t.T().Fatal(err)
+	}
+}
+
+func TestSyncTestSuite(t *testing.T) {
+	suite.Run(t, NewSyncTestSuite())
+}
+
+func (t *SyncTestSuite) write(name string, content string) {
+	if err := os.WriteFile(filepath.Join(t.dir, name), []byte(content), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+}
+
+func (t *SyncTestSuite) TestSync() {
+	summary, err := Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/dog.txt", "doc/fox.txt"}, summary.Copied)
+	assert.Equal(t.T(), []string{"doc"}, summary.Created)
+	assert.Equal(t.T(), int64(42), summary.Bytes)
+
+	b, err := t.dst.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	summary, err = Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), summary.Copied)
+	assert.Equal(t.T(), 2, summary.Unchanged)
+
+	t.write("src/doc/fox.txt", "the quick red fox")
+	summary, err = Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/fox.txt"}, summary.Copied)
+	assert.Equal(t.T(), 1, summary.Unchanged)
+
+	b, err = t.dst.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick red fox", string(b))
+}
+
+func (t *SyncTestSuite) TestCompare() {
+	_, err := Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+
+	// Same size and modification time, but different content.
+	fi, err := t.src.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	t.write("dst/doc/fox.txt", "the quick brown cat")
+	assert.NoError(t.T(), os.Chtimes(filepath.Join(t.dir, "dst/doc/fox.txt"), fi.ModTime(), fi.ModTime()))
+
+	summary, err := Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), summary.Copied)
+
+	summary, err = Sync(t.dst, t.src, WithCompare(CompareHash))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/fox.txt"}, summary.Copied)
+
+	b, err := t.dst.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	// Newer source, but same size.
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t.T(), os.Chtimes(filepath.Join(t.dir, "src/doc/dog.txt"), later, later))
+
+	summary, err = Sync(t.dst, t.src, WithCompare(CompareSize))
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), summary.Copied)
+
+	summary, err = Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/dog.txt"}, summary.Copied)
+}
+
+func (t *SyncTestSuite) TestCompareHashSubtrees() {
+	if err := os.MkdirAll(filepath.Join(t.dir, "src/pictures"), 0755); err != nil {
+		t.T().Fatal(err)
+	}
+	t.write("src/pictures/seals.txt", "seals")
+
+	_, err := Sync(t.dst, t.src, WithCompare(CompareHash))
+	assert.NoError(t.T(), err)
+
+	summary, err := Sync(t.dst, t.src, WithCompare(CompareHash), WithDelete(true))
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), summary.Copied)
+	assert.Empty(t.T(), summary.Deleted)
+	assert.Equal(t.T(), 3, summary.Unchanged)
+
+	t.write("src/pictures/seals.txt", "walrus")
+	summary, err = Sync(t.dst, t.src, WithCompare(CompareHash), WithDelete(true))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"pictures/seals.txt"}, summary.Copied)
+	assert.Empty(t.T(), summary.Deleted)
+	assert.Equal(t.T(), 2, summary.Unchanged)
+
+	b, err := t.dst.ReadFile("pictures/seals.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "walrus", string(b))
+}
+
+func (t *SyncTestSuite) TestDelete() {
+	if err := os.MkdirAll(filepath.Join(t.dir, "dst/old/nested"), 0755); err != nil {
+		t.T().Fatal(err)
+	}
+	t.write("dst/old/nested/cat.txt", "the cat sat")
+	t.write("dst/stale.txt", "stale")
+
+	summary, err := Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), summary.Deleted)
+
+	summary, err = Sync(t.dst, t.src, WithDelete(true), WithDryRun(true))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"old", "stale.txt"}, summary.Deleted)
+
+	_, err = t.dst.Stat("old")
+	assert.NoError(t.T(), err)
+
+	summary, err = Sync(t.dst, t.src, WithDelete(true))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"old", "stale.txt"}, summary.Deleted)
+
+	_, err = t.dst.Stat("old")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.dst.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+}
+
+func (t *SyncTestSuite) TestReplace() {
+	t.write("dst/doc", "not a directory")
+
+	summary, err := Sync(t.dst, t.src)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc"}, summary.Deleted)
+	assert.Equal(t.T(), []string{"doc"}, summary.Created)
+
+	fi, err := t.dst.Stat("doc")
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), fi.IsDir())
+}
+
+func (t *SyncTestSuite) TestDryRun() {
+	summary, err := Sync(t.dst, t.src, WithDryRun(true))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/dog.txt", "doc/fox.txt"}, summary.Copied)
+
+	_, err = t.dst.Stat("doc")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *SyncTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := Sync(t.dst, t.src, WithLogger(logger))
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[sync] copy" path=doc/fox.txt size=19`)
+
+	buf.Reset()
+	_, err = Sync(t.dst, t.src, WithCompare(CompareHash), WithLogger(logger))
+	assert.NoError(t.T(), err)
+	assert.Contains(t.T(), buf.String(), `msg="[sync] unchanged" path=.`)
+}
@@ -0,0 +1,396 @@
+// Package sync provides incremental, one-way synchronization of a tree from one file system to another.
+//
+// Sync compares the entries in the source and destination, copies only the files that differ, and optionally deletes
+// entries in the destination that do not exist in the source:
+//
+//	summary, err := sync.Sync(dst, src, sync.WithCompare(sync.CompareHash), sync.WithDelete(true))
+//
+// The trees are synchronized from the root of each file system. To synchronize a subtree, use fs.Chroot or the Sub
+// method of the file system.
+package sync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Enumeration of methods used to compare files.
+const (
+	// CompareModTime considers a file changed if its size differs, or if the source was modified after the destination.
+	CompareModTime Compare = iota
+
+	// CompareSize considers a file changed only if its size differs.
+	CompareSize
+
+	// CompareHash considers a file changed if its size or its SHA-256 digest differs. Directories whose subtrees have
+	// the same digest in the source and destination, as computed by fs.MerkleTree, are skipped without comparing their
+	// files individually.
+	CompareHash
+)
+
+// Compare defines the method used to determine whether a file differs between the source and the destination.
+type Compare int
+
+// Option defines an option for Sync.
+type Option func(*options)
+
+type options struct {
+	compare  Compare
+	delete   bool
+	dryRun   bool
+	logger   fs.Logger
+	preserve bool
+}
+
+// Summary reports the changes made by Sync.
+type Summary struct {
+	// Bytes is the number of bytes copied.
+	Bytes int64
+
+	// Copied contains the paths of the files that were copied, sorted by path.
+	Copied []string
+
+	// Created contains the paths of the directories that were created, sorted by path.
+	Created []string
+
+	// Deleted contains the paths of the entries that were deleted from the destination, sorted by path.
+	Deleted []string
+
+	// Unchanged is the number of files that were not copied because they did not differ.
+	Unchanged int
+}
+
+// Sync synchronizes dst with src, so that every file and directory in src exists with the same content in dst.
+//
+// Files are compared using the method set with WithCompare, and only files that differ are copied using fs.CopyFile.
+// Symbolic links in src are skipped. If an entry in dst has a different type than the corresponding entry in src, it
+// is replaced.
+func Sync(dst fs.FS, src gofs.FS, opts ...Option) (*Summary, error) {
+	if dst == nil || src == nil {
+		return nil, errors.New("sync: file system is required")
+	}
+
+	s := &syncer{
+		dst:       dst,
+		identical: make(map[string]bool),
+		opts:      &options{logger: fs.NopLogger(), preserve: true},
+		seen:      make(map[string]bool),
+		src:       src,
+		summary:   &Summary{},
+	}
+	for _, opt := range opts {
+		opt(s.opts)
+	}
+
+	if s.opts.compare == CompareHash {
+		srcTree, err := fs.MerkleTree(src, ".")
+		if err != nil {
+			return s.summary, err
+		}
+
+		dstTree, err := fs.MerkleTree(dst, ".")
+		if err != nil {
+			return s.summary, err
+		}
+		s.srcTree, s.dstTree = srcTree, dstTree
+	}
+
+	if err := gofs.WalkDir(src, ".", s.sync); err != nil {
+		return s.summary, err
+	}
+
+	if s.opts.delete {
+		if err := gofs.WalkDir(dst, ".", s.prune); err != nil {
+			return s.summary, err
+		}
+	}
+
+	sort.Strings(s.summary.Copied)
+	sort.Strings(s.summary.Created)
+	sort.Strings(s.summary.Deleted)
+	return s.summary, nil
+}
+
+// WithCompare sets the method used to compare files. The default is CompareModTime.
+func WithCompare(c Compare) Option {
+	return func(o *options) {
+		o.compare = c
+	}
+}
+
+// WithDelete sets whether entries in the destination that do not exist in the source are deleted. Entries are not
+// deleted by default.
+func WithDelete(delete bool) Option {
+	return func(o *options) {
+		o.delete = delete
+	}
+}
+
+// WithDryRun sets whether the changes are only reported in the Summary, without modifying the destination.
+func WithDryRun(dryRun bool) Option {
+	return func(o *options) {
+		o.dryRun = dryRun
+	}
+}
+
+// WithLogger sets the Logger used by Sync to log the changes that it makes. By default, nothing is logged.
+func WithLogger(logger fs.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithPreserveAttributes sets whether the mode and modification time of copied files are preserved, where supported by
+// the destination. Attributes are preserved by default, which allows CompareModTime to detect unchanged files exactly.
+func WithPreserveAttributes(preserve bool) Option {
+	return func(o *options) {
+		o.preserve = preserve
+	}
+}
+
+type syncer struct {
+	dst       fs.FS
+	dstTree   *fs.MerkleNode
+	identical map[string]bool
+	opts      *options
+	seen      map[string]bool
+	src       gofs.FS
+	srcTree   *fs.MerkleNode
+	summary   *Summary
+}
+
+func (s *syncer) sync(p string, d gofs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if d.Type()&gofs.ModeSymlink != 0 {
+		return nil
+	}
+	s.seen[p] = true
+
+	if d.IsDir() && s.srcTree != nil {
+		identical, err := s.unchanged(p)
+		if err != nil {
+			return err
+		}
+
+		if identical {
+			s.opts.logger.Debug("[sync] unchanged", "path", p)
+
+			s.identical[p] = true
+			return gofs.SkipDir
+		}
+	}
+
+	if p == "." {
+		return nil
+	}
+
+	fi, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	dfi, err := s.dst.Stat(p)
+	if err != nil && !errors.Is(err, gofs.ErrNotExist) && !errors.Is(err, fs.ErrNotDir) {
+		return err
+	}
+
+	if dfi != nil && dfi.IsDir() != fi.IsDir() {
+		s.opts.logger.Debug("[sync] replace", "path", p)
+
+		if err := s.remove(p); err != nil {
+			return err
+		}
+		dfi = nil
+	}
+
+	if fi.IsDir() {
+		if dfi != nil {
+			return nil
+		}
+
+		s.summary.Created = append(s.summary.Created, p)
+		if s.opts.dryRun {
+			return nil
+		}
+		return s.dst.Mkdir(p, fi.Mode().Perm())
+	}
+
+	if dfi != nil {
+		changed, err := s.changed(p, fi, dfi)
+		if err != nil {
+			return err
+		}
+
+		if !changed {
+			s.summary.Unchanged++
+			return nil
+		}
+	}
+
+	s.opts.logger.Debug("[sync] copy", "path", p, "size", fi.Size())
+
+	s.summary.Copied = append(s.summary.Copied, p)
+	s.summary.Bytes += fi.Size()
+	if s.opts.dryRun {
+		return nil
+	}
+	return fs.CopyFile(s.dst, p, s.src, p,
+		fs.WithOverwrite(fs.OverwriteAlways),
+		fs.WithPreserveAttributes(s.opts.preserve))
+}
+
+func (s *syncer) prune(p string, d gofs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if s.seen[p] {
+		if s.identical[p] {
+			return gofs.SkipDir
+		}
+		return nil
+	}
+
+	s.opts.logger.Debug("[sync] delete", "path", p)
+
+	if err := s.remove(p); err != nil {
+		return err
+	}
+
+	if d.IsDir() {
+		return gofs.SkipDir
+	}
+	return nil
+}
+
+// changed reports whether the file p differs between the source and destination.
+func (s *syncer) changed(p string, fi gofs.FileInfo, dfi gofs.FileInfo) (bool, error) {
+	if fi.Size() != dfi.Size() {
+		return true, nil
+	}
+
+	switch s.opts.compare {
+	case CompareModTime:
+		return fi.ModTime().After(dfi.ModTime()), nil
+	case CompareHash:
+		sum, err := digest(s.srcTree, p)
+		if err != nil {
+			return false, err
+		}
+
+		dsum, err := digest(s.dstTree, p)
+		if err != nil {
+			return false, err
+		}
+		return !bytes.Equal(sum, dsum), nil
+	}
+	return false, nil
+}
+
+// remove removes the entry p, and any children, from the destination.
+func (s *syncer) remove(p string) error {
+	s.summary.Deleted = append(s.summary.Deleted, p)
+	if s.opts.dryRun {
+		return nil
+	}
+	return s.dst.RemoveAll(p)
+}
+
+// unchanged reports whether the directory p has the same digest in the source and destination, in which case the
+// files in its subtree are counted as unchanged.
+func (s *syncer) unchanged(p string) (bool, error) {
+	dn, err := node(s.dstTree, p)
+	if err != nil || dn == nil || !dn.Mode().IsDir() {
+		return false, err
+	}
+
+	sn, err := node(s.srcTree, p)
+	if err != nil {
+		return false, err
+	}
+
+	sum, err := sn.Digest()
+	if err != nil {
+		return false, err
+	}
+
+	dsum, err := dn.Digest()
+	if err != nil {
+		return false, err
+	}
+
+	if !bytes.Equal(sum, dsum) {
+		return false, nil
+	}
+
+	files, err := countFiles(sn)
+	if err != nil {
+		return false, err
+	}
+	s.summary.Unchanged += files
+	return true, nil
+}
+
+// countFiles returns the number of regular files in the tree rooted at n.
+func countFiles(n *fs.MerkleNode) (int, error) {
+	if n.Mode().IsRegular() {
+		return 1, nil
+	}
+
+	children, err := n.Children()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, c := range children {
+		cc, err := countFiles(c)
+		if err != nil {
+			return 0, err
+		}
+		count += cc
+	}
+	return count, nil
+}
+
+// digest returns the SHA-256 digest of the content of the named file in the tree rooted at root.
+func digest(root *fs.MerkleNode, name string) ([]byte, error) {
+	n, err := node(root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if n == nil {
+		return nil, fmt.Errorf("sync: %w", &gofs.PathError{Op: "digest", Path: name, Err: gofs.ErrNotExist})
+	}
+	return n.Digest()
+}
+
+// node returns the node for the named entry in the tree rooted at root, or nil if the entry does not exist.
+func node(root *fs.MerkleNode, name string) (*fs.MerkleNode, error) {
+	n := root
+	if name == "." {
+		return n, nil
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		c, err := n.Child(elem)
+		if err != nil || c == nil {
+			return nil, err
+		}
+		n = c
+	}
+	return n, nil
+}
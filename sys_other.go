@@ -0,0 +1,13 @@
+//go:build !unix
+
+package fs
+
+// sysAttributes returns no options, since platform-specific file attributes are not supported on this platform.
+func sysAttributes(_ any) []func(*Attribute) {
+	return nil
+}
+
+// sysFileID returns false, since file identifiers are not supported on this platform.
+func sysFileID(_ any) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package fs
+
+import (
+	"syscall"
+)
+
+// sysAttributes returns the options for the attributes provided by the *syscall.Stat_t returned by the Sys method of
+// a gofs.FileInfo.
+func sysAttributes(sys any) []func(*Attribute) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	return []func(*Attribute){
+		WithBlocks(uint64(st.Blocks)),
+		WithBlockSize(uint64(st.Blksize)),
+		WithGID(st.Gid),
+		WithInode(uint64(st.Ino)),
+		WithNlink(uint64(st.Nlink)),
+		WithRdev(uint64(st.Rdev)),
+		WithUID(st.Uid),
+	}
+}
+
+// sysFileID returns the device and inode numbers provided by the *syscall.Stat_t returned by the Sys method of a
+// gofs.FileInfo.
+func sysFileID(sys any) (fileID, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
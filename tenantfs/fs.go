@@ -0,0 +1,187 @@
+package tenantfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// ErrNoTenant is returned when a tenant is not provided by the context of an operation.
+var ErrNoTenant = errors.New("tenantfs: tenant is required")
+
+// QuotaHook defines the behavior for enforcing a quota on the bytes stored by each tenant, such as by tracking usage in
+// an external billing or metering system.
+//
+// The context provided to TenantFS.FS is passed to every call, so that the hook can use values from the request that
+// produced the operation.
+type QuotaHook interface {
+	// Reserve is called before n bytes are added to the files of tenant. An error rejects the operation, and should wrap
+	// fs.ErrQuotaExceeded if the quota of the tenant would be exceeded.
+	Reserve(ctx context.Context, tenant string, n int64) error
+
+	// Release is called after n bytes are removed from the files of tenant, or when bytes that were reserved are not
+	// used.
+	Release(ctx context.Context, tenant string, n int64)
+}
+
+// TenantFS multi-tenant provider that maps each tenant onto an isolated subtree of a backing file system.
+//
+// The subtree of a tenant is the directory named by the tenant ID in the directory set using WithDir, and is created
+// when the tenant first accesses it. The FS for a tenant is rooted at its subtree using fs.Chroot, so that every path
+// is resolved under the subtree and a tenant can never name a path outside of it. Symbolic links are resolved by the
+// backing file system, so links should not be created in the backing file system if it follows them.
+type TenantFS struct {
+	backing fs.FS
+	dir     string
+	logger  fs.Logger
+	perm    gofs.FileMode
+	quota   QuotaHook
+}
+
+// New creates a new TenantFS that maps tenants onto subtrees of backing.
+func New(backing fs.FS, options ...func(*TenantFS)) (*TenantFS, error) {
+	if backing == nil {
+		return nil, errors.New("tenantfs: backing file system is required")
+	}
+
+	t := &TenantFS{backing: backing, dir: ".", logger: fs.NopLogger(), perm: 0755}
+	for _, opt := range options {
+		opt(t)
+	}
+
+	t.dir = gopath.Clean(strings.TrimSpace(t.dir))
+	if !gofs.ValidPath(t.dir) {
+		return nil, fmt.Errorf("tenantfs: directory %q is invalid: %w", t.dir, fs.ErrInvalid)
+	}
+
+	t.logger.Debug("[tenantfs] new", "provider", backing.Provider(), "dir", t.dir, "quota", t.quota != nil)
+	return t, nil
+}
+
+// NewContext returns a copy of ctx that carries the tenant ID.
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant ID carried by ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// Close closes the backing file system.
+func (t *TenantFS) Close() error {
+	return t.backing.Close()
+}
+
+// FS returns the file system for the tenant carried by ctx, which is set using NewContext. An error wrapping
+// ErrNoTenant is returned if ctx does not carry a tenant, and an error wrapping fs.ErrInvalid is returned if the tenant
+// ID is not a single path element.
+//
+// Closing the returned FS does not close the backing file system.
+func (t *TenantFS) FS(ctx context.Context) (fs.FS, error) {
+	tenant, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	dir, err := t.tenantDir(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.backing.MkdirAll(dir, t.perm); err != nil {
+		return nil, err
+	}
+
+	root, err := fs.Chroot(t.backing, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.quota == nil {
+		return root, nil
+	}
+	return &tenantFS{FS: root, ctx: ctx, quota: t.quota, tenant: tenant}, nil
+}
+
+// RemoveTenant removes the subtree of the tenant and every entry it contains. The QuotaHook is not called, since the
+// usage of a removed tenant is expected to be discarded by the caller.
+func (t *TenantFS) RemoveTenant(tenant string) error {
+	dir, err := t.tenantDir(tenant)
+	if err != nil {
+		return err
+	}
+	return t.backing.RemoveAll(dir)
+}
+
+// Tenants returns the IDs of the tenants that have a subtree in the backing file system, sorted by ID.
+func (t *TenantFS) Tenants() ([]string, error) {
+	entries, err := t.backing.ReadDir(t.dir)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tenants []string
+	for _, e := range entries {
+		if e.IsDir() {
+			tenants = append(tenants, e.Name())
+		}
+	}
+	return tenants, nil
+}
+
+// tenantDir returns the path of the subtree for tenant in the backing file system.
+func (t *TenantFS) tenantDir(tenant string) (string, error) {
+	if tenant == "" {
+		return "", ErrNoTenant
+	}
+
+	if tenant == "." || tenant == ".." || strings.ContainsAny(tenant, `/\`) || !gofs.ValidPath(tenant) {
+		return "", fmt.Errorf("tenantfs: tenant %q is invalid: %w", tenant, fs.ErrInvalid)
+	}
+	return gopath.Join(t.dir, tenant), nil
+}
+
+// WithDir sets the directory of the backing file system that contains the subtree of each tenant. The default is the
+// root directory.
+func WithDir(dir string) func(*TenantFS) {
+	return func(t *TenantFS) {
+		t.dir = dir
+	}
+}
+
+// WithLogger sets the Logger used by a TenantFS to log the configuration that it is created with. By default,
+// nothing is logged.
+func WithLogger(logger fs.Logger) func(*TenantFS) {
+	return func(t *TenantFS) {
+		if logger != nil {
+			t.logger = logger
+		}
+	}
+}
+
+// WithPerm sets the permissions used to create the subtree of each tenant. The default is 0755.
+func WithPerm(perm gofs.FileMode) func(*TenantFS) {
+	return func(t *TenantFS) {
+		t.perm = perm
+	}
+}
+
+// WithQuotaHook sets the QuotaHook that is called for the operations of each tenant that add or remove bytes.
+func WithQuotaHook(hook QuotaHook) func(*TenantFS) {
+	return func(t *TenantFS) {
+		t.quota = hook
+	}
+}
+
+type contextKey struct{}
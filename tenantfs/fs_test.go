@@ -0,0 +1,177 @@
+package tenantfs
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// TenantFSTestSuite ...
+type TenantFSTestSuite struct {
+	suite.Suite
+	backing fs.FS
+	quota   *quota
+	tfs     *TenantFS
+}
+
+func NewTenantFSTestSuite() *TenantFSTestSuite {
+	return &TenantFSTestSuite{}
+}
+
+func (t *TenantFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("secret.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.backing = backing
+	t.quota = &quota{limit: 32, usage: make(map[string]int64)}
+
+	tfs, err := New(backing, WithDir("tenants"), WithQuotaHook(t.quota))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.tfs = tfs
+}
+
+func TestTenantFSTestSuite(t *testing.T) {
+	suite.Run(t, NewTenantFSTestSuite())
+}
+
+func (t *TenantFSTestSuite) TestFS() {
+	_, err := t.tfs.FS(context.Background())
+	assert.ErrorIs(t.T(), err, ErrNoTenant)
+
+	for _, tenant := range []string{".", "..", "a/b", `a\b`} {
+		_, err := t.tfs.FS(NewContext(context.Background(), tenant))
+		assert.ErrorIs(t.T(), err, fs.ErrInvalid, tenant)
+	}
+
+	a, err := t.tfs.FS(NewContext(context.Background(), "a"))
+	assert.NoError(t.T(), err)
+
+	b, err := t.tfs.FS(NewContext(context.Background(), "b"))
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), a.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), b.WriteFile("dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t.T(), fstest.TestFS(a, "fox.txt"))
+
+	content, err := t.backing.ReadFile("tenants/a/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(content))
+
+	_, err = a.Stat("dog.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	for _, name := range []string{"../b/dog.txt", "/secret.txt", "../../secret.txt"} {
+		_, err := a.ReadFile(name)
+		assert.ErrorIs(t.T(), err, fs.ErrInvalid, name)
+	}
+
+	tenants, err := t.tfs.Tenants()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"a", "b"}, tenants)
+
+	assert.NoError(t.T(), t.tfs.RemoveTenant("b"))
+
+	tenants, err = t.tfs.Tenants()
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"a"}, tenants)
+}
+
+func (t *TenantFSTestSuite) TestQuotaHook() {
+	a, err := t.tfs.FS(NewContext(context.Background(), "a"))
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), a.WriteFile("fox.txt", []byte("the quick brown fox"), 0644))
+	assert.Equal(t.T(), int64(19), t.quota.used("a"))
+
+	assert.ErrorIs(t.T(), a.WriteFile("dog.txt", []byte("jumps over the lazy dog"), 0644), fs.ErrQuotaExceeded)
+	assert.Equal(t.T(), int64(19), t.quota.used("a"))
+
+	assert.NoError(t.T(), a.WriteFile("fox.txt", []byte("the fox"), 0644))
+	assert.Equal(t.T(), int64(7), t.quota.used("a"))
+
+	f, err := a.Create("dog.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte("jumps over the lazy dog"))
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte("again"))
+	assert.ErrorIs(t.T(), err, fs.ErrQuotaExceeded)
+	assert.NoError(t.T(), f.Close())
+	assert.Equal(t.T(), int64(30), t.quota.used("a"))
+
+	assert.NoError(t.T(), a.Rename("fox.txt", "dog.txt"))
+	assert.Equal(t.T(), int64(7), t.quota.used("a"))
+
+	sub, err := a.Sub(".")
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), sub.(fs.FS).WriteFile("cat.txt", []byte("the cat"), 0644))
+	assert.Equal(t.T(), int64(14), t.quota.used("a"))
+
+	assert.NoError(t.T(), a.Remove("cat.txt"))
+	assert.Equal(t.T(), int64(7), t.quota.used("a"))
+
+	assert.NoError(t.T(), a.RemoveAll("."))
+	assert.Equal(t.T(), int64(0), t.quota.used("a"))
+}
+
+// quota is a QuotaHook that limits the bytes stored by each tenant.
+type quota struct {
+	limit int64
+	mutex sync.Mutex
+	usage map[string]int64
+}
+
+func (q *quota) Release(_ context.Context, tenant string, n int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.usage[tenant] -= n
+}
+
+func (q *quota) Reserve(_ context.Context, tenant string, n int64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.usage[tenant]+n > q.limit {
+		return fs.ErrQuotaExceeded
+	}
+	q.usage[tenant] += n
+	return nil
+}
+
+func (q *quota) used(tenant string) int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.usage[tenant]
+}
+
+func (t *TenantFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := New(t.backing, WithDir("tenants"), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Contains(t.T(), buf.String(), `msg="[tenantfs] new" provider=`+t.backing.Provider()+` dir=tenants quota=false`)
+	assert.Equal(t.T(), fs.NopLogger(), t.tfs.logger)
+}
@@ -0,0 +1,201 @@
+package tenantfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var (
+	_ fs.File = (*file)(nil)
+	_ fs.FS   = (*tenantFS)(nil)
+)
+
+// tenantFS calls the QuotaHook of a TenantFS for the operations of a tenant that add or remove bytes. Every operation
+// is passed through to the FS rooted at the subtree of the tenant.
+type tenantFS struct {
+	fs.FS
+	ctx    context.Context
+	quota  QuotaHook
+	tenant string
+}
+
+func (t *tenantFS) Close() error {
+	return nil
+}
+
+func (t *tenantFS) Create(name string) (fs.File, error) {
+	return t.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// OpenFile opens the named file. Writes to a File opened for writing reserve the length of the written content as the
+// worst-case growth of the file, and release the bytes that were not used once the write completes.
+func (t *tenantFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		return t.FS.OpenFile(name, flag, perm)
+	}
+
+	size := t.size(name)
+	f, err := t.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&fs.O_TRUNC != 0 && size > 0 {
+		t.release(size)
+		size = 0
+	}
+	return &file{File: f, name: name, size: size, tfs: t}, nil
+}
+
+func (t *tenantFS) Remove(name string) error {
+	size := t.size(name)
+	if err := t.FS.Remove(name); err != nil {
+		return err
+	}
+	t.release(size)
+	return nil
+}
+
+func (t *tenantFS) RemoveAll(path string) error {
+	size, _ := t.measure(path)
+	if err := t.FS.RemoveAll(path); err != nil {
+		return err
+	}
+	t.release(size)
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath, and releases the bytes of the file replaced by newpath.
+func (t *tenantFS) Rename(oldpath string, newpath string) error {
+	size := t.size(newpath)
+	if err := t.FS.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	if oldpath != newpath {
+		t.release(size)
+	}
+	return nil
+}
+
+// Sub returns the FS rooted at dir in the subtree of the tenant, which calls the same QuotaHook.
+func (t *tenantFS) Sub(dir string) (gofs.FS, error) {
+	sub, err := t.FS.Sub(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if fsys, ok := sub.(fs.FS); ok {
+		return &tenantFS{FS: fsys, ctx: t.ctx, quota: t.quota, tenant: t.tenant}, nil
+	}
+	return sub, nil
+}
+
+func (t *tenantFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	delta := int64(len(data)) - t.size(name)
+	if delta > 0 {
+		if err := t.reserve("writeFile", name, delta); err != nil {
+			return err
+		}
+	}
+
+	if err := t.FS.WriteFile(name, data, perm); err != nil {
+		if delta > 0 {
+			t.release(delta)
+		}
+		return err
+	}
+
+	if delta < 0 {
+		t.release(-delta)
+	}
+	return nil
+}
+
+// measure returns the total size of the regular files in path.
+func (t *tenantFS) measure(path string) (int64, error) {
+	var size int64
+	err := gofs.WalkDir(t.FS, path, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	return size, err
+}
+
+// release calls the QuotaHook to release n bytes.
+func (t *tenantFS) release(n int64) {
+	if n > 0 {
+		t.quota.Release(t.ctx, t.tenant, n)
+	}
+}
+
+// reserve calls the QuotaHook to reserve n bytes for the named file.
+func (t *tenantFS) reserve(op string, name string, n int64) error {
+	if err := t.quota.Reserve(t.ctx, t.tenant, n); err != nil {
+		return fmt.Errorf("tenantfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return nil
+}
+
+// size returns the size of the named file if it is a regular file, and 0 otherwise.
+func (t *tenantFS) size(name string) int64 {
+	fi, err := t.FS.Stat(name)
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0
+	}
+	return fi.Size()
+}
+
+// file reserves the bytes written to a file opened for writing through a tenantFS.
+type file struct {
+	fs.File
+	name string
+	size int64
+	tfs  *tenantFS
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return f.File.Write(b)
+	}
+
+	reserved := int64(len(b))
+	if err := f.tfs.reserve("write", f.name, reserved); err != nil {
+		return 0, err
+	}
+
+	n, err := f.File.Write(b)
+
+	// The growth of the file is determined from its size after the write, since a write within the existing content
+	// of the file does not use additional bytes.
+	growth := int64(n)
+	if fi, serr := f.File.Stat(); serr == nil {
+		growth = max(fi.Size()-f.size, 0)
+		f.size = fi.Size()
+	} else {
+		f.size += growth
+	}
+	f.tfs.release(reserved - min(growth, reserved))
+	return n, err
+}
@@ -0,0 +1,39 @@
+package timeoutfs
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+)
+
+// TimeoutError records an operation of a TimeoutFS that did not complete before its deadline.
+//
+// A TimeoutError matches fs.ErrTimeout and os.ErrDeadlineExceeded using errors.Is.
+type TimeoutError struct {
+	// Op is the name of the operation that timed out (e.g. "readFile" or "write").
+	Op string
+
+	// Path is the path of the file that the operation was performed on.
+	Path string
+
+	// Duration is the timeout of the operation.
+	Duration time.Duration
+}
+
+// Error returns a string representation of the TimeoutError.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeoutfs: %s %s: %s after %s", e.Op, e.Path, fs.ErrTimeout, e.Duration)
+}
+
+// Is reports whether target is fs.ErrTimeout or os.ErrDeadlineExceeded.
+func (e *TimeoutError) Is(target error) bool {
+	return target == fs.ErrTimeout || target == os.ErrDeadlineExceeded
+}
+
+// Timeout reports that the error is caused by a timeout, for compatibility with code that inspects errors using the
+// Timeout method of net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
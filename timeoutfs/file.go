@@ -0,0 +1,59 @@
+package timeoutfs
+
+import (
+	"context"
+	"io"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+var _ fs.File = (*file)(nil)
+
+// file applies the deadlines of a TimeoutFS to the operations of a File opened through it.
+type file struct {
+	fs.File
+	cancel context.CancelCauseFunc
+	ctx    context.Context
+	name   string
+	tfs    *TimeoutFS
+}
+
+func (f *file) Close() error {
+	defer f.cancel(nil)
+	return f.File.Close()
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	return deadline(f.tfs, f.ctx, f.cancel, DataRead, "read", f.name, func() (int, error) {
+		return f.File.Read(b)
+	})
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	return deadline(f.tfs, f.ctx, f.cancel, DataRead, "readAt", f.name, func() (int, error) {
+		return f.File.ReadAt(b, off)
+	})
+}
+
+func (f *file) ReadDir(n int) ([]gofs.DirEntry, error) {
+	return deadline(f.tfs, f.ctx, f.cancel, MetadataRead, "readDir", f.name, func() ([]gofs.DirEntry, error) {
+		return f.File.ReadDir(n)
+	})
+}
+
+// ReadFrom copies from r using Write, so that the deadline applies to each write rather than to the whole copy.
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *file) Stat() (gofs.FileInfo, error) {
+	return deadline(f.tfs, f.ctx, f.cancel, MetadataRead, "stat", f.name, f.File.Stat)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	return deadline(f.tfs, f.ctx, f.cancel, DataWrite, "write", f.name, func() (int, error) {
+		return f.File.Write(b)
+	})
+}
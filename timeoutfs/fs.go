@@ -0,0 +1,246 @@
+package timeoutfs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+)
+
+// Class identifies a class of operations that share a deadline.
+type Class int
+
+// Enumeration of the classes of operations of a TimeoutFS.
+const (
+	// MetadataRead is the class of operations that read the metadata of entries: Glob, Open, OpenFile for reading,
+	// ReadDir, and Stat, and the ReadDir and Stat methods of a File.
+	MetadataRead Class = iota
+
+	// MetadataWrite is the class of operations that change the structure of the tree: Create, Mkdir, MkdirAll, OpenFile
+	// for writing, Remove, RemoveAll, and Rename.
+	MetadataWrite
+
+	// DataRead is the class of operations that read content: ReadFile, and the Read and ReadAt methods of a File.
+	DataRead
+
+	// DataWrite is the class of operations that write content: WriteFile, and the ReadFrom and Write methods of a File.
+	DataWrite
+
+	classes = iota
+)
+
+var _ fs.FS = (*TimeoutFS)(nil)
+
+// TimeoutFS deadline-enforcing provider that implements fs.FS.
+//
+// A TimeoutFS applies a deadline to each operation performed on a backing file system, which is set for each Class of
+// operations using WithTimeout, so that for example listings can be given less time than large reads. Operations are
+// bound to their deadline using fs.WithContext, and an operation that does not complete before its deadline returns a
+// *TimeoutError, which matches fs.ErrTimeout. Operations of a class without a timeout have no deadline.
+//
+// Since a File may have been left at an unknown offset by an operation that timed out, every later operation of the
+// File returns the *TimeoutError, and the File should be closed.
+type TimeoutFS struct {
+	backing  fs.FS
+	logger   fs.Logger
+	timeouts [classes]time.Duration
+}
+
+// New creates a new TimeoutFS that applies deadlines to the operations performed on backing.
+func New(backing fs.FS, options ...func(*TimeoutFS)) (*TimeoutFS, error) {
+	if backing == nil {
+		return nil, errors.New("timeoutfs: backing file system is required")
+	}
+
+	t := &TimeoutFS{backing: backing, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(t)
+	}
+
+	t.logger.Debug("[timeoutfs] new",
+		"provider", backing.Provider(),
+		"metadata_read", t.timeouts[MetadataRead],
+		"metadata_write", t.timeouts[MetadataWrite],
+		"data_read", t.timeouts[DataRead],
+		"data_write", t.timeouts[DataWrite])
+	return t, nil
+}
+
+// Close closes the backing file system.
+func (t *TimeoutFS) Close() error {
+	return t.backing.Close()
+}
+
+// Create ...
+func (t *TimeoutFS) Create(name string) (fs.File, error) {
+	return t.open(MetadataWrite, "create", name, func(fsys fs.FS) (fs.File, error) { return fsys.Create(name) })
+}
+
+// Glob ...
+func (t *TimeoutFS) Glob(pattern string) ([]string, error) {
+	return timed(t, MetadataRead, "glob", pattern, func(fsys fs.FS) ([]string, error) { return fsys.Glob(pattern) })
+}
+
+// Mkdir ...
+func (t *TimeoutFS) Mkdir(name string, perm gofs.FileMode) error {
+	return t.do(MetadataWrite, "mkdir", name, func(fsys fs.FS) error { return fsys.Mkdir(name, perm) })
+}
+
+// MkdirAll ...
+func (t *TimeoutFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return t.do(MetadataWrite, "mkdirAll", path, func(fsys fs.FS) error { return fsys.MkdirAll(path, perm) })
+}
+
+// Open ...
+func (t *TimeoutFS) Open(name string) (gofs.File, error) {
+	return t.open(MetadataRead, "open", name, func(fsys fs.FS) (fs.File, error) {
+		return fsys.OpenFile(name, fs.O_RDONLY, 0)
+	})
+}
+
+// OpenFile opens the named file. Opening a file for writing is a MetadataWrite operation, and opening it for reading is
+// a MetadataRead operation.
+func (t *TimeoutFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	class := MetadataRead
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) != 0 {
+		class = MetadataWrite
+	}
+	return t.open(class, "open", name, func(fsys fs.FS) (fs.File, error) { return fsys.OpenFile(name, flag, perm) })
+}
+
+// PathSeparator ...
+func (t *TimeoutFS) PathSeparator() string {
+	return t.backing.PathSeparator()
+}
+
+// Provider ...
+func (t *TimeoutFS) Provider() string {
+	return t.backing.Provider()
+}
+
+// ReadDir ...
+func (t *TimeoutFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return timed(t, MetadataRead, "readDir", name, func(fsys fs.FS) ([]gofs.DirEntry, error) {
+		return fsys.ReadDir(name)
+	})
+}
+
+// ReadFile ...
+func (t *TimeoutFS) ReadFile(name string) ([]byte, error) {
+	return timed(t, DataRead, "readFile", name, func(fsys fs.FS) ([]byte, error) { return fsys.ReadFile(name) })
+}
+
+// Remove ...
+func (t *TimeoutFS) Remove(name string) error {
+	return t.do(MetadataWrite, "remove", name, func(fsys fs.FS) error { return fsys.Remove(name) })
+}
+
+// RemoveAll ...
+func (t *TimeoutFS) RemoveAll(path string) error {
+	return t.do(MetadataWrite, "removeAll", path, func(fsys fs.FS) error { return fsys.RemoveAll(path) })
+}
+
+// Rename ...
+func (t *TimeoutFS) Rename(oldpath string, newpath string) error {
+	return t.do(MetadataWrite, "rename", oldpath, func(fsys fs.FS) error { return fsys.Rename(oldpath, newpath) })
+}
+
+// Root ...
+func (t *TimeoutFS) Root() (string, error) {
+	return t.backing.Root()
+}
+
+// Stat ...
+func (t *TimeoutFS) Stat(name string) (gofs.FileInfo, error) {
+	return timed(t, MetadataRead, "stat", name, func(fsys fs.FS) (gofs.FileInfo, error) { return fsys.Stat(name) })
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot, so that the operations of the sub-tree have the same
+// deadlines as those of the TimeoutFS.
+func (t *TimeoutFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(t, dir)
+}
+
+// WriteFile ...
+func (t *TimeoutFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	return t.do(DataWrite, "writeFile", name, func(fsys fs.FS) error { return fsys.WriteFile(name, data, perm) })
+}
+
+// do performs op in the same way as timed for operations that only return an error.
+func (t *TimeoutFS) do(class Class, name string, path string, op func(fs.FS) error) error {
+	_, err := timed(t, class, name, path, func(fsys fs.FS) (struct{}, error) { return struct{}{}, op(fsys) })
+	return err
+}
+
+// open opens the file at path using op, which is an operation of class. The File is bound to a context that is only
+// canceled when an operation of the File times out, or when the File is closed.
+func (t *TimeoutFS) open(class Class, name string, path string, op func(fs.FS) (fs.File, error)) (fs.File, error) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	fsys := fs.WithContext(ctx, t.backing)
+
+	f := &file{cancel: cancel, ctx: ctx, name: path, tfs: t}
+	ff, err := deadline(t, f.ctx, f.cancel, class, name, path, func() (fs.File, error) { return op(fsys) })
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	f.File = ff
+	return f, nil
+}
+
+// WithLogger sets the Logger used by a TimeoutFS to log its operation and the operations that time out. By default,
+// nothing is logged.
+func WithLogger(logger fs.Logger) func(*TimeoutFS) {
+	return func(t *TimeoutFS) {
+		if logger != nil {
+			t.logger = logger
+		}
+	}
+}
+
+// WithTimeout sets the deadline of the operations of class. Operations of the class have no deadline if timeout is
+// less than or equal to zero, which is the default.
+func WithTimeout(class Class, timeout time.Duration) func(*TimeoutFS) {
+	return func(t *TimeoutFS) {
+		if class >= 0 && class < classes {
+			t.timeouts[class] = timeout
+		}
+	}
+}
+
+// timed performs op, which is an operation of class named name on path, using a view of the backing file system bound
+// to the deadline of class.
+func timed[T any](t *TimeoutFS, class Class, name string, path string, op func(fs.FS) (T, error)) (T, error) {
+	if t.timeouts[class] <= 0 {
+		return op(t.backing)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	return deadline(t, ctx, cancel, class, name, path, func() (T, error) { return op(fs.WithContext(ctx, t.backing)) })
+}
+
+// deadline performs op, which is bound to ctx, canceling ctx with a *TimeoutError if op does not complete within the
+// deadline of class. The *TimeoutError is returned if op fails once ctx was canceled by the deadline.
+func deadline[T any](t *TimeoutFS, ctx context.Context, cancel context.CancelCauseFunc, class Class, name string, path string, op func() (T, error)) (T, error) {
+	if timeout := t.timeouts[class]; timeout > 0 {
+		terr := &TimeoutError{Op: name, Path: path, Duration: timeout}
+		timer := time.AfterFunc(timeout, func() {
+			cancel(terr)
+		})
+		defer timer.Stop()
+	}
+
+	result, err := op()
+	if err != nil {
+		var terr *TimeoutError
+		if cause := context.Cause(ctx); errors.As(cause, &terr) {
+			t.logger.Debug("[timeoutfs] timeout", "op", name, "path", path, "error", terr)
+			return result, terr
+		}
+	}
+	return result, err
+}
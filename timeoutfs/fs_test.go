@@ -0,0 +1,163 @@
+package timeoutfs
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// slowFS blocks reads of content until release is closed.
+type slowFS struct {
+	fs.FS
+	release chan struct{}
+}
+
+func (s *slowFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	f, err := s.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &slowFile{File: f, release: s.release}, nil
+}
+
+func (s *slowFS) ReadFile(name string) ([]byte, error) {
+	<-s.release
+	return s.FS.ReadFile(name)
+}
+
+type slowFile struct {
+	fs.File
+	release chan struct{}
+}
+
+func (s *slowFile) Read(b []byte) (int, error) {
+	<-s.release
+	return s.File.Read(b)
+}
+
+// TimeoutFSTestSuite ...
+type TimeoutFSTestSuite struct {
+	suite.Suite
+	backing *slowFS
+	tfs     *TimeoutFS
+}
+
+func NewTimeoutFSTestSuite() *TimeoutFSTestSuite {
+	return &TimeoutFSTestSuite{}
+}
+
+func (t *TimeoutFSTestSuite) SetupTest() {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = &slowFS{FS: mfs, release: make(chan struct{})}
+
+	tfs, err := New(t.backing, WithTimeout(DataRead, 20*time.Millisecond), WithTimeout(MetadataRead, time.Minute))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.tfs = tfs
+}
+
+func (t *TimeoutFSTestSuite) TearDownTest() {
+	select {
+	case <-t.backing.release:
+	default:
+		close(t.backing.release)
+	}
+}
+
+func TestTimeoutFSTestSuite(t *testing.T) {
+	suite.Run(t, NewTimeoutFSTestSuite())
+}
+
+func (t *TimeoutFSTestSuite) TestFS() {
+	close(t.backing.release)
+	assert.NoError(t.T(), fstest.TestFS(t.tfs, "doc/fox.txt"))
+}
+
+func (t *TimeoutFSTestSuite) TestTimeout() {
+	_, err := t.tfs.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, fs.ErrTimeout)
+	assert.ErrorIs(t.T(), err, os.ErrDeadlineExceeded)
+
+	var terr *TimeoutError
+	if assert.ErrorAs(t.T(), err, &terr) {
+		assert.Equal(t.T(), "readFile", terr.Op)
+		assert.Equal(t.T(), "doc/fox.txt", terr.Path)
+		assert.Equal(t.T(), 20*time.Millisecond, terr.Duration)
+	}
+
+	// Operations of other classes are not affected by the deadline of reads.
+	fi, err := t.tfs.Stat("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(19), fi.Size())
+	assert.NoError(t.T(), t.tfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+}
+
+func (t *TimeoutFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tfs, err := New(t.backing, WithTimeout(DataRead, 20*time.Millisecond), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = tfs.ReadFile("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, fs.ErrTimeout)
+	assert.Contains(t.T(), buf.String(), `msg="[timeoutfs] timeout" op=readFile path=doc/fox.txt`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.tfs.logger)
+}
+
+func (t *TimeoutFSTestSuite) TestFileTimeout() {
+	f, err := t.tfs.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Read(make([]byte, 8))
+	assert.ErrorIs(t.T(), err, fs.ErrTimeout)
+
+	// A File whose operation timed out can only be closed.
+	_, err = f.Stat()
+	assert.ErrorIs(t.T(), err, fs.ErrTimeout)
+	assert.NoError(t.T(), f.Close())
+
+	close(t.backing.release)
+
+	f, err = t.tfs.Open("doc/fox.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	b := make([]byte, 9)
+	n, err := f.Read(b)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick", string(b[:n]))
+	assert.NoError(t.T(), f.Close())
+}
+
+func (t *TimeoutFSTestSuite) TestError() {
+	err := &TimeoutError{Op: "stat", Path: "doc", Duration: time.Second}
+	assert.EqualError(t.T(), err, "timeoutfs: stat doc: operation timed out after 1s")
+	assert.ErrorIs(t.T(), err, fs.ErrTimeout)
+	assert.False(t.T(), errors.Is(err, gofs.ErrNotExist))
+}
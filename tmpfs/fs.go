@@ -0,0 +1,455 @@
+package tmpfs
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	gofs "io/fs"
+)
+
+var _ fs.FS = (*TmpFS)(nil)
+
+// TmpFS capacity-bounded in-memory provider that implements fs.FS.
+//
+// A TmpFS stores files using a memfs.MemFS, and enforces a hard capacity on the total size of the files it contains.
+// When a write would exceed the capacity, the least-recently-used files are evicted until the write fits. Files are
+// used when they are opened or read, and files that are open for writing are never evicted.
+//
+// Each eviction is reported as an fs.Event with the operation fs.EventEvict to the handler set using
+// WithEvictionHandler.
+type TmpFS struct {
+	capacity  int64
+	evictions uint64
+	logger    fs.Logger
+	lru       *list.List
+	mfs       *memfs.MemFS
+	mutex     sync.Mutex
+	now       func() time.Time
+	onEvict   func(fs.Event)
+	used      int64
+	usage     map[string]*list.Element
+}
+
+// usage records the size of a file and the number of handles that have it open for writing.
+type usage struct {
+	name   string
+	pinned int
+	size   int64
+}
+
+// New creates a new TmpFS with the provided capacity in bytes.
+func New(capacity int64, options ...func(*TmpFS)) (*TmpFS, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("tmpfs: invalid capacity: %d", capacity)
+	}
+
+	t := &TmpFS{
+		capacity: capacity,
+		logger:   fs.NopLogger(),
+		lru:      list.New(),
+		now:      time.Now,
+		usage:    make(map[string]*list.Element),
+	}
+	for _, opt := range options {
+		opt(t)
+	}
+
+	mfs, err := memfs.New(memfs.WithLogger(t.logger))
+	if err != nil {
+		return nil, err
+	}
+	t.mfs = mfs
+	return t, nil
+}
+
+// Capacity returns the capacity of the TmpFS in bytes.
+func (t *TmpFS) Capacity() int64 {
+	return t.capacity
+}
+
+// Close ...
+func (t *TmpFS) Close() error {
+	return t.mfs.Close()
+}
+
+// Create ...
+func (t *TmpFS) Create(name string) (fs.File, error) {
+	return t.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Evictions returns the number of files that have been evicted.
+func (t *TmpFS) Evictions() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.evictions
+}
+
+// Glob ...
+func (t *TmpFS) Glob(pattern string) ([]string, error) {
+	return t.mfs.Glob(pattern)
+}
+
+// Mkdir ...
+func (t *TmpFS) Mkdir(name string, perm gofs.FileMode) error {
+	return t.mfs.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (t *TmpFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return t.mfs.MkdirAll(path, perm)
+}
+
+// Open ...
+func (t *TmpFS) Open(name string) (gofs.File, error) {
+	f, err := t.mfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	t.touch(name)
+	return f, nil
+}
+
+// OpenFile opens the named file. A file opened for writing is not evicted until it is closed.
+func (t *TmpFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		f, err := t.mfs.OpenFile(name, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+		t.touch(name)
+		return f, nil
+	}
+
+	name, err := t.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	f, err := t.mfs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return f, nil
+	}
+
+	u := t.track(name, fi.Size())
+	u.pinned++
+	return &file{File: f, name: name, tfs: t}, nil
+}
+
+// PathSeparator ...
+func (t *TmpFS) PathSeparator() string {
+	return t.mfs.PathSeparator()
+}
+
+// Provider ...
+func (t *TmpFS) Provider() string {
+	return "tmpfs"
+}
+
+// ReadDir ...
+func (t *TmpFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return t.mfs.ReadDir(name)
+}
+
+// ReadFile ...
+func (t *TmpFS) ReadFile(name string) ([]byte, error) {
+	b, err := t.mfs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t.touch(name)
+	return b, nil
+}
+
+// Remove ...
+func (t *TmpFS) Remove(name string) error {
+	name, err := t.clean("remove", name)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.mfs.Remove(name); err != nil {
+		return err
+	}
+	t.untrack(name)
+	return nil
+}
+
+// RemoveAll ...
+func (t *TmpFS) RemoveAll(path string) error {
+	path, err := t.clean("removeAll", path)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.mfs.RemoveAll(path); err != nil {
+		return err
+	}
+
+	for _, name := range t.tracked(path) {
+		t.untrack(name)
+	}
+	return nil
+}
+
+// Rename ...
+func (t *TmpFS) Rename(oldpath string, newpath string) error {
+	oldpath, err := t.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = t.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.mfs.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+
+	t.untrack(newpath)
+	for _, name := range t.tracked(oldpath) {
+		e := t.usage[name]
+		delete(t.usage, name)
+
+		u := e.Value.(*usage)
+		u.name = newpath + strings.TrimPrefix(name, oldpath)
+		t.usage[u.name] = e
+	}
+	return nil
+}
+
+// Root ...
+func (t *TmpFS) Root() (string, error) {
+	return t.mfs.Root()
+}
+
+// Stat ...
+func (t *TmpFS) Stat(name string) (gofs.FileInfo, error) {
+	return t.mfs.Stat(name)
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot, so that writes to the sub-tree count towards the
+// capacity of the TmpFS.
+func (t *TmpFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(t, dir)
+}
+
+// Used returns the total size in bytes of the files in the TmpFS.
+func (t *TmpFS) Used() int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.used
+}
+
+// WriteFile writes data to the named file, evicting the least-recently-used files if required. An error wrapping
+// fs.ErrTooLarge is returned if data exceeds the capacity of the TmpFS.
+func (t *TmpFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	name, err := t.clean("writeFile", name)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var size int64
+	if e, ok := t.usage[name]; ok {
+		size = e.Value.(*usage).size
+	}
+
+	if err := t.reserve("writeFile", name, int64(len(data))-size); err != nil {
+		return err
+	}
+
+	if err := t.mfs.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	t.track(name, int64(len(data)))
+	return nil
+}
+
+func (t *TmpFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(t.mfs, name)
+	if err != nil {
+		return name, fmt.Errorf("tmpfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+// evict removes the least-recently-used file that is not pinned and is not name. The caller must hold the mutex.
+func (t *TmpFS) evict(name string) bool {
+	for e := t.lru.Back(); e != nil; e = e.Prev() {
+		u := e.Value.(*usage)
+		if u.pinned > 0 || u.name == name {
+			continue
+		}
+
+		if err := t.mfs.Remove(u.name); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+			t.logger.Error("[tmpfs] evict", "name", u.name, "error", err)
+			return false
+		}
+		t.untrack(u.name)
+		t.evictions++
+
+		t.logger.Debug("[tmpfs] evict", "name", u.name, "size", u.size)
+		if t.onEvict != nil {
+			t.onEvict(fs.Event{Op: fs.EventEvict, Path: u.name, Size: u.size, Time: t.now()})
+		}
+		return true
+	}
+	return false
+}
+
+// reserve evicts files until n additional bytes can be written to name. The caller must hold the mutex.
+func (t *TmpFS) reserve(op string, name string, n int64) error {
+	for t.used+n > t.capacity {
+		if !t.evict(name) {
+			return fmt.Errorf("tmpfs: %w", &gofs.PathError{Op: op, Path: name, Err: fs.ErrTooLarge})
+		}
+	}
+	return nil
+}
+
+func (t *TmpFS) touch(name string) {
+	name, err := fs.CleanPath(t.mfs, name)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if e, ok := t.usage[name]; ok {
+		t.lru.MoveToFront(e)
+	}
+}
+
+// track records the size of name and marks it as the most recently used. The caller must hold the mutex.
+func (t *TmpFS) track(name string, size int64) *usage {
+	if e, ok := t.usage[name]; ok {
+		u := e.Value.(*usage)
+		t.used += size - u.size
+		u.size = size
+		t.lru.MoveToFront(e)
+		return u
+	}
+
+	u := &usage{name: name, size: size}
+	t.usage[name] = t.lru.PushFront(u)
+	t.used += size
+	return u
+}
+
+// tracked returns the tracked files that are path or are contained by path. The caller must hold the mutex.
+func (t *TmpFS) tracked(path string) []string {
+	var names []string
+	for name := range t.usage {
+		if path == "." || name == path || strings.HasPrefix(name, path+"/") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// untrack stops tracking name. The caller must hold the mutex.
+func (t *TmpFS) untrack(name string) {
+	if e, ok := t.usage[name]; ok {
+		t.used -= e.Value.(*usage).size
+		t.lru.Remove(e)
+		delete(t.usage, name)
+	}
+}
+
+// WithEvictionHandler sets the function called with an fs.Event for each file evicted from a TmpFS. The handler is
+// called while the TmpFS is locked, so it must not perform operations on the TmpFS.
+func WithEvictionHandler(handler func(fs.Event)) func(*TmpFS) {
+	return func(t *TmpFS) {
+		t.onEvict = handler
+	}
+}
+
+// WithLogger sets the Logger used by a TmpFS to log the files that are evicted, and the operations of the
+// memfs.MemFS that holds the files. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*TmpFS) {
+	return func(t *TmpFS) {
+		if logger != nil {
+			t.logger = logger
+		}
+	}
+}
+
+// file enforces the capacity of a TmpFS for writes to a file opened for writing.
+type file struct {
+	fs.File
+	closed bool
+	name   string
+	tfs    *TmpFS
+}
+
+func (f *file) Close() error {
+	err := f.File.Close()
+
+	f.tfs.mutex.Lock()
+	defer f.tfs.mutex.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		if e, ok := f.tfs.usage[f.name]; ok {
+			e.Value.(*usage).pinned--
+		}
+	}
+	return err
+}
+
+func (f *file) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *file) Write(b []byte) (int, error) {
+	f.tfs.mutex.Lock()
+	defer f.tfs.mutex.Unlock()
+
+	if _, ok := f.tfs.usage[f.name]; !ok {
+		return 0, fmt.Errorf("tmpfs: %w", &gofs.PathError{Op: "write", Path: f.name, Err: gofs.ErrNotExist})
+	}
+
+	if err := f.tfs.reserve("write", f.name, int64(len(b))); err != nil {
+		return 0, err
+	}
+
+	n, err := f.File.Write(b)
+	if fi, serr := f.File.Stat(); serr == nil {
+		f.tfs.track(f.name, fi.Size())
+	}
+	return n, err
+}
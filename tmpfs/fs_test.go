@@ -0,0 +1,132 @@
+package tmpfs
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// TmpFSTestSuite ...
+type TmpFSTestSuite struct {
+	suite.Suite
+	events []fs.Event
+	tfs    *TmpFS
+}
+
+func NewTmpFSTestSuite() *TmpFSTestSuite {
+	return &TmpFSTestSuite{}
+}
+
+func (t *TmpFSTestSuite) SetupTest() {
+	t.events = nil
+
+	tfs, err := New(64, WithEvictionHandler(func(e fs.Event) { t.events = append(t.events, e) }))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.tfs = tfs
+}
+
+func TestTmpFSTestSuite(t *testing.T) {
+	suite.Run(t, NewTmpFSTestSuite())
+}
+
+func (t *TmpFSTestSuite) TestFS() {
+	assert.NoError(t.T(), t.tfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), fstest.TestFS(t.tfs, "doc/fox.txt"))
+}
+
+func (t *TmpFSTestSuite) TestEvict() {
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		assert.NoError(t.T(), t.tfs.WriteFile(name, []byte(strings.Repeat("x", 20)), 0644))
+	}
+	assert.Equal(t.T(), int64(60), t.tfs.Used())
+
+	_, err := t.tfs.ReadFile("a.txt")
+	assert.NoError(t.T(), err)
+
+	assert.NoError(t.T(), t.tfs.WriteFile("d.txt", []byte(strings.Repeat("x", 20)), 0644))
+	assert.Equal(t.T(), int64(60), t.tfs.Used())
+	assert.Equal(t.T(), uint64(1), t.tfs.Evictions())
+
+	_, err = t.tfs.Stat("b.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.tfs.Stat("a.txt")
+	assert.NoError(t.T(), err)
+
+	assert.Len(t.T(), t.events, 1)
+	assert.Equal(t.T(), fs.EventEvict, t.events[0].Op)
+	assert.Equal(t.T(), "b.txt", t.events[0].Path)
+	assert.Equal(t.T(), int64(20), t.events[0].Size)
+
+	err = t.tfs.WriteFile("e.txt", []byte(strings.Repeat("x", 65)), 0644)
+	assert.ErrorIs(t.T(), err, fs.ErrTooLarge)
+}
+
+func (t *TmpFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tfs, err := New(32, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for _, name := range []string{"doc/a.txt", "doc/b.txt"} {
+		assert.NoError(t.T(), tfs.WriteFile(name, []byte(strings.Repeat("x", 20)), 0644))
+	}
+	assert.Contains(t.T(), buf.String(), `msg="[tmpfs] evict" name=doc/a.txt size=20`)
+	assert.Contains(t.T(), buf.String(), `msg="[memfs:create] creating directory for file" directory=doc`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.tfs.logger)
+}
+
+func (t *TmpFSTestSuite) TestWrite() {
+	assert.NoError(t.T(), t.tfs.WriteFile("a.txt", []byte(strings.Repeat("x", 40)), 0644))
+
+	f, err := t.tfs.Create("b.txt")
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte(strings.Repeat("y", 30)))
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(30), t.tfs.Used())
+	assert.Len(t.T(), t.events, 1)
+
+	assert.NoError(t.T(), t.tfs.WriteFile("c.txt", []byte(strings.Repeat("z", 30)), 0644))
+
+	_, err = f.Write([]byte(strings.Repeat("y", 10)))
+	assert.NoError(t.T(), err)
+
+	_, err = t.tfs.Stat("c.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = f.Write([]byte(strings.Repeat("y", 30)))
+	assert.ErrorIs(t.T(), err, fs.ErrTooLarge)
+	assert.NoError(t.T(), f.Close())
+
+	_, err = t.tfs.Stat("b.txt")
+	assert.NoError(t.T(), err)
+}
+
+func (t *TmpFSTestSuite) TestRemoveRename() {
+	assert.NoError(t.T(), t.tfs.WriteFile("doc/a.txt", []byte(strings.Repeat("x", 20)), 0644))
+	assert.NoError(t.T(), t.tfs.WriteFile("doc/b.txt", []byte(strings.Repeat("x", 20)), 0644))
+
+	assert.NoError(t.T(), t.tfs.Rename("doc", "tmp"))
+	assert.Equal(t.T(), int64(40), t.tfs.Used())
+
+	assert.NoError(t.T(), t.tfs.Remove("tmp/a.txt"))
+	assert.Equal(t.T(), int64(20), t.tfs.Used())
+
+	assert.NoError(t.T(), t.tfs.RemoveAll("tmp"))
+	assert.Equal(t.T(), int64(0), t.tfs.Used())
+}
@@ -0,0 +1,504 @@
+package trashfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	// DefaultTrashDir is the default directory used to store removed entries.
+	DefaultTrashDir = ".trash"
+
+	filesDir = "files"
+	infoDir  = "info"
+	infoExt  = ".json"
+)
+
+var _ fs.FS = (*TrashFS)(nil)
+
+// Item describes an entry that has been moved to the trash.
+type Item struct {
+	// ID uniquely identifies the item within the trash.
+	ID string `json:"-"`
+
+	// Path is the path of the entry before it was removed.
+	Path string `json:"path"`
+
+	// Deleted is the time the entry was removed.
+	Deleted time.Time `json:"deleted"`
+
+	// IsDir reports whether the entry is a directory.
+	IsDir bool `json:"is_dir"`
+}
+
+// TrashFS soft-deleting provider that implements fs.FS.
+//
+// A TrashFS moves entries that are removed using Remove or RemoveAll into a trash directory on the backing file
+// system instead of deleting them, recording the original path of each entry so that it can be restored using
+// Restore. Entries are permanently deleted using Purge.
+//
+// The trash directory is hidden from the TrashFS: it is omitted from directory listings and glob matches, and any
+// attempt to access it returns an error wrapping gofs.ErrNotExist.
+type TrashFS struct {
+	backing  fs.FS
+	logger   fs.Logger
+	mutex    sync.Mutex
+	now      func() time.Time
+	seq      uint64
+	trashDir string
+}
+
+// New creates a new TrashFS for backing.
+func New(backing fs.FS, options ...func(*TrashFS)) (*TrashFS, error) {
+	if backing == nil {
+		return nil, errors.New("trashfs: backing file system is required")
+	}
+
+	t := &TrashFS{backing: backing, logger: fs.NopLogger(), now: time.Now, trashDir: DefaultTrashDir}
+	for _, opt := range options {
+		opt(t)
+	}
+
+	if !gofs.ValidPath(t.trashDir) || t.trashDir == "." {
+		return nil, fmt.Errorf("trashfs: invalid trash directory: %s", t.trashDir)
+	}
+	return t, nil
+}
+
+// Close closes the backing file system.
+func (t *TrashFS) Close() error {
+	return t.backing.Close()
+}
+
+// Create ...
+func (t *TrashFS) Create(name string) (fs.File, error) {
+	if err := t.check("create", name); err != nil {
+		return nil, err
+	}
+	return t.backing.Create(name)
+}
+
+// Glob ...
+func (t *TrashFS) Glob(pattern string) ([]string, error) {
+	matches, err := t.backing.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := matches[:0]
+	for _, m := range matches {
+		if !t.hidden(m) {
+			visible = append(visible, m)
+		}
+	}
+	return visible, nil
+}
+
+// Items returns the items in the trash, ordered from the most to the least recently removed.
+func (t *TrashFS) Items() ([]Item, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.items()
+}
+
+// Mkdir ...
+func (t *TrashFS) Mkdir(name string, perm gofs.FileMode) error {
+	if err := t.check("mkdir", name); err != nil {
+		return err
+	}
+	return t.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (t *TrashFS) MkdirAll(path string, perm gofs.FileMode) error {
+	if err := t.check("mkdirAll", path); err != nil {
+		return err
+	}
+	return t.backing.MkdirAll(path, perm)
+}
+
+// Open ...
+func (t *TrashFS) Open(name string) (gofs.File, error) {
+	if err := t.check("open", name); err != nil {
+		return nil, err
+	}
+
+	f, err := t.backing.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if file, ok := f.(fs.File); ok && name == gopath.Dir(t.trashDir) {
+		return &dir{File: file, tfs: t, name: name}, nil
+	}
+	return f, nil
+}
+
+// OpenFile ...
+func (t *TrashFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	if err := t.check("openFile", name); err != nil {
+		return nil, err
+	}
+
+	f, err := t.backing.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == gopath.Dir(t.trashDir) {
+		return &dir{File: f, tfs: t, name: name}, nil
+	}
+	return f, nil
+}
+
+// PathSeparator ...
+func (t *TrashFS) PathSeparator() string {
+	return t.backing.PathSeparator()
+}
+
+// Provider ...
+func (t *TrashFS) Provider() string {
+	return t.backing.Provider()
+}
+
+// Purge permanently deletes the items in the trash that were removed more than olderThan ago, and returns the number
+// of items deleted. If olderThan is less than or equal to zero, every item is deleted.
+func (t *TrashFS) Purge(olderThan time.Duration) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	items, err := t.items()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := t.now().Add(-olderThan)
+
+	var n int
+	for _, item := range items {
+		if olderThan > 0 && item.Deleted.After(cutoff) {
+			continue
+		}
+
+		if err := t.delete(item.ID); err != nil {
+			return n, fmt.Errorf("trashfs: %w", &gofs.PathError{Op: "purge", Path: item.Path, Err: err})
+		}
+		n++
+	}
+
+	t.logger.Debug("[trashfs] purge", "older_than", olderThan, "purged", n)
+	return n, nil
+}
+
+// ReadDir ...
+func (t *TrashFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	if err := t.check("readDir", name); err != nil {
+		return nil, err
+	}
+
+	entries, err := t.backing.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.filter(name, entries), nil
+}
+
+// ReadFile ...
+func (t *TrashFS) ReadFile(name string) ([]byte, error) {
+	if err := t.check("readFile", name); err != nil {
+		return nil, err
+	}
+	return t.backing.ReadFile(name)
+}
+
+// Remove moves the named file or empty directory to the trash.
+func (t *TrashFS) Remove(name string) error {
+	if err := t.check("remove", name); err != nil {
+		return err
+	}
+
+	fi, err := t.backing.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		entries, err := t.backing.ReadDir(name)
+		if err != nil {
+			return err
+		}
+
+		if len(t.filter(name, entries)) > 0 {
+			return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: "remove", Path: name, Err: fs.ErrNotEmpty})
+		}
+	}
+	return t.trash("remove", name, fi)
+}
+
+// RemoveAll moves path and any children it contains to the trash. A nil error is returned if the path does not exist.
+func (t *TrashFS) RemoveAll(path string) error {
+	if err := t.check("removeAll", path); err != nil {
+		return err
+	}
+
+	fi, err := t.backing.Stat(path)
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return t.trash("removeAll", path, fi)
+}
+
+// Rename ...
+func (t *TrashFS) Rename(oldpath string, newpath string) error {
+	if t.hidden(oldpath) || t.hidden(newpath) {
+		return fmt.Errorf("trashfs: %w", &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: gofs.ErrNotExist})
+	}
+	return t.backing.Rename(oldpath, newpath)
+}
+
+// Restore restores the most recently removed entry whose original path is path. An error wrapping gofs.ErrExist is
+// returned if an entry already exists at path.
+func (t *TrashFS) Restore(path string) error {
+	if err := t.check("restore", path); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	items, err := t.items()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Path != path {
+			continue
+		}
+
+		if _, err := t.backing.Stat(path); err == nil {
+			return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: "restore", Path: path, Err: gofs.ErrExist})
+		}
+
+		if parent := gopath.Dir(path); parent != "." {
+			if err := t.backing.MkdirAll(parent, 0755); err != nil {
+				return err
+			}
+		}
+
+		if err := t.backing.Rename(t.filePath(item.ID), path); err != nil {
+			return err
+		}
+
+		t.logger.Debug("[trashfs] restore", "path", path, "id", item.ID)
+		return t.backing.Remove(t.infoPath(item.ID))
+	}
+	return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: "restore", Path: path, Err: gofs.ErrNotExist})
+}
+
+// Root ...
+func (t *TrashFS) Root() (string, error) {
+	return t.backing.Root()
+}
+
+// Stat ...
+func (t *TrashFS) Stat(name string) (gofs.FileInfo, error) {
+	if err := t.check("stat", name); err != nil {
+		return nil, err
+	}
+	return t.backing.Stat(name)
+}
+
+// Sub returns a view of the sub-tree for dir using fs.Chroot, so that entries removed from the sub-tree are moved to
+// the trash of the TrashFS.
+func (t *TrashFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(t, dir)
+}
+
+// TrashDir returns the directory on the backing file system used to store removed entries.
+func (t *TrashFS) TrashDir() string {
+	return t.trashDir
+}
+
+// WriteFile ...
+func (t *TrashFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	if err := t.check("writeFile", name); err != nil {
+		return err
+	}
+	return t.backing.WriteFile(name, data, perm)
+}
+
+// check returns an error if name refers to the trash directory or an entry within it.
+func (t *TrashFS) check(op string, name string) error {
+	if t.hidden(name) {
+		return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrNotExist})
+	}
+	return nil
+}
+
+// delete permanently deletes the item with the provided id. The caller must hold the mutex.
+func (t *TrashFS) delete(id string) error {
+	if err := t.backing.RemoveAll(t.filePath(id)); err != nil {
+		return err
+	}
+	return t.backing.Remove(t.infoPath(id))
+}
+
+func (t *TrashFS) filePath(id string) string {
+	return gopath.Join(t.trashDir, filesDir, id)
+}
+
+// filter removes the trash directory from the entries for the directory name.
+func (t *TrashFS) filter(name string, entries []gofs.DirEntry) []gofs.DirEntry {
+	if name != gopath.Dir(t.trashDir) {
+		return entries
+	}
+
+	visible := make([]gofs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Name() != gopath.Base(t.trashDir) {
+			visible = append(visible, e)
+		}
+	}
+	return visible
+}
+
+func (t *TrashFS) hidden(name string) bool {
+	return name == t.trashDir || strings.HasPrefix(name, t.trashDir+"/")
+}
+
+func (t *TrashFS) infoPath(id string) string {
+	return gopath.Join(t.trashDir, infoDir, id+infoExt)
+}
+
+// items returns the items in the trash, ordered from the most to the least recently removed. The caller must hold the
+// mutex.
+func (t *TrashFS) items() ([]Item, error) {
+	entries, err := t.backing.ReadDir(gopath.Join(t.trashDir, infoDir))
+	if err != nil {
+		if errors.Is(err, gofs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []Item
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), infoExt) {
+			continue
+		}
+
+		b, err := t.backing.ReadFile(gopath.Join(t.trashDir, infoDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var item Item
+		if err := json.Unmarshal(b, &item); err != nil {
+			return nil, fmt.Errorf("trashfs: %w", &gofs.PathError{Op: "items", Path: e.Name(), Err: err})
+		}
+		item.ID = strings.TrimSuffix(e.Name(), infoExt)
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Deleted.Equal(items[j].Deleted) {
+			return items[i].Deleted.After(items[j].Deleted)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+
+// trash moves the entry name to the trash and records its original path.
+func (t *TrashFS) trash(op string, name string, fi gofs.FileInfo) error {
+	if name == "." || name == t.trashDir || strings.HasPrefix(t.trashDir, name+"/") {
+		return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrInvalid})
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := t.backing.MkdirAll(gopath.Join(t.trashDir, dir), 0700); err != nil {
+			return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+		}
+	}
+
+	t.seq++
+	now := t.now()
+	item := Item{
+		ID:      fmt.Sprintf("%d-%d", now.UnixNano(), t.seq),
+		Path:    name,
+		Deleted: now,
+		IsDir:   fi.IsDir(),
+	}
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("trashfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+
+	if err := t.backing.WriteFile(t.infoPath(item.ID), b, 0600); err != nil {
+		return err
+	}
+
+	if err := t.backing.Rename(name, t.filePath(item.ID)); err != nil {
+		if rerr := t.backing.Remove(t.infoPath(item.ID)); rerr != nil {
+			t.logger.Error("[trashfs] removing trash info", "id", item.ID, "error", rerr)
+		}
+		return err
+	}
+
+	t.logger.Debug("[trashfs] "+op, "path", name, "id", item.ID)
+	return nil
+}
+
+// WithLogger sets the Logger used by a TrashFS to log the entries that are moved to, restored from, or purged from
+// the trash. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*TrashFS) {
+	return func(t *TrashFS) {
+		if logger != nil {
+			t.logger = logger
+		}
+	}
+}
+
+// WithTrashDir sets the directory on the backing file system used to store removed entries for a TrashFS.
+func WithTrashDir(dir string) func(*TrashFS) {
+	return func(t *TrashFS) {
+		t.trashDir = gopath.Clean(dir)
+	}
+}
+
+// dir hides the trash directory from the entries read from the directory that contains it.
+type dir struct {
+	fs.File
+	name string
+	tfs  *TrashFS
+}
+
+func (d *dir) ReadDir(n int) ([]gofs.DirEntry, error) {
+	for {
+		entries, err := d.File.ReadDir(n)
+		visible := d.tfs.filter(d.name, entries)
+		if len(visible) > 0 || err != nil || n <= 0 || len(entries) == 0 {
+			return visible, err
+		}
+	}
+}
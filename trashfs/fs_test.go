@@ -0,0 +1,187 @@
+package trashfs
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// TrashFSTestSuite ...
+type TrashFSTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	now     time.Time
+	tfs     *TrashFS
+}
+
+func NewTrashFSTestSuite() *TrashFSTestSuite {
+	return &TrashFSTestSuite{}
+}
+
+func (t *TrashFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	files := map[string]string{
+		"doc/fox.txt": "the quick brown fox",
+		"doc/dog.txt": "jumps over the lazy dog",
+	}
+	for name, content := range files {
+		if err := backing.WriteFile(name, []byte(content), 0644); err != nil {
+			t.T().Fatal(err)
+		}
+	}
+	t.backing = backing
+
+	tfs, err := New(backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.now = time.Now()
+	tfs.now = func() time.Time { return t.now }
+	t.tfs = tfs
+}
+
+func TestTrashFSTestSuite(t *testing.T) {
+	suite.Run(t, NewTrashFSTestSuite())
+}
+
+func (t *TrashFSTestSuite) TestFS() {
+	assert.NoError(t.T(), t.tfs.Remove("doc/dog.txt"))
+	assert.NoError(t.T(), fstest.TestFS(t.tfs, "doc/fox.txt"))
+
+	_, err := t.tfs.Stat(DefaultTrashDir)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	entries, err := t.tfs.ReadDir(".")
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+}
+
+func (t *TrashFSTestSuite) TestRemoveRestore() {
+	assert.NoError(t.T(), t.tfs.Remove("doc/fox.txt"))
+
+	_, err := t.tfs.Stat("doc/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	items, err := t.tfs.Items()
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), items, 1)
+	assert.Equal(t.T(), "doc/fox.txt", items[0].Path)
+	assert.False(t.T(), items[0].IsDir)
+
+	assert.ErrorIs(t.T(), t.tfs.Remove("doc"), fs.ErrNotEmpty)
+
+	assert.NoError(t.T(), t.tfs.Restore("doc/fox.txt"))
+
+	b, err := t.tfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+
+	items, err = t.tfs.Items()
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), items)
+
+	assert.ErrorIs(t.T(), t.tfs.Restore("doc/fox.txt"), gofs.ErrNotExist)
+}
+
+func (t *TrashFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tfs, err := New(t.backing, WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), tfs.Remove("doc/fox.txt"))
+	assert.Contains(t.T(), buf.String(), `msg="[trashfs] remove" path=doc/fox.txt`)
+
+	assert.NoError(t.T(), tfs.Restore("doc/fox.txt"))
+	assert.Contains(t.T(), buf.String(), `msg="[trashfs] restore" path=doc/fox.txt`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.tfs.logger)
+}
+
+func (t *TrashFSTestSuite) TestRemoveAll() {
+	assert.NoError(t.T(), t.tfs.RemoveAll("doc"))
+	assert.NoError(t.T(), t.tfs.RemoveAll("missing"))
+
+	_, err := t.tfs.Stat("doc")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.tfs.WriteFile("doc/cat.txt", []byte("the cat"), 0644))
+	assert.ErrorIs(t.T(), t.tfs.Restore("doc"), gofs.ErrExist)
+
+	t.now = t.now.Add(time.Hour)
+	assert.NoError(t.T(), t.tfs.RemoveAll("doc"))
+	assert.NoError(t.T(), t.tfs.Restore("doc"))
+
+	b, err := t.tfs.ReadFile("doc/cat.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the cat", string(b))
+
+	items, err := t.tfs.Items()
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), items, 1)
+	assert.Equal(t.T(), "doc", items[0].Path)
+	assert.True(t.T(), items[0].IsDir)
+}
+
+func (t *TrashFSTestSuite) TestRemoveTrashAncestor() {
+	tfs, err := New(t.backing, WithTrashDir("data/.trash"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), tfs.WriteFile("data/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t.T(), tfs.Remove("data/fox.txt"))
+
+	assert.EqualError(t.T(), tfs.Remove("data"), "trashfs: remove data: invalid argument")
+	assert.EqualError(t.T(), tfs.RemoveAll("data"), "trashfs: removeAll data: invalid argument")
+	assert.ErrorIs(t.T(), tfs.RemoveAll("."), gofs.ErrInvalid)
+
+	items, err := tfs.Items()
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), items, 1)
+	assert.NoError(t.T(), tfs.Restore("data/fox.txt"))
+
+	b, err := tfs.ReadFile("data/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox", string(b))
+}
+
+func (t *TrashFSTestSuite) TestPurge() {
+	assert.NoError(t.T(), t.tfs.Remove("doc/fox.txt"))
+	t.now = t.now.Add(48 * time.Hour)
+	assert.NoError(t.T(), t.tfs.Remove("doc/dog.txt"))
+
+	n, err := t.tfs.Purge(24 * time.Hour)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, n)
+
+	items, err := t.tfs.Items()
+	assert.NoError(t.T(), err)
+	assert.Len(t.T(), items, 1)
+	assert.Equal(t.T(), "doc/dog.txt", items[0].Path)
+
+	n, err = t.tfs.Purge(0)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, n)
+
+	entries, err := t.backing.ReadDir(DefaultTrashDir + "/files")
+	assert.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+}
@@ -0,0 +1,532 @@
+package fs
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+	gopath "path"
+)
+
+const (
+	// TreeJSON is the format for writing a TreeSpec as JSON.
+	TreeJSON TreeFormat = "json"
+
+	// TreeYAML is the format for writing a TreeSpec as YAML, which is the default format used by DumpTree.
+	TreeYAML TreeFormat = "yaml"
+)
+
+const (
+	// treeDirPerm is the default permissions for directories created by LoadTree.
+	treeDirPerm = 0755
+
+	// treeFilePerm is the default permissions for files created by LoadTree.
+	treeFilePerm = 0644
+)
+
+// TreeFormat defines the format used by DumpTree to write a TreeSpec.
+type TreeFormat string
+
+// TreeOption defines an option for LoadTree, DumpTree, and Tree.
+type TreeOption func(*treeOptions)
+
+type treeOptions struct {
+	format   TreeFormat
+	modTimes bool
+	sizes    bool
+	source   gofs.FS
+}
+
+// TreeMode is the permissions of an entry in a TreeSpec, which are written as a Unix octal string such as "0644" or
+// "4755", where the setuid, setgid, and sticky bits use their Unix values.
+type TreeMode gofs.FileMode
+
+// MarshalText returns the permissions as a Unix octal string.
+func (m TreeMode) MarshalText() ([]byte, error) {
+	mode := gofs.FileMode(m)
+	v := uint32(mode.Perm())
+	if mode&gofs.ModeSetuid != 0 {
+		v |= 04000
+	}
+	if mode&gofs.ModeSetgid != 0 {
+		v |= 02000
+	}
+	if mode&gofs.ModeSticky != 0 {
+		v |= 01000
+	}
+	return []byte(fmt.Sprintf("%04o", v)), nil
+}
+
+// UnmarshalText parses the permissions from a Unix octal string.
+func (m *TreeMode) UnmarshalText(text []byte) error {
+	v, err := strconv.ParseUint(strings.TrimPrefix(string(text), "0o"), 8, 32)
+	if err != nil || v > 07777 {
+		return fmt.Errorf("fs: tree: mode %q is invalid: %w", text, ErrInvalid)
+	}
+
+	mode := gofs.FileMode(v & 0777)
+	if v&04000 != 0 {
+		mode |= gofs.ModeSetuid
+	}
+	if v&02000 != 0 {
+		mode |= gofs.ModeSetgid
+	}
+	if v&01000 != 0 {
+		mode |= gofs.ModeSticky
+	}
+	*m = TreeMode(mode)
+	return nil
+}
+
+// TreeEntry describes a file, directory, or symbolic link in a TreeSpec.
+type TreeEntry struct {
+	// Path is the slash-separated path of the entry, relative to the root of the tree.
+	Path string `json:"path" yaml:"path"`
+
+	// Dir indicates that the entry is a directory.
+	Dir bool `json:"dir,omitempty" yaml:"dir,omitempty"`
+
+	// Target is the destination of a symbolic link.
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+
+	// Content is the inline content of a file.
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+
+	// Encoding is the encoding of Content, which is either empty for text, or "base64".
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+
+	// Source is the path of a file that provides the content of the file, instead of Content. The path is resolved
+	// using the file system set using WithTreeSource, or relative to the working directory by default.
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+
+	// Mode is the permissions of the entry. If it is not set, directories are created with 0755 and files with 0644.
+	Mode *TreeMode `json:"mode,omitempty" yaml:"mode,omitempty"`
+
+	// ModTime is the modification time of the entry. If it is not set, the time the entry was created is kept.
+	ModTime *time.Time `json:"mtime,omitempty" yaml:"mtime,omitempty"`
+}
+
+// TreeSpec describes a tree of files and directories that can be created using LoadTree, and is written by DumpTree.
+//
+// A TreeSpec is written as YAML or JSON. For example, the following YAML describes a directory containing a text
+// file, a binary file whose content is read from another file, and an empty directory:
+//
+//	entries:
+//	  - path: doc
+//	    dir: true
+//	    mode: "0750"
+//	  - path: doc/fox.txt
+//	    content: the quick brown fox
+//	    mode: "0640"
+//	    mtime: 2024-05-01T12:00:00Z
+//	  - path: doc/seals.png
+//	    source: testdata/pictures/seals.png
+//	  - path: tmp
+//	    dir: true
+//
+// Parent directories that are not listed are created with the default permissions.
+type TreeSpec struct {
+	Entries []TreeEntry `json:"entries" yaml:"entries"`
+}
+
+// LoadTree creates the tree described by the TreeSpec read from r under root, so that test fixtures can be declared
+// in a single YAML or JSON document instead of being created by code. The spec is read as YAML, which also accepts
+// JSON.
+//
+// Entries are created in the order they are listed. Existing files are replaced, and existing directories are kept.
+// Modes are applied if fsys implements ChmodFS, and modification times are applied if fsys implements ChtimesFS.
+// Symbolic links are created if fsys implements SymlinkFS, and an error wrapping ErrUnsupported is returned otherwise.
+func LoadTree(fsys FS, root string, r io.Reader, options ...TreeOption) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	if r == nil {
+		return errors.New("fs: reader is required")
+	}
+
+	opts := &treeOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("fs: tree: %w", err)
+	}
+
+	spec := &TreeSpec{}
+	if err := yaml.Unmarshal(b, spec); err != nil {
+		return fmt.Errorf("fs: tree: %w", err)
+	}
+
+	// Directory attributes are applied once the tree has been created, since creating the entries of a directory changes
+	// its modification time.
+	var dirs []TreeEntry
+	for _, e := range spec.Entries {
+		if !gofs.ValidPath(e.Path) || e.Path == "." {
+			return fmt.Errorf("fs: tree: path %q is invalid: %w", e.Path, ErrInvalid)
+		}
+
+		p := Join(fsys, root, e.Path)
+		if dir := Dir(fsys, p); dir != "." {
+			if err := fsys.MkdirAll(dir, treeDirPerm); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case e.Dir:
+			if err := fsys.MkdirAll(p, e.perm(treeDirPerm)); err != nil {
+				return err
+			}
+			dirs = append(dirs, e)
+			continue
+		case e.Target != "":
+			sfs, ok := fsys.(SymlinkFS)
+			if !ok {
+				return NewOpError(fsys.Provider(), "loadTree", p, ErrUnsupported)
+			}
+
+			if err := sfs.Symlink(e.Target, p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := e.content(opts)
+		if err != nil {
+			return err
+		}
+
+		if err := fsys.WriteFile(p, data, e.perm(treeFilePerm)); err != nil {
+			return err
+		}
+
+		if err := applyTreeAttributes(fsys, p, e); err != nil {
+			return err
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyTreeAttributes(fsys, Join(fsys, root, dirs[i].Path), dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpTree writes a TreeSpec describing the tree rooted at root to w, with paths relative to root, so that the tree
+// can be recreated using LoadTree or compared with an expected tree. Entries are listed in lexical order, and root
+// itself is not listed.
+//
+// The content of each file is written inline, as text if it is printable UTF-8 and base64-encoded otherwise. The
+// targets of symbolic links are written if fsys implements SymlinkFS. The spec is written as YAML unless a different
+// format is set using WithTreeFormat.
+func DumpTree(w io.Writer, fsys gofs.FS, root string, options ...TreeOption) error {
+	if w == nil {
+		return errors.New("fs: writer is required")
+	}
+
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &treeOptions{format: TreeYAML}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	spec := &TreeSpec{Entries: []TreeEntry{}}
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	err := Walk(fsys, root, func(p string, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == root {
+			return nil
+		}
+
+		mode := TreeMode(entry.Mode() &^ gofs.ModeType)
+		mtime := entry.ModTime().UTC()
+		e := TreeEntry{Path: strings.TrimPrefix(p, prefix), Mode: &mode, ModTime: &mtime}
+		if root == "." {
+			e.Path = p
+		}
+
+		switch m := entry.Mode(); {
+		case m.IsDir():
+			e.Dir = true
+		case m&gofs.ModeSymlink != 0:
+			sfs, ok := fsys.(SymlinkFS)
+			if !ok {
+				return fmt.Errorf("fs: tree: %s: %w", p, ErrUnsupported)
+			}
+
+			if e.Target, err = sfs.Readlink(p); err != nil {
+				return err
+			}
+			e.Mode, e.ModTime = nil, nil
+		case m.IsRegular():
+			b, err := gofs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+
+			e.Content = string(b)
+			if !isText(b) {
+				e.Content, e.Encoding = base64.StdEncoding.EncodeToString(b), "base64"
+			}
+		default:
+			return fmt.Errorf("fs: tree: %s: %w", p, ErrUnsupported)
+		}
+		spec.Entries = append(spec.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch opts.format {
+	case TreeJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(spec); err != nil {
+			return fmt.Errorf("fs: tree: %w", err)
+		}
+	case TreeYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(spec); err != nil {
+			return fmt.Errorf("fs: tree: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("fs: tree: %w", err)
+		}
+	default:
+		return fmt.Errorf("fs: tree: format %q is not supported: %w", opts.format, ErrInvalid)
+	}
+	return nil
+}
+
+// Tree returns a rendering of the tree rooted at root in the same way as the Unix tree command, for example to make
+// the content of a file system readable in the message of a failed test:
+//
+//	tree
+//	├── doc
+//	│   └── fox.txt
+//	└── pictures
+//	    └── seals.png
+//
+//	2 directories, 2 files
+//
+// Entries are listed in lexical order, and the targets of symbolic links are listed if fsys implements SymlinkFS. The
+// sizes and modification times of entries are listed if enabled using WithTreeSizes and WithTreeModTimes. Errors are
+// rendered in place of the entries that could not be read, so that a partial tree is always returned.
+func Tree(fsys gofs.FS, root string, options ...TreeOption) string {
+	opts := &treeOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	var sb strings.Builder
+	if fsys == nil {
+		sb.WriteString(root + " [error: file system is required]\n")
+		return sb.String()
+	}
+
+	t := &treeRenderer{fsys: fsys, opts: opts, sb: &sb}
+	fi, err := gofs.Stat(fsys, root)
+	if err != nil {
+		sb.WriteString(root + " [error: " + err.Error() + "]\n")
+		return sb.String()
+	}
+
+	sb.WriteString(t.label(root, root, fi) + "\n")
+	if fi.IsDir() {
+		t.render(root, "")
+	}
+
+	dirs := "directories"
+	if t.dirs == 1 {
+		dirs = "directory"
+	}
+
+	files := "files"
+	if t.files == 1 {
+		files = "file"
+	}
+	fmt.Fprintf(&sb, "\n%d %s, %d %s\n", t.dirs, dirs, t.files, files)
+	return sb.String()
+}
+
+// WithTreeFormat sets the format used by DumpTree to write a TreeSpec. The default is TreeYAML.
+func WithTreeFormat(format TreeFormat) TreeOption {
+	return func(o *treeOptions) {
+		o.format = format
+	}
+}
+
+// WithTreeModTimes enables listing the modification time of each entry rendered by Tree.
+func WithTreeModTimes() TreeOption {
+	return func(o *treeOptions) {
+		o.modTimes = true
+	}
+}
+
+// WithTreeSizes enables listing the size in bytes of each entry rendered by Tree.
+func WithTreeSizes() TreeOption {
+	return func(o *treeOptions) {
+		o.sizes = true
+	}
+}
+
+// WithTreeSource sets the file system used by LoadTree to read the content of files that reference a Source, such as
+// os.DirFS("testdata"). By default, sources are read relative to the working directory.
+func WithTreeSource(fsys gofs.FS) TreeOption {
+	return func(o *treeOptions) {
+		o.source = fsys
+	}
+}
+
+// treeRenderer renders the entries of a tree for Tree.
+type treeRenderer struct {
+	dirs  int
+	files int
+	fsys  gofs.FS
+	opts  *treeOptions
+	sb    *strings.Builder
+}
+
+// render writes the entries of the directory dir, each prefixed by indent.
+func (t *treeRenderer) render(dir string, indent string) {
+	entries, err := gofs.ReadDir(t.fsys, dir)
+	if err != nil {
+		t.sb.WriteString(indent + "└── [error: " + err.Error() + "]\n")
+		return
+	}
+
+	for i, entry := range entries {
+		connector, next := "├── ", "│   "
+		if i == len(entries)-1 {
+			connector, next = "└── ", "    "
+		}
+
+		p := gopath.Join(dir, entry.Name())
+		fi, err := entry.Info()
+		if err != nil {
+			t.sb.WriteString(indent + connector + entry.Name() + " [error: " + err.Error() + "]\n")
+			continue
+		}
+
+		t.sb.WriteString(indent + connector + t.label(p, entry.Name(), fi) + "\n")
+		if fi.IsDir() {
+			t.dirs++
+			t.render(p, indent+next)
+			continue
+		}
+		t.files++
+	}
+}
+
+// label returns the line rendered for the entry at path p with the provided name and info.
+func (t *treeRenderer) label(p string, name string, fi gofs.FileInfo) string {
+	var attrs []string
+	if t.opts.sizes {
+		attrs = append(attrs, fmt.Sprintf("%10d", fi.Size()))
+	}
+
+	if t.opts.modTimes {
+		attrs = append(attrs, fi.ModTime().Format(time.DateTime))
+	}
+
+	if len(attrs) > 0 {
+		name = "[" + strings.Join(attrs, "  ") + "]  " + name
+	}
+
+	if fi.Mode()&gofs.ModeSymlink != 0 {
+		if sfs, ok := t.fsys.(SymlinkFS); ok {
+			if target, err := sfs.Readlink(p); err == nil {
+				name += " -> " + target
+			}
+		}
+	}
+	return name
+}
+
+// content returns the content of the file described by the TreeEntry.
+func (e TreeEntry) content(opts *treeOptions) ([]byte, error) {
+	if e.Source != "" {
+		if e.Content != "" {
+			return nil, fmt.Errorf("fs: tree: %s: content and source are both set: %w", e.Path, ErrInvalid)
+		}
+
+		if opts.source != nil {
+			return gofs.ReadFile(opts.source, e.Source)
+		}
+		return os.ReadFile(e.Source)
+	}
+
+	switch e.Encoding {
+	case "":
+		return []byte(e.Content), nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(e.Content)
+		if err != nil {
+			return nil, fmt.Errorf("fs: tree: %s: %w", e.Path, err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("fs: tree: %s: encoding %q is not supported: %w", e.Path, e.Encoding, ErrInvalid)
+	}
+}
+
+// perm returns the permissions of the TreeEntry, or def if the mode is not set.
+func (e TreeEntry) perm(def gofs.FileMode) gofs.FileMode {
+	if e.Mode == nil {
+		return def
+	}
+	return gofs.FileMode(*e.Mode)
+}
+
+// applyTreeAttributes applies the mode and modification time of the TreeEntry e to the entry at path p.
+func applyTreeAttributes(fsys FS, p string, e TreeEntry) error {
+	if c, ok := fsys.(ChmodFS); ok && e.Mode != nil {
+		if err := c.Chmod(p, e.perm(0)); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := fsys.(ChtimesFS); ok && e.ModTime != nil {
+		if err := c.Chtimes(p, *e.ModTime, *e.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isText returns whether b is valid UTF-8 that does not contain control characters other than whitespace.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package fs_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+const treeSpec = `
+entries:
+  - path: doc
+    dir: true
+    mode: "0750"
+    mtime: 2024-05-01T12:00:00Z
+  - path: doc/fox.txt
+    content: the quick brown fox
+    mode: "0640"
+    mtime: 2024-05-02T12:00:00Z
+  - path: doc/zero.bin
+    content: AAEC
+    encoding: base64
+  - path: pictures/seals.png
+    source: pictures/seals.png
+  - path: tmp
+    dir: true
+`
+
+func TestLoadTree(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, fs.LoadTree(mfs, "tree", strings.NewReader(treeSpec), fs.WithTreeSource(os.DirFS("testdata"))))
+
+	fi, err := mfs.Stat("tree/doc")
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, gofs.FileMode(0750), fi.Mode().Perm())
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), fi.ModTime().UTC())
+
+	fi, err = mfs.Stat("tree/doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0640), fi.Mode().Perm())
+	assert.Equal(t, time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC), fi.ModTime().UTC())
+
+	b, err := mfs.ReadFile("tree/doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(b))
+
+	b, err = mfs.ReadFile("tree/doc/zero.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, b)
+
+	want, err := os.ReadFile("testdata/pictures/seals.png")
+	assert.NoError(t, err)
+
+	b, err = mfs.ReadFile("tree/pictures/seals.png")
+	assert.NoError(t, err)
+	assert.Equal(t, want, b)
+
+	fi, err = mfs.Stat("tree/tmp")
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Equal(t, gofs.FileMode(0755), fi.Mode().Perm())
+
+	json := `{"entries": [{"path": "a/b.txt", "content": "b", "mode": "0600"}]}`
+	assert.NoError(t, fs.LoadTree(mfs, ".", strings.NewReader(json)))
+
+	fi, err = mfs.Stat("a/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0600), fi.Mode().Perm())
+
+	assert.ErrorIs(t, fs.LoadTree(mfs, ".", strings.NewReader(`entries: [{path: ../escape}]`)), fs.ErrInvalid)
+	assert.ErrorIs(t, fs.LoadTree(mfs, ".", strings.NewReader(`entries: [{path: f, mode: "0999"}]`)), fs.ErrInvalid)
+	assert.ErrorIs(t, fs.LoadTree(mfs, ".", strings.NewReader(`entries: [{path: f, content: x, encoding: hex}]`)),
+		fs.ErrInvalid)
+}
+
+func TestDumpTree(t *testing.T) {
+	src, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.LoadTree(src, "tree", strings.NewReader(treeSpec), fs.WithTreeSource(os.DirFS("testdata"))))
+
+	for _, format := range []fs.TreeFormat{fs.TreeYAML, fs.TreeJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, fs.DumpTree(&buf, src, "tree", fs.WithTreeFormat(format)))
+
+			dst, err := memfs.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.NoError(t, fs.LoadTree(dst, ".", bytes.NewReader(buf.Bytes())))
+
+			var got bytes.Buffer
+			assert.NoError(t, fs.DumpTree(&got, dst, ".", fs.WithTreeFormat(format)))
+			assert.Equal(t, buf.String(), got.String())
+
+			b, err := dst.ReadFile("doc/zero.bin")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{0, 1, 2}, b)
+
+			fi, err := dst.Stat("doc")
+			assert.NoError(t, err)
+			assert.Equal(t, gofs.FileMode(0750), fi.Mode().Perm())
+			assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), fi.ModTime().UTC())
+		})
+	}
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, fs.DumpTree(&buf, src, "tree", fs.WithTreeFormat("xml")), fs.ErrInvalid)
+}
+
+func TestTree(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, fs.LoadTree(mfs, "tree", strings.NewReader(treeSpec), fs.WithTreeSource(os.DirFS("testdata"))))
+
+	want := `tree
+├── doc
+│   ├── fox.txt
+│   └── zero.bin
+├── pictures
+│   └── seals.png
+└── tmp
+
+3 directories, 3 files
+`
+	assert.Equal(t, want, fs.Tree(mfs, "tree"))
+
+	fi, err := mfs.Stat("tree/doc/fox.txt")
+	assert.NoError(t, err)
+
+	got := fs.Tree(mfs, "tree/doc", fs.WithTreeSizes(), fs.WithTreeModTimes())
+	assert.Contains(t, got, "├── [        19  "+fi.ModTime().Format(time.DateTime)+"]  fox.txt\n")
+	assert.Contains(t, got, "└── [         3  ")
+	assert.True(t, strings.HasSuffix(got, "]  zero.bin\n\n0 directories, 2 files\n"))
+
+	assert.Equal(t, "tree/doc/fox.txt\n\n0 directories, 0 files\n", fs.Tree(mfs, "tree/doc/fox.txt"))
+	assert.Contains(t, fs.Tree(mfs, "missing"), "missing [error: ")
+
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(dir+"/fox.txt", []byte("fox"), 0644))
+	assert.NoError(t, os.Symlink("fox.txt", dir+"/link"))
+	assert.Contains(t, fs.Tree(osfs, dir), "└── link -> fox.txt\n")
+}
@@ -1,13 +1,27 @@
 package fs
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"crypto"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/transientvariable/cadre"
+	"github.com/transientvariable/cadre/ecs"
+
+	gofs "io/fs"
+	gopath "path"
 )
 
+// xattrPrefix is the prefix of the PAX records used for extended attributes in the user namespace, which represent the
+// user-defined metadata of an entry in a tar archive.
+const xattrPrefix = "SCHILY.xattr.user."
+
 // FileMetadata converts a file system entry and produces a cadre.File.
 func FileMetadata(fsys FS, entry *Entry) (*cadre.File, error) {
 	if fsys == nil {
@@ -23,30 +37,288 @@ func FileMetadata(fsys FS, entry *Entry) (*cadre.File, error) {
 		return nil, err
 	}
 
+	attrs := entry.Attributes()
 	mtime := entry.ModTime()
 	m := &cadre.File{
+		Accessed:  timeOf(attrs.Atime()),
+		Created:   timeOf(attrs.Btime()),
 		Ctime:     &mtime,
-		GID:       itoa(int(entry.Attributes().GID())),
+		GID:       strconv.FormatUint(uint64(uint32(attrs.GID())), 10),
+		Group:     attrs.Group(),
 		Directory: filepath.Join(fsys.PathSeparator(), r, filepath.Dir(entry.Path())),
-		Inode:     itoa(int(entry.Attributes().Inode())),
-		Mode:      itoa(int(entry.Mode())),
+		Inode:     strconv.FormatUint(uint64(attrs.Inode()), 10),
+		Mode:      strconv.FormatUint(uint64(entry.Mode()), 10),
 		Mtime:     &mtime,
 		Name:      entry.Name(),
-		Owner:     entry.Attributes().Owner(),
+		Owner:     attrs.Owner(),
 		Path:      filepath.Join(fsys.PathSeparator(), r, entry.Path()),
-		UID:       itoa(int(entry.Attributes().UID())),
+		UID:       strconv.FormatUint(uint64(uint32(attrs.UID())), 10),
 	}
 
 	if !entry.IsDir() {
-		m.MimeType = entry.Attributes().MimeType()
+		m.Hash = hashOf(attrs)
+		m.MimeType = attrs.MimeType()
 		m.Size = entry.Size()
 	}
 	return m, nil
 }
 
-func itoa(v int) string {
-	if v > 0 {
-		return strconv.Itoa(v)
+// FileMetadataToEntry converts a cadre.File produced by FileMetadata for the provided file system back to an Entry, so
+// that indexed file metadata can be used with the file system.
+//
+// The path of the Entry is the path of the cadre.File relative to the root of fsys, and an error is returned if the
+// path is not under the root. Properties that are not represented by a cadre.File, such as the generation and the
+// user-defined metadata, are not set.
+func FileMetadataToEntry(fsys FS, file *cadre.File) (*Entry, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	if file == nil {
+		return nil, errors.New("fs: file metadata is required")
+	}
+
+	r, err := fsys.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := filepath.Rel(filepath.Join(fsys.PathSeparator(), r), filepath.Join(fsys.PathSeparator(), file.Path))
+	if err != nil || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("fs: path %s is not under root %s: %w", file.Path, r, ErrInvalid)
+	}
+
+	mode, err := atoi(file.Mode, 32)
+	if err != nil {
+		return nil, fmt.Errorf("fs: mode for %s: %w", file.Path, err)
+	}
+
+	uid, err := atoi(file.UID, 32)
+	if err != nil {
+		return nil, fmt.Errorf("fs: uid for %s: %w", file.Path, err)
+	}
+
+	gid, err := atoi(file.GID, 32)
+	if err != nil {
+		return nil, fmt.Errorf("fs: gid for %s: %w", file.Path, err)
+	}
+
+	inode, err := atoi(file.Inode, 64)
+	if err != nil {
+		return nil, fmt.Errorf("fs: inode for %s: %w", file.Path, err)
+	}
+
+	attributes := []func(*Attribute){
+		WithGID(uint32(gid)),
+		WithGroup(file.Group),
+		WithInode(inode),
+		WithMode(uint32(mode)),
+		WithOwner(file.Owner),
+		WithUID(uint32(uid)),
+	}
+
+	for _, t := range []struct {
+		opt func(time.Time) func(*Attribute)
+		t   *time.Time
+	}{
+		{WithAtime, file.Accessed},
+		{WithBtime, file.Created},
+		{WithCtime, file.Ctime},
+		{WithMtime, file.Mtime},
+	} {
+		if t.t != nil {
+			attributes = append(attributes, t.opt(*t.t))
+		}
+	}
+
+	if !gofs.FileMode(mode).IsDir() {
+		attributes = append(attributes, WithMimeType(file.MimeType), WithSize(uint64(max(file.Size, 0))))
+		if file.Hash != nil {
+			for alg, digest := range map[string]string{
+				"adler32":                        file.Hash.Adler32,
+				ChecksumAlgorithm(crypto.MD5):    file.Hash.Md5,
+				ChecksumAlgorithm(crypto.SHA1):   file.Hash.Sha1,
+				ChecksumAlgorithm(crypto.SHA256): file.Hash.Sha256,
+				ChecksumAlgorithm(crypto.SHA512): file.Hash.Sha512,
+				"ssdeep":                         file.Hash.Ssdeep,
+			} {
+				attributes = append(attributes, WithChecksum(alg, digest))
+			}
+		}
+	}
+
+	attrs, err := NewAttributes(attributes...)
+	if err != nil {
+		return nil, err
+	}
+	return NewEntry(filepath.ToSlash(p), WithAttributes(attrs))
+}
+
+// TarHeader converts an Entry to a tar.Header that can be used to add the entry to a tar archive.
+//
+// The name of the header is the path of the Entry, with a trailing slash for directories. The mode, owner and group,
+// size, access and modification times, and the destination of symbolic links are preserved, and the user-defined
+// metadata of the Entry is represented by extended attributes in the user namespace. The header uses the PAX format
+// so that the times are not rounded.
+func TarHeader(entry *Entry) (*tar.Header, error) {
+	if entry == nil {
+		return nil, errors.New("fs: entry is required")
+	}
+
+	attrs := entry.Attributes()
+	hdr, err := tar.FileInfoHeader(entry, attrs.LinkTarget())
+	if err != nil {
+		return nil, fmt.Errorf("fs: %w", err)
+	}
+
+	hdr.Name = entry.Path()
+	if entry.IsDir() {
+		hdr.Name += "/"
+	}
+
+	hdr.AccessTime = attrs.Atime()
+	hdr.Format = tar.FormatPAX
+	hdr.Gid = int(attrs.GID())
+	hdr.Gname = attrs.Group()
+	hdr.ModTime = entry.ModTime()
+	hdr.Uid = int(attrs.UID())
+	hdr.Uname = attrs.Owner()
+
+	for k, v := range attrs.Metadata() {
+		if hdr.PAXRecords == nil {
+			hdr.PAXRecords = make(map[string]string)
+		}
+		hdr.PAXRecords[xattrPrefix+k] = v
+	}
+	return hdr, nil
+}
+
+// EntryFromTarHeader converts a tar.Header read from a tar archive to an Entry, preserving the properties represented
+// by TarHeader.
+//
+// The path of the Entry is the cleaned name of the header, without leading or trailing slashes, and an error is
+// returned if the name is not a valid path (e.g. if it contains ".." elements).
+func EntryFromTarHeader(hdr *tar.Header) (*Entry, error) {
+	if hdr == nil {
+		return nil, errors.New("fs: tar header is required")
+	}
+
+	p, err := archivePath(hdr.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range hdr.PAXRecords {
+		if key, ok := strings.CutPrefix(k, xattrPrefix); ok {
+			metadata[key] = v
+		}
+	}
+
+	attributes := []func(*Attribute){
+		WithGID(uint32(max(hdr.Gid, 0))),
+		WithGroup(hdr.Gname),
+		WithLinkTarget(hdr.Linkname),
+		WithMetadata(metadata),
+		WithOwner(hdr.Uname),
+		WithUID(uint32(max(hdr.Uid, 0))),
+	}
+
+	if !hdr.AccessTime.IsZero() {
+		attributes = append(attributes, WithAtime(hdr.AccessTime))
+	}
+	return entryFromFileInfo(p, hdr.FileInfo(), attributes...)
+}
+
+// ZipFileHeader converts an Entry to a zip.FileHeader that can be used to add the entry to a zip archive.
+//
+// The name of the header is the path of the Entry, with a trailing slash for directories. Only the mode, size and
+// modification time are preserved, since zip archives do not represent the other properties of an Entry. Files are
+// compressed using zip.Deflate.
+func ZipFileHeader(entry *Entry) (*zip.FileHeader, error) {
+	if entry == nil {
+		return nil, errors.New("fs: entry is required")
+	}
+
+	hdr, err := zip.FileInfoHeader(entry)
+	if err != nil {
+		return nil, fmt.Errorf("fs: %w", err)
+	}
+
+	hdr.Name = entry.Path()
+	if entry.IsDir() {
+		hdr.Name += "/"
+	} else {
+		hdr.Method = zip.Deflate
+	}
+	return hdr, nil
+}
+
+// EntryFromZipFileHeader converts a zip.FileHeader read from a zip archive to an Entry, preserving the properties
+// represented by ZipFileHeader.
+//
+// The path of the Entry is the cleaned name of the header, without leading or trailing slashes, and an error is
+// returned if the name is not a valid path (e.g. if it contains ".." elements).
+func EntryFromZipFileHeader(hdr *zip.FileHeader) (*Entry, error) {
+	if hdr == nil {
+		return nil, errors.New("fs: zip file header is required")
+	}
+
+	p, err := archivePath(hdr.Name)
+	if err != nil {
+		return nil, err
+	}
+	return entryFromFileInfo(p, hdr.FileInfo())
+}
+
+// archivePath returns the path for the name of an entry in an archive, rejecting names that are not valid paths.
+func archivePath(name string) (string, error) {
+	p := gopath.Clean(strings.TrimLeft(strings.ReplaceAll(name, "\\", "/"), "/"))
+	if !gofs.ValidPath(p) {
+		return "", fmt.Errorf("fs: archive entry name %s: %w", name, ErrInvalid)
+	}
+	return p, nil
+}
+
+// hashOf returns the checksums of attrs that are represented by an ecs.Hash, or nil if there are none.
+func hashOf(attrs *Attribute) *ecs.Hash {
+	digest := func(alg string) string {
+		d, _ := attrs.Checksum(alg)
+		return d
+	}
+
+	h := &ecs.Hash{
+		Adler32: digest("adler32"),
+		Md5:     digest(ChecksumAlgorithm(crypto.MD5)),
+		Sha1:    digest(ChecksumAlgorithm(crypto.SHA1)),
+		Sha256:  digest(ChecksumAlgorithm(crypto.SHA256)),
+		Sha512:  digest(ChecksumAlgorithm(crypto.SHA512)),
+		Ssdeep:  digest("ssdeep"),
+	}
+
+	if *h == (ecs.Hash{}) {
+		return nil
+	}
+	return h
+}
+
+// timeOf returns a pointer to t, or nil if t is the zero time.
+func timeOf(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// atoi parses the unsigned integer s, where an empty string represents 0.
+func atoi(s string, bitSize int) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalid, err)
 	}
-	return ""
+	return v, nil
 }
@@ -0,0 +1,233 @@
+package fs_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/transientvariable/cadre"
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestFileMetadataToEntry(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	attrs, err := fs.NewAttributes(
+		fs.WithAtime(now),
+		fs.WithBtime(now.Add(-time.Hour)),
+		fs.WithChecksum("sha256", "9b2cf535f27731c974343645a3985328"),
+		fs.WithCtime(now),
+		fs.WithGID(100),
+		fs.WithGroup("animals"),
+		fs.WithInode(42),
+		fs.WithMimeType("text/plain; charset=utf-8"),
+		fs.WithMode(0644),
+		fs.WithMtime(now),
+		fs.WithOwner("fox"),
+		fs.WithSize(19),
+		fs.WithUID(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.NewEntry("doc/fox.txt", fs.WithAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := fs.FileMetadata(mfs, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/doc/fox.txt", m.Path)
+
+	rt, err := fs.FileMetadataToEntry(mfs, m)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", rt.Path())
+	assert.Equal(t, gofs.FileMode(0644), rt.Mode())
+	assert.Equal(t, int64(19), rt.Size())
+	assert.True(t, rt.ModTime().Equal(now))
+
+	a := rt.Attributes()
+	assert.True(t, a.Atime().Equal(now))
+	assert.True(t, a.Btime().Equal(now.Add(-time.Hour)))
+	assert.Equal(t, int32(100), a.GID())
+	assert.Equal(t, "animals", a.Group())
+	assert.Equal(t, int64(42), a.Inode())
+	assert.Equal(t, "text/plain; charset=utf-8", a.MimeType())
+	assert.Equal(t, "fox", a.Owner())
+	assert.Equal(t, int32(1000), a.UID())
+	assert.Equal(t, map[string]string{"sha256": "9b2cf535f27731c974343645a3985328"}, a.Checksums())
+
+	dir, err := fs.NewEntry("doc", fs.WithAttributes(must(fs.NewAttributes(fs.WithMode(uint32(gofs.ModeDir|0755))))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = fs.FileMetadata(mfs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err = fs.FileMetadataToEntry(mfs, m)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc", rt.Path())
+	assert.True(t, rt.IsDir())
+
+	_, err = fs.FileMetadataToEntry(mfs, &cadre.File{Path: "/doc/fox.txt", Mode: "rw-r--r--"})
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = fs.FileMetadataToEntry(mfs, nil)
+	assert.Error(t, err)
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestTarHeader(t *testing.T) {
+	now := time.Now().UTC()
+	attrs, err := fs.NewAttributes(
+		fs.WithAtime(now.Add(time.Minute)),
+		fs.WithCtime(now),
+		fs.WithGID(100),
+		fs.WithGroup("animals"),
+		fs.WithMetadata(map[string]string{"color": "brown"}),
+		fs.WithMode(0640),
+		fs.WithMtime(now),
+		fs.WithOwner("fox"),
+		fs.WithSize(19),
+		fs.WithUID(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.NewEntry("doc/fox.txt", fs.WithAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr, err := fs.TarHeader(e)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.WriteHeader(hdr))
+	_, err = tw.Write([]byte("the quick brown fox"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+
+	hdr, err = tar.NewReader(&buf).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "doc/fox.txt", hdr.Name)
+
+	rt, err := fs.EntryFromTarHeader(hdr)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", rt.Path())
+	assert.Equal(t, gofs.FileMode(0640), rt.Mode())
+	assert.Equal(t, int64(19), rt.Size())
+	assert.True(t, rt.ModTime().Equal(now))
+
+	a := rt.Attributes()
+	assert.True(t, a.Atime().Equal(now.Add(time.Minute)))
+	assert.Equal(t, int32(100), a.GID())
+	assert.Equal(t, "animals", a.Group())
+	assert.Equal(t, map[string]string{"color": "brown"}, a.Metadata())
+	assert.Equal(t, "fox", a.Owner())
+	assert.Equal(t, int32(1000), a.UID())
+
+	link, err := fs.NewEntry("doc/link", fs.WithAttributes(must(fs.NewAttributes(
+		fs.WithLinkTarget("fox.txt"),
+		fs.WithMode(uint32(gofs.ModeSymlink|0777))))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, err = fs.TarHeader(link)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(tar.TypeSymlink), hdr.Typeflag)
+
+	rt, err = fs.EntryFromTarHeader(hdr)
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.ModeSymlink, rt.Type())
+	assert.Equal(t, "fox.txt", rt.Attributes().LinkTarget())
+
+	rt, err = fs.EntryFromTarHeader(&tar.Header{Name: "./doc/", Typeflag: tar.TypeDir, Mode: 0755})
+	assert.NoError(t, err)
+	assert.Equal(t, "doc", rt.Path())
+	assert.True(t, rt.IsDir())
+
+	_, err = fs.EntryFromTarHeader(&tar.Header{Name: "../../etc/passwd", Typeflag: tar.TypeReg})
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestZipFileHeader(t *testing.T) {
+	mtime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	attrs, err := fs.NewAttributes(fs.WithCtime(mtime), fs.WithMode(0600), fs.WithMtime(mtime), fs.WithSize(19))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := fs.NewEntry("doc/fox.txt", fs.WithAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, err := fs.ZipFileHeader(e)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", hdr.Name)
+	assert.Equal(t, zip.Deflate, hdr.Method)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Write([]byte("the quick brown fox"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := fs.EntryFromZipFileHeader(&zr.File[0].FileHeader)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/fox.txt", rt.Path())
+	assert.Equal(t, gofs.FileMode(0600), rt.Mode())
+	assert.Equal(t, int64(19), rt.Size())
+	assert.True(t, rt.ModTime().Equal(mtime))
+
+	dir, err := fs.NewEntry("doc", fs.WithAttributes(must(fs.NewAttributes(fs.WithMode(uint32(gofs.ModeDir|0755))))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, err = fs.ZipFileHeader(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc/", hdr.Name)
+
+	rt, err = fs.EntryFromZipFileHeader(hdr)
+	assert.NoError(t, err)
+	assert.True(t, rt.IsDir())
+
+	_, err = fs.EntryFromZipFileHeader(&zip.FileHeader{Name: "..\\..\\fox.txt"})
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
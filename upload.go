@@ -0,0 +1,207 @@
+package fs
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	gofs "io/fs"
+)
+
+var _ MultipartWriter = (*stagedMultipart)(nil)
+
+// MultipartFS is implemented by a file system that supports writing large files in parts, such as a provider for an
+// object store that supports multipart uploads.
+type MultipartFS interface {
+	FS
+
+	// BeginMultipart starts writing the named file in parts. The file is not created or replaced until the parts are
+	// completed using the returned MultipartWriter.
+	BeginMultipart(name string, perm gofs.FileMode) (MultipartWriter, error)
+}
+
+// MultipartWriter writes the content of a file in numbered parts, so that files that are too large to be written
+// reliably in a single operation can be written in parts that are retried individually.
+type MultipartWriter interface {
+	// WritePart writes the content read from r as the part numbered n, starting at 1, and returns the number of bytes
+	// written. Writing a part that was already written replaces it, so a part that could not be written can be retried.
+	// Parts can be written in any order, and by concurrent callers.
+	WritePart(n int, r io.Reader) (int64, error)
+
+	// Complete creates or replaces the file with the content of the parts in ascending order of their numbers. If an
+	// error is returned, the parts are retained, so that Complete can be retried or the parts can be discarded using
+	// Abort.
+	Complete() error
+
+	// Abort discards the parts that were written. The file is not changed.
+	Abort() error
+}
+
+// BeginMultipart starts writing the named file in fsys in parts using a MultipartWriter.
+//
+// If fsys implements MultipartFS, its BeginMultipart method is used, so that providers can map the parts to the native
+// parts of a multipart upload. Otherwise, each part is staged as a temporary file in a hidden directory next to the
+// named file, and the parts are assembled into a temporary file that is renamed to the named file by Complete, so that
+// readers never observe a partially written file if fsys renames files atomically.
+func BeginMultipart(fsys FS, name string, perm gofs.FileMode) (MultipartWriter, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	if m, ok := fsys.(MultipartFS); ok {
+		return m.BeginMultipart(name, perm)
+	}
+
+	if base := Base(fsys, name); base == "." || base == ".." || base == fsys.PathSeparator() {
+		return nil, NewOpError(fsys.Provider(), "beginMultipart", name, ErrInvalid)
+	}
+
+	staging := Join(fsys, Dir(fsys, name), "."+Base(fsys, name)+".multipart-"+rand.Text())
+	if err := fsys.MkdirAll(staging, 0700); err != nil {
+		return nil, err
+	}
+	return &stagedMultipart{fsys: fsys, name: name, parts: make(map[int]int64), perm: perm, staging: staging}, nil
+}
+
+// stagedMultipart is a MultipartWriter that stages each part as a temporary file.
+type stagedMultipart struct {
+	done    bool
+	fsys    FS
+	mutex   sync.Mutex
+	name    string
+	parts   map[int]int64
+	perm    gofs.FileMode
+	staging string
+}
+
+func (m *stagedMultipart) Abort() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.done {
+		return NewOpError(m.fsys.Provider(), "abortMultipart", m.name, ErrClosed)
+	}
+	m.done = true
+	return m.fsys.RemoveAll(m.staging)
+}
+
+func (m *stagedMultipart) Complete() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.done {
+		return NewOpError(m.fsys.Provider(), "completeMultipart", m.name, ErrClosed)
+	}
+
+	tmp := Join(m.fsys, m.staging, "complete.tmp")
+	if err := m.assemble(tmp); err != nil {
+		_ = m.fsys.Remove(tmp)
+		return err
+	}
+
+	if err := m.fsys.Rename(tmp, m.name); err != nil {
+		_ = m.fsys.Remove(tmp)
+		return err
+	}
+	m.done = true
+	return m.fsys.RemoveAll(m.staging)
+}
+
+func (m *stagedMultipart) WritePart(n int, r io.Reader) (int64, error) {
+	if n < 1 {
+		return 0, NewOpError(m.fsys.Provider(), "writePart", m.name, fmt.Errorf("part %d: %w", n, ErrInvalid))
+	}
+
+	if r == nil {
+		return 0, NewOpError(m.fsys.Provider(), "writePart", m.name, ErrInvalid)
+	}
+
+	m.mutex.Lock()
+	done := m.done
+	m.mutex.Unlock()
+
+	if done {
+		return 0, NewOpError(m.fsys.Provider(), "writePart", m.name, ErrClosed)
+	}
+
+	// Each part is written to a temporary file that is renamed once the part is complete, so that a part that could
+	// not be written, or that is being written by another caller, never replaces a complete part.
+	part := m.part(n)
+	tmp := part + "." + rand.Text() + ".tmp"
+	f, err := m.fsys.OpenFile(tmp, O_WRONLY|O_CREATE|O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = m.fsys.Remove(tmp)
+		return size, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.done {
+		_ = m.fsys.Remove(tmp)
+		return 0, NewOpError(m.fsys.Provider(), "writePart", m.name, ErrClosed)
+	}
+
+	if err := m.fsys.Rename(tmp, part); err != nil {
+		_ = m.fsys.Remove(tmp)
+		return 0, err
+	}
+	m.parts[n] = size
+	return size, nil
+}
+
+// assemble writes the content of the parts in ascending order of their numbers to the named file. The caller must
+// hold the mutex.
+func (m *stagedMultipart) assemble(name string) error {
+	f, err := m.fsys.OpenFile(name, O_WRONLY|O_CREATE|O_TRUNC, m.perm)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range slices.Sorted(maps.Keys(m.parts)) {
+		if err := m.copyPart(f, n); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// copyPart copies the content of the part numbered n to w.
+func (m *stagedMultipart) copyPart(w io.Writer, n int) error {
+	f, err := m.fsys.Open(m.part(n))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	size, err := io.Copy(w, f)
+	if err != nil {
+		return err
+	}
+
+	if size != m.parts[n] {
+		return NewOpError(m.fsys.Provider(), "completeMultipart", m.name,
+			fmt.Errorf("part %d has %d bytes, expected %d: %w", n, size, m.parts[n], ErrInvalid))
+	}
+	return nil
+}
+
+// part returns the path of the staged file for the part numbered n.
+func (m *stagedMultipart) part(n int) string {
+	return Join(m.fsys, m.staging, strconv.Itoa(n))
+}
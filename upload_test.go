@@ -0,0 +1,92 @@
+package fs_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestBeginMultipart(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the lazy dog"), 0644))
+
+	w, err := fs.BeginMultipart(mfs, "doc/fox.txt", 0640)
+	assert.NoError(t, err)
+
+	parts := []string{"the ", "quick ", "brown ", "fox"}
+	var wg sync.WaitGroup
+	for i := len(parts) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := w.WritePart(i+1, strings.NewReader(parts[i]))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(parts[i])), n)
+		}()
+	}
+	wg.Wait()
+
+	// A part that could not be written is retried without replacing the part that was written before.
+	_, err = w.WritePart(2, iotest.ErrReader(errors.New("connection reset")))
+	assert.Error(t, err)
+
+	_, err = w.WritePart(0, strings.NewReader("jumps"))
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	content, err := mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the lazy dog", string(content))
+
+	assert.NoError(t, w.Complete())
+	assert.ErrorIs(t, w.Complete(), fs.ErrClosed)
+
+	content, err = mfs.ReadFile("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick brown fox", string(content))
+
+	fi, err := mfs.Stat("doc/fox.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, gofs.FileMode(0640), fi.Mode().Perm())
+
+	entries, err := mfs.ReadDir("doc")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestBeginMultipart_Abort(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := fs.BeginMultipart(mfs, "large.bin", 0644)
+	assert.NoError(t, err)
+
+	_, err = w.WritePart(1, bytes.NewReader(make([]byte, 1<<16)))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Abort())
+
+	_, err = w.WritePart(2, bytes.NewReader(nil))
+	assert.ErrorIs(t, err, fs.ErrClosed)
+
+	entries, err := mfs.ReadDir(".")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = fs.BeginMultipart(mfs, ".", 0644)
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
@@ -0,0 +1,552 @@
+//go:build linux
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	gofs "io/fs"
+)
+
+// Constants defined by the io_uring interface of the Linux kernel.
+const (
+	uringOffSQRing = 0
+	uringOffCQRing = 0x8000000
+	uringOffSQEs   = 0x10000000
+
+	uringEnterGetEvents = 1 << 0
+
+	uringFeatNoDrop   = 1 << 1
+	uringFeatRWCurPos = 1 << 3
+
+	uringOpNop   = 0
+	uringOpRead  = 22
+	uringOpWrite = 23
+
+	// uringMaxEntries is the maximum number of submission queue entries supported by the kernel.
+	uringMaxEntries = 32768
+
+	// uringCurPos is the offset that reads and writes from the current position of a file.
+	uringCurPos = ^uint64(0)
+)
+
+// uringParams is the struct io_uring_params passed to io_uring_setup.
+type uringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        struct {
+		head        uint32
+		tail        uint32
+		ringMask    uint32
+		ringEntries uint32
+		flags       uint32
+		dropped     uint32
+		array       uint32
+		resv1       uint32
+		userAddr    uint64
+	}
+	cqOff struct {
+		head        uint32
+		tail        uint32
+		ringMask    uint32
+		ringEntries uint32
+		overflow    uint32
+		cqes        uint32
+		flags       uint32
+		resv1       uint32
+		userAddr    uint64
+	}
+}
+
+// uringSQE is the struct io_uring_sqe that describes an operation in the submission queue.
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	addr3       uint64
+	_           uint64
+}
+
+// uringCQE is the struct io_uring_cqe that describes the result of an operation in the completion queue.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uringReq is a read or write submitted to a uring, and its result, which is the number of bytes transferred or a
+// negated errno.
+type uringReq struct {
+	buf []byte
+	fd  int32
+	off uint64
+	op  uint8
+	res int32
+}
+
+// uringPending is a request that was submitted to a uring, and the group of requests it was submitted with.
+type uringPending struct {
+	req *uringReq
+	wg  *sync.WaitGroup
+}
+
+// uring submits reads and writes to an io_uring instance, and dispatches their completions using a poller goroutine.
+//
+// Requests are submitted in batches, so that the requests of a batch are submitted with a single io_uring_enter call.
+// The number of requests in flight is limited to the size of the completion queue.
+type uring struct {
+	acquire   sync.Mutex
+	closed    bool
+	cq        []byte
+	cqHead    *uint32
+	cqMask    uint32
+	cqTail    *uint32
+	cqes      []uringCQE
+	done      chan struct{}
+	err       error
+	fd        int
+	inflight  chan struct{}
+	mutex     sync.Mutex
+	next      uint64
+	ops       sync.RWMutex
+	pending   map[uint64]uringPending
+	sq        []byte
+	sqArray   []uint32
+	sqEntries uint32
+	sqHead    *uint32
+	sqMask    uint32
+	sqTail    *uint32
+	sqeMem    []byte
+	sqes      []uringSQE
+}
+
+// newURing creates an io_uring instance with a submission queue of the given number of entries, and starts the poller
+// for its completions. An error wrapping ErrUnsupported is returned if io_uring is not available, or if the kernel
+// does not support the features used by uring.
+func newURing(entries int) (*uring, error) {
+	if entries <= 0 || entries > uringMaxEntries {
+		return nil, fmt.Errorf("fs: io_uring entries must be between 1 and %d: %w", uringMaxEntries, ErrInvalid)
+	}
+
+	var p uringParams
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EPERM || errno == unix.EACCES {
+			return nil, fmt.Errorf("fs: io_uring is not available: %w: %w", ErrUnsupported, errno)
+		}
+		return nil, fmt.Errorf("fs: io_uring setup: %w", errno)
+	}
+
+	r := &uring{
+		done:     make(chan struct{}),
+		fd:       int(fd),
+		inflight: make(chan struct{}, p.cqEntries),
+		next:     1,
+		pending:  make(map[uint64]uringPending),
+	}
+
+	if p.features&uringFeatNoDrop == 0 || p.features&uringFeatRWCurPos == 0 {
+		_ = unix.Close(r.fd)
+		return nil, fmt.Errorf("fs: io_uring features are not supported by the kernel: %w", ErrUnsupported)
+	}
+
+	if err := r.mmap(&p); err != nil {
+		r.unmap()
+		return nil, fmt.Errorf("fs: io_uring mmap: %w", err)
+	}
+
+	go r.poll()
+	return r, nil
+}
+
+// close waits for the requests in progress to complete, stops the poller, and releases the io_uring instance.
+func (r *uring) close() error {
+	r.ops.Lock()
+	defer r.ops.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	// The poller stops once it receives the completion of a no-op request with no user data.
+	r.mutex.Lock()
+	err := r.push([]*uringReq{{op: uringOpNop}}, nil)
+	r.mutex.Unlock()
+
+	if err == nil {
+		<-r.done
+	}
+	r.unmap()
+	return err
+}
+
+// enter calls io_uring_enter to submit n requests, and to wait for wait completions if flags includes
+// uringEnterGetEvents.
+func (r *uring) enter(n uint32, wait uint32, flags uint32) (int, error) {
+	c, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(n), uintptr(wait), uintptr(flags), 0, 0)
+	if errno != 0 {
+		return int(c), errno
+	}
+	return int(c), nil
+}
+
+// file returns f as a File whose reads and writes are performed using the uring.
+func (r *uring) file(f *os.File) File {
+	return &uringFile{osFile: &osFile{File: f}, ring: r}
+}
+
+func (r *uring) mmap(p *uringParams) error {
+	var err error
+	if r.sq, err = unix.Mmap(r.fd, uringOffSQRing, int(p.sqOff.array+p.sqEntries*4),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		return err
+	}
+
+	if r.cq, err = unix.Mmap(r.fd, uringOffCQRing, int(p.cqOff.cqes+p.cqEntries*uint32(unsafe.Sizeof(uringCQE{}))),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		return err
+	}
+
+	if r.sqeMem, err = unix.Mmap(r.fd, uringOffSQEs, int(p.sqEntries*uint32(unsafe.Sizeof(uringSQE{}))),
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		return err
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sq[p.sqOff.head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sq[p.sqOff.tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&r.sq[p.sqOff.ringMask]))
+	r.sqEntries = p.sqEntries
+	r.sqArray = unsafe.Slice((*uint32)(unsafe.Pointer(&r.sq[p.sqOff.array])), p.sqEntries)
+	r.sqes = unsafe.Slice((*uringSQE)(unsafe.Pointer(&r.sqeMem[0])), p.sqEntries)
+
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cq[p.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cq[p.cqOff.tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&r.cq[p.cqOff.ringMask]))
+	r.cqes = unsafe.Slice((*uringCQE)(unsafe.Pointer(&r.cq[p.cqOff.cqes])), p.cqEntries)
+	return nil
+}
+
+// poll dispatches the completions of requests until the poller is stopped by close. If waiting for completions fails,
+// the requests in flight are failed with the error, and no further requests are accepted.
+func (r *uring) poll() {
+	defer close(r.done)
+
+	for {
+		if _, err := r.enter(0, 1, uringEnterGetEvents); err != nil && !errors.Is(err, syscall.EINTR) {
+			r.mutex.Lock()
+			r.err = err
+			for id, p := range r.pending {
+				delete(r.pending, id)
+				p.req.res = -int32(unix.EIO)
+				p.wg.Done()
+				<-r.inflight
+			}
+			r.mutex.Unlock()
+			return
+		}
+
+		var stop bool
+		head := *r.cqHead
+		tail := atomic.LoadUint32(r.cqTail)
+
+		r.mutex.Lock()
+		for ; head != tail; head++ {
+			cqe := &r.cqes[head&r.cqMask]
+			if cqe.userData == 0 {
+				stop = true
+				continue
+			}
+
+			if p, ok := r.pending[cqe.userData]; ok {
+				delete(r.pending, cqe.userData)
+				p.req.res = cqe.res
+				p.wg.Done()
+				<-r.inflight
+			}
+		}
+		r.mutex.Unlock()
+		atomic.StoreUint32(r.cqHead, head)
+
+		if stop {
+			return
+		}
+	}
+}
+
+// push adds reqs to the submission queue and submits them. If wg is not nil, the requests are added to the pending
+// requests, so that their completions are dispatched by the poller. If the requests can not be submitted, the requests
+// that were not consumed by the kernel are removed from the submission queue, and completed with the error. The caller
+// must hold the lock for the submission queue.
+func (r *uring) push(reqs []*uringReq, wg *sync.WaitGroup) error {
+	ids := make([]uint64, len(reqs))
+	start := *r.sqTail
+	tail := start
+	for i, req := range reqs {
+		if wg != nil {
+			ids[i] = r.next
+			r.next++
+			r.pending[ids[i]] = uringPending{req: req, wg: wg}
+		}
+
+		idx := tail & r.sqMask
+		r.sqes[idx] = uringSQE{
+			opcode:   req.op,
+			fd:       req.fd,
+			off:      req.off,
+			addr:     uint64(uintptr(unsafe.Pointer(unsafe.SliceData(req.buf)))),
+			len:      uint32(len(req.buf)),
+			userData: ids[i],
+		}
+		r.sqArray[idx] = idx
+		tail++
+	}
+	atomic.StoreUint32(r.sqTail, tail)
+
+	for atomic.LoadUint32(r.sqHead) != tail {
+		_, err := r.enter(tail-atomic.LoadUint32(r.sqHead), 0, 0)
+		if err == nil || errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY) {
+			continue
+		}
+
+		// The kernel consumes the submission queue in order, so the requests after the head were not submitted.
+		head := atomic.LoadUint32(r.sqHead)
+		atomic.StoreUint32(r.sqTail, head)
+		for i := head - start; i < uint32(len(reqs)); i++ {
+			reqs[i].res = -int32(err.(syscall.Errno))
+			if wg != nil {
+				delete(r.pending, ids[i])
+				wg.Done()
+				<-r.inflight
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// submit submits reqs and waits for them to complete. The requests are submitted in batches of at most the size of the
+// submission queue, and their buffers are pinned until they complete.
+func (r *uring) submit(reqs []*uringReq) error {
+	r.ops.RLock()
+	defer r.ops.RUnlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	var (
+		pinner runtime.Pinner
+		wg     sync.WaitGroup
+	)
+	defer pinner.Unpin()
+
+	for _, req := range reqs {
+		if len(req.buf) > 0 {
+			pinner.Pin(unsafe.SliceData(req.buf))
+		}
+	}
+
+	var err error
+	for len(reqs) > 0 && err == nil {
+		batch := reqs[:min(len(reqs), int(r.sqEntries))]
+		reqs = reqs[len(batch):]
+
+		// Slots for the batch are acquired by one caller at a time, so that callers waiting for slots do not hold slots
+		// that another caller is waiting for.
+		r.acquire.Lock()
+		for range batch {
+			r.inflight <- struct{}{}
+		}
+		r.acquire.Unlock()
+
+		r.mutex.Lock()
+		if r.err != nil {
+			err = r.err
+			for range batch {
+				<-r.inflight
+			}
+		} else {
+			wg.Add(len(batch))
+			err = r.push(batch, &wg)
+		}
+		r.mutex.Unlock()
+	}
+	wg.Wait()
+	return err
+}
+
+func (r *uring) unmap() {
+	for _, b := range [][]byte{r.sq, r.cq, r.sqeMem} {
+		if b != nil {
+			_ = unix.Munmap(b)
+		}
+	}
+	_ = unix.Close(r.fd)
+}
+
+// uringFile is a file opened by OSFS whose reads and writes are performed using a uring. Reading the ranges of the file
+// using ReadAtMulti submits the reads for every range in a single batch.
+type uringFile struct {
+	*osFile
+	ring *uring
+}
+
+// Read reads up to len(b) bytes from the current position of the file.
+func (f *uringFile) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	req := &uringReq{buf: b, off: uringCurPos, op: uringOpRead}
+	if err := f.do("read", req); err != nil {
+		return 0, err
+	}
+
+	if req.res == 0 {
+		return 0, io.EOF
+	}
+	return int(req.res), nil
+}
+
+// ReadAt reads len(b) bytes from the file starting at off.
+func (f *uringFile) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &gofs.PathError{Op: "readat", Path: f.Name(), Err: ErrInvalid}
+	}
+
+	bufs, err := f.ReadAtMulti([]Range{{Off: off, Len: int64(len(b))}})
+	if bufs == nil {
+		return 0, err
+	}
+	return copy(b, bufs[0]), err
+}
+
+// ReadAtMulti reads each range of the file in ranges, submitting the reads for every range in a single batch. The
+// content of each range is returned in a new slice.
+func (f *uringFile) ReadAtMulti(ranges []Range) ([][]byte, error) {
+	var eof bool
+	bufs := make([][]byte, len(ranges))
+	reqs := make([]*uringReq, 0, len(ranges))
+	idx := make([]int, 0, len(ranges))
+	for i, r := range ranges {
+		if r.Off < 0 || r.Len < 0 {
+			return nil, &gofs.PathError{Op: "readat", Path: f.Name(), Err: ErrInvalid}
+		}
+
+		bufs[i] = make([]byte, r.Len)
+		if r.Len > 0 {
+			reqs = append(reqs, &uringReq{buf: bufs[i], off: uint64(r.Off), op: uringOpRead})
+			idx = append(idx, i)
+		}
+	}
+
+	// A read may transfer fewer bytes than requested, in which case the remainder of the range is read again, until
+	// the range is complete or the end of the file is reached.
+	n := make([]int, len(ranges))
+	for len(reqs) > 0 {
+		if err := f.do("readat", reqs...); err != nil {
+			return nil, err
+		}
+
+		var (
+			next    []*uringReq
+			nextIdx []int
+		)
+		for j, req := range reqs {
+			i := idx[j]
+			n[i] += int(req.res)
+			switch {
+			case req.res == 0:
+				eof = true
+			case n[i] < len(bufs[i]):
+				next = append(next, &uringReq{buf: bufs[i][n[i]:], off: uint64(ranges[i].Off) + uint64(n[i]), op: uringOpRead})
+				nextIdx = append(nextIdx, i)
+			}
+		}
+		reqs, idx = next, nextIdx
+	}
+
+	for i := range bufs {
+		bufs[i] = bufs[i][:n[i]]
+	}
+
+	if eof {
+		return bufs, io.EOF
+	}
+	return bufs, nil
+}
+
+// Write writes len(b) bytes to the current position of the file.
+func (f *uringFile) Write(b []byte) (int, error) {
+	var n int
+	for n < len(b) {
+		req := &uringReq{buf: b[n:], off: uringCurPos, op: uringOpWrite}
+		if err := f.do("write", req); err != nil {
+			return n, err
+		}
+
+		if req.res == 0 {
+			return n, &gofs.PathError{Op: "write", Path: f.Name(), Err: io.ErrShortWrite}
+		}
+		n += int(req.res)
+	}
+	return n, nil
+}
+
+// do submits reqs for the file descriptor of the file and waits for them to complete. The file can not be closed while
+// the requests are in progress. An error is returned if a request failed.
+func (f *uringFile) do(op string, reqs ...*uringReq) error {
+	rc, err := f.File.SyscallConn()
+	if err != nil {
+		return &gofs.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		for _, req := range reqs {
+			req.fd = int32(fd)
+		}
+		serr = f.ring.submit(reqs)
+	})
+
+	if err != nil {
+		return &gofs.PathError{Op: op, Path: f.Name(), Err: ErrClosed}
+	}
+
+	if serr != nil {
+		return &gofs.PathError{Op: op, Path: f.Name(), Err: serr}
+	}
+
+	for _, req := range reqs {
+		if req.res < 0 {
+			return &gofs.PathError{Op: op, Path: f.Name(), Err: syscall.Errno(-req.res)}
+		}
+	}
+	return nil
+}
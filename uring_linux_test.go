@@ -0,0 +1,86 @@
+package fs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIOUring(t *testing.T) {
+	osfs, err := fs.New(fs.WithIOUring(8))
+	if errors.Is(err, fs.ErrUnsupported) {
+		t.Skip("io_uring is not available")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "fox.txt")
+	f, err := osfs.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("the quick brown fox "), 1000)
+	n, err := f.Write(content)
+	assert.NoError(t, err)
+	assert.Equal(t, len(content), n)
+	assert.NoError(t, f.Close())
+
+	f, err = osfs.OpenFile(name, fs.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, content, b)
+
+	// The ranges are read using more requests than the size of the submission queue, from concurrent readers.
+	ranges := make([]fs.Range, 20)
+	for i := range ranges {
+		ranges[i] = fs.Range{Off: int64(i * 20), Len: 9}
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			bufs, err := fs.ReadAtMulti(f, ranges)
+			assert.NoError(t, err)
+			for _, b := range bufs {
+				assert.Equal(t, "the quick", string(b))
+			}
+		}()
+	}
+	wg.Wait()
+
+	bufs, err := fs.ReadAtMulti(f, []fs.Range{{Off: int64(len(content) - 4), Len: 8}})
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "fox ", string(bufs[0]))
+
+	p := make([]byte, 5)
+	_, err = f.ReadAt(p, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "brown", string(p))
+
+	assert.NoError(t, f.Close())
+	_, err = f.Read(p)
+	assert.ErrorIs(t, err, fs.ErrClosed)
+
+	assert.NoError(t, osfs.Close())
+	assert.NoError(t, osfs.Close())
+
+	_, err = fs.New(fs.WithIOUring(-1))
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package fs
+
+import (
+	"fmt"
+	"os"
+)
+
+// uring is not supported on this platform.
+type uring struct{}
+
+// newURing returns an error wrapping ErrUnsupported, since io_uring is only supported on Linux.
+func newURing(_ int) (*uring, error) {
+	return nil, fmt.Errorf("fs: io_uring is not available: %w", ErrUnsupported)
+}
+
+func (r *uring) close() error {
+	return nil
+}
+
+func (r *uring) file(f *os.File) File {
+	return &osFile{File: f}
+}
@@ -0,0 +1,252 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	gofs "io/fs"
+)
+
+// Corruption describes an entry whose type, size, or content does not match the expected entry.
+type Corruption struct {
+	// Path is the path of the entry relative to the root of the verified tree.
+	Path string `json:"path"`
+
+	// Check is the attribute that does not match, which is "type", "size", or the name of a checksum algorithm as
+	// returned by ChecksumAlgorithm (e.g. "sha256").
+	Check string `json:"check"`
+
+	// Expected is the expected value of the attribute.
+	Expected string `json:"expected"`
+
+	// Actual is the value of the attribute computed from the entry.
+	Actual string `json:"actual"`
+}
+
+// VerifyOption defines an option for Verify.
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	checksums []crypto.Hash
+	walk      []WalkOption
+}
+
+// VerifyReport describes the result of verifying a tree using Verify. Paths are relative to the root of the tree, and
+// are sorted in lexical order.
+type VerifyReport struct {
+	// Corrupted lists the entries whose type, size, or content does not match the expected entry.
+	Corrupted []Corruption `json:"corrupted,omitempty"`
+
+	// Extra lists the entries in the tree that are not listed by the manifest.
+	Extra []string `json:"extra,omitempty"`
+
+	// Missing lists the entries listed by the manifest that are not in the tree.
+	Missing []string `json:"missing,omitempty"`
+
+	// Unverified lists the regular files whose content could not be verified, since no checksum with an available hash
+	// function is expected for them.
+	Unverified []string `json:"unverified,omitempty"`
+
+	// Verified is the number of regular files whose content matches every expected checksum that was verified.
+	Verified int `json:"verified"`
+}
+
+// OK reports whether no corrupted, extra, or missing entries were found.
+func (r *VerifyReport) OK() bool {
+	return len(r.Corrupted) == 0 && len(r.Extra) == 0 && len(r.Missing) == 0
+}
+
+// Verify recomputes the checksums of the regular files in the tree rooted at root and compares them with the expected
+// checksums, so that backups and replicas can be validated.
+//
+// If manifest is not nil, it must be a listing produced by Manifest, and each entry in the tree is compared with the
+// entry listed for the same path: entries that are not listed or not found are reported as extra and missing
+// respectively, and entries whose type or size differ are reported as corrupted. Otherwise, the content of each regular
+// file is compared with the checksums already known by the file system, which are provided by the Attribute of its
+// Entry, such as the checksums stored by MemFS when WithChecksum is enabled.
+//
+// Only checksums whose algorithm matches an available hash function are verified, and the hash functions can be
+// limited using WithVerifyChecksums. An error is returned if the tree can not be read, and the report is returned
+// otherwise, even if corrupted, extra, or missing entries were found.
+func Verify(fsys gofs.FS, root string, manifest io.Reader, options ...VerifyOption) (*VerifyReport, error) {
+	if fsys == nil {
+		return nil, errors.New("fs: file system is required")
+	}
+
+	opts := &verifyOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	var expected map[string]*Entry
+	if manifest != nil {
+		m, err := readManifest(manifest)
+		if err != nil {
+			return nil, err
+		}
+		expected = m
+	}
+
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	report := &VerifyReport{}
+	err := Walk(fsys, root, func(p string, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == root {
+			return nil
+		}
+
+		rel := p
+		if root != "." {
+			rel = strings.TrimPrefix(p, prefix)
+		}
+
+		want := entry
+		if expected != nil {
+			e, ok := expected[rel]
+			if !ok {
+				report.Extra = append(report.Extra, rel)
+				return nil
+			}
+			delete(expected, rel)
+			want = e
+
+			if want.Mode().Type() != entry.Mode().Type() {
+				report.Corrupted = append(report.Corrupted, Corruption{
+					Path:     rel,
+					Check:    "type",
+					Expected: want.Mode().Type().String(),
+					Actual:   entry.Mode().Type().String(),
+				})
+				return nil
+			}
+
+			if want.Mode().IsRegular() && want.Size() != entry.Size() {
+				report.Corrupted = append(report.Corrupted, Corruption{
+					Path:     rel,
+					Check:    "size",
+					Expected: strconv.FormatInt(want.Size(), 10),
+					Actual:   strconv.FormatInt(entry.Size(), 10),
+				})
+				return nil
+			}
+		}
+
+		if entry.Mode().IsRegular() {
+			return verifyChecksums(fsys, p, rel, want, opts, report)
+		}
+		return nil
+	}, opts.walk...)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Missing = slices.Sorted(maps.Keys(expected))
+	slices.Sort(report.Extra)
+	slices.SortFunc(report.Corrupted, func(a Corruption, b Corruption) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	slices.Sort(report.Unverified)
+	return report, nil
+}
+
+// WithVerifyChecksums sets the hash functions used by Verify to verify the content of regular files. Expected
+// checksums for other algorithms are ignored. By default, every expected checksum whose hash function is available is
+// verified.
+func WithVerifyChecksums(hashes ...crypto.Hash) VerifyOption {
+	return func(o *verifyOptions) {
+		o.checksums = append(o.checksums, hashes...)
+	}
+}
+
+// WithVerifyWalkOptions sets the options used by Verify to walk the tree, such as WithSkipPatterns or WithMaxDepth.
+// The manifest should be produced using the same options, so that skipped entries are not reported as missing.
+func WithVerifyWalkOptions(options ...WalkOption) VerifyOption {
+	return func(o *verifyOptions) {
+		o.walk = append(o.walk, options...)
+	}
+}
+
+// checksumHash returns the available hash function for the checksum algorithm, if any.
+func checksumHash(algorithm string) (crypto.Hash, bool) {
+	for h := crypto.MD4; h <= crypto.BLAKE2b_512; h++ {
+		if ChecksumAlgorithm(h) == algorithm {
+			return h, h.Available()
+		}
+	}
+	return 0, false
+}
+
+// readManifest reads the entries listed by a manifest produced by Manifest, keyed by path.
+func readManifest(r io.Reader) (map[string]*Entry, error) {
+	entries := make(map[string]*Entry)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("fs: manifest: %w", err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			e := &Entry{}
+			if err := json.Unmarshal(line, e); err != nil {
+				return nil, fmt.Errorf("fs: manifest: %w", err)
+			}
+			entries[e.Path()] = e
+		}
+
+		if err != nil {
+			return entries, nil
+		}
+	}
+}
+
+// verifyChecksums compares the content of the regular file at path p with the checksums of the expected entry want,
+// and records the result in report.
+func verifyChecksums(fsys gofs.FS, p string, rel string, want *Entry, opts *verifyOptions, report *VerifyReport) error {
+	checksums := want.Attributes().Checksums()
+	algorithms := slices.Sorted(maps.Keys(checksums))
+
+	verified := false
+	for _, algorithm := range algorithms {
+		hash, ok := checksumHash(algorithm)
+		if !ok || (len(opts.checksums) > 0 && !slices.Contains(opts.checksums, hash)) {
+			continue
+		}
+
+		digest, err := HashFile(fsys, p, hash)
+		if err != nil {
+			return err
+		}
+
+		if actual := hex.EncodeToString(digest); !strings.EqualFold(actual, checksums[algorithm]) {
+			report.Corrupted = append(report.Corrupted, Corruption{
+				Path:     rel,
+				Check:    algorithm,
+				Expected: checksums[algorithm],
+				Actual:   actual,
+			})
+			return nil
+		}
+		verified = true
+	}
+
+	if verified {
+		report.Verified++
+	} else {
+		report.Unverified = append(report.Unverified, rel)
+	}
+	return nil
+}
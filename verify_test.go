@@ -0,0 +1,78 @@
+package fs_test
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	_ "crypto/md5"
+	_ "crypto/sha256"
+)
+
+func TestVerify(t *testing.T) {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("backup/doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, mfs.WriteFile("backup/doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t, mfs.WriteFile("backup/cat.txt", []byte("the cat"), 0644))
+	assert.NoError(t, mfs.WriteFile("backup/seals.png", []byte("seals"), 0644))
+
+	var manifest bytes.Buffer
+	assert.NoError(t, fs.Manifest(&manifest, mfs, "backup", fs.WithManifestChecksums(crypto.SHA256, crypto.MD5)))
+
+	report, err := fs.Verify(mfs, "backup", bytes.NewReader(manifest.Bytes()))
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, &fs.VerifyReport{Verified: 4}, report)
+
+	assert.NoError(t, mfs.WriteFile("backup/doc/fox.txt", []byte("the quick brown cat"), 0644))
+	assert.NoError(t, mfs.WriteFile("backup/cat.txt", []byte("the cats"), 0644))
+	assert.NoError(t, mfs.Remove("backup/doc/dog.txt"))
+	assert.NoError(t, mfs.RemoveAll("backup/seals.png"))
+	assert.NoError(t, mfs.Mkdir("backup/seals.png", 0755))
+	assert.NoError(t, mfs.WriteFile("backup/doc/bird.txt", []byte("the bird"), 0644))
+
+	report, err = fs.Verify(mfs, "backup", bytes.NewReader(manifest.Bytes()), fs.WithVerifyChecksums(crypto.SHA256))
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []fs.Corruption{
+		{Path: "cat.txt", Check: "size", Expected: "7", Actual: "8"},
+		{
+			Path:     "doc/fox.txt",
+			Check:    "sha256",
+			Expected: "9ecb36561341d18eb65484e833efea61edc74b84cf5e6ae1b81c63533e25fc8f",
+			Actual:   "04069b42a637cb8897cab6b3e56a99f1fc2b1ca60750dfd3961ca4d7925c675e",
+		},
+		{Path: "seals.png", Check: "type", Expected: "----------", Actual: "d---------"},
+	}, report.Corrupted)
+	assert.Equal(t, []string{"doc/bird.txt"}, report.Extra)
+	assert.Equal(t, []string{"doc/dog.txt"}, report.Missing)
+	assert.Zero(t, report.Verified)
+}
+
+func TestVerify_Attributes(t *testing.T) {
+	mfs, err := memfs.New(memfs.WithChecksum(crypto.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, mfs.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644))
+	assert.NoError(t, mfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+
+	report, err := fs.Verify(mfs, ".", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &fs.VerifyReport{Verified: 2}, report)
+
+	report, err = fs.Verify(mfs, ".", nil, fs.WithVerifyChecksums(crypto.MD5))
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, []string{"doc/dog.txt", "doc/fox.txt"}, report.Unverified)
+}
@@ -0,0 +1,368 @@
+package fs
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+// WalkFunc is the type of the function called by Walk to visit each file or directory.
+//
+// The path argument contains the argument to Walk as a prefix, and the entry provides the metadata for the file or
+// directory. The err argument and the result of the function are handled in the same way as for gofs.WalkDirFunc:
+// returning gofs.SkipDir skips the directory (or the remaining entries in the parent directory of a file), and
+// returning gofs.SkipAll skips all remaining files and directories.
+type WalkFunc func(path string, entry *Entry, err error) error
+
+// WalkOption defines an option for Walk.
+type WalkOption func(*walkOptions)
+
+type walkOptions struct {
+	collect     bool
+	follow      bool
+	maxDepth    int
+	maxLinks    int
+	parallelism int
+	skip        []string
+}
+
+// Walk walks the file tree rooted at root, calling fn for each file or directory in the tree, including root.
+//
+// Unlike gofs.WalkDir, entries are provided to fn as an *Entry, which is the metadata returned by fsys if it is an
+// *Entry, and is otherwise created from the gofs.FileInfo returned by fsys.
+//
+// By default, the tree is walked in lexical order by a single goroutine, and symbolic links are not followed. If the
+// parallelism is set using WithParallelism, directories are read concurrently and fn must be safe to call from multiple
+// goroutines. The entries of a directory are always visited after the directory itself, but otherwise the order in
+// which entries are visited is not deterministic.
+func Walk(fsys gofs.FS, root string, fn WalkFunc, options ...WalkOption) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	opts := &walkOptions{maxDepth: -1, maxLinks: maxSymlinkDepth, parallelism: 1}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	fi, err := gofs.Stat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		var e *Entry
+		if e, err = toEntry(root, fi); err == nil {
+			w := &walker{fn: fn, fsys: fsys, opts: opts, sem: make(chan struct{}, max(opts.parallelism-1, 0))}
+			if err = fn(root, e, nil); err == nil && e.IsDir() && opts.maxDepth != 0 {
+				var ancestors []dirKey
+				if opts.follow {
+					ancestors = []dirKey{newDirKey(gopath.Clean(root), fi)}
+				}
+				w.walkDir(root, e, 0, 0, ancestors)
+				w.wg.Wait()
+				err = w.result()
+			}
+		}
+	}
+
+	if errors.Is(err, gofs.SkipDir) || errors.Is(err, gofs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// WalkParallel walks the file tree rooted at root in the same way as Walk, reading directories concurrently using at
+// most the provided number of workers, so fn must be safe to call from multiple goroutines.
+//
+// Unlike Walk, an error returned by fn for an entry other than root does not stop the walk: the error is recorded, the
+// entry is not descended into if it is a directory, and the walk continues with the remaining entries. Once the walk is
+// complete, the recorded errors are returned sorted by the path of the entry they were returned for, using errors.Join,
+// so that the result does not depend on the order in which entries were visited. Returning gofs.SkipAll from fn stops
+// the walk, and gofs.SkipDir is handled in the same way as for Walk.
+func WalkParallel(fsys gofs.FS, root string, fn WalkFunc, workers int, options ...WalkOption) error {
+	options = append(options[:len(options):len(options)], WithParallelism(workers), func(o *walkOptions) {
+		o.collect = true
+	})
+	return Walk(fsys, root, fn, options...)
+}
+
+// WithFollowSymlinks sets whether symbolic links are followed by Walk. If links are followed, the entry for a link is
+// the metadata of the file or directory it refers to, and a linked directory is walked as if it were a directory in
+// the tree.
+//
+// A linked directory is not walked if it is the same directory as one of its ancestors in the tree, so that links
+// referring to a parent directory do not cause the walk to loop, or if walking it would exceed the depth of links set
+// using WithMaxLinkDepth. The entry for the link is still passed to the WalkFunc. Directories are identified by their
+// device and inode numbers where the platform provides them, and otherwise by their path with the destinations of the
+// followed links resolved.
+func WithFollowSymlinks(follow bool) WalkOption {
+	return func(o *walkOptions) {
+		o.follow = follow
+	}
+}
+
+// WithMaxLinkDepth sets the maximum number of symbolic links to directories that are followed by Walk within a single
+// branch of the tree. The default is 40, and if n is 0, linked directories are not walked.
+func WithMaxLinkDepth(n int) WalkOption {
+	return func(o *walkOptions) {
+		o.maxLinks = max(n, 0)
+	}
+}
+
+// WithMaxDepth sets the maximum depth of the entries visited by Walk, where the root is at depth 0 and its entries are
+// at depth 1. If n is negative, which is the default, the depth is unlimited.
+func WithMaxDepth(n int) WalkOption {
+	return func(o *walkOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithParallelism sets the maximum number of goroutines used by Walk to read directories and visit their entries. The
+// default is 1.
+func WithParallelism(n int) WalkOption {
+	return func(o *walkOptions) {
+		o.parallelism = max(n, 1)
+	}
+}
+
+// WithSkipPatterns sets patterns for entries that are skipped by Walk. Each pattern is matched using path.Match
+// against both the name and the path of an entry, and a matching entry is not passed to the WalkFunc. If the entry is
+// a directory, its content is not walked. The root of the walk is never skipped.
+func WithSkipPatterns(patterns ...string) WalkOption {
+	return func(o *walkOptions) {
+		o.skip = append(o.skip, patterns...)
+	}
+}
+
+// walker holds the state of a tree walked by Walk.
+type walker struct {
+	err   error
+	errs  []walkError
+	fn    WalkFunc
+	fsys  gofs.FS
+	mutex sync.Mutex
+	opts  *walkOptions
+	sem   chan struct{}
+	stop  atomic.Bool
+	wg    sync.WaitGroup
+}
+
+// walkDir walks the entries of the directory at path p. If links are followed, ancestors holds the keys of the
+// directory and each of its ancestors in the tree, and links is the number of links followed to reach the directory.
+func (w *walker) walkDir(p string, dir *Entry, depth int, links int, ancestors []dirKey) {
+	entries, err := gofs.ReadDir(w.fsys, p)
+	if err != nil {
+		// As with gofs.WalkDir, fn is called a second time for a directory that can not be read.
+		if err = w.fn(p, dir, err); err != nil && !errors.Is(err, gofs.SkipDir) {
+			w.fail(p, err)
+		}
+		return
+	}
+
+	for _, d := range entries {
+		if w.stop.Load() {
+			return
+		}
+
+		name := gopath.Join(p, d.Name())
+		if w.skipped(name, d.Name()) {
+			continue
+		}
+
+		var fi gofs.FileInfo
+		if d.Type()&gofs.ModeSymlink != 0 && w.opts.follow {
+			fi, err = gofs.Stat(w.fsys, name)
+		} else {
+			fi, err = d.Info()
+		}
+
+		if err != nil {
+			if err = w.fn(name, nil, err); err != nil {
+				if errors.Is(err, gofs.SkipDir) {
+					return
+				}
+
+				if w.fail(name, err) {
+					return
+				}
+			}
+			continue
+		}
+
+		e, err := toEntry(name, fi)
+		if err != nil {
+			if w.fail(name, err) {
+				return
+			}
+			continue
+		}
+
+		if err := w.fn(name, e, nil); err != nil {
+			if errors.Is(err, gofs.SkipDir) {
+				if e.IsDir() {
+					continue
+				}
+				return
+			}
+
+			if w.fail(name, err) {
+				return
+			}
+			continue
+		}
+
+		if !e.IsDir() || w.opts.maxDepth >= 0 && depth+1 >= w.opts.maxDepth {
+			continue
+		}
+
+		l := links
+		next := ancestors
+		if w.opts.follow {
+			key := w.dirKey(ancestors[len(ancestors)-1], name, fi, d.Type()&gofs.ModeSymlink != 0)
+			if d.Type()&gofs.ModeSymlink != 0 {
+				if l++; l > w.opts.maxLinks || slices.ContainsFunc(ancestors, key.equal) {
+					continue
+				}
+			}
+			next = append(ancestors[:len(ancestors):len(ancestors)], key)
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func() {
+				defer func() {
+					<-w.sem
+					w.wg.Done()
+				}()
+				w.walkDir(name, e, depth+1, l, next)
+			}()
+		default:
+			w.walkDir(name, e, depth+1, l, next)
+		}
+	}
+}
+
+// dirKey returns the key for the directory at path p whose parent has the key parent. If the directory is a link, its
+// path is the destination of the link, resolved relative to the path of the parent.
+func (w *walker) dirKey(parent dirKey, p string, fi gofs.FileInfo, link bool) dirKey {
+	path := gopath.Join(parent.path, gopath.Base(p))
+	if link {
+		if dest, err := readLink(w.fsys, p); err == nil {
+			if gopath.IsAbs(dest) {
+				path = gopath.Clean(dest)
+			} else {
+				path = gopath.Join(parent.path, dest)
+			}
+		}
+	}
+	return newDirKey(path, fi)
+}
+
+// fail records err returned for the entry at path p, and reports whether the walk was stopped. Unless errors are
+// collected for WalkParallel, the walk is stopped and err is recorded as the result if it is the first error.
+func (w *walker) fail(p string, err error) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.opts.collect && !errors.Is(err, gofs.SkipAll) {
+		w.errs = append(w.errs, walkError{err: err, path: p})
+		return false
+	}
+
+	if w.err == nil {
+		w.err = err
+	}
+	w.stop.Store(true)
+	return true
+}
+
+// result returns the result of the walk once every goroutine has completed.
+func (w *walker) result() error {
+	if len(w.errs) == 0 {
+		return w.err
+	}
+
+	sort.SliceStable(w.errs, func(i, j int) bool { return w.errs[i].path < w.errs[j].path })
+
+	errs := make([]error, len(w.errs))
+	for i, e := range w.errs {
+		errs[i] = e.err
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+func (w *walker) skipped(p string, name string) bool {
+	for _, pattern := range w.opts.skip {
+		if ok, _ := gopath.Match(pattern, name); ok {
+			return true
+		}
+
+		if ok, _ := gopath.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dirKey identifies a directory visited by Walk, so that links to directories that form a cycle can be detected.
+type dirKey struct {
+	hasID bool
+	id    fileID
+	path  string
+}
+
+func newDirKey(path string, fi gofs.FileInfo) dirKey {
+	id, ok := sysFileID(fi.Sys())
+	return dirKey{hasID: ok, id: id, path: path}
+}
+
+// equal reports whether k and o identify the same directory, comparing their file identifiers if both are known, and
+// otherwise their paths.
+func (k dirKey) equal(o dirKey) bool {
+	if k.hasID && o.hasID {
+		return k.id == o.id
+	}
+	return k.path == o.path
+}
+
+// fileID is the device and inode numbers that identify a file on platforms that provide them.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// readLink returns the destination of the named symbolic link if fsys implements SymlinkFS, or provides the ReadLink
+// method defined by the io/fs package.
+func readLink(fsys gofs.FS, name string) (string, error) {
+	switch l := fsys.(type) {
+	case SymlinkFS:
+		return l.Readlink(name)
+	case interface{ ReadLink(string) (string, error) }:
+		return l.ReadLink(name)
+	}
+	return "", &gofs.PathError{Op: "readlink", Path: name, Err: ErrUnsupported}
+}
+
+// walkError is an error returned by the WalkFunc for the entry at path.
+type walkError struct {
+	err  error
+	path string
+}
+
+// toEntry returns fi if it is an *Entry, and otherwise creates an Entry for the named file from fi, applying the
+// provided attribute options.
+func toEntry(name string, fi gofs.FileInfo, attributes ...func(*Attribute)) (*Entry, error) {
+	if e, ok := fi.(*Entry); ok {
+		return e, nil
+	}
+	return entryFromFileInfo(name, fi, attributes...)
+}
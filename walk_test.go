@@ -0,0 +1,236 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func walkFS(t *testing.T) fs.FS {
+	mfs, err := memfs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a/b/c/deep.txt", "a/b/fox.txt", "a/dog.txt", "a/.git/HEAD", "cat.txt"} {
+		if err := mfs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mfs
+}
+
+func walkPaths(t *testing.T, fsys gofs.FS, root string, options ...fs.WalkOption) []string {
+	var (
+		mutex sync.Mutex
+		paths []string
+	)
+	err := fs.Walk(fsys, root, func(p string, e *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		assert.NotNil(t, e)
+		mutex.Lock()
+		defer mutex.Unlock()
+		paths = append(paths, p)
+		return nil
+	}, options...)
+	assert.NoError(t, err)
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	mfs := walkFS(t)
+
+	assert.Equal(t, []string{
+		".",
+		"a",
+		"a/.git",
+		"a/.git/HEAD",
+		"a/b",
+		"a/b/c",
+		"a/b/c/deep.txt",
+		"a/b/fox.txt",
+		"a/dog.txt",
+		"cat.txt",
+	}, walkPaths(t, mfs, "."))
+
+	assert.Equal(t, []string{"a", "a/.git", "a/b", "a/dog.txt"}, walkPaths(t, mfs, "a", fs.WithMaxDepth(1)))
+	assert.Equal(t, []string{"a"}, walkPaths(t, mfs, "a", fs.WithMaxDepth(0)))
+
+	assert.Equal(t, []string{"a", "a/b", "a/b/fox.txt", "a/dog.txt"},
+		walkPaths(t, mfs, "a", fs.WithSkipPatterns(".git", "a/b/c")))
+
+	paths := walkPaths(t, mfs, ".", fs.WithParallelism(4))
+	sort.Strings(paths)
+	assert.Equal(t, walkPaths(t, mfs, "."), paths)
+}
+
+func TestWalkSkip(t *testing.T) {
+	mfs := walkFS(t)
+
+	var paths []string
+	err := fs.Walk(mfs, ".", func(p string, e *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p)
+
+		if e.IsDir() && e.Name() == "b" {
+			return gofs.SkipDir
+		}
+
+		if p == "a/dog.txt" {
+			return gofs.SkipAll
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".", "a", "a/.git", "a/.git/HEAD", "a/b", "a/dog.txt"}, paths)
+
+	errStop := errors.New("stop")
+	err = fs.Walk(mfs, ".", func(p string, e *fs.Entry, err error) error {
+		if p == "a/b" {
+			return errStop
+		}
+		return err
+	}, fs.WithParallelism(2))
+	assert.ErrorIs(t, err, errStop)
+
+	err = fs.Walk(mfs, "missing", func(p string, e *fs.Entry, err error) error {
+		assert.Nil(t, e)
+		return err
+	})
+	assert.ErrorIs(t, err, gofs.ErrNotExist)
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "doc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "doc", "fox.txt"), []byte("the quick brown fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("doc", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	rel := func(paths []string) []string {
+		for i, p := range paths {
+			paths[i], _ = filepath.Rel(dir, p)
+		}
+		return paths
+	}
+
+	assert.Equal(t, []string{".", "doc", "doc/fox.txt", "link"}, rel(walkPaths(t, osfs, dir)))
+	assert.Equal(t, []string{".", "doc", "doc/fox.txt", "link", "link/fox.txt"},
+		rel(walkPaths(t, osfs, dir, fs.WithFollowSymlinks(true))))
+}
+
+func TestWalkSymlinkLoop(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "doc", "pets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for link, dest := range map[string]string{"doc/pets/up": "..", "doc/self": ".", "root": "."} {
+		if err := os.Symlink(dest, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rel := func(paths []string) []string {
+		for i, p := range paths {
+			paths[i], _ = filepath.Rel(dir, p)
+		}
+		return paths
+	}
+
+	// Links to an ancestor of the link are visited, but not walked.
+	assert.Equal(t, []string{".", "doc", "doc/pets", "doc/pets/up", "doc/self", "root"},
+		rel(walkPaths(t, osfs, dir, fs.WithFollowSymlinks(true))))
+
+	// Directories without file identifiers are identified by the destinations of the links.
+	mapfs := fstest.MapFS{
+		"doc/fox.txt":  {Data: []byte("the quick brown fox")},
+		"doc/loop":     {Data: []byte("../doc"), Mode: gofs.ModeSymlink},
+		"doc/pets/cat": {Data: []byte("../../doc/pets"), Mode: gofs.ModeSymlink},
+		"link":         {Data: []byte("doc/pets"), Mode: gofs.ModeSymlink},
+	}
+	assert.Equal(t, []string{".", "doc", "doc/fox.txt", "doc/loop", "doc/pets", "doc/pets/cat", "link", "link/cat"},
+		walkPaths(t, mapfs, ".", fs.WithFollowSymlinks(true)))
+
+	assert.Equal(t, []string{".", "doc", "doc/fox.txt", "doc/loop", "doc/pets", "doc/pets/cat", "link"},
+		walkPaths(t, mapfs, ".", fs.WithFollowSymlinks(true), fs.WithMaxLinkDepth(0)))
+}
+
+func TestWalkParallel(t *testing.T) {
+	mfs := walkFS(t)
+
+	var (
+		mutex sync.Mutex
+		paths []string
+	)
+	errFox := errors.New("fox")
+	errDog := errors.New("dog")
+	err := fs.WalkParallel(mfs, ".", func(p string, e *fs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		paths = append(paths, p)
+		mutex.Unlock()
+
+		switch p {
+		case "a/b":
+			return errFox
+		case "a/dog.txt":
+			return errDog
+		}
+		return nil
+	}, 4)
+	assert.ErrorIs(t, err, errFox)
+	assert.ErrorIs(t, err, errDog)
+	assert.Equal(t, "fox\ndog", err.Error())
+
+	sort.Strings(paths)
+	assert.Equal(t, []string{".", "a", "a/.git", "a/.git/HEAD", "a/b", "a/dog.txt", "cat.txt"}, paths)
+
+	assert.Equal(t, walkPaths(t, mfs, "."), func() []string {
+		var paths []string
+		assert.NoError(t, fs.WalkParallel(mfs, ".", func(p string, _ *fs.Entry, err error) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			paths = append(paths, p)
+			return err
+		}, 4))
+		sort.Strings(paths)
+		return paths
+	}())
+}
@@ -0,0 +1,198 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/transientvariable/fs-go"
+	"golang.org/x/net/webdav"
+
+	gofs "io/fs"
+)
+
+var (
+	_ webdav.File       = (*file)(nil)
+	_ webdav.FileSystem = (*fileSystem)(nil)
+)
+
+// FileSystem returns a webdav.FileSystem backed by fsys, which can be used to configure a webdav.Handler directly.
+//
+// Paths provided by the webdav.Handler are slash-separated and absolute, and are interpreted relative to the root of
+// fsys. Paths are resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a
+// location outside of fsys.
+func FileSystem(fsys fs.FS) webdav.FileSystem {
+	return &fileSystem{fsys: fsys}
+}
+
+type fileSystem struct {
+	fsys fs.FS
+}
+
+func (f *fileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	p, err := f.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	if err := f.fsys.Mkdir(p, perm); err != nil {
+		return pathError("mkdir", name, err)
+	}
+	return nil
+}
+
+func (f *fileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	p, err := f.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.fsys.Stat(p)
+	if err == nil && fi.IsDir() {
+		entries, err := f.fsys.ReadDir(p)
+		if err != nil {
+			return nil, pathError("open", name, err)
+		}
+		return &dir{entries: entries, info: fi}, nil
+	}
+
+	fsf, err := f.fsys.OpenFile(p, flag, perm)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return &file{File: fsf, name: name}, nil
+}
+
+func (f *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	p, err := f.path("removeAll", name)
+	if err != nil {
+		return err
+	}
+
+	if p == "." {
+		return pathError("removeAll", name, gofs.ErrPermission)
+	}
+
+	if err := f.fsys.RemoveAll(p); err != nil {
+		return pathError("removeAll", name, err)
+	}
+	return nil
+}
+
+func (f *fileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	oldpath, err := f.path("rename", oldName)
+	if err != nil {
+		return err
+	}
+
+	newpath, err := f.path("rename", newName)
+	if err != nil {
+		return err
+	}
+
+	if oldpath == "." || newpath == "." {
+		return pathError("rename", oldName, gofs.ErrPermission)
+	}
+
+	if err := f.fsys.Rename(oldpath, newpath); err != nil {
+		return pathError("rename", oldName, err)
+	}
+	return nil
+}
+
+func (f *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	p, err := f.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.fsys.Stat(p)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return fi, nil
+}
+
+// path converts the slash-separated absolute name provided by a webdav.Handler to a path for the fs.FS. The path is
+// resolved using fs.SecureJoin, so that neither ".." elements nor symbolic links can refer to a location outside of
+// the fs.FS.
+func (f *fileSystem) path(op string, name string) (string, error) {
+	p, err := fs.SecureJoin(f.fsys, ".", name)
+	if err != nil {
+		return "", pathError(op, name, err)
+	}
+	return p, nil
+}
+
+// pathError returns err as a *gofs.PathError that is recognized by os.IsNotExist and similar functions, which are
+// used by webdav.Handler to determine the response status.
+func pathError(op string, name string, err error) error {
+	for _, target := range []error{gofs.ErrNotExist, gofs.ErrExist, gofs.ErrPermission} {
+		if errors.Is(err, target) {
+			return &gofs.PathError{Op: op, Path: name, Err: target}
+		}
+	}
+	return err
+}
+
+// dir provides access to a directory as a webdav.File. The entries of the directory are read when it is opened.
+type dir struct {
+	entries []gofs.DirEntry
+	info    gofs.FileInfo
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &gofs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrIsDir}
+}
+
+func (d *dir) Readdir(count int) ([]gofs.FileInfo, error) {
+	if count > 0 && len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := len(d.entries)
+	if count > 0 && count < n {
+		n = count
+	}
+
+	infos := make([]gofs.FileInfo, 0, n)
+	for _, e := range d.entries[:n] {
+		fi, err := e.Info()
+		if err != nil {
+			if errors.Is(err, gofs.ErrNotExist) {
+				continue
+			}
+			return infos, err
+		}
+		infos = append(infos, fi)
+	}
+	d.entries = d.entries[n:]
+	return infos, nil
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	return 0, &gofs.PathError{Op: "seek", Path: d.info.Name(), Err: fs.ErrIsDir}
+}
+
+func (d *dir) Stat() (gofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, &gofs.PathError{Op: "write", Path: d.info.Name(), Err: fs.ErrIsDir}
+}
+
+// file provides access to an fs.File as a webdav.File.
+type file struct {
+	fs.File
+	name string
+}
+
+func (f *file) Readdir(count int) ([]gofs.FileInfo, error) {
+	return nil, &gofs.PathError{Op: "readdir", Path: f.name, Err: fs.ErrNotDir}
+}
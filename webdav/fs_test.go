@@ -0,0 +1,194 @@
+package webdav
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// linkFS is a MemFS with symbolic links, whose destinations are resolved by the server rather than by the MemFS.
+type linkFS struct {
+	*memfs.MemFS
+	links map[string]string
+}
+
+func (l *linkFS) Readlink(name string) (string, error) {
+	if dest, ok := l.links[name]; ok {
+		return dest, nil
+	}
+	return "", &gofs.PathError{Op: "readlink", Path: name, Err: gofs.ErrInvalid}
+}
+
+func (l *linkFS) Symlink(oldname string, newname string) error {
+	return &gofs.PathError{Op: "symlink", Path: newname, Err: fs.ErrUnsupported}
+}
+
+// WebDAVTestSuite ...
+type WebDAVTestSuite struct {
+	suite.Suite
+	backing *memfs.MemFS
+	server  *httptest.Server
+}
+
+func NewWebDAVTestSuite() *WebDAVTestSuite {
+	return &WebDAVTestSuite{}
+}
+
+func (t *WebDAVTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = backing
+
+	h, err := New(backing, WithPrefix("/dav"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.server = httptest.NewServer(h)
+}
+
+func (t *WebDAVTestSuite) TearDownTest() {
+	t.server.Close()
+}
+
+func TestWebDAVTestSuite(t *testing.T) {
+	suite.Run(t, NewWebDAVTestSuite())
+}
+
+func (t *WebDAVTestSuite) do(method string, path string, body string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, t.server.URL+"/dav"+path, strings.NewReader(body))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func (t *WebDAVTestSuite) TestGet() {
+	resp, body := t.do(http.MethodGet, "/doc/fox.txt", "", nil)
+	assert.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(t.T(), "the quick brown fox", body)
+
+	resp, body = t.do(http.MethodGet, "/doc/fox.txt", "", map[string]string{"Range": "bytes=4-8"})
+	assert.Equal(t.T(), http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t.T(), "quick", body)
+
+	resp, _ = t.do(http.MethodGet, "/doc/missing.txt", "", nil)
+	assert.Equal(t.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (t *WebDAVTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	h, err := New(t.backing, WithPrefix("/dav"), WithLogger(logger))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.server.Close()
+	t.server = httptest.NewServer(h)
+
+	resp, _ := t.do(http.MethodGet, "/doc/missing.txt", "", nil)
+	assert.Equal(t.T(), http.StatusNotFound, resp.StatusCode)
+
+	// The request is logged once the response has been written, and closing the server waits for the request to
+	// complete.
+	t.server.Close()
+	assert.Contains(t.T(), buf.String(), `msg="[webdav] request" method=GET path=/dav/doc/missing.txt error=`)
+
+	h, err = New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	assert.Equal(t.T(), fs.NopLogger(), h.logger)
+}
+
+func (t *WebDAVTestSuite) TestPropfind() {
+	resp, body := t.do("PROPFIND", "/doc", "", map[string]string{"Depth": "1"})
+	assert.Equal(t.T(), http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t.T(), body, "/dav/doc/fox.txt")
+	assert.Contains(t.T(), body, "<D:getcontentlength>19</D:getcontentlength>")
+}
+
+func (t *WebDAVTestSuite) TestWrite() {
+	resp, _ := t.do(http.MethodPut, "/doc/dog.txt", "jumps over the lazy dog", nil)
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	b, err := t.backing.ReadFile("doc/dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "jumps over the lazy dog", string(b))
+
+	resp, _ = t.do("MKCOL", "/tmp", "", nil)
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	resp, _ = t.do("MOVE", "/doc/dog.txt", "", map[string]string{"Destination": t.server.URL + "/dav/tmp/dog.txt"})
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	_, err = t.backing.Stat("tmp/dog.txt")
+	assert.NoError(t.T(), err)
+
+	resp, _ = t.do(http.MethodDelete, "/tmp", "", nil)
+	assert.Equal(t.T(), http.StatusNoContent, resp.StatusCode)
+
+	_, err = t.backing.Stat("tmp")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+}
+
+func (t *WebDAVTestSuite) TestSecurePath() {
+	assert.NoError(t.T(), t.backing.WriteFile("pictures/seal.txt", []byte("the happy seal"), 0644))
+
+	h, err := New(&linkFS{MemFS: t.backing, links: map[string]string{
+		"doc/escape":   "../../../pictures",
+		"doc/absolute": "/pictures",
+	}}, WithPrefix("/dav"))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.server.Close()
+	t.server = httptest.NewServer(h)
+
+	// Symbolic links are resolved as if the root of the file system were the root, so that they can not refer to a
+	// location outside of it.
+	for _, name := range []string{"/doc/escape/seal.txt", "/doc/absolute/seal.txt"} {
+		resp, body := t.do(http.MethodGet, name, "", nil)
+		assert.Equal(t.T(), http.StatusOK, resp.StatusCode, name)
+		assert.Equal(t.T(), "the happy seal", body, name)
+	}
+
+	resp, _ := t.do(http.MethodPut, "/doc/escape/owl.txt", "the wise owl", nil)
+	assert.Equal(t.T(), http.StatusCreated, resp.StatusCode)
+
+	b, err := t.backing.ReadFile("pictures/owl.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the wise owl", string(b))
+}
@@ -0,0 +1,91 @@
+// Package webdav serves an fs.FS over WebDAV, so that the file system can be browsed and edited remotely using standard
+// clients.
+//
+// The WebDAV protocol is provided by golang.org/x/net/webdav, with resource operations mapped to the corresponding
+// fs.FS operations: GET and PROPFIND read from the file system, PUT writes files, MKCOL creates directories, MOVE
+// renames entries, and DELETE removes entries.
+package webdav
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/transientvariable/fs-go"
+	"golang.org/x/net/webdav"
+)
+
+var _ http.Handler = (*Handler)(nil)
+
+// Handler is an http.Handler that serves an fs.FS over WebDAV.
+type Handler struct {
+	dav    webdav.Handler
+	fsys   fs.FS
+	locks  webdav.LockSystem
+	logger fs.Logger
+	prefix string
+}
+
+// New creates a new Handler that serves fsys.
+func New(fsys fs.FS, options ...func(*Handler)) (*Handler, error) {
+	if fsys == nil {
+		return nil, errors.New("webdav: file system is required")
+	}
+
+	h := &Handler{fsys: fsys, logger: fs.NopLogger()}
+	for _, opt := range options {
+		opt(h)
+	}
+
+	if h.locks == nil {
+		h.locks = webdav.NewMemLS()
+	}
+
+	h.dav = webdav.Handler{
+		Prefix:     h.prefix,
+		FileSystem: FileSystem(fsys),
+		LockSystem: h.locks,
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				h.logger.Debug("[webdav] request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"error", err)
+			}
+		},
+	}
+	return h, nil
+}
+
+// FS returns the fs.FS served by the Handler.
+func (h *Handler) FS() fs.FS {
+	return h.fsys
+}
+
+// ServeHTTP ...
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.dav.ServeHTTP(w, r)
+}
+
+// WithLockSystem sets the webdav.LockSystem used to manage WebDAV locks. By default, locks are held in memory.
+func WithLockSystem(locks webdav.LockSystem) func(*Handler) {
+	return func(h *Handler) {
+		h.locks = locks
+	}
+}
+
+// WithLogger sets the Logger used by a Handler to log the requests that fail. By default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*Handler) {
+	return func(h *Handler) {
+		if logger != nil {
+			h.logger = logger
+		}
+	}
+}
+
+// WithPrefix sets the URL path prefix that is stripped from request paths, for when the Handler is not mounted at the
+// root of a server.
+func WithPrefix(prefix string) func(*Handler) {
+	return func(h *Handler) {
+		h.prefix = prefix
+	}
+}
@@ -0,0 +1,52 @@
+package fs
+
+import (
+	"errors"
+	"slices"
+
+	gofs "io/fs"
+)
+
+// WriteAll writes the data of each named file in files to fsys with permissions perm (before umask), creating missing
+// parent directories. Parent directories are created with perm, with the execute bit set wherever the read bit is set.
+//
+// If fsys implements BatchWriteFS, its WriteFiles method is used. Otherwise, the files are written one at a time in
+// lexical order of their names, and the first error is returned.
+func WriteAll(fsys FS, files map[string][]byte, perm gofs.FileMode) error {
+	if fsys == nil {
+		return errors.New("fs: file system is required")
+	}
+
+	if b, ok := fsys.(BatchWriteFS); ok {
+		return b.WriteFiles(files, perm)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	dirs := make(map[string]bool)
+	for _, name := range names {
+		if dir := Dir(fsys, name); dir != "." && !dirs[dir] {
+			if err := fsys.MkdirAll(dir, DirPerm(perm)); err != nil {
+				return err
+			}
+			dirs[dir] = true
+		}
+
+		if err := fsys.WriteFile(name, files[name], perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirPerm returns the permissions for a directory that holds files with permissions perm, which are perm with the
+// execute bit set wherever the read bit is set, so that the files can be listed and accessed by the same principals
+// that can read them.
+func DirPerm(perm gofs.FileMode) gofs.FileMode {
+	perm = perm.Perm()
+	return perm | (perm&0444)>>2
+}
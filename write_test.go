@@ -0,0 +1,56 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/transientvariable/fs-go"
+
+	"github.com/stretchr/testify/assert"
+
+	gofs "io/fs"
+)
+
+func TestWriteAll(t *testing.T) {
+	osfs, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	files := map[string][]byte{
+		filepath.Join(root, "fox.txt"):           []byte("the quick brown fox"),
+		filepath.Join(root, "doc", "dog.txt"):    []byte("the lazy dog"),
+		filepath.Join(root, "doc", "a", "b.txt"): []byte("b"),
+	}
+	assert.NoError(t, fs.WriteAll(osfs, files, 0640))
+
+	for name, data := range files {
+		b, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, data, b)
+
+		fi, err := os.Stat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+	}
+
+	fi, err := os.Stat(filepath.Join(root, "doc", "a"))
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	assert.Error(t, fs.WriteAll(nil, files, 0640))
+}
+
+func TestDirPerm(t *testing.T) {
+	for perm, want := range map[gofs.FileMode]gofs.FileMode{
+		0644: 0755,
+		0640: 0750,
+		0600: 0700,
+		0200: 0200,
+		0755: 0755,
+	} {
+		assert.Equal(t, want, fs.DirPerm(perm), perm.String())
+	}
+}
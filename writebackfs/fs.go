@@ -0,0 +1,800 @@
+package writebackfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	gofs "io/fs"
+	gopath "path"
+)
+
+var _ fs.FS = (*WritebackFS)(nil)
+
+// DirtyFile describes a file whose content was written to a WritebackFS and has not been flushed to the backing file
+// system.
+type DirtyFile struct {
+	// Name is the path of the file.
+	Name string
+
+	// Size is the size of the buffered content of the file.
+	Size int64
+
+	// Since is the time the file was first written after it was last flushed.
+	Since time.Time
+
+	// Writes is the number of times the file was written since it was last flushed, which are coalesced into a single
+	// write to the backing file system.
+	Writes int
+
+	// Open reports whether the file is open for writing, in which case it is not flushed until it is closed.
+	Open bool
+}
+
+// WritebackFS write-back caching provider that implements fs.FS.
+//
+// A WritebackFS absorbs the content written to files in a fast buffer file system (a memfs.MemFS by default), and
+// flushes it to a slow backing file system, such as an object store, in the background. Rewrites of a file between
+// flushes are coalesced, so that only the latest content of the file is written to the backing file system.
+//
+// Dirty files are flushed each time the interval set using WithFlushInterval elapses, once the buffered content
+// exceeds the size set using WithMaxDirtyBytes, when Flush is called, and when the WritebackFS is closed. Reads of
+// dirty files are served from the buffer, and directory listings include dirty files that have not been flushed.
+//
+// Directory operations, such as Mkdir and Rename, are passed through to the backing file system. Dirty files that are
+// renamed are flushed first, and dirty files that are removed are discarded without being flushed.
+type WritebackFS struct {
+	backing  fs.FS
+	buffer   fs.FS
+	closed   bool
+	dirty    map[string]*dirtyFile
+	done     chan struct{}
+	flushing sync.Mutex
+	interval time.Duration
+	kick     chan struct{}
+	logger   fs.Logger
+	maxDirty int64
+	mutex    sync.Mutex
+	now      func() time.Time
+	opening  sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// New creates a new WritebackFS that flushes the content written to it to backing.
+func New(backing fs.FS, options ...func(*WritebackFS)) (*WritebackFS, error) {
+	if backing == nil {
+		return nil, errors.New("writebackfs: backing file system is required")
+	}
+
+	w := &WritebackFS{
+		backing: backing,
+		dirty:   make(map[string]*dirtyFile),
+		done:    make(chan struct{}),
+		kick:    make(chan struct{}, 1),
+		logger:  fs.NopLogger(),
+		now:     time.Now,
+	}
+	for _, opt := range options {
+		opt(w)
+	}
+
+	if w.buffer == nil {
+		mfs, err := memfs.New()
+		if err != nil {
+			return nil, err
+		}
+		w.buffer = mfs
+	}
+
+	if w.interval > 0 || w.maxDirty > 0 {
+		w.wg.Add(1)
+		go w.run()
+	}
+
+	w.logger.Debug("[writebackfs] new",
+		"provider", backing.Provider(),
+		"interval", w.interval.String(),
+		"max_dirty", w.maxDirty)
+	return w, nil
+}
+
+// Close stops the background flushes, and flushes the dirty files to the backing file system. The backing file system
+// is not closed.
+func (w *WritebackFS) Close() error {
+	if w == nil {
+		return gofs.ErrInvalid
+	}
+
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return fmt.Errorf("writebackfs: %w", gofs.ErrClosed)
+	}
+	w.closed = true
+	close(w.done)
+	w.mutex.Unlock()
+
+	w.wg.Wait()
+	return w.Flush()
+}
+
+// Create ...
+func (w *WritebackFS) Create(name string) (fs.File, error) {
+	return w.OpenFile(name, fs.O_RDWR|fs.O_CREATE|fs.O_TRUNC, 0666)
+}
+
+// Dirty returns the files that have not been flushed to the backing file system, sorted by name.
+func (w *WritebackFS) Dirty() []DirtyFile {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	files := make([]DirtyFile, 0, len(w.dirty))
+	for name, d := range w.dirty {
+		files = append(files, DirtyFile{Name: name, Size: d.size, Since: d.since, Writes: d.writes, Open: d.writers > 0})
+	}
+
+	slices.SortFunc(files, func(a DirtyFile, b DirtyFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return files
+}
+
+// DirtyBytes returns the total size of the buffered content of the files that have not been flushed.
+func (w *WritebackFS) DirtyBytes() int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.dirtyBytes()
+}
+
+// Flush writes the content of the dirty files to the backing file system. Files that are open for writing are not
+// flushed. If a file can not be flushed, it remains dirty, the remaining files are still flushed, and the errors are
+// returned.
+func (w *WritebackFS) Flush() error {
+	n, err := w.flushMatching(func(_ string, d *dirtyFile) bool {
+		return d.writers == 0
+	})
+
+	if n > 0 {
+		w.logger.Debug("[writebackfs] flush", "files", n, "error", err)
+	}
+	return err
+}
+
+// Glob ...
+func (w *WritebackFS) Glob(pattern string) ([]string, error) {
+	return gofs.Glob(readOnly{w}, pattern)
+}
+
+// Mkdir ...
+func (w *WritebackFS) Mkdir(name string, perm gofs.FileMode) error {
+	return w.backing.Mkdir(name, perm)
+}
+
+// MkdirAll ...
+func (w *WritebackFS) MkdirAll(path string, perm gofs.FileMode) error {
+	return w.backing.MkdirAll(path, perm)
+}
+
+// Open opens the named file for reading. Dirty files are read from the buffer.
+func (w *WritebackFS) Open(name string) (gofs.File, error) {
+	return w.OpenFile(name, fs.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file. Files opened for writing are opened in the buffer, after copying their content from
+// the backing file system unless they are truncated, and are flushed once they are closed.
+func (w *WritebackFS) OpenFile(name string, flag int, perm gofs.FileMode) (fs.File, error) {
+	w.logger.Debug("[writebackfs] openFile", "name", name, "flag", flag)
+
+	name, err := w.clean("openFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(fs.O_WRONLY|fs.O_RDWR|fs.O_APPEND|fs.O_CREATE|fs.O_TRUNC) == 0 {
+		if w.isDirty(name) {
+			return w.buffer.OpenFile(name, fs.O_RDONLY, 0)
+		}
+		return w.openBacking(name)
+	}
+
+	d, err := w.begin("openFile", name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := w.buffer.OpenFile(name, flag, perm)
+	if err != nil {
+		w.abort(d)
+		return nil, err
+	}
+	return &writeFile{File: f, end: func() { w.end(d) }}, nil
+}
+
+// PathSeparator ...
+func (w *WritebackFS) PathSeparator() string {
+	return w.backing.PathSeparator()
+}
+
+// Provider ...
+func (w *WritebackFS) Provider() string {
+	return w.backing.Provider()
+}
+
+// ReadDir returns the entries of the named directory in the backing file system, including dirty files that have not
+// been flushed.
+func (w *WritebackFS) ReadDir(name string) ([]gofs.DirEntry, error) {
+	name, err := w.clean("readDir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := w.backing.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	var dirty []string
+	for p := range w.dirty {
+		if gopath.Dir(p) == name {
+			dirty = append(dirty, p)
+		}
+	}
+	w.mutex.Unlock()
+
+	if len(dirty) == 0 {
+		return entries, nil
+	}
+
+	byName := make(map[string]gofs.DirEntry, len(entries)+len(dirty))
+	for _, e := range entries {
+		byName[e.Name()] = e
+	}
+
+	for _, p := range dirty {
+		fi, err := w.buffer.Stat(p)
+		if err != nil {
+			continue
+		}
+		byName[fi.Name()] = gofs.FileInfoToDirEntry(fi)
+	}
+
+	entries = entries[:0]
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+
+	slices.SortFunc(entries, func(a gofs.DirEntry, b gofs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// ReadFile reads the named file. Dirty files are read from the buffer.
+func (w *WritebackFS) ReadFile(name string) ([]byte, error) {
+	name, err := w.clean("readFile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.isDirty(name) {
+		return w.buffer.ReadFile(name)
+	}
+	return w.backing.ReadFile(name)
+}
+
+// Remove removes the named file or empty directory. The buffered content of a dirty file is discarded.
+func (w *WritebackFS) Remove(name string) error {
+	name, err := w.clean("remove", name)
+	if err != nil {
+		return err
+	}
+
+	discarded := w.discard(name)
+	if err := w.backing.Remove(name); err != nil && !(discarded && errors.Is(err, gofs.ErrNotExist)) {
+		return err
+	}
+	return nil
+}
+
+// RemoveAll removes path and any children it contains. The buffered content of dirty files is discarded.
+func (w *WritebackFS) RemoveAll(path string) error {
+	path, err := w.clean("removeAll", path)
+	if err != nil {
+		return err
+	}
+
+	w.discard(path)
+	return w.backing.RemoveAll(path)
+}
+
+// Rename renames oldpath to newpath. Dirty files within oldpath are flushed before the rename, and the buffered content
+// of dirty files replaced by the rename is discarded. Files within oldpath that are open for writing are moved to the
+// corresponding paths within newpath in the buffer, so that the content written to them is flushed to newpath once
+// they are closed.
+func (w *WritebackFS) Rename(oldpath string, newpath string) error {
+	oldpath, err := w.clean("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	newpath, err = w.clean("rename", newpath)
+	if err != nil {
+		return err
+	}
+
+	if err := w.flushTree(oldpath); err != nil {
+		return err
+	}
+
+	if err := w.backing.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	w.discard(newpath)
+	return w.move(oldpath, newpath)
+}
+
+// Root ...
+func (w *WritebackFS) Root() (string, error) {
+	return w.backing.Root()
+}
+
+// Stat returns the file info for the named file. The file info of dirty files is provided by the buffer.
+func (w *WritebackFS) Stat(name string) (gofs.FileInfo, error) {
+	name, err := w.clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.isDirty(name) {
+		return w.buffer.Stat(name)
+	}
+	return w.backing.Stat(name)
+}
+
+// Sub returns the sub-tree of the WritebackFS rooted at dir.
+func (w *WritebackFS) Sub(dir string) (gofs.FS, error) {
+	return fs.Chroot(w, dir)
+}
+
+// WriteFile writes data to the named file in the buffer, and flushes it to the backing file system later.
+func (w *WritebackFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	name, err := w.clean("writeFile", name)
+	if err != nil {
+		return err
+	}
+
+	d, err := w.begin("writeFile", name, fs.O_WRONLY|fs.O_CREATE|fs.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if err := w.buffer.WriteFile(name, data, perm); err != nil {
+		w.abort(d)
+		return err
+	}
+	w.end(d)
+	return nil
+}
+
+// begin prepares the buffer for writing the named file, and records that the file is open for writing. The content of
+// the file is copied from the backing file system unless the flag truncates it.
+func (w *WritebackFS) begin(op string, name string, flag int, perm gofs.FileMode) (*dirtyFile, error) {
+	// Files are opened one at a time, so that the content of a file is only copied from the backing file system once,
+	// and a file is not removed from the buffer by a flush while it is being opened.
+	w.opening.Lock()
+	defer w.opening.Unlock()
+
+	w.mutex.Lock()
+	closed := w.closed
+	d, dirty := w.dirty[name]
+	w.mutex.Unlock()
+
+	if closed {
+		return nil, fmt.Errorf("writebackfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrClosed})
+	}
+
+	if !dirty {
+		fi, err := w.backing.Stat(name)
+		switch {
+		case err == nil && fi.IsDir():
+			return nil, fmt.Errorf("writebackfs: %w", &gofs.PathError{Op: op, Path: name, Err: fs.ErrIsDir})
+		case err == nil && flag&fs.O_CREATE != 0 && flag&fs.O_EXCL != 0:
+			return nil, fmt.Errorf("writebackfs: %w", &gofs.PathError{Op: op, Path: name, Err: gofs.ErrExist})
+		case errors.Is(err, gofs.ErrNotExist):
+			if flag&fs.O_CREATE == 0 {
+				return nil, err
+			}
+
+			// The parent directory must exist in the backing file system, so that the file can be flushed.
+			if dir := gopath.Dir(name); dir != "." {
+				if dfi, err := w.backing.Stat(dir); err != nil {
+					return nil, err
+				} else if !dfi.IsDir() {
+					return nil, fmt.Errorf("writebackfs: %w", &gofs.PathError{Op: op, Path: name, Err: fs.ErrNotDir})
+				}
+			}
+		case err != nil:
+			return nil, err
+		}
+
+		if err := w.prepare(name, fi, flag); err != nil {
+			return nil, err
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if d = w.dirty[name]; d == nil {
+		d = &dirtyFile{name: name, since: w.now()}
+		w.dirty[name] = d
+	}
+	d.writers++
+	return d, nil
+}
+
+// openBacking opens the named file in the backing file system for reading. The entries of directories include dirty
+// files that have not been flushed.
+func (w *WritebackFS) openBacking(name string) (fs.File, error) {
+	f, err := w.backing.OpenFile(name, fs.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil || !fi.IsDir() {
+		return f, err
+	}
+
+	entries, err := w.ReadDir(name)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &dirFile{File: f, entries: entries}, nil
+}
+
+// prepare creates the parent directory of the named file in the buffer, and copies the content of the file from the
+// backing file system if it exists and is not truncated by flag.
+func (w *WritebackFS) prepare(name string, fi gofs.FileInfo, flag int) error {
+	if dir := gopath.Dir(name); dir != "." {
+		if err := w.buffer.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	if fi == nil || flag&fs.O_TRUNC != 0 {
+		return nil
+	}
+
+	b, err := w.backing.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return w.buffer.WriteFile(name, b, fi.Mode().Perm())
+}
+
+// abort records that the file of d could not be opened for writing after begin was called. If the file was not
+// written before, it is no longer dirty.
+func (w *WritebackFS) abort(d *dirtyFile) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if d.writers--; d.writers == 0 && d.writes == 0 && w.dirty[d.name] == d {
+		delete(w.dirty, d.name)
+	}
+}
+
+// end records that a write to the file of d opened using begin completed, and triggers a flush if the buffered content
+// exceeds the maximum size.
+func (w *WritebackFS) end(d *dirtyFile) {
+	w.mutex.Lock()
+	name := d.name
+	w.mutex.Unlock()
+
+	var size int64
+	if fi, err := w.buffer.Stat(name); err == nil {
+		size = fi.Size()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	d.writers--
+	d.version++
+	d.writes++
+	d.size = size
+
+	// The file was discarded while it was open, so it is not flushed.
+	if w.dirty[d.name] != d {
+		return
+	}
+
+	if w.maxDirty > 0 && w.dirtyBytes() > w.maxDirty {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// discard discards the buffered content of the dirty files at path and within it, and returns whether any was
+// discarded.
+func (w *WritebackFS) discard(path string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var discarded bool
+	for name := range w.dirty {
+		if within(name, path) {
+			delete(w.dirty, name)
+			if err := w.buffer.Remove(name); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+				w.logger.Warn("[writebackfs] discard", "name", name, "error", err)
+			}
+			discarded = true
+		}
+	}
+	return discarded
+}
+
+// flush writes the buffered content of the named file to the backing file system, and removes it from the buffer if
+// the file was not written again since version.
+func (w *WritebackFS) flush(name string, version int) error {
+	fi, err := w.buffer.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	b, err := w.buffer.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	if err := w.backing.WriteFile(name, b, fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	w.opening.Lock()
+	defer w.opening.Unlock()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if d, ok := w.dirty[name]; ok && d.version == version && d.writers == 0 {
+		delete(w.dirty, name)
+		if err := w.buffer.Remove(name); err != nil && !errors.Is(err, gofs.ErrNotExist) {
+			w.logger.Warn("[writebackfs] flush", "name", name, "error", err)
+		}
+	}
+	return nil
+}
+
+// flushMatching flushes the dirty files for which match returns true, and returns the number of files flushed.
+func (w *WritebackFS) flushMatching(match func(string, *dirtyFile) bool) (int, error) {
+	w.flushing.Lock()
+	defer w.flushing.Unlock()
+
+	w.mutex.Lock()
+	pending := make(map[string]int)
+	for name, d := range w.dirty {
+		if match(name, d) {
+			pending[name] = d.version
+		}
+	}
+	w.mutex.Unlock()
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(pending)) {
+		if err := w.flush(name, pending[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(pending) - len(errs), errors.Join(errs...)
+}
+
+// flushTree flushes the dirty files at path and within it. Files that are open for writing are flushed with the
+// content written so far.
+func (w *WritebackFS) flushTree(path string) error {
+	_, err := w.flushMatching(func(name string, _ *dirtyFile) bool {
+		return within(name, path)
+	})
+	return err
+}
+
+// move moves the dirty files within oldpath, which remain in the buffer after they are flushed by Rename if they are
+// open for writing, to the corresponding paths within newpath.
+func (w *WritebackFS) move(oldpath string, newpath string) error {
+	w.opening.Lock()
+	defer w.opening.Unlock()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var names []string
+	for name := range w.dirty {
+		if within(name, oldpath) {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		to := newpath + strings.TrimPrefix(name, oldpath)
+		if dir := gopath.Dir(to); dir != "." {
+			if err := w.buffer.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+
+		if err := w.buffer.Rename(name, to); err != nil {
+			return err
+		}
+
+		d := w.dirty[name]
+		delete(w.dirty, name)
+		d.name = to
+		w.dirty[to] = d
+	}
+	return nil
+}
+
+// run flushes the dirty files each time the flush interval elapses, or the maximum size of the buffered content is
+// exceeded, until the WritebackFS is closed.
+func (w *WritebackFS) run() {
+	defer w.wg.Done()
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick:
+		case <-w.kick:
+		}
+
+		if err := w.Flush(); err != nil {
+			w.logger.Warn("[writebackfs] flush", "error", err)
+		}
+	}
+}
+
+func (w *WritebackFS) clean(op string, name string) (string, error) {
+	name, err := fs.CleanPath(w, name)
+	if err != nil {
+		return name, fmt.Errorf("writebackfs: %w", &gofs.PathError{Op: op, Path: name, Err: err})
+	}
+	return name, nil
+}
+
+// dirtyBytes returns the total size of the buffered content of the dirty files. The caller must hold the mutex.
+func (w *WritebackFS) dirtyBytes() int64 {
+	var n int64
+	for _, d := range w.dirty {
+		n += d.size
+	}
+	return n
+}
+
+func (w *WritebackFS) isDirty(name string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	_, ok := w.dirty[name]
+	return ok
+}
+
+// WithBuffer sets the file system that buffers the content written to a WritebackFS, such as a memfs.MemFS created
+// with a quota or an arena. The default is a memfs.MemFS created using memfs.New.
+func WithBuffer(buffer fs.FS) func(*WritebackFS) {
+	return func(w *WritebackFS) {
+		w.buffer = buffer
+	}
+}
+
+// WithFlushInterval sets the interval at which a WritebackFS flushes its dirty files in the background. By default,
+// dirty files are not flushed periodically.
+func WithFlushInterval(interval time.Duration) func(*WritebackFS) {
+	return func(w *WritebackFS) {
+		w.interval = interval
+	}
+}
+
+// WithLogger sets the Logger used by a WritebackFS to log its operation and the errors of background flushes. By
+// default, nothing is logged.
+func WithLogger(logger fs.Logger) func(*WritebackFS) {
+	return func(w *WritebackFS) {
+		if logger != nil {
+			w.logger = logger
+		}
+	}
+}
+
+// WithMaxDirtyBytes sets the size of the buffered content of dirty files above which a WritebackFS flushes them in the
+// background. By default, the size of the buffered content is not limited.
+func WithMaxDirtyBytes(n int64) func(*WritebackFS) {
+	return func(w *WritebackFS) {
+		w.maxDirty = n
+	}
+}
+
+// dirtyFile holds the state of a file that was written and has not been flushed.
+type dirtyFile struct {
+	name    string
+	since   time.Time
+	size    int64
+	version int
+	writers int
+	writes  int
+}
+
+// dirFile is a directory opened in the backing file system whose entries include dirty files.
+type dirFile struct {
+	fs.File
+	entries []gofs.DirEntry
+}
+
+func (d *dirFile) ReadDir(n int) ([]gofs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	entries := d.entries[:min(n, len(d.entries))]
+	d.entries = d.entries[len(entries):]
+	return entries, nil
+}
+
+// readOnly hides the methods of a WritebackFS that are not part of gofs.FS, so that gofs.Glob uses its ReadDir method
+// instead of calling Glob again.
+type readOnly struct {
+	w *WritebackFS
+}
+
+func (r readOnly) Open(name string) (gofs.File, error) {
+	return r.w.Open(name)
+}
+
+func (r readOnly) ReadDir(name string) ([]gofs.DirEntry, error) {
+	return r.w.ReadDir(name)
+}
+
+func (r readOnly) Stat(name string) (gofs.FileInfo, error) {
+	return r.w.Stat(name)
+}
+
+// writeFile is a file opened for writing in the buffer that records the write once it is closed.
+type writeFile struct {
+	fs.File
+	end  func()
+	once sync.Once
+}
+
+func (f *writeFile) Close() error {
+	defer f.once.Do(f.end)
+	return f.File.Close()
+}
+
+// within reports whether name is path or is within path.
+func within(name string, path string) bool {
+	return path == "." || name == path || strings.HasPrefix(name, path+"/")
+}
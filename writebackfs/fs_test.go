@@ -0,0 +1,238 @@
+package writebackfs
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/transientvariable/fs-go"
+	"github.com/transientvariable/fs-go/memfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	gofs "io/fs"
+)
+
+// countingFS records the number of writes passed through to the backing file system.
+type countingFS struct {
+	fs.FS
+	mutex  sync.Mutex
+	writes map[string]int
+}
+
+func (c *countingFS) WriteFile(name string, data []byte, perm gofs.FileMode) error {
+	c.mutex.Lock()
+	c.writes[name]++
+	c.mutex.Unlock()
+	return c.FS.WriteFile(name, data, perm)
+}
+
+func (c *countingFS) count(name string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.writes[name]
+}
+
+// WritebackFSTestSuite ...
+type WritebackFSTestSuite struct {
+	suite.Suite
+	backing *countingFS
+	wfs     *WritebackFS
+}
+
+func NewWritebackFSTestSuite() *WritebackFSTestSuite {
+	return &WritebackFSTestSuite{}
+}
+
+func (t *WritebackFSTestSuite) SetupTest() {
+	backing, err := memfs.New()
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	if err := backing.WriteFile("doc/fox.txt", []byte("the quick brown fox"), 0644); err != nil {
+		t.T().Fatal(err)
+	}
+	t.backing = &countingFS{FS: backing, writes: make(map[string]int)}
+
+	wfs, err := New(t.backing)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+	t.wfs = wfs
+}
+
+func (t *WritebackFSTestSuite) TearDownTest() {
+	_ = t.wfs.Close()
+}
+
+func TestWritebackFSTestSuite(t *testing.T) {
+	suite.Run(t, NewWritebackFSTestSuite())
+}
+
+func (t *WritebackFSTestSuite) TestFlush() {
+	for _, content := range []string{"the", "the lazy", "the lazy dog"} {
+		assert.NoError(t.T(), t.wfs.WriteFile("doc/dog.txt", []byte(content), 0644))
+	}
+
+	f, err := t.wfs.OpenFile("doc/fox.txt", fs.O_WRONLY|fs.O_APPEND, 0)
+	assert.NoError(t.T(), err)
+
+	_, err = f.Write([]byte(" jumps"))
+	assert.NoError(t.T(), err)
+
+	// Files that are open for writing are not flushed.
+	assert.NoError(t.T(), t.wfs.Flush())
+	assert.Equal(t.T(), 1, t.backing.count("doc/dog.txt"))
+	assert.Zero(t.T(), t.backing.count("doc/fox.txt"))
+	assert.NoError(t.T(), f.Close())
+
+	assert.NoError(t.T(), t.wfs.WriteFile("doc/cat.txt", []byte("the sleepy cat"), 0644))
+	assert.NoError(t.T(), t.wfs.WriteFile("doc/cat.txt", []byte("the sleepy cat"), 0644))
+
+	dirty := t.wfs.Dirty()
+	assert.Len(t.T(), dirty, 2)
+	assert.Equal(t.T(), "doc/cat.txt", dirty[0].Name)
+	assert.Equal(t.T(), 2, dirty[0].Writes)
+	assert.Equal(t.T(), int64(25), dirty[1].Size)
+	assert.Equal(t.T(), int64(39), t.wfs.DirtyBytes())
+
+	// Dirty files are read from the buffer and listed before they are flushed.
+	b, err := t.wfs.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox jumps", string(b))
+
+	_, err = t.backing.Stat("doc/cat.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	assert.NoError(t.T(), fstest.TestFS(t.wfs, "doc/cat.txt", "doc/dog.txt", "doc/fox.txt"))
+
+	matches, err := t.wfs.Glob("doc/*.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []string{"doc/cat.txt", "doc/dog.txt", "doc/fox.txt"}, matches)
+
+	assert.NoError(t.T(), t.wfs.Flush())
+	assert.Empty(t.T(), t.wfs.Dirty())
+	assert.Equal(t.T(), 1, t.backing.count("doc/cat.txt"))
+
+	for name, content := range map[string]string{
+		"doc/cat.txt": "the sleepy cat",
+		"doc/dog.txt": "the lazy dog",
+		"doc/fox.txt": "the quick brown fox jumps",
+	} {
+		b, err := t.backing.ReadFile(name)
+		assert.NoError(t.T(), err)
+		assert.Equal(t.T(), content, string(b))
+	}
+}
+
+func (t *WritebackFSTestSuite) TestRemoveRename() {
+	assert.NoError(t.T(), t.wfs.WriteFile("doc/tmp.txt", []byte("temporary"), 0644))
+	assert.NoError(t.T(), t.wfs.Remove("doc/tmp.txt"))
+	assert.Empty(t.T(), t.wfs.Dirty())
+	assert.ErrorIs(t.T(), t.wfs.Remove("doc/tmp.txt"), gofs.ErrNotExist)
+
+	assert.NoError(t.T(), t.wfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t.T(), t.wfs.Rename("doc/dog.txt", "dog.txt"))
+	assert.Empty(t.T(), t.wfs.Dirty())
+
+	b, err := t.backing.ReadFile("dog.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the lazy dog", string(b))
+	assert.Zero(t.T(), t.backing.count("doc/tmp.txt"))
+
+	assert.NoError(t.T(), t.wfs.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0644))
+	assert.NoError(t.T(), t.wfs.RemoveAll("doc"))
+	assert.Empty(t.T(), t.wfs.Dirty())
+
+	_, err = t.backing.Stat("doc")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.wfs.Create("missing/fox.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	_, err = t.wfs.OpenFile("fox.txt", fs.O_WRONLY, 0)
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+	assert.Empty(t.T(), t.wfs.Dirty())
+
+	// Files that are open for writing are moved by a rename, and flushed to the new path once they are closed.
+	f, err := t.wfs.Create("a.txt")
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	_, err = f.Write([]byte("hello"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), t.wfs.Rename("a.txt", "b.txt"))
+
+	_, err = f.Write([]byte(" world"))
+	assert.NoError(t.T(), err)
+	assert.NoError(t.T(), f.Close())
+	assert.NoError(t.T(), t.wfs.Flush())
+	assert.Empty(t.T(), t.wfs.Dirty())
+
+	_, err = t.backing.Stat("a.txt")
+	assert.ErrorIs(t.T(), err, gofs.ErrNotExist)
+
+	b, err = t.backing.ReadFile("b.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "hello world", string(b))
+}
+
+func (t *WritebackFSTestSuite) TestTriggers() {
+	wfs, err := New(t.backing, WithMaxDirtyBytes(16))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), wfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.Len(t.T(), wfs.Dirty(), 1)
+
+	// Exceeding the maximum size of the buffered content triggers a flush in the background.
+	assert.NoError(t.T(), wfs.WriteFile("doc/cat.txt", []byte("the sleepy cat"), 0644))
+	assert.Eventually(t.T(), func() bool {
+		return len(wfs.Dirty()) == 0
+	}, 5*time.Second, time.Millisecond)
+	assert.NoError(t.T(), wfs.Close())
+
+	wfs, err = New(t.backing, WithFlushInterval(10*time.Millisecond))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), wfs.WriteFile("doc/owl.txt", []byte("the wise owl"), 0644))
+	assert.Eventually(t.T(), func() bool {
+		return t.backing.count("doc/owl.txt") == 1
+	}, 5*time.Second, time.Millisecond)
+
+	// Closing the file system flushes the dirty files.
+	wfs.interval = 0
+	assert.NoError(t.T(), wfs.WriteFile("doc/fox.txt", []byte("the quick brown fox jumps"), 0644))
+	assert.NoError(t.T(), wfs.Close())
+	assert.ErrorIs(t.T(), wfs.Close(), gofs.ErrClosed)
+
+	b, err := t.backing.ReadFile("doc/fox.txt")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "the quick brown fox jumps", string(b))
+
+	assert.ErrorIs(t.T(), wfs.WriteFile("doc/fox.txt", nil, 0644), gofs.ErrClosed)
+}
+
+func (t *WritebackFSTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	wfs, err := New(t.backing, WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	assert.NoError(t.T(), wfs.WriteFile("doc/dog.txt", []byte("the lazy dog"), 0644))
+	assert.NoError(t.T(), wfs.Flush())
+	assert.NoError(t.T(), wfs.Close())
+	assert.Contains(t.T(), buf.String(), `msg="[writebackfs] flush" files=1`)
+
+	assert.Equal(t.T(), fs.NopLogger(), t.wfs.logger)
+}